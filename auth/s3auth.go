@@ -58,6 +58,31 @@ type S3BearerIdentity struct {
 	// MaxScanBytes is the maximum number of bytes
 	// allowed to be scanned on any query.
 	MaxScanBytes uint64 `json:"MaxScanBytes"`
+	// MaxScanBytesPerHour and MaxScanBytesPerDay
+	// are the maximum number of bytes allowed to
+	// be scanned by all queries over the past
+	// hour and day, respectively.
+	MaxScanBytesPerHour uint64 `json:"MaxScanBytesPerHour,omitempty"`
+	MaxScanBytesPerDay  uint64 `json:"MaxScanBytesPerDay,omitempty"`
+	// MaxConcurrentQueries is the maximum number
+	// of queries executed at the same time, and
+	// MaxQueuedQueries is the maximum number of
+	// queries waiting for them to finish.
+	MaxConcurrentQueries int `json:"MaxConcurrentQueries,omitempty"`
+	MaxQueuedQueries     int `json:"MaxQueuedQueries,omitempty"`
+	// MaxQuerySeconds is the maximum
+	// duration of a query in seconds.
+	MaxQuerySeconds int64 `json:"MaxQuerySeconds,omitempty"`
+	// MaxMemoryBytes is the maximum amount of memory
+	// used by the processes executing queries.
+	MaxMemoryBytes uint64 `json:"MaxMemoryBytes,omitempty"`
+	// CPUWeight is the relative CPU share
+	// of the processes executing queries.
+	CPUWeight int `json:"CPUWeight,omitempty"`
+	// Principal optionally identifies the user
+	// or service on whose behalf the token was
+	// issued; it is recorded in audit logs.
+	Principal string `json:"Principal,omitempty"`
 }
 
 type S3BearerCredentials struct {
@@ -109,9 +134,18 @@ func (s *S3BearerIdentity) Tenant(ctx context.Context) (db.Tenant, error) {
 	root.Key = aws.DeriveKey(c.BaseURI, c.AccessKeyID, c.SecretAccessKey, s.Region, "s3")
 	root.Key.Token = c.SessionToken
 	cfg := &db.TenantConfig{
-		MaxScanBytes: s.MaxScanBytes,
+		MaxScanBytes:         s.MaxScanBytes,
+		MaxScanBytesPerHour:  s.MaxScanBytesPerHour,
+		MaxScanBytesPerDay:   s.MaxScanBytesPerDay,
+		MaxConcurrentQueries: s.MaxConcurrentQueries,
+		MaxQueuedQueries:     s.MaxQueuedQueries,
+		MaxQueryDuration:     time.Duration(s.MaxQuerySeconds) * time.Second,
+		MaxMemoryBytes:       s.MaxMemoryBytes,
+		CPUWeight:            s.CPUWeight,
 	}
-	return S3Tenant(ctx, s.ID, root, k, cfg), nil
+	t := S3Tenant(ctx, s.ID, root, k, cfg).(*s3Tenant)
+	t.principal = s.Principal
+	return t, nil
 }
 
 func (s *S3Bearer) client() *http.Client {
@@ -159,6 +193,8 @@ type s3Tenant struct {
 	root *db.S3FS
 	ikey *blockfmt.Key
 	cfg  *db.TenantConfig
+
+	principal string
 }
 
 // S3TenantFromEnv constructs an s3 tenant from the environment.
@@ -207,6 +243,7 @@ func (s *s3Tenant) ID() string                { return s.id }
 func (s *s3Tenant) Key() *blockfmt.Key        { return s.ikey }
 func (s *s3Tenant) Root() (db.InputFS, error) { return s.root, nil }
 func (s *s3Tenant) Config() *db.TenantConfig  { return s.cfg }
+func (s *s3Tenant) Principal() string         { return s.principal }
 
 // S3Static is a Provider that is backed
 // by a single static S3 identity.
@@ -0,0 +1,291 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller"
+	"github.com/SnellerInc/sneller/db"
+	"github.com/google/uuid"
+)
+
+const (
+	// auditFlushInterval is how often
+	// the audit entries of each tenant
+	// are written to the audit table
+	auditFlushInterval = time.Minute
+
+	// auditFlushSize is the size at which the
+	// buffered entries of a tenant are written
+	// to the audit table without waiting
+	// for the next flush
+	auditFlushSize = 1024 * 1024
+
+	// auditDir is the directory in the
+	// tenant root under which the objects
+	// of the audit table are stored
+	auditDir = "audit"
+)
+
+// audit statuses, in addition to
+// the query outcomes (see metrics.go)
+const (
+	auditInvalid     = "invalid"
+	auditNotModified = "not-modified"
+	auditDryRun      = "dry-run"
+	auditCached      = "cached"
+	auditDenied      = "denied"
+)
+
+// auditEntry is one entry of the audit log.
+//
+// The entry only ever contains the redacted
+// text of the query, and errors are not
+// included since their text may contain
+// parts of the query.
+type auditEntry struct {
+	Time      time.Time `json:"time"`
+	Tenant    string    `json:"tenant"`
+	Principal string    `json:"principal,omitempty"`
+	Client    string    `json:"client,omitempty"`
+	// Interface is the interface through which
+	// the query was submitted (http, async or postgres)
+	Interface string   `json:"interface"`
+	QueryID   string   `json:"query_id,omitempty"`
+	Query     string   `json:"query,omitempty"`
+	Database  string   `json:"database,omitempty"`
+	Tables    []string `json:"tables,omitempty"`
+	Scanned   int64    `json:"scanned"`
+	// Duration is the duration in seconds
+	Duration float64 `json:"duration"`
+	Status   string  `json:"status"`
+
+	creds db.Tenant
+}
+
+// setTables sets the tables touched by a query
+// from the indexes opened while planning it
+func (e *auditEntry) setTables(env *sneller.FSEnv) {
+	idx := env.Indexes()
+	e.Tables = make([]string, len(idx))
+	for i := range idx {
+		e.Tables[i] = idx[i].DB + "." + idx[i].Table
+	}
+}
+
+// principalOf returns the principal on whose behalf
+// a request is made: the principal of the tenant,
+// if it is known, or otherwise a fingerprint of
+// the token presented by the client
+func principalOf(creds db.Tenant, token string) string {
+	if tp, ok := creds.(db.TenantPrincipal); ok {
+		if p := tp.Principal(); p != "" {
+			return p
+		}
+	}
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:8])
+}
+
+// auditBatch holds the entries of one tenant
+// that have not been written to the audit table yet
+type auditBatch struct {
+	creds db.Tenant
+	buf   bytes.Buffer
+}
+
+// auditLog writes audit log entries as JSON lines
+// to a file and/or, for each tenant, to objects
+// in the tenant's storage that comprise a table
+type auditLog struct {
+	lock sync.Mutex
+	// out, if non-nil, receives every entry
+	out io.Writer
+	// db and table, if set, name the
+	// table of each tenant that receives
+	// the entries of the tenant
+	db, table string
+
+	pending map[string]*auditBatch
+	// defined holds the tenants for which
+	// the audit table has been defined
+	defined map[string]bool
+	logf    func(f string, args ...any)
+}
+
+func (a *auditLog) enabled() bool { return a.out != nil || a.table != "" }
+
+// parseAuditTable parses the name of the
+// audit table as it is passed to -audit-table
+func parseAuditTable(name string) (string, string, error) {
+	dbname, table, ok := strings.Cut(name, ".")
+	if !ok || dbname == "" || table == "" || strings.Contains(table, ".") {
+		return "", "", fmt.Errorf("invalid audit table %q (expected db.table)", name)
+	}
+	return dbname, table, nil
+}
+
+// begin returns a new entry for a query
+// submitted by a tenant
+func (a *auditLog) begin(creds db.Tenant, iface, principal, client string) *auditEntry {
+	return &auditEntry{
+		Time:      time.Now().UTC(),
+		Tenant:    creds.ID(),
+		Principal: principal,
+		Client:    client,
+		Interface: iface,
+		Status:    outcomeError,
+		creds:     creds,
+	}
+}
+
+// end completes an entry and writes it to the log
+func (a *auditLog) end(e *auditEntry) {
+	if !a.enabled() {
+		return
+	}
+	e.Duration = time.Since(e.Time).Seconds()
+	buf, err := json.Marshal(e)
+	if err != nil {
+		a.logf("audit: %s", err)
+		return
+	}
+	buf = append(buf, '\n')
+	var flush *auditBatch
+	a.lock.Lock()
+	if a.out != nil {
+		if _, err := a.out.Write(buf); err != nil {
+			a.logf("audit: %s", err)
+		}
+	}
+	if a.table != "" {
+		if a.pending == nil {
+			a.pending = make(map[string]*auditBatch)
+		}
+		b := a.pending[e.Tenant]
+		if b == nil {
+			b = &auditBatch{}
+			a.pending[e.Tenant] = b
+		}
+		b.creds = e.creds
+		b.buf.Write(buf)
+		if b.buf.Len() >= auditFlushSize {
+			delete(a.pending, e.Tenant)
+			flush = b
+		}
+	}
+	a.lock.Unlock()
+	if flush != nil {
+		a.write(flush)
+	}
+}
+
+// flush writes all of the pending
+// entries to the audit tables
+func (a *auditLog) flush() {
+	a.lock.Lock()
+	pending := a.pending
+	a.pending = nil
+	a.lock.Unlock()
+	for _, b := range pending {
+		a.write(b)
+	}
+}
+
+// run flushes the pending entries
+// periodically until stop is closed
+func (a *auditLog) run(stop chan struct{}) {
+	t := time.NewTicker(auditFlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			a.flush()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// write writes a batch of entries to a new
+// object in the audit table of the tenant
+func (a *auditLog) write(b *auditBatch) {
+	tenantID := b.creds.ID()
+	if err := a.writeBatch(b); err != nil {
+		a.logf("tenant %s: writing audit log: %s", tenantID, err)
+	}
+}
+
+func (a *auditLog) writeBatch(b *auditBatch) error {
+	root, err := b.creds.Root()
+	if err != nil {
+		return err
+	}
+	out, ok := root.(db.OutputFS)
+	if !ok {
+		return fmt.Errorf("tenant storage does not support writing")
+	}
+	if err := a.define(b.creds.ID(), out); err != nil {
+		return err
+	}
+	name := path.Join(auditDir, time.Now().UTC().Format("20060102T150405Z")+"-"+uuid.New().String()+".json")
+	_, err = out.WriteFile(name, b.buf.Bytes())
+	return err
+}
+
+// define creates the definition of the audit
+// table of a tenant if it does not exist yet
+func (a *auditLog) define(tenantID string, out db.OutputFS) error {
+	a.lock.Lock()
+	done := a.defined[tenantID]
+	a.lock.Unlock()
+	if done {
+		return nil
+	}
+	_, err := db.OpenDefinition(out, a.db, a.table)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = db.WriteDefinition(out, a.db, a.table, &db.Definition{
+			Inputs: []db.Input{{
+				Pattern: out.Prefix() + path.Join(auditDir, "*.json"),
+				Format:  "json",
+			}},
+		})
+	}
+	if err != nil {
+		return err
+	}
+	a.lock.Lock()
+	if a.defined == nil {
+		a.defined = make(map[string]bool)
+	}
+	a.defined[tenantID] = true
+	a.lock.Unlock()
+	return nil
+}
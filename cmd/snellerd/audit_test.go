@@ -0,0 +1,143 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/SnellerInc/sneller/db"
+)
+
+type principalTenant struct {
+	db.Tenant
+	principal string
+}
+
+func (p *principalTenant) Principal() string { return p.principal }
+
+func TestPrincipalOf(t *testing.T) {
+	tt := testdirEnviron(t)
+	if p := principalOf(tt, ""); p != "" {
+		t.Errorf("got %q without a token", p)
+	}
+	p := principalOf(tt, "secret-token")
+	if !strings.HasPrefix(p, "token:") || len(p) != len("token:")+16 {
+		t.Errorf("unexpected principal %q", p)
+	}
+	if strings.Contains(p, "secret") {
+		t.Error("principal contains the token")
+	}
+	if p2 := principalOf(tt, "other-token"); p2 == p {
+		t.Error("different tokens have the same principal")
+	}
+	pt := &principalTenant{Tenant: tt, principal: "alice"}
+	if p := principalOf(pt, "secret-token"); p != "alice" {
+		t.Errorf("got %q", p)
+	}
+}
+
+func TestParseAuditTable(t *testing.T) {
+	dbname, table, err := parseAuditTable("logs.audit")
+	if err != nil || dbname != "logs" || table != "audit" {
+		t.Errorf("got %q %q %v", dbname, table, err)
+	}
+	for _, bad := range []string{"", "audit", ".audit", "logs.", "a.b.c"} {
+		if _, _, err := parseAuditTable(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	tt := testdirEnviron(t)
+	var out bytes.Buffer
+	a := auditLog{
+		out:   &out,
+		db:    "logs",
+		table: "audit",
+		logf:  t.Logf,
+	}
+	for _, status := range []string{outcomeOK, outcomeError} {
+		e := a.begin(tt, "http", "alice", "10.0.0.1")
+		e.QueryID = "id-" + status
+		e.Query = "SELECT COUNT(*) FROM parking WHERE x = ?"
+		e.Tables = []string{"default.parking"}
+		e.Scanned = 100
+		e.Status = status
+		a.end(e)
+	}
+
+	var entries []auditEntry
+	s := bufio.NewScanner(&out)
+	for s.Scan() {
+		var e auditEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries", len(entries))
+	}
+	e := &entries[0]
+	if e.Tenant != tt.ID() || e.Principal != "alice" || e.Client != "10.0.0.1" ||
+		e.Interface != "http" || e.QueryID != "id-ok" || e.Scanned != 100 ||
+		e.Status != outcomeOK || len(e.Tables) != 1 || e.Time.IsZero() {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	// nothing is written to the table until a flush
+	root, err := tt.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(root, auditDir); err == nil {
+		t.Fatal("audit objects written before a flush")
+	}
+	a.flush()
+	objects, err := fs.Glob(root, auditDir+"/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("got objects %v", objects)
+	}
+	buf, err := fs.ReadFile(root, objects[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(buf, []byte{'\n'}); n != 2 {
+		t.Errorf("object has %d entries", n)
+	}
+	def, err := db.OpenDefinition(root, "logs", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(def.Inputs) != 1 || def.Inputs[0].Format != "json" ||
+		!strings.HasSuffix(def.Inputs[0].Pattern, auditDir+"/*.json") {
+		t.Errorf("unexpected definition %+v", def)
+	}
+	// a second flush has nothing to write
+	a.flush()
+	objects, _ = fs.Glob(root, auditDir+"/*.json")
+	if len(objects) != 1 {
+		t.Fatalf("got objects %v", objects)
+	}
+}
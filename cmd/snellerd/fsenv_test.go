@@ -17,10 +17,12 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net"
 	"net/http"
 	"os"
@@ -33,12 +35,15 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/SnellerInc/sneller/db"
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
 	"github.com/SnellerInc/sneller/tenant"
+	"github.com/SnellerInc/sneller/tracing"
 
+	"github.com/google/uuid"
 	"golang.org/x/exp/slices"
 )
 
@@ -167,6 +172,76 @@ func listen(t *testing.T) net.Listener {
 	return sock
 }
 
+// startServer starts a snellerd server without peers
+// and returns it along with a requester for its HTTP address.
+// configure is called before the server starts serving
+// and must at least set the auth provider.
+// The server is closed when the test completes.
+func startServer(t *testing.T, configure func(*server)) (*server, *requester) {
+	s := &server{
+		logger:    testlogger(t),
+		sandbox:   tenant.CanSandbox(),
+		cachedir:  t.TempDir(),
+		cgroot:    os.Getenv("CGROOT"),
+		tenantcmd: []string{"./snellerd-test-binary", "worker"},
+		peers:     noPeers{},
+	}
+	configure(s)
+	httpsock := listen(t)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	s.aboutToServe = (&wg).Done
+	go s.Serve(httpsock, nil)
+	wg.Wait()
+	t.Cleanup(func() { s.Close() })
+	return s, &requester{
+		t:    t,
+		host: "http://" + httpsock.Addr().String(),
+	}
+}
+
+// do sends a request for uri with the
+// test tenant's token and returns the response
+func (r *requester) do(method, uri, accept string, body io.Reader) *http.Response {
+	req, err := http.NewRequest(method, r.host+uri, body)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer snellerd-test")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return res
+}
+
+// queryStatus returns the status of an asynchronous query
+func (r *requester) queryStatus(id string) asyncStatus {
+	res := r.do(http.MethodGet, "/queries/"+id, "", nil)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		r.t.Fatalf("get /queries/%s: %s", id, res.Status)
+	}
+	var st asyncStatus
+	if err := json.NewDecoder(res.Body).Decode(&st); err != nil {
+		r.t.Fatal(err)
+	}
+	return st
+}
+
+// waitQuery polls the status of an asynchronous
+// query until it is no longer running
+func (r *requester) waitQuery(st asyncStatus) asyncStatus {
+	for st.State == asyncRunning {
+		time.Sleep(10 * time.Millisecond)
+		st = r.queryStatus(st.ID)
+	}
+	return st
+}
+
 func testFiles(t *testing.T) {
 	now, err := os.ReadDir("/proc/self/fd")
 	if err == nil {
@@ -641,3 +716,739 @@ func TestSimpleFS(t *testing.T) {
 		})
 	}
 }
+
+func TestAsyncQuery(t *testing.T) {
+	s, rq := startServer(t, func(s *server) {
+		s.auth = testAuth{testdirEnviron(t)}
+	})
+	res := rq.do(http.MethodPost, "/queries?database=default", "", strings.NewReader("SELECT Ticket FROM parking"))
+	if res.StatusCode != http.StatusAccepted {
+		buf, _ := io.ReadAll(res.Body)
+		t.Fatalf("post /queries: %s %s", res.Status, buf)
+	}
+	var st asyncStatus
+	err := json.NewDecoder(res.Body).Decode(&st)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ID == "" || res.Header.Get("X-Sneller-Query-ID") != st.ID {
+		t.Fatalf("unexpected query ID %q", st.ID)
+	}
+	if loc := res.Header.Get("Location"); loc != "/queries/"+st.ID {
+		t.Errorf("unexpected location %q", loc)
+	}
+	st = rq.waitQuery(st)
+	if st.State != asyncSucceeded {
+		t.Fatalf("query state %q: %s", st.State, st.Error)
+	}
+	if st.Rows != 1023 || st.Pages != 2 {
+		t.Fatalf("got %d rows in %d pages", st.Rows, st.Pages)
+	}
+
+	// the pages should contain all of the rows
+	rows := 0
+	for i := 0; i < st.Pages; i++ {
+		res := rq.do(http.MethodGet, fmt.Sprintf("/queries/%s/results?page=%d", st.ID, i), "application/x-ndjson", nil)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("page %d: %s", i, res.Status)
+		}
+		s := bufio.NewScanner(res.Body)
+		for s.Scan() {
+			if !strings.HasPrefix(s.Text(), `{"Ticket": `) {
+				t.Fatalf("unexpected row %q", s.Text())
+			}
+			rows++
+		}
+		res.Body.Close()
+	}
+	if rows != 1023 {
+		t.Errorf("got %d rows from pages", rows)
+	}
+	res = rq.do(http.MethodGet, fmt.Sprintf("/queries/%s/results?page=%d", st.ID, st.Pages), "", nil)
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("page past the end: %s", res.Status)
+	}
+
+	// the status should survive being forgotten by the server
+	s.async.remove(st.ID)
+	if got := rq.queryStatus(st.ID); got.State != asyncSucceeded || got.Rows != st.Rows {
+		t.Errorf("persisted status %+v", got)
+	}
+
+	res = rq.do(http.MethodDelete, "/queries/"+st.ID, "", nil)
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete /queries/%s: %s", st.ID, res.Status)
+	}
+	for _, uri := range []string{
+		"/queries/" + st.ID,
+		"/queries/" + st.ID + "/results",
+		"/queries/not-a-query-id",
+	} {
+		res = rq.do(http.MethodGet, uri, "", nil)
+		res.Body.Close()
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("get %s: %s", uri, res.Status)
+		}
+	}
+}
+
+func TestRunningQueries(t *testing.T) {
+	_, rq := startServer(t, func(s *server) {
+		s.auth = testAuth{testdirEnviron(t)}
+	})
+	submit := func(query string) asyncStatus {
+		res := rq.do(http.MethodPost, "/queries?database=default", "", strings.NewReader(query))
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusAccepted {
+			t.Fatalf("post /queries: %s", res.Status)
+		}
+		var st asyncStatus
+		if err := json.NewDecoder(res.Body).Decode(&st); err != nil {
+			t.Fatal(err)
+		}
+		return st
+	}
+	cancel := func(id string) int {
+		res := rq.do(http.MethodPost, "/queries/"+id+"/cancel", "", nil)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// finished queries are not running
+	st := rq.waitQuery(submit("SELECT COUNT(*) FROM parking"))
+	if st.State != asyncSucceeded {
+		t.Fatalf("query state %q: %s", st.State, st.Error)
+	}
+	res := rq.do(http.MethodGet, "/queries/running", "", nil)
+	var running []runningStatus
+	err := json.NewDecoder(res.Body).Decode(&running)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(running) != 0 {
+		t.Errorf("running queries: %+v", running)
+	}
+	if code := cancel(st.ID); code != http.StatusNotFound {
+		t.Errorf("cancel finished query: %d", code)
+	}
+
+	// a query is either canceled or
+	// finishes before it can be canceled
+	st = submit("SELECT * FROM taxi ORDER BY tpep_pickup_datetime LIMIT 5000")
+	code := cancel(st.ID)
+	canceled := code == http.StatusNoContent
+	if !canceled && code != http.StatusNotFound {
+		t.Fatalf("cancel: %d", code)
+	}
+	st = rq.waitQuery(st)
+	want := asyncSucceeded
+	if canceled {
+		want = asyncCanceled
+	}
+	if st.State != want {
+		t.Errorf("state %q after cancel; want %q", st.State, want)
+	}
+	t.Logf("canceled: %v", canceled)
+}
+
+func TestQueryMetrics(t *testing.T) {
+	tt := testdirEnviron(t)
+	s, rq := startServer(t, func(s *server) {
+		s.auth = testAuth{tt}
+		s.metricsock = listen(t)
+	})
+	res, err := http.DefaultClient.Do(rq.getQuery("default", "SELECT COUNT(*) FROM parking"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got %s", res.Status)
+	}
+
+	// the metrics are only served on their own listener
+	res = rq.do(http.MethodGet, "/metrics", "", nil)
+	buf, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if strings.Contains(string(buf), "sneller_queries_total") {
+		t.Error("metrics served on the query listener")
+	}
+	res, err = http.Get("http://" + s.metricsock.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, _ = io.ReadAll(res.Body)
+	res.Body.Close()
+	want := fmt.Sprintf("sneller_queries_total{tenant=%q,outcome=\"ok\"} 1\n", tt.ID())
+	if !strings.Contains(string(buf), want) {
+		t.Errorf("metrics do not contain %q:\n%s", want, buf)
+	}
+}
+
+// deadlineRecorder records the read deadline
+// set on a connection
+type deadlineRecorder struct {
+	io.Reader
+	deadline time.Time
+}
+
+func (d *deadlineRecorder) SetReadDeadline(t time.Time) error {
+	d.deadline = t
+	return nil
+}
+
+func TestAsyncQueryDeadline(t *testing.T) {
+	var rc, out deadlineRecorder
+	var a asyncQueries
+	if a.setDeadline(&rc, &out) || !rc.deadline.IsZero() || !out.deadline.IsZero() {
+		t.Fatal("deadline set without a timeout")
+	}
+	a.timeout = defaultAsyncTimeout
+	start := time.Now()
+	if !a.setDeadline(&rc, &out) {
+		t.Fatal("deadline not set")
+	}
+	// asynchronous queries must outlive the
+	// timeout of synchronous queries
+	for _, d := range []time.Time{rc.deadline, out.deadline} {
+		if !d.After(start.Add(queryKillTimeout)) {
+			t.Errorf("deadline in %s, want more than %s", d.Sub(start), queryKillTimeout)
+		}
+	}
+}
+
+func TestResultCacheQuery(t *testing.T) {
+	tt := testdirEnviron(t)
+	s, rq := startServer(t, func(s *server) {
+		s.auth = testAuth{tt}
+		s.cache = resultCache{max: 1024 * 1024}
+	})
+	query := func(text, want string, nocache bool) string {
+		req := rq.getQuery("default", text)
+		req.Header.Set("Accept", "application/x-ndjson")
+		if nocache {
+			req.Header.Set("Cache-Control", "no-cache")
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("%s: %s", res.Status, body)
+		}
+		if got := res.Header.Get("X-Sneller-Cache"); got != want {
+			t.Fatalf("X-Sneller-Cache: got %q, want %q", got, want)
+		}
+		return string(body)
+	}
+
+	const text = "SELECT COUNT(*) AS n FROM parking2"
+	first := query(text, "miss", false)
+	if second := query(text, "hit", false); second != first {
+		t.Fatalf("cached result %q differs from %q", second, first)
+	}
+	query(text, "miss", true)
+	// a different query is a different entry
+	query("SELECT COUNT(*) AS n FROM taxi", "miss", false)
+
+	// add a row to the table; the cached
+	// result must not be used any more
+	dfs, err := tt.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := dfs.(*db.DirFS).Root
+	err = os.WriteFile(filepath.Join(root, "a-prefix", "parking4.json"), []byte(`{"Ticket": 1}`), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := db.Config{
+		Align:         testBlocksize,
+		RangeMultiple: 10,
+	}
+	if err := c.Sync(tt, "default", "parking2"); err != nil {
+		t.Fatal(err)
+	}
+	updated := query(text, "miss", false)
+	if updated == first {
+		t.Fatalf("got stale result %q", updated)
+	}
+	query(text, "hit", false)
+	if st := s.cache.stats(); st.entries != 2 || st.invalidations != 1 || st.hits != 2 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+// configuredTenant is a tenant with a configuration
+type configuredTenant struct {
+	db.Tenant
+	cfg *db.TenantConfig
+}
+
+func (c *configuredTenant) Config() *db.TenantConfig { return c.cfg }
+
+func TestQueryQuota(t *testing.T) {
+	tt := &configuredTenant{
+		Tenant: testdirEnviron(t),
+		cfg:    &db.TenantConfig{MaxScanBytesPerHour: 1},
+	}
+	s, rq := startServer(t, func(s *server) {
+		s.auth = testAuth{tt}
+		s.metricsock = listen(t)
+	})
+	res, err := http.DefaultClient.Do(rq.getQuery("default", "SELECT COUNT(*) FROM parking"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got %s %s", res.Status, body)
+	}
+	if !strings.Contains(string(body), "per hour") {
+		t.Errorf("unexpected reason %q", body)
+	}
+
+	// with a larger limit the query is
+	// admitted, and its usage is recorded
+	tt.cfg.MaxScanBytesPerHour = 1 << 30
+	res, err = http.DefaultClient.Do(rq.getQuery("default", "SELECT COUNT(*) FROM parking"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got %s", res.Status)
+	}
+	s.quotas.lock.Lock()
+	used := s.quotas.tenants[tt.ID()].hour.total(time.Now())
+	s.quotas.lock.Unlock()
+	if used == 0 {
+		t.Error("no usage recorded")
+	}
+	res, err = http.Get("http://" + s.metricsock.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	want := fmt.Sprintf("sneller_queries_total{tenant=%q,outcome=\"rejected\"} 1\n", tt.ID())
+	if !strings.Contains(string(metrics), want) {
+		t.Errorf("metrics do not contain %q", want)
+	}
+}
+
+func TestQuerySpill(t *testing.T) {
+	spilldir := t.TempDir()
+	s, rq := startServer(t, func(s *server) {
+		s.auth = testAuth{testdirEnviron(t)}
+		s.spilldir = spilldir
+		// make ORDER BY spill almost immediately
+		s.tenantcmd = append(s.tenantcmd, "-sort-memory", "4096")
+	})
+	res, err := http.DefaultClient.Do(rq.getQueryJSON("default", "SELECT Ticket FROM parking ORDER BY Ticket"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got %s (%s)", res.Status, buf)
+	}
+	var rows []struct{ Ticket int64 }
+	if err := json.Unmarshal(buf, &rows); err != nil {
+		t.Fatalf("%s: %s", buf, err)
+	}
+	if len(rows) != 1023 {
+		t.Errorf("got %d rows", len(rows))
+	}
+	for i := 1; i < len(rows); i++ {
+		if rows[i-1].Ticket > rows[i].Ticket {
+			t.Fatalf("row %d: %d > %d", i, rows[i-1].Ticket, rows[i].Ticket)
+		}
+	}
+	// the tenant process has its own spill directory,
+	// which the query has emptied, and no spill files
+	// were written to the cache directory
+	dirs, err := os.ReadDir(spilldir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 1 || !dirs[0].IsDir() {
+		t.Fatalf("unexpected spill dir contents %v", dirs)
+	}
+	files, _ := os.ReadDir(filepath.Join(spilldir, dirs[0].Name()))
+	if len(files) != 0 {
+		t.Errorf("spill files left behind: %v", files)
+	}
+	filepath.WalkDir(s.cachedir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && strings.HasPrefix(d.Name(), "sneller-") {
+			t.Errorf("spill file %s in cache dir", p)
+		}
+		return nil
+	})
+}
+
+func TestQueryAudit(t *testing.T) {
+	tt := testdirEnviron(t)
+	var out bytes.Buffer
+	s, rq := startServer(t, func(s *server) {
+		s.auth = testAuth{tt}
+		s.audit = auditLog{out: &out}
+	})
+	req := rq.getQuery("default", "SELECT COUNT(*) FROM parking WHERE Color = 'secret'")
+	req.Header.Set("X-Forwarded-For", "192.0.2.1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got %s", res.Status)
+	}
+	queryID := res.Header.Get("X-Sneller-Query-ID")
+
+	// the entry is written once the
+	// handler has returned
+	var line []byte
+	for i := 0; i < 100 && line == nil; i++ {
+		s.audit.lock.Lock()
+		if out.Len() > 0 {
+			line = bytes.Clone(out.Bytes())
+		}
+		s.audit.lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if line == nil {
+		t.Fatal("no audit entry written")
+	}
+	if bytes.Contains(line, []byte("secret")) {
+		t.Errorf("audit entry contains the query literal: %s", line)
+	}
+	var e auditEntry
+	if err := json.Unmarshal(line, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Tenant != tt.ID() || e.QueryID != queryID || e.Interface != "http" ||
+		e.Client != "192.0.2.1" || e.Status != outcomeOK || e.Scanned == 0 ||
+		e.Database != "default" || !strings.HasPrefix(e.Principal, "token:") {
+		t.Errorf("unexpected entry %s", line)
+	}
+	if len(e.Tables) != 1 || e.Tables[0] != "default.parking" {
+		t.Errorf("unexpected tables %v", e.Tables)
+	}
+}
+
+// spanCollector is a tracing.Exporter
+// that keeps the spans it exports
+type spanCollector struct {
+	lock  sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (c *spanCollector) Export(spans []tracing.SpanData) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.spans = append(c.spans, spans...)
+	return nil
+}
+
+func (c *spanCollector) take() []tracing.SpanData {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	spans := c.spans
+	c.spans = nil
+	return spans
+}
+
+func TestQueryTrace(t *testing.T) {
+	tt := testdirEnviron(t)
+	var c spanCollector
+	_, rq := startServer(t, func(s *server) {
+		s.auth = testAuth{tt}
+		s.tracer = tracer{exp: &c}
+	})
+	query := func(traceparent string) *http.Response {
+		req := rq.getQuery("default", "SELECT COUNT(*) FROM parking")
+		if traceparent != "" {
+			req.Header.Set("traceparent", traceparent)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("got %s", res.Status)
+		}
+		return res
+	}
+	wait := func() []tracing.SpanData {
+		// the trace is exported once
+		// the handler has returned
+		var spans []tracing.SpanData
+		for i := 0; i < 100; i++ {
+			spans = append(spans, c.take()...)
+			for j := range spans {
+				if spans[j].Name == "query" {
+					return spans
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("no trace exported")
+		return nil
+	}
+
+	// sample is zero, so only queries that
+	// are already part of a trace are traced
+	res := query("")
+	if res.Header.Get("X-Sneller-Trace-ID") != "" {
+		t.Error("unexpected trace ID")
+	}
+	res = query("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	if res.Header.Get("X-Sneller-Trace-ID") != "" {
+		t.Error("unexpected trace ID for an unsampled parent")
+	}
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	res = query(traceparent)
+	if got := res.Header.Get("X-Sneller-Trace-ID"); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("got trace ID %q", got)
+	}
+	spans := wait()
+	ids := make(map[tracing.SpanID]string)
+	for i := range spans {
+		ids[spans[i].SpanID] = spans[i].Name
+	}
+	parents := make(map[string]string)
+	for i := range spans {
+		s := &spans[i]
+		if s.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("span %q has trace ID %s", s.Name, s.TraceID)
+		}
+		parents[s.Name] = ids[s.Parent]
+	}
+	for name, parent := range map[string]string{
+		"query":          "",
+		"plan":           "query",
+		"execute":        "query",
+		"tenant execute": "execute",
+	} {
+		got, ok := parents[name]
+		if !ok {
+			t.Errorf("no %q span in %v", name, parents)
+		} else if got != parent {
+			t.Errorf("%q span has parent %q; expected %q", name, got, parent)
+		}
+	}
+	if spans := c.take(); len(spans) != 0 {
+		t.Errorf("unexpected spans %v", spans)
+	}
+}
+
+func TestQueryPolicy(t *testing.T) {
+	tt := testdirEnviron(t)
+	p, err := parsePolicy([]byte(`{
+  "default_roles": ["analyst"],
+  "roles": {
+    "analyst": [{
+      "database": "default",
+      "table": "parking2",
+      "columns": ["Make", "Color"],
+      "mask": ["BodyStyle"],
+      "filter": "Make = 'HOND'"
+    }]
+  }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, rq := startServer(t, func(s *server) {
+		s.auth = testAuth{tt}
+		s.policy = policySource{file: p}
+	})
+	do := func(req *http.Request, want int) []byte {
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		buf, _ := io.ReadAll(res.Body)
+		if res.StatusCode != want {
+			t.Fatalf("%s: got %s (%s)", req.URL, res.Status, buf)
+		}
+		return buf
+	}
+
+	// the parking2 table has 123 rows with Make = 'HOND'
+	var counts []map[string]int
+	buf := do(rq.getQueryJSON("default", "SELECT COUNT(*) FROM parking2"), http.StatusOK)
+	if err := json.Unmarshal(buf, &counts); err != nil {
+		t.Fatalf("%s: %s", buf, err)
+	}
+	if len(counts) != 1 || counts[0]["count"] != 123 {
+		t.Errorf("unexpected result %s", buf)
+	}
+
+	var rows []map[string]any
+	buf = do(rq.getQueryJSON("default", "SELECT * FROM parking2 LIMIT 3"), http.StatusOK)
+	if err := json.Unmarshal(buf, &rows); err != nil {
+		t.Fatalf("%s: %s", buf, err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("unexpected result %s", buf)
+	}
+	for _, row := range rows {
+		body, ok := row["BodyStyle"]
+		if len(row) != 3 || row["Make"] != "HOND" || row["Color"] == nil || !ok || body != nil {
+			t.Errorf("unexpected row %v", row)
+		}
+	}
+
+	do(rq.getQuery("default", "SELECT COUNT(*) FROM taxi"), http.StatusForbidden)
+	do(rq.getQuery("default", "SELECT COUNT(*) FROM TABLE_GLOB(\"park*\")"), http.StatusForbidden)
+	do(rq.getInputs("default", "taxi"), http.StatusForbidden)
+	do(rq.getInputs("default", "parking2"), http.StatusOK)
+
+	var tables []string
+	buf = do(rq.getTables("default"), http.StatusOK)
+	if err := json.Unmarshal(buf, &tables); err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0] != "parking2" {
+		t.Errorf("unexpected tables %v", tables)
+	}
+}
+
+// tokenAuth authorizes each of tokens as the same
+// tenant, so each token is a different principal
+type tokenAuth struct {
+	self   db.Tenant
+	tokens []string
+}
+
+func (a tokenAuth) Authorize(_ context.Context, token string) (db.Tenant, error) {
+	if slices.Contains(a.tokens, token) {
+		return a.self, nil
+	}
+	return nil, errors.New("no such tenant: " + token)
+}
+
+func TestQueryPrincipals(t *testing.T) {
+	tt := testdirEnviron(t)
+	p, err := parsePolicy([]byte(`{
+  "default_roles": ["analyst"],
+  "roles": {
+    "analyst": [{
+      "database": "default",
+      "table": "parking2",
+      "filter": "Make = 'HOND'"
+    }]
+  }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, rq := startServer(t, func(s *server) {
+		s.auth = tokenAuth{tt, []string{"owner", "other"}}
+		s.policy = policySource{file: p}
+	})
+	do := func(token, method, uri string, body io.Reader, want int) []byte {
+		req, err := http.NewRequest(method, rq.host+uri, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		buf, _ := io.ReadAll(res.Body)
+		if res.StatusCode != want {
+			t.Fatalf("%s %s as %s: got %s (%s)", method, uri, token, res.Status, buf)
+		}
+		return buf
+	}
+
+	const text = "SELECT Make FROM parking2"
+	var st asyncStatus
+	buf := do("owner", http.MethodPost, "/queries?database=default", strings.NewReader(text), http.StatusAccepted)
+	if err := json.Unmarshal(buf, &st); err != nil {
+		t.Fatal(err)
+	}
+	for st.State == asyncRunning {
+		time.Sleep(10 * time.Millisecond)
+		buf = do("owner", http.MethodGet, "/queries/"+st.ID, nil, http.StatusOK)
+		if err := json.Unmarshal(buf, &st); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st.State != asyncSucceeded || st.Rows != 123 {
+		t.Fatalf("unexpected status %+v", st)
+	}
+	// the status must not reveal the row filter
+	if strings.Contains(st.Query, "HOND") {
+		t.Errorf("status contains the rewritten query %q", st.Query)
+	}
+
+	// other principals of the tenant can't see
+	// the query, whether it is in memory or not
+	for _, forget := range []bool{false, true} {
+		if forget {
+			s.async.remove(st.ID)
+		}
+		do("other", http.MethodGet, "/queries/"+st.ID, nil, http.StatusNotFound)
+		do("other", http.MethodGet, "/queries/"+st.ID+"/results", nil, http.StatusNotFound)
+		do("other", http.MethodDelete, "/queries/"+st.ID, nil, http.StatusNotFound)
+		do("owner", http.MethodGet, "/queries/"+st.ID, nil, http.StatusOK)
+	}
+	do("owner", http.MethodDelete, "/queries/"+st.ID, nil, http.StatusNoContent)
+
+	// nor list or cancel its running queries
+	canceled := false
+	q := &runningQuery{
+		tenantID:  tt.ID(),
+		principal: principalOf(tt, "owner"),
+		id:        uuid.New().String(),
+		started:   time.Now(),
+		cancel:    func() { canceled = true },
+	}
+	s.running.add(q)
+	defer s.running.remove(q.id)
+	running := func(token string) []runningStatus {
+		var out []runningStatus
+		buf := do(token, http.MethodGet, "/queries/running", nil, http.StatusOK)
+		if err := json.Unmarshal(buf, &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	if got := running("other"); len(got) != 0 {
+		t.Errorf("other principal lists %+v", got)
+	}
+	if got := running("owner"); len(got) != 1 || got[0].ID != q.id {
+		t.Errorf("owner lists %+v", got)
+	}
+	do("other", http.MethodPost, "/queries/"+q.id+"/cancel", nil, http.StatusNotFound)
+	if canceled {
+		t.Fatal("query canceled by another principal")
+	}
+	do("owner", http.MethodPost, "/queries/"+q.id+"/cancel", nil, http.StatusNoContent)
+	if !canceled {
+		t.Fatal("query not canceled by its principal")
+	}
+}
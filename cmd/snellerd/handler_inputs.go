@@ -57,6 +57,15 @@ func (s *server) inputsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	acc, ok := s.accessOf(w, r, tenant)
+	if !ok {
+		return
+	}
+	if !acc.allowed(databaseName, tableName) {
+		http.Error(w, errAccessDenied.Error(), http.StatusForbidden)
+		return
+	}
+
 	next := r.URL.Query().Get("next")
 
 	// start is for backwards compatibility
@@ -0,0 +1,777 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller"
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/tenant"
+	"github.com/SnellerInc/sneller/tenant/tnproto"
+	"github.com/SnellerInc/sneller/tracing"
+	"github.com/SnellerInc/sneller/usock"
+	"github.com/google/uuid"
+)
+
+const (
+	// asyncPageRows is the number of rows
+	// in each page of the results of an
+	// asynchronous query
+	asyncPageRows = 1000
+
+	// asyncResultDir is the directory in the
+	// tenant root under which the results of
+	// asynchronous queries are stored
+	asyncResultDir = "queries"
+
+	// asyncRetention is how long the status of
+	// a finished asynchronous query is kept in
+	// memory; afterwards it is read back from
+	// the status file stored with the results
+	asyncRetention = time.Hour
+
+	// defaultAsyncTimeout is the default maximum
+	// run time of an asynchronous query; it is much
+	// longer than queryKillTimeout since nobody
+	// waits for the results to be sent back
+	defaultAsyncTimeout = 24 * time.Hour
+)
+
+// states of an asynchronous query
+const (
+	asyncRunning   = "running"
+	asyncSucceeded = "succeeded"
+	asyncFailed    = "failed"
+	asyncCanceled  = "canceled"
+)
+
+// asyncStatus is the status of an asynchronous
+// query as returned by GET /queries/{id}
+// and stored alongside the query results
+type asyncStatus struct {
+	ID    string `json:"id"`
+	State string `json:"state"`
+	// Principal is the principal (see principalOf)
+	// that submitted the query; only the same
+	// principal can read or cancel the query
+	Principal string     `json:"principal,omitempty"`
+	Query     string     `json:"query"`
+	Database  string     `json:"database,omitempty"`
+	Created   time.Time  `json:"created"`
+	Finished  *time.Time `json:"finished,omitempty"`
+	// Rows and Pages are the number of rows
+	// and pages written so far
+	Rows       int64  `json:"rows"`
+	Pages      int    `json:"pages"`
+	MaxScanned int64  `json:"max_scanned"`
+	Hits       int64  `json:"hits"`
+	Misses     int64  `json:"misses"`
+	Scanned    int64  `json:"scanned"`
+	Error      string `json:"error,omitempty"`
+}
+
+// asyncQuery is an asynchronous query
+// that has been submitted to this server
+type asyncQuery struct {
+	tenantID string
+	root     db.OutputFS
+	done     chan struct{}
+
+	lock     sync.Mutex
+	status   asyncStatus
+	canceled bool
+	cancel   func()
+
+	// span and exec are the spans of the query
+	// and of its execution, if it is traced
+	span, exec *tracing.Span
+}
+
+// asyncQueries is the set of asynchronous
+// queries known to a server
+type asyncQueries struct {
+	lock    sync.Mutex
+	queries map[string]*asyncQuery
+	// timeout is the maximum run time
+	// of an asynchronous query; zero
+	// means there is no limit
+	timeout time.Duration
+}
+
+func (a *asyncQueries) add(q *asyncQuery) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.queries == nil {
+		a.queries = make(map[string]*asyncQuery)
+	}
+	// forget queries that finished long ago
+	for id, old := range a.queries {
+		old.lock.Lock()
+		finished := old.status.Finished
+		old.lock.Unlock()
+		if finished != nil && time.Since(*finished) > asyncRetention {
+			delete(a.queries, id)
+		}
+	}
+	a.queries[q.status.ID] = q
+}
+
+// get returns the query with the given ID
+// if it was submitted by principal of tenantID
+func (a *asyncQueries) get(tenantID, principal, id string) *asyncQuery {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	q := a.queries[id]
+	if q == nil || q.tenantID != tenantID || q.status.Principal != principal {
+		return nil
+	}
+	return q
+}
+
+func (a *asyncQueries) remove(id string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.queries, id)
+}
+
+func asyncDir(id string) string {
+	return path.Join(asyncResultDir, id)
+}
+
+func asyncPage(id string, page int) string {
+	return path.Join(asyncDir(id), strconv.Itoa(page)+".ion")
+}
+
+func asyncStatusFile(id string) string {
+	return path.Join(asyncDir(id), "status.json")
+}
+
+// snapshot returns a copy of the query status
+func (q *asyncQuery) snapshot() asyncStatus {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.status
+}
+
+// abort cancels the query if it is still running
+func (q *asyncQuery) abort() {
+	q.lock.Lock()
+	running := q.status.State == asyncRunning
+	if running {
+		q.canceled = true
+	}
+	q.lock.Unlock()
+	if running {
+		q.cancel()
+		<-q.done
+	}
+}
+
+// writePage writes one page of results
+func (q *asyncQuery) writePage(st *ion.Symtab, rows *ion.Buffer, count int) error {
+	var page ion.Buffer
+	st.Marshal(&page, true)
+	page.UnsafeAppend(rows.Bytes())
+	q.lock.Lock()
+	n := q.status.Pages
+	q.lock.Unlock()
+	_, err := q.root.WriteFile(asyncPage(q.status.ID, n), page.Bytes())
+	if err != nil {
+		return err
+	}
+	q.lock.Lock()
+	q.status.Pages++
+	q.status.Rows += int64(count)
+	q.lock.Unlock()
+	return nil
+}
+
+// readRows reads a raw ion stream produced by a query
+// and calls fn for each row; the row is only valid
+// until fn returns
+func readRows(src io.Reader, fn func(row ion.Datum) error) error {
+	var st ion.Symtab
+	var buf []byte
+	r := bufio.NewReaderSize(src, 64*1024)
+	for {
+		typ, size, err := ion.Peek(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if typ == ion.NullType && size > 1 {
+			r.Discard(size)
+			continue
+		}
+		if cap(buf) < size {
+			buf = make([]byte, size)
+		}
+		this := buf[:size]
+		_, err = io.ReadFull(r, this)
+		if err != nil {
+			return err
+		}
+		row, _, err := ion.ReadDatum(&st, this)
+		if err != nil {
+			return err
+		}
+		if row.IsEmpty() {
+			// symbol table
+			continue
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// collect reads the raw ion output of the query
+// and writes it to the tenant root in pages
+// of asyncPageRows rows, each page beginning
+// with its own symbol table
+func (q *asyncQuery) collect(src io.Reader) error {
+	var out ion.Symtab
+	var rows ion.Buffer
+	count := 0
+	err := readRows(src, func(row ion.Datum) error {
+		row.Encode(&rows, &out)
+		count++
+		if count == asyncPageRows {
+			if err := q.writePage(&out, &rows, count); err != nil {
+				return err
+			}
+			out.Reset()
+			rows.Reset()
+			count = 0
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// always write at least one page,
+	// even if the query yielded no rows
+	if count > 0 || q.snapshot().Pages == 0 {
+		return q.writePage(&out, &rows, count)
+	}
+	return nil
+}
+
+// removeResults removes the pages and the status
+// file of the query with the given status
+func removeResults(root fs.FS, status *asyncStatus) error {
+	rfs, ok := root.(db.RemoveFS)
+	if !ok {
+		return fmt.Errorf("cannot remove results from %T", root)
+	}
+	var err error
+	for i := 0; i < status.Pages; i++ {
+		if rerr := rfs.Remove(asyncPage(status.ID, i)); rerr != nil && !errors.Is(rerr, fs.ErrNotExist) {
+			err = rerr
+		}
+	}
+	if rerr := rfs.Remove(asyncStatusFile(status.ID)); rerr != nil && !errors.Is(rerr, fs.ErrNotExist) {
+		err = rerr
+	}
+	// only meaningful for file systems
+	// with real directories
+	rfs.Remove(asyncDir(status.ID))
+	return err
+}
+
+// setDeadline sets the read deadline of the connections
+// of an asynchronous query according to a.timeout and
+// returns whether a deadline was set on rc
+func (a *asyncQueries) setDeadline(rc, out io.Reader) bool {
+	if a.timeout <= 0 {
+		return false
+	}
+	setDeadline(out, a.timeout)
+	return setDeadline(rc, a.timeout)
+}
+
+// runAsync waits for an asynchronous query to finish,
+// collecting its results as they are produced
+func (s *server) runAsync(q *asyncQuery, running *runningQuery, id tnproto.ID, key tnproto.Key, rc io.ReadCloser, out net.Conn, release func(scanned int64), audit *auditEntry) {
+	defer close(q.done)
+	defer s.tracer.finish(q.span)
+	defer s.running.remove(running.id)
+	start := time.Now()
+	deadlined := s.async.setDeadline(rc, out)
+	var stats plan.ExecStats
+	defer func() { release(stats.BytesScanned) }()
+	checked := make(chan error, 1)
+	go func() {
+		checked <- tenant.CheckTrace(rc, &stats, func(cur *plan.ExecStats) {
+			running.progress(cur)
+			q.lock.Lock()
+			q.status.Hits = cur.CacheHits
+			q.status.Misses = cur.CacheMisses
+			q.status.Scanned = cur.BytesScanned
+			q.lock.Unlock()
+		}, q.exec.Recorder())
+	}()
+	err := q.collect(out)
+	out.Close()
+	if cerr := <-checked; cerr != nil {
+		if deadlined && isTimeout(cerr) {
+			s.logger.Printf("tenant %s query ID %s killing tenant worker %s due to timeout", q.tenantID, q.status.ID, id)
+			s.manager.Quit(id, key)
+		}
+		err = cerr
+	}
+	q.exec.SetAttr("bytes_scanned", stats.BytesScanned)
+	q.exec.Fail(err)
+	q.exec.End()
+
+	now := time.Now()
+	q.lock.Lock()
+	q.status.Finished = &now
+	q.status.Hits = stats.CacheHits
+	q.status.Misses = stats.CacheMisses
+	q.status.Scanned = stats.BytesScanned
+	switch {
+	case running.timedOut.Load():
+		q.status.State = asyncFailed
+		q.status.Error = "query exceeded the maximum query duration"
+	case q.canceled:
+		q.status.State = asyncCanceled
+	case err != nil:
+		q.status.State = asyncFailed
+		q.status.Error = err.Error()
+	default:
+		q.status.State = asyncSucceeded
+	}
+	status := q.status
+	q.lock.Unlock()
+
+	outcome := outcomeOK
+	switch status.State {
+	case asyncCanceled:
+		outcome = outcomeCanceled
+	case asyncFailed:
+		outcome = outcomeError
+	}
+	s.metrics.observe(q.tenantID, outcome, time.Since(start), &stats)
+	audit.Status = outcome
+	audit.Scanned = stats.BytesScanned
+	s.audit.end(audit)
+
+	switch status.State {
+	case asyncCanceled:
+		s.logger.Printf("tenant %s query ID %s canceled after %s", q.tenantID, status.ID, time.Since(start))
+		if err := removeResults(q.root, &status); err != nil {
+			s.logger.Printf("tenant %s query ID %s removing results: %s", q.tenantID, status.ID, err)
+		}
+		return
+	case asyncFailed:
+		s.logger.Printf("tenant %s query ID %s execution failed: %s", q.tenantID, status.ID, err)
+	default:
+		s.logger.Printf("tenant %s query ID %s duration %s rows %d pages %d bytes %d hits %d misses %d",
+			q.tenantID, status.ID, time.Since(start), status.Rows, status.Pages,
+			stats.BytesScanned, stats.CacheHits, stats.CacheMisses)
+	}
+	buf, err := json.Marshal(&status)
+	if err == nil {
+		_, err = q.root.WriteFile(asyncStatusFile(status.ID), buf)
+	}
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s writing status: %s", q.tenantID, status.ID, err)
+	}
+}
+
+// example invocation:
+// curl -v -X POST -H 'Authorization: Bearer token' --data-raw 'SELECT * FROM nation' 'http://localhost:8080/queries?database=sf1-new'
+func (s *server) queriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	creds, err := s.getTenant(ctx, w, r)
+	if err != nil {
+		return
+	}
+	tenantID := creds.ID()
+	token, _ := bearerToken(r)
+	principal := principalOf(creds, token)
+	client, _ := clientAddress(r)
+	audit := s.audit.begin(creds, "async", principal, client)
+	audit.Status = auditInvalid
+	defer func() {
+		// once the query is running, the
+		// entry is completed by runAsync
+		if audit != nil {
+			s.audit.end(audit)
+		}
+	}()
+	ctx, span := s.traceQuery(ctx, w, r, tenantID, "async")
+	defer func() { s.tracer.finish(span) }()
+
+	// restrict the size of the query text to something reasonable
+	body := http.MaxBytesReader(w, r.Body, 128*1024*1024)
+	query, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, "cannot read query", http.StatusBadRequest)
+		return
+	}
+	if len(query) == 0 {
+		http.Error(w, "no query", http.StatusBadRequest)
+		return
+	}
+	defaultDatabase := r.URL.Query().Get("database")
+	parsedQuery, err := partiql.Parse(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = parsedQuery.Check()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// the status and the list of running queries
+	// show the query as submitted, since the text
+	// rewritten by the access policy contains the
+	// row filters and masks of the policy
+	submitted := parsedQuery.Text()
+	audit.Query = parsedQuery.Redacted()
+	audit.Database = defaultDatabase
+	acc, ok := s.accessOf(w, r, creds)
+	if !ok {
+		return
+	}
+	if err := acc.rewrite(parsedQuery, defaultDatabase); err != nil {
+		audit.Status = auditDenied
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	audit.Status = outcomeError
+
+	root, err := creds.Root()
+	if err != nil {
+		http.Error(w, "tenant ID disallowed", http.StatusForbidden)
+		s.logger.Printf("refusing query: %s", err)
+		return
+	}
+	out, ok := root.(db.OutputFS)
+	if !ok {
+		http.Error(w, "tenant storage does not support storing query results", http.StatusNotImplemented)
+		return
+	}
+
+	id, key := tenantProcess(creds)
+	planEnv, err := sneller.Environ(creds, defaultDatabase)
+	if err != nil {
+		http.Error(w, "tenant ID disallowed", http.StatusForbidden)
+		s.logger.Printf("refusing query: %s", err)
+		return
+	}
+	queryID := uuid.New().String()
+	w.Header().Add("X-Sneller-Query-ID", queryID)
+	audit.QueryID = queryID
+	span.SetAttr("query_id", queryID)
+	endPoints := s.peers.Get()
+	tree, err := s.planQuery(ctx, parsedQuery, planEnv, id, key, endPoints, queryID)
+	audit.setTables(planEnv)
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s planning failed: %s", tenantID, queryID, err)
+		planError(w, err)
+		return
+	}
+	willScan := uint64(tree.MaxScanned())
+	w.Header().Set("X-Sneller-Max-Scanned-Bytes", utoa(willScan))
+	if maxScan := maxScanBytes(creds); maxScan > 0 && willScan > maxScan {
+		audit.Status = outcomeRejected
+		planError(w, &errPlanLimit{scan: willScan, max: maxScan})
+		return
+	}
+	release, err := s.admit(ctx, creds, id, int64(willScan))
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s not admitted: %s", tenantID, queryID, err)
+		s.metrics.reject(tenantID)
+		audit.Status = outcomeRejected
+		quotaError(w, err)
+		return
+	}
+
+	here, there, err := usock.SocketPair()
+	if err != nil {
+		release(0)
+		s.logger.Printf("tenant %s query ID %s socketpair: %s", tenantID, queryID, err)
+		http.Error(w, "cannot start query", http.StatusInternalServerError)
+		return
+	}
+	exec := execSpan(ctx, tree)
+	startrun := time.Now()
+	rc, err := s.manager.Do(id, key, tree, tnproto.OutputRaw, there)
+	there.Close()
+	if err != nil {
+		exec.Fail(err)
+		exec.End()
+		release(0)
+		here.Close()
+		s.logger.Printf("tenant %s query ID %s execution failed (do): %v", tenantID, queryID, err)
+		s.metrics.observe(tenantID, outcomeError, time.Since(startrun), nil)
+		if errors.Is(err, tenant.ErrOverloaded) {
+			http.Error(w, "too many queries", http.StatusTooManyRequests)
+		} else {
+			http.Error(w, "error dispatching query", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	q := &asyncQuery{
+		tenantID: tenantID,
+		root:     out,
+		done:     make(chan struct{}),
+		status: asyncStatus{
+			ID:         queryID,
+			State:      asyncRunning,
+			Principal:  principal,
+			Query:      submitted,
+			Database:   defaultDatabase,
+			Created:    time.Now().UTC(),
+			MaxScanned: int64(willScan),
+		},
+		cancel: func() {
+			s.manager.Cancel(queryID)
+			here.Close()
+		},
+		span: span,
+		exec: exec,
+	}
+	span = nil
+	s.async.add(q)
+	running := &runningQuery{
+		tenantID:  tenantID,
+		principal: principal,
+		id:        queryID,
+		query:     audit.Query,
+		database:  defaultDatabase,
+		started:   time.Now(),
+		peers:     peerNames(endPoints),
+		async:     true,
+		cancel:    q.abort,
+	}
+	s.running.add(running)
+	stop := limitDuration(running, tenantConfig(creds))
+	entry := audit
+	audit = nil
+	go func() {
+		defer stop()
+		s.runAsync(q, running, id, key, rc, here, release, entry)
+	}()
+
+	w.Header().Set("Location", "/queries/"+queryID)
+	writeResultResponse(w, http.StatusAccepted, q.snapshot())
+}
+
+// lookupAsync returns the in-memory query with the given ID
+// (if there is one) along with the status of the query;
+// the queries of other principals are reported as
+// not existing
+func (s *server) lookupAsync(creds db.Tenant, principal, id string) (*asyncQuery, *asyncStatus, error) {
+	if q := s.async.get(creds.ID(), principal, id); q != nil {
+		status := q.snapshot()
+		return q, &status, nil
+	}
+	root, err := creds.Root()
+	if err != nil {
+		return nil, nil, err
+	}
+	buf, err := fs.ReadFile(root, asyncStatusFile(id))
+	if err != nil {
+		return nil, nil, err
+	}
+	status := new(asyncStatus)
+	if err := json.Unmarshal(buf, status); err != nil {
+		return nil, nil, err
+	}
+	if status.Principal != principal {
+		return nil, nil, fs.ErrNotExist
+	}
+	return nil, status, nil
+}
+
+// example invocations:
+// curl -v -H 'Authorization: Bearer token' 'http://localhost:8080/queries/<id>'
+// curl -v -H 'Authorization: Bearer token' -H 'Accept: application/json' 'http://localhost:8080/queries/<id>/results?page=0'
+// curl -v -X DELETE -H 'Authorization: Bearer token' 'http://localhost:8080/queries/<id>'
+func (s *server) queryIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	creds, err := s.getTenant(ctx, w, r)
+	if err != nil {
+		return
+	}
+	token, _ := bearerToken(r)
+	principal := principalOf(creds, token)
+
+	id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/queries/"), "/")
+	if id == "running" && rest == "" {
+		s.runningHandler(w, r, creds, principal)
+		return
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	switch rest {
+	case "cancel":
+		s.cancelHandler(w, r, creds, principal, id)
+		return
+	case "", "results":
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	results := rest == "results"
+	if r.Method == http.MethodPost || (results && r.Method != http.MethodGet) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q, status, err := s.lookupAsync(creds, principal, id)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, "no such query", http.StatusNotFound)
+			return
+		}
+		s.logger.Printf("tenant %s query ID %s reading status: %s", creds.ID(), id, err)
+		writeInternalServerResponse(w, err)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodDelete:
+		s.deleteAsync(w, creds, q, status)
+	case results:
+		s.asyncResults(w, r, creds, status)
+	default:
+		writeResultResponse(w, http.StatusOK, status)
+	}
+}
+
+func (s *server) deleteAsync(w http.ResponseWriter, creds db.Tenant, q *asyncQuery, status *asyncStatus) {
+	if q != nil {
+		q.abort()
+		*status = q.snapshot()
+		s.async.remove(status.ID)
+	}
+	if status.State != asyncCanceled {
+		// canceled queries have had
+		// their results removed already
+		root, err := creds.Root()
+		if err == nil {
+			err = removeResults(root, status)
+		}
+		if err != nil {
+			s.logger.Printf("tenant %s query ID %s removing results: %s", creds.ID(), status.ID, err)
+			writeInternalServerResponse(w, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) asyncResults(w http.ResponseWriter, r *http.Request, creds db.Tenant, status *asyncStatus) {
+	page := 0
+	if str := r.URL.Query().Get("page"); str != "" {
+		n, err := strconv.Atoi(str)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid page", http.StatusBadRequest)
+			return
+		}
+		page = n
+	}
+
+	var sep byte
+	contentType := r.Header.Get("Accept")
+	switch contentType {
+	case "application/x-ndjson", "application/x-jsonlines":
+		sep = '\n'
+	case "application/json":
+		sep = ','
+	case "application/ion":
+	case "", "*/*":
+		if r.URL.Query().Has("json") {
+			sep = '\n'
+			contentType = "application/x-ndjson"
+		} else {
+			contentType = "application/ion"
+		}
+	default:
+		http.Error(w, "invalid 'Accept' header", http.StatusBadRequest)
+		return
+	}
+
+	switch status.State {
+	case asyncFailed:
+		http.Error(w, "query failed: "+status.Error, http.StatusConflict)
+		return
+	case asyncCanceled:
+		http.Error(w, "query canceled", http.StatusConflict)
+		return
+	}
+	if page >= status.Pages {
+		if status.State == asyncRunning {
+			http.Error(w, "page not yet available", http.StatusConflict)
+		} else {
+			http.Error(w, "no such page", http.StatusNotFound)
+		}
+		return
+	}
+
+	root, err := creds.Root()
+	if err != nil {
+		writeInternalServerResponse(w, err)
+		return
+	}
+	buf, err := fs.ReadFile(root, asyncPage(status.ID, page))
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s reading page %d: %s", creds.ID(), status.ID, page, err)
+		writeInternalServerResponse(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Sneller-Query-Pages", strconv.Itoa(status.Pages))
+	if sep == 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	jw := ion.NewJSONWriter(w, sep)
+	if _, err := jw.Write(buf); err != nil {
+		s.logger.Printf("tenant %s query ID %s translating page %d: %s", creds.ID(), status.ID, page, err)
+		return
+	}
+	jw.Close()
+}
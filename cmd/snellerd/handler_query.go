@@ -15,6 +15,7 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -37,6 +38,8 @@ import (
 	"github.com/SnellerInc/sneller/plan/pir"
 	"github.com/SnellerInc/sneller/tenant"
 	"github.com/SnellerInc/sneller/tenant/tnproto"
+	"github.com/SnellerInc/sneller/tracing"
+	"github.com/SnellerInc/sneller/usock"
 	"github.com/google/uuid"
 )
 
@@ -98,6 +101,14 @@ func (s *server) queryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	authElapsed := time.Since(start)
 	tenantID := creds.ID()
+	token, _ := bearerToken(r)
+	principal := principalOf(creds, token)
+	client, _ := clientAddress(r)
+	audit := s.audit.begin(creds, "http", principal, client)
+	audit.Status = auditInvalid
+	defer s.audit.end(audit)
+	ctx, span := s.traceQuery(ctx, w, r, tenantID, "http")
+	defer s.tracer.finish(span)
 
 	isHeadRequest := r.Method == http.MethodHead
 
@@ -167,25 +178,25 @@ func (s *server) queryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	audit.Query = parsedQuery.Redacted()
+	audit.Database = defaultDatabase
+
+	acc, ok := s.accessOf(w, r, creds)
+	if !ok {
+		return
+	}
+	if err := acc.rewrite(parsedQuery, defaultDatabase); err != nil {
+		audit.Status = auditDenied
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 
 	normalized := parsedQuery.Text()
 	redacted := parsedQuery.Text()
+	audit.Status = outcomeError
 
-	var id tnproto.ID
-	var key tnproto.Key
-	hash := sha256.Sum256([]byte(tenantID))
-	copy(id[:], hash[:])
-	hash = sha256.Sum256([]byte(tenantID + string(creds.Key()[:])))
-	copy(key[:], hash[:])
-
-	// determine scan limit
-	maxScan := uint64(DefaultMaxScan)
-	if ct, ok := creds.(db.TenantConfigurable); ok {
-		cfg := ct.Config()
-		if cfg != nil && cfg.MaxScanBytes > 0 {
-			maxScan = cfg.MaxScanBytes
-		}
-	}
+	id, key := tenantProcess(creds)
+	maxScan := maxScanBytes(creds)
 
 	planEnv, err := sneller.Environ(creds, defaultDatabase)
 	if err != nil {
@@ -193,50 +204,34 @@ func (s *server) queryHandler(w http.ResponseWriter, r *http.Request) {
 		s.logger.Printf("refusing query: %s", err)
 		return
 	}
+
 	endPoints := s.peers.Get()
 
 	queryID := uuid.New().String()
 	w.Header().Add("X-Sneller-Query-ID", queryID)
+	audit.QueryID = queryID
+	span.SetAttr("query_id", queryID)
 
-	var tree *plan.Tree
 	start = time.Now()
-	if len(endPoints) == 0 {
-		tree, err = plan.New(parsedQuery, planEnv)
-	} else {
-		splitter := s.newSplitter(id, key, endPoints)
-		tree, err = plan.NewSplit(parsedQuery, struct {
-			*sneller.FSEnv
-			*sneller.Splitter
-		}{planEnv, splitter})
-	}
+	tree, err := s.planQuery(ctx, parsedQuery, planEnv, id, key, endPoints, queryID)
+	audit.setTables(planEnv)
 	if err != nil {
 		s.logger.Printf("tenant %s query ID %s planning failed: %s", tenantID, queryID, err)
 		planError(w, err)
 		return
 	}
-	tree.ID = queryID
-	// TODO: clean this up
-	if enc, ok := planEnv.Root.(interface {
-		Encode(*ion.Buffer, *ion.Symtab) error
-	}); ok {
-		var buf ion.Buffer
-		var st ion.Symtab
-		if err := enc.Encode(&buf, &st); err != nil {
-			s.logger.Printf("tenant %s query ID %s encoding file system: %s", tenantID, queryID, err)
-			planError(w, err)
-			return
-		}
-		tree.Data, _, _ = ion.ReadDatum(&st, buf.Bytes())
-	}
 	willScan := uint64(tree.MaxScanned())
 	w.Header().Set("X-Sneller-Max-Scanned-Bytes", utoa(willScan))
 	if maxScan > 0 && willScan > maxScan {
+		audit.Status = outcomeRejected
 		planError(w, &errPlanLimit{scan: willScan, max: maxScan})
 		return
 	}
 	s.logger.Printf("tenant %s query ID %s auth %s planning %s", tenantID, queryID, authElapsed, time.Since(start))
 
 	planHash, newestBlobTime := planEnv.CacheValues()
+	indexes := planEnv.Indexes()
+	s.cache.observe(tenantID, indexes)
 
 	// hash the tenant/query/plan/format to an eTag
 	hasher := sha256.New()
@@ -261,6 +256,7 @@ func (s *server) queryHandler(w http.ResponseWriter, r *http.Request) {
 		for _, matchEtag := range strings.Split(ifNoneMatch, ",") {
 			matchEtag = strings.TrimSpace(matchEtag)
 			if eTag == matchEtag {
+				audit.Status = auditNotModified
 				w.WriteHeader(skipped)
 				return
 			}
@@ -276,6 +272,7 @@ func (s *server) queryHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if !newestBlobTime.After(ifModifiedSinceTime) {
+			audit.Status = auditNotModified
 			w.WriteHeader(skipped)
 			return
 		}
@@ -283,6 +280,7 @@ func (s *server) queryHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Add("Content-Type", acceptHeader)
 	if isHeadRequest {
+		audit.Status = auditDryRun
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -296,9 +294,61 @@ func (s *server) queryHandler(w http.ResponseWriter, r *http.Request) {
 		req:   r,
 		res:   w,
 	}
+	cacheable := s.cache.enabled()
+	if cacheable {
+		if !noCache(r) {
+			if body, ok := s.cache.get(tenantID, eTag); ok {
+				w.Header().Set("X-Sneller-Cache", "hit")
+				audit.Status = auditCached
+				s.serveCached(w, conn, body, tree, encodingFormat, statsOptIn, sendTrailer, tenantID, queryID)
+				return
+			}
+		}
+		w.Header().Set("X-Sneller-Cache", "miss")
+	}
+
+	var stats plan.ExecStats
+	release, err := s.admit(ctx, creds, id, int64(willScan))
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s not admitted: %s", tenantID, queryID, err)
+		s.metrics.reject(tenantID)
+		audit.Status = outcomeRejected
+		w.Header().Del("Trailer")
+		quotaError(w, err)
+		return
+	}
+	defer func() {
+		release(stats.BytesScanned)
+		audit.Scanned = stats.BytesScanned
+	}()
+
+	// if the result may be cached, then the
+	// tenant writes it to a socket from which
+	// it is copied to the client, rather than
+	// writing it to the client directly
+	var dst net.Conn = conn
+	var here net.Conn
+	if cacheable {
+		var there net.Conn
+		here, there, err = usock.SocketPair()
+		if err != nil {
+			s.logger.Printf("tenant %s query ID %s socketpair: %s", tenantID, queryID, err)
+			w.Header().Del("Trailer")
+			http.Error(w, "error dispatching query", http.StatusInternalServerError)
+			return
+		}
+		defer here.Close()
+		dst = there
+	}
+	exec := execSpan(ctx, tree)
+	defer exec.End()
 	startrun := time.Now()
-	rc, err := s.manager.Do(id, key, tree, encodingFormat, conn)
+	rc, err := s.manager.Do(id, key, tree, encodingFormat, dst)
+	if here != nil {
+		dst.Close()
+	}
 	if err != nil {
+		exec.Fail(err)
 		if !conn.hijacked {
 			// didn't call w.WriteHeader() yet;
 			// we can write a plaintext error
@@ -318,18 +368,59 @@ func (s *server) queryHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		s.logger.Printf("tenant %s query ID %s %q execution failed (do): %v", tenantID, queryID, redacted, err)
+		s.metrics.observe(tenantID, outcomeError, time.Since(startrun), nil)
 		return
 	}
 	go func() {
 		<-r.Context().Done()
 		rc.Close()
 	}()
+	running := &runningQuery{
+		tenantID:  tenantID,
+		principal: principal,
+		id:        queryID,
+		query:     audit.Query,
+		database:  defaultDatabase,
+		started:   startrun,
+		peers:     peerNames(endPoints),
+		cancel:    func() { s.manager.Cancel(queryID) },
+	}
+	s.running.add(running)
+	defer s.running.remove(queryID)
+	defer limitDuration(running, tenantConfig(creds))()
 	s.logger.Printf("tenant %s query ID %s plan transfer took %s", tenantID, queryID, time.Since(startrun))
-	var stats plan.ExecStats
+	type teed struct {
+		body []byte
+		ok   bool
+	}
+	var copied chan teed
+	if here != nil {
+		raw, err := conn.hijack()
+		if err != nil {
+			s.logger.Printf("tenant %s query ID %s hijacking connection: %s", tenantID, queryID, err)
+			here.Close()
+		} else {
+			copied = make(chan teed, 1)
+			setDeadline(here, queryKillTimeout)
+			go func() {
+				body, ok := s.teeResult(raw, here)
+				copied <- teed{body, ok}
+			}()
+		}
+	}
 	deadlined := setDeadline(rc, queryKillTimeout)
-	err = tenant.Check(rc, &stats)
+	err = tenant.CheckTrace(rc, &stats, running.progress, exec.Recorder())
+	exec.SetAttr("bytes_scanned", stats.BytesScanned)
+	exec.Fail(err)
+	var result teed
+	if copied != nil {
+		if err != nil {
+			here.Close()
+		}
+		result = <-copied
+	}
 	if err != nil {
-		canceled := false
+		canceled := running.canceled.Load()
 		if ctxerr := r.Context().Err(); ctxerr != nil {
 			// see if we got an error due to cancellation
 			err = ctxerr
@@ -338,11 +429,22 @@ func (s *server) queryHandler(w http.ResponseWriter, r *http.Request) {
 		if sendTrailer {
 			setError(w)
 		}
+		if running.timedOut.Load() {
+			s.logger.Printf("tenant %s query ID %s exceeded the maximum query duration after %s", tenantID, queryID, time.Since(startrun))
+			s.metrics.observe(tenantID, outcomeError, time.Since(startrun), &stats)
+			if encodingFormat == tnproto.OutputChunkedIon {
+				writeError(w, "query exceeded the maximum query duration")
+			}
+			return
+		}
 		if canceled {
 			s.logger.Printf("tenant %s query ID %s canceled after %s", tenantID, queryID, time.Since(startrun))
+			s.metrics.observe(tenantID, outcomeCanceled, time.Since(startrun), &stats)
+			audit.Status = outcomeCanceled
 			return
 		}
 		s.logger.Printf("tenant %s query ID %s %q execution failed (check): %v", tenantID, queryID, redacted, err)
+		s.metrics.observe(tenantID, outcomeError, time.Since(startrun), &stats)
 		if deadlined && isTimeout(err) {
 			s.logger.Printf("tenant %s query ID %s killing tenant worker %s due to timeout", tenantID, queryID, id)
 			s.manager.Quit(id, key)
@@ -350,19 +452,154 @@ func (s *server) queryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	elapsed := time.Since(startrun)
+	s.metrics.observe(tenantID, outcomeOK, elapsed, &stats)
+	audit.Status = outcomeOK
+	if result.ok {
+		s.cache.add(tenantID, eTag, indexes, result.body)
+	}
 	if sendTrailer {
 		setTiming(w, elapsed, &stats)
 	}
-	switch encodingFormat {
+	writeStatus(w, encodingFormat, statsOptIn, &stats, tree)
+	s.logger.Printf("tenant %s query ID %s duration %s bytes %d hits %d misses %d",
+		tenantID, queryID, elapsed, stats.BytesScanned, stats.CacheHits, stats.CacheMisses)
+}
+
+// noCache returns whether the client asked for
+// the response not to be served from a cache
+func noCache(r *http.Request) bool {
+	for _, v := range r.Header.Values("Cache-Control") {
+		for _, dir := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(dir), "no-cache") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serveCached writes a response from the result cache
+func (s *server) serveCached(w http.ResponseWriter, conn *delayedHijack, body []byte, tree *plan.Tree, format tnproto.OutputFormat, statsOptIn, sendTrailer bool, tenantID, queryID string) {
+	start := time.Now()
+	raw, err := conn.hijack()
+	if err == nil {
+		_, err = raw.Write(body)
+	}
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s writing cached result: %s", tenantID, queryID, err)
+		return
+	}
+	// nothing was scanned
+	var stats plan.ExecStats
+	elapsed := time.Since(start)
+	s.metrics.observe(tenantID, outcomeOK, elapsed, &stats)
+	if sendTrailer {
+		setTiming(w, elapsed, &stats)
+	}
+	writeStatus(w, format, statsOptIn, &stats, tree)
+	s.logger.Printf("tenant %s query ID %s served from result cache (%d bytes)", tenantID, queryID, len(body))
+}
+
+// teeResult copies the response body written
+// by the tenant from src to dst, and returns
+// a copy of it if it is small enough to be cached
+func (s *server) teeResult(dst io.Writer, src io.Reader) ([]byte, bool) {
+	var body []byte
+	keep := true
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return nil, false
+			}
+			if keep && s.cache.fits(int64(len(body)+n)) {
+				body = append(body, buf[:n]...)
+			} else {
+				keep = false
+				body = nil
+			}
+		}
+		if err == io.EOF {
+			return body, keep
+		}
+		if err != nil {
+			return nil, false
+		}
+	}
+}
+
+// writeStatus writes the final status
+// of a query, if the format has one
+func writeStatus(w http.ResponseWriter, format tnproto.OutputFormat, statsOptIn bool, stats *plan.ExecStats, tree *plan.Tree) {
+	switch format {
 	case tnproto.OutputChunkedIon:
-		writeStatusIon(w, &stats, tree.Results, tree.ResultTypes)
+		writeStatusIon(w, stats, tree.Results, tree.ResultTypes)
 	case tnproto.OutputChunkedJSON:
 		if statsOptIn {
-			writeStatusJSON(w, &stats, tree.Results, tree.ResultTypes)
+			writeStatusJSON(w, stats, tree.Results, tree.ResultTypes)
 		}
 	}
-	s.logger.Printf("tenant %s query ID %s duration %s bytes %d hits %d misses %d",
-		tenantID, queryID, elapsed, stats.BytesScanned, stats.CacheHits, stats.CacheMisses)
+}
+
+// tenantProcess returns the ID and key
+// of the tenant process that runs queries
+// on behalf of the given tenant
+func tenantProcess(creds db.Tenant) (tnproto.ID, tnproto.Key) {
+	var id tnproto.ID
+	var key tnproto.Key
+	tenantID := creds.ID()
+	hash := sha256.Sum256([]byte(tenantID))
+	copy(id[:], hash[:])
+	hash = sha256.Sum256([]byte(tenantID + string(creds.Key()[:])))
+	copy(key[:], hash[:])
+	return id, key
+}
+
+// maxScanBytes returns the scan limit of
+// the given tenant, or zero if it has none
+func maxScanBytes(creds db.Tenant) uint64 {
+	maxScan := uint64(DefaultMaxScan)
+	if cfg := tenantConfig(creds); cfg != nil && cfg.MaxScanBytes > 0 {
+		maxScan = cfg.MaxScanBytes
+	}
+	return maxScan
+}
+
+// planQuery builds the plan for a query,
+// splitting it across the given peers
+// if there are any
+func (s *server) planQuery(ctx context.Context, q *expr.Query, env *sneller.FSEnv, id tnproto.ID, key tnproto.Key, endPoints []*net.TCPAddr, queryID string) (tree *plan.Tree, err error) {
+	_, span := tracing.Start(ctx, "plan")
+	defer func() {
+		span.Fail(err)
+		span.End()
+	}()
+	if len(endPoints) == 0 {
+		tree, err = plan.New(q, env)
+	} else {
+		splitter := s.newSplitter(id, key, endPoints)
+		tree, err = plan.NewSplit(q, struct {
+			*sneller.FSEnv
+			*sneller.Splitter
+		}{env, splitter})
+	}
+	if err != nil {
+		return nil, err
+	}
+	tree.ID = queryID
+	// TODO: clean this up
+	if enc, ok := env.Root.(interface {
+		Encode(*ion.Buffer, *ion.Symtab) error
+	}); ok {
+		var buf ion.Buffer
+		var st ion.Symtab
+		if err := enc.Encode(&buf, &st); err != nil {
+			return nil, fmt.Errorf("encoding file system: %w", err)
+		}
+		tree.Data, _, _ = ion.ReadDatum(&st, buf.Bytes())
+	}
+	return tree, nil
 }
 
 // satisfied by net.Conn and friends
@@ -0,0 +1,181 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/plan"
+)
+
+// runningQuery is a query that is
+// currently being executed on behalf
+// of a tenant by this server
+type runningQuery struct {
+	tenantID string
+	// principal is the principal (see principalOf)
+	// that submitted the query
+	principal string
+	id        string
+	query     string
+	database  string
+	started   time.Time
+	peers     []string
+	async     bool
+
+	// cancel cancels the query
+	cancel   func()
+	canceled atomic.Bool
+	// timedOut is set if the query was canceled
+	// because it exceeded the maximum duration
+	timedOut atomic.Bool
+	scanned  atomic.Int64
+}
+
+// progress records the statistics
+// reported by the tenant while the
+// query is running
+func (r *runningQuery) progress(stats *plan.ExecStats) {
+	r.scanned.Store(stats.BytesScanned)
+}
+
+// runningStatus is the description of a
+// running query returned by GET /queries/running
+type runningStatus struct {
+	ID       string    `json:"id"`
+	Query    string    `json:"query"`
+	Database string    `json:"database,omitempty"`
+	Started  time.Time `json:"started"`
+	Scanned  int64     `json:"scanned"`
+	Peers    []string  `json:"peers,omitempty"`
+	Async    bool      `json:"async"`
+}
+
+// runningQueries is the set of queries
+// currently being executed by a server
+type runningQueries struct {
+	lock    sync.Mutex
+	queries map[string]*runningQuery
+}
+
+func (r *runningQueries) add(q *runningQuery) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.queries == nil {
+		r.queries = make(map[string]*runningQuery)
+	}
+	r.queries[q.id] = q
+}
+
+func (r *runningQueries) remove(id string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.queries, id)
+}
+
+// get returns the query with the given ID
+// if it was submitted by principal of tenantID
+func (r *runningQueries) get(tenantID, principal, id string) *runningQuery {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	q := r.queries[id]
+	if q == nil || q.tenantID != tenantID || q.principal != principal {
+		return nil
+	}
+	return q
+}
+
+// list returns the queries running on behalf
+// of principal of a tenant, oldest first
+func (r *runningQueries) list(tenantID, principal string) []runningStatus {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	out := make([]runningStatus, 0)
+	for _, q := range r.queries {
+		if q.tenantID != tenantID || q.principal != principal {
+			continue
+		}
+		out = append(out, runningStatus{
+			ID:       q.id,
+			Query:    q.query,
+			Database: q.database,
+			Started:  q.started,
+			Scanned:  q.scanned.Load(),
+			Peers:    q.peers,
+			Async:    q.async,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Started.Before(out[j].Started)
+	})
+	return out
+}
+
+// count returns the number of
+// running queries of each tenant
+func (r *runningQueries) count() map[string]int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	out := make(map[string]int)
+	for _, q := range r.queries {
+		out[q.tenantID]++
+	}
+	return out
+}
+
+func peerNames(peers []*net.TCPAddr) []string {
+	if len(peers) == 0 {
+		return nil
+	}
+	out := make([]string, len(peers))
+	for i := range peers {
+		out[i] = peers[i].String()
+	}
+	return out
+}
+
+// example invocation:
+// curl -v -H 'Authorization: Bearer token' 'http://localhost:8080/queries/running'
+func (s *server) runningHandler(w http.ResponseWriter, r *http.Request, creds db.Tenant, principal string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeResultResponse(w, http.StatusOK, s.running.list(creds.ID(), principal))
+}
+
+// example invocation:
+// curl -v -X POST -H 'Authorization: Bearer token' 'http://localhost:8080/queries/<id>/cancel'
+func (s *server) cancelHandler(w http.ResponseWriter, r *http.Request, creds db.Tenant, principal, id string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	q := s.running.get(creds.ID(), principal, id)
+	if q == nil {
+		http.Error(w, "no such running query", http.StatusNotFound)
+		return
+	}
+	s.logger.Printf("tenant %s query ID %s canceling", creds.ID(), id)
+	q.canceled.Store(true)
+	q.cancel()
+	w.WriteHeader(http.StatusNoContent)
+}
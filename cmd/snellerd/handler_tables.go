@@ -38,6 +38,11 @@ func (s *server) tablesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	acc, ok := s.accessOf(w, r, tenant)
+	if !ok {
+		return
+	}
+
 	pattern := r.URL.Query().Get("pattern")
 	e, err := sneller.Environ(tenant, databaseName)
 	if err != nil {
@@ -62,7 +67,7 @@ func (s *server) tablesHandler(w http.ResponseWriter, r *http.Request) {
 
 	out := make([]string, 0)
 	for i := range tables {
-		if pattern == "" || matchPattern(tables[i], pattern) {
+		if (pattern == "" || matchPattern(tables[i], pattern)) && acc.allowed(databaseName, tables[i]) {
 			out = append(out, tables[i])
 		}
 	}
@@ -30,14 +30,7 @@ import (
 func (s *server) handle(handler func(http.ResponseWriter, *http.Request), methods ...string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
-		// obtain the real address
-		remoteAddress := r.RemoteAddr
-		forwarded := false
-		if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
-			parts := strings.Split(forwardedFor, ",")
-			remoteAddress = strings.TrimSpace(parts[len(parts)-1])
-			forwarded = true
-		}
+		remoteAddress, forwarded := clientAddress(r)
 		// unforwarded requests to "/"
 		// are just ELB heartbeats;
 		// don't log these, as they spam the logs
@@ -50,7 +43,7 @@ func (s *server) handle(handler func(http.ResponseWriter, *http.Request), method
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
 		w.Header().Set("Access-Control-Expose-Headers", "Etag, X-Sneller-Max-Scanned-Bytes, X-Sneller-Query-ID, X-Sneller-Total-Table-Bytes, X-Sneller-Version")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
@@ -66,6 +59,27 @@ func (s *server) handle(handler func(http.ResponseWriter, *http.Request), method
 	}
 }
 
+// clientAddress returns the real address of the
+// client of a request, and whether the request
+// was forwarded by a proxy
+func clientAddress(r *http.Request) (string, bool) {
+	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+		parts := strings.Split(forwardedFor, ",")
+		return strings.TrimSpace(parts[len(parts)-1]), true
+	}
+	return r.RemoteAddr, false
+}
+
+// bearerToken returns the bearer token
+// in the Authorization header of a request
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if parts[0] != "Bearer" || len(parts) != 2 {
+		return "", false
+	}
+	return parts[1], true
+}
+
 func (s *server) getTenant(ctx context.Context, w http.ResponseWriter, r *http.Request) (db.Tenant, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
@@ -74,15 +88,15 @@ func (s *server) getTenant(ctx context.Context, w http.ResponseWriter, r *http.R
 	}
 
 	// Check if it's a bearer token
-	parts := strings.SplitN(authHeader, " ", 2)
-	if parts[0] != "Bearer" || len(parts) != 2 {
+	token, ok := bearerToken(r)
+	if !ok {
 		err := errors.New("invalid authorization header format")
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(err.Error())) // TODO: we might want to remove this in production
 		return nil, err
 	}
 
-	creds, err := s.auth.Authorize(ctx, parts[1])
+	creds, err := s.auth.Authorize(ctx, token)
 	if err != nil {
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(err.Error())) // TODO: we might want to remove this in production
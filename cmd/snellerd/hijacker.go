@@ -35,6 +35,22 @@ type sysconn interface {
 }
 
 func (d *delayedHijack) SyscallConn() (syscall.RawConn, error) {
+	conn, err := d.hijack()
+	if err != nil {
+		return nil, err
+	}
+	sc, ok := conn.(sysconn)
+	if !ok {
+		return nil, fmt.Errorf("can't use %T as sysconn", conn)
+	}
+	return sc.SyscallConn()
+}
+
+// hijack writes the response header and returns the
+// connection underlying the response; the body
+// is written directly to the connection using
+// the HTTP chunked encoding
+func (d *delayedHijack) hijack() (net.Conn, error) {
 	d.hijacked = true
 	d.res.Header().Add("Transfer-Encoding", "chunked")
 	d.res.WriteHeader(http.StatusOK)
@@ -43,11 +59,7 @@ func (d *delayedHijack) SyscallConn() (syscall.RawConn, error) {
 	if !ok {
 		return nil, fmt.Errorf("no rawConn value?")
 	}
-	sc, ok := conn.(sysconn)
-	if !ok {
-		return nil, fmt.Errorf("can't use %T as sysconn", conn)
-	}
-	return sc.SyscallConn()
+	return conn, nil
 }
 
 func (d *delayedHijack) Write(p []byte) (int, error) {
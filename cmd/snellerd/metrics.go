@@ -0,0 +1,254 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller/plan"
+)
+
+// metrics are exposed on /metrics of the
+// listener set with -metrics in the
+// Prometheus text exposition format;
+// the format is simple enough that we
+// write it by hand rather than pulling
+// in the Prometheus client libraries
+
+// latencyBuckets are the upper bounds (in seconds)
+// of the buckets of the query latency histograms
+var latencyBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5,
+	1, 2.5, 5, 10, 30, 60, 300,
+}
+
+// query outcomes
+const (
+	outcomeOK       = "ok"
+	outcomeError    = "error"
+	outcomeCanceled = "canceled"
+	// rejected by admission control
+	outcomeRejected = "rejected"
+)
+
+type histogram struct {
+	counts []int64 // per bucket, not cumulative
+	count  int64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]int64, len(latencyBuckets))
+	}
+	for i := range latencyBuckets {
+		if v <= latencyBuckets[i] {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// tenantMetrics are the metrics
+// collected for each tenant
+type tenantMetrics struct {
+	queries      map[string]int64 // by outcome
+	latency      histogram
+	scanned      int64
+	hits, misses int64
+}
+
+// metrics are the metrics collected
+// by the server for the queries it runs
+type metrics struct {
+	lock    sync.Mutex
+	tenants map[string]*tenantMetrics
+}
+
+// tenant returns the metrics of a tenant;
+// the lock must be held
+func (m *metrics) tenant(tenantID string) *tenantMetrics {
+	if m.tenants == nil {
+		m.tenants = make(map[string]*tenantMetrics)
+	}
+	tm := m.tenants[tenantID]
+	if tm == nil {
+		tm = &tenantMetrics{queries: make(map[string]int64)}
+		m.tenants[tenantID] = tm
+	}
+	return tm
+}
+
+// observe records the outcome of a query
+func (m *metrics) observe(tenantID, outcome string, elapsed time.Duration, stats *plan.ExecStats) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	tm := m.tenant(tenantID)
+	tm.queries[outcome]++
+	tm.latency.observe(elapsed.Seconds())
+	if stats != nil {
+		tm.scanned += stats.BytesScanned
+		tm.hits += stats.CacheHits
+		tm.misses += stats.CacheMisses
+	}
+}
+
+// reject records a query that was
+// rejected by admission control
+func (m *metrics) reject(tenantID string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.tenant(tenantID).queries[outcomeRejected]++
+}
+
+// escapeLabel escapes a label value
+// per the text exposition format
+func escapeLabel(s string) string {
+	if !strings.ContainsAny(s, "\\\"\n") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func header(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (m *metrics) writeTo(w io.Writer, running, queued map[string]int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	tenants := make([]string, 0, len(m.tenants))
+	for id := range m.tenants {
+		tenants = append(tenants, id)
+	}
+	sort.Strings(tenants)
+
+	header(w, "sneller_queries_total", "counter", "Number of queries executed, by tenant and outcome.")
+	for _, id := range tenants {
+		tm := m.tenants[id]
+		for _, outcome := range []string{outcomeOK, outcomeError, outcomeCanceled, outcomeRejected} {
+			fmt.Fprintf(w, "sneller_queries_total{tenant=\"%s\",outcome=\"%s\"} %d\n", escapeLabel(id), outcome, tm.queries[outcome])
+		}
+	}
+	header(w, "sneller_query_duration_seconds", "histogram", "Query execution latency, by tenant.")
+	for _, id := range tenants {
+		h := &m.tenants[id].latency
+		label := escapeLabel(id)
+		cumulative := int64(0)
+		for i := range latencyBuckets {
+			if h.counts != nil {
+				cumulative += h.counts[i]
+			}
+			fmt.Fprintf(w, "sneller_query_duration_seconds_bucket{tenant=\"%s\",le=\"%s\"} %d\n", label, formatFloat(latencyBuckets[i]), cumulative)
+		}
+		fmt.Fprintf(w, "sneller_query_duration_seconds_bucket{tenant=\"%s\",le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(w, "sneller_query_duration_seconds_sum{tenant=\"%s\"} %s\n", label, formatFloat(h.sum))
+		fmt.Fprintf(w, "sneller_query_duration_seconds_count{tenant=\"%s\"} %d\n", label, h.count)
+	}
+	header(w, "sneller_query_scanned_bytes_total", "counter", "Number of bytes scanned by queries, by tenant.")
+	for _, id := range tenants {
+		fmt.Fprintf(w, "sneller_query_scanned_bytes_total{tenant=\"%s\"} %d\n", escapeLabel(id), m.tenants[id].scanned)
+	}
+	header(w, "sneller_query_cache_hits_total", "counter", "Number of cache hits of queries, by tenant.")
+	for _, id := range tenants {
+		fmt.Fprintf(w, "sneller_query_cache_hits_total{tenant=\"%s\"} %d\n", escapeLabel(id), m.tenants[id].hits)
+	}
+	header(w, "sneller_query_cache_misses_total", "counter", "Number of cache misses of queries, by tenant.")
+	for _, id := range tenants {
+		fmt.Fprintf(w, "sneller_query_cache_misses_total{tenant=\"%s\"} %d\n", escapeLabel(id), m.tenants[id].misses)
+	}
+
+	ids := make([]string, 0, len(running))
+	for id := range running {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	header(w, "sneller_queries_running", "gauge", "Number of queries currently running, by tenant.")
+	for _, id := range ids {
+		fmt.Fprintf(w, "sneller_queries_running{tenant=\"%s\"} %d\n", escapeLabel(id), running[id])
+	}
+
+	ids = ids[:0]
+	for id := range queued {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	header(w, "sneller_queries_queued", "gauge", "Number of queries waiting for other queries to finish, by tenant.")
+	for _, id := range ids {
+		fmt.Fprintf(w, "sneller_queries_queued{tenant=\"%s\"} %d\n", escapeLabel(id), queued[id])
+	}
+}
+
+// example invocation:
+// curl -v 'http://localhost:9100/metrics'
+// (with -metrics localhost:9100)
+func (s *server) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	b := bufio.NewWriter(w)
+	defer b.Flush()
+	s.metrics.writeTo(b, s.running.count(), s.quotas.queued())
+	gauge := func(name, help string, v int64) {
+		header(b, name, "gauge", help)
+		fmt.Fprintf(b, "%s %d\n", name, v)
+	}
+	counter := func(name, help string, v int64) {
+		header(b, name, "counter", help)
+		fmt.Fprintf(b, "%s %d\n", name, v)
+	}
+	if s.cache.enabled() {
+		cs := s.cache.stats()
+		gauge("sneller_result_cache_entries", "Number of query results in the result cache.", cs.entries)
+		gauge("sneller_result_cache_bytes", "Bytes used by the query results in the result cache.", cs.size)
+		counter("sneller_result_cache_hits_total", "Number of queries served from the result cache.", cs.hits)
+		counter("sneller_result_cache_misses_total", "Number of queries not found in the result cache.", cs.misses)
+		counter("sneller_result_cache_evictions_total", "Number of results evicted from the result cache to make room.", cs.evictions)
+		counter("sneller_result_cache_invalidations_total", "Number of results removed from the result cache because a table changed.", cs.invalidations)
+	}
+	if s.tracer.enabled() {
+		counter("sneller_traces_dropped_total", "Number of query traces dropped because the exporter was not keeping up.", s.tracer.dropped.Load())
+	}
+	if s.manager == nil {
+		return
+	}
+	ms := s.manager.Stats()
+	gauge("sneller_tenant_processes", "Number of tenant processes currently running.", int64(ms.Live))
+	counter("sneller_tenant_process_launches_total", "Number of tenant processes launched.", ms.Launches)
+	counter("sneller_tenant_process_exits_total", "Number of tenant processes that have exited.", ms.Exits)
+	counter("sneller_tenant_process_idle_kills_total", "Number of tenant processes killed due to inactivity.", ms.IdleKills)
+	counter("sneller_peer_errors_total", "Number of requests from peers that could not be served.", ms.RemoteErrors)
+	gauge("sneller_cache_used_bytes", "Bytes used on the file system holding the cache.", ms.CacheUsed)
+	gauge("sneller_cache_size_bytes", "Size of the file system holding the cache.", ms.CacheSize)
+	counter("sneller_cache_evicted_files_total", "Number of files evicted from the cache.", ms.EvictedFiles)
+	counter("sneller_cache_evicted_bytes_total", "Number of bytes evicted from the cache.", ms.EvictedBytes)
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/plan"
+)
+
+func TestMetrics(t *testing.T) {
+	var m metrics
+	m.observe("a\"b", outcomeOK, 30*time.Millisecond, &plan.ExecStats{BytesScanned: 100, CacheHits: 2})
+	m.observe("a\"b", outcomeOK, 2*time.Second, &plan.ExecStats{BytesScanned: 50, CacheMisses: 1})
+	m.observe("a\"b", outcomeError, time.Hour, nil)
+	m.reject("a\"b")
+
+	var out strings.Builder
+	m.writeTo(&out, map[string]int{"a\"b": 1}, map[string]int{"a\"b": 3})
+	text := out.String()
+	for _, want := range []string{
+		"# TYPE sneller_queries_total counter\n",
+		`sneller_queries_total{tenant="a\"b",outcome="ok"} 2` + "\n",
+		`sneller_queries_total{tenant="a\"b",outcome="error"} 1` + "\n",
+		`sneller_queries_total{tenant="a\"b",outcome="canceled"} 0` + "\n",
+		"# TYPE sneller_query_duration_seconds histogram\n",
+		`sneller_query_duration_seconds_bucket{tenant="a\"b",le="0.025"} 0` + "\n",
+		`sneller_query_duration_seconds_bucket{tenant="a\"b",le="0.05"} 1` + "\n",
+		`sneller_query_duration_seconds_bucket{tenant="a\"b",le="2.5"} 2` + "\n",
+		`sneller_query_duration_seconds_bucket{tenant="a\"b",le="300"} 2` + "\n",
+		`sneller_query_duration_seconds_bucket{tenant="a\"b",le="+Inf"} 3` + "\n",
+		`sneller_query_duration_seconds_count{tenant="a\"b"} 3` + "\n",
+		`sneller_query_scanned_bytes_total{tenant="a\"b"} 150` + "\n",
+		`sneller_query_cache_hits_total{tenant="a\"b"} 2` + "\n",
+		`sneller_query_cache_misses_total{tenant="a\"b"} 1` + "\n",
+		`sneller_queries_total{tenant="a\"b",outcome="rejected"} 1` + "\n",
+		`sneller_queries_running{tenant="a\"b"} 1` + "\n",
+		`sneller_queries_queued{tenant="a\"b"} 3` + "\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Log(text)
+	}
+}
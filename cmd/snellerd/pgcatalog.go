@@ -0,0 +1,410 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SnellerInc/sneller"
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/ion/blockfmt"
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// The catalog is a minimal emulation of the
+// information_schema and pg_catalog schemas that
+// is generated from the databases and tables of
+// the tenant. Queries that reference catalog tables
+// (or no tables at all) are executed in-process.
+
+const (
+	// pgCatalogTTL is how long the catalog of
+	// a connection is used before it is rebuilt
+	pgCatalogTTL = time.Minute
+
+	// pgDatashapeRows is the number of rows
+	// of each table that are examined in order
+	// to determine the columns of the table
+	pgDatashapeRows = 1000
+
+	// pgFirstOID is the first OID assigned
+	// to schemas and tables in the catalog
+	pgFirstOID = 16384
+	// pgCatalogNamespace is the OID of pg_catalog
+	pgCatalogNamespace = 11
+)
+
+// pgCatalogName returns the name of the
+// catalog table referenced by the table
+// expression e, if there is one
+func pgCatalogName(e expr.Node) (string, bool) {
+	switch e := e.(type) {
+	case expr.Ident:
+		// pg_catalog is always first in the search path
+		if strings.HasPrefix(string(e), "pg_") {
+			return "pg_catalog." + string(e), true
+		}
+	case *expr.Dot:
+		id, ok := e.Inner.(expr.Ident)
+		if ok && (id == "information_schema" || id == "pg_catalog") {
+			return string(id) + "." + e.Field, true
+		}
+	}
+	return "", false
+}
+
+// pgColumnsNeeded returns whether any of
+// the catalog tables list the columns of tables
+func pgColumnsNeeded(tables []string) bool {
+	for _, t := range tables {
+		if t == "information_schema.columns" || t == "pg_catalog.pg_attribute" {
+			return true
+		}
+	}
+	return false
+}
+
+// pgTable accumulates the rows of a catalog table
+type pgTable struct {
+	st   ion.Symtab
+	body ion.Buffer
+}
+
+// add adds a row with the given
+// alternating field names and values
+func (t *pgTable) add(kv ...any) {
+	t.body.BeginStruct(-1)
+	for i := 0; i < len(kv); i += 2 {
+		t.body.BeginField(t.st.Intern(kv[i].(string)))
+		switch v := kv[i+1].(type) {
+		case string:
+			t.body.WriteString(v)
+		case int:
+			t.body.WriteInt(int64(v))
+		case bool:
+			t.body.WriteBool(v)
+		default:
+			panic(fmt.Sprintf("unexpected catalog value %T", v))
+		}
+	}
+	t.body.EndStruct()
+}
+
+func (t *pgTable) bytes() []byte {
+	var buf ion.Buffer
+	t.st.Marshal(&buf, true)
+	buf.UnsafeAppend(t.body.Bytes())
+	return buf.Bytes()
+}
+
+// pgCatalog is the catalog of one connection;
+// it implements plan.Env and plan.Runner
+type pgCatalog struct {
+	built time.Time
+	// columns is set if the columns
+	// of tables have been determined
+	columns bool
+	tables  map[string][]byte
+}
+
+// Stat implements plan.Env.Stat
+func (p *pgCatalog) Stat(e expr.Node, h *plan.Hints) (*plan.Input, error) {
+	name, ok := pgCatalogName(e)
+	if !ok {
+		return nil, pgErrorf(pgCodeUndefined, "relation %q does not exist", expr.ToString(e))
+	}
+	buf, ok := p.tables[name]
+	if !ok {
+		return nil, pgErrorf(pgCodeUndefined, "relation %q does not exist", name)
+	}
+	tr := blockfmt.Trailer{
+		BlockShift: bits.Len(uint(len(buf))),
+		Blocks:     []blockfmt.Blockdesc{{Chunks: 1}},
+	}
+	tr.Sparse.Push(nil)
+	return &plan.Input{
+		Descs: []plan.Descriptor{{
+			Descriptor: blockfmt.Descriptor{
+				ObjectInfo: blockfmt.ObjectInfo{
+					Path: name,
+					Size: int64(len(buf)),
+				},
+				Trailer: tr,
+			},
+			Blocks: []int{0},
+		}},
+		Fields: h.Fields,
+	}, nil
+}
+
+// Run implements plan.Runner.Run
+func (p *pgCatalog) Run(dst vm.QuerySink, in *plan.Input, ep *plan.ExecParams) error {
+	for i := range in.Descs {
+		if err := ep.Context.Err(); err != nil {
+			return err
+		}
+		buf, ok := p.tables[in.Descs[i].Path]
+		if !ok {
+			return fmt.Errorf("no catalog table %s", in.Descs[i].Path)
+		}
+		err := vm.BufferTable(buf, len(buf)).WriteChunks(dst, 1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localQuery executes a query that
+// only references catalog tables
+//
+// The query is executed in this process rather
+// than in the tenant process, but it is admitted
+// and limited like the queries of the tenant,
+// and a CancelRequest cancels it.
+func (c *pgConn) localQuery(q *expr.Query, tables []string, cols []pgColumn, describe bool) (*pgResult, error) {
+	cat := &pgCatalog{}
+	if len(tables) > 0 {
+		var err error
+		cat, err = c.getCatalog(pgColumnsNeeded(tables))
+		if err != nil {
+			return nil, err
+		}
+	}
+	tree, err := plan.New(q, cat)
+	if err != nil {
+		return nil, err
+	}
+	if describe && cols == nil && len(tree.Results) > 0 {
+		return &pgResult{cols: pgColumns(nil, tree.Results, tree.ResultTypes)}, nil
+	}
+	s := c.srv
+	creds := c.creds
+	cfg := tenantConfig(creds)
+	timeout := queryKillTimeout
+	if cfg != nil && cfg.MaxQueryDuration > 0 && cfg.MaxQueryDuration < timeout {
+		timeout = cfg.MaxQueryDuration
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c.setCancel(cancel)
+	defer c.setCancel(nil)
+	id, _ := tenantProcess(creds)
+	release, err := s.admit(ctx, creds, id, 0)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, pgContextError(ctx)
+		}
+		s.metrics.reject(creds.ID())
+		return nil, err
+	}
+	defer release(0)
+	var memory *vm.Budget
+	if cfg != nil && cfg.MaxMemoryBytes > 0 {
+		memory = vm.NewBudget(int64(cfg.MaxMemoryBytes))
+	}
+	var out bytes.Buffer
+	ep := &plan.ExecParams{
+		Plan:     tree,
+		Output:   &out,
+		Parallel: 1,
+		Context:  ctx,
+		Runner:   cat,
+		Memory:   memory,
+	}
+	if err := plan.Exec(ep); err != nil {
+		if ctx.Err() != nil {
+			return nil, pgContextError(ctx)
+		}
+		return nil, err
+	}
+	res := pgRows{max: c.srv.pgResultMax}
+	if err := readRows(&out, res.add); err != nil {
+		return nil, err
+	}
+	return pgResultOf(res.rows, tree, cols), nil
+}
+
+// pgContextError returns the error reported for
+// a statement that was stopped because ctx ended
+func pgContextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return pgErrorf(pgCodeCanceled, "canceling statement due to statement timeout")
+	}
+	return pgErrorf(pgCodeCanceled, "canceling statement due to user request")
+}
+
+// getCatalog returns the catalog of the connection,
+// building it if necessary
+func (c *pgConn) getCatalog(columns bool) (*pgCatalog, error) {
+	if cat := c.catalog; cat != nil && time.Since(cat.built) < pgCatalogTTL && (cat.columns || !columns) {
+		return cat, nil
+	}
+	cat, err := c.buildCatalog(columns)
+	if err != nil {
+		return nil, err
+	}
+	c.catalog = cat
+	return cat, nil
+}
+
+func (c *pgConn) buildCatalog(columns bool) (*pgCatalog, error) {
+	env, err := sneller.Environ(c.creds, "")
+	if err != nil {
+		return nil, err
+	}
+	dbs, err := db.List(env.Root)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dbs)
+	catalog := c.params["database"]
+	owner := c.params["user"]
+	var schemata, tables, cols, databases, namespaces, classes, attributes, types pgTable
+	databases.add("oid", 1, "datname", catalog)
+	namespaces.add("oid", pgCatalogNamespace, "nspname", "pg_catalog", "nspowner", 10)
+	for _, t := range pgTypes {
+		types.add("oid", int(t.oid), "typname", t.name, "typnamespace", pgCatalogNamespace,
+			"typlen", int(t.size), "typtype", "b")
+	}
+	oid := pgFirstOID
+	for _, dbname := range dbs {
+		nsoid := oid
+		oid++
+		schemata.add("catalog_name", catalog, "schema_name", dbname, "schema_owner", owner)
+		namespaces.add("oid", nsoid, "nspname", dbname, "nspowner", 10)
+		list, err := db.Tables(env.Root, dbname)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(list)
+		for _, table := range list {
+			if !c.access.allowed(dbname, table) {
+				continue
+			}
+			reloid := oid
+			oid++
+			tables.add("table_catalog", catalog, "table_schema", dbname,
+				"table_name", table, "table_type", "BASE TABLE")
+			classes.add("oid", reloid, "relname", table, "relnamespace", nsoid, "relkind", "r")
+			if !columns {
+				continue
+			}
+			shape, err := c.tableColumns(dbname, table)
+			if err != nil {
+				c.srv.logger.Printf("tenant %s: determining columns of %s.%s: %s", c.creds.ID(), dbname, table, err)
+				continue
+			}
+			for i := range shape {
+				cols.add("table_catalog", catalog, "table_schema", dbname, "table_name", table,
+					"column_name", shape[i].name, "ordinal_position", i+1,
+					"data_type", shape[i].typ.sql, "udt_name", shape[i].typ.name, "is_nullable", "YES")
+				attributes.add("attrelid", reloid, "attname", shape[i].name,
+					"atttypid", int(shape[i].typ.oid), "attnum", i+1,
+					"attnotnull", false, "attisdropped", false)
+			}
+		}
+	}
+	return &pgCatalog{
+		built:   time.Now(),
+		columns: columns,
+		tables: map[string][]byte{
+			"information_schema.schemata": schemata.bytes(),
+			"information_schema.tables":   tables.bytes(),
+			"information_schema.columns":  cols.bytes(),
+			"pg_catalog.pg_database":      databases.bytes(),
+			"pg_catalog.pg_namespace":     namespaces.bytes(),
+			"pg_catalog.pg_class":         classes.bytes(),
+			"pg_catalog.pg_attribute":     attributes.bytes(),
+			"pg_catalog.pg_type":          types.bytes(),
+		},
+	}, nil
+}
+
+// pgShapeTypes maps the type names
+// produced by SNELLER_DATASHAPE to types
+var pgShapeTypes = map[string]expr.TypeSet{
+	"null":       expr.NullType,
+	"bool":       expr.BoolType,
+	"int":        expr.IntegerType,
+	"float":      expr.FloatType,
+	"decimal":    expr.DecimalType,
+	"timestamp":  expr.TimeType,
+	"string":     expr.StringType,
+	"list":       expr.ListType,
+	"struct":     expr.StructType,
+	"sexp":       expr.SymbolType,
+	"clob":       expr.SymbolType,
+	"blob":       expr.SymbolType,
+	"annotation": expr.SymbolType,
+}
+
+// tableColumns determines the top-level columns
+// of a table by sampling its rows with SNELLER_DATASHAPE
+func (c *pgConn) tableColumns(dbname, table string) ([]pgColumn, error) {
+	text := fmt.Sprintf("WITH subset AS (SELECT * FROM %s.%s LIMIT %d) SELECT SNELLER_DATASHAPE(*) FROM subset",
+		expr.QuoteID(dbname), expr.QuoteID(table), pgDatashapeRows)
+	q, err := partiql.Parse([]byte(text))
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.tenantQuery(q, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.rows) != 1 {
+		return nil, fmt.Errorf("datashape returned %d rows", len(res.rows))
+	}
+	f, ok := res.rows[0].FieldByName("fields")
+	if !ok {
+		return nil, nil // no rows
+	}
+	fields, err := f.Struct()
+	if err != nil {
+		return nil, err
+	}
+	var out []pgColumn
+	err = fields.Each(func(f ion.Field) error {
+		if strings.Contains(f.Label, ".") {
+			return nil // not a top-level field
+		}
+		counts, err := f.Struct()
+		if err != nil {
+			return err
+		}
+		var ts expr.TypeSet
+		counts.Each(func(f ion.Field) error {
+			ts |= pgShapeTypes[f.Label]
+			return nil
+		})
+		out = append(out, pgColumn{name: f.Label, typ: pgTypeOf(ts)})
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].name < out[j].name
+	})
+	return out, err
+}
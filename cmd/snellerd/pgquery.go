@@ -0,0 +1,431 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SnellerInc/sneller"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/tenant"
+	"github.com/SnellerInc/sneller/tenant/tnproto"
+	"github.com/SnellerInc/sneller/usock"
+	"github.com/google/uuid"
+)
+
+// pgSessionFunc matches queries that
+// only select a session information function
+var pgSessionFunc = regexp.MustCompile(`(?i)^select\s+(?:pg_catalog\.)?(version|current_database|current_catalog|current_schema|current_user|session_user|user)\s*(?:\(\s*\))?(?:\s+(?:as\s+)?"?(\w+)"?)?$`)
+
+// exec executes one statement.
+//
+// If cols is non-nil, the rows of the result are
+// presented using those columns rather than columns
+// determined from the rows themselves.
+// If describe is set, then the statement is only
+// executed when its columns cannot be determined
+// without doing so, and the statement must not
+// have any side effects.
+func (c *pgConn) exec(text string, cols []pgColumn, describe bool) (*pgResult, error) {
+	if text == "" {
+		return &pgResult{empty: true}, nil
+	}
+	word, rest, _ := strings.Cut(text, " ")
+	rest = strings.TrimSpace(rest)
+	switch word = strings.ToUpper(word); word {
+	case "SET", "RESET", "LISTEN", "UNLISTEN":
+		// session settings are accepted and ignored
+		return &pgResult{tag: word}, nil
+	case "DISCARD", "DEALLOCATE":
+		if !describe && word == "DEALLOCATE" {
+			name := strings.TrimPrefix(rest, "PREPARE ")
+			if strings.EqualFold(name, "ALL") {
+				c.stmts = make(map[string]*pgStatement)
+			} else {
+				delete(c.stmts, strings.Trim(name, `"`))
+			}
+		}
+		if strings.EqualFold(rest, "ALL") {
+			return &pgResult{tag: word + " ALL"}, nil
+		}
+		return &pgResult{tag: word}, nil
+	case "BEGIN", "START":
+		if !describe {
+			c.txn = true
+		}
+		if word == "START" {
+			return &pgResult{tag: "START TRANSACTION"}, nil
+		}
+		return &pgResult{tag: "BEGIN"}, nil
+	case "COMMIT", "END":
+		if !describe {
+			c.txn = false
+		}
+		return &pgResult{tag: "COMMIT"}, nil
+	case "ROLLBACK", "ABORT":
+		if !describe {
+			c.txn = false
+		}
+		return &pgResult{tag: "ROLLBACK"}, nil
+	case "SHOW":
+		return c.show(rest)
+	}
+	if m := pgSessionFunc.FindStringSubmatch(text); m != nil {
+		return c.sessionFunc(strings.ToLower(m[1]), m[2]), nil
+	}
+
+	q, err := partiql.Parse([]byte(text))
+	if err != nil {
+		return nil, &pgError{code: pgCodeSyntax, msg: err.Error()}
+	}
+	if err := q.Check(); err != nil {
+		return nil, err
+	}
+	catalog, tenantTables := pgQueryTables(q)
+	if tenantTables {
+		if len(catalog) > 0 {
+			return nil, pgErrorf(pgCodeNotSupported, "queries cannot reference both catalog tables and sneller tables")
+		}
+		return c.tenantQuery(q, cols, describe)
+	}
+	return c.localQuery(q, catalog, cols, describe)
+}
+
+// show implements SHOW
+func (c *pgConn) show(name string) (*pgResult, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if name == "transaction isolation level" {
+		name = "transaction_isolation"
+	}
+	value, ok := c.params[name]
+	if !ok {
+		return nil, pgErrorf(pgCodeUndefinedObj, "unrecognized configuration parameter %q", name)
+	}
+	return pgSingleton(name, value), nil
+}
+
+// sessionFunc returns the result of
+// selecting a session information function
+func (c *pgConn) sessionFunc(fn, as string) *pgResult {
+	var value string
+	switch fn {
+	case "version":
+		value = "PostgreSQL " + pgServerVersion + " (sneller " + version + ")"
+	case "current_database", "current_catalog":
+		value = c.params["database"]
+	case "current_schema":
+		value = c.database
+		if value == "" {
+			value = "public"
+		}
+	default:
+		value = c.params["user"]
+	}
+	if as == "" {
+		as = fn
+	}
+	return pgSingleton(as, value)
+}
+
+// pgSingleton returns a result with a single text value
+func pgSingleton(name, value string) *pgResult {
+	return &pgResult{
+		cols: []pgColumn{{name: name, typ: pgText}},
+		rows: []ion.Struct{ion.NewStruct(nil, []ion.Field{{Label: name, Datum: ion.String(value)}})},
+	}
+}
+
+// pgQueryTables returns the names of the catalog
+// tables referenced by q, and whether q also
+// references any tables that are not catalog tables
+func pgQueryTables(q *expr.Query) ([]string, bool) {
+	var catalog []string
+	other := false
+	visit := expr.WalkFunc(func(n expr.Node) bool {
+		t, ok := n.(*expr.Table)
+		if !ok {
+			return true
+		}
+		if name, ok := pgCatalogName(t.Expr); ok {
+			catalog = append(catalog, name)
+			return true
+		}
+		if id, ok := t.Expr.(expr.Ident); ok {
+			for i := range q.With {
+				if q.With[i].Table == string(id) {
+					return true
+				}
+			}
+		}
+		other = true
+		return true
+	})
+	for i := range q.With {
+		expr.Walk(visit, q.With[i].As)
+	}
+	expr.Walk(visit, q.Body)
+	return catalog, other
+}
+
+// pgColumns determines the columns of a result set.
+//
+// When the planner knows the names of the output
+// columns, those columns are used; otherwise the
+// columns are the union of the fields in the rows.
+// The type of each column is determined from the
+// values in the rows, or from the types determined
+// by the planner if there are no values.
+func pgColumns(rows []ion.Struct, results []expr.Binding, types []expr.TypeSet) []pgColumn {
+	var cols []pgColumn
+	var seen []expr.TypeSet
+	index := make(map[string]int)
+	add := func(name string) int {
+		if i, ok := index[name]; ok {
+			return i
+		}
+		index[name] = len(cols)
+		cols = append(cols, pgColumn{name: name})
+		seen = append(seen, 0)
+		return len(cols) - 1
+	}
+	for i := range results {
+		add(results[i].Result())
+	}
+	static := len(cols) > 0
+	for i := range rows {
+		rows[i].Each(func(f ion.Field) error {
+			j, ok := index[f.Label]
+			if !ok {
+				if static {
+					return nil
+				}
+				j = add(f.Label)
+			}
+			seen[j] |= 1 << f.Type()
+			return nil
+		})
+	}
+	for i := range cols {
+		ts := seen[i]
+		if ts == 0 && i < len(types) {
+			ts = types[i]
+		}
+		cols[i].typ = pgTypeOf(ts)
+	}
+	return cols
+}
+
+func pgResultOf(rows []ion.Struct, tree *plan.Tree, cols []pgColumn) *pgResult {
+	if cols == nil {
+		cols = pgColumns(rows, tree.Results, tree.ResultTypes)
+	}
+	return &pgResult{cols: cols, rows: rows}
+}
+
+// tenantQuery executes a query in
+// the tenant process of the connection
+func (c *pgConn) tenantQuery(q *expr.Query, cols []pgColumn, describe bool) (*pgResult, error) {
+	s := c.srv
+	creds := c.creds
+	tenantID := creds.ID()
+	redacted := q.Redacted()
+	audit := s.audit.begin(creds, "postgres", c.principal, c.client)
+	audit.Query = redacted
+	audit.Database = c.database
+	defer s.audit.end(audit)
+	if c.access != nil {
+		// q may be executed more than once
+		q = q.Clone()
+		if err := c.access.rewrite(q, c.database); err != nil {
+			audit.Status = auditDenied
+			return nil, pgErrorf(pgCodePrivilege, "%s", err)
+		}
+	}
+	env, err := sneller.Environ(creds, c.database)
+	if err != nil {
+		s.logger.Printf("refusing query: %s", err)
+		return nil, pgErrorf(pgCodeAuth, "tenant ID disallowed")
+	}
+	id, key := tenantProcess(creds)
+	endPoints := s.peers.Get()
+	queryID := uuid.New().String()
+	audit.QueryID = queryID
+	tctx, span := s.tracer.start(context.Background(), "")
+	defer s.tracer.finish(span)
+	span.SetAttr("tenant", tenantID)
+	span.SetAttr("interface", "postgres")
+	span.SetAttr("query_id", queryID)
+	tree, err := s.planQuery(tctx, q, env, id, key, endPoints, queryID)
+	audit.setTables(env)
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s planning failed: %s", tenantID, queryID, err)
+		return nil, err
+	}
+	if describe && cols == nil && len(tree.Results) > 0 {
+		audit.Status = auditDryRun
+		return &pgResult{cols: pgColumns(nil, tree.Results, tree.ResultTypes)}, nil
+	}
+	willScan := uint64(tree.MaxScanned())
+	if maxScan := maxScanBytes(creds); maxScan > 0 && willScan > maxScan {
+		audit.Status = outcomeRejected
+		return nil, &errPlanLimit{scan: willScan, max: maxScan}
+	}
+	// a CancelRequest also cancels
+	// waiting for admission
+	ctx, cancel := context.WithCancel(context.Background())
+	c.setCancel(cancel)
+	release, err := s.admit(ctx, creds, id, int64(willScan))
+	c.setCancel(nil)
+	cancel()
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s not admitted: %s", tenantID, queryID, err)
+		if errors.Is(err, context.Canceled) {
+			audit.Status = outcomeCanceled
+			return nil, pgErrorf(pgCodeCanceled, "canceling statement due to user request")
+		}
+		s.metrics.reject(tenantID)
+		audit.Status = outcomeRejected
+		return nil, err
+	}
+	var stats plan.ExecStats
+	defer func() {
+		release(stats.BytesScanned)
+		audit.Scanned = stats.BytesScanned
+	}()
+
+	here, there, err := usock.SocketPair()
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s socketpair: %s", tenantID, queryID, err)
+		return nil, err
+	}
+	exec := execSpan(tctx, tree)
+	defer exec.End()
+	startrun := time.Now()
+	rc, err := s.manager.Do(id, key, tree, tnproto.OutputRaw, there)
+	there.Close()
+	if err != nil {
+		exec.Fail(err)
+		here.Close()
+		s.logger.Printf("tenant %s query ID %s %q execution failed (do): %v", tenantID, queryID, redacted, err)
+		s.metrics.observe(tenantID, outcomeError, time.Since(startrun), nil)
+		if errors.Is(err, tenant.ErrOverloaded) {
+			return nil, err
+		}
+		return nil, pgErrorf(pgCodeInternal, "error dispatching query")
+	}
+	running := &runningQuery{
+		tenantID:  tenantID,
+		principal: c.principal,
+		id:        queryID,
+		query:     redacted,
+		database:  c.database,
+		started:   startrun,
+		peers:     peerNames(endPoints),
+		cancel: func() {
+			s.manager.Cancel(queryID)
+			here.Close()
+		},
+	}
+	s.running.add(running)
+	defer s.running.remove(queryID)
+	c.setCancel(func() {
+		running.canceled.Store(true)
+		running.cancel()
+	})
+	defer c.setCancel(nil)
+	defer limitDuration(running, tenantConfig(creds))()
+
+	deadlined := setDeadline(rc, queryKillTimeout)
+	setDeadline(here, queryKillTimeout)
+	checked := make(chan error, 1)
+	go func() {
+		checked <- tenant.CheckTrace(rc, &stats, running.progress, exec.Recorder())
+	}()
+	res := pgRows{max: s.pgResultMax}
+	err = readRows(here, res.add)
+	if res.exceeded {
+		// stop the query rather than
+		// discarding the rest of its output
+		s.manager.Cancel(queryID)
+	}
+	here.Close()
+	if cerr := <-checked; cerr != nil && !res.exceeded {
+		if deadlined && isTimeout(cerr) {
+			s.logger.Printf("tenant %s query ID %s killing tenant worker %s due to timeout", tenantID, queryID, id)
+			s.manager.Quit(id, key)
+		}
+		err = cerr
+	}
+	exec.SetAttr("bytes_scanned", stats.BytesScanned)
+	exec.Fail(err)
+	elapsed := time.Since(startrun)
+	if running.timedOut.Load() {
+		s.logger.Printf("tenant %s query ID %s exceeded the maximum query duration after %s", tenantID, queryID, elapsed)
+		s.metrics.observe(tenantID, outcomeError, elapsed, &stats)
+		return nil, pgErrorf(pgCodeCanceled, "canceling statement due to statement timeout")
+	}
+	if running.canceled.Load() {
+		s.logger.Printf("tenant %s query ID %s canceled after %s", tenantID, queryID, elapsed)
+		s.metrics.observe(tenantID, outcomeCanceled, elapsed, &stats)
+		audit.Status = outcomeCanceled
+		return nil, pgErrorf(pgCodeCanceled, "canceling statement due to user request")
+	}
+	if err != nil {
+		s.logger.Printf("tenant %s query ID %s %q execution failed (check): %v", tenantID, queryID, redacted, err)
+		s.metrics.observe(tenantID, outcomeError, elapsed, &stats)
+		return nil, err
+	}
+	s.metrics.observe(tenantID, outcomeOK, elapsed, &stats)
+	audit.Status = outcomeOK
+	s.logger.Printf("tenant %s query ID %s duration %s rows %d bytes %d hits %d misses %d",
+		tenantID, queryID, elapsed, len(res.rows), stats.BytesScanned, stats.CacheHits, stats.CacheMisses)
+	return pgResultOf(res.rows, tree, cols), nil
+}
+
+// pgRows collects the rows of a result set
+type pgRows struct {
+	// max, if positive, is the maximum
+	// encoded size of the rows in bytes
+	max  int64
+	size int64
+	rows []ion.Struct
+	// exceeded is set once the
+	// rows are larger than max
+	exceeded bool
+}
+
+// add adds a row; it fails the statement
+// rather than buffering more than max bytes
+func (r *pgRows) add(row ion.Datum) error {
+	r.size += int64(len(row.Raw()))
+	if r.max > 0 && r.size > r.max {
+		r.exceeded = true
+		r.rows = nil
+		return pgErrorf(pgCodeLimit, "query result exceeds the maximum size of %d bytes", r.max)
+	}
+	st, err := row.Clone().Struct()
+	if err != nil {
+		return err
+	}
+	r.rows = append(r.rows, st)
+	return nil
+}
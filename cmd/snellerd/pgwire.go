@@ -0,0 +1,1327 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/plan/pir"
+	"github.com/SnellerInc/sneller/tenant"
+)
+
+// This file implements the subset of the PostgreSQL
+// frontend/backend protocol (version 3) that is
+// necessary for BI tools and database drivers to
+// run queries against snellerd.
+//
+// Clients authenticate with a cleartext password,
+// which is interpreted as the bearer token that would
+// otherwise be presented to the HTTP API. When the
+// server has a TLS certificate, clients must encrypt
+// the connection with SSLRequest before they send
+// the token; otherwise SSLRequest is declined and
+// the listener must only be reachable from trusted
+// networks (see -pg-insecure).
+// Sneller databases are presented as schemas,
+// and the database requested by the client during
+// startup is used to resolve unqualified table names.
+
+const (
+	pgProtocolVersion = 196608
+	pgCancelRequest   = 80877102
+	pgSSLRequest      = 80877103
+	pgGSSENCRequest   = 80877104
+
+	// pgMaxMessage is the largest message
+	// that a client is allowed to send
+	pgMaxMessage = 128 * 1024 * 1024
+
+	// pgMaxStartupMessage is the largest message
+	// that a client is allowed to send before
+	// it is authenticated
+	pgMaxStartupMessage = 10000
+
+	// pgStartupTimeout is how long a client has
+	// to complete the startup handshake
+	pgStartupTimeout = time.Minute
+
+	// pgServerVersion is the server version
+	// reported to clients; drivers use it to
+	// decide which features they can use
+	pgServerVersion = "14.0"
+)
+
+// SQLSTATE codes sent in error responses
+const (
+	pgCodeProtocol      = "08P01"
+	pgCodeAuth          = "28P01"
+	pgCodeInvalidAuth   = "28000"
+	pgCodeSyntax        = "42601"
+	pgCodeDatatype      = "42804"
+	pgCodeUndefined     = "42P01"
+	pgCodeUndefinedStmt = "26000"
+	pgCodeUndefinedObj  = "42704"
+	pgCodePrivilege     = "42501"
+	pgCodeParameter     = "22023"
+	pgCodeNotSupported  = "0A000"
+	pgCodeLimit         = "54000"
+	pgCodeOverloaded    = "53300"
+	pgCodeQuota         = "53400"
+	pgCodeCanceled      = "57014"
+	pgCodeInternal      = "XX000"
+)
+
+// pgError is an error with an associated SQLSTATE
+type pgError struct {
+	code, msg string
+}
+
+func (e *pgError) Error() string { return e.msg }
+
+func pgErrorf(code, f string, args ...any) error {
+	return &pgError{code: code, msg: fmt.Sprintf(f, args...)}
+}
+
+// pgErrorCode determines the SQLSTATE for err
+func pgErrorCode(err error) string {
+	var pe *pgError
+	var syntax *expr.SyntaxError
+	var typ *expr.TypeError
+	var compile *pir.CompileError
+	var limit *errPlanLimit
+	var quota *errQuota
+	switch {
+	case errors.As(err, &pe):
+		return pe.code
+	case errors.As(err, &syntax), errors.As(err, &compile):
+		return pgCodeSyntax
+	case errors.As(err, &typ):
+		return pgCodeDatatype
+	case errors.As(err, &limit):
+		return pgCodeLimit
+	case errors.As(err, &quota):
+		return pgCodeQuota
+	case errors.Is(err, fs.ErrNotExist):
+		return pgCodeUndefined
+	case errors.Is(err, tenant.ErrOverloaded):
+		return pgCodeOverloaded
+	}
+	return pgCodeInternal
+}
+
+// pgType is a PostgreSQL data type
+type pgType struct {
+	oid  uint32
+	name string // name in pg_type
+	sql  string // name in information_schema
+	size int16
+}
+
+var (
+	pgBool        = &pgType{oid: 16, name: "bool", sql: "boolean", size: 1}
+	pgInt8        = &pgType{oid: 20, name: "int8", sql: "bigint", size: 8}
+	pgInt2        = &pgType{oid: 21, name: "int2", sql: "smallint", size: 2}
+	pgInt4        = &pgType{oid: 23, name: "int4", sql: "integer", size: 4}
+	pgText        = &pgType{oid: 25, name: "text", sql: "text", size: -1}
+	pgJSON        = &pgType{oid: 114, name: "json", sql: "json", size: -1}
+	pgFloat4      = &pgType{oid: 700, name: "float4", sql: "real", size: 4}
+	pgFloat8      = &pgType{oid: 701, name: "float8", sql: "double precision", size: 8}
+	pgVarchar     = &pgType{oid: 1043, name: "varchar", sql: "character varying", size: -1}
+	pgTimestamp   = &pgType{oid: 1114, name: "timestamp", sql: "timestamp without time zone", size: 8}
+	pgTimestamptz = &pgType{oid: 1184, name: "timestamptz", sql: "timestamp with time zone", size: 8}
+	pgNumeric     = &pgType{oid: 1700, name: "numeric", sql: "numeric", size: -1}
+
+	pgTypes = []*pgType{
+		pgBool, pgInt8, pgInt2, pgInt4, pgText, pgJSON, pgFloat4,
+		pgFloat8, pgVarchar, pgTimestamp, pgTimestamptz, pgNumeric,
+	}
+)
+
+func pgTypeByOID(oid uint32) *pgType {
+	for _, t := range pgTypes {
+		if t.oid == oid {
+			return t
+		}
+	}
+	return nil
+}
+
+// pgTypeOf returns the type used for a column
+// that may hold values from the set of types ts.
+//
+// Columns with heterogeneous values are presented
+// as json if every value is a structure or a list,
+// or as text otherwise.
+func pgTypeOf(ts expr.TypeSet) *pgType {
+	ts &^= expr.NullType | expr.MissingType
+	switch {
+	case ts == 0:
+		return pgText
+	case ts&^expr.BoolType == 0:
+		return pgBool
+	case ts&^expr.IntegerType == 0:
+		return pgInt8
+	case ts&^expr.NumericType == 0:
+		return pgFloat8
+	case ts&^expr.TimeType == 0:
+		return pgTimestamptz
+	case ts&^(expr.StructType|expr.ListType) == 0:
+		return pgJSON
+	}
+	return pgText
+}
+
+// pgColumn is one column of a result set
+type pgColumn struct {
+	name string
+	typ  *pgType
+}
+
+// pgResult is the result of one statement
+type pgResult struct {
+	// empty is set for an empty query
+	empty bool
+	// tag is the command tag for statements
+	// that do not produce a result set
+	tag  string
+	cols []pgColumn
+	rows []ion.Struct
+}
+
+// pgStatement is a prepared statement
+type pgStatement struct {
+	text   string
+	params []uint32
+	// cols, if non-nil, are the columns that
+	// were reported when the statement was described
+	cols []pgColumn
+}
+
+// pgPortal is a bound statement
+type pgPortal struct {
+	text    string
+	cols    []pgColumn
+	formats []int16
+	res     *pgResult
+	sent    int
+}
+
+// format returns the format code of the ith result column
+func (p *pgPortal) format(i int) int16 {
+	switch len(p.formats) {
+	case 0:
+		return 0
+	case 1:
+		return p.formats[0]
+	}
+	if i < len(p.formats) {
+		return p.formats[i]
+	}
+	return 0
+}
+
+// pgSessions is the set of open
+// PostgreSQL protocol connections
+type pgSessions struct {
+	lock  sync.Mutex
+	conns map[int32]*pgConn
+}
+
+func (p *pgSessions) add(c *pgConn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.conns == nil {
+		p.conns = make(map[int32]*pgConn)
+	}
+	for {
+		var buf [8]byte
+		rand.Read(buf[:])
+		c.pid = int32(binary.BigEndian.Uint32(buf[:]) &^ (1 << 31))
+		c.secret = int32(binary.BigEndian.Uint32(buf[4:]))
+		if c.pid != 0 && p.conns[c.pid] == nil {
+			break
+		}
+	}
+	p.conns[c.pid] = c
+}
+
+func (p *pgSessions) remove(c *pgConn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.conns, c.pid)
+}
+
+func (p *pgSessions) get(pid, secret int32) *pgConn {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	c := p.conns[pid]
+	if c == nil || c.secret != secret {
+		return nil
+	}
+	return c
+}
+
+// closeAll closes all of the connections
+func (p *pgSessions) closeAll() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, c := range p.conns {
+		c.conn.Close()
+	}
+}
+
+// servePostgres accepts PostgreSQL protocol
+// connections on l until it is closed
+func (s *server) servePostgres(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		c := &pgConn{
+			srv:     s,
+			conn:    conn,
+			r:       bufio.NewReader(conn),
+			w:       bufio.NewWriter(conn),
+			tls:     s.pgTLS,
+			stmts:   make(map[string]*pgStatement),
+			portals: make(map[string]*pgPortal),
+		}
+		go c.serve()
+	}
+}
+
+// pgConn is one PostgreSQL protocol connection
+type pgConn struct {
+	srv  *server
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	msg  []byte
+	// tls, if non-nil, is used to encrypt the
+	// connection, which is then required
+	tls *tls.Config
+
+	pid, secret int32
+	creds       db.Tenant
+	database    string
+	params      map[string]string
+	// principal and client identify the
+	// connection in the audit log
+	principal, client string
+	// access is the access of the principal
+	// to the tables of the tenant
+	access *access
+
+	stmts   map[string]*pgStatement
+	portals map[string]*pgPortal
+	// txn is set between BEGIN and COMMIT/ROLLBACK;
+	// statements are not actually transactional
+	txn bool
+	// failed is set when an error occurs while
+	// processing an extended query, in which case
+	// messages are discarded until the next Sync
+	failed bool
+
+	catalog *pgCatalog
+
+	lock sync.Mutex
+	// cancel cancels the query that is currently
+	// running, if there is one
+	cancel func()
+}
+
+func (c *pgConn) setCancel(fn func()) {
+	c.lock.Lock()
+	c.cancel = fn
+	c.lock.Unlock()
+}
+
+func (c *pgConn) cancelQuery() {
+	c.lock.Lock()
+	fn := c.cancel
+	c.lock.Unlock()
+	if fn != nil {
+		fn()
+	}
+}
+
+func (c *pgConn) serve() {
+	defer c.conn.Close()
+	ok, err := c.startup()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			c.srv.logger.Printf("postgres connection from %s: %s", c.conn.RemoteAddr(), err)
+		}
+		return
+	}
+	if !ok {
+		return
+	}
+	defer c.srv.pg.remove(c)
+	for {
+		typ, body, err := c.read()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				c.srv.logger.Printf("tenant %s postgres connection: %s", c.creds.ID(), err)
+			}
+			return
+		}
+		if typ == 'X' {
+			return
+		}
+		if err := c.handle(typ, body); err != nil {
+			c.srv.logger.Printf("tenant %s postgres connection: %s", c.creds.ID(), err)
+			return
+		}
+	}
+}
+
+// readStartup reads a message without a type byte
+func (c *pgConn) readStartup() ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return nil, err
+	}
+	size := int(binary.BigEndian.Uint32(hdr[:]))
+	if size < 8 || size > pgMaxStartupMessage {
+		return nil, fmt.Errorf("invalid startup message length %d", size)
+	}
+	body := make([]byte, size-4)
+	_, err := io.ReadFull(c.r, body)
+	return body, err
+}
+
+func (c *pgConn) read() (byte, []byte, error) {
+	return c.readMax(pgMaxMessage)
+}
+
+// readMax reads a message that is
+// at most max bytes long
+func (c *pgConn) readMax(max int) (byte, []byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	size := int(binary.BigEndian.Uint32(hdr[1:]))
+	if size < 4 || size > max {
+		return 0, nil, fmt.Errorf("invalid message length %d", size)
+	}
+	body := make([]byte, size-4)
+	_, err := io.ReadFull(c.r, body)
+	return hdr[0], body, err
+}
+
+// startup performs the startup handshake
+// and authenticates the client; it returns
+// false if the connection should be closed
+func (c *pgConn) startup() (bool, error) {
+	// nothing is authenticated until the
+	// handshake completes, so it must not
+	// hold the connection open indefinitely
+	deadline := time.Now().Add(pgStartupTimeout)
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return false, err
+	}
+	defer c.conn.SetDeadline(time.Time{})
+	var body []byte
+	encrypted := false
+	for {
+		var err error
+		body, err = c.readStartup()
+		if err != nil {
+			return false, err
+		}
+		r := pgReader{buf: body}
+		switch code := r.int32(); code {
+		case pgSSLRequest:
+			if c.tls == nil || encrypted {
+				if _, err := c.conn.Write([]byte{'N'}); err != nil {
+					return false, err
+				}
+				continue
+			}
+			if _, err := c.conn.Write([]byte{'S'}); err != nil {
+				return false, err
+			}
+			// anything sent before the TLS
+			// handshake was sent in cleartext
+			if c.r.Buffered() > 0 {
+				return false, fmt.Errorf("unexpected data before the TLS handshake")
+			}
+			tc := tls.Server(c.conn, c.tls)
+			if err := tc.Handshake(); err != nil {
+				return false, err
+			}
+			c.conn = tc
+			c.r = bufio.NewReader(tc)
+			c.w = bufio.NewWriter(tc)
+			encrypted = true
+			continue
+		case pgGSSENCRequest:
+			// GSSAPI encryption is not supported
+			if _, err := c.conn.Write([]byte{'N'}); err != nil {
+				return false, err
+			}
+			continue
+		case pgCancelRequest:
+			pid, secret := r.int32(), r.int32()
+			if target := c.srv.pg.get(pid, secret); target != nil {
+				target.cancelQuery()
+			}
+			return false, nil
+		case pgProtocolVersion:
+		default:
+			c.sendError(pgErrorf(pgCodeProtocol, "unsupported frontend protocol %d.%d", code>>16, code&0xffff))
+			return false, c.w.Flush()
+		}
+		break
+	}
+	if c.tls != nil && !encrypted {
+		// refuse before the client sends its token
+		c.sendError(pgErrorf(pgCodeInvalidAuth, "the connection must be encrypted with SSL"))
+		return false, c.w.Flush()
+	}
+	r := pgReader{buf: body[4:]}
+	c.params = make(map[string]string)
+	for r.err == nil && len(r.buf) > 1 {
+		k := r.cstring()
+		v := r.cstring()
+		c.params[k] = v
+	}
+	if r.err != nil {
+		return false, r.err
+	}
+	user := c.params["user"]
+
+	// AuthenticationCleartextPassword
+	c.begin('R')
+	c.int32(3)
+	c.end()
+	if err := c.w.Flush(); err != nil {
+		return false, err
+	}
+	typ, msg, err := c.readMax(pgMaxStartupMessage)
+	if err != nil {
+		return false, err
+	}
+	if typ != 'p' {
+		return false, fmt.Errorf("unexpected message %q during authentication", typ)
+	}
+	r = pgReader{buf: msg}
+	password := r.cstring()
+	if r.err != nil {
+		return false, r.err
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	c.creds, err = c.srv.auth.Authorize(ctx, password)
+	cancel()
+	if err != nil {
+		c.srv.logger.Printf("postgres connection from %s: authorization failed: %s", c.conn.RemoteAddr(), err)
+		c.sendError(pgErrorf(pgCodeAuth, "password authentication failed for user %q", user))
+		return false, c.w.Flush()
+	}
+	c.principal = principalOf(c.creds, password)
+	c.client = c.conn.RemoteAddr().String()
+	c.access, err = c.srv.policy.access(c.creds, c.principal)
+	if err != nil {
+		c.srv.logger.Printf("postgres connection from %s: %s", c.conn.RemoteAddr(), err)
+		c.sendError(pgErrorf(pgCodeInternal, "cannot load access policy"))
+		return false, c.w.Flush()
+	}
+
+	// the client's database is used to
+	// resolve unqualified table names
+	c.database = c.params["database"]
+
+	// AuthenticationOk
+	c.begin('R')
+	c.int32(0)
+	c.end()
+	c.params["server_version"] = pgServerVersion
+	c.params["server_encoding"] = "UTF8"
+	c.params["client_encoding"] = "UTF8"
+	c.params["datestyle"] = "ISO, MDY"
+	c.params["intervalstyle"] = "postgres"
+	c.params["timezone"] = "UTC"
+	c.params["integer_datetimes"] = "on"
+	c.params["standard_conforming_strings"] = "on"
+	c.params["transaction_isolation"] = "read committed"
+	c.params["search_path"] = "public"
+	for _, k := range []string{
+		"server_version", "server_encoding", "client_encoding",
+		"DateStyle", "IntervalStyle", "TimeZone", "integer_datetimes",
+		"standard_conforming_strings", "application_name",
+	} {
+		c.begin('S')
+		c.cstring(k)
+		c.cstring(c.params[strings.ToLower(k)])
+		c.end()
+	}
+	c.srv.pg.add(c)
+	// BackendKeyData
+	c.begin('K')
+	c.int32(c.pid)
+	c.int32(c.secret)
+	c.end()
+	c.srv.logger.Printf("tenant %s postgres connection from %s", c.creds.ID(), c.conn.RemoteAddr())
+	return true, c.ready()
+}
+
+func (c *pgConn) ready() error {
+	c.begin('Z')
+	if c.txn {
+		c.byte('T')
+	} else {
+		c.byte('I')
+	}
+	c.end()
+	return c.w.Flush()
+}
+
+func (c *pgConn) begin(typ byte) {
+	c.msg = append(c.msg[:0], typ, 0, 0, 0, 0)
+}
+
+func (c *pgConn) byte(b byte)   { c.msg = append(c.msg, b) }
+func (c *pgConn) int16(i int16) { c.msg = binary.BigEndian.AppendUint16(c.msg, uint16(i)) }
+func (c *pgConn) int32(i int32) { c.msg = binary.BigEndian.AppendUint32(c.msg, uint32(i)) }
+func (c *pgConn) cstring(s string) {
+	c.msg = append(c.msg, s...)
+	c.msg = append(c.msg, 0)
+}
+
+func (c *pgConn) end() {
+	binary.BigEndian.PutUint32(c.msg[1:], uint32(len(c.msg)-1))
+	c.w.Write(c.msg)
+}
+
+func (c *pgConn) sendError(err error) {
+	c.begin('E')
+	c.byte('S')
+	c.cstring("ERROR")
+	c.byte('V')
+	c.cstring("ERROR")
+	c.byte('C')
+	c.cstring(pgErrorCode(err))
+	c.byte('M')
+	c.cstring(err.Error())
+	c.byte(0)
+	c.end()
+}
+
+// pgReader decodes the body of a frontend message
+type pgReader struct {
+	buf []byte
+	err error
+}
+
+var errPgShort = errors.New("message too short")
+
+func (r *pgReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buf) < n {
+		r.err = errPgShort
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *pgReader) byte() byte {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *pgReader) int16() int16 {
+	if b := r.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *pgReader) int32() int32 {
+	if b := r.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *pgReader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	i := strings.IndexByte(string(r.buf), 0)
+	if i < 0 {
+		r.err = errPgShort
+		return ""
+	}
+	s := string(r.buf[:i])
+	r.buf = r.buf[i+1:]
+	return s
+}
+
+// handle handles one message from the client
+func (c *pgConn) handle(typ byte, body []byte) error {
+	r := &pgReader{buf: body}
+	switch typ {
+	case 'Q':
+		text := r.cstring()
+		if r.err != nil {
+			return r.err
+		}
+		c.failed = false
+		c.simpleQuery(text)
+		return c.ready()
+	case 'S':
+		c.failed = false
+		return c.ready()
+	case 'H':
+		return c.w.Flush()
+	case 'P', 'B', 'D', 'E', 'C':
+		if c.failed {
+			return nil
+		}
+		var err error
+		switch typ {
+		case 'P':
+			err = c.parse(r)
+		case 'B':
+			err = c.bind(r)
+		case 'D':
+			err = c.describe(r)
+		case 'E':
+			err = c.execute(r)
+		case 'C':
+			err = c.close(r)
+		}
+		if r.err != nil {
+			return r.err
+		}
+		if err != nil {
+			c.sendError(err)
+			c.failed = true
+		}
+		return nil
+	case 'p':
+		return errors.New("unexpected password message")
+	case 'd', 'c', 'f':
+		// COPY data is never expected
+		return nil
+	}
+	return fmt.Errorf("unexpected message type %q", typ)
+}
+
+// simpleQuery executes each of the
+// statements in text in turn
+func (c *pgConn) simpleQuery(text string) {
+	delete(c.stmts, "")
+	delete(c.portals, "")
+	stmts := pgSplit(text)
+	if len(stmts) == 0 {
+		c.begin('I')
+		c.end()
+		return
+	}
+	for _, stmt := range stmts {
+		res, err := c.exec(stmt, nil, false)
+		if err != nil {
+			c.sendError(err)
+			return
+		}
+		p := &pgPortal{res: res}
+		if !res.empty && res.tag == "" {
+			c.rowDescription(res.cols, p)
+		}
+		c.sendRows(p, 0)
+	}
+}
+
+func (c *pgConn) parse(r *pgReader) error {
+	name := r.cstring()
+	text := r.cstring()
+	n := int(r.int16())
+	params := make([]uint32, 0, n)
+	for i := 0; i < n; i++ {
+		params = append(params, uint32(r.int32()))
+	}
+	if r.err != nil {
+		return nil
+	}
+	stmts := pgSplit(text)
+	if len(stmts) > 1 {
+		return pgErrorf(pgCodeSyntax, "cannot insert multiple commands into a prepared statement")
+	}
+	text = ""
+	if len(stmts) == 1 {
+		text = stmts[0]
+	}
+	// the parameter types that the client
+	// did not specify are presented as text
+	for i := len(params); i < pgCountParams(text); i++ {
+		params = append(params, 0)
+	}
+	c.stmts[name] = &pgStatement{text: text, params: params}
+	c.begin('1')
+	c.end()
+	return nil
+}
+
+func (c *pgConn) bind(r *pgReader) error {
+	portal := r.cstring()
+	name := r.cstring()
+	formats := make([]int16, r.int16())
+	for i := range formats {
+		formats[i] = r.int16()
+	}
+	values := make([][]byte, r.int16())
+	for i := range values {
+		size := r.int32()
+		if size >= 0 {
+			values[i] = r.take(int(size))
+			if values[i] == nil {
+				values[i] = []byte{}
+			}
+		}
+	}
+	results := make([]int16, r.int16())
+	for i := range results {
+		results[i] = r.int16()
+	}
+	if r.err != nil {
+		return nil
+	}
+	stmt := c.stmts[name]
+	if stmt == nil {
+		return pgErrorf(pgCodeUndefinedStmt, "prepared statement %q does not exist", name)
+	}
+	if len(values) != len(stmt.params) {
+		return pgErrorf(pgCodeProtocol, "bind message supplies %d parameters, but prepared statement %q requires %d", len(values), name, len(stmt.params))
+	}
+	lits := make([]string, len(values))
+	for i := range values {
+		format := int16(0)
+		if len(formats) == 1 {
+			format = formats[0]
+		} else if i < len(formats) {
+			format = formats[i]
+		}
+		lit, err := pgLiteral(values[i], stmt.params[i], format)
+		if err != nil {
+			return err
+		}
+		lits[i] = lit
+	}
+	c.portals[portal] = &pgPortal{
+		text:    pgSubstitute(stmt.text, lits),
+		cols:    stmt.cols,
+		formats: results,
+	}
+	c.begin('2')
+	c.end()
+	return nil
+}
+
+func (c *pgConn) describe(r *pgReader) error {
+	kind := r.byte()
+	name := r.cstring()
+	if r.err != nil {
+		return nil
+	}
+	switch kind {
+	case 'S':
+		stmt := c.stmts[name]
+		if stmt == nil {
+			return pgErrorf(pgCodeUndefinedStmt, "prepared statement %q does not exist", name)
+		}
+		c.begin('t')
+		c.int16(int16(len(stmt.params)))
+		for _, oid := range stmt.params {
+			if oid == 0 {
+				oid = pgText.oid
+			}
+			c.int32(int32(oid))
+		}
+		c.end()
+		// describe the statement as if
+		// all of its parameters were NULL
+		nulls := make([]string, len(stmt.params))
+		for i := range nulls {
+			nulls[i] = "NULL"
+		}
+		res, err := c.exec(pgSubstitute(stmt.text, nulls), nil, true)
+		if err != nil {
+			return err
+		}
+		if res.empty || res.tag != "" {
+			c.begin('n')
+			c.end()
+			return nil
+		}
+		stmt.cols = res.cols
+		c.rowDescription(res.cols, &pgPortal{})
+	case 'P':
+		p := c.portals[name]
+		if p == nil {
+			return pgErrorf(pgCodeUndefinedObj, "portal %q does not exist", name)
+		}
+		if p.res == nil {
+			res, err := c.exec(p.text, p.cols, false)
+			if err != nil {
+				return err
+			}
+			p.res = res
+		}
+		if p.res.empty || p.res.tag != "" {
+			c.begin('n')
+			c.end()
+			return nil
+		}
+		c.rowDescription(p.res.cols, p)
+	default:
+		return pgErrorf(pgCodeProtocol, "invalid describe message subtype %q", kind)
+	}
+	return nil
+}
+
+func (c *pgConn) execute(r *pgReader) error {
+	name := r.cstring()
+	max := int(r.int32())
+	if r.err != nil {
+		return nil
+	}
+	p := c.portals[name]
+	if p == nil {
+		return pgErrorf(pgCodeUndefinedObj, "portal %q does not exist", name)
+	}
+	if p.res == nil {
+		res, err := c.exec(p.text, p.cols, false)
+		if err != nil {
+			return err
+		}
+		p.res = res
+	}
+	c.sendRows(p, max)
+	return nil
+}
+
+func (c *pgConn) close(r *pgReader) error {
+	kind := r.byte()
+	name := r.cstring()
+	if r.err != nil {
+		return nil
+	}
+	switch kind {
+	case 'S':
+		delete(c.stmts, name)
+	case 'P':
+		delete(c.portals, name)
+	default:
+		return pgErrorf(pgCodeProtocol, "invalid close message subtype %q", kind)
+	}
+	c.begin('3')
+	c.end()
+	return nil
+}
+
+func (c *pgConn) rowDescription(cols []pgColumn, p *pgPortal) {
+	c.begin('T')
+	c.int16(int16(len(cols)))
+	for i := range cols {
+		c.cstring(cols[i].name)
+		c.int32(0) // table OID
+		c.int16(0) // column number
+		c.int32(int32(cols[i].typ.oid))
+		c.int16(cols[i].typ.size)
+		c.int32(-1) // type modifier
+		c.int16(p.format(i))
+	}
+	c.end()
+}
+
+// sendRows sends up to max rows (or all of them
+// if max is zero) from the results of p followed
+// by CommandComplete or PortalSuspended
+func (c *pgConn) sendRows(p *pgPortal, max int) {
+	res := p.res
+	if res.empty {
+		c.begin('I')
+		c.end()
+		return
+	}
+	if res.tag != "" {
+		c.begin('C')
+		c.cstring(res.tag)
+		c.end()
+		return
+	}
+	index := make(map[string]int, len(res.cols))
+	for i := range res.cols {
+		if _, ok := index[res.cols[i].name]; !ok {
+			index[res.cols[i].name] = i
+		}
+	}
+	values := make([]ion.Datum, len(res.cols))
+	end := len(res.rows)
+	if max > 0 && p.sent+max < end {
+		end = p.sent + max
+	}
+	for ; p.sent < end; p.sent++ {
+		for i := range values {
+			values[i] = ion.Empty
+		}
+		res.rows[p.sent].Each(func(f ion.Field) error {
+			if i, ok := index[f.Label]; ok {
+				values[i] = f.Datum
+			}
+			return nil
+		})
+		c.begin('D')
+		c.int16(int16(len(values)))
+		for i := range values {
+			c.value(values[i], res.cols[i].typ, p.format(i) == 1)
+		}
+		c.end()
+	}
+	if p.sent < len(res.rows) {
+		c.begin('s')
+		c.end()
+		return
+	}
+	c.begin('C')
+	c.cstring("SELECT " + strconv.Itoa(len(res.rows)))
+	c.end()
+}
+
+// pgEpoch is the PostgreSQL epoch
+// in microseconds since the Unix epoch
+const pgEpoch = 946684800000000
+
+// value encodes one column of a DataRow
+func (c *pgConn) value(d ion.Datum, typ *pgType, binary bool) {
+	if d.IsEmpty() || d.IsNull() {
+		c.int32(-1)
+		return
+	}
+	pos := len(c.msg)
+	c.int32(0)
+	if binary {
+		c.binaryValue(d, typ)
+	} else {
+		c.msg = append(c.msg, pgTextValue(d, typ)...)
+	}
+	size := len(c.msg) - pos - 4
+	c.msg[pos] = byte(size >> 24)
+	c.msg[pos+1] = byte(size >> 16)
+	c.msg[pos+2] = byte(size >> 8)
+	c.msg[pos+3] = byte(size)
+}
+
+func (c *pgConn) binaryValue(d ion.Datum, typ *pgType) {
+	switch typ {
+	case pgBool:
+		if b, _ := d.Bool(); b {
+			c.byte(1)
+		} else {
+			c.byte(0)
+		}
+	case pgInt8:
+		c.msg = binary.BigEndian.AppendUint64(c.msg, uint64(pgInt(d)))
+	case pgFloat8:
+		c.msg = binary.BigEndian.AppendUint64(c.msg, math.Float64bits(pgFloat(d)))
+	case pgTimestamptz:
+		t, _ := d.Timestamp()
+		c.msg = binary.BigEndian.AppendUint64(c.msg, uint64(t.UnixMicro()-pgEpoch))
+	default:
+		c.msg = append(c.msg, pgTextValue(d, typ)...)
+	}
+}
+
+func pgInt(d ion.Datum) int64 {
+	if d.IsUint() {
+		u, _ := d.Uint()
+		return int64(u)
+	}
+	i, _ := d.Int()
+	return i
+}
+
+func pgFloat(d ion.Datum) float64 {
+	switch d.Type() {
+	case ion.IntType, ion.UintType:
+		return float64(pgInt(d))
+	}
+	f, _ := d.Float()
+	return f
+}
+
+// pgTextValue returns the text representation
+// of d in a column of type typ
+func pgTextValue(d ion.Datum, typ *pgType) string {
+	switch d.Type() {
+	case ion.StringType, ion.SymbolType:
+		if typ == pgJSON {
+			return d.JSON()
+		}
+		s, _ := d.String()
+		return s
+	case ion.BoolType:
+		b, _ := d.Bool()
+		if typ == pgBool {
+			if b {
+				return "t"
+			}
+			return "f"
+		}
+		return strconv.FormatBool(b)
+	case ion.IntType:
+		i, _ := d.Int()
+		return strconv.FormatInt(i, 10)
+	case ion.UintType:
+		u, _ := d.Uint()
+		return strconv.FormatUint(u, 10)
+	case ion.FloatType:
+		f, _ := d.Float()
+		switch {
+		case math.IsNaN(f):
+			return "NaN"
+		case math.IsInf(f, 1):
+			return "Infinity"
+		case math.IsInf(f, -1):
+			return "-Infinity"
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	case ion.TimestampType:
+		t, _ := d.Timestamp()
+		return t.Time().UTC().Format("2006-01-02 15:04:05.999999-07")
+	}
+	return d.JSON()
+}
+
+// pgSkip returns the position following the
+// quoted string, quoted identifier or comment
+// that begins at text[i], or i if there is none
+func pgSkip(text string, i int) int {
+	switch text[i] {
+	case '\'', '"':
+		q := text[i]
+		for j := i + 1; j < len(text); j++ {
+			if text[j] == '\\' {
+				j++ // skip the escaped character
+				continue
+			}
+			if text[j] == q {
+				if j+1 < len(text) && text[j+1] == q {
+					j++
+					continue
+				}
+				return j + 1
+			}
+		}
+		return len(text)
+	case '-':
+		if strings.HasPrefix(text[i:], "--") {
+			if j := strings.IndexByte(text[i:], '\n'); j >= 0 {
+				return i + j + 1
+			}
+			return len(text)
+		}
+	case '/':
+		if strings.HasPrefix(text[i:], "/*") {
+			if j := strings.Index(text[i+2:], "*/"); j >= 0 {
+				return i + 2 + j + 2
+			}
+			return len(text)
+		}
+	}
+	return i
+}
+
+// pgSplit splits text into the
+// statements separated by semicolons,
+// dropping any empty statements
+func pgSplit(text string) []string {
+	var out []string
+	start := 0
+	add := func(stmt string) {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			out = append(out, stmt)
+		}
+	}
+	for i := 0; i < len(text); {
+		if j := pgSkip(text, i); j > i {
+			i = j
+			continue
+		}
+		if text[i] == ';' {
+			add(text[start:i])
+			start = i + 1
+		}
+		i++
+	}
+	add(text[start:])
+	return out
+}
+
+// pgParams calls fn with the position, length and
+// index of each $n parameter placeholder in text
+func pgParams(text string, fn func(pos, size, n int)) {
+	for i := 0; i < len(text); {
+		if j := pgSkip(text, i); j > i {
+			i = j
+			continue
+		}
+		if text[i] != '$' {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(text) && text[j] >= '0' && text[j] <= '9' {
+			j++
+		}
+		if j > i+1 {
+			n, _ := strconv.Atoi(text[i+1 : j])
+			fn(i, j-i, n)
+		}
+		i = j
+	}
+}
+
+// pgCountParams returns the number of
+// parameters referenced by text
+func pgCountParams(text string) int {
+	max := 0
+	pgParams(text, func(_, _, n int) {
+		if n > max {
+			max = n
+		}
+	})
+	return max
+}
+
+// pgSubstitute replaces each $n placeholder
+// in text with the literal lits[n-1]
+func pgSubstitute(text string, lits []string) string {
+	if len(lits) == 0 {
+		return text
+	}
+	var out strings.Builder
+	prev := 0
+	pgParams(text, func(pos, size, n int) {
+		if n < 1 || n > len(lits) {
+			return
+		}
+		out.WriteString(text[prev:pos])
+		out.WriteString(lits[n-1])
+		prev = pos + size
+	})
+	out.WriteString(text[prev:])
+	return out.String()
+}
+
+// pgLiteral converts a parameter value
+// into a PartiQL literal
+func pgLiteral(value []byte, oid uint32, format int16) (string, error) {
+	if value == nil {
+		return "NULL", nil
+	}
+	typ := pgTypeByOID(oid)
+	if format == 1 {
+		switch {
+		case typ == pgBool && len(value) == 1:
+			return strconv.FormatBool(value[0] != 0), nil
+		case typ == pgInt2 && len(value) == 2:
+			return pgIntLiteral(int64(int16(binary.BigEndian.Uint16(value)))), nil
+		case typ == pgInt4 && len(value) == 4:
+			return pgIntLiteral(int64(int32(binary.BigEndian.Uint32(value)))), nil
+		case typ == pgInt8 && len(value) == 8:
+			return pgIntLiteral(int64(binary.BigEndian.Uint64(value))), nil
+		case typ == pgFloat4 && len(value) == 4:
+			return pgFloatLiteral(float64(math.Float32frombits(binary.BigEndian.Uint32(value))))
+		case typ == pgFloat8 && len(value) == 8:
+			return pgFloatLiteral(math.Float64frombits(binary.BigEndian.Uint64(value)))
+		case typ == pgText || typ == pgVarchar || typ == nil:
+			return expr.ToString(expr.String(value)), nil
+		}
+		return "", pgErrorf(pgCodeParameter, "unsupported binary format for parameter of type %d", oid)
+	}
+	str := string(value)
+	switch typ {
+	case pgBool:
+		switch strings.ToLower(strings.TrimSpace(str)) {
+		case "t", "true", "y", "yes", "on", "1":
+			return "TRUE", nil
+		case "f", "false", "n", "no", "off", "0":
+			return "FALSE", nil
+		}
+	case pgInt2, pgInt4, pgInt8:
+		if i, err := strconv.ParseInt(strings.TrimSpace(str), 10, 64); err == nil {
+			return pgIntLiteral(i), nil
+		}
+	case pgFloat4, pgFloat8, pgNumeric:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(str), 64); err == nil {
+			return pgFloatLiteral(f)
+		}
+	default:
+		return expr.ToString(expr.String(str)), nil
+	}
+	return "", pgErrorf(pgCodeParameter, "invalid input syntax for type %s: %q", typ.name, str)
+}
+
+func pgIntLiteral(i int64) string {
+	return pgSigned(strconv.FormatInt(i, 10))
+}
+
+func pgFloatLiteral(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", pgErrorf(pgCodeParameter, "unsupported parameter value %g", f)
+	}
+	return pgSigned(strconv.FormatFloat(f, 'f', -1, 64)), nil
+}
+
+// pgSigned parenthesizes a negative number,
+// which could otherwise form a comment with
+// a preceding minus sign (as in a-$1)
+func pgSigned(num string) string {
+	if strings.HasPrefix(num, "-") {
+		return "(" + num + ")"
+	}
+	return num
+}
@@ -0,0 +1,616 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/tenant"
+)
+
+// pgClient is a minimal PostgreSQL protocol client
+type pgClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// pgMsg builds a frontend message
+type pgMsg []byte
+
+func (m pgMsg) int16(i int16) pgMsg { return binary.BigEndian.AppendUint16(m, uint16(i)) }
+func (m pgMsg) int32(i int32) pgMsg { return binary.BigEndian.AppendUint32(m, uint32(i)) }
+func (m pgMsg) int64(i int64) pgMsg { return binary.BigEndian.AppendUint64(m, uint64(i)) }
+func (m pgMsg) str(s string) pgMsg  { return append(append(m, s...), 0) }
+
+func (c *pgClient) send(typ byte, body pgMsg) {
+	var msg []byte
+	if typ != 0 {
+		msg = append(msg, typ)
+	}
+	msg = binary.BigEndian.AppendUint32(msg, uint32(len(body)+4))
+	msg = append(msg, body...)
+	if _, err := c.conn.Write(msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *pgClient) recv() (byte, []byte) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	body := make([]byte, binary.BigEndian.Uint32(hdr[1:])-4)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		c.t.Fatal(err)
+	}
+	return hdr[0], body
+}
+
+// pgReply is the set of messages
+// received up to ReadyForQuery
+type pgReply struct {
+	cols  []string
+	types []uint32
+	rows  [][]*string
+	tags  []string
+	codes []string
+	msgs  []string
+}
+
+func (c *pgClient) reply() *pgReply {
+	rep := &pgReply{}
+	for {
+		typ, body := c.recv()
+		r := &pgReader{buf: body}
+		switch typ {
+		case 'Z':
+			return rep
+		case 'T':
+			n := int(r.int16())
+			for i := 0; i < n; i++ {
+				rep.cols = append(rep.cols, r.cstring())
+				r.int32()
+				r.int16()
+				rep.types = append(rep.types, uint32(r.int32()))
+				r.int16()
+				r.int32()
+				r.int16()
+			}
+		case 'D':
+			n := int(r.int16())
+			var row []*string
+			for i := 0; i < n; i++ {
+				size := r.int32()
+				if size < 0 {
+					row = append(row, nil)
+					continue
+				}
+				s := string(r.take(int(size)))
+				row = append(row, &s)
+			}
+			rep.rows = append(rep.rows, row)
+		case 'C':
+			rep.tags = append(rep.tags, r.cstring())
+		case 'I':
+			rep.tags = append(rep.tags, "")
+		case 'E':
+			for {
+				code := r.byte()
+				if code == 0 {
+					break
+				}
+				v := r.cstring()
+				switch code {
+				case 'C':
+					rep.codes = append(rep.codes, v)
+				case 'M':
+					rep.msgs = append(rep.msgs, v)
+				}
+			}
+		}
+		if r.err != nil {
+			c.t.Fatalf("message %q: %s", typ, r.err)
+		}
+	}
+}
+
+// strs returns the rows of rep as strings
+func (r *pgReply) strs() [][]string {
+	var out [][]string
+	for _, row := range r.rows {
+		var lst []string
+		for _, v := range row {
+			if v == nil {
+				lst = append(lst, "NULL")
+			} else {
+				lst = append(lst, *v)
+			}
+		}
+		out = append(out, lst)
+	}
+	return out
+}
+
+func (c *pgClient) query(text string) *pgReply {
+	c.send('Q', pgMsg(nil).str(text))
+	return c.reply()
+}
+
+func pgConnect(t *testing.T, addr, password string) (*pgClient, []string) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pgLogin(t, conn, password)
+}
+
+// pgLogin performs the startup handshake on conn
+func pgLogin(t *testing.T, conn net.Conn, password string) (*pgClient, []string) {
+	c := &pgClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	c.send(0, pgMsg(nil).int32(pgProtocolVersion).
+		str("user").str("tester").
+		str("database").str("default").
+		str("application_name").str("pgwire_test").
+		str(""))
+	typ, body := c.recv()
+	if typ != 'R' || binary.BigEndian.Uint32(body) != 3 {
+		t.Fatalf("expected cleartext password request; got %q", typ)
+	}
+	c.send('p', pgMsg(nil).str(password))
+	typ, body = c.recv()
+	if typ == 'E' {
+		rep := &pgReply{}
+		r := &pgReader{buf: body}
+		for code := r.byte(); code != 0; code = r.byte() {
+			v := r.cstring()
+			if code == 'C' {
+				rep.codes = append(rep.codes, v)
+			}
+		}
+		return nil, rep.codes
+	}
+	if typ != 'R' || binary.BigEndian.Uint32(body) != 0 {
+		t.Fatalf("expected authentication ok; got %q", typ)
+	}
+	if rep := c.reply(); len(rep.codes) > 0 {
+		t.Fatalf("startup errors: %v", rep.msgs)
+	}
+	return c, nil
+}
+
+func TestPostgres(t *testing.T) {
+	tt := testdirEnviron(t)
+	s := server{
+		logger:    testlogger(t),
+		sandbox:   tenant.CanSandbox(),
+		cachedir:  t.TempDir(),
+		cgroot:    os.Getenv("CGROOT"),
+		tenantcmd: []string{"./snellerd-test-binary", "worker"},
+		peers:     noPeers{},
+		auth:      testAuth{tt},
+		pgsock:    listen(t),
+	}
+	httpsock := listen(t)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	s.aboutToServe = (&wg).Done
+	go s.Serve(httpsock, nil)
+	wg.Wait()
+	defer s.Close()
+	addr := s.pgsock.Addr().String()
+
+	if _, codes := pgConnect(t, addr, "wrong"); !reflect.DeepEqual(codes, []string{pgCodeAuth}) {
+		t.Fatalf("bad password: got codes %v", codes)
+	}
+	c, _ := pgConnect(t, addr, "snellerd-test")
+
+	check := func(t *testing.T, rep *pgReply, cols []string, types []uint32, rows [][]string, tags ...string) {
+		t.Helper()
+		if len(rep.codes) > 0 {
+			t.Fatalf("errors %v: %v", rep.codes, rep.msgs)
+		}
+		if !reflect.DeepEqual(rep.cols, cols) {
+			t.Errorf("got columns %v; expected %v", rep.cols, cols)
+		}
+		if !reflect.DeepEqual(rep.types, types) {
+			t.Errorf("got types %v; expected %v", rep.types, types)
+		}
+		if got := rep.strs(); !reflect.DeepEqual(got, rows) {
+			t.Errorf("got rows %v; expected %v", got, rows)
+		}
+		if !reflect.DeepEqual(rep.tags, tags) {
+			t.Errorf("got tags %v; expected %v", rep.tags, tags)
+		}
+	}
+
+	t.Run("simple", func(t *testing.T) {
+		rep := c.query("SELECT COUNT(*) AS n, MAX(Ticket) AS m FROM parking")
+		check(t, rep, []string{"n", "m"}, []uint32{20, 20}, [][]string{{"1023", "4272473892"}}, "SELECT 1")
+
+		rep = c.query("SET extra_float_digits = 3; SHOW TimeZone; SELECT current_schema()")
+		check(t, rep, []string{"timezone", "current_schema"}, []uint32{25, 25},
+			[][]string{{"UTC"}, {"default"}}, "SET", "SELECT 1", "SELECT 1")
+
+		rep = c.query(";")
+		check(t, rep, nil, nil, nil, "")
+	})
+	t.Run("heterogeneous", func(t *testing.T) {
+		// column types are determined from the values;
+		// columns with values of different types are text
+		rep := c.query("SELECT Ticket, Fine + 0.5 AS fine, CASE WHEN Ticket = 1103341116 THEN 'x' ELSE Fine END AS mixed, [Ticket] AS lst FROM parking ORDER BY Ticket LIMIT 2")
+		check(t, rep, []string{"Ticket", "fine", "mixed", "lst"}, []uint32{20, 701, 25, 114},
+			[][]string{
+				{"1103341116", "50.5", "x", "[1103341116]"},
+				{"1103700150", "50.5", "50", "[1103700150]"},
+			}, "SELECT 2")
+	})
+	t.Run("catalog", func(t *testing.T) {
+		rep := c.query("SELECT table_schema, table_name FROM information_schema.tables ORDER BY table_name LIMIT 100")
+		check(t, rep, []string{"table_schema", "table_name"}, []uint32{25, 25}, [][]string{
+			{"default", "combined"},
+			{"default", "parking"},
+			{"default", "parking2"},
+			{"default", "taxi"},
+		}, "SELECT 4")
+
+		rep = c.query("SELECT column_name, data_type FROM information_schema.columns WHERE table_name = 'parking' AND column_name IN ('Ticket', 'IssueData', 'Location') ORDER BY column_name LIMIT 100")
+		check(t, rep, []string{"column_name", "data_type"}, []uint32{25, 25}, [][]string{
+			{"IssueData", "timestamp with time zone"},
+			{"Location", "text"},
+			{"Ticket", "bigint"},
+		}, "SELECT 3")
+
+		rep = c.query("SELECT relname FROM pg_class WHERE relnamespace = (SELECT oid FROM pg_namespace WHERE nspname = 'default' LIMIT 1) AND relname LIKE 'parking%' ORDER BY relname LIMIT 100")
+		check(t, rep, []string{"relname"}, []uint32{25}, [][]string{{"parking"}, {"parking2"}}, "SELECT 2")
+	})
+	t.Run("extended", func(t *testing.T) {
+		// a statement that is described before it is
+		// bound uses the column types from the planner,
+		// which are text unless they are known precisely
+		c.send('P', pgMsg(nil).str("stmt").str("SELECT Ticket, COUNT(*) AS n FROM parking WHERE Ticket = $1 GROUP BY Ticket").int16(1).int32(20))
+		c.send('D', pgMsg{'S'}.str("stmt"))
+		c.send('B', pgMsg(nil).str("").str("stmt").
+			int16(1).int16(1). // binary parameters
+			int16(1).int32(8).int64(1103341116).
+			int16(1).int16(0)) // text results
+		c.send('E', pgMsg(nil).str("").int32(0))
+		c.send('S', nil)
+		rep := c.reply()
+		check(t, rep, []string{"Ticket", "n"}, []uint32{25, 25}, [][]string{{"1103341116", "1"}}, "SELECT 1")
+
+		// a portal that is described after it is
+		// bound uses the types of the values
+		c.send('P', pgMsg(nil).str("").str("SELECT Ticket, Make FROM parking WHERE Ticket = $1").int16(1).int32(20))
+		c.send('B', append(pgMsg(nil).str("").str("").int16(0).int16(1).int32(10), "1103341116"...).
+			int16(2).int16(1).int16(0)) // binary Ticket, text Make
+		c.send('D', pgMsg{'P'}.str(""))
+		c.send('E', pgMsg(nil).str("").int32(0))
+		c.send('S', nil)
+		rep = c.reply()
+		if len(rep.codes) > 0 {
+			t.Fatalf("errors %v: %v", rep.codes, rep.msgs)
+		}
+		if !reflect.DeepEqual(rep.types, []uint32{20, 25}) {
+			t.Fatalf("types %v", rep.types)
+		}
+		if len(rep.rows) != 1 || len(rep.rows[0]) != 2 {
+			t.Fatalf("rows %v", rep.strs())
+		}
+		if ticket := int64(binary.BigEndian.Uint64([]byte(*rep.rows[0][0]))); ticket != 1103341116 {
+			t.Errorf("ticket %d", ticket)
+		}
+		if make := *rep.rows[0][1]; make != "HOND" {
+			t.Errorf("make %q", make)
+		}
+
+		// rows may be fetched a few at a time
+		c.send('P', pgMsg(nil).str("").str("SELECT Ticket FROM parking ORDER BY Ticket LIMIT $1").int16(1).int32(23))
+		c.send('B', append(pgMsg(nil).str("p").str("").int16(0).int16(1).int32(1), '3').int16(0))
+		c.send('D', pgMsg{'P'}.str("p"))
+		c.send('E', pgMsg(nil).str("p").int32(2))
+		c.send('E', pgMsg(nil).str("p").int32(2))
+		c.send('S', nil)
+		rep = c.reply()
+		check(t, rep, []string{"Ticket"}, []uint32{20}, [][]string{{"1103341116"}, {"1103700150"}, {"1104803000"}}, "SELECT 3")
+	})
+	t.Run("errors", func(t *testing.T) {
+		rep := c.query("SELECT FROM WHERE")
+		if !reflect.DeepEqual(rep.codes, []string{pgCodeSyntax}) {
+			t.Errorf("syntax error: got %v %v", rep.codes, rep.msgs)
+		}
+		rep = c.query("SELECT * FROM no_such_table")
+		if !reflect.DeepEqual(rep.codes, []string{pgCodeUndefined}) {
+			t.Errorf("missing table: got %v %v", rep.codes, rep.msgs)
+		}
+		// messages following an error are
+		// ignored until the next Sync
+		c.send('P', pgMsg(nil).str("").str("SELECT Ticket FROM parking LIMIT $1").int16(0))
+		c.send('B', pgMsg(nil).str("").str("").int16(0).int16(0).int16(0))
+		c.send('E', pgMsg(nil).str("").int32(0))
+		c.send('S', nil)
+		rep = c.reply()
+		if !reflect.DeepEqual(rep.codes, []string{pgCodeProtocol}) || len(rep.tags) != 0 {
+			t.Errorf("after error: got %v %v", rep.codes, rep.tags)
+		}
+		// the connection is still usable
+		rep = c.query("SELECT 1 AS x")
+		check(t, rep, []string{"x"}, []uint32{20}, [][]string{{"1"}}, "SELECT 1")
+	})
+	c.send('X', nil)
+}
+
+// deadlineConn records the deadlines
+// set on a connection
+type deadlineConn struct {
+	net.Conn
+	deadlines []time.Time
+}
+
+func (d *deadlineConn) SetDeadline(t time.Time) error {
+	d.deadlines = append(d.deadlines, t)
+	return d.Conn.SetDeadline(t)
+}
+
+func TestPostgresStartupLimits(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	conn := &deadlineConn{Conn: server}
+	c := &pgConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.startup()
+		server.Close()
+		errc <- err
+	}()
+
+	pc := &pgClient{t: t, conn: client, r: bufio.NewReader(client)}
+	pc.send(0, pgMsg(nil).int32(pgProtocolVersion).str("user").str("tester").str(""))
+	if typ, _ := pc.recv(); typ != 'R' {
+		t.Fatalf("expected cleartext password request; got %q", typ)
+	}
+	// the password message is rejected based on
+	// its header alone, before it is authenticated
+	if _, err := client.Write(pgMsg{'p'}.int32(pgMaxStartupMessage + 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err == nil {
+		t.Fatal("oversized password message accepted")
+	}
+	if len(conn.deadlines) != 2 {
+		t.Fatalf("got deadlines %v", conn.deadlines)
+	}
+	if d := time.Until(conn.deadlines[0]); d <= 0 || d > pgStartupTimeout {
+		t.Errorf("handshake deadline in %s, want at most %s", d, pgStartupTimeout)
+	}
+	if !conn.deadlines[1].IsZero() {
+		t.Errorf("deadline %s not cleared after the handshake", conn.deadlines[1])
+	}
+}
+
+func TestPostgresResultLimit(t *testing.T) {
+	tt := testdirEnviron(t)
+	s, _ := startServer(t, func(s *server) {
+		s.auth = testAuth{tt}
+		s.pgsock = listen(t)
+		s.pgResultMax = 512
+	})
+	c, _ := pgConnect(t, s.pgsock.Addr().String(), "snellerd-test")
+	defer c.send('X', nil)
+
+	// the statement fails rather than
+	// buffering the whole result
+	rep := c.query("SELECT * FROM parking")
+	if !reflect.DeepEqual(rep.codes, []string{pgCodeLimit}) || len(rep.rows) != 0 {
+		t.Fatalf("got codes %v, %d rows", rep.codes, len(rep.rows))
+	}
+	rep = c.query("SELECT column_name FROM information_schema.columns LIMIT 10000")
+	if !reflect.DeepEqual(rep.codes, []string{pgCodeLimit}) {
+		t.Fatalf("catalog query: got codes %v", rep.codes)
+	}
+	// and the connection is still usable
+	rep = c.query("SELECT COUNT(*) AS n FROM parking")
+	if len(rep.codes) > 0 || !reflect.DeepEqual(rep.strs(), [][]string{{"1023"}}) {
+		t.Fatalf("got %v %v", rep.codes, rep.strs())
+	}
+}
+
+func TestPostgresCatalogAdmission(t *testing.T) {
+	tt := &configuredTenant{
+		Tenant: testdirEnviron(t),
+		cfg: &db.TenantConfig{
+			MaxConcurrentQueries: 1,
+			MaxQueuedQueries:     1,
+			MaxQueryDuration:     100 * time.Millisecond,
+		},
+	}
+	s, _ := startServer(t, func(s *server) {
+		s.auth = testAuth{tt}
+		s.pgsock = listen(t)
+	})
+	c, _ := pgConnect(t, s.pgsock.Addr().String(), "snellerd-test")
+	defer c.send('X', nil)
+
+	// occupy the only query slot of the tenant
+	release, err := s.quotas.admit(context.Background(), tt.ID(), tt.cfg, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// queries that are executed in-process
+	// wait for admission like any other query
+	rep := c.query("SELECT 1 AS x")
+	if !reflect.DeepEqual(rep.codes, []string{pgCodeCanceled}) || !strings.Contains(rep.msgs[0], "statement timeout") {
+		t.Fatalf("got %v %v", rep.codes, rep.msgs)
+	}
+	// and can be canceled while they wait
+	tt.cfg.MaxQueryDuration = 0
+	c.send('Q', pgMsg(nil).str("SELECT 1 AS x"))
+	for canceled := false; !canceled; time.Sleep(time.Millisecond) {
+		s.pg.lock.Lock()
+		for _, conn := range s.pg.conns {
+			conn.lock.Lock()
+			canceled = conn.cancel != nil
+			conn.lock.Unlock()
+			if canceled {
+				conn.cancelQuery()
+			}
+		}
+		s.pg.lock.Unlock()
+	}
+	rep = c.reply()
+	if !reflect.DeepEqual(rep.codes, []string{pgCodeCanceled}) || !strings.Contains(rep.msgs[0], "user request") {
+		t.Fatalf("got %v %v", rep.codes, rep.msgs)
+	}
+	release(0)
+	rep = c.query("SELECT 1 AS x")
+	if len(rep.codes) > 0 || !reflect.DeepEqual(rep.strs(), [][]string{{"1"}}) {
+		t.Fatalf("got %v %v", rep.codes, rep.strs())
+	}
+}
+
+// testCert returns a self-signed certificate for localhost
+func testCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestPostgresTLS(t *testing.T) {
+	tt := testdirEnviron(t)
+	s, _ := startServer(t, func(s *server) {
+		s.auth = testAuth{tt}
+		s.pgsock = listen(t)
+		s.pgTLS = &tls.Config{Certificates: []tls.Certificate{testCert(t)}}
+	})
+	addr := s.pgsock.Addr().String()
+
+	// unencrypted connections are refused
+	// before the client sends its password
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := &pgClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	c.send(0, pgMsg(nil).int32(pgProtocolVersion).str("user").str("tester").str(""))
+	if typ, body := c.recv(); typ != 'E' || !strings.Contains(string(body), pgCodeInvalidAuth) {
+		t.Fatalf("got %q %q", typ, body)
+	}
+
+	conn, err = net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write(pgMsg(nil).int32(8).int32(pgSSLRequest)); err != nil {
+		t.Fatal(err)
+	}
+	var answer [1]byte
+	if _, err := io.ReadFull(conn, answer[:]); err != nil || answer[0] != 'S' {
+		t.Fatalf("got %q %v", answer[0], err)
+	}
+	tc := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	c, _ = pgLogin(t, tc, "snellerd-test")
+	if c == nil {
+		t.Fatal("authentication failed")
+	}
+	rep := c.query("SELECT COUNT(*) AS n FROM parking")
+	if len(rep.codes) > 0 || !reflect.DeepEqual(rep.strs(), [][]string{{"1023"}}) {
+		t.Fatalf("got %v %v", rep.codes, rep.strs())
+	}
+	c.send('X', nil)
+}
+
+func TestPostgresSubstitute(t *testing.T) {
+	lit := func(value string, oid uint32) string {
+		s, err := pgLiteral([]byte(value), oid, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	tcs := []struct {
+		text string
+		lits []string
+		want string
+	}{
+		// a negative number must not turn
+		// the preceding minus into a comment
+		{
+			text: "SELECT a-$1 FROM t",
+			lits: []string{lit("-1", pgInt8.oid)},
+			want: "SELECT a-(-1) FROM t",
+		},
+		{
+			text: "SELECT a-$1, b - $2 FROM t",
+			lits: []string{lit("-1.5", pgFloat8.oid), lit("2", pgInt4.oid)},
+			want: "SELECT a-(-1.5), b - 2 FROM t",
+		},
+		// an escaped quote does not end the string
+		{
+			text: `SELECT 'a\'$1' AS x, $1 AS y`,
+			lits: []string{lit("z", pgText.oid)},
+			want: `SELECT 'a\'$1' AS x, 'z' AS y`,
+		},
+		{
+			text: `SELECT "a\"$1" FROM t WHERE x = $1`,
+			lits: []string{lit("1", pgInt8.oid)},
+			want: `SELECT "a\"$1" FROM t WHERE x = 1`,
+		},
+	}
+	for i := range tcs {
+		got := pgSubstitute(tcs[i].text, tcs[i].lits)
+		if got != tcs[i].want {
+			t.Errorf("%q: got %q, want %q", tcs[i].text, got, tcs[i].want)
+			continue
+		}
+		if _, err := partiql.Parse([]byte(got)); err != nil {
+			t.Errorf("%q: %s", got, err)
+		}
+	}
+}
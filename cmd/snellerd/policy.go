@@ -0,0 +1,551 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+
+	"golang.org/x/exp/slices"
+)
+
+// policyRefresh is how long a policy read
+// from the storage of a tenant is used
+// before it is read again
+const policyRefresh = time.Minute
+
+// errAccessDenied is returned when a query
+// references a table or a column that the
+// principal has not been granted
+var errAccessDenied = errors.New("access denied")
+
+// grant allows access to the tables that match
+// Table in the databases that match Database
+// (both of which are path.Match patterns)
+type grant struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	// Columns, if non-empty, are the only
+	// top-level columns that can be read
+	Columns []string `json:"columns,omitempty"`
+	// Mask are columns that can be referenced,
+	// but that always read as NULL; the columns
+	// that are not in Columns or Mask cannot be
+	// referenced at all, so Mask requires Columns
+	Mask []string `json:"mask,omitempty"`
+	// Filter, if present, is a predicate that
+	// the rows of the table must satisfy
+	Filter string `json:"filter,omitempty"`
+
+	filter expr.Node
+}
+
+// policy maps principals to roles
+// and roles to grants
+type policy struct {
+	// Principals maps principals
+	// (see principalOf) to roles
+	Principals map[string][]string `json:"principals"`
+	// DefaultRoles are the roles of
+	// the principals not in Principals
+	DefaultRoles []string `json:"default_roles,omitempty"`
+	// Roles maps roles to grants
+	Roles map[string][]grant `json:"roles"`
+}
+
+// parseFilter parses the row filter of a grant
+func parseFilter(text string) (expr.Node, error) {
+	q, err := partiql.Parse([]byte("SELECT * FROM t WHERE " + text))
+	if err != nil {
+		return nil, err
+	}
+	sel, ok := q.Body.(*expr.Select)
+	if !ok || len(q.With) != 0 || sel.Where == nil || sel.GroupBy != nil ||
+		sel.OrderBy != nil || sel.Limit != nil || sel.Offset != nil {
+		return nil, fmt.Errorf("invalid filter %q", text)
+	}
+	return sel.Where, nil
+}
+
+func parsePolicy(buf []byte) (*policy, error) {
+	p := new(policy)
+	d := json.NewDecoder(bytes.NewReader(buf))
+	d.DisallowUnknownFields()
+	if err := d.Decode(p); err != nil {
+		return nil, fmt.Errorf("parsing policy: %w", err)
+	}
+	for name, grants := range p.Roles {
+		for i := range grants {
+			g := &grants[i]
+			if g.Database == "" || g.Table == "" {
+				return nil, fmt.Errorf("role %q: grant %d: database and table are required", name, i)
+			}
+			if _, err := path.Match(g.Database, ""); err != nil {
+				return nil, fmt.Errorf("role %q: grant %d: database: %w", name, i, err)
+			}
+			if _, err := path.Match(g.Table, ""); err != nil {
+				return nil, fmt.Errorf("role %q: grant %d: table: %w", name, i, err)
+			}
+			if len(g.Mask) > 0 && len(g.Columns) == 0 {
+				return nil, fmt.Errorf("role %q: grant %d: mask requires columns", name, i)
+			}
+			for _, c := range g.Mask {
+				if slices.Contains(g.Columns, c) {
+					return nil, fmt.Errorf("role %q: grant %d: column %q is both granted and masked", name, i, c)
+				}
+			}
+			if g.Filter != "" {
+				f, err := parseFilter(g.Filter)
+				if err != nil {
+					return nil, fmt.Errorf("role %q: grant %d: %w", name, i, err)
+				}
+				g.filter = f
+			}
+		}
+	}
+	return p, nil
+}
+
+// access returns the access that p
+// grants to a principal
+func (p *policy) access(principal string) *access {
+	roles, ok := p.Principals[principal]
+	if !ok {
+		roles = p.DefaultRoles
+	}
+	a := &access{}
+	for _, r := range roles {
+		a.grants = append(a.grants, p.Roles[r]...)
+	}
+	return a
+}
+
+// access is the union of the grants of the
+// roles of a principal. A nil *access is
+// unrestricted, which is the case when
+// there is no policy.
+type access struct {
+	grants []grant
+}
+
+// tableAccess is the access to one table
+type tableAccess struct {
+	// all is set if all the columns can be read;
+	// otherwise only columns can be read and
+	// mask are the columns that read as NULL
+	all     bool
+	columns []string
+	mask    []string
+	// filter is the row filter, or nil
+	filter expr.Node
+}
+
+func (t *tableAccess) restricted() bool {
+	return !t.all || t.filter != nil
+}
+
+func (a *access) matching(dbname, table string) []*grant {
+	var out []*grant
+	for i := range a.grants {
+		g := &a.grants[i]
+		if ok, _ := path.Match(g.Database, dbname); !ok {
+			continue
+		}
+		if ok, _ := path.Match(g.Table, table); ok {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// allowed returns whether any
+// access to a table is granted
+func (a *access) allowed(dbname, table string) bool {
+	return a == nil || len(a.matching(dbname, table)) > 0
+}
+
+// table returns the combined access to a table
+// granted by all of the grants that match it;
+// a row or column is accessible if any grant
+// makes it accessible
+func (a *access) table(dbname, table string) (tableAccess, bool) {
+	if a == nil {
+		return tableAccess{all: true}, true
+	}
+	grants := a.matching(dbname, table)
+	if len(grants) == 0 {
+		return tableAccess{}, false
+	}
+	var t tableAccess
+	filtered := true
+	for _, g := range grants {
+		if len(g.Columns) == 0 {
+			t.all = true
+		}
+		t.columns = append(t.columns, g.Columns...)
+		t.mask = append(t.mask, g.Mask...)
+		if g.filter == nil {
+			filtered = false
+		} else if t.filter == nil {
+			t.filter = g.filter
+		} else {
+			t.filter = expr.Or(t.filter, g.filter)
+		}
+	}
+	if !filtered {
+		t.filter = nil
+	}
+	if t.all {
+		t.columns, t.mask = nil, nil
+		return t, true
+	}
+	slices.Sort(t.columns)
+	t.columns = slices.Compact(t.columns)
+	slices.Sort(t.mask)
+	t.mask = slices.Compact(t.mask)
+	masked := t.mask[:0]
+	for _, c := range t.mask {
+		// a column that is granted by
+		// any grant is not masked
+		if _, found := slices.BinarySearch(t.columns, c); !found {
+			masked = append(masked, c)
+		}
+	}
+	t.mask = masked
+	return t, true
+}
+
+// unrestricted returns whether all of the tables
+// of a database can be read without restrictions,
+// which is required for TABLE_GLOB and TABLE_PATTERN
+func (a *access) unrestricted(dbname string) bool {
+	if a == nil {
+		return true
+	}
+	for i := range a.grants {
+		g := &a.grants[i]
+		if ok, _ := path.Match(g.Database, dbname); ok &&
+			g.Table == "*" && len(g.Columns) == 0 && g.filter == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// tableName returns the database and table
+// that a table expression references
+func tableName(e expr.Node, dbname string) (string, string, bool) {
+	p, ok := expr.FlatPath(e)
+	switch {
+	case !ok:
+		return "", "", false
+	case len(p) == 1:
+		return dbname, p[0], true
+	case len(p) == 2:
+		return p[0], p[1], true
+	}
+	return "", "", false
+}
+
+// restrict returns the expression that replaces
+// a reference to a table with restricted access:
+//
+//	(SELECT <columns>, NULL AS <mask>... FROM <table> WHERE <filter>)
+func restrict(tbl expr.Node, t *tableAccess) *expr.Select {
+	sel := &expr.Select{
+		From: &expr.Table{Binding: expr.Bind(tbl, "")},
+	}
+	if t.filter != nil {
+		sel.Where = expr.Copy(t.filter)
+	}
+	if t.all {
+		sel.Columns = []expr.Binding{expr.Bind(expr.Star{}, "")}
+		return sel
+	}
+	for _, c := range t.columns {
+		sel.Columns = append(sel.Columns, expr.Bind(expr.Ident(c), ""))
+	}
+	for _, c := range t.mask {
+		sel.Columns = append(sel.Columns, expr.Bind(expr.Null{}, c))
+	}
+	return sel
+}
+
+// policyRewriter replaces the references
+// to tables in a query with subqueries
+// that enforce the access to the tables
+type policyRewriter struct {
+	access *access
+	db     string
+	// ctes are the names of the
+	// CTEs that are in scope
+	ctes []string
+	err  error
+}
+
+func (r *policyRewriter) fail(err error) expr.Node {
+	if r.err == nil {
+		r.err = err
+	}
+	return nil
+}
+
+func (r *policyRewriter) Walk(e expr.Node) expr.Rewriter {
+	if r.err != nil {
+		return nil
+	}
+	return r
+}
+
+func (r *policyRewriter) Rewrite(e expr.Node) expr.Node {
+	if r.err != nil {
+		return e
+	}
+	switch e := e.(type) {
+	case *expr.Table:
+		return r.rewriteTable(e)
+	case *expr.Join:
+		// the right-hand side of a join is a binding
+		// rather than a table, but it can reference
+		// a table just the same, unless it is a path
+		// into one of the tables on the left-hand side
+		if !correlated(e) {
+			if err := r.table(&e.Right); err != nil {
+				r.fail(err)
+			}
+		}
+	}
+	return e
+}
+
+// correlated returns whether the right-hand
+// side of j is a path rooted at one of
+// the bindings on the left-hand side
+func correlated(j *expr.Join) bool {
+	p, ok := expr.FlatPath(j.Right.Expr)
+	if !ok {
+		return false
+	}
+	for _, b := range j.Left.Tables() {
+		if b.Result() == p[0] {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *policyRewriter) rewriteTable(t *expr.Table) expr.Node {
+	_, sub := t.Expr.(*expr.Select)
+	if err := r.table(&t.Binding); err != nil {
+		r.fail(err)
+	}
+	// sample the restricted table
+	// rather than the subquery
+	if sel, ok := t.Expr.(*expr.Select); ok && !sub && t.Sample != nil {
+		sel.From.(*expr.Table).Sample = t.Sample
+		t.Sample = nil
+	}
+	return t
+}
+
+func (r *policyRewriter) table(b *expr.Binding) error {
+	switch e := b.Expr.(type) {
+	case *expr.Select:
+		// already rewritten
+		return nil
+	case expr.Ident:
+		if slices.Contains(r.ctes, string(e)) {
+			return nil
+		}
+	case *expr.Appended:
+		// each of the tables must be unrestricted
+		for i := range e.Values {
+			dbname, table, ok := tableName(e.Values[i], r.db)
+			if !ok {
+				continue
+			}
+			t, ok := r.access.table(dbname, table)
+			if !ok || t.restricted() {
+				return fmt.Errorf("%w: %s.%s", errAccessDenied, dbname, table)
+			}
+		}
+		return nil
+	case *expr.Builtin:
+		switch e.Func {
+		case expr.TableGlob, expr.TablePattern:
+			dbname := r.db
+			if len(e.Args) == 1 {
+				if p, ok := expr.FlatPath(e.Args[0]); ok && len(p) == 2 {
+					dbname = p[0]
+				}
+			}
+			if !r.access.unrestricted(dbname) {
+				return fmt.Errorf("%w: %s in %s", errAccessDenied, e.Func, dbname)
+			}
+		}
+		return nil
+	}
+	dbname, table, ok := tableName(b.Expr, r.db)
+	if !ok {
+		// not a table; the planner
+		// will reject it if necessary
+		return nil
+	}
+	t, ok := r.access.table(dbname, table)
+	if !ok {
+		return fmt.Errorf("%w: %s.%s", errAccessDenied, dbname, table)
+	}
+	if !t.restricted() {
+		return nil
+	}
+	// preserve the implicit binding
+	// of the table (e.g. FROM t WHERE t.x ...)
+	as := b.Result()
+	b.Expr = restrict(b.Expr, &t)
+	b.As(as)
+	return nil
+}
+
+// rewrite enforces a on q, which is
+// planned with dbname as its default database
+func (a *access) rewrite(q *expr.Query, dbname string) error {
+	if a == nil {
+		return nil
+	}
+	r := &policyRewriter{access: a, db: dbname}
+	for i := range q.With {
+		q.With[i].As = expr.Rewrite(r, q.With[i].As).(*expr.Select)
+		r.ctes = append(r.ctes, q.With[i].Table)
+	}
+	q.Body = expr.Rewrite(r, q.Body)
+	return r.err
+}
+
+// policySource provides the policy of each tenant:
+// the policy stored in the tenant's storage at path,
+// if there is one, or otherwise the policy in file
+type policySource struct {
+	file *policy
+	path string
+
+	lock  sync.Mutex
+	cache map[string]*cachedPolicy
+}
+
+type cachedPolicy struct {
+	policy *policy
+	loaded time.Time
+}
+
+// load loads the policy at fileName
+func (ps *policySource) load(fileName string) error {
+	buf, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	p, err := parsePolicy(buf)
+	if err != nil {
+		return err
+	}
+	ps.file = p
+	return nil
+}
+
+func (ps *policySource) enabled() bool {
+	return ps.file != nil || ps.path != ""
+}
+
+// get returns the policy of a tenant,
+// or nil if access is unrestricted
+func (ps *policySource) get(creds db.Tenant) (*policy, error) {
+	if ps.path == "" {
+		return ps.file, nil
+	}
+	id := creds.ID()
+	ps.lock.Lock()
+	c := ps.cache[id]
+	ps.lock.Unlock()
+	if c != nil && time.Since(c.loaded) < policyRefresh {
+		if c.policy != nil {
+			return c.policy, nil
+		}
+		return ps.file, nil
+	}
+	root, err := creds.Root()
+	if err != nil {
+		return nil, err
+	}
+	c = &cachedPolicy{loaded: time.Now()}
+	buf, err := fs.ReadFile(root, ps.path)
+	if err == nil {
+		c.policy, err = parsePolicy(buf)
+	} else if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading policy of tenant %s: %w", id, err)
+	}
+	ps.lock.Lock()
+	if ps.cache == nil {
+		ps.cache = make(map[string]*cachedPolicy)
+	}
+	ps.cache[id] = c
+	ps.lock.Unlock()
+	if c.policy != nil {
+		return c.policy, nil
+	}
+	return ps.file, nil
+}
+
+// access returns the access of principal
+// to the tables of a tenant, or nil
+// if access is unrestricted
+func (ps *policySource) access(creds db.Tenant, principal string) (*access, error) {
+	if !ps.enabled() {
+		return nil, nil
+	}
+	p, err := ps.get(creds)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	return p.access(principal), nil
+}
+
+// accessOf returns the access of the principal
+// making a request to the tables of a tenant;
+// if the policy can't be loaded, it writes
+// an error response and returns false
+func (s *server) accessOf(w http.ResponseWriter, r *http.Request, creds db.Tenant) (*access, bool) {
+	token, _ := bearerToken(r)
+	a, err := s.policy.access(creds, principalOf(creds, token))
+	if err != nil {
+		s.logger.Printf("%s", err)
+		http.Error(w, "cannot load access policy", http.StatusInternalServerError)
+		return nil, false
+	}
+	return a, true
+}
@@ -0,0 +1,242 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/expr/partiql"
+)
+
+const testPolicy = `{
+  "principals": {"alice": ["admin"], "bob": ["analyst", "auditor"]},
+  "default_roles": ["guest"],
+  "roles": {
+    "admin": [{"database": "*", "table": "*"}],
+    "analyst": [
+      {"database": "default", "table": "parking*", "columns": ["Make", "Color"], "mask": ["BodyStyle"], "filter": "Make = 'HOND'"},
+      {"database": "other", "table": "*"}
+    ],
+    "auditor": [{"database": "default", "table": "parking", "columns": ["BodyStyle"], "filter": "Color = 'BK'"}],
+    "guest": [{"database": "default", "table": "taxi", "filter": "fare > 0"}]
+  }
+}`
+
+func TestParsePolicy(t *testing.T) {
+	if _, err := parsePolicy([]byte(testPolicy)); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{
+		`{"roles": {"r": [{"table": "t"}]}}`,
+		`{"roles": {"r": [{"database": "d", "table": "["}]}}`,
+		`{"roles": {"r": [{"database": "d", "table": "t", "mask": ["x"]}]}}`,
+		`{"roles": {"r": [{"database": "d", "table": "t", "columns": ["x"], "mask": ["x"]}]}}`,
+		`{"roles": {"r": [{"database": "d", "table": "t", "filter": "x = "}]}}`,
+		`{"roles": {"r": [{"database": "d", "table": "t", "filter": "x = 1 LIMIT 1"}]}}`,
+		`{"roles": {}, "unknown": true}`,
+	} {
+		if _, err := parsePolicy([]byte(bad)); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestPolicyRewrite(t *testing.T) {
+	p, err := parsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcs := []struct {
+		principal, query, want string
+		denied                 bool
+	}{
+		{
+			principal: "alice",
+			query:     "SELECT * FROM parking WHERE x = 1",
+			want:      "SELECT * FROM parking WHERE x = 1",
+		},
+		{
+			principal: "alice",
+			query:     "SELECT * FROM TABLE_GLOB(\"park*\")",
+			want:      "SELECT * FROM TABLE_GLOB(park*)",
+		},
+		{
+			principal: "bob",
+			query:     "SELECT COUNT(*) FROM parking2",
+			want:      "SELECT COUNT(*) FROM (SELECT Color, Make, NULL AS BodyStyle FROM parking2 WHERE Make = 'HOND') AS parking2",
+		},
+		{
+			// the grants of both roles are combined
+			principal: "bob",
+			query:     "SELECT p.Make FROM default.parking AS p",
+			want:      "SELECT p.Make FROM (SELECT BodyStyle, Color, Make FROM default.parking WHERE Make = 'HOND' OR Color = 'BK') AS p",
+		},
+		{
+			// the restricted table is sampled
+			principal: "bob",
+			query:     "SELECT COUNT(*) FROM parking2 TABLESAMPLE SYSTEM (10)",
+			want:      "SELECT COUNT(*) FROM (SELECT Color, Make, NULL AS BodyStyle FROM parking2 TABLESAMPLE SYSTEM (10) WHERE Make = 'HOND') AS parking2",
+		},
+		{
+			principal: "bob",
+			query:     "WITH parking AS (SELECT * FROM other.t) SELECT * FROM parking",
+			want:      "WITH parking AS (SELECT * FROM other.t) SELECT * FROM parking",
+		},
+		{
+			principal: "bob",
+			query:     "SELECT * FROM other.t WHERE x IN (SELECT Make FROM parking2)",
+			want:      "SELECT * FROM other.t WHERE IN_SUBQUERY(x, (SELECT Make FROM (SELECT Color, Make, NULL AS BodyStyle FROM parking2 WHERE Make = 'HOND') AS parking2))",
+		},
+		{
+			principal: "bob",
+			query:     "SELECT * FROM taxi",
+			denied:    true,
+		},
+		{
+			principal: "bob",
+			query:     "SELECT * FROM TABLE_GLOB(\"park*\")",
+			denied:    true,
+		},
+		{
+			principal: "bob",
+			query:     "SELECT * FROM TABLE_GLOB(other.\"*\")",
+			want:      "SELECT * FROM TABLE_GLOB(other.*)",
+		},
+		{
+			principal: "bob",
+			query:     "SELECT * FROM parking ++ parking2",
+			denied:    true,
+		},
+		{
+			principal: "bob",
+			query:     "SELECT * FROM other.t AS a JOIN taxi AS b ON a.x = b.x",
+			denied:    true,
+		},
+		{
+			principal: "bob",
+			query:     "SELECT * FROM other.t CROSS JOIN taxi",
+			denied:    true,
+		},
+		{
+			principal: "bob",
+			query:     "SELECT * FROM other.t, other.u, taxi",
+			denied:    true,
+		},
+		{
+			// the join target is restricted like a table
+			principal: "bob",
+			query:     "SELECT a.x, p.Make FROM other.t AS a JOIN parking2 AS p ON a.x = p.Make",
+			want:      "SELECT a.x, p.Make FROM other.t AS a JOIN (SELECT Color, Make, NULL AS BodyStyle FROM parking2 WHERE Make = 'HOND') AS p ON a.x = p.Make",
+		},
+		{
+			// paths into the left-hand side are not tables
+			principal: "bob",
+			query:     "SELECT i.x FROM other.t AS a, a.items AS i",
+			want:      "SELECT i.x FROM other.t AS a CROSS JOIN a.items AS i",
+		},
+		{
+			principal: "eve",
+			query:     "SELECT * FROM taxi",
+			want:      "SELECT * FROM (SELECT * FROM taxi WHERE fare > 0) AS taxi",
+		},
+	}
+	for i := range tcs {
+		tc := &tcs[i]
+		q, err := partiql.Parse([]byte(tc.query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = p.access(tc.principal).rewrite(q, "default")
+		if tc.denied {
+			if !errors.Is(err, errAccessDenied) {
+				t.Errorf("%s %q: got error %v", tc.principal, tc.query, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %q: %s", tc.principal, tc.query, err)
+			continue
+		}
+		if got := q.Text(); got != tc.want {
+			t.Errorf("%s %q:\ngot  %s\nwant %s", tc.principal, tc.query, got, tc.want)
+		}
+	}
+
+	// no policy means no restrictions
+	var a *access
+	if !a.allowed("default", "taxi") {
+		t.Error("nil access does not allow a table")
+	}
+}
+
+func TestPolicySource(t *testing.T) {
+	tt := testdirEnviron(t)
+	file, err := parsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := policySource{file: file, path: "policy.json"}
+	// without a policy in the tenant's
+	// storage, the file is used
+	a, err := ps.access(tt, "eve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.allowed("default", "parking") || !a.allowed("default", "taxi") {
+		t.Error("unexpected access from the policy file")
+	}
+
+	root, err := tt.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = root.(db.OutputFS).WriteFile("policy.json", []byte(`{
+  "default_roles": ["r"],
+  "roles": {"r": [{"database": "default", "table": "parking"}]}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the previous result is cached
+	a, _ = ps.access(tt, "eve")
+	if a.allowed("default", "parking") {
+		t.Error("cached policy not used")
+	}
+	ps.cache = nil
+	a, err = ps.access(tt, "eve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.allowed("default", "parking") || a.allowed("default", "taxi") {
+		t.Error("unexpected access from the tenant's policy")
+	}
+
+	_, err = root.(db.OutputFS).WriteFile("policy.json", []byte(`{"roles": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps.cache = nil
+	if _, err := ps.access(tt, "eve"); err == nil {
+		t.Error("expected an error for an invalid policy")
+	}
+
+	// without a policy, access is unrestricted
+	ps = policySource{}
+	if a, err := ps.access(tt, "eve"); a != nil || err != nil {
+		t.Errorf("got %v %v", a, err)
+	}
+}
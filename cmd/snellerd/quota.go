@@ -0,0 +1,411 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller/cgroup"
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/tenant/tnproto"
+)
+
+// maxQueueWait is the maximum amount of time
+// that a query waits for other queries of the
+// same tenant to finish before it is rejected
+const maxQueueWait = time.Minute
+
+// errQuota is returned when a query
+// is rejected due to a tenant limit
+type errQuota struct {
+	reason string
+	// retry is the time after which the
+	// query might be admitted, or zero
+	// if it is not known
+	retry time.Duration
+}
+
+func (e *errQuota) Error() string {
+	return "tenant limit exceeded: " + e.reason
+}
+
+// quotaError writes err as a response
+// if it is an *errQuota, and returns
+// whether or not it did so
+func quotaError(w http.ResponseWriter, err error) bool {
+	var eq *errQuota
+	if !errors.As(err, &eq) {
+		return false
+	}
+	if eq.retry > 0 {
+		secs := int64(math.Ceil(eq.retry.Seconds()))
+		w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	}
+	http.Error(w, eq.Error(), http.StatusTooManyRequests)
+	return true
+}
+
+// tenantConfig returns the configuration
+// of a tenant, or nil if it has none
+func tenantConfig(creds db.Tenant) *db.TenantConfig {
+	if ct, ok := creds.(db.TenantConfigurable); ok {
+		return ct.Config()
+	}
+	return nil
+}
+
+// usage tracks the number of bytes scanned over a
+// sliding window divided into a fixed number of buckets
+type usage struct {
+	width   time.Duration // of each bucket
+	buckets []int64
+	last    int64 // number of the most recent bucket
+}
+
+func newUsage(window time.Duration, buckets int) usage {
+	return usage{
+		width:   window / time.Duration(buckets),
+		buckets: make([]int64, buckets),
+	}
+}
+
+// advance clears the buckets that
+// have fallen out of the window
+func (u *usage) advance(now time.Time) {
+	cur := now.UnixNano() / int64(u.width)
+	n := int64(len(u.buckets))
+	if cur-u.last >= n {
+		for i := range u.buckets {
+			u.buckets[i] = 0
+		}
+	} else {
+		for i := u.last + 1; i <= cur; i++ {
+			u.buckets[i%n] = 0
+		}
+	}
+	if cur > u.last {
+		u.last = cur
+	}
+}
+
+func (u *usage) add(now time.Time, n int64) {
+	u.advance(now)
+	u.buckets[u.last%int64(len(u.buckets))] += n
+}
+
+func (u *usage) total(now time.Time) int64 {
+	u.advance(now)
+	t := int64(0)
+	for _, b := range u.buckets {
+		t += b
+	}
+	return t
+}
+
+// expires returns the amount of time until
+// at least n of the bytes in the window
+// have fallen out of it
+func (u *usage) expires(now time.Time, n int64) time.Duration {
+	u.advance(now)
+	size := int64(len(u.buckets))
+	freed := int64(0)
+	for i := u.last - size + 1; i <= u.last; i++ {
+		freed += u.buckets[(i%size+size)%size]
+		if freed >= n {
+			end := time.Unix(0, (i+size)*int64(u.width))
+			return end.Sub(now)
+		}
+	}
+	return 0
+}
+
+// tenantQuota is the admission
+// state of one tenant
+type tenantQuota struct {
+	running int
+	// queue holds the queries waiting to
+	// run in order; a query's channel is
+	// closed when it has been admitted
+	queue     []chan struct{}
+	hour, day usage
+}
+
+// quotas implements the admission control
+// of the queries of each tenant
+//
+// The state is kept in memory, so the limits
+// apply to the queries submitted to this node
+// only; a tenant whose queries are spread over
+// n nodes may run up to n times as many queries
+// and scan up to n times as many bytes.
+type quotas struct {
+	lock    sync.Mutex
+	tenants map[string]*tenantQuota
+}
+
+func (q *quotas) tenant(tenantID string) *tenantQuota {
+	if q.tenants == nil {
+		q.tenants = make(map[string]*tenantQuota)
+	}
+	tq := q.tenants[tenantID]
+	if tq == nil {
+		tq = &tenantQuota{
+			hour: newUsage(time.Hour, 60),
+			day:  newUsage(24*time.Hour, 24),
+		}
+		q.tenants[tenantID] = tq
+	}
+	return tq
+}
+
+// checkScan returns an error if scanning willScan
+// more bytes would exceed the limit on the bytes
+// scanned over the given window
+func checkScan(u *usage, now time.Time, willScan int64, limit uint64, window string) error {
+	if limit == 0 {
+		return nil
+	}
+	if uint64(willScan) > limit {
+		return &errQuota{reason: fmt.Sprintf("query may scan %d bytes, more than the limit of %d bytes per %s per node", willScan, limit, window)}
+	}
+	used := u.total(now)
+	if uint64(used+willScan) <= limit {
+		return nil
+	}
+	return &errQuota{
+		reason: fmt.Sprintf("scanned %d bytes on this node in the past %s; query may scan %d more (limit %d)", used, window, willScan, limit),
+		retry:  u.expires(now, used+willScan-int64(limit)),
+	}
+}
+
+// admit determines whether a query of a tenant
+// that may scan willScan bytes can be executed,
+// waiting for other queries of the tenant to finish
+// if too many are running. If the query may run, then
+// admit returns a function that must be called with
+// the number of bytes scanned once the query has finished.
+func (q *quotas) admit(ctx context.Context, tenantID string, cfg *db.TenantConfig, willScan int64) (func(scanned int64), error) {
+	if cfg == nil {
+		cfg = &db.TenantConfig{}
+	}
+	q.lock.Lock()
+	tq := q.tenant(tenantID)
+	now := time.Now()
+	err := checkScan(&tq.hour, now, willScan, cfg.MaxScanBytesPerHour, "hour")
+	if err == nil {
+		err = checkScan(&tq.day, now, willScan, cfg.MaxScanBytesPerDay, "day")
+	}
+	if err != nil {
+		q.lock.Unlock()
+		return nil, err
+	}
+	done := func(scanned int64) {
+		q.lock.Lock()
+		defer q.lock.Unlock()
+		now := time.Now()
+		tq.hour.add(now, scanned)
+		tq.day.add(now, scanned)
+		q.next(tq)
+	}
+	max := cfg.MaxConcurrentQueries
+	if max <= 0 || (tq.running < max && len(tq.queue) == 0) {
+		tq.running++
+		q.lock.Unlock()
+		return done, nil
+	}
+	if len(tq.queue) >= cfg.MaxQueuedQueries {
+		q.lock.Unlock()
+		return nil, &errQuota{reason: fmt.Sprintf("%d queries running and %d queued on this node (limits %d and %d)",
+			tq.running, len(tq.queue), max, cfg.MaxQueuedQueries)}
+	}
+	wait := make(chan struct{})
+	tq.queue = append(tq.queue, wait)
+	q.lock.Unlock()
+
+	timer := time.NewTimer(maxQueueWait)
+	defer timer.Stop()
+	select {
+	case <-wait:
+		return done, nil
+	case <-timer.C:
+		err = &errQuota{reason: fmt.Sprintf("timed out after %s waiting for other queries to finish", maxQueueWait)}
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for i := range tq.queue {
+		if tq.queue[i] == wait {
+			tq.queue = append(tq.queue[:i], tq.queue[i+1:]...)
+			return nil, err
+		}
+	}
+	// we were admitted after all;
+	// pass the slot on to the next query
+	q.next(tq)
+	return nil, err
+}
+
+// next gives up a slot of a running query,
+// admitting the next queued query if there
+// is one; the lock must be held
+func (q *quotas) next(tq *tenantQuota) {
+	if len(tq.queue) == 0 {
+		tq.running--
+		return
+	}
+	close(tq.queue[0])
+	tq.queue = tq.queue[1:]
+}
+
+// queued returns the number of
+// queued queries of each tenant
+func (q *quotas) queued() map[string]int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	out := make(map[string]int)
+	for id, tq := range q.tenants {
+		if len(tq.queue) > 0 {
+			out[id] = len(tq.queue)
+		}
+	}
+	return out
+}
+
+// cgroupLimits are the resource limits
+// applied to the cgroup of a tenant process
+type cgroupLimits struct {
+	memory    uint64
+	cpuWeight int
+}
+
+func limitsOf(cfg *db.TenantConfig) cgroupLimits {
+	if cfg == nil {
+		return cgroupLimits{}
+	}
+	return cgroupLimits{memory: cfg.MaxMemoryBytes, cpuWeight: cfg.CPUWeight}
+}
+
+// apply writes the limits to dir; prev are the
+// limits that were applied previously, so that
+// limits that are not set are only written
+// when they need to be removed
+func (l cgroupLimits) apply(dir cgroup.Dir, prev cgroupLimits) error {
+	if l.memory != prev.memory {
+		val := "max"
+		if l.memory > 0 {
+			val = strconv.FormatUint(l.memory, 10)
+		}
+		if err := dir.WriteLine("memory.max", []byte(val)); err != nil {
+			return err
+		}
+	}
+	if l.cpuWeight != prev.cpuWeight {
+		weight := l.cpuWeight
+		if weight <= 0 {
+			weight = 100
+		} else if weight > 10000 {
+			weight = 10000
+		}
+		if err := dir.WriteInt("cpu.weight", weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tenantLimits holds the resource limits
+// of the cgroups of tenant processes
+type tenantLimits struct {
+	lock   sync.Mutex
+	limits map[tnproto.ID]cgroupLimits
+}
+
+// setup implements the tenant.WithCgroupSetup
+// callback, applying the most recent limits
+// of the tenant to a new cgroup
+func (t *tenantLimits) setup(id tnproto.ID, dir cgroup.Dir) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.limits[id].apply(dir, cgroupLimits{})
+}
+
+// update records the limits of a tenant process,
+// and applies them to its cgroup (if it has one)
+// if they have changed
+func (t *tenantLimits) update(id tnproto.ID, dir cgroup.Dir, l cgroupLimits) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	prev := t.limits[id]
+	if prev == l {
+		return nil
+	}
+	if t.limits == nil {
+		t.limits = make(map[tnproto.ID]cgroupLimits)
+	}
+	t.limits[id] = l
+	if dir.IsZero() {
+		return nil
+	}
+	err := l.apply(dir, prev)
+	if errors.Is(err, fs.ErrNotExist) {
+		// the process has not been started yet
+		return nil
+	}
+	return err
+}
+
+// admit performs admission control for a query
+// of a tenant that may scan willScan bytes, and
+// updates the resource limits of the tenant process
+func (s *server) admit(ctx context.Context, creds db.Tenant, id tnproto.ID, willScan int64) (func(scanned int64), error) {
+	cfg := tenantConfig(creds)
+	var dir cgroup.Dir
+	if s.cgroot != "" && s.sandbox {
+		dir = s.tenantCgroup(id)
+	}
+	if err := s.limits.update(id, dir, limitsOf(cfg)); err != nil {
+		s.logger.Printf("tenant %s: updating cgroup limits: %s", creds.ID(), err)
+	}
+	return s.quotas.admit(ctx, creds.ID(), cfg, willScan)
+}
+
+// tenantCgroup returns the cgroup of a tenant process
+func (s *server) tenantCgroup(id tnproto.ID) cgroup.Dir {
+	return cgroup.Dir(s.cgroot).Sub(id.String())
+}
+
+// limitDuration cancels a running query if it
+// is still running after the maximum query
+// duration of the tenant; the returned function
+// must be called once the query has finished
+func limitDuration(r *runningQuery, cfg *db.TenantConfig) func() bool {
+	if cfg == nil || cfg.MaxQueryDuration <= 0 {
+		return func() bool { return false }
+	}
+	t := time.AfterFunc(cfg.MaxQueryDuration, func() {
+		r.timedOut.Store(true)
+		r.cancel()
+	})
+	return t.Stop
+}
@@ -0,0 +1,204 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/cgroup"
+	"github.com/SnellerInc/sneller/db"
+	"github.com/SnellerInc/sneller/tenant/tnproto"
+)
+
+func TestUsage(t *testing.T) {
+	u := newUsage(time.Hour, 60)
+	start := time.Unix(3600*1000, 0)
+	u.add(start, 10)
+	u.add(start.Add(30*time.Minute), 20)
+	if got := u.total(start.Add(59 * time.Minute)); got != 30 {
+		t.Fatalf("total %d", got)
+	}
+	// the first 10 bytes expire an hour after they were added
+	if got := u.expires(start.Add(59*time.Minute), 5); got != time.Minute {
+		t.Fatalf("expires %s", got)
+	}
+	if got := u.expires(start.Add(59*time.Minute), 11); got != 31*time.Minute {
+		t.Fatalf("expires %s", got)
+	}
+	if got := u.total(start.Add(time.Hour)); got != 20 {
+		t.Fatalf("total after an hour %d", got)
+	}
+	if got := u.total(start.Add(5 * time.Hour)); got != 0 {
+		t.Fatalf("total after 5 hours %d", got)
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	var q quotas
+	ctx := context.Background()
+	cfg := &db.TenantConfig{
+		MaxConcurrentQueries: 1,
+		MaxQueuedQueries:     1,
+		MaxScanBytesPerHour:  100,
+	}
+	quota := func(err error) bool {
+		var eq *errQuota
+		return errors.As(err, &eq)
+	}
+
+	release, err := q.admit(ctx, "a", cfg, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// another tenant is not affected
+	other, err := q.admit(ctx, "b", cfg, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other(0)
+
+	// the second query is queued, and
+	// the third one is rejected
+	admitted := make(chan func(int64))
+	go func() {
+		r, err := q.admit(ctx, "a", cfg, 10)
+		if err != nil {
+			t.Error(err)
+		}
+		admitted <- r
+	}()
+	for q.queued()["a"] != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := q.admit(ctx, "a", cfg, 10); !quota(err) {
+		t.Fatalf("expected a quota error; got %v", err)
+	}
+	select {
+	case <-admitted:
+		t.Fatal("queued query admitted too early")
+	default:
+	}
+	release(50)
+	release = <-admitted
+	if release == nil {
+		t.FailNow()
+	}
+
+	// a canceled query leaves the queue
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := q.admit(cctx, "a", cfg, 10); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled; got %v", err)
+	}
+	if len(q.queued()) != 0 {
+		t.Fatal("canceled query still queued")
+	}
+	release(40)
+
+	// 90 bytes have been scanned
+	_, err = q.admit(ctx, "a", cfg, 20)
+	var eq *errQuota
+	if !errors.As(err, &eq) || eq.retry <= 0 || eq.retry > time.Hour {
+		t.Fatalf("expected an error with a retry time; got %v", err)
+	}
+	_, err = q.admit(ctx, "a", cfg, 200)
+	if !errors.As(err, &eq) || eq.retry != 0 {
+		t.Fatalf("expected an error without a retry time; got %v", err)
+	}
+	release, err = q.admit(ctx, "a", cfg, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release(10)
+
+	w := httptest.NewRecorder()
+	if !quotaError(w, &errQuota{reason: "test", retry: 1500 * time.Millisecond}) {
+		t.Fatal("not a quota error")
+	}
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" ||
+		!strings.Contains(w.Body.String(), "test") {
+		t.Fatalf("unexpected response %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+}
+
+func TestLimitDuration(t *testing.T) {
+	canceled := make(chan struct{})
+	r := &runningQuery{cancel: func() { close(canceled) }}
+	stop := limitDuration(r, &db.TenantConfig{MaxQueryDuration: time.Millisecond})
+	<-canceled
+	if stop() || !r.timedOut.Load() {
+		t.Fatal("query not timed out")
+	}
+	r = &runningQuery{cancel: func() { t.Error("canceled") }}
+	limitDuration(r, &db.TenantConfig{})()
+	limitDuration(r, &db.TenantConfig{MaxQueryDuration: time.Hour})()
+}
+
+func TestCgroupLimits(t *testing.T) {
+	dir := cgroup.Dir(t.TempDir())
+	// the files in a real cgroup are not
+	// truncated when they are written
+	reset := func() {
+		for _, name := range []string{"memory.max", "cpu.weight"} {
+			if err := os.WriteFile(filepath.Join(string(dir), name), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	reset()
+	read := func(name string) string {
+		buf, err := os.ReadFile(filepath.Join(string(dir), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf)
+	}
+	var tl tenantLimits
+	var id tnproto.ID
+	// limits are recorded even if
+	// the cgroup does not exist yet
+	err := tl.update(id, dir.Sub("missing"), cgroupLimits{memory: 1 << 20, cpuWeight: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tl.setup(id, dir); err != nil {
+		t.Fatal(err)
+	}
+	if read("memory.max") != "1048576\n" || read("cpu.weight") != "50\n" {
+		t.Fatalf("unexpected limits %q %q", read("memory.max"), read("cpu.weight"))
+	}
+	reset()
+	if err := tl.update(id, dir, cgroupLimits{}); err != nil {
+		t.Fatal(err)
+	}
+	if read("memory.max") != "max\n" || read("cpu.weight") != "100\n" {
+		t.Fatalf("unexpected limits %q %q", read("memory.max"), read("cpu.weight"))
+	}
+	// nothing is written for a
+	// tenant that has no limits
+	var other tnproto.ID
+	other[0] = 1
+	if err := tl.setup(other, dir.Sub("missing")); err != nil {
+		t.Fatal(err)
+	}
+}
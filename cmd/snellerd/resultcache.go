@@ -0,0 +1,239 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"container/list"
+	"path"
+	"sync"
+
+	"github.com/SnellerInc/sneller"
+)
+
+// resultCache caches the responses of queries.
+//
+// Entries are keyed by the same hash that is used
+// as the HTTP ETag of the response, which covers
+// the tenant, the normalized query text, the output
+// format and the ETags of all of the indexes that
+// the query plan references, so a changed index
+// never produces a hit for a stale entry.
+// Additionally, entries are dropped as soon as
+// a newer version of one of their indexes is
+// observed so that they do not occupy space
+// until they are evicted.
+//
+// Each tenant's entries are kept separately,
+// and each tenant can use at most tenantMax
+// bytes of the cache so that one tenant cannot
+// evict all of the entries of other tenants.
+type resultCache struct {
+	// max is the maximum total size of the entries;
+	// the cache is disabled if it is zero
+	max int64
+	// tenantMax is the maximum total size of the
+	// entries of one tenant; no limit if zero
+	tenantMax int64
+	// entryMax is the maximum size of one entry;
+	// no limit if zero
+	entryMax int64
+
+	lock    sync.Mutex
+	size    int64
+	lru     list.List // of *cacheEntry; most recently used first
+	tenants map[string]*tenantCache
+
+	hits, misses, evictions, invalidations int64
+}
+
+// tenantCache is the part of
+// the cache belonging to one tenant
+type tenantCache struct {
+	size    int64
+	entries map[string]*list.Element
+	// etags are the most recently observed
+	// ETags of indexes, by db/table
+	etags map[string]string
+}
+
+type cacheEntry struct {
+	tenantID, key string
+	indexes       []sneller.IndexETag
+	body          []byte
+}
+
+// resultCacheStats are the statistics of a resultCache
+type resultCacheStats struct {
+	entries, size                          int64
+	hits, misses, evictions, invalidations int64
+}
+
+func indexPath(idx *sneller.IndexETag) string {
+	return path.Join(idx.DB, idx.Table)
+}
+
+func (c *resultCache) enabled() bool { return c.max > 0 }
+
+func (c *resultCache) tenant(tenantID string) *tenantCache {
+	if c.tenants == nil {
+		c.tenants = make(map[string]*tenantCache)
+	}
+	tc := c.tenants[tenantID]
+	if tc == nil {
+		tc = &tenantCache{
+			entries: make(map[string]*list.Element),
+			etags:   make(map[string]string),
+		}
+		c.tenants[tenantID] = tc
+	}
+	return tc
+}
+
+// remove removes an entry; the lock must be held
+func (c *resultCache) remove(el *list.Element) {
+	ent := c.lru.Remove(el).(*cacheEntry)
+	tc := c.tenants[ent.tenantID]
+	delete(tc.entries, ent.key)
+	size := int64(len(ent.body))
+	tc.size -= size
+	c.size -= size
+}
+
+// observe records the ETags of the indexes
+// referenced by a query plan, and drops any entries
+// that reference older versions of those indexes
+func (c *resultCache) observe(tenantID string, indexes []sneller.IndexETag) {
+	if !c.enabled() {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	tc := c.tenant(tenantID)
+	var changed map[string]string
+	for i := range indexes {
+		p := indexPath(&indexes[i])
+		if old, ok := tc.etags[p]; ok && old == indexes[i].ETag {
+			continue
+		}
+		tc.etags[p] = indexes[i].ETag
+		if changed == nil {
+			changed = make(map[string]string)
+		}
+		changed[p] = indexes[i].ETag
+	}
+	if changed == nil {
+		return
+	}
+	for _, el := range tc.entries {
+		ent := el.Value.(*cacheEntry)
+		for i := range ent.indexes {
+			etag, ok := changed[indexPath(&ent.indexes[i])]
+			if ok && etag != ent.indexes[i].ETag {
+				c.remove(el)
+				c.invalidations++
+				break
+			}
+		}
+	}
+}
+
+// get returns the cached body for key, if any
+func (c *resultCache) get(tenantID, key string) ([]byte, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	tc := c.tenants[tenantID]
+	if tc != nil {
+		if el, ok := tc.entries[key]; ok {
+			c.lru.MoveToFront(el)
+			c.hits++
+			return el.Value.(*cacheEntry).body, true
+		}
+	}
+	c.misses++
+	return nil, false
+}
+
+// fits returns whether an entry
+// of the given size can be cached
+func (c *resultCache) fits(size int64) bool {
+	if !c.enabled() || size > c.max {
+		return false
+	}
+	return (c.entryMax <= 0 || size <= c.entryMax) &&
+		(c.tenantMax <= 0 || size <= c.tenantMax)
+}
+
+// add adds an entry to the cache, evicting the least
+// recently used entries of the tenant and then of
+// the whole cache until the new entry fits
+//
+// The entry is not added if one of its indexes
+// has changed since the query was planned.
+func (c *resultCache) add(tenantID, key string, indexes []sneller.IndexETag, body []byte) {
+	size := int64(len(body))
+	if !c.fits(size) {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	tc := c.tenant(tenantID)
+	for i := range indexes {
+		if tc.etags[indexPath(&indexes[i])] != indexes[i].ETag {
+			return
+		}
+	}
+	if el, ok := tc.entries[key]; ok {
+		c.remove(el)
+	}
+	if c.tenantMax > 0 {
+		for el := c.lru.Back(); el != nil && tc.size+size > c.tenantMax; {
+			prev := el.Prev()
+			if el.Value.(*cacheEntry).tenantID == tenantID {
+				c.remove(el)
+				c.evictions++
+			}
+			el = prev
+		}
+	}
+	for c.size+size > c.max {
+		c.remove(c.lru.Back())
+		c.evictions++
+	}
+	ent := &cacheEntry{
+		tenantID: tenantID,
+		key:      key,
+		indexes:  indexes,
+		body:     body,
+	}
+	tc.entries[key] = c.lru.PushFront(ent)
+	tc.size += size
+	c.size += size
+}
+
+func (c *resultCache) stats() resultCacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return resultCacheStats{
+		entries:       int64(c.lru.Len()),
+		size:          c.size,
+		hits:          c.hits,
+		misses:        c.misses,
+		evictions:     c.evictions,
+		invalidations: c.invalidations,
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/SnellerInc/sneller"
+)
+
+func TestResultCache(t *testing.T) {
+	c := resultCache{max: 100, tenantMax: 60, entryMax: 40}
+	idx := func(table, etag string) []sneller.IndexETag {
+		return []sneller.IndexETag{{DB: "db", Table: table, ETag: etag}}
+	}
+	body := func(n int) []byte { return make([]byte, n) }
+	has := func(tenantID, key string) bool {
+		t.Helper()
+		_, ok := c.get(tenantID, key)
+		return ok
+	}
+
+	c.observe("a", idx("x", "1"))
+	c.add("a", "k0", idx("x", "1"), body(20))
+	if !has("a", "k0") {
+		t.Fatal("missing k0")
+	}
+	if has("b", "k0") {
+		t.Fatal("tenant b got tenant a's entry")
+	}
+	// too large
+	c.add("a", "big", idx("x", "1"), body(41))
+	if has("a", "big") {
+		t.Fatal("entry larger than entryMax was added")
+	}
+	// not observed yet, or stale
+	c.add("a", "k1", idx("y", "1"), body(10))
+	c.add("a", "k1", idx("x", "0"), body(10))
+	if has("a", "k1") {
+		t.Fatal("entry with stale index was added")
+	}
+
+	// tenant a is limited to 60 bytes,
+	// so adding k2 evicts k0 (the oldest)
+	c.add("a", "k1", idx("x", "1"), body(30))
+	c.add("a", "k2", idx("x", "1"), body(20))
+	if has("a", "k0") || !has("a", "k1") || !has("a", "k2") {
+		t.Fatal("unexpected tenant eviction")
+	}
+	// tenant b can still use the rest of the cache,
+	// and adding b1 evicts a's least recently used entry
+	c.observe("b", idx("x", "9"))
+	c.add("b", "b0", idx("x", "9"), body(40))
+	if !has("a", "k1") {
+		t.Fatal("k1 evicted")
+	}
+	c.add("b", "b1", idx("x", "9"), body(11))
+	if has("a", "k2") || !has("a", "k1") || !has("b", "b0") || !has("b", "b1") {
+		t.Fatal("unexpected global eviction")
+	}
+
+	// a new version of a's index drops a's entries,
+	// but not b's entries for b's index of the same name
+	c.observe("a", idx("x", "2"))
+	if has("a", "k1") {
+		t.Fatal("entry not invalidated")
+	}
+	if !has("b", "b0") {
+		t.Fatal("invalidated entry of another tenant")
+	}
+	st := c.stats()
+	if st.entries != 2 || st.size != 51 || st.evictions != 2 || st.invalidations != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+
+	var disabled resultCache
+	disabled.observe("a", idx("x", "1"))
+	disabled.add("a", "k0", idx("x", "1"), body(1))
+	if _, ok := disabled.get("a", "k0"); ok {
+		t.Fatal("disabled cache returned an entry")
+	}
+}
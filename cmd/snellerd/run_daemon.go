@@ -16,11 +16,13 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"log"
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -28,6 +30,7 @@ import (
 	"github.com/SnellerInc/sneller/auth"
 	"github.com/SnellerInc/sneller/debug"
 	"github.com/SnellerInc/sneller/tenant"
+	"github.com/SnellerInc/sneller/vm"
 )
 
 func runDaemon(args []string) {
@@ -38,9 +41,27 @@ func runDaemon(args []string) {
 	authEndpoint := daemonCmd.String("a", "", "authorization specification (file://, http://, https://, empty uses environment)")
 	daemonEndpoint := daemonCmd.String("e", "127.0.0.1:8000", "endpoint to listen on (REST API)")
 	remoteEndpoint := daemonCmd.String("r", "127.0.0.1:9000", "endpoint to listen on for remote requests (inter-node)")
+	pgEndpoint := daemonCmd.String("pg", "", "endpoint to listen on for PostgreSQL protocol connections (empty disables)")
+	pgCert := daemonCmd.String("pg-cert", "", "TLS certificate file for PostgreSQL protocol connections (empty disables TLS)")
+	pgKey := daemonCmd.String("pg-key", "", "TLS private key file for PostgreSQL protocol connections")
+	pgInsecure := daemonCmd.Bool("pg-insecure", false, "allow PostgreSQL protocol connections without TLS on a non-loopback address")
+	pgResultMax := daemonCmd.Int64("pg-max-result", 256*1024*1024, "maximum size in bytes of the result of one PostgreSQL protocol statement (0 means no limit)")
+	metricsEndpoint := daemonCmd.String("metrics", "", "endpoint to listen on for Prometheus metrics (empty disables)")
 	cgroupRoot := daemonCmd.String("cgroot", "", "delegated cgroup root for tenant processes")
 	peerExec := daemonCmd.String("x", "", "command to exec for fetching peers")
 	debugSock := daemonCmd.Int("debug", -1, "file descriptor to listen on for pprof debug activity")
+	cacheSize := daemonCmd.Int64("result-cache", 0, "maximum size in bytes of the query result cache (0 disables)")
+	cacheTenant := daemonCmd.Int64("result-cache-tenant", 0, "maximum size in bytes of the cached results of one tenant (0 means no limit)")
+	cacheEntry := daemonCmd.Int64("result-cache-entry", 16*1024*1024, "maximum size in bytes of one cached query result (0 means no limit)")
+	auditFile := daemonCmd.String("audit", "", "file to append the query audit log to (- for stdout, empty disables)")
+	auditTable := daemonCmd.String("audit-table", "", "table (db.table) of each tenant to write the query audit log to (empty disables)")
+	traceDst := daemonCmd.String("trace", "", "OTLP/HTTP endpoint (http:// or https://) or file (- for stdout) to export query traces to (empty disables)")
+	traceSample := daemonCmd.Float64("trace-sample", 1, "fraction of queries without a sampled traceparent header to trace")
+	policyFile := daemonCmd.String("policy", "", "file with the access control policy of tenants (empty disables)")
+	policyPath := daemonCmd.String("policy-path", "", "path of the access control policy in the storage of each tenant (empty disables)")
+	spillDir := daemonCmd.String("spill-dir", tenant.DefaultSpillDir, "directory for the temporary files of queries that exceed their memory budget (empty uses the tenant's temporary directory)")
+	sortMemory := daemonCmd.Int("sort-memory", vm.SortMemory, "bytes of memory an ORDER BY may use in a tenant process before spilling to -spill-dir")
+	asyncTimeout := daemonCmd.Duration("async-timeout", defaultAsyncTimeout, "maximum run time of an asynchronous query (0 means no limit)")
 
 	if daemonCmd.Parse(args) != nil {
 		os.Exit(1)
@@ -58,12 +79,51 @@ func runDaemon(args []string) {
 	}
 
 	server := &server{
-		logger:    logger,
-		cgroot:    *cgroupRoot,
-		sandbox:   tenant.CanSandbox(),
-		tenantcmd: []string{exe, "worker"},
-		peers:     noPeers{},
+		logger:      logger,
+		cgroot:      *cgroupRoot,
+		sandbox:     tenant.CanSandbox(),
+		spilldir:    *spillDir,
+		tenantcmd:   []string{exe, "worker", "-sort-memory", strconv.Itoa(*sortMemory)},
+		peers:       noPeers{},
+		pgResultMax: *pgResultMax,
+		cache: resultCache{
+			max:       *cacheSize,
+			tenantMax: *cacheTenant,
+			entryMax:  *cacheEntry,
+		},
 	}
+	switch *auditFile {
+	case "":
+	case "-":
+		server.audit.out = os.Stdout
+	default:
+		f, err := os.OpenFile(*auditFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			server.logger.Fatal(err)
+		}
+		defer f.Close()
+		server.audit.out = f
+	}
+	if *auditTable != "" {
+		server.audit.db, server.audit.table, err = parseAuditTable(*auditTable)
+		if err != nil {
+			server.logger.Fatal(err)
+		}
+	}
+	if *traceDst != "" {
+		server.tracer.exp, err = newExporter(*traceDst)
+		if err != nil {
+			server.logger.Fatal(err)
+		}
+		server.tracer.sample = *traceSample
+	}
+	if *policyFile != "" {
+		if err := server.policy.load(*policyFile); err != nil {
+			server.logger.Fatal(err)
+		}
+	}
+	server.policy.path = *policyPath
+	server.async.timeout = *asyncTimeout
 	httpl, err := net.Listen("tcp", *daemonEndpoint)
 	if err != nil {
 		server.logger.Fatal(err)
@@ -75,6 +135,34 @@ func runDaemon(args []string) {
 			server.logger.Fatal(err)
 		}
 	}
+	if *pgCert != "" {
+		cert, err := tls.LoadX509KeyPair(*pgCert, *pgKey)
+		if err != nil {
+			server.logger.Fatal(err)
+		}
+		server.pgTLS = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+	if *pgEndpoint != "" {
+		server.pgsock, err = net.Listen("tcp", *pgEndpoint)
+		if err != nil {
+			server.logger.Fatal(err)
+		}
+		// clients send their token as the password,
+		// so it must not cross the network in cleartext
+		addr := server.pgsock.Addr().(*net.TCPAddr)
+		if server.pgTLS == nil && !addr.IP.IsLoopback() && !*pgInsecure {
+			server.logger.Fatalf("refusing to accept PostgreSQL connections on %s without TLS (see -pg-cert and -pg-insecure)", addr)
+		}
+	}
+	if *metricsEndpoint != "" {
+		server.metricsock, err = net.Listen("tcp", *metricsEndpoint)
+		if err != nil {
+			server.logger.Fatal(err)
+		}
+	}
 	provider, err := auth.Parse(*authEndpoint)
 	if err != nil {
 		if len(*authEndpoint) == 0 {
@@ -102,6 +190,12 @@ func runDaemon(args []string) {
 	}
 	go func() {
 		server.logger.Printf("Sneller daemon %s listening on %v\n", version, httpl.Addr())
+		if server.pgsock != nil {
+			server.logger.Printf("Accepting PostgreSQL connections on %v\n", server.pgsock.Addr())
+		}
+		if server.metricsock != nil {
+			server.logger.Printf("Serving metrics on %v\n", server.metricsock.Addr())
+		}
 		err := server.Serve(httpl, tenantl)
 		if err != nil {
 			server.logger.Fatal(err)
@@ -24,6 +24,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -50,6 +51,7 @@ func runWorker(args []string) {
 	workerTenant := workerCmd.String("t", "", "tenant identifier")
 	workerControlSocket := workerCmd.Int("c", -1, "control socket")
 	eventfd := workerCmd.Int("e", -1, "eventfd")
+	sortMemory := workerCmd.Int("sort-memory", vm.SortMemory, "bytes of memory an ORDER BY may use before spilling")
 	if workerCmd.Parse(args) != nil {
 		os.Exit(1)
 	}
@@ -111,6 +113,16 @@ func runWorker(args []string) {
 		}
 	}
 
+	vm.SortMemory = *sortMemory
+	if spilldir := os.Getenv("SPILLDIR"); spilldir != "" {
+		info, err := os.Stat(spilldir)
+		if err != nil || !info.IsDir() {
+			logger.Printf("ignoring invalid spill dir %s", spilldir)
+		} else {
+			vm.SpillDir = spilldir
+		}
+	}
+
 	// use a dedicated http client configuration for aws s3
 	// so that we can limit the number of idle conns;
 	// see #3055
@@ -136,10 +148,21 @@ func runWorker(args []string) {
 		s3fs.Client = s3client
 		return s3fs, nil
 	}
+	// QUERY_MEMORY_LIMIT is the maximum
+	// number of bytes of memory per query
+	var memlimit int64
+	if str := os.Getenv("QUERY_MEMORY_LIMIT"); str != "" {
+		memlimit, err = strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			logger.Printf("ignoring invalid QUERY_MEMORY_LIMIT %q", str)
+			memlimit = 0
+		}
+	}
 	srv := tnproto.Server{
 		Server: plan.Server{
-			Runner: &run,
-			InitFS: initfs,
+			Runner:      &run,
+			InitFS:      initfs,
+			MemoryLimit: memlimit,
 		},
 		Logf: logger.Printf,
 	}
@@ -16,6 +16,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"log"
 	"net"
 	"net/http"
@@ -23,7 +24,6 @@ import (
 
 	"github.com/SnellerInc/sneller"
 	"github.com/SnellerInc/sneller/auth"
-	"github.com/SnellerInc/sneller/cgroup"
 	"github.com/SnellerInc/sneller/tenant"
 	"github.com/SnellerInc/sneller/tenant/tnproto"
 )
@@ -40,6 +40,7 @@ type server struct {
 
 	sandbox   bool
 	cachedir  string
+	spilldir  string
 	cgroot    string
 	tenantcmd []string
 
@@ -56,18 +57,81 @@ type server struct {
 	// and the tenant remote socket, respectively
 	bound, remote net.Addr
 
+	// asynchronous queries submitted
+	// through POST /queries
+	async asyncQueries
+	// queries currently being executed
+	running runningQueries
+	// metrics exposed on /metrics
+	metrics metrics
+	// cached query results
+	cache resultCache
+	// admission control and resource
+	// limits of the queries of tenants
+	quotas quotas
+	limits tenantLimits
+	// the audit log of queries
+	audit     auditLog
+	auditStop chan struct{}
+	// distributed tracing of queries
+	tracer tracer
+	// the access control policy of tenants
+	policy policySource
+
+	// if non-nil, the listener for
+	// PostgreSQL protocol connections
+	pgsock net.Listener
+	// if non-nil, PostgreSQL protocol
+	// connections must use TLS
+	pgTLS *tls.Config
+	// if positive, the maximum size in bytes of
+	// the result of one PostgreSQL protocol statement
+	pgResultMax int64
+	// open PostgreSQL protocol connections
+	pg pgSessions
+
+	// if non-nil, the listener for
+	// the metrics endpoint
+	metricsock net.Listener
+	metricsrv  http.Server
+
 	// hack to avoid data races in testing
 	aboutToServe func()
 }
 
 func (s *server) Close() error {
+	s.closePostgres()
+	s.metricsrv.Close()
+	s.stopAudit()
+	s.tracer.close()
 	s.manager.Stop()
 	s.peers.Stop()
 	s.srv.Close()
 	return nil
 }
 
+func (s *server) closePostgres() {
+	if s.pgsock != nil {
+		s.pgsock.Close()
+		s.pg.closeAll()
+	}
+}
+
+// stopAudit stops flushing the audit log
+// periodically and flushes it one last time
+func (s *server) stopAudit() {
+	if s.auditStop != nil {
+		close(s.auditStop)
+		s.auditStop = nil
+		s.audit.flush()
+	}
+}
+
 func (s *server) Shutdown(ctx context.Context) error {
+	s.closePostgres()
+	s.metricsrv.Close()
+	s.stopAudit()
+	s.tracer.close()
 	if s.manager != nil {
 		s.manager.Stop()
 		s.manager = nil
@@ -80,6 +144,8 @@ func (s *server) handler() *http.ServeMux {
 	r.HandleFunc("/", s.handle(s.versionHandler, http.MethodHead, http.MethodGet))
 	r.HandleFunc("/ping", s.handle(s.pingHandler, http.MethodHead, http.MethodGet))
 	r.HandleFunc("/query", s.handle(s.queryHandler, http.MethodHead, http.MethodGet, http.MethodPost))
+	r.HandleFunc("/queries", s.handle(s.queriesHandler, http.MethodPost))
+	r.HandleFunc("/queries/", s.handle(s.queryIDHandler, http.MethodGet, http.MethodPost, http.MethodDelete))
 	r.HandleFunc("/databases", s.handle(s.databasesHandler, http.MethodHead, http.MethodGet))
 	r.HandleFunc("/tables", s.handle(s.tablesHandler, http.MethodHead, http.MethodGet))
 	r.HandleFunc("/inputs", s.handle(s.inputsHandler, http.MethodHead, http.MethodGet))
@@ -88,19 +154,30 @@ func (s *server) handler() *http.ServeMux {
 	return r
 }
 
+// metricsMux returns the handler of the metrics listener;
+// the metrics are kept off the query listener
+// because they cover every tenant and
+// are served without authorization
+func (s *server) metricsMux() *http.ServeMux {
+	r := http.NewServeMux()
+	r.HandleFunc("/metrics", s.handle(s.metricsHandler, http.MethodHead, http.MethodGet))
+	return r
+}
+
 func (s *server) Serve(httpsock, tenantsock net.Listener) error {
 	opts := []tenant.Option{
 		tenant.WithLogger(s.logger),
 		tenant.WithRemote(tenantsock),
 	}
 	if s.cgroot != "" {
-		opts = append(opts, tenant.WithCgroup(func(id tnproto.ID) cgroup.Dir {
-			return cgroup.Dir(s.cgroot).Sub(id.String())
-		}))
+		opts = append(opts,
+			tenant.WithCgroup(s.tenantCgroup),
+			tenant.WithCgroupSetup(s.limits.setup))
 	}
 	s.manager = tenant.NewManager(s.tenantcmd, opts...)
 	s.manager.Sandbox = s.sandbox
 	s.manager.CacheDir = s.cachedir
+	s.manager.SpillDir = s.spilldir
 	if tenantsock != nil {
 		go func() {
 			if err := s.manager.Serve(); err != nil {
@@ -122,6 +199,28 @@ func (s *server) Serve(httpsock, tenantsock net.Listener) error {
 		s.logger.Fatal(err)
 	}
 	s.srv.Handler = s.handler()
+	s.audit.logf = s.logger.Printf
+	if s.audit.table != "" {
+		s.auditStop = make(chan struct{})
+		go s.audit.run(s.auditStop)
+	}
+	s.tracer.run(s.logger.Printf)
+	if s.pgsock != nil {
+		go func() {
+			if err := s.servePostgres(s.pgsock); err != nil {
+				s.logger.Printf("postgres listener: %s", err)
+			}
+		}()
+	}
+	if s.metricsock != nil {
+		s.metricsrv.Handler = s.metricsMux()
+		go func() {
+			err := s.metricsrv.Serve(s.metricsock)
+			if err != nil && err != http.ErrServerClosed {
+				s.logger.Printf("metrics listener: %s", err)
+			}
+		}()
+	}
 	if s.aboutToServe != nil {
 		s.aboutToServe()
 	}
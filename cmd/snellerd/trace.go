@@ -0,0 +1,177 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/tracing"
+)
+
+// traceQueueSize is the number of traces
+// that may be waiting to be exported before
+// new traces are dropped
+const traceQueueSize = 256
+
+// tracer starts the traces of queries
+// and exports them once they have finished
+type tracer struct {
+	// exp exports the spans of each trace;
+	// tracing is disabled if it is nil
+	exp tracing.Exporter
+	// sample is the fraction of the queries
+	// that are not already part of a trace
+	// that are traced
+	sample float64
+
+	queue   chan []tracing.SpanData
+	stop    chan struct{}
+	done    chan struct{}
+	dropped atomic.Int64
+	logf    func(f string, args ...any)
+}
+
+// newExporter returns the exporter for
+// the destination passed to -trace
+func newExporter(dst string) (tracing.Exporter, error) {
+	const service = "snellerd"
+	if dst == "-" {
+		return &tracing.FileExporter{Service: service, W: os.Stdout}, nil
+	}
+	if u, err := tracing.TracesURL(dst); err == nil {
+		return &tracing.HTTPExporter{Service: service, URL: u}, nil
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &tracing.FileExporter{Service: service, W: f}, nil
+}
+
+func (t *tracer) enabled() bool { return t.exp != nil }
+
+// run starts exporting traces
+func (t *tracer) run(logf func(f string, args ...any)) {
+	if !t.enabled() {
+		return
+	}
+	t.logf = logf
+	t.queue = make(chan []tracing.SpanData, traceQueueSize)
+	t.stop = make(chan struct{})
+	t.done = make(chan struct{})
+	go func() {
+		defer close(t.done)
+		for {
+			select {
+			case spans := <-t.queue:
+				t.export(spans)
+			case <-t.stop:
+				// export whatever is left
+				for {
+					select {
+					case spans := <-t.queue:
+						t.export(spans)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+}
+
+func (t *tracer) export(spans []tracing.SpanData) {
+	if err := t.exp.Export(spans); err != nil {
+		t.logf("exporting trace: %s", err)
+	}
+}
+
+// close stops exporting traces once
+// the queued traces have been exported
+func (t *tracer) close() {
+	if t.stop != nil {
+		close(t.stop)
+		<-t.done
+		t.stop = nil
+	}
+}
+
+// start starts the root span of a query. If traceparent
+// is a valid traceparent header, then the query is traced
+// only if the caller is recording the trace, and the span
+// is part of the caller's trace; otherwise, the query
+// is traced with a probability of t.sample.
+//
+// The returned span is nil if the query is not traced.
+func (t *tracer) start(ctx context.Context, traceparent string) (context.Context, *tracing.Span) {
+	if !t.enabled() || t.queue == nil {
+		return ctx, nil
+	}
+	var parent tracing.SpanContext
+	if traceparent != "" {
+		if sc, err := tracing.ParseTraceparent(traceparent); err == nil {
+			if !sc.Sampled() {
+				return ctx, nil
+			}
+			parent = sc
+		}
+	}
+	if !parent.IsValid() && (t.sample <= 0 || rand.Float64() >= t.sample) {
+		return ctx, nil
+	}
+	return tracing.Root(ctx, "query", parent, new(tracing.Recorder))
+}
+
+// finish ends the root span of a query
+// and queues its trace to be exported
+func (t *tracer) finish(span *tracing.Span) {
+	if span == nil {
+		return
+	}
+	span.End()
+	select {
+	case t.queue <- span.Recorder().Take():
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// execSpan starts the span of the execution of
+// tree by a tenant process, and arranges for
+// the spans of the tenant to be its children
+func execSpan(ctx context.Context, tree *plan.Tree) *tracing.Span {
+	_, span := tracing.Start(ctx, "execute")
+	tree.Trace = span.Context()
+	return span
+}
+
+// traceQuery starts the root span of a query
+// submitted over HTTP by a tenant, continuing
+// the trace of the client if it sent a
+// traceparent header
+func (s *server) traceQuery(ctx context.Context, w http.ResponseWriter, r *http.Request, tenantID, iface string) (context.Context, *tracing.Span) {
+	ctx, span := s.tracer.start(ctx, r.Header.Get("traceparent"))
+	if span != nil {
+		span.SetAttr("tenant", tenantID)
+		span.SetAttr("interface", iface)
+		w.Header().Set("X-Sneller-Trace-ID", span.Context().TraceID.String())
+	}
+	return ctx, span
+}
@@ -129,6 +129,22 @@ func OpenPartialIndex(s fs.FS, db, table string, key *blockfmt.Key) (*blockfmt.I
 	return i, err
 }
 
+// OpenPartialIndexETag is equivalent to
+// OpenPartialIndex, but it also returns
+// the ETag of the index file.
+func OpenPartialIndexETag(s InputFS, db, table string, key *blockfmt.Key) (*blockfmt.Index, string, error) {
+	ipath := IndexPath(db, table)
+	i, info, err := openIndex(s, ipath, key, blockfmt.FlagSkipInputs)
+	if err != nil {
+		return nil, "", err
+	}
+	etag, err := s.ETag(ipath, info)
+	if err != nil {
+		return nil, "", err
+	}
+	return i, etag, nil
+}
+
 func openIndex(s fs.FS, ipath string, key *blockfmt.Key, opts blockfmt.Flag) (*blockfmt.Index, fs.FileInfo, error) {
 	// prevent DoS: make sure index
 	// is reasonably sized
@@ -15,6 +15,8 @@
 package db
 
 import (
+	"time"
+
 	"github.com/SnellerInc/sneller/ion/blockfmt"
 )
 
@@ -49,6 +51,56 @@ type TenantConfig struct {
 	// allowed to be scanned for each query. If
 	// this is 0, there is no limit.
 	MaxScanBytes uint64
+
+	// MaxScanBytesPerHour and MaxScanBytesPerDay
+	// are the maximum number of bytes that the
+	// queries of the tenant may scan in total
+	// over the past hour and day, respectively.
+	// If these are 0, there is no limit.
+	//
+	// Like MaxConcurrentQueries and MaxQueuedQueries,
+	// these limits are enforced separately by each
+	// node for the queries submitted to that node;
+	// they are not shared across a cluster.
+	MaxScanBytesPerHour uint64
+	MaxScanBytesPerDay  uint64
+
+	// MaxConcurrentQueries is the maximum number
+	// of queries of the tenant that are executed
+	// at the same time by one node. If this is 0,
+	// there is no limit.
+	MaxConcurrentQueries int
+
+	// MaxQueuedQueries is the maximum number of
+	// queries that wait for other queries to
+	// finish when MaxConcurrentQueries queries
+	// are already running. Queries beyond this
+	// limit are rejected.
+	MaxQueuedQueries int
+
+	// MaxQueryDuration is the maximum amount
+	// of time that a query may run before it is
+	// canceled. If this is 0, there is no limit.
+	MaxQueryDuration time.Duration
+
+	// MaxMemoryBytes is the maximum amount of
+	// memory used by the processes executing
+	// queries on behalf of the tenant. If this
+	// is 0, there is no limit.
+	//
+	// This limit is only enforced when the
+	// processes run in their own cgroups.
+	MaxMemoryBytes uint64
+
+	// CPUWeight is the relative share of CPU
+	// time given to the processes executing
+	// queries on behalf of the tenant when CPU
+	// time is contended, from 1 to 10000.
+	// If this is 0, the default weight (100) is used.
+	//
+	// This limit is only enforced when the
+	// processes run in their own cgroups.
+	CPUWeight int
 }
 
 // TenantConfigurable is a tenant that may provide
@@ -61,3 +113,14 @@ type TenantConfigurable interface {
 	// indicate all defaults should be used.
 	Config() *TenantConfig
 }
+
+// TenantPrincipal is a tenant that may identify
+// the principal (a user, service account, etc.)
+// on whose behalf requests are made.
+type TenantPrincipal interface {
+	Tenant
+
+	// Principal returns the name of the principal,
+	// or the empty string if it is not known.
+	Principal() string
+}
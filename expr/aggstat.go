@@ -0,0 +1,33 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+// The statistical aggregates are computed by the vm
+// from the count, the mean and the central moments of
+// their inputs, which are updated one value at a time
+// rather than derived from power sums, so that they do not
+// lose precision when the inputs have a large mean.
+// The moments are mergeable, so the aggregates are split
+// for distributed execution like SUM (see AggregateRole).
+
+// statistic returns whether or not the aggregate op
+// is computed from the moments of its inputs
+func (a AggregateOp) statistic() bool {
+	switch a {
+	case OpVarianceSamp, OpStdDevSamp, OpSkewness, OpKurtosis:
+		return true
+	}
+	return a.Bivariate()
+}
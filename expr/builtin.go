@@ -19,7 +19,6 @@ package expr
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"math"
 	"net"
@@ -119,6 +118,26 @@ const (
 	Substring
 	SplitPart
 
+	MD5      // sql:MD5
+	SHA1     // sql:SHA1
+	SHA256   // sql:SHA256
+	XXHash64 // sql:XXHASH64
+	Fingerprint
+	ToHex
+	FromHex
+	ToBase64   // sql:TO_BASE64
+	FromBase64 // sql:FROM_BASE64
+
+	URLExtractHost           // sql:URL_EXTRACT_HOST
+	URLExtractPath           // sql:URL_EXTRACT_PATH
+	URLExtractQuery          // sql:URL_EXTRACT_QUERY
+	URLExtractQueryParameter // sql:URL_EXTRACT_QUERY_PARAMETER
+	URLDecode                // sql:URL_DECODE
+	InetAton                 // sql:INET_ATON
+	InetNtoa                 // sql:INET_NTOA
+	IPToCIDR                 // sql:IP_TO_CIDR
+	IP6ToCIDR                // sql:IP6_TO_CIDR
+
 	BitCount
 
 	Abs
@@ -435,12 +454,17 @@ func checkIsSubnetOf(h Hint, args []Node) error {
 			return errtype(args[0], "%s", err)
 		}
 	} else {
-		if net.ParseIP(string(arg0)) == nil {
+		minIP := net.ParseIP(string(arg0))
+		if minIP == nil {
 			return errtype(args[0], "not an IP address")
 		}
-		if net.ParseIP(string(arg1)) == nil {
+		maxIP := net.ParseIP(string(arg1))
+		if maxIP == nil {
 			return errtype(args[1], "not an IP address")
 		}
+		if (minIP.To4() == nil) != (maxIP.To4() == nil) {
+			return errtype(args[1], "not an IP address of the same family as %s", arg0)
+		}
 		if !TypeOf(args[2], h).AnyOf(StringType) {
 			return errtype(args[2], "not a string but a %T", args[2])
 		}
@@ -449,23 +473,21 @@ func checkIsSubnetOf(h Hint, args []Node) error {
 }
 
 func simplifyIsSubnetOf(h Hint, args []Node) Node {
-	if len(args) == 2 { // first argument is a CIDR subnet e.g. 192.1.2.3/8
+	if len(args) == 2 { // first argument is a CIDR subnet e.g. 192.1.2.3/8 or 2001:db8::/32
 		arg0, ok := args[0].(String)
 		if !ok {
 			return nil // found an error: let checkIsSubnetOf handle this
 		}
-		_, ipv4Net, err := net.ParseCIDR(string(arg0))
+		_, ipNet, err := net.ParseCIDR(string(arg0))
 		if err != nil {
 			return nil // found an error: let checkIsSubnetOf handle this
 		}
-		mask := binary.BigEndian.Uint32(ipv4Net.Mask)
-		start := binary.BigEndian.Uint32(ipv4Net.IP)
-		finish := (start & mask) | (mask ^ 0xffffffff)
-
-		minIP := make(net.IP, 4)
-		binary.BigEndian.PutUint32(minIP, start)
-		maxIP := make(net.IP, 4)
-		binary.BigEndian.PutUint32(maxIP, finish)
+		minIP := make(net.IP, len(ipNet.IP))
+		maxIP := make(net.IP, len(ipNet.IP))
+		for i := range ipNet.IP {
+			minIP[i] = ipNet.IP[i] & ipNet.Mask[i]
+			maxIP[i] = ipNet.IP[i] | ^ipNet.Mask[i]
+		}
 
 		arg1 := missingUnless(args[1], h, StringType)
 		return Call(IsSubnetOf, Node(String(minIP.String())), Node(String(maxIP.String())), arg1)
@@ -486,11 +508,13 @@ func simplifyIsSubnetOf(h Hint, args []Node) Node {
 		if maxIP == nil {
 			return nil // found an invalid IP address: let checkIsSubnetOf handle this
 		}
-
-		switch bytes.Compare(minIP.To4(), maxIP.To4()) {
-		case 0: // min == max: simplify to trivial str cmp
-			return Compare(Equals, args[0], args[1])
-		case 1: // min > max has no solutions
+		if minIP.To4() != nil {
+			minIP, maxIP = minIP.To4(), maxIP.To4()
+			if maxIP == nil {
+				return nil // found a mix of IPv4 and IPv6: let checkIsSubnetOf handle this
+			}
+		}
+		if bytes.Compare(minIP, maxIP) > 0 { // min > max has no solutions
 			return Bool(false)
 		}
 	}
@@ -1092,12 +1116,31 @@ var builtinInfo = [maxBuiltin]binfo{
 	IsSubnetOf:           {check: checkIsSubnetOf, ret: LogicalType, simplify: simplifyIsSubnetOf},
 	Substring:            {check: checkSubstring, ret: StringType | MissingType},
 	SplitPart:            {check: checkSplitPart, ret: StringType | MissingType},
+	MD5:                  {check: unaryStringArgs, ret: StringType | MissingType, simplify: stringfunc(md5hex)},
+	SHA1:                 {check: unaryStringArgs, ret: StringType | MissingType, simplify: stringfunc(sha1hex)},
+	SHA256:               {check: unaryStringArgs, ret: StringType | MissingType, simplify: stringfunc(sha256hex)},
+	XXHash64:             {check: unaryStringArgs, ret: IntegerType | MissingType, simplify: simplifyXXHash64},
+	Fingerprint:          {check: checkFingerprint, ret: IntegerType | MissingType, simplify: simplifyFingerprint},
+	ToHex:                {check: unaryStringArgs, ret: StringType | MissingType, simplify: stringfunc(tohex)},
+	FromHex:              {check: unaryStringArgs, ret: StringType | MissingType, simplify: simplifyFromHex},
+	ToBase64:             {check: unaryStringArgs, ret: StringType | MissingType, simplify: stringfunc(tobase64)},
+	FromBase64:           {check: unaryStringArgs, ret: StringType | MissingType, simplify: simplifyFromBase64},
 	EqualsCI:             {ret: LogicalType, private: true},
 	EqualsFuzzy:          {check: checkEqualsContainsFuzzy, ret: LogicalType},
 	EqualsFuzzyUnicode:   {check: checkEqualsContainsFuzzy, ret: LogicalType},
 	ContainsFuzzy:        {check: checkEqualsContainsFuzzy, ret: LogicalType},
 	ContainsFuzzyUnicode: {check: checkEqualsContainsFuzzy, ret: LogicalType},
 
+	URLExtractHost:           {check: unaryStringArgs, ret: StringType | MissingType, simplify: simplifyURLPart(URLExtractHost)},
+	URLExtractPath:           {check: unaryStringArgs, ret: StringType | MissingType, simplify: simplifyURLPart(URLExtractPath)},
+	URLExtractQuery:          {check: unaryStringArgs, ret: StringType | MissingType, simplify: simplifyURLPart(URLExtractQuery)},
+	URLExtractQueryParameter: {check: checkURLExtractQueryParameter, ret: StringType | MissingType, simplify: simplifyURLExtractQueryParameter},
+	URLDecode:                {check: unaryStringArgs, ret: StringType | MissingType, simplify: simplifyURLDecode},
+	InetAton:                 {check: unaryStringArgs, ret: IntegerType | MissingType, simplify: simplifyInetAton},
+	InetNtoa:                 {check: fixedArgs(NumericType), ret: StringType | MissingType, simplify: simplifyInetNtoa},
+	IPToCIDR:                 {check: checkIPToCIDR, ret: StringType | MissingType, simplify: simplifyIPToCIDR},
+	IP6ToCIDR:                {check: checkIPToCIDR, private: true, ret: StringType | MissingType, simplify: simplifyIP6ToCIDR},
+
 	BitCount:  {check: fixedArgs(NumericType), ret: IntegerType | MissingType},
 	Abs:       {check: fixedArgs(NumericType), ret: NumericType},
 	Sign:      {check: fixedArgs(NumericType), ret: NumericType},
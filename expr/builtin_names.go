@@ -2,133 +2,151 @@ package expr
 
 // Code generated automatically; DO NOT EDIT
 
-var builtin2Name = [126]string{
-	"CONCAT",                   // Concat
-	"TRIM",                     // Trim
-	"LTRIM",                    // Ltrim
-	"RTRIM",                    // Rtrim
-	"UPPER",                    // Upper
-	"LOWER",                    // Lower
-	"CONTAINS",                 // Contains
-	"CONTAINS_CI",              // ContainsCI
-	"EQUALS_CI",                // EqualsCI
-	"EQUALS_FUZZY",             // EqualsFuzzy
-	"EQUALS_FUZZY_UNICODE",     // EqualsFuzzyUnicode
-	"CONTAINS_FUZZY",           // ContainsFuzzy
-	"CONTAINS_FUZZY_UNICODE",   // ContainsFuzzyUnicode
-	"OCTET_LENGTH",             // OctetLength
-	"CHAR_LENGTH",              // CharLength
-	"IS_SUBNET_OF",             // IsSubnetOf
-	"SUBSTRING",                // Substring
-	"SPLIT_PART",               // SplitPart
-	"BIT_COUNT",                // BitCount
-	"ABS",                      // Abs
-	"SIGN",                     // Sign
-	"ROUND",                    // Round
-	"ROUND_EVEN",               // RoundEven
-	"TRUNC",                    // Trunc
-	"FLOOR",                    // Floor
-	"CEIL",                     // Ceil
-	"SQRT",                     // Sqrt
-	"CBRT",                     // Cbrt
-	"EXP",                      // Exp
-	"EXPM1",                    // ExpM1
-	"EXP2",                     // Exp2
-	"EXP10",                    // Exp10
-	"HYPOT",                    // Hypot
-	"LN",                       // Ln
-	"LN1P",                     // Ln1p
-	"LOG",                      // Log
-	"LOG2",                     // Log2
-	"LOG10",                    // Log10
-	"POW",                      // Pow
-	"POW_UINT",                 // PowUint
-	"PI",                       // Pi
-	"DEGREES",                  // Degrees
-	"RADIANS",                  // Radians
-	"SIN",                      // Sin
-	"COS",                      // Cos
-	"TAN",                      // Tan
-	"ASIN",                     // Asin
-	"ACOS",                     // Acos
-	"ATAN",                     // Atan
-	"ATAN2",                    // Atan2
-	"PMOD",                     // Pmod
-	"LEAST",                    // Least
-	"GREATEST",                 // Greatest
-	"WIDTH_BUCKET",             // WidthBucket
-	"DATE_ADD_MICROSECOND",     // DateAddMicrosecond
-	"DATE_ADD_MILLISECOND",     // DateAddMillisecond
-	"DATE_ADD_SECOND",          // DateAddSecond
-	"DATE_ADD_MINUTE",          // DateAddMinute
-	"DATE_ADD_HOUR",            // DateAddHour
-	"DATE_ADD_DAY",             // DateAddDay
-	"DATE_ADD_WEEK",            // DateAddWeek
-	"DATE_ADD_MONTH",           // DateAddMonth
-	"DATE_ADD_QUARTER",         // DateAddQuarter
-	"DATE_ADD_YEAR",            // DateAddYear
-	"DATE_BIN",                 // DateBin
-	"DATE_DIFF_MICROSECOND",    // DateDiffMicrosecond
-	"DATE_DIFF_MILLISECOND",    // DateDiffMillisecond
-	"DATE_DIFF_SECOND",         // DateDiffSecond
-	"DATE_DIFF_MINUTE",         // DateDiffMinute
-	"DATE_DIFF_HOUR",           // DateDiffHour
-	"DATE_DIFF_DAY",            // DateDiffDay
-	"DATE_DIFF_WEEK",           // DateDiffWeek
-	"DATE_DIFF_MONTH",          // DateDiffMonth
-	"DATE_DIFF_QUARTER",        // DateDiffQuarter
-	"DATE_DIFF_YEAR",           // DateDiffYear
-	"DATE_EXTRACT_MICROSECOND", // DateExtractMicrosecond
-	"DATE_EXTRACT_MILLISECOND", // DateExtractMillisecond
-	"DATE_EXTRACT_SECOND",      // DateExtractSecond
-	"DATE_EXTRACT_MINUTE",      // DateExtractMinute
-	"DATE_EXTRACT_HOUR",        // DateExtractHour
-	"DATE_EXTRACT_DAY",         // DateExtractDay
-	"DATE_EXTRACT_DOW",         // DateExtractDOW
-	"DATE_EXTRACT_DOY",         // DateExtractDOY
-	"DATE_EXTRACT_MONTH",       // DateExtractMonth
-	"DATE_EXTRACT_QUARTER",     // DateExtractQuarter
-	"DATE_EXTRACT_YEAR",        // DateExtractYear
-	"DATE_TRUNC_MICROSECOND",   // DateTruncMicrosecond
-	"DATE_TRUNC_MILLISECOND",   // DateTruncMillisecond
-	"DATE_TRUNC_SECOND",        // DateTruncSecond
-	"DATE_TRUNC_MINUTE",        // DateTruncMinute
-	"DATE_TRUNC_HOUR",          // DateTruncHour
-	"DATE_TRUNC_DAY",           // DateTruncDay
-	"DATE_TRUNC_DOW",           // DateTruncDOW
-	"DATE_TRUNC_MONTH",         // DateTruncMonth
-	"DATE_TRUNC_QUARTER",       // DateTruncQuarter
-	"DATE_TRUNC_YEAR",          // DateTruncYear
-	"TO_UNIX_EPOCH",            // ToUnixEpoch
-	"TO_UNIX_MICRO",            // ToUnixMicro
-	"GEO_HASH",                 // GeoHash
-	"GEO_TILE_X",               // GeoTileX
-	"GEO_TILE_Y",               // GeoTileY
-	"GEO_TILE_ES",              // GeoTileES
-	"GEO_DISTANCE",             // GeoDistance
-	"SIZE",                     // ObjectSize
-	"ARRAY_CONTAINS",           // ArrayContains
-	"ARRAY_SIZE",               // ArraySize
-	"ARRAY_POSITION",           // ArrayPosition
-	"ARRAY_SUM",                // ArraySum
-	"INNER_PRODUCT",            // VectorInnerProduct
-	"L1_DISTANCE",              // VectorL1Distance
-	"L2_DISTANCE",              // VectorL2Distance
-	"COSINE_DISTANCE",          // VectorCosineDistance
-	"TABLE_GLOB",               // TableGlob
-	"TABLE_PATTERN",            // TablePattern
-	"IN_SUBQUERY",              // InSubquery
-	"IN_REPLACEMENT",           // InReplacement
-	"HASH_REPLACEMENT",         // HashReplacement
-	"SCALAR_REPLACEMENT",       // ScalarReplacement
-	"STRUCT_REPLACEMENT",       // StructReplacement
-	"LIST_REPLACEMENT",         // ListReplacement
-	"TIME_BUCKET",              // TimeBucket
-	"MAKE_LIST",                // MakeList
-	"MAKE_STRUCT",              // MakeStruct
-	"TYPE_BIT",                 // TypeBit
-	"ASSERT_ION_TYPE",          // AssertIonType
-	"PARTITION_VALUE",          // PartitionValue
+var builtin2Name = [144]string{
+	"CONCAT",                      // Concat
+	"TRIM",                        // Trim
+	"LTRIM",                       // Ltrim
+	"RTRIM",                       // Rtrim
+	"UPPER",                       // Upper
+	"LOWER",                       // Lower
+	"CONTAINS",                    // Contains
+	"CONTAINS_CI",                 // ContainsCI
+	"EQUALS_CI",                   // EqualsCI
+	"EQUALS_FUZZY",                // EqualsFuzzy
+	"EQUALS_FUZZY_UNICODE",        // EqualsFuzzyUnicode
+	"CONTAINS_FUZZY",              // ContainsFuzzy
+	"CONTAINS_FUZZY_UNICODE",      // ContainsFuzzyUnicode
+	"OCTET_LENGTH",                // OctetLength
+	"CHAR_LENGTH",                 // CharLength
+	"IS_SUBNET_OF",                // IsSubnetOf
+	"SUBSTRING",                   // Substring
+	"SPLIT_PART",                  // SplitPart
+	"MD5",                         // MD5
+	"SHA1",                        // SHA1
+	"SHA256",                      // SHA256
+	"XXHASH64",                    // XXHash64
+	"FINGERPRINT",                 // Fingerprint
+	"TO_HEX",                      // ToHex
+	"FROM_HEX",                    // FromHex
+	"TO_BASE64",                   // ToBase64
+	"FROM_BASE64",                 // FromBase64
+	"URL_EXTRACT_HOST",            // URLExtractHost
+	"URL_EXTRACT_PATH",            // URLExtractPath
+	"URL_EXTRACT_QUERY",           // URLExtractQuery
+	"URL_EXTRACT_QUERY_PARAMETER", // URLExtractQueryParameter
+	"URL_DECODE",                  // URLDecode
+	"INET_ATON",                   // InetAton
+	"INET_NTOA",                   // InetNtoa
+	"IP_TO_CIDR",                  // IPToCIDR
+	"IP6_TO_CIDR",                 // IP6ToCIDR
+	"BIT_COUNT",                   // BitCount
+	"ABS",                         // Abs
+	"SIGN",                        // Sign
+	"ROUND",                       // Round
+	"ROUND_EVEN",                  // RoundEven
+	"TRUNC",                       // Trunc
+	"FLOOR",                       // Floor
+	"CEIL",                        // Ceil
+	"SQRT",                        // Sqrt
+	"CBRT",                        // Cbrt
+	"EXP",                         // Exp
+	"EXPM1",                       // ExpM1
+	"EXP2",                        // Exp2
+	"EXP10",                       // Exp10
+	"HYPOT",                       // Hypot
+	"LN",                          // Ln
+	"LN1P",                        // Ln1p
+	"LOG",                         // Log
+	"LOG2",                        // Log2
+	"LOG10",                       // Log10
+	"POW",                         // Pow
+	"POW_UINT",                    // PowUint
+	"PI",                          // Pi
+	"DEGREES",                     // Degrees
+	"RADIANS",                     // Radians
+	"SIN",                         // Sin
+	"COS",                         // Cos
+	"TAN",                         // Tan
+	"ASIN",                        // Asin
+	"ACOS",                        // Acos
+	"ATAN",                        // Atan
+	"ATAN2",                       // Atan2
+	"PMOD",                        // Pmod
+	"LEAST",                       // Least
+	"GREATEST",                    // Greatest
+	"WIDTH_BUCKET",                // WidthBucket
+	"DATE_ADD_MICROSECOND",        // DateAddMicrosecond
+	"DATE_ADD_MILLISECOND",        // DateAddMillisecond
+	"DATE_ADD_SECOND",             // DateAddSecond
+	"DATE_ADD_MINUTE",             // DateAddMinute
+	"DATE_ADD_HOUR",               // DateAddHour
+	"DATE_ADD_DAY",                // DateAddDay
+	"DATE_ADD_WEEK",               // DateAddWeek
+	"DATE_ADD_MONTH",              // DateAddMonth
+	"DATE_ADD_QUARTER",            // DateAddQuarter
+	"DATE_ADD_YEAR",               // DateAddYear
+	"DATE_BIN",                    // DateBin
+	"DATE_DIFF_MICROSECOND",       // DateDiffMicrosecond
+	"DATE_DIFF_MILLISECOND",       // DateDiffMillisecond
+	"DATE_DIFF_SECOND",            // DateDiffSecond
+	"DATE_DIFF_MINUTE",            // DateDiffMinute
+	"DATE_DIFF_HOUR",              // DateDiffHour
+	"DATE_DIFF_DAY",               // DateDiffDay
+	"DATE_DIFF_WEEK",              // DateDiffWeek
+	"DATE_DIFF_MONTH",             // DateDiffMonth
+	"DATE_DIFF_QUARTER",           // DateDiffQuarter
+	"DATE_DIFF_YEAR",              // DateDiffYear
+	"DATE_EXTRACT_MICROSECOND",    // DateExtractMicrosecond
+	"DATE_EXTRACT_MILLISECOND",    // DateExtractMillisecond
+	"DATE_EXTRACT_SECOND",         // DateExtractSecond
+	"DATE_EXTRACT_MINUTE",         // DateExtractMinute
+	"DATE_EXTRACT_HOUR",           // DateExtractHour
+	"DATE_EXTRACT_DAY",            // DateExtractDay
+	"DATE_EXTRACT_DOW",            // DateExtractDOW
+	"DATE_EXTRACT_DOY",            // DateExtractDOY
+	"DATE_EXTRACT_MONTH",          // DateExtractMonth
+	"DATE_EXTRACT_QUARTER",        // DateExtractQuarter
+	"DATE_EXTRACT_YEAR",           // DateExtractYear
+	"DATE_TRUNC_MICROSECOND",      // DateTruncMicrosecond
+	"DATE_TRUNC_MILLISECOND",      // DateTruncMillisecond
+	"DATE_TRUNC_SECOND",           // DateTruncSecond
+	"DATE_TRUNC_MINUTE",           // DateTruncMinute
+	"DATE_TRUNC_HOUR",             // DateTruncHour
+	"DATE_TRUNC_DAY",              // DateTruncDay
+	"DATE_TRUNC_DOW",              // DateTruncDOW
+	"DATE_TRUNC_MONTH",            // DateTruncMonth
+	"DATE_TRUNC_QUARTER",          // DateTruncQuarter
+	"DATE_TRUNC_YEAR",             // DateTruncYear
+	"TO_UNIX_EPOCH",               // ToUnixEpoch
+	"TO_UNIX_MICRO",               // ToUnixMicro
+	"GEO_HASH",                    // GeoHash
+	"GEO_TILE_X",                  // GeoTileX
+	"GEO_TILE_Y",                  // GeoTileY
+	"GEO_TILE_ES",                 // GeoTileES
+	"GEO_DISTANCE",                // GeoDistance
+	"SIZE",                        // ObjectSize
+	"ARRAY_CONTAINS",              // ArrayContains
+	"ARRAY_SIZE",                  // ArraySize
+	"ARRAY_POSITION",              // ArrayPosition
+	"ARRAY_SUM",                   // ArraySum
+	"INNER_PRODUCT",               // VectorInnerProduct
+	"L1_DISTANCE",                 // VectorL1Distance
+	"L2_DISTANCE",                 // VectorL2Distance
+	"COSINE_DISTANCE",             // VectorCosineDistance
+	"TABLE_GLOB",                  // TableGlob
+	"TABLE_PATTERN",               // TablePattern
+	"IN_SUBQUERY",                 // InSubquery
+	"IN_REPLACEMENT",              // InReplacement
+	"HASH_REPLACEMENT",            // HashReplacement
+	"SCALAR_REPLACEMENT",          // ScalarReplacement
+	"STRUCT_REPLACEMENT",          // StructReplacement
+	"LIST_REPLACEMENT",            // ListReplacement
+	"TIME_BUCKET",                 // TimeBucket
+	"MAKE_LIST",                   // MakeList
+	"MAKE_STRUCT",                 // MakeStruct
+	"TYPE_BIT",                    // TypeBit
+	"ASSERT_ION_TYPE",             // AssertIonType
+	"PARTITION_VALUE",             // PartitionValue
 }
 
 func name2Builtin(s string) BuiltinOp {
@@ -171,6 +189,42 @@ func name2Builtin(s string) BuiltinOp {
 		return Substring
 	case "SPLIT_PART":
 		return SplitPart
+	case "MD5":
+		return MD5
+	case "SHA1":
+		return SHA1
+	case "SHA256":
+		return SHA256
+	case "XXHASH64":
+		return XXHash64
+	case "FINGERPRINT":
+		return Fingerprint
+	case "TO_HEX":
+		return ToHex
+	case "FROM_HEX":
+		return FromHex
+	case "TO_BASE64":
+		return ToBase64
+	case "FROM_BASE64":
+		return FromBase64
+	case "URL_EXTRACT_HOST":
+		return URLExtractHost
+	case "URL_EXTRACT_PATH":
+		return URLExtractPath
+	case "URL_EXTRACT_QUERY":
+		return URLExtractQuery
+	case "URL_EXTRACT_QUERY_PARAMETER":
+		return URLExtractQueryParameter
+	case "URL_DECODE":
+		return URLDecode
+	case "INET_ATON":
+		return InetAton
+	case "INET_NTOA":
+		return InetNtoa
+	case "IP_TO_CIDR":
+		return IPToCIDR
+	case "IP6_TO_CIDR":
+		return IP6ToCIDR
 	case "BIT_COUNT":
 		return BitCount
 	case "ABS":
@@ -395,4 +449,4 @@ func name2Builtin(s string) BuiltinOp {
 	return Unspecified
 }
 
-// checksum: 5ab82de4e9716a9be1d64c0692e6674b
+// checksum: 2c46bb23424e4292b658b277488d9c37
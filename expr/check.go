@@ -22,6 +22,8 @@ import (
 
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/regexp2"
+
+	"golang.org/x/exp/slices"
 )
 
 // TypeError is the error type returned
@@ -206,6 +208,44 @@ func CheckHint(n Node, h Hint) error {
 	return combine(c.errors)
 }
 
+func (t *Table) check(h Hint) error {
+	if t.Sample == nil {
+		return nil
+	}
+	switch t.Expr.(type) {
+	case *Select, *Unpivot:
+		return errsyntax(t, "TABLESAMPLE cannot be applied to a subquery")
+	}
+	if t.Sample.Method != SampleSystem && t.Sample.Method != SampleBernoulli {
+		return errsyntaxf("unknown TABLESAMPLE method %s", t.Sample.Method)
+	}
+	if !(t.Sample.Percent > 0 && t.Sample.Percent <= 100) {
+		return errsyntaxf("TABLESAMPLE percentage %g has to be in range (0, 100]", t.Sample.Percent)
+	}
+	return nil
+}
+
+func (p *PivotTable) check(h Hint) error {
+	if p.Agg.Over != nil || p.Agg.Op.Collects() {
+		return errsyntaxf("PIVOT does not support %s", ToString(p.Agg))
+	}
+	if len(p.In) == 0 {
+		return errsyntaxf("PIVOT requires at least one value in IN (...)")
+	}
+	for i := range p.In {
+		if _, ok := p.In[i].Expr.(Constant); !ok {
+			return errsyntax(p.In[i].Expr, "PIVOT value has to be a constant")
+		}
+	}
+	names := p.Names()
+	for i := range names {
+		if slices.Contains(names[:i], names[i]) {
+			return errsyntaxf("PIVOT column %q is produced more than once", names[i])
+		}
+	}
+	return nil
+}
+
 func (n *Not) check(h Hint) error {
 	if !TypeOf(n.Expr, h).Logical() {
 		return errtype(n, "can't compute NOT of non-logical expression")
@@ -322,9 +362,51 @@ func (a *Aggregate) check(h Hint) error {
 		if len(a.Over.OrderBy) == 0 {
 			return errsyntax(a, "window function is meaningless without ORDER BY")
 		}
+	} else if a.Op.OrderedSet() {
+		if a.Inner != nil || len(a.OrderBy) != 1 {
+			return errsyntax(a, "aggregate needs a WITHIN GROUP (ORDER BY ...) clause with one expression")
+		}
 	} else if a.Inner == nil {
 		return errsyntax(a, "aggregate needs an argument")
 	}
+	if (a.Op.Collects() || a.Op.statistic()) && a.Over != nil {
+		return errsyntax(a, "aggregate cannot be used as a window function")
+	}
+	if (len(a.OrderBy) > 0 && a.Op != OpArrayAgg && !a.Op.OrderedSet()) ||
+		(a.Limit != 0 && a.Op != OpArrayAgg) {
+		return errsyntax(a, "only ARRAY_AGG accepts ORDER BY and LIMIT")
+	}
+	if a.Limit < 0 {
+		return errsyntaxf("negative LIMIT %d is not supported", a.Limit)
+	}
+	switch a.Op {
+	case OpStringAgg:
+		if _, ok := a.Arg.(String); !ok {
+			return errsyntax(a, "the separator of STRING_AGG has to be a constant string")
+		}
+	case OpObjectAgg:
+		if a.Arg == nil && a.Role != AggregateRoleMerge {
+			return errsyntax(a, "OBJECT_AGG needs a key and a value")
+		}
+	case OpPercentileCont, OpPercentileDisc:
+		f, ok := a.Arg.(Float)
+		if !ok || f < 0 || f > 1 {
+			return errsyntax(a, "the percentile has to be a constant in range [0, 1]")
+		}
+	case OpApproxTopK:
+		k, ok := a.Arg.(Integer)
+		if !ok || k < 1 || k > ApproxTopKMax {
+			return errsyntaxf("the number of values of APPROX_TOP_K has to be a constant in range [1, %d]", ApproxTopKMax)
+		}
+	case OpCovarPop, OpCovarSamp, OpCorr, OpRegrSlope, OpRegrIntercept, OpRegrR2:
+		if a.Arg == nil && a.Role != AggregateRoleMerge {
+			return errsyntax(a, "aggregate needs two arguments")
+		}
+	default:
+		if a.Arg != nil {
+			return errsyntax(a, "aggregate accepts only one argument")
+		}
+	}
 	return nil
 }
 
@@ -386,7 +468,20 @@ func (s *Select) check(h Hint) error {
 		}
 	}
 
-	// 3. OFFSET and LIMIT checks
+	// 3. PIVOT v AT k replaces the columns
+	if s.Pivot != nil {
+		if len(s.Columns) > 0 {
+			return fmt.Errorf("PIVOT cannot be mixed with output columns")
+		}
+		if s.From == nil {
+			return fmt.Errorf("PIVOT without FROM is not allowed")
+		}
+		if s.HasDistinct() || s.OrderBy != nil || s.Limit != nil {
+			return fmt.Errorf("PIVOT with DISTINCT, ORDER BY or LIMIT is not allowed")
+		}
+	}
+
+	// 4. OFFSET and LIMIT checks
 	if s.Limit == nil && s.Offset != nil {
 		return fmt.Errorf("OFFSET without LIMIT is not supported")
 	}
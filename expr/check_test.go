@@ -166,6 +166,33 @@ func TestCheckExpressions(t *testing.T) {
 			nil,
 			"value 512 is not a supported Ion type",
 		},
+		{
+			// SELECT * FROM t TABLESAMPLE BERNOULLI (0)
+			&Select{
+				Columns: []Binding{Bind(Star{}, "")},
+				From: &Table{
+					Binding: Bind(path("t"), ""),
+					Sample:  &TableSample{Method: SampleBernoulli, Percent: 0},
+				},
+			},
+			&SyntaxError{},
+			"TABLESAMPLE percentage 0 has to be in range (0, 100]",
+		},
+		{
+			// SELECT * FROM (SELECT * FROM t) TABLESAMPLE SYSTEM (10)
+			&Select{
+				Columns: []Binding{Bind(Star{}, "")},
+				From: &Table{
+					Binding: Bind(&Select{
+						Columns: []Binding{Bind(Star{}, "")},
+						From:    &Table{Binding: Bind(path("t"), "")},
+					}, ""),
+					Sample: &TableSample{Method: SampleSystem, Percent: 10},
+				},
+			},
+			&SyntaxError{},
+			"TABLESAMPLE cannot be applied to a subquery",
+		},
 	}
 	for i := range testcases {
 		err := Check(testcases[i].expr)
@@ -130,6 +130,10 @@ func getEmpty(name string) (composite, bool) {
 		return &List{}, true
 	case "unpivot":
 		return &Unpivot{}, true
+	case "unnest":
+		return &Unnest{}, true
+	case "pivot_table":
+		return &PivotTable{}, true
 	case "union":
 		return &Union{}, true
 	default:
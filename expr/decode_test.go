@@ -48,6 +48,8 @@ func TestDecodeFailures(t *testing.T) {
 		"struct",
 		"list",
 		"unpivot",
+		"unnest",
+		"pivot_table",
 		"union",
 	}
 
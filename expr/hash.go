@@ -0,0 +1,177 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"unicode/utf8"
+
+	"github.com/dchest/siphash"
+
+	"github.com/SnellerInc/sneller/internal/xxhash"
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// The functions in this file are the portable
+// implementations of the hashing and encoding
+// built-ins; they are used for constant propagation
+// and they define the results that the vectorized
+// implementations in the vm package must produce.
+
+func md5hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha1hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha256hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// stringfunc produces a simplifier
+// for a unary string -> string function
+func stringfunc(fn func(string) string) func(Hint, []Node) Node {
+	return func(h Hint, args []Node) Node {
+		if len(args) != 1 {
+			return nil
+		}
+		s, ok := args[0].(String)
+		if !ok {
+			return nil
+		}
+		return String(fn(string(s)))
+	}
+}
+
+func tohex(s string) string {
+	return hex.EncodeToString([]byte(s))
+}
+
+func tobase64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func simplifyXXHash64(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	s, ok := args[0].(String)
+	if !ok {
+		return nil
+	}
+	return Integer(int64(xxhash.Sum64([]byte(s))))
+}
+
+// fingerprint computes the result of FINGERPRINT(x)
+// for the ion-encoded value x; x must not contain symbols.
+//
+// The fingerprint is the low 64 bits of SipHash-2-4
+// (with a zero key) computed over the encoded value,
+// which is identical to the hash computed by the vm
+// for hash joins and IN lookups.
+func fingerprint(value []byte) int64 {
+	lo, _ := siphash.Hash128(0, 0, value)
+	return int64(lo)
+}
+
+func checkFingerprint(h Hint, args []Node) error {
+	if len(args) != 1 {
+		return mismatch(1, len(args))
+	}
+	if !TypeOf(args[0], h).AnyOf(fingerprintTypes) {
+		return errtype(args[0], "FINGERPRINT expects a scalar argument")
+	}
+	return nil
+}
+
+// fingerprintTypes is the set of types for
+// which FINGERPRINT produces a non-MISSING result
+const fingerprintTypes = NullType | BoolType | NumericType | TimeType | StringType
+
+func simplifyFingerprint(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	c, ok := args[0].(Constant)
+	if !ok {
+		return nil
+	}
+	d := c.Datum()
+	switch d.Type() {
+	case ion.ListType, ion.StructType:
+		return Missing{}
+	}
+	var buf ion.Buffer
+	var st ion.Symtab
+	d.Encode(&buf, &st)
+	return Integer(fingerprint(buf.Bytes()))
+}
+
+func simplifyFromHex(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	s, ok := args[0].(String)
+	if !ok {
+		return nil
+	}
+	b, err := hex.DecodeString(string(s))
+	if err != nil || !utf8.Valid(b) {
+		return Missing{}
+	}
+	return String(b)
+}
+
+func simplifyFromBase64(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	s, ok := args[0].(String)
+	if !ok {
+		return nil
+	}
+	b, ok := decodeBase64(string(s))
+	if !ok || !utf8.Valid(b) {
+		return Missing{}
+	}
+	return String(b)
+}
+
+// decodeBase64 decodes a string encoded with
+// the standard base64 alphabet and padding.
+//
+// Unlike base64.StdEncoding, it does not
+// skip over embedded newline characters.
+func decodeBase64(s string) ([]byte, bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\r' || s[i] == '\n' {
+			return nil, false
+		}
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
@@ -0,0 +1,381 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package expr
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// The functions in this file are the portable
+// implementations of the URL and network address
+// built-ins; the vectorized implementations in the
+// vm package must produce identical results.
+
+// urlParts holds the components of a URL
+// that are exposed by the URL_EXTRACT_* functions
+type urlParts struct {
+	host, path, query string
+	hasHost, hasQuery bool
+}
+
+func isSchemeStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSchemeChar(c byte) bool {
+	return isSchemeStart(c) || (c >= '0' && c <= '9') || c == '+' || c == '-' || c == '.'
+}
+
+// splitURL splits s into its components
+// following the generic syntax of RFC 3986:
+//
+//	[scheme:][//[userinfo@]host[:port]]path[?query][#fragment]
+//
+// The host does not include the brackets
+// around an IPv6 address literal.
+func splitURL(s string) (u urlParts) {
+	pos := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == ':' {
+			if i > 0 {
+				pos = i + 1
+			}
+			break
+		}
+		if !isSchemeChar(c) || (i == 0 && !isSchemeStart(c)) {
+			break
+		}
+	}
+	if strings.HasPrefix(s[pos:], "//") {
+		pos += 2
+		end := pos
+		for end < len(s) && s[end] != '/' && s[end] != '?' && s[end] != '#' {
+			end++
+		}
+		host := s[pos:end]
+		if i := strings.LastIndexByte(host, '@'); i >= 0 {
+			host = host[i+1:]
+		}
+		if strings.HasPrefix(host, "[") {
+			if i := strings.IndexByte(host, ']'); i >= 0 {
+				host = host[1:i]
+			} else {
+				host = ""
+			}
+		} else if i := strings.IndexByte(host, ':'); i >= 0 {
+			host = host[:i]
+		}
+		u.host = host
+		u.hasHost = host != ""
+		pos = end
+	}
+	end := pos
+	for end < len(s) && s[end] != '?' && s[end] != '#' {
+		end++
+	}
+	u.path = s[pos:end]
+	if end < len(s) && s[end] == '?' {
+		pos = end + 1
+		end = pos
+		for end < len(s) && s[end] != '#' {
+			end++
+		}
+		u.query = s[pos:end]
+		u.hasQuery = true
+	}
+	return u
+}
+
+// queryParameter returns the (still encoded) value
+// of the first parameter called name in query
+func queryParameter(query, name string) (string, bool) {
+	for {
+		param, rest, more := strings.Cut(query, "&")
+		key, value, _ := strings.Cut(param, "=")
+		if key == name {
+			return value, true
+		}
+		if !more {
+			return "", false
+		}
+		query = rest
+	}
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// urlDecode decodes percent-encoded octets
+// and replaces '+' with a space
+func urlDecode(s string) (string, bool) {
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '+':
+			buf = append(buf, ' ')
+		case '%':
+			if i+2 >= len(s) {
+				return "", false
+			}
+			hi, ok0 := unhex(s[i+1])
+			lo, ok1 := unhex(s[i+2])
+			if !ok0 || !ok1 {
+				return "", false
+			}
+			buf = append(buf, hi<<4|lo)
+			i += 2
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return string(buf), true
+}
+
+func simplifyURLPart(part BuiltinOp) func(Hint, []Node) Node {
+	return func(h Hint, args []Node) Node {
+		if len(args) != 1 {
+			return nil
+		}
+		s, ok := args[0].(String)
+		if !ok {
+			return nil
+		}
+		u := splitURL(string(s))
+		switch part {
+		case URLExtractHost:
+			if u.hasHost {
+				return String(u.host)
+			}
+		case URLExtractPath:
+			return String(u.path)
+		case URLExtractQuery:
+			if u.hasQuery {
+				return String(u.query)
+			}
+		}
+		return Missing{}
+	}
+}
+
+func checkURLExtractQueryParameter(h Hint, args []Node) error {
+	if len(args) != 2 {
+		return mismatch(2, len(args))
+	}
+	if !TypeOf(args[0], h).AnyOf(StringType) {
+		return errtype(args[0], "not a string")
+	}
+	name, ok := args[1].(String)
+	if !ok {
+		return errtype(args[1], "the parameter name must be a string literal")
+	}
+	if strings.ContainsAny(string(name), "&=") {
+		return errsyntaxf("the parameter name %q cannot contain '&' or '='", string(name))
+	}
+	return nil
+}
+
+func simplifyURLExtractQueryParameter(h Hint, args []Node) Node {
+	if len(args) != 2 {
+		return nil
+	}
+	name, ok := args[1].(String)
+	if !ok {
+		return nil
+	}
+	s, ok := args[0].(String)
+	if !ok {
+		return nil
+	}
+	u := splitURL(string(s))
+	if !u.hasQuery {
+		return Missing{}
+	}
+	value, ok := queryParameter(u.query, string(name))
+	if !ok {
+		return Missing{}
+	}
+	return String(value)
+}
+
+func simplifyURLDecode(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	s, ok := args[0].(String)
+	if !ok {
+		return nil
+	}
+	out, ok := urlDecode(string(s))
+	if !ok {
+		return Missing{}
+	}
+	return String(out)
+}
+
+// parseIPv4 parses a dotted-quad IPv4 address;
+// leading zeros in the octets are not permitted
+func parseIPv4(s string) (uint32, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil || !addr.Is4() {
+		return 0, false
+	}
+	b := addr.As4()
+	return binary.BigEndian.Uint32(b[:]), true
+}
+
+func formatIPv4(ip uint32) string {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], ip)
+	return netip.AddrFrom4(b).String()
+}
+
+func simplifyInetAton(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	s, ok := args[0].(String)
+	if !ok {
+		return nil
+	}
+	ip, ok := parseIPv4(string(s))
+	if !ok {
+		return Missing{}
+	}
+	return Integer(ip)
+}
+
+func simplifyInetNtoa(h Hint, args []Node) Node {
+	if len(args) != 1 {
+		return nil
+	}
+	i, ok := args[0].(Integer)
+	if !ok {
+		return nil
+	}
+	if i < 0 || i > 0xffffffff {
+		return Missing{}
+	}
+	return String(formatIPv4(uint32(i)))
+}
+
+func checkIPToCIDR(h Hint, args []Node) error {
+	if len(args) != 2 {
+		return mismatch(2, len(args))
+	}
+	if !TypeOf(args[0], h).AnyOf(StringType) {
+		return errtype(args[0], "not a string")
+	}
+	n, ok := args[1].(Integer)
+	if !ok {
+		return errtype(args[1], "the prefix length must be an integer literal")
+	}
+	if n < 0 || n > 128 {
+		return errsyntaxf("prefix length %d is out of range [0, 128]", n)
+	}
+	return nil
+}
+
+// ipToCIDR returns the network of the address s having
+// the prefix length n in CIDR notation, formatted
+// the way net/netip does
+func ipToCIDR(s string, n int) (string, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil || addr.Zone() != "" {
+		return "", false
+	}
+	if addr.Is4() && n > 32 {
+		return "", false
+	}
+	p, err := addr.Prefix(n)
+	if err != nil {
+		return "", false
+	}
+	return p.String(), true
+}
+
+// simplifyIPToCIDR rewrites
+//
+//	IP_TO_CIDR(ip, n)
+//
+// into
+//
+//	CASE WHEN INET_ATON(ip) IS NOT MISSING
+//	THEN INET_NTOA(INET_ATON(ip) & mask) || '/n'
+//	ELSE IP6_TO_CIDR(ip, n) END
+//
+// so that IPv4 addresses are handled by the integer
+// conversions and everything else is parsed as IPv6;
+// prefixes longer than 32 bits only apply to IPv6
+func simplifyIPToCIDR(h Hint, args []Node) Node {
+	if len(args) != 2 {
+		return nil
+	}
+	n, ok := args[1].(Integer)
+	if !ok || n < 0 || n > 128 {
+		return nil
+	}
+	if s, ok := args[0].(String); ok {
+		cidr, ok := ipToCIDR(string(s), int(n))
+		if !ok {
+			return Missing{}
+		}
+		return String(cidr)
+	}
+	ip6 := Call(IP6ToCIDR, args[0], n)
+	if n > 32 {
+		return ip6
+	}
+	mask := uint32(0xffffffff << (32 - n))
+	ip := Call(InetAton, args[0])
+	network := Call(InetNtoa, BitAnd(Copy(ip), Integer(mask)))
+	ip4 := Call(Concat, network, String(fmt.Sprintf("/%d", n)))
+	return IfThenElse(Is(ip, IsNotMissing), ip4, ip6)
+}
+
+func simplifyIP6ToCIDR(h Hint, args []Node) Node {
+	if len(args) != 2 {
+		return nil
+	}
+	n, ok := args[1].(Integer)
+	if !ok {
+		return nil
+	}
+	s, ok := args[0].(String)
+	if !ok {
+		return nil
+	}
+	addr, err := netip.ParseAddr(string(s))
+	if err != nil || !addr.Is6() {
+		return Missing{}
+	}
+	cidr, ok := ipToCIDR(string(s), int(n))
+	if !ok {
+		return Missing{}
+	}
+	return String(cidr)
+}
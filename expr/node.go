@@ -201,6 +201,103 @@ const (
 	// aggregates.
 	OpSystemDatashapeMerge
 
+	// OpArrayAgg corresponds to ARRAY_AGG()
+	OpArrayAgg
+
+	// OpStringAgg corresponds to STRING_AGG()
+	OpStringAgg
+
+	// OpObjectAgg corresponds to OBJECT_AGG()
+	OpObjectAgg
+
+	// OpPercentileCont corresponds to
+	// PERCENTILE_CONT(p) WITHIN GROUP (ORDER BY x)
+	OpPercentileCont
+
+	// OpPercentileDisc corresponds to
+	// PERCENTILE_DISC(p) WITHIN GROUP (ORDER BY x)
+	OpPercentileDisc
+
+	// OpMode corresponds to MODE() WITHIN GROUP (ORDER BY x)
+	OpMode
+
+	// OpVarianceSamp corresponds to VAR_SAMP()
+	OpVarianceSamp
+
+	// OpStdDevSamp corresponds to STDDEV_SAMP()
+	OpStdDevSamp
+
+	// OpCovarPop corresponds to COVAR_POP(y, x)
+	OpCovarPop
+
+	// OpCovarSamp corresponds to COVAR_SAMP(y, x)
+	OpCovarSamp
+
+	// OpCorr corresponds to CORR(y, x)
+	OpCorr
+
+	// OpRegrSlope corresponds to REGR_SLOPE(y, x)
+	OpRegrSlope
+
+	// OpRegrIntercept corresponds to REGR_INTERCEPT(y, x)
+	OpRegrIntercept
+
+	// OpRegrR2 corresponds to REGR_R2(y, x)
+	OpRegrR2
+
+	// OpSkewness corresponds to SKEWNESS()
+	OpSkewness
+
+	// OpKurtosis corresponds to KURTOSIS()
+	OpKurtosis
+
+	// OpApproxTopK corresponds to APPROX_TOP_K(x, k)
+	// and produces a list of the k most frequent values
+	OpApproxTopK
+
+	// OpApproxCountDistinctState corresponds to
+	// APPROX_COUNT_DISTINCT_STATE(x) and produces
+	// the HyperLogLog state of APPROX_COUNT_DISTINCT
+	// as a blob
+	OpApproxCountDistinctState
+
+	// OpHLLMerge corresponds to HLL_MERGE(state)
+	// and merges the states produced by
+	// APPROX_COUNT_DISTINCT_STATE into one state
+	OpHLLMerge
+
+	// OpHLLEstimate corresponds to HLL_ESTIMATE(state)
+	// and merges the states produced by
+	// APPROX_COUNT_DISTINCT_STATE into an estimate
+	// of the number of distinct values
+	OpHLLEstimate
+
+	// OpApproxPercentileState corresponds to
+	// APPROX_PERCENTILE_STATE(x) and produces
+	// the t-digest state of APPROX_PERCENTILE
+	// as a blob
+	OpApproxPercentileState
+
+	// OpTDigestMerge corresponds to TDIGEST_MERGE(state)
+	// and merges the states produced by
+	// APPROX_PERCENTILE_STATE into one state
+	OpTDigestMerge
+
+	// OpTDigestQuantile corresponds to TDIGEST_QUANTILE(state, p)
+	// and merges the states produced by
+	// APPROX_PERCENTILE_STATE into the percentile p
+	OpTDigestQuantile
+
+	// OpScaledCount corresponds to SCALED_COUNT(x)
+	// and estimates COUNT(x) over the whole table
+	// from a TABLESAMPLE of the table
+	OpScaledCount
+
+	// OpScaledSum corresponds to SCALED_SUM(x)
+	// and estimates SUM(x) over the whole table
+	// from a TABLESAMPLE of the table
+	OpScaledSum
+
 	// anchor for the last aggregate operator
 	maxAggregateOp
 )
@@ -211,9 +308,12 @@ const (
 	ApproxCountDistinctDefaultPrecision = 11
 )
 
+// ApproxTopKMax is the largest k accepted by APPROX_TOP_K
+const ApproxTopKMax = 10000
+
 func (a AggregateOp) defaultResult() string {
 	switch a {
-	case OpCount, OpCountDistinct, OpSumCount, OpApproxCountDistinct:
+	case OpCount, OpCountDistinct, OpSumCount, OpApproxCountDistinct, OpHLLEstimate:
 		return "count"
 	case OpSum, OpSumInt:
 		return "sum"
@@ -223,8 +323,16 @@ func (a AggregateOp) defaultResult() string {
 		return "variance_pop"
 	case OpStdDevPop:
 		return "stddev_pop"
-	case OpApproxPercentile:
+	case OpApproxPercentile, OpTDigestQuantile:
 		return "approx_percentile"
+	case OpApproxCountDistinctState, OpHLLMerge:
+		return "hll"
+	case OpApproxPercentileState, OpTDigestMerge:
+		return "tdigest"
+	case OpScaledCount:
+		return "scaled_count"
+	case OpScaledSum:
+		return "scaled_sum"
 	case OpMin, OpEarliest:
 		return "min"
 	case OpMax, OpLatest:
@@ -237,6 +345,40 @@ func (a AggregateOp) defaultResult() string {
 		return "rank"
 	case OpDenseRank:
 		return "dense_rank"
+	case OpArrayAgg:
+		return "array_agg"
+	case OpStringAgg:
+		return "string_agg"
+	case OpObjectAgg:
+		return "object_agg"
+	case OpPercentileCont:
+		return "percentile_cont"
+	case OpPercentileDisc:
+		return "percentile_disc"
+	case OpMode:
+		return "mode"
+	case OpVarianceSamp:
+		return "var_samp"
+	case OpStdDevSamp:
+		return "stddev_samp"
+	case OpCovarPop:
+		return "covar_pop"
+	case OpCovarSamp:
+		return "covar_samp"
+	case OpCorr:
+		return "corr"
+	case OpRegrSlope:
+		return "regr_slope"
+	case OpRegrIntercept:
+		return "regr_intercept"
+	case OpRegrR2:
+		return "regr_r2"
+	case OpSkewness:
+		return "skewness"
+	case OpKurtosis:
+		return "kurtosis"
+	case OpApproxTopK:
+		return "approx_top_k"
 	default:
 		return ""
 	}
@@ -294,6 +436,56 @@ func (a AggregateOp) String() string {
 		return "SNELLER_DATASHAPE"
 	case OpSystemDatashapeMerge:
 		return "SNELLER_DATASHAPE_MERGE"
+	case OpArrayAgg:
+		return "ARRAY_AGG"
+	case OpStringAgg:
+		return "STRING_AGG"
+	case OpObjectAgg:
+		return "OBJECT_AGG"
+	case OpPercentileCont:
+		return "PERCENTILE_CONT"
+	case OpPercentileDisc:
+		return "PERCENTILE_DISC"
+	case OpMode:
+		return "MODE"
+	case OpVarianceSamp:
+		return "VAR_SAMP"
+	case OpStdDevSamp:
+		return "STDDEV_SAMP"
+	case OpCovarPop:
+		return "COVAR_POP"
+	case OpCovarSamp:
+		return "COVAR_SAMP"
+	case OpCorr:
+		return "CORR"
+	case OpRegrSlope:
+		return "REGR_SLOPE"
+	case OpRegrIntercept:
+		return "REGR_INTERCEPT"
+	case OpRegrR2:
+		return "REGR_R2"
+	case OpSkewness:
+		return "SKEWNESS"
+	case OpKurtosis:
+		return "KURTOSIS"
+	case OpApproxTopK:
+		return "APPROX_TOP_K"
+	case OpApproxCountDistinctState:
+		return "APPROX_COUNT_DISTINCT_STATE"
+	case OpHLLMerge:
+		return "HLL_MERGE"
+	case OpHLLEstimate:
+		return "HLL_ESTIMATE"
+	case OpApproxPercentileState:
+		return "APPROX_PERCENTILE_STATE"
+	case OpTDigestMerge:
+		return "TDIGEST_MERGE"
+	case OpTDigestQuantile:
+		return "TDIGEST_QUANTILE"
+	case OpScaledCount:
+		return "SCALED_COUNT"
+	case OpScaledSum:
+		return "SCALED_SUM"
 	default:
 		return fmt.Sprintf("<AggregateOp=%d>", int(a))
 	}
@@ -305,7 +497,14 @@ func (a AggregateOp) private() bool {
 		OpApproxMedian, OpApproxPercentile,
 		OpMin, OpMax, OpEarliest, OpLatest,
 		OpBitAnd, OpBitOr, OpBitXor, OpBoolAnd, OpBoolOr,
-		OpApproxCountDistinct, OpSystemDatashape, OpRowNumber, OpRank, OpDenseRank:
+		OpApproxCountDistinct, OpSystemDatashape, OpRowNumber, OpRank, OpDenseRank,
+		OpArrayAgg, OpStringAgg, OpObjectAgg,
+		OpPercentileCont, OpPercentileDisc, OpMode,
+		OpVarianceSamp, OpStdDevSamp, OpCovarPop, OpCovarSamp, OpCorr,
+		OpRegrSlope, OpRegrIntercept, OpRegrR2, OpSkewness, OpKurtosis,
+		OpApproxTopK, OpApproxCountDistinctState, OpHLLMerge, OpHLLEstimate,
+		OpApproxPercentileState, OpTDigestMerge, OpTDigestQuantile,
+		OpScaledCount, OpScaledSum:
 		return false
 	}
 
@@ -323,6 +522,57 @@ func (a AggregateOp) WindowOnly() bool {
 	}
 }
 
+// Collects returns whether or not the aggregate op
+// collects its input values (rather than reducing
+// them to a fixed-size state)
+func (a AggregateOp) Collects() bool {
+	switch a {
+	case OpArrayAgg, OpStringAgg, OpObjectAgg,
+		OpPercentileCont, OpPercentileDisc, OpMode, OpApproxTopK:
+		return true
+	default:
+		return false
+	}
+}
+
+// Bivariate returns whether or not the aggregate op
+// computes a statistic of pairs of values (y, x);
+// the dependent variable y is the Inner expression
+// and the independent variable x is the Arg expression
+func (a AggregateOp) Bivariate() bool {
+	switch a {
+	case OpCovarPop, OpCovarSamp, OpCorr, OpRegrSlope, OpRegrIntercept, OpRegrR2:
+		return true
+	default:
+		return false
+	}
+}
+
+// OrderedSet returns whether or not the aggregate op
+// is an ordered-set aggregate, i.e. one that aggregates
+// the expression in its WITHIN GROUP (ORDER BY ...) clause
+func (a AggregateOp) OrderedSet() bool {
+	switch a {
+	case OpPercentileCont, OpPercentileDisc, OpMode:
+		return true
+	default:
+		return false
+	}
+}
+
+// HLL returns whether or not the aggregate op
+// computes or consumes the HyperLogLog state
+// of APPROX_COUNT_DISTINCT, which has the
+// precision given by Aggregate.Precision
+func (a AggregateOp) HLL() bool {
+	switch a {
+	case OpApproxCountDistinct, OpApproxCountDistinctState, OpHLLMerge, OpHLLEstimate:
+		return true
+	default:
+		return false
+	}
+}
+
 // AcceptDistinct returns true if the aggregate can be used with DISTINCT keyword.
 func (a AggregateOp) AcceptDistinct() bool {
 	switch a {
@@ -336,7 +586,7 @@ func (a AggregateOp) AcceptDistinct() bool {
 // AcceptStar returns true if the aggregate can be used with '*'.
 func (a AggregateOp) AcceptStar() bool {
 	switch a {
-	case OpCount, OpSystemDatashape:
+	case OpCount, OpSystemDatashape, OpScaledCount:
 		return true
 	}
 
@@ -393,6 +643,7 @@ type Aggregate struct {
 	// Miscellaneous data: used by OpTDigest to store percentile values q
 	Misc float32
 	// Precision is the parameter for OpApproxCountDistinct
+	// and the other aggregates for which AggregateOp.HLL is true
 	Precision uint8
 	// Role describes how aggregate is supposed to be used in a multi-node architecture
 	Role AggregateRole
@@ -404,6 +655,22 @@ type Aggregate struct {
 	Over *Window
 	// Filter is an optional filtering expression
 	Filter Node
+	// Arg is the second argument of the aggregate:
+	// the separator for OpStringAgg, the value
+	// for OpObjectAgg, the fraction for
+	// OpPercentileCont and OpPercentileDisc,
+	// the number of values for OpApproxTopK
+	// and the independent variable for the
+	// bivariate aggregates (see AggregateOp.Bivariate)
+	Arg Node
+	// OrderBy is the optional ordering
+	// of the values collected by OpArrayAgg,
+	// or the WITHIN GROUP ordering of
+	// an ordered-set aggregate
+	OrderBy []Order
+	// Limit, if non-zero, is the maximum number
+	// of values collected by OpArrayAgg
+	Limit int
 }
 
 func (a *Aggregate) Equals(e Node) bool {
@@ -430,6 +697,15 @@ func (a *Aggregate) Equals(e Node) bool {
 	if (a.Filter != nil) && !a.Filter.Equals(ea.Filter) {
 		return false
 	}
+	if (a.Arg != nil) != (ea.Arg != nil) {
+		return false
+	}
+	if (a.Arg != nil) && !a.Arg.Equals(ea.Arg) {
+		return false
+	}
+	if a.Limit != ea.Limit || !slices.EqualFunc(a.OrderBy, ea.OrderBy, Order.Equals) {
+		return false
+	}
 
 	if a.Over == nil {
 		return ea.Over == nil
@@ -452,11 +728,11 @@ func (a *Aggregate) Encode(dst *ion.Buffer, st *ion.Symtab) {
 	dst.WriteUint(uint64(a.Op))
 	dst.BeginField(st.Intern("role"))
 	dst.WriteUint(uint64(a.Role))
-	switch a.Op {
-	case OpApproxCountDistinct:
+	switch {
+	case a.Op.HLL():
 		dst.BeginField(st.Intern("precision"))
 		dst.WriteUint(uint64(a.Precision))
-	case OpApproxPercentile, OpApproxMedian:
+	case a.Op == OpApproxPercentile || a.Op == OpApproxMedian || a.Op == OpTDigestQuantile:
 		dst.BeginField(st.Intern("misc"))
 		dst.WriteFloat64(float64(a.Misc))
 	}
@@ -481,6 +757,18 @@ func (a *Aggregate) Encode(dst *ion.Buffer, st *ion.Symtab) {
 		dst.BeginField(st.Intern("filter_where"))
 		a.Filter.Encode(dst, st)
 	}
+	if a.Arg != nil {
+		dst.BeginField(st.Intern("arg"))
+		a.Arg.Encode(dst, st)
+	}
+	if len(a.OrderBy) > 0 {
+		dst.BeginField(st.Intern("order_by"))
+		EncodeOrder(a.OrderBy, dst, st)
+	}
+	if a.Limit > 0 {
+		dst.BeginField(st.Intern("limit"))
+		dst.WriteInt(int64(a.Limit))
+	}
 
 	dst.EndStruct()
 }
@@ -526,6 +814,20 @@ func (a *Aggregate) SetField(f ion.Field) error {
 		var err error
 		a.Filter, err = Decode(f.Datum)
 		return err
+	case "arg":
+		var err error
+		a.Arg, err = Decode(f.Datum)
+		return err
+	case "order_by":
+		var err error
+		a.OrderBy, err = decodeOrder(f.Datum)
+		return err
+	case "limit":
+		i, err := f.Int()
+		if err != nil {
+			return err
+		}
+		a.Limit = int(i)
 	case "precision":
 		p, err := f.Uint()
 		if err != nil {
@@ -545,6 +847,10 @@ func (a *Aggregate) SetField(f ion.Field) error {
 }
 
 func (a *Aggregate) text(dst *strings.Builder, redact bool) {
+	if a.Op.OrderedSet() {
+		a.orderedSetText(dst, redact)
+		return
+	}
 	if a.Op == OpCountDistinct {
 		dst.WriteString("COUNT(DISTINCT ")
 	} else {
@@ -564,15 +870,30 @@ func (a *Aggregate) text(dst *strings.Builder, redact bool) {
 		a.Inner.text(dst, redact)
 	}
 
-	switch a.Op {
-	case OpApproxCountDistinct:
+	switch {
+	case a.Op.HLL():
 		if a.Precision > 0 && a.Precision != ApproxCountDistinctDefaultPrecision {
 			fmt.Fprintf(dst, ", %d", a.Precision)
 		}
 
-	case OpApproxPercentile:
+	case a.Op == OpApproxPercentile || a.Op == OpTDigestQuantile:
 		fmt.Fprintf(dst, ", %v", a.Misc)
 	}
+	if a.Arg != nil {
+		dst.WriteString(", ")
+		a.Arg.text(dst, redact)
+	}
+	for i := range a.OrderBy {
+		if i == 0 {
+			dst.WriteString(" ORDER BY ")
+		} else {
+			dst.WriteString(", ")
+		}
+		a.OrderBy[i].text(dst, redact)
+	}
+	if a.Limit > 0 {
+		fmt.Fprintf(dst, " LIMIT %d", a.Limit)
+	}
 	dst.WriteByte(')')
 
 	if a.Filter != nil {
@@ -606,6 +927,33 @@ func (a *Aggregate) text(dst *strings.Builder, redact bool) {
 	}
 }
 
+func (a *Aggregate) orderedSetText(dst *strings.Builder, redact bool) {
+	dst.WriteString(a.Op.String())
+	switch a.Role {
+	case AggregateRolePartial:
+		dst.WriteString(".PARTIAL")
+	case AggregateRoleMerge:
+		dst.WriteString(".MERGE")
+	}
+	dst.WriteByte('(')
+	if a.Arg != nil {
+		a.Arg.text(dst, redact)
+	}
+	dst.WriteString(") WITHIN GROUP (ORDER BY ")
+	for i := range a.OrderBy {
+		if i > 0 {
+			dst.WriteString(", ")
+		}
+		a.OrderBy[i].text(dst, redact)
+	}
+	dst.WriteByte(')')
+	if a.Filter != nil {
+		dst.WriteString(" FILTER (WHERE ")
+		a.Filter.text(dst, redact)
+		dst.WriteString(")")
+	}
+}
+
 func (a *Aggregate) walk(v Visitor) {
 	if a.Inner != nil {
 		Walk(v, a.Inner)
@@ -621,6 +969,12 @@ func (a *Aggregate) walk(v Visitor) {
 	if a.Filter != nil {
 		Walk(v, a.Filter)
 	}
+	if a.Arg != nil {
+		Walk(v, a.Arg)
+	}
+	for i := range a.OrderBy {
+		Walk(v, a.OrderBy[i].Column)
+	}
 }
 
 func (a *Aggregate) rewrite(r Rewriter) Node {
@@ -638,13 +992,21 @@ func (a *Aggregate) rewrite(r Rewriter) Node {
 	if a.Filter != nil {
 		a.Filter = Rewrite(r, a.Filter)
 	}
+	if a.Arg != nil {
+		a.Arg = Rewrite(r, a.Arg)
+	}
+	for i := range a.OrderBy {
+		a.OrderBy[i].Column = Rewrite(r, a.OrderBy[i].Column)
+	}
 	return a
 }
 
 func (a *Aggregate) typeof(h Hint) TypeSet {
 	switch a.Op {
-	case OpCount, OpCountDistinct, OpSumCount, OpApproxCountDistinct, OpRowNumber, OpRank, OpDenseRank:
+	case OpCount, OpCountDistinct, OpSumCount, OpApproxCountDistinct, OpHLLEstimate, OpRowNumber, OpRank, OpDenseRank:
 		return UnsignedType
+	case OpApproxCountDistinctState, OpHLLMerge, OpApproxPercentileState, OpTDigestMerge:
+		return BlobType
 	case OpSumInt:
 		// if the inner type is only ever unsigned,
 		// then the result is only ever unsigned,
@@ -652,8 +1014,24 @@ func (a *Aggregate) typeof(h Hint) TypeSet {
 		return TypeOf(a.Inner, h)
 	case OpLatest, OpEarliest:
 		return TimeType | NullType
-	case OpSystemDatashape:
+	case OpSystemDatashape, OpScaledCount, OpScaledSum:
 		return StructType
+	case OpArrayAgg, OpApproxTopK:
+		return ListType | NullType
+	case OpStringAgg:
+		return StringType | NullType
+	case OpObjectAgg:
+		return StructType | NullType
+	case OpPercentileCont:
+		return FloatType | NullType
+	case OpPercentileDisc, OpMode:
+		if len(a.OrderBy) == 0 {
+			return AnyType
+		}
+		return (TypeOf(a.OrderBy[0].Column, h) &^ MissingType) | NullType
+	case OpVarianceSamp, OpStdDevSamp, OpCovarPop, OpCovarSamp, OpCorr,
+		OpRegrSlope, OpRegrIntercept, OpRegrR2, OpSkewness, OpKurtosis:
+		return FloatType | NullType
 	default:
 		return NumericType | NullType
 	}
@@ -3791,6 +4169,10 @@ const (
 
 	// ExplainGraphviz returns plan in graphviz format
 	ExplainGraphviz
+
+	// ExplainAnalyze executes the query and returns
+	// the plan annotated with execution statistics
+	ExplainAnalyze
 )
 
 // UnionType describes type of union expression
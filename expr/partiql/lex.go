@@ -25,10 +25,13 @@ import (
 	"io"
 	"math/big"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/SnellerInc/sneller/date"
 	"github.com/SnellerInc/sneller/expr"
+
+	"golang.org/x/exp/slices"
 )
 
 const eof = -1
@@ -642,8 +645,8 @@ func (e *LexerError) Error() string {
 
 var exprstar = expr.Star{}
 
-func toAggregate(op expr.AggregateOp, distinct bool, args []expr.Node, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
-	agg, err := toAggregateAux(op, distinct, args, filter, over)
+func toAggregate(op expr.AggregateOp, distinct bool, args []expr.Node, order []expr.Order, limit *expr.Integer, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
+	agg, err := toAggregateAux(op, distinct, args, order, limit, filter, over)
 	if err != nil {
 		return nil, fmt.Errorf("%v: %s", op, err)
 	}
@@ -651,7 +654,11 @@ func toAggregate(op expr.AggregateOp, distinct bool, args []expr.Node, filter ex
 	return agg, nil
 }
 
-func toAggregateAux(op expr.AggregateOp, distinct bool, args []expr.Node, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
+func toAggregateAux(op expr.AggregateOp, distinct bool, args []expr.Node, order []expr.Order, limit *expr.Integer, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
+	if op.OrderedSet() {
+		return nil, fmt.Errorf("requires WITHIN GROUP (ORDER BY ...)")
+	}
+
 	var body expr.Node
 	if len(args) > 0 {
 		body = args[0]
@@ -677,11 +684,36 @@ func toAggregateAux(op expr.AggregateOp, distinct bool, args []expr.Node, filter
 		}
 	}
 
+	if op == expr.OpArrayAgg {
+		return createArrayAgg(body, args, order, limit, filter, over)
+	}
+	if order != nil || limit != nil {
+		return nil, fmt.Errorf("does not accept ORDER BY or LIMIT")
+	}
+
 	switch op {
-	case expr.OpApproxCountDistinct:
-		return createApproxCountDistinct(body, args, filter, over)
-	case expr.OpApproxPercentile:
-		return createApproxPercentile(body, args, filter, over)
+	case expr.OpStringAgg, expr.OpObjectAgg:
+		if len(args) != 1 {
+			return nil, fmt.Errorf("accepts 2 arguments")
+		}
+		if op == expr.OpStringAgg {
+			if _, ok := args[0].(expr.String); !ok {
+				return nil, fmt.Errorf("separator has to be a constant string")
+			}
+		}
+		return &expr.Aggregate{Op: op, Inner: body, Arg: args[0], Over: over, Filter: filter}, nil
+	case expr.OpCovarPop, expr.OpCovarSamp, expr.OpCorr,
+		expr.OpRegrSlope, expr.OpRegrIntercept, expr.OpRegrR2:
+		if len(args) != 1 {
+			return nil, fmt.Errorf("accepts 2 arguments")
+		}
+		return &expr.Aggregate{Op: op, Inner: body, Arg: args[0], Over: over, Filter: filter}, nil
+	case expr.OpApproxCountDistinct, expr.OpApproxCountDistinctState, expr.OpHLLMerge, expr.OpHLLEstimate:
+		return createApproxCountDistinct(op, body, args, filter, over)
+	case expr.OpApproxPercentile, expr.OpTDigestQuantile:
+		return createApproxPercentile(op, body, args, filter, over)
+	case expr.OpApproxTopK:
+		return createApproxTopK(body, args, filter, over)
 	default:
 		if len(args) > 0 {
 			return nil, fmt.Errorf("does not accept arguments")
@@ -691,7 +723,79 @@ func toAggregateAux(op expr.AggregateOp, distinct bool, args []expr.Node, filter
 	}
 }
 
-func createApproxCountDistinct(body expr.Node, args []expr.Node, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
+// toOrderedSetAggregate creates PERCENTILE_CONT, PERCENTILE_DISC
+// or MODE from 'op(args) WITHIN GROUP (ORDER BY order)'.
+func toOrderedSetAggregate(op expr.AggregateOp, distinct bool, args []expr.Node, order []expr.Order, limit *expr.Integer, within expr.Order, filter expr.Node) (*expr.Aggregate, error) {
+	agg, err := toOrderedSetAggregateAux(op, distinct, args, order, limit, within, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", op, err)
+	}
+
+	return agg, nil
+}
+
+func toOrderedSetAggregateAux(op expr.AggregateOp, distinct bool, args []expr.Node, order []expr.Order, limit *expr.Integer, within expr.Order, filter expr.Node) (*expr.Aggregate, error) {
+	if !op.OrderedSet() {
+		return nil, fmt.Errorf("does not accept WITHIN GROUP")
+	}
+	if distinct {
+		return nil, fmt.Errorf("does not accept DISTINCT")
+	}
+	if order != nil || limit != nil {
+		return nil, fmt.Errorf("does not accept ORDER BY or LIMIT")
+	}
+
+	agg := &expr.Aggregate{
+		Op:      op,
+		OrderBy: []expr.Order{within},
+		Filter:  filter}
+	if op == expr.OpMode {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("does not accept arguments")
+		}
+		return agg, nil
+	}
+
+	if len(args) != 1 {
+		return nil, fmt.Errorf("accepts 1 argument")
+	}
+	var p expr.Float
+	switch v := args[0].(type) {
+	case expr.Float:
+		p = v
+	case expr.Integer:
+		p = expr.Float(v)
+	default:
+		return nil, fmt.Errorf("percentile p=%v has to be a constant number", expr.ToString(args[0]))
+	}
+	if p < 0.0 || p > 1.0 {
+		return nil, fmt.Errorf("percentile p=%v has to be in range [0.0, 1.0]", p)
+	}
+	agg.Arg = p
+	return agg, nil
+}
+
+func createArrayAgg(body expr.Node, args []expr.Node, order []expr.Order, limit *expr.Integer, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("does not accept arguments")
+	}
+
+	agg := &expr.Aggregate{
+		Op:      expr.OpArrayAgg,
+		Inner:   body,
+		OrderBy: order,
+		Over:    over,
+		Filter:  filter}
+	if limit != nil {
+		if *limit <= 0 {
+			return nil, fmt.Errorf("LIMIT has to be positive")
+		}
+		agg.Limit = int(*limit)
+	}
+	return agg, nil
+}
+
+func createApproxCountDistinct(op expr.AggregateOp, body expr.Node, args []expr.Node, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
 	if len(args) > 1 {
 		return nil, fmt.Errorf("accepts at most 1 argument")
 	}
@@ -711,14 +815,34 @@ func createApproxCountDistinct(body expr.Node, args []expr.Node, filter expr.Nod
 	}
 
 	return &expr.Aggregate{
-		Op:        expr.OpApproxCountDistinct,
+		Op:        op,
 		Precision: uint8(precision),
 		Inner:     body,
 		Over:      over,
 		Filter:    filter}, nil
 }
 
-func createApproxPercentile(body expr.Node, args []expr.Node, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
+func createApproxTopK(body expr.Node, args []expr.Node, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("accepts 2 arguments")
+	}
+	k, ok := args[0].(expr.Integer)
+	if !ok {
+		return nil, fmt.Errorf("k=%v has to be a constant integer", expr.ToString(args[0]))
+	}
+	if k < 1 || k > expr.ApproxTopKMax {
+		return nil, fmt.Errorf("k=%d has to be in range [1, %d]", k, expr.ApproxTopKMax)
+	}
+
+	return &expr.Aggregate{
+		Op:     expr.OpApproxTopK,
+		Inner:  body,
+		Arg:    k,
+		Over:   over,
+		Filter: filter}, nil
+}
+
+func createApproxPercentile(op expr.AggregateOp, body expr.Node, args []expr.Node, filter expr.Node, over *expr.Window) (*expr.Aggregate, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("accepts 1 argument")
 	}
@@ -730,13 +854,88 @@ func createApproxPercentile(body expr.Node, args []expr.Node, filter expr.Node,
 		return nil, fmt.Errorf("percentile p=%v has to be in range [0.0, 1.0]", p)
 	}
 	return &expr.Aggregate{
-		Op:     expr.OpApproxPercentile,
+		Op:     op,
 		Misc:   float32(p),
 		Inner:  body,
 		Over:   over,
 		Filter: filter}, nil
 }
 
+func toTableSample(method string, percent expr.Node) (*expr.TableSample, error) {
+	var m expr.SampleMethod
+	switch strings.ToUpper(method) {
+	case "SYSTEM":
+		m = expr.SampleSystem
+	case "BERNOULLI":
+		m = expr.SampleBernoulli
+	default:
+		return nil, fmt.Errorf("TABLESAMPLE: unknown sampling method %s", method)
+	}
+	var p float64
+	switch n := percent.(type) {
+	case expr.Integer:
+		p = float64(n)
+	case expr.Float:
+		p = float64(n)
+	default:
+		return nil, fmt.Errorf("TABLESAMPLE: percentage %s is out of range", expr.ToString(percent))
+	}
+	return &expr.TableSample{Method: m, Percent: p}, nil
+}
+
+// checkLateral checks the item following LATERAL
+// in a FROM clause; every item of a FROM clause can
+// reference the preceding items, so LATERAL does not
+// change the meaning of a sub-query
+func checkLateral(b expr.Binding) error {
+	if _, ok := b.Expr.(*expr.Select); !ok {
+		return fmt.Errorf("LATERAL: expected a sub-query instead of %s", expr.ToString(b.Expr))
+	}
+	return nil
+}
+
+// toPivotTable builds PIVOT (agg FOR col IN (values))
+// over the rows of from
+func toPivotTable(from expr.From, agg expr.Node, kw string, col expr.Node, values []expr.Binding) (*expr.PivotTable, error) {
+	if !strings.EqualFold(kw, "FOR") {
+		return nil, fmt.Errorf("PIVOT: unexpected %s", kw)
+	}
+	a, ok := agg.(*expr.Aggregate)
+	if !ok {
+		return nil, fmt.Errorf("PIVOT: expected an aggregate instead of %s", expr.ToString(agg))
+	}
+	if !expr.IsPath(col) {
+		return nil, fmt.Errorf("PIVOT: expected a column instead of %s", expr.ToString(col))
+	}
+	return &expr.PivotTable{From: from, Agg: a, For: col, In: values}, nil
+}
+
+// toUnnest builds the UNNEST of arrays with
+// the given names for their elements followed
+// by the name of the ordinality, if any
+func toUnnest(arrays []expr.Node, ordinality bool, names []string) (*expr.Unnest, error) {
+	want := len(arrays)
+	if ordinality {
+		want++
+	}
+	if len(names) != want {
+		return nil, fmt.Errorf("UNNEST: expected %d names instead of %d", want, len(names))
+	}
+	for i := range names {
+		if slices.Contains(names[:i], names[i]) {
+			return nil, fmt.Errorf("UNNEST: duplicate name %s", names[i])
+		}
+	}
+	u := &expr.Unnest{Ordinality: ordinality}
+	for i := range arrays {
+		u.Arrays = append(u.Arrays, expr.Bind(arrays[i], names[i]))
+	}
+	if ordinality {
+		u.At = names[len(names)-1]
+	}
+	return u, nil
+}
+
 func createCase(optionalExpr expr.Node, limbs []expr.CaseLimb, elseExpr expr.Node) expr.Node {
 	if optionalExpr != nil {
 		// "simplified" CASE
@@ -763,6 +962,8 @@ func parseExplain(s string) (expr.ExplainFormat, error) {
 		return expr.ExplainList, nil
 	case "gv", "graphviz":
 		return expr.ExplainGraphviz, nil
+	case "analyze":
+		return expr.ExplainAnalyze, nil
 	}
 
 	return expr.ExplainNone, fmt.Errorf("%q is a wrong explain type", s)
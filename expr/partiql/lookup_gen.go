@@ -8,7 +8,7 @@ import (
 
 func lookupKeyword(word []byte) (int, int) {
 	n := len(word)
-	if n < 2 || n > 21 {
+	if n < 2 || n > 27 {
 		return -1, -1
 	}
 	switch n {
@@ -96,6 +96,9 @@ func lookupKeyword(word []byte) (int, int) {
 			if equalASCIILetters4([4]byte(word), [4]byte{'C', 'A', 'S', 'E'}) {
 				return CASE, -1
 			}
+			if equalASCIILetters4([4]byte(word), [4]byte{'C', 'O', 'R', 'R'}) {
+				return AGGREGATE, int(expr.OpCorr)
+			}
 		case 'D':
 			if equalASCIILetters4([4]byte(word), [4]byte{'D', 'E', 'S', 'C'}) {
 				return DESC, -1
@@ -126,6 +129,10 @@ func lookupKeyword(word []byte) (int, int) {
 			if equalASCIILetters4([4]byte(word), [4]byte{'L', 'A', 'S', 'T'}) {
 				return LAST, -1
 			}
+		case 'M':
+			if equalASCIILetters4([4]byte(word), [4]byte{'M', 'O', 'D', 'E'}) {
+				return AGGREGATE, int(expr.OpMode)
+			}
 		case 'N':
 			if equalASCIILetters4([4]byte(word), [4]byte{'N', 'U', 'L', 'L'}) {
 				return NULL, -1
@@ -199,6 +206,10 @@ func lookupKeyword(word []byte) (int, int) {
 			if equalASCIILetters5([5]byte(word), [5]byte{'O', 'R', 'D', 'E', 'R'}) {
 				return ORDER, -1
 			}
+		case 'P':
+			if equalASCIILetters5([5]byte(word), [5]byte{'P', 'I', 'V', 'O', 'T'}) {
+				return PIVOT, -1
+			}
 		case 'R':
 			if equalASCIILetters5([5]byte(word), [5]byte{'R', 'I', 'G', 'H', 'T'}) {
 				return RIGHT, -1
@@ -264,46 +275,64 @@ func lookupKeyword(word []byte) (int, int) {
 			if equalASCIILetters6([6]byte(word), [6]byte{'U', 'T', 'C', 'N', 'O', 'W'}) {
 				return UTCNOW, -1
 			}
-		}
-	case 7:
-		switch asciiUpper(word[3]) {
-		case 'D':
-			if equalASCIILetters7([7]byte(word), [7]byte{'L', 'E', 'A', 'D', 'I', 'N', 'G'}) {
-				return LEADING, -1
+			if equalASCIILetters6([6]byte(word), [6]byte{'U', 'N', 'N', 'E', 'S', 'T'}) {
+				return UNNEST, -1
 			}
-		case 'I':
-			if equalASCIILetters7([7]byte(word), [7]byte{'S', 'I', 'M', 'I', 'L', 'A', 'R'}) {
-				return SIMILAR, -1
+		case 'W':
+			if equalASCIILetters6([6]byte(word), [6]byte{'W', 'I', 'T', 'H', 'I', 'N'}) {
+				return WITHIN, -1
 			}
-			if equalASCIILetters7([7]byte(word), [7]byte{'U', 'N', 'P', 'I', 'V', 'O', 'T'}) {
-				return UNPIVOT, -1
+		}
+	case 7:
+		switch asciiUpper(word[4]) {
+		case 'A':
+			if equalASCIILetters7([7]byte(word), [7]byte{'E', 'X', 'T', 'R', 'A', 'C', 'T'}) {
+				return EXTRACT, -1
 			}
-		case 'L':
 			if equalASCIILetters7([7]byte(word), [7]byte{'E', 'X', 'P', 'L', 'A', 'I', 'N'}) {
 				return EXPLAIN, -1
 			}
-			if equalASCII(word, []byte("BOOL_OR")) {
-				return AGGREGATE, int(expr.OpBoolOr)
+			if equalASCII(word, []byte("BIT_AND")) {
+				return AGGREGATE, int(expr.OpBitAnd)
 			}
-		case 'R':
-			if equalASCIILetters7([7]byte(word), [7]byte{'E', 'X', 'T', 'R', 'A', 'C', 'T'}) {
-				return EXTRACT, -1
+		case 'E':
+			if equalASCIILetters7([7]byte(word), [7]byte{'B', 'E', 'T', 'W', 'E', 'E', 'N'}) {
+				return BETWEEN, -1
 			}
-		case 'S':
+		case 'I':
 			if equalASCIILetters7([7]byte(word), [7]byte{'M', 'I', 'S', 'S', 'I', 'N', 'G'}) {
 				return MISSING, -1
 			}
-		case 'W':
-			if equalASCIILetters7([7]byte(word), [7]byte{'B', 'E', 'T', 'W', 'E', 'E', 'N'}) {
-				return BETWEEN, -1
+			if equalASCIILetters7([7]byte(word), [7]byte{'L', 'E', 'A', 'D', 'I', 'N', 'G'}) {
+				return LEADING, -1
 			}
-		case '_':
-			if equalASCII(word, []byte("BIT_AND")) {
-				return AGGREGATE, int(expr.OpBitAnd)
+		case 'L':
+			if equalASCIILetters7([7]byte(word), [7]byte{'S', 'I', 'M', 'I', 'L', 'A', 'R'}) {
+				return SIMILAR, -1
+			}
+		case 'R':
+			if equalASCIILetters7([7]byte(word), [7]byte{'L', 'A', 'T', 'E', 'R', 'A', 'L'}) {
+				return LATERAL, -1
 			}
+		case 'V':
+			if equalASCIILetters7([7]byte(word), [7]byte{'U', 'N', 'P', 'I', 'V', 'O', 'T'}) {
+				return UNPIVOT, -1
+			}
+		case 'X':
 			if equalASCII(word, []byte("BIT_XOR")) {
 				return AGGREGATE, int(expr.OpBitXor)
 			}
+		case 'Y':
+			if equalASCIILetters7([7]byte(word), [7]byte{'A', 'N', 'A', 'L', 'Y', 'Z', 'E'}) {
+				return ANALYZE, -1
+			}
+		case '_':
+			if equalASCII(word, []byte("BOOL_OR")) {
+				return AGGREGATE, int(expr.OpBoolOr)
+			}
+			if equalASCII(word, []byte("REGR_R2")) {
+				return AGGREGATE, int(expr.OpRegrR2)
+			}
 		}
 	case 8:
 		switch asciiUpper(word[0]) {
@@ -329,6 +358,14 @@ func lookupKeyword(word []byte) (int, int) {
 			if equalASCIILetters8([8]byte(word), [8]byte{'E', 'A', 'R', 'L', 'I', 'E', 'S', 'T'}) {
 				return AGGREGATE, int(expr.OpEarliest)
 			}
+		case 'K':
+			if equalASCIILetters8([8]byte(word), [8]byte{'K', 'U', 'R', 'T', 'O', 'S', 'I', 'S'}) {
+				return AGGREGATE, int(expr.OpKurtosis)
+			}
+		case 'S':
+			if equalASCIILetters8([8]byte(word), [8]byte{'S', 'K', 'E', 'W', 'N', 'E', 'S', 'S'}) {
+				return AGGREGATE, int(expr.OpSkewness)
+			}
 		case 'T':
 			if equalASCIILetters8([8]byte(word), [8]byte{'T', 'R', 'A', 'I', 'L', 'I', 'N', 'G'}) {
 				return TRAILING, -1
@@ -337,41 +374,123 @@ func lookupKeyword(word []byte) (int, int) {
 			if equalASCIILetters8([8]byte(word), [8]byte{'V', 'A', 'R', 'I', 'A', 'N', 'C', 'E'}) {
 				return AGGREGATE, int(expr.OpVariancePop)
 			}
+			if equalASCII(word, []byte("VAR_SAMP")) {
+				return AGGREGATE, int(expr.OpVarianceSamp)
+			}
 		}
 	case 9:
-		if equalASCII(word, []byte("DATE_DIFF")) {
-			return DATE_DIFF, -1
-		}
-		if equalASCIILetters9([9]byte(word), [9]byte{'P', 'A', 'R', 'T', 'I', 'T', 'I', 'O', 'N'}) {
-			return PARTITION, -1
+		switch asciiUpper(word[0]) {
+		case 'A':
+			if equalASCII(word, []byte("ARRAY_AGG")) {
+				return AGGREGATE, int(expr.OpArrayAgg)
+			}
+		case 'C':
+			if equalASCII(word, []byte("COVAR_POP")) {
+				return AGGREGATE, int(expr.OpCovarPop)
+			}
+		case 'D':
+			if equalASCII(word, []byte("DATE_DIFF")) {
+				return DATE_DIFF, -1
+			}
+		case 'H':
+			if equalASCII(word, []byte("HLL_MERGE")) {
+				return AGGREGATE, int(expr.OpHLLMerge)
+			}
+		case 'P':
+			if equalASCIILetters9([9]byte(word), [9]byte{'P', 'A', 'R', 'T', 'I', 'T', 'I', 'O', 'N'}) {
+				return PARTITION, -1
+			}
 		}
 	case 10:
-		switch asciiUpper(word[1]) {
+		switch asciiUpper(word[2]) {
 		case 'A':
-			if equalASCII(word, []byte("DATE_TRUNC")) {
-				return DATE_TRUNC, -1
+			if equalASCII(word, []byte("SCALED_SUM")) {
+				return AGGREGATE, int(expr.OpScaledSum)
 			}
-		case 'E':
+		case 'D':
+			if equalASCII(word, []byte("STDDEV_POP")) {
+				return AGGREGATE, int(expr.OpStdDevPop)
+			}
+		case 'G':
+			if equalASCII(word, []byte("REGR_SLOPE")) {
+				return AGGREGATE, int(expr.OpRegrSlope)
+			}
+		case 'J':
+			if equalASCII(word, []byte("OBJECT_AGG")) {
+				return AGGREGATE, int(expr.OpObjectAgg)
+			}
+		case 'N':
 			if equalASCII(word, []byte("DENSE_RANK")) {
 				return AGGREGATE, int(expr.OpDenseRank)
 			}
-		case 'O':
-			if equalASCII(word, []byte("ROW_NUMBER")) {
-				return AGGREGATE, int(expr.OpRowNumber)
+		case 'R':
+			if equalASCII(word, []byte("STRING_AGG")) {
+				return AGGREGATE, int(expr.OpStringAgg)
 			}
 		case 'T':
-			if equalASCII(word, []byte("STDDEV_POP")) {
-				return AGGREGATE, int(expr.OpStdDevPop)
+			if equalASCII(word, []byte("DATE_TRUNC")) {
+				return DATE_TRUNC, -1
+			}
+		case 'V':
+			if equalASCII(word, []byte("COVAR_SAMP")) {
+				return AGGREGATE, int(expr.OpCovarSamp)
 			}
+		case 'W':
+			if equalASCII(word, []byte("ROW_NUMBER")) {
+				return AGGREGATE, int(expr.OpRowNumber)
+			}
+		}
+	case 11:
+		if equalASCIILetters11([11]byte(word), [11]byte{'T', 'A', 'B', 'L', 'E', 'S', 'A', 'M', 'P', 'L', 'E'}) {
+			return TABLESAMPLE, -1
+		}
+		if equalASCII(word, []byte("STDDEV_SAMP")) {
+			return AGGREGATE, int(expr.OpStdDevSamp)
 		}
 	case 12:
-		if equalASCII(word, []byte("VARIANCE_POP")) {
-			return AGGREGATE, int(expr.OpVariancePop)
+		switch asciiUpper(word[0]) {
+		case 'A':
+			if equalASCII(word, []byte("APPROX_TOP_K")) {
+				return AGGREGATE, int(expr.OpApproxTopK)
+			}
+		case 'H':
+			if equalASCII(word, []byte("HLL_ESTIMATE")) {
+				return AGGREGATE, int(expr.OpHLLEstimate)
+			}
+		case 'S':
+			if equalASCII(word, []byte("SCALED_COUNT")) {
+				return AGGREGATE, int(expr.OpScaledCount)
+			}
+		case 'V':
+			if equalASCII(word, []byte("VARIANCE_POP")) {
+				return AGGREGATE, int(expr.OpVariancePop)
+			}
 		}
 	case 13:
+		if equalASCII(word, []byte("VARIANCE_SAMP")) {
+			return AGGREGATE, int(expr.OpVarianceSamp)
+		}
 		if equalASCII(word, []byte("APPROX_MEDIAN")) {
 			return AGGREGATE, int(expr.OpApproxMedian)
 		}
+		if equalASCII(word, []byte("TDIGEST_MERGE")) {
+			return AGGREGATE, int(expr.OpTDigestMerge)
+		}
+	case 14:
+		if equalASCII(word, []byte("REGR_INTERCEPT")) {
+			return AGGREGATE, int(expr.OpRegrIntercept)
+		}
+	case 15:
+		if equalASCII(word, []byte("PERCENTILE_CONT")) {
+			return AGGREGATE, int(expr.OpPercentileCont)
+		}
+		if equalASCII(word, []byte("PERCENTILE_DISC")) {
+			return AGGREGATE, int(expr.OpPercentileDisc)
+		}
+	case 16:
+		if equalASCII(word, []byte("TDIGEST_QUANTILE")) {
+			return AGGREGATE, int(expr.OpTDigestQuantile)
+		}
 	case 17:
 		if equalASCII(word, []byte("APPROX_PERCENTILE")) {
 			return AGGREGATE, int(expr.OpApproxPercentile)
@@ -383,6 +502,14 @@ func lookupKeyword(word []byte) (int, int) {
 		if equalASCII(word, []byte("APPROX_COUNT_DISTINCT")) {
 			return AGGREGATE, int(expr.OpApproxCountDistinct)
 		}
+	case 23:
+		if equalASCII(word, []byte("APPROX_PERCENTILE_STATE")) {
+			return AGGREGATE, int(expr.OpApproxPercentileState)
+		}
+	case 27:
+		if equalASCII(word, []byte("APPROX_COUNT_DISTINCT_STATE")) {
+			return AGGREGATE, int(expr.OpApproxCountDistinctState)
+		}
 	}
 	return -1, -1
 }
@@ -441,4 +568,13 @@ func equalASCIILetters9(anyCase [9]byte, upperCaseLetters [9]byte) bool {
 	return true
 }
 
-// checksum: e31bf3b2a31f75afe8eebbceb182f14b
+func equalASCIILetters11(anyCase [11]byte, upperCaseLetters [11]byte) bool {
+	for i := range upperCaseLetters {
+		if (upperCaseLetters[i]^anyCase[i])&0xdf != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// checksum: bfe279faa1c5efa3f88c80cecead60d4
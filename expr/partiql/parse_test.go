@@ -91,7 +91,39 @@ var sameq = []string{
 	`EXPLAIN AS text SELECT * FROM table`,
 	`EXPLAIN AS list SELECT * FROM table`,
 	`EXPLAIN AS graphviz SELECT * FROM table`,
+	`EXPLAIN ANALYZE SELECT * FROM table`,
 	`SELECT SNELLER_DATASHAPE(*) FROM table`,
+	`SELECT ARRAY_AGG(x) FROM table`,
+	`SELECT ARRAY_AGG(x ORDER BY y DESC NULLS LAST, z ASC NULLS FIRST LIMIT 10) FROM table GROUP BY w`,
+	`SELECT ARRAY_AGG(x LIMIT 3) FILTER (WHERE x > 0) FROM table`,
+	`SELECT STRING_AGG(x, ', ') FROM table`,
+	`SELECT OBJECT_AGG(k, v) FROM table GROUP BY w`,
+	`SELECT PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY x ASC NULLS FIRST) FROM table`,
+	`SELECT PERCENTILE_DISC(0.9) WITHIN GROUP (ORDER BY x DESC NULLS FIRST) FILTER (WHERE x > 0) FROM table GROUP BY w`,
+	`SELECT MODE() WITHIN GROUP (ORDER BY x ASC NULLS FIRST) FROM table GROUP BY w`,
+	`SELECT APPROX_TOP_K(x, 10) FROM table`,
+	`SELECT APPROX_TOP_K(x, 3) FILTER (WHERE x > 0) FROM table GROUP BY w`,
+	`SELECT APPROX_COUNT_DISTINCT_STATE(x, 12) FROM table GROUP BY w`,
+	`SELECT HLL_ESTIMATE(s, 12) FROM table`,
+	`SELECT HLL_MERGE(s) FROM table GROUP BY w`,
+	`SELECT APPROX_PERCENTILE_STATE(x) FROM table`,
+	`SELECT TDIGEST_QUANTILE(s, 0.9) FROM table GROUP BY w`,
+	`SELECT TDIGEST_MERGE(s) FILTER (WHERE w > 0) FROM table`,
+	"SELECT x FROM table TABLESAMPLE SYSTEM (10)",
+	"SELECT x FROM table AS t TABLESAMPLE BERNOULLI (0.5) WHERE t.y > 0",
+	"SELECT SCALED_COUNT(*), SCALED_SUM(x) FROM table TABLESAMPLE SYSTEM (1) GROUP BY y",
+	`SELECT VAR_SAMP(x), STDDEV_SAMP(x), SKEWNESS(x), KURTOSIS(x) FROM table`,
+	`SELECT COVAR_POP(y, x), COVAR_SAMP(y, x), CORR(y, x) FROM table GROUP BY w`,
+	`SELECT REGR_SLOPE(y, x), REGR_INTERCEPT(y, x), REGR_R2(y, x) FILTER (WHERE x > 0) FROM table`,
+	`SELECT item, idx FROM table AS x CROSS JOIN x.items AS item AT idx`,
+	`SELECT item, idx FROM table AS x CROSS JOIN UNNEST(x.items) WITH ORDINALITY AS (item, idx) WHERE idx > 1`,
+	`SELECT a, b FROM table AS x CROSS JOIN UNNEST(x.a, x.b) AS (a, b)`,
+	`SELECT a, b, i FROM table AS x CROSS JOIN UNNEST(x.a, x.b) WITH ORDINALITY AS (a, b, i)`,
+	`PIVOT v AT k FROM table`,
+	`PIVOT COUNT(*) AT status FROM table WHERE x > 0 GROUP BY status`,
+	`SELECT day, us, eu FROM table PIVOT (SUM(amount) FOR region IN ('us' AS us, 'eu' AS eu)) WHERE day > 1`,
+	`SELECT * FROM table AS t PIVOT (COUNT(*) FOR t.status IN (200, 404 AS not_found))`,
+	`SELECT x.status FROM (PIVOT c AT k FROM table) AS x`,
 	`SELECT * FROM table1 UNION SELECT * FROM table2`,
 	`SELECT * FROM table1 UNION ALL SELECT * FROM table2`,
 	`SELECT * FROM table1 UNION SELECT * FROM table2 UNION ALL SELECT * FROM table3 UNION SELECT * FROM table4`,
@@ -217,6 +249,24 @@ func TestParseNormalization(t *testing.T) {
 			`select * from table where x IN (1)`,
 			`SELECT * FROM table WHERE x = 1`,
 		},
+		{
+			// LATERAL is accepted and has no effect
+			`SELECT r.id, l.x FROM table AS r CROSS JOIN LATERAL (SELECT x FROM r.xs AS x) AS l`,
+			`SELECT r.id, l.x FROM table AS r CROSS JOIN (SELECT x FROM r.xs AS x) AS l`,
+		},
+		{
+			`SELECT r.id, l.x FROM table AS r, LATERAL (SELECT x FROM r.xs AS x) AS l`,
+			`SELECT r.id, l.x FROM table AS r CROSS JOIN (SELECT x FROM r.xs AS x) AS l`,
+		},
+		{
+			// an UNNEST of one array without ORDINALITY
+			`SELECT item FROM table AS x, UNNEST(x.items) AS item`,
+			`SELECT item FROM table AS x CROSS JOIN x.items AS item`,
+		},
+		{
+			`SELECT item FROM table AS x, UNNEST(x.items) AS (item)`,
+			`SELECT item FROM table AS x CROSS JOIN x.items AS item`,
+		},
 		{
 			// test COALESCE -> CASE
 			`SELECT COALESCE(x, y) FROM foo`,
@@ -511,6 +561,110 @@ func TestParseErrors(t *testing.T) {
 			query: `SELECT APPROX_COUNT_DISTINCT(x, 'test') FROM table`,
 			msg:   `precision has to be a constant integer`,
 		},
+		{
+			query: `SELECT SUM(x ORDER BY y) FROM table`,
+			msg:   `SUM: does not accept ORDER BY or LIMIT`,
+		},
+		{
+			query: `SELECT ARRAY_AGG(x LIMIT 0) FROM table`,
+			msg:   `ARRAY_AGG: LIMIT has to be positive`,
+		},
+		{
+			query: `SELECT STRING_AGG(x, y) FROM table`,
+			msg:   `STRING_AGG: separator has to be a constant string`,
+		},
+		{
+			query: `SELECT OBJECT_AGG(k) FROM table`,
+			msg:   `OBJECT_AGG: accepts 2 arguments`,
+		},
+		{
+			query: `SELECT PERCENTILE_CONT(0.5) FROM table`,
+			msg:   `PERCENTILE_CONT: requires WITHIN GROUP (ORDER BY ...)`,
+		},
+		{
+			query: `SELECT PERCENTILE_CONT(1.5) WITHIN GROUP (ORDER BY x ASC NULLS FIRST) FROM table`,
+			msg:   `PERCENTILE_CONT: percentile p=1.5 has to be in range [0.0, 1.0]`,
+		},
+		{
+			query: `SELECT PERCENTILE_DISC(y) WITHIN GROUP (ORDER BY x ASC NULLS FIRST) FROM table`,
+			msg:   `PERCENTILE_DISC: percentile p=y has to be a constant number`,
+		},
+		{
+			query: `SELECT MODE(x) WITHIN GROUP (ORDER BY x ASC NULLS FIRST) FROM table`,
+			msg:   `MODE: does not accept arguments`,
+		},
+		{
+			query: `SELECT SUM(x) WITHIN GROUP (ORDER BY x ASC NULLS FIRST) FROM table`,
+			msg:   `SUM: does not accept WITHIN GROUP`,
+		},
+		{
+			query: `SELECT APPROX_TOP_K(x) FROM table`,
+			msg:   `APPROX_TOP_K: accepts 2 arguments`,
+		},
+		{
+			query: `SELECT APPROX_TOP_K(x, y) FROM table`,
+			msg:   `APPROX_TOP_K: k=y has to be a constant integer`,
+		},
+		{
+			query: `SELECT APPROX_TOP_K(x, 0) FROM table`,
+			msg:   `APPROX_TOP_K: k=0 has to be in range [1, 10000]`,
+		},
+		{
+			query: `SELECT x FROM table TABLESAMPLE RANDOM (10)`,
+			msg:   `TABLESAMPLE: unknown sampling method RANDOM`,
+		},
+		{
+			query: `SELECT r.id FROM table AS r CROSS JOIN LATERAL r.xs AS x`,
+			msg:   `LATERAL: expected a sub-query instead of r.xs`,
+		},
+		{
+			query: `SELECT a FROM table AS x, UNNEST(x.a, x.b) AS (a)`,
+			msg:   `UNNEST: expected 2 names instead of 1`,
+		},
+		{
+			query: `SELECT a FROM table AS x, UNNEST(x.a) WITH ORDINALITY AS a`,
+			msg:   `UNNEST: expected 2 names instead of 1`,
+		},
+		{
+			query: `SELECT a FROM table AS x, UNNEST(x.a, x.b) AS (a, a)`,
+			msg:   `UNNEST: duplicate name a`,
+		},
+		{
+			query: `SELECT a FROM table AS x, UNNEST(x.a) WITH POSITION AS (a, i)`,
+			msg:   `UNNEST: unexpected WITH POSITION`,
+		},
+		{
+			query: `SELECT * FROM table PIVOT (SUM(x) FRO y IN ('a'))`,
+			msg:   `PIVOT: unexpected FRO`,
+		},
+		{
+			query: `SELECT * FROM table PIVOT (x FOR y IN ('a'))`,
+			msg:   `PIVOT: expected an aggregate instead of x`,
+		},
+		{
+			query: `SELECT SCALED_SUM(*) FROM table`,
+			msg:   `SCALED_SUM: does not accept '*'`,
+		},
+		{
+			query: `SELECT HLL_ESTIMATE(s, 2) FROM table`,
+			msg:   `precision has to be in range [4, 16]`,
+		},
+		{
+			query: `SELECT TDIGEST_QUANTILE(s) FROM table`,
+			msg:   `TDIGEST_QUANTILE: accepts 1 argument`,
+		},
+		{
+			query: `SELECT TDIGEST_QUANTILE(s, 2.0) FROM table`,
+			msg:   `TDIGEST_QUANTILE: percentile p=2 has to be in range [0.0, 1.0]`,
+		},
+		{
+			query: `SELECT CORR(y) FROM table`,
+			msg:   `CORR: accepts 2 arguments`,
+		},
+		{
+			query: `SELECT VAR_SAMP(x, y) FROM table`,
+			msg:   `VAR_SAMP: does not accept arguments`,
+		},
 		{
 			query: `SELECT 1.test`,
 			msg:   `strconv.ParseFloat: parsing "1.test": invalid syntax`,
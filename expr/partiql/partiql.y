@@ -29,6 +29,7 @@
 package partiql
 
 import (
+    "fmt"
     "strings"
 
     "github.com/SnellerInc/sneller/expr"
@@ -55,13 +56,14 @@ import (
     values   []expr.Node
     orders   []expr.Order
     unions   []unionItem
+    strs     []string
 }
 
 %token ERROR EOF
 %left UNION
-%token SELECT FROM WHERE GROUP ORDER BY HAVING LIMIT OFFSET WITH INTO EXPLAIN
+%token SELECT FROM WHERE GROUP ORDER BY HAVING LIMIT OFFSET WITH INTO EXPLAIN ANALYZE
 %token DISTINCT ALL AS EXISTS NULLS FIRST LAST ASC DESC UNPIVOT AT
-%token PARTITION
+%token PARTITION TABLESAMPLE LATERAL UNNEST PIVOT
 %token VALUE
 %token LEADING TRAILING BOTH
 %right COALESCE NULLIF EXTRACT DATE_TRUNC
@@ -80,7 +82,7 @@ import (
 %right '!' '~' NOT
 %left BETWEEN CASE WHEN THEN ELSE END TO TRIM
 %left <empty> EQ NE LT LE GT GE
-%left <empty> SIMILAR REGEXP_MATCH_CI ILIKE LIKE IN IS OVER FILTER ESCAPE
+%left <empty> SIMILAR REGEXP_MATCH_CI ILIKE LIKE IN IS OVER FILTER ESCAPE WITHIN
 %left <empty> '|'
 %left <empty> '^'
 %left <empty> '&'
@@ -100,13 +102,14 @@ import (
 %type <expr> optional_filter
 %type <expr> unpivot unpivot_source
 %type <with> maybe_cte_bindings cte_bindings
-%type <yesno> ascdesc nullslast maybe_distinct
+%type <yesno> ascdesc nullslast maybe_distinct maybe_ordinality
 %type <str> identifier
+%type <strs> identifier_list
 %type <integer> literal_int
 %type <sel> select_stmt
 %type <selinto> select_with_into_stmt
 %type <bindings> group_expr binding_list
-%type <bind> value_binding
+%type <bind> value_binding join_binding
 %type <from> from_expr lhs_from_expr
 %type <values> partition_expr value_list any_value_list field_value_list field_value_pair agg_value_list maybe_toplevel_distinct
 %type <order> order_one_col
@@ -140,6 +143,11 @@ SELECT maybe_toplevel_distinct binding_list maybe_into from_expr where_expr grou
     distinct, distinctExpr := decodeDistinct($2)
     $$.sel = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: $3, From: $5, Where: $6, GroupBy: $7, Having: $8, OrderBy: $9, Limit: $10, Offset: $11}
     $$.into = $4
+} |
+PIVOT expr AT expr lhs_from_expr where_expr group_expr having_expr
+{
+    $$.sel = &expr.Select{Pivot: &expr.Pivot{Value: $2, At: $4}, From: $5, Where: $6, GroupBy: $7, Having: $8}
+    $$.into = nil
 }
 
 select_stmt:
@@ -147,11 +155,16 @@ SELECT maybe_toplevel_distinct binding_list from_expr where_expr group_expr havi
 {
     distinct, distinctExpr := decodeDistinct($2)
     $$ = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: $3, From: $4, Where: $5, GroupBy: $6, Having: $7, OrderBy: $8, Limit: $9, Offset: $10}
+} |
+PIVOT expr AT expr lhs_from_expr where_expr group_expr having_expr
+{
+    $$ = &expr.Select{Pivot: &expr.Pivot{Value: $2, At: $4}, From: $5, Where: $6, GroupBy: $7, Having: $8}
 }
 
 maybe_explain:
   EXPLAIN               { $$ = "default" }
 | EXPLAIN AS identifier { $$ = $3 }
+| EXPLAIN ANALYZE       { $$ = "analyze" }
 |                       { $$ = "" }
 
 maybe_into:
@@ -235,15 +248,31 @@ datum_or_parens
 }
 | AGGREGATE '(' ')' optional_filter maybe_window
 {
-  agg, err := toAggregate(expr.AggregateOp($1), false, nil, $4, $5)
+  agg, err := toAggregate(expr.AggregateOp($1), false, nil, nil, nil, $4, $5)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = agg
+}
+| AGGREGATE '(' maybe_distinct agg_value_list order_expr limit_expr ')' optional_filter maybe_window
+{
+  agg, err := toAggregate(expr.AggregateOp($1), $3, $4, $5, $6, $8, $9)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = agg
+}
+| AGGREGATE '(' ')' WITHIN GROUP '(' ORDER BY order_one_col ')' optional_filter
+{
+  agg, err := toOrderedSetAggregate(expr.AggregateOp($1), false, nil, nil, nil, $9, $11)
   if err != nil {
     yylex.Error(err.Error())
   }
   $$ = agg
 }
-| AGGREGATE '(' maybe_distinct agg_value_list ')' optional_filter maybe_window
+| AGGREGATE '(' maybe_distinct agg_value_list order_expr limit_expr ')' WITHIN GROUP '(' ORDER BY order_one_col ')' optional_filter
 {
-  agg, err := toAggregate(expr.AggregateOp($1), $3, $4, $6, $7)
+  agg, err := toOrderedSetAggregate(expr.AggregateOp($1), $3, $4, $5, $6, $13, $15)
   if err != nil {
     yylex.Error(err.Error())
   }
@@ -633,9 +662,78 @@ lhs_from_expr { $$ = $1 } |
 
 lhs_from_expr:
 FROM value_binding { $$ = &expr.Table{Binding: $2} } |
-lhs_from_expr cross_symbol value_binding { $$ = &expr.Join{Kind: expr.CrossJoin, Left: $1, Right: $3} } |
-lhs_from_expr join_kind value_binding ON expr
-{ $$ = &expr.Join{Kind: $2, Left: $1, Right: $3, On: $5 } }
+FROM value_binding TABLESAMPLE identifier '(' NUMBER ')'
+{
+  sample, err := toTableSample($4, $6)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = &expr.Table{Binding: $2, Sample: sample}
+} |
+lhs_from_expr cross_symbol join_binding { $$ = &expr.Join{Kind: expr.CrossJoin, Left: $1, Right: $3} } |
+lhs_from_expr cross_symbol LATERAL value_binding
+{
+  if err := checkLateral($4); err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = &expr.Join{Kind: expr.CrossJoin, Left: $1, Right: $4}
+} |
+lhs_from_expr join_kind join_binding ON expr
+{ $$ = &expr.Join{Kind: $2, Left: $1, Right: $3, On: $5 } } |
+lhs_from_expr join_kind LATERAL value_binding ON expr
+{
+  if err := checkLateral($4); err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = &expr.Join{Kind: $2, Left: $1, Right: $4, On: $6 }
+} |
+lhs_from_expr PIVOT '(' expr identifier datum IN '(' binding_list ')' ')'
+{
+  p, err := toPivotTable($1, $4, $5, $6, $9)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = p
+}
+
+// match the right-hand side of a join,
+// which may un-nest arrays with positions
+join_binding:
+value_binding { $$ = $1 } |
+expr AS identifier AT identifier
+{
+  $$ = expr.Bind(&expr.Unnest{Arrays: []expr.Binding{expr.Bind($1, $3)}, At: $5}, "")
+} |
+UNNEST '(' value_list ')' maybe_ordinality AS identifier
+{
+  u, err := toUnnest($3, $5, []string{$7})
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = expr.Bind(u, "")
+} |
+UNNEST '(' value_list ')' maybe_ordinality AS '(' identifier_list ')'
+{
+  u, err := toUnnest($3, $5, $8)
+  if err != nil {
+    yylex.Error(err.Error())
+  }
+  $$ = expr.Bind(u, "")
+}
+
+maybe_ordinality:
+WITH identifier
+{
+  if !strings.EqualFold($2, "ORDINALITY") {
+    yylex.Error(fmt.Sprintf("UNNEST: unexpected WITH %s", $2))
+  }
+  $$ = true
+} |
+{ $$ = false }
+
+identifier_list:
+identifier { $$ = []string{$1} } |
+identifier_list ',' identifier { $$ = append($1, $3) }
 
 literal_int:
 NUMBER { var idxerr error; $$, idxerr = toint($1); if idxerr != nil { yylex.Error(idxerr.Error()) } }
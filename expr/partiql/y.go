@@ -17,14 +17,18 @@
 
 package partiql
 
+import __yyfmt__ "fmt"
+
+//line partiql.y:29
+
 import (
-	__yyfmt__ "fmt"
+	"fmt"
 	"strings"
 
 	"github.com/SnellerInc/sneller/expr"
-) //line partiql.y:29
+)
 
-//line partiql.y:38
+//line partiql.y:39
 type yySymType struct {
 	yys      int
 	bytes    []byte
@@ -46,6 +50,7 @@ type yySymType struct {
 	values   []expr.Node
 	orders   []expr.Order
 	unions   []unionItem
+	strs     []string
 }
 
 const ERROR = 57346
@@ -63,83 +68,89 @@ const OFFSET = 57357
 const WITH = 57358
 const INTO = 57359
 const EXPLAIN = 57360
-const DISTINCT = 57361
-const ALL = 57362
-const AS = 57363
-const EXISTS = 57364
-const NULLS = 57365
-const FIRST = 57366
-const LAST = 57367
-const ASC = 57368
-const DESC = 57369
-const UNPIVOT = 57370
-const AT = 57371
-const PARTITION = 57372
-const VALUE = 57373
-const LEADING = 57374
-const TRAILING = 57375
-const BOTH = 57376
-const COALESCE = 57377
-const NULLIF = 57378
-const EXTRACT = 57379
-const DATE_TRUNC = 57380
-const CAST = 57381
-const UTCNOW = 57382
-const DATE_ADD = 57383
-const DATE_BIN = 57384
-const DATE_DIFF = 57385
-const EARLIEST = 57386
-const LATEST = 57387
-const JOIN = 57388
-const LEFT = 57389
-const RIGHT = 57390
-const CROSS = 57391
-const INNER = 57392
-const OUTER = 57393
-const FULL = 57394
-const ON = 57395
-const APPROX_COUNT_DISTINCT = 57396
-const AGGREGATE = 57397
-const ID = 57398
-const NULL = 57399
-const TRUE = 57400
-const FALSE = 57401
-const MISSING = 57402
-const OR = 57403
-const AND = 57404
-const NOT = 57405
-const BETWEEN = 57406
-const CASE = 57407
-const WHEN = 57408
-const THEN = 57409
-const ELSE = 57410
-const END = 57411
-const TO = 57412
-const TRIM = 57413
-const EQ = 57414
-const NE = 57415
-const LT = 57416
-const LE = 57417
-const GT = 57418
-const GE = 57419
-const SIMILAR = 57420
-const REGEXP_MATCH_CI = 57421
-const ILIKE = 57422
-const LIKE = 57423
-const IN = 57424
-const IS = 57425
-const OVER = 57426
-const FILTER = 57427
-const ESCAPE = 57428
-const SHIFT_LEFT_LOGICAL = 57429
-const SHIFT_RIGHT_ARITHMETIC = 57430
-const SHIFT_RIGHT_LOGICAL = 57431
-const CONCAT = 57432
-const APPEND = 57433
-const NEGATION_PRECEDENCE = 57434
-const NUMBER = 57435
-const ION = 57436
-const STRING = 57437
+const ANALYZE = 57361
+const DISTINCT = 57362
+const ALL = 57363
+const AS = 57364
+const EXISTS = 57365
+const NULLS = 57366
+const FIRST = 57367
+const LAST = 57368
+const ASC = 57369
+const DESC = 57370
+const UNPIVOT = 57371
+const AT = 57372
+const PARTITION = 57373
+const TABLESAMPLE = 57374
+const LATERAL = 57375
+const UNNEST = 57376
+const PIVOT = 57377
+const VALUE = 57378
+const LEADING = 57379
+const TRAILING = 57380
+const BOTH = 57381
+const COALESCE = 57382
+const NULLIF = 57383
+const EXTRACT = 57384
+const DATE_TRUNC = 57385
+const CAST = 57386
+const UTCNOW = 57387
+const DATE_ADD = 57388
+const DATE_BIN = 57389
+const DATE_DIFF = 57390
+const EARLIEST = 57391
+const LATEST = 57392
+const JOIN = 57393
+const LEFT = 57394
+const RIGHT = 57395
+const CROSS = 57396
+const INNER = 57397
+const OUTER = 57398
+const FULL = 57399
+const ON = 57400
+const APPROX_COUNT_DISTINCT = 57401
+const AGGREGATE = 57402
+const ID = 57403
+const NULL = 57404
+const TRUE = 57405
+const FALSE = 57406
+const MISSING = 57407
+const OR = 57408
+const AND = 57409
+const NOT = 57410
+const BETWEEN = 57411
+const CASE = 57412
+const WHEN = 57413
+const THEN = 57414
+const ELSE = 57415
+const END = 57416
+const TO = 57417
+const TRIM = 57418
+const EQ = 57419
+const NE = 57420
+const LT = 57421
+const LE = 57422
+const GT = 57423
+const GE = 57424
+const SIMILAR = 57425
+const REGEXP_MATCH_CI = 57426
+const ILIKE = 57427
+const LIKE = 57428
+const IN = 57429
+const IS = 57430
+const OVER = 57431
+const FILTER = 57432
+const ESCAPE = 57433
+const WITHIN = 57434
+const SHIFT_LEFT_LOGICAL = 57435
+const SHIFT_RIGHT_ARITHMETIC = 57436
+const SHIFT_RIGHT_LOGICAL = 57437
+const CONCAT = 57438
+const APPEND = 57439
+const NEGATION_PRECEDENCE = 57440
+const NUMBER = 57441
+const ION = 57442
+const STRING = 57443
 
 var yyToknames = [...]string{
 	"$end",
@@ -160,6 +171,7 @@ var yyToknames = [...]string{
 	"WITH",
 	"INTO",
 	"EXPLAIN",
+	"ANALYZE",
 	"DISTINCT",
 	"ALL",
 	"AS",
@@ -172,6 +184,10 @@ var yyToknames = [...]string{
 	"UNPIVOT",
 	"AT",
 	"PARTITION",
+	"TABLESAMPLE",
+	"LATERAL",
+	"UNNEST",
+	"PIVOT",
 	"VALUE",
 	"LEADING",
 	"TRAILING",
@@ -237,6 +253,7 @@ var yyToknames = [...]string{
 	"OVER",
 	"FILTER",
 	"ESCAPE",
+	"WITHIN",
 	"'|'",
 	"'^'",
 	"'&'",
@@ -273,407 +290,486 @@ var yyExca = [...]int8{
 
 const yyPrivate = 57344
 
-const yyLast = 2001
+const yyLast = 2514
 
 var yyAct = [...]int16{
-	25, 383, 205, 379, 184, 352, 368, 324, 245, 300,
-	280, 28, 218, 125, 134, 211, 207, 331, 206, 330,
-	23, 24, 76, 77, 78, 79, 80, 81, 82, 299,
-	295, 101, 71, 72, 73, 75, 74, 76, 77, 78,
-	79, 80, 81, 82, 114, 115, 116, 118, 298, 123,
-	294, 126, 240, 239, 237, 236, 234, 189, 128, 72,
-	73, 75, 74, 76, 77, 78, 79, 80, 81, 82,
-	159, 142, 143, 144, 145, 146, 147, 148, 149, 150,
-	151, 152, 153, 154, 133, 158, 20, 137, 41, 160,
-	161, 162, 163, 164, 165, 11, 13, 172, 173, 18,
-	156, 155, 207, 185, 186, 187, 166, 120, 62, 81,
-	82, 297, 194, 185, 68, 233, 232, 200, 73, 75,
-	74, 76, 77, 78, 79, 80, 81, 82, 246, 301,
-	185, 238, 157, 170, 214, 122, 78, 79, 80, 81,
-	82, 305, 185, 251, 183, 252, 231, 131, 217, 169,
-	171, 168, 167, 235, 47, 139, 140, 119, 229, 85,
-	87, 83, 84, 69, 98, 273, 272, 181, 70, 71,
-	72, 73, 75, 74, 76, 77, 78, 79, 80, 81,
-	82, 248, 213, 139, 253, 212, 210, 174, 177, 178,
-	176, 209, 14, 12, 48, 175, 267, 57, 385, 56,
-	201, 52, 50, 51, 53, 304, 303, 179, 204, 255,
-	293, 343, 275, 61, 276, 255, 277, 215, 255, 268,
-	282, 138, 339, 12, 274, 292, 136, 57, 230, 56,
-	279, 52, 50, 51, 53, 241, 243, 244, 242, 255,
-	254, 278, 283, 284, 269, 261, 262, 296, 49, 55,
-	54, 216, 306, 307, 208, 132, 309, 310, 193, 312,
-	313, 314, 66, 316, 317, 255, 318, 319, 390, 224,
-	226, 227, 223, 225, 65, 228, 65, 365, 49, 55,
-	54, 222, 260, 259, 258, 10, 332, 302, 141, 130,
-	323, 270, 271, 129, 113, 112, 111, 110, 109, 108,
-	107, 106, 12, 65, 105, 335, 104, 103, 102, 337,
-	99, 60, 334, 315, 311, 192, 191, 190, 188, 327,
-	58, 348, 289, 329, 139, 287, 354, 290, 356, 328,
-	288, 351, 291, 286, 359, 285, 358, 361, 202, 321,
-	322, 362, 363, 364, 360, 396, 203, 355, 397, 398,
-	59, 22, 16, 19, 7, 17, 3, 367, 6, 325,
-	380, 369, 372, 371, 21, 370, 377, 326, 353, 281,
-	333, 384, 381, 185, 378, 63, 219, 386, 263, 136,
-	22, 9, 388, 389, 42, 15, 220, 2, 195, 182,
-	221, 384, 394, 382, 196, 197, 198, 31, 32, 38,
-	37, 33, 39, 34, 35, 36, 247, 124, 127, 357,
-	349, 350, 135, 8, 180, 395, 391, 29, 12, 48,
-	5, 4, 57, 117, 56, 27, 52, 50, 51, 53,
-	121, 250, 100, 45, 44, 64, 30, 1, 0, 0,
-	0, 0, 40, 42, 0, 0, 0, 0, 0, 46,
-	0, 0, 0, 0, 0, 0, 31, 32, 38, 37,
-	33, 39, 34, 35, 36, 43, 266, 0, 0, 0,
-	0, 0, 0, 49, 55, 54, 29, 12, 48, 0,
-	0, 57, 0, 56, 0, 52, 50, 51, 53, 0,
-	0, 0, 45, 44, 0, 30, 0, 0, 0, 0,
-	0, 40, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 265, 264, 0, 0,
-	0, 0, 0, 0, 43, 26, 97, 96, 0, 86,
-	95, 94, 49, 55, 54, 0, 0, 0, 0, 88,
-	89, 90, 91, 92, 93, 85, 87, 83, 84, 69,
-	98, 0, 0, 0, 70, 71, 72, 73, 75, 74,
-	76, 77, 78, 79, 80, 81, 82, 42, 0, 0,
+	58, 239, 393, 444, 200, 56, 299, 352, 181, 325,
+	303, 57, 20, 371, 39, 328, 21, 274, 215, 214,
+	118, 206, 348, 347, 96, 14, 40, 16, 202, 49,
+	201, 48, 298, 44, 42, 43, 45, 109, 110, 111,
+	294, 116, 293, 119, 34, 234, 233, 231, 230, 228,
+	121, 13, 15, 186, 155, 154, 127, 50, 152, 151,
+	52, 202, 134, 433, 136, 297, 138, 139, 140, 141,
+	142, 143, 144, 145, 146, 147, 148, 149, 150, 296,
+	124, 41, 47, 46, 156, 157, 158, 159, 160, 161,
+	227, 113, 169, 170, 76, 77, 226, 241, 182, 183,
+	184, 115, 241, 132, 417, 300, 162, 191, 182, 14,
+	241, 232, 240, 49, 125, 48, 197, 44, 42, 43,
+	45, 67, 68, 70, 69, 71, 72, 73, 74, 75,
+	76, 77, 212, 153, 163, 306, 182, 113, 182, 180,
+	229, 217, 112, 266, 222, 218, 225, 73, 74, 75,
+	76, 77, 265, 211, 167, 223, 473, 199, 71, 72,
+	73, 74, 75, 76, 77, 41, 47, 46, 449, 198,
+	166, 168, 165, 164, 250, 163, 219, 246, 243, 247,
+	205, 248, 471, 470, 210, 204, 129, 468, 112, 171,
+	174, 175, 173, 262, 250, 430, 178, 172, 224, 235,
+	237, 238, 236, 208, 250, 292, 207, 268, 472, 269,
+	250, 289, 250, 263, 273, 68, 70, 69, 71, 72,
+	73, 74, 75, 76, 77, 451, 267, 450, 286, 250,
+	249, 434, 272, 390, 363, 359, 322, 275, 291, 271,
+	176, 290, 305, 216, 264, 203, 295, 307, 308, 256,
+	257, 310, 311, 190, 313, 314, 315, 130, 317, 318,
+	129, 319, 320, 278, 418, 287, 288, 14, 463, 459,
+	270, 399, 255, 254, 163, 253, 327, 331, 331, 281,
+	283, 284, 280, 282, 12, 285, 452, 411, 330, 330,
+	323, 279, 324, 333, 304, 278, 376, 14, 129, 350,
+	346, 349, 316, 129, 335, 354, 302, 209, 137, 135,
+	357, 281, 283, 284, 280, 282, 123, 285, 108, 107,
+	106, 105, 368, 279, 104, 103, 102, 101, 100, 99,
+	98, 97, 94, 369, 312, 189, 379, 188, 187, 185,
+	409, 374, 370, 377, 62, 340, 378, 18, 338, 381,
+	341, 380, 389, 339, 342, 337, 394, 395, 391, 336,
+	385, 396, 397, 398, 387, 54, 10, 54, 343, 429,
+	344, 453, 454, 404, 441, 457, 132, 182, 408, 405,
+	402, 53, 403, 401, 8, 407, 345, 7, 382, 383,
+	384, 220, 416, 55, 11, 55, 122, 412, 51, 221,
+	425, 19, 126, 3, 448, 6, 445, 353, 372, 465,
+	431, 428, 426, 414, 182, 394, 427, 436, 413, 394,
+	406, 439, 435, 437, 410, 432, 373, 355, 461, 305,
+	388, 438, 326, 301, 443, 66, 67, 68, 70, 69,
+	71, 72, 73, 74, 75, 76, 77, 455, 351, 275,
+	456, 258, 216, 460, 17, 163, 276, 2, 192, 179,
+	277, 392, 242, 117, 120, 464, 394, 386, 469, 9,
+	466, 447, 177, 440, 446, 475, 419, 5, 4, 133,
+	60, 114, 245, 95, 128, 1, 0, 0, 0, 0,
+	0, 0, 0, 458, 0, 0, 0, 0, 0, 0,
+	35, 0, 462, 0, 0, 0, 61, 0, 467, 0,
+	334, 332, 0, 0, 0, 0, 474, 24, 25, 31,
+	30, 26, 32, 27, 28, 29, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 22, 14, 40,
+	0, 0, 49, 0, 48, 0, 44, 42, 43, 45,
+	0, 0, 0, 38, 37, 0, 23, 0, 0, 0,
+	35, 0, 33, 0, 0, 0, 61, 0, 0, 0,
+	329, 332, 0, 0, 0, 0, 0, 24, 25, 31,
+	30, 26, 32, 27, 28, 29, 36, 59, 0, 0,
+	0, 0, 0, 0, 41, 47, 46, 22, 14, 40,
+	0, 0, 49, 0, 48, 0, 44, 42, 43, 45,
+	0, 0, 0, 38, 37, 0, 23, 0, 0, 0,
+	35, 0, 33, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 193, 194, 195, 24, 25, 31,
+	30, 26, 32, 27, 28, 29, 36, 59, 0, 0,
+	0, 0, 0, 0, 41, 47, 46, 22, 14, 40,
+	0, 0, 49, 0, 48, 0, 44, 42, 43, 45,
+	0, 0, 0, 38, 37, 0, 23, 0, 0, 0,
+	35, 0, 33, 0, 0, 0, 61, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 24, 25, 31,
+	30, 26, 32, 27, 28, 29, 36, 0, 0, 0,
+	0, 0, 0, 0, 41, 47, 46, 22, 14, 40,
+	0, 0, 49, 0, 48, 0, 44, 42, 43, 45,
+	0, 0, 0, 38, 37, 0, 23, 0, 0, 0,
+	0, 0, 33, 0, 54, 0, 0, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	31, 32, 38, 37, 33, 39, 34, 35, 36, 0,
+	35, 0, 0, 0, 0, 0, 36, 59, 0, 0,
+	0, 0, 55, 0, 41, 47, 46, 24, 25, 31,
+	30, 26, 32, 27, 28, 29, 0, 0, 0, 0,
+	0, 0, 261, 0, 0, 0, 0, 22, 14, 40,
+	0, 0, 49, 0, 48, 0, 44, 42, 43, 45,
+	0, 0, 0, 38, 37, 0, 23, 0, 0, 0,
+	0, 0, 33, 80, 82, 78, 79, 64, 93, 0,
+	0, 0, 0, 65, 66, 67, 68, 70, 69, 71,
+	72, 73, 74, 75, 76, 77, 36, 260, 259, 0,
+	0, 0, 0, 0, 41, 47, 46, 92, 91, 0,
+	81, 90, 89, 0, 0, 0, 0, 0, 0, 0,
+	83, 84, 85, 86, 87, 88, 80, 82, 78, 79,
+	64, 93, 35, 0, 0, 0, 65, 66, 67, 68,
+	70, 69, 71, 72, 73, 74, 75, 76, 77, 24,
+	25, 31, 30, 26, 32, 27, 28, 29, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 22,
+	14, 40, 0, 0, 49, 0, 48, 0, 44, 42,
+	43, 45, 0, 0, 0, 38, 37, 0, 23, 0,
+	0, 0, 35, 0, 33, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 24,
+	25, 31, 30, 26, 32, 27, 28, 29, 36, 244,
+	0, 0, 0, 0, 0, 0, 41, 47, 46, 22,
+	14, 40, 0, 196, 49, 0, 48, 0, 44, 42,
+	43, 45, 0, 0, 0, 38, 37, 0, 23, 0,
+	0, 0, 35, 0, 33, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 24,
+	25, 31, 30, 26, 32, 27, 28, 29, 36, 0,
+	0, 0, 0, 0, 0, 0, 41, 47, 46, 22,
+	14, 40, 0, 0, 49, 0, 48, 0, 44, 42,
+	43, 45, 0, 420, 421, 38, 37, 0, 23, 0,
+	0, 0, 0, 0, 33, 0, 0, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	29, 12, 48, 0, 0, 57, 0, 56, 0, 52,
-	50, 51, 53, 0, 0, 0, 45, 44, 0, 30,
-	0, 0, 0, 0, 0, 40, 0, 0, 0, 0,
-	0, 22, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 42, 0, 43, 249,
-	0, 0, 0, 0, 0, 0, 49, 55, 54, 31,
-	32, 38, 37, 33, 39, 34, 35, 36, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 29,
-	12, 48, 0, 0, 57, 0, 56, 0, 52, 50,
-	51, 53, 0, 0, 0, 45, 44, 0, 30, 0,
-	0, 0, 0, 0, 40, 42, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 31, 32,
-	38, 37, 33, 39, 34, 35, 36, 43, 0, 0,
-	0, 0, 0, 0, 0, 49, 55, 54, 29, 12,
-	48, 0, 199, 57, 0, 56, 0, 52, 50, 51,
-	53, 0, 0, 0, 45, 44, 0, 30, 0, 0,
-	0, 0, 0, 40, 42, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 31, 32, 38,
-	37, 33, 39, 34, 35, 36, 43, 0, 0, 0,
-	0, 0, 0, 0, 49, 55, 54, 29, 12, 48,
-	0, 0, 57, 0, 56, 0, 52, 50, 51, 53,
-	0, 0, 0, 45, 44, 0, 30, 392, 393, 0,
-	0, 0, 40, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 36, 0,
+	0, 0, 0, 0, 0, 0, 41, 47, 46, 92,
+	91, 0, 81, 90, 89, 375, 0, 0, 0, 0,
+	0, 0, 83, 84, 85, 86, 87, 88, 80, 82,
+	78, 79, 64, 93, 0, 0, 0, 0, 65, 66,
+	67, 68, 70, 69, 71, 72, 73, 74, 75, 76,
+	77, 0, 0, 0, 14, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 92, 91, 0, 81,
+	90, 89, 131, 0, 0, 0, 0, 0, 0, 83,
+	84, 85, 86, 87, 88, 80, 82, 78, 79, 64,
+	93, 0, 0, 0, 0, 65, 66, 67, 68, 70,
+	69, 71, 72, 73, 74, 75, 76, 77, 0, 0,
+	0, 14, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 92, 91, 0, 81, 90, 89, 0,
+	0, 0, 0, 0, 0, 0, 83, 84, 85, 86,
+	87, 88, 80, 82, 78, 79, 64, 93, 0, 0,
+	0, 0, 65, 66, 67, 68, 70, 69, 71, 72,
+	73, 74, 75, 76, 77, 442, 0, 0, 0, 0,
+	0, 0, 0, 0, 92, 91, 0, 81, 90, 89,
+	0, 0, 0, 0, 0, 0, 0, 83, 84, 85,
+	86, 87, 88, 80, 82, 78, 79, 64, 93, 0,
+	0, 0, 0, 65, 66, 67, 68, 70, 69, 71,
+	72, 73, 74, 75, 76, 77, 424, 0, 0, 0,
+	0, 0, 0, 0, 0, 92, 91, 0, 81, 90,
+	89, 0, 0, 0, 0, 0, 0, 0, 83, 84,
+	85, 86, 87, 88, 80, 82, 78, 79, 64, 93,
+	0, 0, 0, 0, 65, 66, 67, 68, 70, 69,
+	71, 72, 73, 74, 75, 76, 77, 423, 0, 0,
+	0, 0, 0, 0, 0, 0, 92, 91, 0, 81,
+	90, 89, 0, 0, 0, 0, 0, 0, 0, 83,
+	84, 85, 86, 87, 88, 80, 82, 78, 79, 64,
+	93, 0, 0, 0, 0, 65, 66, 67, 68, 70,
+	69, 71, 72, 73, 74, 75, 76, 77, 422, 0,
+	0, 0, 0, 0, 0, 0, 0, 92, 91, 0,
+	81, 90, 89, 0, 0, 0, 0, 0, 0, 0,
+	83, 84, 85, 86, 87, 88, 80, 82, 78, 79,
+	64, 93, 0, 0, 0, 0, 65, 66, 67, 68,
+	70, 69, 71, 72, 73, 74, 75, 76, 77, 415,
+	0, 0, 0, 0, 0, 0, 0, 0, 92, 91,
+	0, 81, 90, 89, 0, 0, 0, 0, 0, 0,
+	0, 83, 84, 85, 86, 87, 88, 80, 82, 78,
+	79, 64, 93, 0, 0, 0, 0, 65, 66, 67,
+	68, 70, 69, 71, 72, 73, 74, 75, 76, 77,
+	14, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 92, 91, 0, 81, 90, 89, 0, 0,
+	0, 0, 0, 0, 0, 83, 84, 85, 86, 87,
+	88, 80, 82, 78, 79, 64, 93, 0, 0, 0,
+	0, 65, 66, 67, 68, 70, 69, 71, 72, 73,
+	74, 75, 76, 77, 400, 0, 0, 0, 0, 0,
+	0, 0, 0, 92, 91, 0, 81, 90, 89, 0,
+	0, 0, 0, 0, 0, 0, 83, 84, 85, 86,
+	87, 88, 80, 82, 78, 79, 64, 93, 0, 0,
+	0, 0, 65, 66, 67, 68, 70, 69, 71, 72,
+	73, 74, 75, 76, 77, 367, 0, 0, 0, 0,
+	0, 0, 0, 0, 92, 91, 0, 81, 90, 89,
+	0, 0, 0, 0, 0, 0, 0, 83, 84, 85,
+	86, 87, 88, 80, 82, 78, 79, 64, 93, 0,
+	0, 0, 0, 65, 66, 67, 68, 70, 69, 71,
+	72, 73, 74, 75, 76, 77, 366, 0, 0, 0,
+	0, 0, 0, 0, 0, 92, 91, 0, 81, 90,
+	89, 0, 0, 0, 0, 0, 0, 0, 83, 84,
+	85, 86, 87, 88, 80, 82, 78, 79, 64, 93,
+	0, 0, 0, 0, 65, 66, 67, 68, 70, 69,
+	71, 72, 73, 74, 75, 76, 77, 365, 0, 0,
+	0, 0, 0, 0, 0, 0, 92, 91, 0, 81,
+	90, 89, 0, 0, 0, 0, 0, 0, 0, 83,
+	84, 85, 86, 87, 88, 80, 82, 78, 79, 64,
+	93, 0, 0, 0, 0, 65, 66, 67, 68, 70,
+	69, 71, 72, 73, 74, 75, 76, 77, 364, 0,
+	0, 0, 0, 0, 0, 0, 0, 92, 91, 0,
+	81, 90, 89, 0, 0, 0, 0, 0, 0, 0,
+	83, 84, 85, 86, 87, 88, 80, 82, 78, 79,
+	64, 93, 0, 0, 0, 0, 65, 66, 67, 68,
+	70, 69, 71, 72, 73, 74, 75, 76, 77, 362,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 92,
+	91, 0, 81, 90, 89, 0, 0, 0, 0, 0,
+	0, 0, 83, 84, 85, 86, 87, 88, 80, 82,
+	78, 79, 64, 93, 0, 0, 0, 0, 65, 66,
+	67, 68, 70, 69, 71, 72, 73, 74, 75, 76,
+	77, 361, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 92, 91, 0, 81, 90, 89, 0, 0, 0,
+	0, 0, 0, 0, 83, 84, 85, 86, 87, 88,
+	80, 82, 78, 79, 64, 93, 0, 0, 0, 0,
+	65, 66, 67, 68, 70, 69, 71, 72, 73, 74,
+	75, 76, 77, 360, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 92, 91, 0, 81, 90, 89, 0,
+	0, 0, 0, 0, 0, 0, 83, 84, 85, 86,
+	87, 88, 80, 82, 78, 79, 64, 93, 0, 0,
+	0, 0, 65, 66, 67, 68, 70, 69, 71, 72,
+	73, 74, 75, 76, 77, 358, 0, 0, 0, 0,
+	0, 0, 0, 0, 92, 91, 0, 81, 90, 89,
+	0, 0, 0, 0, 0, 0, 0, 83, 84, 85,
+	86, 87, 88, 80, 82, 78, 79, 64, 93, 216,
+	0, 0, 0, 65, 66, 67, 68, 70, 69, 71,
+	72, 73, 74, 75, 76, 77, 92, 91, 0, 81,
+	90, 89, 0, 0, 356, 0, 0, 0, 0, 83,
+	84, 85, 86, 87, 88, 80, 82, 78, 79, 64,
+	93, 321, 0, 0, 0, 65, 66, 67, 68, 70,
+	69, 71, 72, 73, 74, 75, 76, 77, 0, 0,
+	0, 0, 0, 0, 92, 91, 0, 81, 90, 89,
+	0, 0, 0, 0, 0, 0, 0, 83, 84, 85,
+	86, 87, 88, 80, 82, 78, 79, 64, 93, 0,
+	0, 0, 0, 65, 66, 67, 68, 70, 69, 71,
+	72, 73, 74, 75, 76, 77, 92, 91, 0, 81,
+	90, 89, 0, 0, 0, 0, 0, 0, 0, 83,
+	84, 85, 86, 87, 88, 80, 82, 78, 79, 64,
+	93, 0, 0, 0, 0, 65, 66, 67, 68, 70,
+	69, 71, 72, 73, 74, 75, 76, 77, 92, 91,
+	252, 81, 90, 89, 0, 0, 309, 0, 0, 0,
+	0, 83, 84, 85, 86, 87, 88, 80, 82, 78,
+	79, 64, 93, 0, 0, 0, 0, 65, 66, 67,
+	68, 70, 69, 71, 72, 73, 74, 75, 76, 77,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 43, 0, 0, 0, 0,
-	0, 0, 0, 49, 55, 54, 0, 0, 0, 97,
-	96, 0, 86, 95, 94, 67, 0, 0, 0, 0,
-	0, 0, 88, 89, 90, 91, 92, 93, 85, 87,
-	83, 84, 69, 98, 0, 0, 0, 70, 71, 72,
-	73, 75, 74, 76, 77, 78, 79, 80, 81, 82,
-	12, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 387, 0, 0, 0, 0, 0, 0,
-	0, 0, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 376, 0, 0, 0, 0, 0, 0,
-	0, 0, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 375, 0, 0, 0, 0, 0, 0,
-	0, 0, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 374, 0, 0, 0, 0, 0, 0,
-	0, 0, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 373, 0, 0, 0, 0, 0, 0,
-	0, 0, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 366, 0, 0, 0, 0, 0, 0,
-	0, 0, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 347, 0, 0, 0, 0, 0, 0,
-	0, 0, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 346, 0, 0, 0, 0, 0, 0,
-	0, 0, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 345, 0, 0, 0, 0, 0, 0,
-	0, 0, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 344, 0, 0, 0, 0, 0, 0,
-	0, 0, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 342, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 97, 96, 0, 86, 95, 94, 0,
-	0, 0, 0, 0, 0, 0, 88, 89, 90, 91,
-	92, 93, 85, 87, 83, 84, 69, 98, 0, 0,
-	0, 70, 71, 72, 73, 75, 74, 76, 77, 78,
-	79, 80, 81, 82, 341, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 97, 96, 0, 86, 95, 94,
-	0, 0, 0, 0, 0, 0, 0, 88, 89, 90,
-	91, 92, 93, 85, 87, 83, 84, 69, 98, 0,
-	0, 0, 70, 71, 72, 73, 75, 74, 76, 77,
-	78, 79, 80, 81, 82, 340, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 97, 96, 0, 86, 95,
-	94, 0, 0, 0, 0, 0, 0, 0, 88, 89,
-	90, 91, 92, 93, 85, 87, 83, 84, 69, 98,
-	0, 0, 0, 70, 71, 72, 73, 75, 74, 76,
-	77, 78, 79, 80, 81, 82, 338, 0, 0, 0,
-	0, 0, 0, 0, 0, 97, 96, 0, 86, 95,
-	94, 0, 0, 0, 0, 0, 0, 0, 88, 89,
-	90, 91, 92, 93, 85, 87, 83, 84, 69, 98,
-	320, 0, 0, 70, 71, 72, 73, 75, 74, 76,
-	77, 78, 79, 80, 81, 82, 97, 96, 0, 86,
-	95, 94, 0, 0, 336, 0, 0, 0, 0, 88,
-	89, 90, 91, 92, 93, 85, 87, 83, 84, 69,
-	98, 0, 0, 0, 70, 71, 72, 73, 75, 74,
-	76, 77, 78, 79, 80, 81, 82, 0, 0, 0,
-	97, 96, 0, 86, 95, 94, 0, 0, 0, 0,
-	0, 0, 0, 88, 89, 90, 91, 92, 93, 85,
-	87, 83, 84, 69, 98, 0, 0, 0, 70, 71,
-	72, 73, 75, 74, 76, 77, 78, 79, 80, 81,
-	82, 97, 96, 257, 86, 95, 94, 0, 0, 308,
-	0, 0, 0, 0, 88, 89, 90, 91, 92, 93,
-	85, 87, 83, 84, 69, 98, 0, 0, 0, 70,
-	71, 72, 73, 75, 74, 76, 77, 78, 79, 80,
-	81, 82, 0, 0, 0, 0, 0, 0, 0, 0,
-	97, 96, 0, 86, 95, 94, 0, 0, 0, 0,
-	0, 0, 0, 88, 89, 90, 91, 92, 93, 85,
-	87, 83, 84, 69, 98, 0, 0, 0, 70, 71,
-	72, 73, 75, 74, 76, 77, 78, 79, 80, 81,
-	82, 256, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 97, 96, 0, 86, 95, 94, 0, 0, 0,
-	0, 0, 0, 0, 88, 89, 90, 91, 92, 93,
-	85, 87, 83, 84, 69, 98, 0, 0, 0, 70,
-	71, 72, 73, 75, 74, 76, 77, 78, 79, 80,
-	81, 82, 97, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 96, 0, 86, 95, 94, 0, 0,
-	0, 0, 0, 0, 0, 88, 89, 90, 91, 92,
-	93, 85, 87, 83, 84, 69, 98, 0, 0, 0,
-	70, 71, 72, 73, 75, 74, 76, 77, 78, 79,
-	80, 81, 82, 86, 95, 94, 0, 0, 0, 0,
-	0, 0, 0, 88, 89, 90, 91, 92, 93, 85,
-	87, 83, 84, 69, 98, 0, 0, 0, 70, 71,
-	72, 73, 75, 74, 76, 77, 78, 79, 80, 81,
-	82,
+	0, 92, 91, 0, 81, 90, 89, 0, 0, 0,
+	0, 0, 0, 0, 83, 84, 85, 86, 87, 88,
+	80, 82, 78, 79, 64, 93, 0, 0, 0, 0,
+	65, 66, 67, 68, 70, 69, 71, 72, 73, 74,
+	75, 76, 77, 251, 0, 0, 0, 0, 0, 0,
+	0, 0, 213, 92, 91, 0, 81, 90, 89, 0,
+	0, 0, 0, 0, 0, 0, 83, 84, 85, 86,
+	87, 88, 80, 82, 78, 79, 64, 93, 0, 0,
+	0, 0, 65, 66, 67, 68, 70, 69, 71, 72,
+	73, 74, 75, 76, 77, 92, 91, 63, 81, 90,
+	89, 0, 0, 0, 0, 0, 0, 0, 83, 84,
+	85, 86, 87, 88, 80, 82, 78, 79, 64, 93,
+	0, 0, 0, 0, 65, 66, 67, 68, 70, 69,
+	71, 72, 73, 74, 75, 76, 77, 0, 0, 0,
+	92, 91, 0, 81, 90, 89, 0, 0, 0, 0,
+	0, 0, 0, 83, 84, 85, 86, 87, 88, 80,
+	82, 78, 79, 64, 93, 0, 0, 0, 0, 65,
+	66, 67, 68, 70, 69, 71, 72, 73, 74, 75,
+	76, 77, 92, 91, 0, 81, 90, 89, 0, 0,
+	0, 0, 0, 0, 0, 83, 84, 85, 86, 87,
+	88, 80, 82, 78, 79, 64, 93, 0, 0, 0,
+	0, 65, 66, 67, 68, 70, 69, 71, 72, 73,
+	74, 75, 76, 77, 91, 0, 81, 90, 89, 0,
+	0, 0, 0, 0, 0, 0, 83, 84, 85, 86,
+	87, 88, 80, 82, 78, 79, 64, 93, 0, 0,
+	0, 0, 65, 66, 67, 68, 70, 69, 71, 72,
+	73, 74, 75, 76, 77, 81, 90, 89, 0, 0,
+	0, 0, 0, 0, 0, 83, 84, 85, 86, 87,
+	88, 80, 82, 78, 79, 64, 93, 0, 0, 0,
+	0, 65, 66, 67, 68, 70, 69, 71, 72, 73,
+	74, 75, 76, 77,
 }
 
 var yyPact = [...]int16{
-	338, -1000, 342, 333, 374, 227, 246, 246, 379, 336,
-	246, 332, -1000, -1000, -1000, 344, 421, 267, 329, 254,
-	379, 373, 336, 245, -1000, 844, -1000, -1000, -1000, 253,
-	742, 251, 250, 249, 247, 244, 243, 242, 241, 240,
-	239, 238, 237, 742, 742, 742, 742, 47, 624, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, -62, 742, 236, 232,
-	373, -1000, 379, 421, 371, 421, 167, 246, -1000, 231,
-	742, 742, 742, 742, 742, 742, 742, 742, 742, 742,
-	742, 742, 742, -12, -13, 53, -28, -43, 742, 742,
-	742, 742, 742, 742, 137, 62, 742, 742, 123, 148,
-	69, 1814, 742, 742, 742, 262, -56, 261, 260, 259,
-	199, 362, 683, 373, -1000, 1892, 1892, 317, 1814, 246,
-	-95, 195, -1000, 1814, 128, -1000, -99, 124, 1814, 742,
-	373, 192, -1000, 218, 367, 223, 421, -1000, 47, -1000,
-	-1000, 624, -65, -39, 19, -80, -80, -80, 32, 32,
-	2, 2, 2, -1000, -1000, 21, 20, -57, -1000, -1000,
-	72, 72, 72, 72, 72, 72, 84, -58, -59, 52,
-	-60, -61, 1892, 1854, -1000, 171, -1000, -1000, -1000, 34,
-	545, -1000, 68, 742, 181, 1814, 1773, 1722, 226, 225,
-	224, 188, 370, -1000, 458, 742, -1000, -1000, -1000, -1000,
-	160, 185, 246, 246, -1000, 105, 104, -1000, -1000, -1000,
-	-62, 742, -1000, 742, 157, 182, -1000, 367, 359, 742,
-	421, 421, -1000, 289, -1000, 287, 279, 276, 286, -1000,
-	166, 151, -63, -83, -1000, 137, 16, -47, -84, -1000,
-	-1000, -1000, -1000, -1000, -1000, 36, 230, 147, 1814, -1000,
-	63, 742, 742, 1673, -1000, 742, 742, 258, 742, 742,
-	742, 257, 742, 742, -1000, 742, 742, 1632, -1000, -1000,
-	310, 319, -1000, -1000, -1000, 1814, 1814, -1000, -1000, 359,
-	346, 355, 1814, -1000, 266, -1000, -1000, -1000, 283, -1000,
-	277, -1000, -1000, -1000, -1000, -1000, -1000, -94, -96, -1000,
-	-1000, 229, 361, 34, 742, -1000, 1588, 1814, 742, 1814,
-	1547, 163, 1497, 1446, 1395, 152, 1344, 1294, 1244, 1194,
-	742, 246, 246, 346, 357, 742, 421, 742, -1000, -1000,
-	-1000, -1000, 306, 742, 36, 1814, 742, 1814, -1000, -1000,
-	742, 742, 742, 219, -1000, -1000, -1000, -1000, 1144, -1000,
-	-1000, 357, 347, 353, 1814, 216, 1814, 357, 350, 1094,
-	-1000, 1814, 1044, 994, 944, 742, -1000, 347, 345, -9,
-	742, 139, 742, -1000, -1000, -1000, -1000, 894, 345, -1000,
-	-9, -1000, 210, -1000, 791, -1000, 207, -1000, -1000, -1000,
-	742, 322, -1000, -1000, -1000, -1000, 324, -1000, -1000,
+	385, -1000, 389, 365, 359, 221, 236, 236, -1000, 448,
+	381, 979, 236, 376, -1000, -1000, -1000, 360, 657, 286,
+	2277, -1000, 270, 979, 269, 268, 267, 266, 265, 264,
+	263, 262, 259, 258, 257, 256, 979, 979, 979, 26,
+	737, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -76, 979,
+	374, 254, 448, 358, 381, 979, 240, -1000, 1140, -1000,
+	-1000, 979, 247, 979, 246, 979, 979, 979, 979, 979,
+	979, 979, 979, 979, 979, 979, 979, 979, -60, -61,
+	49, -64, -65, 979, 979, 979, 979, 979, 979, -36,
+	78, 979, 979, 120, 176, 59, 2319, 979, 979, 979,
+	278, -66, 277, 276, 274, 189, 597, 919, 358, -1000,
+	2399, 2399, 236, -89, 181, -1000, 2319, 117, -1000, -99,
+	140, 2319, 245, 358, -1000, 448, 657, 2232, 444, 657,
+	48, 236, -1000, 369, 2319, 979, 2001, 737, 332, 17,
+	110, 50, 50, 50, 37, 37, -19, -19, -19, -1000,
+	-1000, -4, -10, -70, -1000, -1000, 731, 731, 731, 731,
+	731, 731, 66, -1000, -71, -72, 27, -73, -74, 2399,
+	2360, -1000, 130, -1000, -1000, -1000, 11, 859, -1000, 97,
+	979, 166, 2319, 2190, 2138, 212, 210, 209, 187, 443,
+	-1000, 784, 979, -1000, -1000, -1000, -1000, 149, 180, -1000,
+	86, 77, -1000, -1000, -1000, -76, 979, -1000, 979, 358,
+	175, -1000, 235, 979, 440, 260, 657, -1000, 26, -1000,
+	236, 236, 147, 228, 174, 141, -77, -79, -1000, -36,
+	-21, -35, -87, -1000, -1000, -1000, -1000, -1000, -1000, 7,
+	423, 244, 231, 2319, -1000, 52, 979, 979, 2085, -1000,
+	979, 979, 273, 979, 979, 979, 241, 979, 979, -1000,
+	979, 979, 2043, -1000, -1000, -1000, -1000, -1000, 2319, 2319,
+	172, -1000, 440, 2001, 422, 979, 537, 477, 242, -1000,
+	308, -1000, 304, 297, 294, 303, 336, 340, 364, -1000,
+	422, -1000, -1000, -1000, -1000, -1000, -96, -97, -1000, -1000,
+	239, 237, 439, 393, 979, 415, -1000, 1953, 2319, 979,
+	2319, 1911, 171, 1860, 1808, 1756, 170, 1704, 1653, 1602,
+	1551, 979, -1000, 422, 228, 395, 414, 2319, -1000, 657,
+	-1000, 1083, 234, 285, 657, 979, -1000, -1000, -1000, 300,
+	-1000, 298, -1000, 236, 236, 236, 395, -1000, -1000, 333,
+	419, 979, 169, -56, 2319, 979, 979, 2319, -1000, -1000,
+	979, 979, 979, 208, -1000, -1000, -1000, -1000, 1500, 395,
+	422, 418, 979, 657, -1000, 236, 979, 979, 282, 1449,
+	-1000, -1000, 225, -1000, -1000, -1000, 418, 406, 401, 1395,
+	3, -1000, 201, -1000, 1026, 2319, 1344, 1293, 1242, 979,
+	-1000, 418, 395, 393, 2319, 197, 339, 131, 2319, 979,
+	48, -54, 167, 979, 979, -1000, 7, 421, 979, 350,
+	-1000, -1000, -1000, -1000, -1000, 1191, 393, -1000, 391, 236,
+	388, 2319, 72, 163, -1000, 111, 161, -1000, 224, -1000,
+	-1000, 346, -1000, 391, -1000, -56, -1000, 353, 236, 207,
+	-1000, -2, 417, -1000, -1000, -1000, -1000, 206, -1000, 657,
+	-1000, 397, -1000, 236, 123, 979, 119, -1000, 144, 92,
+	-1000, 236, -1000, -2, -1000, -1000,
 }
 
 var yyPgo = [...]int16{
-	0, 437, 0, 154, 11, 435, 12, 7, 432, 431,
-	430, 8, 425, 423, 421, 420, 416, 415, 414, 88,
-	2, 86, 413, 10, 20, 21, 14, 412, 409, 4,
-	408, 407, 13, 406, 352, 1, 5, 393, 390, 6,
-	3, 389, 9, 388, 387, 192, 386,
+	0, 485, 0, 14, 16, 484, 17, 13, 483, 482,
+	481, 1, 480, 479, 478, 477, 476, 473, 472, 471,
+	44, 470, 4, 60, 469, 9, 5, 11, 15, 19,
+	18, 467, 8, 464, 463, 20, 462, 347, 2, 10,
+	461, 460, 7, 3, 459, 6, 458, 457, 27, 456,
 }
 
 var yyR1 = [...]int8{
-	0, 1, 22, 21, 44, 44, 44, 5, 5, 14,
-	14, 45, 45, 45, 15, 15, 25, 25, 25, 25,
-	25, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 4, 4, 10, 10, 18, 18,
-	34, 34, 34, 2, 2, 2, 2, 2, 2, 2,
+	0, 1, 24, 24, 23, 23, 47, 47, 47, 47,
+	5, 5, 14, 14, 48, 48, 48, 15, 15, 27,
+	27, 27, 27, 27, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 4, 4, 10,
+	10, 18, 18, 37, 37, 37, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
 	2, 2, 2, 2, 2, 2, 2, 2, 2, 2,
-	2, 2, 2, 24, 24, 29, 29, 33, 33, 33,
-	30, 30, 30, 31, 31, 31, 32, 28, 28, 42,
-	42, 38, 38, 38, 38, 38, 38, 38, 46, 46,
-	26, 26, 27, 27, 27, 20, 19, 9, 9, 41,
-	41, 8, 8, 11, 11, 6, 6, 7, 7, 23,
-	23, 17, 17, 17, 16, 16, 16, 35, 37, 37,
-	36, 36, 39, 39, 40, 40, 12, 12, 12, 12,
-	13, 43, 43, 43,
+	2, 2, 2, 2, 2, 2, 2, 2, 26, 26,
+	32, 32, 36, 36, 36, 33, 33, 33, 34, 34,
+	34, 35, 31, 31, 45, 45, 41, 41, 41, 41,
+	41, 41, 41, 49, 49, 29, 29, 30, 30, 30,
+	30, 30, 30, 30, 28, 28, 28, 28, 19, 19,
+	21, 21, 22, 20, 9, 9, 44, 44, 8, 8,
+	11, 11, 6, 6, 7, 7, 25, 25, 17, 17,
+	17, 16, 16, 16, 38, 40, 40, 39, 39, 42,
+	42, 43, 43, 12, 12, 12, 12, 13, 46, 46,
+	46,
 }
 
 var yyR2 = [...]int8{
-	0, 4, 11, 10, 1, 3, 0, 2, 0, 1,
-	0, 0, 3, 4, 6, 7, 3, 2, 1, 1,
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 3,
-	3, 3, 4, 4, 1, 3, 1, 1, 1, 0,
-	5, 1, 0, 1, 5, 7, 5, 4, 6, 6,
-	8, 8, 8, 9, 6, 6, 3, 4, 6, 6,
-	7, 3, 4, 5, 5, 4, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 2,
-	5, 3, 5, 3, 4, 3, 3, 3, 3, 3,
-	3, 3, 3, 5, 4, 6, 4, 6, 5, 4,
-	4, 2, 2, 3, 3, 3, 4, 3, 4, 3,
-	4, 3, 4, 1, 3, 1, 3, 1, 1, 3,
-	1, 3, 0, 1, 3, 0, 3, 3, 0, 5,
-	0, 1, 2, 2, 3, 2, 3, 2, 1, 2,
-	1, 0, 2, 3, 5, 1, 1, 0, 2, 4,
-	5, 0, 1, 0, 5, 0, 2, 0, 2, 0,
-	3, 0, 2, 2, 0, 1, 1, 3, 3, 1,
-	0, 3, 0, 2, 0, 2, 6, 6, 4, 4,
-	1, 1, 1, 1,
+	0, 4, 11, 8, 10, 8, 1, 3, 2, 0,
+	2, 0, 1, 0, 0, 3, 4, 6, 7, 3,
+	2, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 3, 3, 3, 4, 4, 1, 3, 1,
+	1, 1, 0, 5, 1, 0, 1, 5, 9, 11,
+	15, 5, 4, 6, 6, 8, 8, 8, 9, 6,
+	6, 3, 4, 6, 6, 7, 3, 4, 5, 5,
+	4, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 2, 5, 3, 5, 3, 4,
+	3, 3, 3, 3, 3, 3, 3, 3, 5, 4,
+	6, 4, 6, 5, 4, 4, 2, 2, 3, 3,
+	3, 4, 3, 4, 3, 4, 3, 4, 1, 3,
+	1, 3, 1, 1, 3, 1, 3, 0, 1, 3,
+	0, 3, 3, 0, 5, 0, 1, 2, 2, 3,
+	2, 3, 2, 1, 2, 1, 0, 2, 7, 3,
+	4, 5, 6, 11, 1, 5, 7, 9, 2, 0,
+	1, 3, 1, 1, 0, 2, 4, 5, 0, 1,
+	0, 5, 0, 2, 0, 2, 0, 3, 0, 2,
+	2, 0, 1, 1, 3, 3, 1, 0, 3, 0,
+	2, 0, 2, 6, 6, 4, 4, 1, 1, 1,
+	1,
 }
 
 var yyChk = [...]int16{
-	-1000, -1, -44, 18, -14, -15, 16, 21, -22, 7,
-	58, -19, 56, -19, -45, 6, -34, 19, -19, 21,
-	-21, 20, 7, -24, -25, -2, 104, -12, -4, 55,
-	74, 35, 36, 39, 41, 42, 43, 38, 37, 40,
-	80, -19, 22, 103, 72, 71, 28, -3, 57, 111,
-	65, 66, 64, 67, 113, 112, 62, 60, 53, 21,
-	57, -45, -21, -34, -5, 58, 17, 21, -19, 91,
-	96, 97, 98, 99, 101, 100, 102, 103, 104, 105,
-	106, 107, 108, 89, 90, 87, 71, 88, 81, 82,
-	83, 84, 85, 86, 73, 72, 69, 68, 92, 57,
-	-8, -2, 57, 57, 57, 57, 57, 57, 57, 57,
-	57, 57, 57, 57, -2, -2, -2, -13, -2, 110,
-	60, -10, -21, -2, -31, -32, 113, -30, -2, 57,
-	57, -21, -45, -24, -26, -27, 8, -25, -3, -19,
-	-19, 57, -2, -2, -2, -2, -2, -2, -2, -2,
-	-2, -2, -2, -2, -2, 113, 113, 79, 113, 113,
-	-2, -2, -2, -2, -2, -2, -4, 90, 89, 87,
-	71, 88, -2, -2, 64, 72, 67, 65, 66, 59,
-	-18, 19, -41, 75, -29, -2, -2, -2, 56, 113,
-	56, 56, 56, 59, -2, -43, 32, 33, 34, 59,
-	-29, -21, 21, 29, -19, -20, 113, 111, 59, 63,
-	58, 114, 61, 58, -29, -21, 59, -26, -6, 9,
-	-46, -38, 58, 49, 46, 50, 47, 48, 52, -25,
-	-21, -29, 95, 95, 113, 69, 113, 113, 79, 113,
-	113, 64, 67, 65, 66, -11, 94, -33, -2, 104,
-	-9, 75, 77, -2, 59, 58, 58, 21, 58, 58,
-	58, 57, 58, 8, 59, 58, 8, -2, 59, 59,
-	-19, -19, 61, 61, -32, -2, -2, 59, 59, -6,
-	-23, 10, -2, -25, -25, 46, 46, 46, 51, 46,
-	51, 46, 59, 59, 113, 113, -4, 95, 95, 113,
-	-42, 93, 57, 59, 58, 78, -2, -2, 76, -2,
-	-2, 56, -2, -2, -2, 56, -2, -2, -2, -2,
-	8, 29, 21, -23, -7, 13, 12, 53, 46, 46,
-	113, 113, 57, 9, -11, -2, 76, -2, 59, 59,
-	58, 58, 58, 59, 59, 59, 59, 59, -2, -19,
-	-19, -7, -36, 11, -2, -24, -2, -28, 30, -2,
-	-42, -2, -2, -2, -2, 58, 59, -36, -39, 14,
-	12, -36, 12, 59, 59, 59, 59, -2, -39, -40,
-	15, -20, -37, -35, -2, 59, -29, 59, -40, -20,
-	58, -16, 26, 27, -35, -17, 23, 24, 25,
+	-1000, -1, -47, 18, -14, -15, 16, 22, 19, -24,
+	7, 35, 63, -20, 61, -20, -48, 6, -37, 20,
+	-2, -4, 60, 79, 40, 41, 44, 46, 47, 48,
+	43, 42, 45, 85, -20, 23, 109, 77, 76, -3,
+	62, 117, 70, 71, 69, 72, 119, 118, 67, 65,
+	-20, 22, -23, 21, 7, 35, -26, -27, -2, 110,
+	-12, 29, 58, 30, 96, 102, 103, 104, 105, 107,
+	106, 108, 109, 110, 111, 112, 113, 114, 94, 95,
+	92, 76, 93, 86, 87, 88, 89, 90, 91, 78,
+	77, 74, 73, 97, 62, -8, -2, 62, 62, 62,
+	62, 62, 62, 62, 62, 62, 62, 62, 62, -2,
+	-2, -2, 116, 65, -10, -23, -2, -34, -35, 119,
+	-33, -2, 22, 62, -48, -23, -37, -2, -5, 63,
+	17, 22, -20, -13, -2, 62, -2, 62, -2, -2,
+	-2, -2, -2, -2, -2, -2, -2, -2, -2, -2,
+	-2, 119, 119, 84, 119, 119, -2, -2, -2, -2,
+	-2, -2, -4, -20, 95, 94, 92, 76, 93, -2,
+	-2, 69, 77, 72, 70, 71, 64, -18, 20, -44,
+	80, -32, -2, -2, -2, 61, 119, 61, 61, 61,
+	64, -2, -46, 37, 38, 39, 64, -32, -23, -20,
+	-22, 119, 117, 64, 68, 63, 120, 66, 63, 62,
+	-23, -48, -26, 30, -29, -30, 8, -27, -3, -20,
+	22, 30, -32, -30, -23, -32, 100, 100, 119, 74,
+	119, 119, 84, 119, 119, 69, 72, 70, 71, -11,
+	101, 99, -36, -2, 110, -9, 80, 82, -2, 64,
+	63, 63, 22, 63, 63, 63, 62, 63, 8, 64,
+	63, 8, -2, 64, 64, 66, 66, -35, -2, -2,
+	-23, 64, -29, -2, -6, 9, -49, -41, 35, 63,
+	54, 51, 55, 52, 53, 57, -27, -20, -20, 64,
+	-6, 64, 64, 119, 119, -4, 100, 100, 119, -45,
+	98, 10, 62, -39, 63, 11, 83, -2, -2, 81,
+	-2, -2, 61, -2, -2, -2, 61, -2, -2, -2,
+	-2, 8, 64, -6, -30, -25, 10, -2, -28, 33,
+	-27, -2, 34, -28, 33, 62, 51, 51, 51, 56,
+	51, 56, 51, 32, 30, 22, -25, 119, 119, 62,
+	62, 9, -42, 14, -2, 12, 81, -2, 64, 64,
+	63, 63, 63, 64, 64, 64, 64, 64, -2, -25,
+	-6, -7, 13, 12, -27, 22, 62, 58, -27, -2,
+	51, 51, -20, -20, -20, -7, -31, 31, 11, -2,
+	64, -22, -40, -38, -2, -2, -2, -2, -2, 63,
+	64, -7, -25, -39, -2, -26, -20, -32, -2, 58,
+	-20, 62, -39, 12, 12, 64, -11, 101, 63, -16,
+	27, 28, 64, 64, 64, -2, -39, -7, -42, 30,
+	64, -2, -3, 117, 64, -32, -38, -45, 10, -38,
+	-17, 24, 64, -42, -43, 15, -20, -19, 16, 96,
+	64, 64, 62, 25, 26, -43, -22, 22, -20, 62,
+	-11, 11, -20, 62, -26, 12, -21, -20, 64, -38,
+	64, 63, 64, 64, -20, -11,
 }
 
 var yyDef = [...]int16{
-	6, -2, 10, 4, 0, 9, 0, 0, 11, 42,
-	0, 0, 146, 5, 1, 0, 0, 41, 0, 0,
-	11, 0, 42, 8, 113, 18, 19, 20, 43, 0,
-	151, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 21, 0, 0, 0, 0, 0, 34, 0, 22,
-	23, 24, 25, 26, 27, 28, 125, 122, 0, 0,
-	0, 12, 11, 0, 141, 0, 0, 0, 17, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	9, -2, 13, 6, 0, 12, 0, 0, 8, 14,
+	45, 0, 0, 0, 163, 7, 1, 0, 0, 44,
+	0, 46, 0, 168, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 24, 0, 0, 0, 0, 37,
+	0, 25, 26, 27, 28, 29, 30, 31, 130, 127,
+	0, 0, 14, 0, 45, 0, 11, 118, 21, 22,
+	23, 0, 0, 0, 0, 0, 0, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 39,
-	0, 152, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 79, 101, 102, 0, 180, 0,
-	0, 0, 36, 37, 0, 123, 0, 0, 120, 0,
-	0, 0, 13, 141, 155, 140, 0, 114, 7, 21,
-	16, 0, 66, 67, 68, 69, 70, 71, 72, 73,
-	74, 75, 76, 77, 78, 81, 83, 0, 85, 86,
-	87, 88, 89, 90, 91, 92, 0, 0, 0, 0,
-	0, 0, 103, 104, 105, 0, 107, 109, 111, 153,
-	0, 38, 147, 0, 0, 115, 0, 0, 0, 0,
-	0, 0, 0, 56, 0, 0, 181, 182, 183, 61,
-	0, 0, 0, 0, 31, 0, 0, 145, 35, 29,
-	0, 0, 30, 0, 0, 0, 14, 155, 159, 0,
-	0, 0, 138, 0, 131, 0, 0, 0, 0, 142,
-	0, 0, 0, 0, 84, 0, 94, 96, 0, 99,
-	100, 106, 108, 110, 112, 130, 0, 0, 117, 118,
-	0, 0, 0, 0, 47, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 57, 0, 0, 0, 62, 65,
-	178, 179, 32, 33, 124, 126, 121, 40, 15, 159,
-	157, 0, 156, 143, 0, 139, 132, 133, 0, 135,
-	0, 137, 63, 64, 80, 82, 93, 0, 0, 98,
-	44, 0, 0, 153, 0, 46, 0, 148, 0, 116,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 157, 170, 0, 0, 0, 134, 136,
-	95, 97, 128, 0, 130, 119, 0, 149, 48, 49,
-	0, 0, 0, 0, 54, 55, 58, 59, 0, 176,
-	177, 170, 172, 0, 158, 160, 144, 170, 0, 0,
-	45, 150, 0, 0, 0, 0, 60, 172, 174, 0,
-	0, 0, 0, 154, 50, 51, 52, 0, 174, 2,
-	0, 173, 171, 169, 164, 129, 127, 53, 3, 175,
-	0, 161, 165, 166, 168, 167, 0, 162, 163,
+	0, 0, 0, 0, 42, 0, 169, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 84,
+	106, 107, 0, 0, 0, 39, 40, 0, 128, 0,
+	0, 125, 0, 0, 15, 14, 0, 0, 146, 0,
+	0, 0, 20, 0, 197, 0, 0, 0, 71, 72,
+	73, 74, 75, 76, 77, 78, 79, 80, 81, 82,
+	83, 86, 88, 0, 90, 91, 92, 93, 94, 95,
+	96, 97, 0, 24, 0, 0, 0, 0, 0, 108,
+	109, 110, 0, 112, 114, 116, 170, 0, 41, 164,
+	0, 0, 120, 0, 0, 0, 0, 0, 0, 0,
+	61, 0, 0, 198, 199, 200, 66, 0, 0, 34,
+	0, 0, 162, 38, 32, 0, 0, 33, 0, 0,
+	0, 16, 146, 0, 172, 145, 0, 119, 10, 19,
+	0, 0, 0, 172, 0, 0, 0, 0, 89, 0,
+	99, 101, 0, 104, 105, 111, 113, 115, 117, 135,
+	0, 0, 187, 122, 123, 0, 0, 0, 0, 52,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 62,
+	0, 0, 0, 67, 70, 35, 36, 129, 131, 126,
+	0, 17, 172, 0, 176, 0, 0, 0, 0, 143,
+	0, 136, 0, 0, 0, 0, 147, 195, 196, 43,
+	176, 68, 69, 85, 87, 98, 0, 0, 103, 47,
+	0, 0, 0, 189, 0, 0, 51, 0, 165, 0,
+	121, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 18, 176, 172, 174, 0, 173, 149, 0,
+	154, 21, 0, 0, 0, 0, 144, 137, 138, 0,
+	140, 0, 142, 0, 0, 0, 174, 100, 102, 133,
+	0, 0, 0, 0, 124, 0, 0, 166, 53, 54,
+	0, 0, 0, 0, 59, 60, 63, 64, 0, 174,
+	176, 187, 0, 0, 150, 0, 0, 0, 0, 0,
+	139, 141, 0, 193, 194, 3, 187, 0, 0, 0,
+	170, 190, 188, 186, 181, 167, 0, 0, 0, 0,
+	65, 187, 174, 189, 175, 177, 19, 0, 151, 0,
+	0, 0, 0, 0, 0, 171, 135, 0, 0, 178,
+	182, 183, 55, 56, 57, 0, 189, 5, 191, 0,
+	159, 152, 0, 0, 134, 132, 0, 48, 0, 185,
+	184, 0, 58, 191, 2, 0, 155, 0, 0, 0,
+	148, 170, 0, 179, 180, 4, 192, 0, 158, 0,
+	49, 0, 156, 0, 0, 0, 0, 160, 0, 0,
+	157, 0, 153, 170, 161, 50,
 }
 
 var yyTok1 = [...]int8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 70, 3, 3, 3, 106, 98, 3,
-	57, 59, 104, 102, 58, 103, 110, 105, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 114, 3,
+	3, 3, 3, 75, 3, 3, 3, 112, 104, 3,
+	62, 64, 110, 108, 63, 109, 116, 111, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 120, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 60, 3, 61, 97, 3, 3, 3, 3, 3,
+	3, 65, 3, 66, 103, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 62, 96, 63, 71,
+	3, 3, 3, 67, 102, 68, 76,
 }
 
 var yyTok2 = [...]int8{
@@ -682,11 +778,11 @@ var yyTok2 = [...]int8{
 	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
 	32, 33, 34, 35, 36, 37, 38, 39, 40, 41,
 	42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
-	52, 53, 54, 55, 56, 64, 65, 66, 67, 68,
-	69, 72, 73, 74, 75, 76, 77, 78, 79, 80,
+	52, 53, 54, 55, 56, 57, 58, 59, 60, 61,
+	69, 70, 71, 72, 73, 74, 77, 78, 79, 80,
 	81, 82, 83, 84, 85, 86, 87, 88, 89, 90,
-	91, 92, 93, 94, 95, 99, 100, 101, 107, 108,
-	109, 111, 112, 113,
+	91, 92, 93, 94, 95, 96, 97, 98, 99, 100,
+	101, 105, 106, 107, 113, 114, 115, 117, 118, 119,
 }
 
 var yyTok3 = [...]int8{
@@ -1032,7 +1128,7 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:128
+//line partiql.y:131
 		{
 			query, err := buildQuery(yyDollar[1].str, yyDollar[2].with, yyDollar[3].selinto, yyDollar[4].unions)
 			if err != nil {
@@ -1043,302 +1139,341 @@ yydefault:
 		}
 	case 2:
 		yyDollar = yyS[yypt-11 : yypt+1]
-//line partiql.y:139
+//line partiql.y:142
 		{
 			distinct, distinctExpr := decodeDistinct(yyDollar[2].values)
 			yyVAL.selinto.sel = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: yyDollar[3].bindings, From: yyDollar[5].from, Where: yyDollar[6].expr, GroupBy: yyDollar[7].bindings, Having: yyDollar[8].expr, OrderBy: yyDollar[9].orders, Limit: yyDollar[10].exprint, Offset: yyDollar[11].exprint}
 			yyVAL.selinto.into = yyDollar[4].expr
 		}
 	case 3:
+		yyDollar = yyS[yypt-8 : yypt+1]
+//line partiql.y:148
+		{
+			yyVAL.selinto.sel = &expr.Select{Pivot: &expr.Pivot{Value: yyDollar[2].expr, At: yyDollar[4].expr}, From: yyDollar[5].from, Where: yyDollar[6].expr, GroupBy: yyDollar[7].bindings, Having: yyDollar[8].expr}
+			yyVAL.selinto.into = nil
+		}
+	case 4:
 		yyDollar = yyS[yypt-10 : yypt+1]
-//line partiql.y:147
+//line partiql.y:155
 		{
 			distinct, distinctExpr := decodeDistinct(yyDollar[2].values)
 			yyVAL.sel = &expr.Select{Distinct: distinct, DistinctExpr: distinctExpr, Columns: yyDollar[3].bindings, From: yyDollar[4].from, Where: yyDollar[5].expr, GroupBy: yyDollar[6].bindings, Having: yyDollar[7].expr, OrderBy: yyDollar[8].orders, Limit: yyDollar[9].exprint, Offset: yyDollar[10].exprint}
 		}
-	case 4:
+	case 5:
+		yyDollar = yyS[yypt-8 : yypt+1]
+//line partiql.y:160
+		{
+			yyVAL.sel = &expr.Select{Pivot: &expr.Pivot{Value: yyDollar[2].expr, At: yyDollar[4].expr}, From: yyDollar[5].from, Where: yyDollar[6].expr, GroupBy: yyDollar[7].bindings, Having: yyDollar[8].expr}
+		}
+	case 6:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:153
+//line partiql.y:165
 		{
 			yyVAL.str = "default"
 		}
-	case 5:
+	case 7:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:154
+//line partiql.y:166
 		{
 			yyVAL.str = yyDollar[3].str
 		}
-	case 6:
+	case 8:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:167
+		{
+			yyVAL.str = "analyze"
+		}
+	case 9:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:155
+//line partiql.y:168
 		{
 			yyVAL.str = ""
 		}
-	case 7:
+	case 10:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:158
+//line partiql.y:171
 		{
 			yyVAL.expr = yyDollar[2].expr
 		}
-	case 8:
+	case 11:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:158
+//line partiql.y:171
 		{
 			yyVAL.expr = nil
 		}
-	case 9:
+	case 12:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:161
+//line partiql.y:174
 		{
 			yyVAL.with = yyDollar[1].with
 		}
-	case 10:
+	case 13:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:161
+//line partiql.y:174
 		{
 			yyVAL.with = nil
 		}
-	case 11:
+	case 14:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:164
+//line partiql.y:177
 		{
 			yyVAL.unions = []unionItem{}
 		}
-	case 12:
+	case 15:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:165
+//line partiql.y:178
 		{
 			yyVAL.unions = append(yyVAL.unions, unionItem{typ: expr.UnionDistinct, sel: yyDollar[2].sel})
 			yyVAL.unions = append(yyVAL.unions, yyDollar[3].unions...)
 		}
-	case 13:
+	case 16:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:169
+//line partiql.y:182
 		{
 			yyVAL.unions = append(yyVAL.unions, unionItem{typ: expr.UnionAll, sel: yyDollar[3].sel})
 			yyVAL.unions = append(yyVAL.unions, yyDollar[4].unions...)
 		}
-	case 14:
+	case 17:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:175
+//line partiql.y:188
 		{
 			yyVAL.with = []expr.CTE{{Table: yyDollar[2].str, As: yyDollar[5].sel}}
 		}
-	case 15:
+	case 18:
 		yyDollar = yyS[yypt-7 : yypt+1]
-//line partiql.y:176
+//line partiql.y:189
 		{
 			yyVAL.with = append(yyDollar[1].with, expr.CTE{Table: yyDollar[3].str, As: yyDollar[6].sel})
 		}
-	case 16:
+	case 19:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:182
+//line partiql.y:195
 		{
 			yyVAL.bind = expr.Bind(yyDollar[1].expr, yyDollar[3].str)
 		}
-	case 17:
+	case 20:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:183
+//line partiql.y:196
 		{
 			yyVAL.bind = expr.Bind(yyDollar[1].expr, yyDollar[2].str)
 		}
-	case 18:
+	case 21:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:184
+//line partiql.y:197
 		{
 			yyVAL.bind = expr.Bind(yyDollar[1].expr, "")
 		}
-	case 19:
+	case 22:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:185
+//line partiql.y:198
 		{
 			yyVAL.bind = expr.Bind(expr.Star{}, "")
 		}
-	case 20:
+	case 23:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:186
+//line partiql.y:199
 		{
 			yyVAL.bind = expr.Bind(yyDollar[1].expr, "")
 		}
-	case 21:
+	case 24:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:190
+//line partiql.y:203
 		{
 			yyVAL.expr = expr.Ident(yyDollar[1].str)
 		}
-	case 22:
+	case 25:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:191
+//line partiql.y:204
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
-	case 23:
+	case 26:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:192
+//line partiql.y:205
 		{
 			yyVAL.expr = expr.Bool(true)
 		}
-	case 24:
+	case 27:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:193
+//line partiql.y:206
 		{
 			yyVAL.expr = expr.Bool(false)
 		}
-	case 25:
+	case 28:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:194
+//line partiql.y:207
 		{
 			yyVAL.expr = expr.Null{}
 		}
-	case 26:
+	case 29:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:195
+//line partiql.y:208
 		{
 			yyVAL.expr = expr.Missing{}
 		}
-	case 27:
+	case 30:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:196
+//line partiql.y:209
 		{
 			yyVAL.expr = expr.String(yyDollar[1].str)
 		}
-	case 28:
+	case 31:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:197
+//line partiql.y:210
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
-	case 29:
+	case 32:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:198
+//line partiql.y:211
 		{
 			yyVAL.expr = expr.Call(expr.MakeStruct, yyDollar[2].values...)
 		}
-	case 30:
+	case 33:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:199
+//line partiql.y:212
 		{
 			yyVAL.expr = expr.Call(expr.MakeList, yyDollar[2].values...)
 		}
-	case 31:
+	case 34:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:200
+//line partiql.y:213
 		{
 			yyVAL.expr = &expr.Dot{Inner: yyDollar[1].expr, Field: yyDollar[3].str}
 		}
-	case 32:
+	case 35:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:201
+//line partiql.y:214
 		{
 			yyVAL.expr = &expr.Index{Inner: yyDollar[1].expr, Offset: yyDollar[3].integer}
 		}
-	case 33:
+	case 36:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:202
+//line partiql.y:215
 		{
 			yyVAL.expr = &expr.Dot{Inner: yyDollar[1].expr, Field: yyDollar[3].str}
 		}
-	case 34:
+	case 37:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:214
+//line partiql.y:227
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
-	case 35:
+	case 38:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:215
+//line partiql.y:228
 		{
 			yyVAL.expr = yyDollar[2].expr
 		}
-	case 36:
+	case 39:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:218
+//line partiql.y:231
 		{
 			yyVAL.expr = yyDollar[1].sel
 		}
-	case 37:
+	case 40:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:219
+//line partiql.y:232
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
-	case 38:
+	case 41:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:222
+//line partiql.y:235
 		{
 			yyVAL.yesno = true
 		}
-	case 39:
+	case 42:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:222
+//line partiql.y:235
 		{
 			yyVAL.yesno = false
 		}
-	case 40:
+	case 43:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:225
+//line partiql.y:238
 		{
 			yyVAL.values = yyDollar[4].values
 		}
-	case 41:
+	case 44:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:226
+//line partiql.y:239
 		{
 			yyVAL.values = []expr.Node{}
 		}
-	case 42:
+	case 45:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:227
+//line partiql.y:240
 		{
 			yyVAL.values = nil
 		}
-	case 43:
+	case 46:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:233
+//line partiql.y:246
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
-	case 44:
+	case 47:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:237
+//line partiql.y:250
 		{
-			agg, err := toAggregate(expr.AggregateOp(yyDollar[1].integer), false, nil, yyDollar[4].expr, yyDollar[5].wind)
+			agg, err := toAggregate(expr.AggregateOp(yyDollar[1].integer), false, nil, nil, nil, yyDollar[4].expr, yyDollar[5].wind)
 			if err != nil {
 				yylex.Error(err.Error())
 			}
 			yyVAL.expr = agg
 		}
-	case 45:
-		yyDollar = yyS[yypt-7 : yypt+1]
-//line partiql.y:245
+	case 48:
+		yyDollar = yyS[yypt-9 : yypt+1]
+//line partiql.y:258
 		{
-			agg, err := toAggregate(expr.AggregateOp(yyDollar[1].integer), yyDollar[3].yesno, yyDollar[4].values, yyDollar[6].expr, yyDollar[7].wind)
+			agg, err := toAggregate(expr.AggregateOp(yyDollar[1].integer), yyDollar[3].yesno, yyDollar[4].values, yyDollar[5].orders, yyDollar[6].exprint, yyDollar[8].expr, yyDollar[9].wind)
 			if err != nil {
 				yylex.Error(err.Error())
 			}
 			yyVAL.expr = agg
 		}
-	case 46:
+	case 49:
+		yyDollar = yyS[yypt-11 : yypt+1]
+//line partiql.y:266
+		{
+			agg, err := toOrderedSetAggregate(expr.AggregateOp(yyDollar[1].integer), false, nil, nil, nil, yyDollar[9].order, yyDollar[11].expr)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.expr = agg
+		}
+	case 50:
+		yyDollar = yyS[yypt-15 : yypt+1]
+//line partiql.y:274
+		{
+			agg, err := toOrderedSetAggregate(expr.AggregateOp(yyDollar[1].integer), yyDollar[3].yesno, yyDollar[4].values, yyDollar[5].orders, yyDollar[6].exprint, yyDollar[13].order, yyDollar[15].expr)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.expr = agg
+		}
+	case 51:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:253
+//line partiql.y:282
 		{
 			yyVAL.expr = createCase(yyDollar[2].expr, yyDollar[3].limbs, yyDollar[4].expr)
 		}
-	case 47:
+	case 52:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:257
+//line partiql.y:286
 		{
 			yyVAL.expr = expr.Coalesce(yyDollar[3].values)
 		}
-	case 48:
+	case 53:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:261
+//line partiql.y:290
 		{
 			yyVAL.expr = expr.NullIf(yyDollar[3].expr, yyDollar[5].expr)
 		}
-	case 49:
+	case 54:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:265
+//line partiql.y:294
 		{
 			nod, ok := buildCast(yyDollar[3].expr, yyDollar[5].str)
 			if !ok {
@@ -1346,9 +1481,9 @@ yydefault:
 			}
 			yyVAL.expr = nod
 		}
-	case 50:
+	case 55:
 		yyDollar = yyS[yypt-8 : yypt+1]
-//line partiql.y:273
+//line partiql.y:302
 		{
 			part, ok := timePartFor(yyDollar[3].str, "DATE_ADD")
 			if !ok {
@@ -1356,9 +1491,9 @@ yydefault:
 			}
 			yyVAL.expr = expr.DateAdd(part, yyDollar[5].expr, yyDollar[7].expr)
 		}
-	case 51:
+	case 56:
 		yyDollar = yyS[yypt-8 : yypt+1]
-//line partiql.y:281
+//line partiql.y:310
 		{
 			interval, err := parseInterval(yyDollar[3].str)
 			if err != nil {
@@ -1366,9 +1501,9 @@ yydefault:
 			}
 			yyVAL.expr = expr.DateBinWithInterval(interval, yyDollar[5].expr, yyDollar[7].expr)
 		}
-	case 52:
+	case 57:
 		yyDollar = yyS[yypt-8 : yypt+1]
-//line partiql.y:289
+//line partiql.y:318
 		{
 			part, ok := timePartFor(yyDollar[3].str, "DATE_DIFF")
 			if !ok {
@@ -1376,9 +1511,9 @@ yydefault:
 			}
 			yyVAL.expr = expr.DateDiff(part, yyDollar[5].expr, yyDollar[7].expr)
 		}
-	case 53:
+	case 58:
 		yyDollar = yyS[yypt-9 : yypt+1]
-//line partiql.y:297
+//line partiql.y:326
 		{
 			dow, ok := weekday(yyDollar[5].str)
 			if strings.ToUpper(yyDollar[3].str) != "WEEK" || !ok {
@@ -1386,9 +1521,9 @@ yydefault:
 			}
 			yyVAL.expr = expr.DateTruncWeekday(yyDollar[8].expr, dow)
 		}
-	case 54:
+	case 59:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:305
+//line partiql.y:334
 		{
 			part, ok := timePartFor(yyDollar[3].str, "DATE_TRUNC")
 			if !ok {
@@ -1396,9 +1531,9 @@ yydefault:
 			}
 			yyVAL.expr = expr.DateTrunc(part, yyDollar[5].expr)
 		}
-	case 55:
+	case 60:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:313
+//line partiql.y:342
 		{
 			part, ok := timePartFor(yyDollar[3].str, "EXTRACT")
 			if !ok {
@@ -1406,15 +1541,15 @@ yydefault:
 			}
 			yyVAL.expr = expr.DateExtract(part, yyDollar[5].expr)
 		}
-	case 56:
+	case 61:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:321
+//line partiql.y:350
 		{
 			yyVAL.expr = yylex.(*scanner).utcnow()
 		}
-	case 57:
+	case 62:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:325
+//line partiql.y:354
 		{
 			node, err := createTrimInvocation(trimBoth, yyDollar[3].expr, nil)
 			if err != nil {
@@ -1422,9 +1557,9 @@ yydefault:
 			}
 			yyVAL.expr = node
 		}
-	case 58:
+	case 63:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:333
+//line partiql.y:362
 		{
 			node, err := createTrimInvocation(trimBoth, yyDollar[3].expr, yyDollar[5].expr)
 			if err != nil {
@@ -1432,9 +1567,9 @@ yydefault:
 			}
 			yyVAL.expr = node
 		}
-	case 59:
+	case 64:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:341
+//line partiql.y:370
 		{
 			node, err := createTrimInvocation(trimBoth, yyDollar[5].expr, yyDollar[3].expr)
 			if err != nil {
@@ -1442,9 +1577,9 @@ yydefault:
 			}
 			yyVAL.expr = node
 		}
-	case 60:
+	case 65:
 		yyDollar = yyS[yypt-7 : yypt+1]
-//line partiql.y:349
+//line partiql.y:378
 		{
 			node, err := createTrimInvocation(yyDollar[3].integer, yyDollar[6].expr, yyDollar[4].expr)
 			if err != nil {
@@ -1452,9 +1587,9 @@ yydefault:
 			}
 			yyVAL.expr = node
 		}
-	case 61:
+	case 66:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:357
+//line partiql.y:386
 		{
 			op := expr.CallByName(yyDollar[1].str)
 			if op.Private() {
@@ -1462,9 +1597,9 @@ yydefault:
 			}
 			yyVAL.expr = op
 		}
-	case 62:
+	case 67:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:365
+//line partiql.y:394
 		{
 			op := expr.CallByName(yyDollar[1].str, yyDollar[3].values...)
 			if op.Private() {
@@ -1472,489 +1607,586 @@ yydefault:
 			}
 			yyVAL.expr = op
 		}
-	case 63:
+	case 68:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:373
+//line partiql.y:402
 		{
 			yyVAL.expr = expr.Call(expr.InSubquery, yyDollar[1].expr, yyDollar[4].sel)
 		}
-	case 64:
+	case 69:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:377
+//line partiql.y:406
 		{
 			yyVAL.expr = expr.In(yyDollar[1].expr, yyDollar[4].values...)
 		}
-	case 65:
+	case 70:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:381
+//line partiql.y:410
 		{
 			yyVAL.expr = exists(yyDollar[3].sel)
 		}
-	case 66:
+	case 71:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:385
+//line partiql.y:414
 		{
 			yyVAL.expr = expr.BitOr(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 67:
+	case 72:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:389
+//line partiql.y:418
 		{
 			yyVAL.expr = expr.BitXor(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 68:
+	case 73:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:393
+//line partiql.y:422
 		{
 			yyVAL.expr = expr.BitAnd(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 69:
+	case 74:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:397
+//line partiql.y:426
 		{
 			yyVAL.expr = expr.ShiftLeftLogical(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 70:
+	case 75:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:401
+//line partiql.y:430
 		{
 			yyVAL.expr = expr.ShiftRightLogical(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 71:
+	case 76:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:405
+//line partiql.y:434
 		{
 			yyVAL.expr = expr.ShiftRightArithmetic(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 72:
+	case 77:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:409
+//line partiql.y:438
 		{
 			yyVAL.expr = expr.Add(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 73:
+	case 78:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:413
+//line partiql.y:442
 		{
 			yyVAL.expr = expr.Sub(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 74:
+	case 79:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:417
+//line partiql.y:446
 		{
 			yyVAL.expr = expr.Mul(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 75:
+	case 80:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:421
+//line partiql.y:450
 		{
 			yyVAL.expr = expr.Div(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 76:
+	case 81:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:425
+//line partiql.y:454
 		{
 			yyVAL.expr = expr.Mod(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 77:
+	case 82:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:429
+//line partiql.y:458
 		{
 			yyVAL.expr = expr.Call(expr.Concat, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 78:
+	case 83:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:433
+//line partiql.y:462
 		{
 			yyVAL.expr = expr.Append(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 79:
+	case 84:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:437
+//line partiql.y:466
 		{
 			yyVAL.expr = expr.Neg(yyDollar[2].expr)
 		}
-	case 80:
+	case 85:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:441
+//line partiql.y:470
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.Ilike, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str, Escape: yyDollar[5].str}
 		}
-	case 81:
+	case 86:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:445
+//line partiql.y:474
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.Ilike, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str}
 		}
-	case 82:
+	case 87:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:449
+//line partiql.y:478
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.Like, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str, Escape: yyDollar[5].str}
 		}
-	case 83:
+	case 88:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:453
+//line partiql.y:482
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.Like, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str}
 		}
-	case 84:
+	case 89:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:457
+//line partiql.y:486
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.SimilarTo, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str}
 		}
-	case 85:
+	case 90:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:461
+//line partiql.y:490
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.RegexpMatch, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str}
 		}
-	case 86:
+	case 91:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:465
+//line partiql.y:494
 		{
 			yyVAL.expr = &expr.StringMatch{Op: expr.RegexpMatchCi, Expr: yyDollar[1].expr, Pattern: yyDollar[3].str}
 		}
-	case 87:
+	case 92:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:469
+//line partiql.y:498
 		{
 			yyVAL.expr = expr.Compare(expr.Equals, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 88:
+	case 93:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:473
+//line partiql.y:502
 		{
 			yyVAL.expr = expr.Compare(expr.NotEquals, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 89:
+	case 94:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:477
+//line partiql.y:506
 		{
 			yyVAL.expr = expr.Compare(expr.Less, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 90:
+	case 95:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:481
+//line partiql.y:510
 		{
 			yyVAL.expr = expr.Compare(expr.LessEquals, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 91:
+	case 96:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:485
+//line partiql.y:514
 		{
 			yyVAL.expr = expr.Compare(expr.Greater, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 92:
+	case 97:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:489
+//line partiql.y:518
 		{
 			yyVAL.expr = expr.Compare(expr.GreaterEquals, yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 93:
+	case 98:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:493
+//line partiql.y:522
 		{
 			yyVAL.expr = expr.Between(yyDollar[1].expr, yyDollar[3].expr, yyDollar[5].expr)
 		}
-	case 94:
+	case 99:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:497
+//line partiql.y:526
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.Like, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str}}
 		}
-	case 95:
+	case 100:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:501
+//line partiql.y:530
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.Like, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str, Escape: yyDollar[6].str}}
 		}
-	case 96:
+	case 101:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:505
+//line partiql.y:534
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.Like, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str}}
 		}
-	case 97:
+	case 102:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:509
+//line partiql.y:538
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.Ilike, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str, Escape: yyDollar[6].str}}
 		}
-	case 98:
+	case 103:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:513
+//line partiql.y:542
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.SimilarTo, Expr: yyDollar[1].expr, Pattern: yyDollar[5].str}}
 		}
-	case 99:
+	case 104:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:517
+//line partiql.y:546
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.RegexpMatch, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str}}
 		}
-	case 100:
+	case 105:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:521
+//line partiql.y:550
 		{
 			yyVAL.expr = &expr.Not{Expr: &expr.StringMatch{Op: expr.RegexpMatchCi, Expr: yyDollar[1].expr, Pattern: yyDollar[4].str}}
 		}
-	case 101:
+	case 106:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:525
+//line partiql.y:554
 		{
 			yyVAL.expr = &expr.Not{Expr: yyDollar[2].expr}
 		}
-	case 102:
+	case 107:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:529
+//line partiql.y:558
 		{
 			yyVAL.expr = expr.BitNot(yyDollar[2].expr)
 		}
-	case 103:
+	case 108:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:533
+//line partiql.y:562
 		{
 			yyVAL.expr = expr.And(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 104:
+	case 109:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:537
+//line partiql.y:566
 		{
 			yyVAL.expr = expr.Or(yyDollar[1].expr, yyDollar[3].expr)
 		}
-	case 105:
+	case 110:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:541
+//line partiql.y:570
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsNull, Expr: yyDollar[1].expr}
 		}
-	case 106:
+	case 111:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:545
+//line partiql.y:574
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsNotNull, Expr: yyDollar[1].expr}
 		}
-	case 107:
+	case 112:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:549
+//line partiql.y:578
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsMissing, Expr: yyDollar[1].expr}
 		}
-	case 108:
+	case 113:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:553
+//line partiql.y:582
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsNotMissing, Expr: yyDollar[1].expr}
 		}
-	case 109:
+	case 114:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:557
+//line partiql.y:586
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsTrue, Expr: yyDollar[1].expr}
 		}
-	case 110:
+	case 115:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:561
+//line partiql.y:590
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsNotTrue, Expr: yyDollar[1].expr}
 		}
-	case 111:
+	case 116:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:565
+//line partiql.y:594
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsFalse, Expr: yyDollar[1].expr}
 		}
-	case 112:
+	case 117:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:569
+//line partiql.y:598
 		{
 			yyVAL.expr = &expr.IsKey{Key: expr.IsNotFalse, Expr: yyDollar[1].expr}
 		}
-	case 113:
+	case 118:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:575
+//line partiql.y:604
 		{
 			yyVAL.bindings = []expr.Binding{yyDollar[1].bind}
 		}
-	case 114:
+	case 119:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:576
+//line partiql.y:605
 		{
 			yyVAL.bindings = append(yyDollar[1].bindings, yyDollar[3].bind)
 		}
-	case 115:
+	case 120:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:580
+//line partiql.y:609
 		{
 			yyVAL.values = []expr.Node{yyDollar[1].expr}
 		}
-	case 116:
+	case 121:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:581
+//line partiql.y:610
 		{
 			yyVAL.values = append(yyDollar[1].values, yyDollar[3].expr)
 		}
-	case 117:
+	case 122:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:585
+//line partiql.y:614
 		{
 			yyVAL.values = []expr.Node{yyDollar[1].expr}
 		}
-	case 118:
+	case 123:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:586
+//line partiql.y:615
 		{
 			yyVAL.values = []expr.Node{expr.Star{}}
 		}
-	case 119:
+	case 124:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:587
+//line partiql.y:616
 		{
 			yyVAL.values = append(yyDollar[1].values, yyDollar[3].expr)
 		}
-	case 120:
+	case 125:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:591
+//line partiql.y:620
 		{
 			yyVAL.values = []expr.Node{yyDollar[1].expr}
 		}
-	case 121:
+	case 126:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:592
+//line partiql.y:621
 		{
 			yyVAL.values = append(yyDollar[1].values, yyDollar[3].expr)
 		}
-	case 122:
+	case 127:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:593
+//line partiql.y:622
 		{
 			yyVAL.values = nil
 		}
-	case 123:
+	case 128:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:597
+//line partiql.y:626
 		{
 			yyVAL.values = yyDollar[1].values
 		}
-	case 124:
+	case 129:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:598
+//line partiql.y:627
 		{
 			yyVAL.values = append(yyDollar[1].values, yyDollar[3].values...)
 		}
-	case 125:
+	case 130:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:599
+//line partiql.y:628
 		{
 			yyVAL.values = nil
 		}
-	case 126:
+	case 131:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:603
+//line partiql.y:632
 		{
 			yyVAL.values = []expr.Node{expr.String(yyDollar[1].str), yyDollar[3].expr}
 		}
-	case 127:
+	case 132:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:607
+//line partiql.y:636
 		{
 			yyVAL.values = yyDollar[3].values
 		}
-	case 128:
+	case 133:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:610
+//line partiql.y:639
 		{
 			yyVAL.values = nil
 		}
-	case 129:
+	case 134:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:614
+//line partiql.y:643
 		{
 			yyVAL.wind = &expr.Window{PartitionBy: yyDollar[3].values, OrderBy: yyDollar[4].orders}
 		}
-	case 130:
+	case 135:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:617
+//line partiql.y:646
 		{
 			yyVAL.wind = nil
 		}
-	case 131:
+	case 136:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:620
+//line partiql.y:649
 		{
 			yyVAL.jk = expr.InnerJoin
 		}
-	case 132:
+	case 137:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:621
+//line partiql.y:650
 		{
 			yyVAL.jk = expr.InnerJoin
 		}
-	case 133:
+	case 138:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:622
+//line partiql.y:651
 		{
 			yyVAL.jk = expr.LeftJoin
 		}
-	case 134:
+	case 139:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:623
+//line partiql.y:652
 		{
 			yyVAL.jk = expr.LeftJoin
 		}
-	case 135:
+	case 140:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:624
+//line partiql.y:653
 		{
 			yyVAL.jk = expr.RightJoin
 		}
-	case 136:
+	case 141:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:625
+//line partiql.y:654
 		{
 			yyVAL.jk = expr.RightJoin
 		}
-	case 137:
+	case 142:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:626
+//line partiql.y:655
 		{
 			yyVAL.jk = expr.FullJoin
 		}
-	case 140:
+	case 145:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:631
+//line partiql.y:660
 		{
 			yyVAL.from = yyDollar[1].from
 		}
-	case 141:
+	case 146:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:632
+//line partiql.y:661
 		{
 			yyVAL.from = nil
 		}
-	case 142:
+	case 147:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:635
+//line partiql.y:664
 		{
 			yyVAL.from = &expr.Table{Binding: yyDollar[2].bind}
 		}
-	case 143:
+	case 148:
+		yyDollar = yyS[yypt-7 : yypt+1]
+//line partiql.y:666
+		{
+			sample, err := toTableSample(yyDollar[4].str, yyDollar[6].expr)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.from = &expr.Table{Binding: yyDollar[2].bind, Sample: sample}
+		}
+	case 149:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:636
+//line partiql.y:673
 		{
 			yyVAL.from = &expr.Join{Kind: expr.CrossJoin, Left: yyDollar[1].from, Right: yyDollar[3].bind}
 		}
-	case 144:
+	case 150:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line partiql.y:675
+		{
+			if err := checkLateral(yyDollar[4].bind); err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.from = &expr.Join{Kind: expr.CrossJoin, Left: yyDollar[1].from, Right: yyDollar[4].bind}
+		}
+	case 151:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:638
+//line partiql.y:682
 		{
 			yyVAL.from = &expr.Join{Kind: yyDollar[2].jk, Left: yyDollar[1].from, Right: yyDollar[3].bind, On: yyDollar[5].expr}
 		}
-	case 145:
+	case 152:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line partiql.y:684
+		{
+			if err := checkLateral(yyDollar[4].bind); err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.from = &expr.Join{Kind: yyDollar[2].jk, Left: yyDollar[1].from, Right: yyDollar[4].bind, On: yyDollar[6].expr}
+		}
+	case 153:
+		yyDollar = yyS[yypt-11 : yypt+1]
+//line partiql.y:691
+		{
+			p, err := toPivotTable(yyDollar[1].from, yyDollar[4].expr, yyDollar[5].str, yyDollar[6].expr, yyDollar[9].bindings)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.from = p
+		}
+	case 154:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:702
+		{
+			yyVAL.bind = yyDollar[1].bind
+		}
+	case 155:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line partiql.y:704
+		{
+			yyVAL.bind = expr.Bind(&expr.Unnest{Arrays: []expr.Binding{expr.Bind(yyDollar[1].expr, yyDollar[3].str)}, At: yyDollar[5].str}, "")
+		}
+	case 156:
+		yyDollar = yyS[yypt-7 : yypt+1]
+//line partiql.y:708
+		{
+			u, err := toUnnest(yyDollar[3].values, yyDollar[5].yesno, []string{yyDollar[7].str})
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.bind = expr.Bind(u, "")
+		}
+	case 157:
+		yyDollar = yyS[yypt-9 : yypt+1]
+//line partiql.y:716
+		{
+			u, err := toUnnest(yyDollar[3].values, yyDollar[5].yesno, yyDollar[8].strs)
+			if err != nil {
+				yylex.Error(err.Error())
+			}
+			yyVAL.bind = expr.Bind(u, "")
+		}
+	case 158:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line partiql.y:726
+		{
+			if !strings.EqualFold(yyDollar[2].str, "ORDINALITY") {
+				yylex.Error(fmt.Sprintf("UNNEST: unexpected WITH %s", yyDollar[2].str))
+			}
+			yyVAL.yesno = true
+		}
+	case 159:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line partiql.y:732
+		{
+			yyVAL.yesno = false
+		}
+	case 160:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:641
+//line partiql.y:735
+		{
+			yyVAL.strs = []string{yyDollar[1].str}
+		}
+	case 161:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line partiql.y:736
+		{
+			yyVAL.strs = append(yyDollar[1].strs, yyDollar[3].str)
+		}
+	case 162:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line partiql.y:739
 		{
 			var idxerr error
 			yyVAL.integer, idxerr = toint(yyDollar[1].expr)
@@ -1962,239 +2194,239 @@ yydefault:
 				yylex.Error(idxerr.Error())
 			}
 		}
-	case 146:
+	case 163:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:650
+//line partiql.y:748
 		{
 			yyVAL.str = yyDollar[1].str
 		}
-	case 147:
+	case 164:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:653
+//line partiql.y:751
 		{
 			yyVAL.expr = nil
 		}
-	case 148:
+	case 165:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:654
+//line partiql.y:752
 		{
 			yyVAL.expr = yyDollar[2].expr
 		}
-	case 149:
+	case 166:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:657
+//line partiql.y:755
 		{
 			yyVAL.limbs = []expr.CaseLimb{{When: yyDollar[2].expr, Then: yyDollar[4].expr}}
 		}
-	case 150:
+	case 167:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:658
+//line partiql.y:756
 		{
 			yyVAL.limbs = append(yyDollar[1].limbs, expr.CaseLimb{When: yyDollar[3].expr, Then: yyDollar[5].expr})
 		}
-	case 151:
+	case 168:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:661
+//line partiql.y:759
 		{
 			yyVAL.expr = nil
 		}
-	case 152:
+	case 169:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:662
+//line partiql.y:760
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
-	case 153:
+	case 170:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:665
+//line partiql.y:763
 		{
 			yyVAL.expr = nil
 		}
-	case 154:
+	case 171:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line partiql.y:666
+//line partiql.y:764
 		{
 			yyVAL.expr = yyDollar[4].expr
 		}
-	case 155:
+	case 172:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:669
+//line partiql.y:767
 		{
 			yyVAL.expr = nil
 		}
-	case 156:
+	case 173:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:670
+//line partiql.y:768
 		{
 			yyVAL.expr = yyDollar[2].expr
 		}
-	case 157:
+	case 174:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:673
+//line partiql.y:771
 		{
 			yyVAL.expr = nil
 		}
-	case 158:
+	case 175:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:674
+//line partiql.y:772
 		{
 			yyVAL.expr = yyDollar[2].expr
 		}
-	case 159:
+	case 176:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:677
+//line partiql.y:775
 		{
 			yyVAL.bindings = nil
 		}
-	case 160:
+	case 177:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:678
+//line partiql.y:776
 		{
 			yyVAL.bindings = yyDollar[3].bindings
 		}
-	case 161:
+	case 178:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:682
+//line partiql.y:780
 		{
 			yyVAL.yesno = false
 		}
-	case 162:
+	case 179:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:683
+//line partiql.y:781
 		{
 			yyVAL.yesno = false
 		}
-	case 163:
+	case 180:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:684
+//line partiql.y:782
 		{
 			yyVAL.yesno = true
 		}
-	case 164:
+	case 181:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:688
+//line partiql.y:786
 		{
 			yyVAL.yesno = false
 		}
-	case 165:
+	case 182:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:689
+//line partiql.y:787
 		{
 			yyVAL.yesno = false
 		}
-	case 166:
+	case 183:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:690
+//line partiql.y:788
 		{
 			yyVAL.yesno = true
 		}
-	case 167:
+	case 184:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:694
+//line partiql.y:792
 		{
 			yyVAL.order = expr.Order{Column: yyDollar[1].expr, Desc: yyDollar[2].yesno, NullsLast: yyDollar[3].yesno}
 		}
-	case 168:
+	case 185:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:697
+//line partiql.y:795
 		{
 			yyVAL.orders = append(yyDollar[1].orders, yyDollar[3].order)
 		}
-	case 169:
+	case 186:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:698
+//line partiql.y:796
 		{
 			yyVAL.orders = []expr.Order{yyDollar[1].order}
 		}
-	case 170:
+	case 187:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:701
+//line partiql.y:799
 		{
 			yyVAL.orders = nil
 		}
-	case 171:
+	case 188:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line partiql.y:702
+//line partiql.y:800
 		{
 			yyVAL.orders = yyDollar[3].orders
 		}
-	case 172:
+	case 189:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:705
+//line partiql.y:803
 		{
 			yyVAL.exprint = nil
 		}
-	case 173:
+	case 190:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:706
+//line partiql.y:804
 		{
 			n := expr.Integer(yyDollar[2].integer)
 			yyVAL.exprint = &n
 		}
-	case 174:
+	case 191:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line partiql.y:709
+//line partiql.y:807
 		{
 			yyVAL.exprint = nil
 		}
-	case 175:
+	case 192:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line partiql.y:710
+//line partiql.y:808
 		{
 			n := expr.Integer(yyDollar[2].integer)
 			yyVAL.exprint = &n
 		}
-	case 176:
+	case 193:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:713
+//line partiql.y:811
 		{ /*Cloning, as the buffer gets overwritten*/
 			as := yyDollar[4].str
 			at := yyDollar[6].str
 			yyVAL.expr = &expr.Unpivot{TupleRef: yyDollar[2].expr, As: &as, At: &at}
 		}
-	case 177:
+	case 194:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line partiql.y:714
+//line partiql.y:812
 		{ /*Cloning, as the buffer gets overwritten*/
 			as := yyDollar[6].str
 			at := yyDollar[4].str
 			yyVAL.expr = &expr.Unpivot{TupleRef: yyDollar[2].expr, As: &as, At: &at}
 		}
-	case 178:
+	case 195:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:715
+//line partiql.y:813
 		{ /*Cloning, as the buffer gets overwritten*/
 			as := yyDollar[4].str
 			yyVAL.expr = &expr.Unpivot{TupleRef: yyDollar[2].expr, As: &as, At: nil}
 		}
-	case 179:
+	case 196:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line partiql.y:716
+//line partiql.y:814
 		{ /*Cloning, as the buffer gets overwritten*/
 			at := yyDollar[4].str
 			yyVAL.expr = &expr.Unpivot{TupleRef: yyDollar[2].expr, As: nil, At: &at}
 		}
-	case 180:
+	case 197:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:719
+//line partiql.y:817
 		{
 			yyVAL.expr = &expr.Table{Binding: expr.Bind(yyDollar[1].expr, "")}
 		}
-	case 181:
+	case 198:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:723
+//line partiql.y:821
 		{
 			yyVAL.integer = trimLeading
 		}
-	case 182:
+	case 199:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:724
+//line partiql.y:822
 		{
 			yyVAL.integer = trimTrailing
 		}
-	case 183:
+	case 200:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line partiql.y:725
+//line partiql.y:823
 		{
 			yyVAL.integer = trimBoth
 		}
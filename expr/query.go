@@ -83,6 +83,8 @@ func (q *Query) text(dst *strings.Builder, redact bool) {
 		dst.WriteString("EXPLAIN AS list ")
 	case ExplainGraphviz:
 		dst.WriteString("EXPLAIN AS graphviz ")
+	case ExplainAnalyze:
+		dst.WriteString("EXPLAIN ANALYZE ")
 	}
 
 	if len(q.With) > 0 {
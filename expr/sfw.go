@@ -163,6 +163,60 @@ type From interface {
 // as a bag of values
 type Table struct {
 	Binding
+	// Sample, if non-nil, is the
+	// TABLESAMPLE clause of the table
+	Sample *TableSample
+}
+
+// SampleMethod is the sampling
+// method of a TABLESAMPLE clause
+type SampleMethod uint8
+
+const (
+	// SampleSystem samples whole blocks of a table
+	SampleSystem SampleMethod = iota + 1
+	// SampleBernoulli samples individual rows
+	SampleBernoulli
+)
+
+func (m SampleMethod) String() string {
+	switch m {
+	case SampleSystem:
+		return "SYSTEM"
+	case SampleBernoulli:
+		return "BERNOULLI"
+	default:
+		return fmt.Sprintf("SampleMethod(%d)", m)
+	}
+}
+
+// TableSample is a TABLESAMPLE clause
+type TableSample struct {
+	Method SampleMethod
+	// Percent is the percentage of
+	// the table to sample in (0, 100]
+	Percent float64
+}
+
+// Fraction returns the fraction
+// of the table that is sampled
+func (t *TableSample) Fraction() float64 {
+	return t.Percent / 100
+}
+
+func (t *TableSample) Equals(o *TableSample) bool {
+	if t == nil || o == nil {
+		return t == o
+	}
+	return *t == *o
+}
+
+func (t *TableSample) text(dst *strings.Builder) {
+	dst.WriteString(" TABLESAMPLE ")
+	dst.WriteString(t.Method.String())
+	dst.WriteString(" (")
+	dst.WriteString(strconv.FormatFloat(t.Percent, 'g', -1, 64))
+	dst.WriteString(")")
 }
 
 func (t *Table) Tables() []Binding {
@@ -189,7 +243,15 @@ func (t *Table) rewrite(r Rewriter) Node {
 
 func (t *Table) Equals(x Node) bool {
 	xt, ok := x.(*Table)
-	return ok && t.explicit == xt.explicit && t.as == xt.as && t.Expr.Equals(xt.Expr)
+	return ok && t.explicit == xt.explicit && t.as == xt.as && t.Expr.Equals(xt.Expr) &&
+		t.Sample.Equals(xt.Sample)
+}
+
+func (t *Table) text(dst *strings.Builder, redact bool) {
+	t.Binding.text(dst, redact)
+	if t.Sample != nil {
+		t.Sample.text(dst)
+	}
 }
 
 func (t *Table) Encode(dst *ion.Buffer, st *ion.Symtab) {
@@ -201,9 +263,22 @@ func (t *Table) Encode(dst *ion.Buffer, st *ion.Symtab) {
 		dst.BeginField(st.Intern("bind"))
 		dst.WriteString(t.Result())
 	}
+	if t.Sample != nil {
+		dst.BeginField(st.Intern("sample_method"))
+		dst.WriteUint(uint64(t.Sample.Method))
+		dst.BeginField(st.Intern("sample_percent"))
+		dst.WriteFloat64(t.Sample.Percent)
+	}
 	dst.EndStruct()
 }
 
+func (t *Table) sample() *TableSample {
+	if t.Sample == nil {
+		t.Sample = new(TableSample)
+	}
+	return t.Sample
+}
+
 func (t *Table) SetField(f ion.Field) error {
 	var err error
 	switch f.Label {
@@ -215,6 +290,12 @@ func (t *Table) SetField(f ion.Field) error {
 			return err
 		}
 		t.As(str)
+	case "sample_method":
+		var m uint64
+		m, err = f.Uint()
+		t.sample().Method = SampleMethod(m)
+	case "sample_percent":
+		t.sample().Percent, err = f.Float()
 	default:
 		return errUnexpectedField
 	}
@@ -338,6 +419,260 @@ func (j *Join) text(out *strings.Builder, redact bool) {
 	}
 }
 
+// Unnest is the right-hand side of a join
+// that iterates the elements of one or more
+// arrays in lockstep, as in
+//
+//	x.items AS item AT idx
+//	UNNEST(x.a, x.b) WITH ORDINALITY AS (a, b, idx)
+//
+// Shorter arrays are padded with MISSING.
+type Unnest struct {
+	// Arrays are the arrays to iterate
+	// and the bindings of their elements
+	Arrays []Binding
+	// At, if non-empty, is the binding
+	// of the position of each element
+	At string
+	// Ordinality indicates that positions
+	// start at 1 (WITH ORDINALITY) rather
+	// than 0 (AT)
+	Ordinality bool
+}
+
+func (u *Unnest) Equals(x Node) bool {
+	xu, ok := x.(*Unnest)
+	return ok && u.At == xu.At && u.Ordinality == xu.Ordinality &&
+		slices.EqualFunc(u.Arrays, xu.Arrays, Binding.Equals)
+}
+
+func (u *Unnest) Encode(dst *ion.Buffer, st *ion.Symtab) {
+	dst.BeginStruct(-1)
+	settype(dst, st, "unnest")
+	dst.BeginField(st.Intern("arrays"))
+	EncodeBindings(u.Arrays, dst, st)
+	if u.At != "" {
+		dst.BeginField(st.Intern("at"))
+		dst.WriteString(u.At)
+	}
+	if u.Ordinality {
+		dst.BeginField(st.Intern("ordinality"))
+		dst.WriteBool(true)
+	}
+	dst.EndStruct()
+}
+
+func (u *Unnest) SetField(f ion.Field) error {
+	var err error
+	switch f.Label {
+	case "arrays":
+		u.Arrays, err = decodeBindings(f.Datum)
+	case "at":
+		u.At, err = f.String()
+	case "ordinality":
+		u.Ordinality, err = f.Bool()
+	default:
+		return errUnexpectedField
+	}
+	return err
+}
+
+func (u *Unnest) walk(v Visitor) {
+	for i := range u.Arrays {
+		walkbind(v, &u.Arrays[i])
+	}
+}
+
+func (u *Unnest) rewrite(r Rewriter) Node {
+	for i := range u.Arrays {
+		u.Arrays[i] = rewritebind(r, &u.Arrays[i])
+	}
+	return u
+}
+
+func (u *Unnest) text(dst *strings.Builder, redact bool) {
+	if len(u.Arrays) == 1 && !u.Ordinality {
+		u.Arrays[0].text(dst, redact)
+		if u.At != "" {
+			dst.WriteString(" AT ")
+			dst.WriteString(QuoteID(u.At))
+		}
+		return
+	}
+	dst.WriteString("UNNEST(")
+	for i := range u.Arrays {
+		if i > 0 {
+			dst.WriteString(", ")
+		}
+		u.Arrays[i].Expr.text(dst, redact)
+	}
+	dst.WriteString(")")
+	if u.Ordinality {
+		dst.WriteString(" WITH ORDINALITY")
+	}
+	dst.WriteString(" AS (")
+	for i := range u.Arrays {
+		if i > 0 {
+			dst.WriteString(", ")
+		}
+		dst.WriteString(QuoteID(u.Arrays[i].Result()))
+	}
+	if u.At != "" {
+		dst.WriteString(", ")
+		dst.WriteString(QuoteID(u.At))
+	}
+	dst.WriteString(")")
+}
+
+// Pivot is the PIVOT v AT k clause that takes
+// the place of the output columns of a query
+// and produces a single structure with a
+// field k set to v for each input row
+// (or for each group with GROUP BY)
+type Pivot struct {
+	Value, At Node
+}
+
+func (p *Pivot) Equals(x *Pivot) bool {
+	if p == nil || x == nil {
+		return p == x
+	}
+	return p.Value.Equals(x.Value) && p.At.Equals(x.At)
+}
+
+func (p *Pivot) text(dst *strings.Builder, redact bool) {
+	dst.WriteString("PIVOT ")
+	p.Value.text(dst, redact)
+	dst.WriteString(" AT ")
+	p.At.text(dst, redact)
+}
+
+// PivotTable is an implementation of From
+// that spreads an aggregate over the rows of
+// From into one column per value of For, as in
+//
+//	FROM t PIVOT (SUM(x) FOR y IN ('a' AS a, 'b'))
+//
+// The columns of the query that are not
+// produced by the pivot are grouped.
+type PivotTable struct {
+	From From
+	Agg  *Aggregate
+	For  Node
+	// In are the values of For
+	// and the names of their columns
+	In []Binding
+}
+
+// Names returns the names of
+// the columns produced for In
+func (p *PivotTable) Names() []string {
+	out := make([]string, len(p.In))
+	for i := range p.In {
+		if p.In[i].Explicit() {
+			out[i] = p.In[i].Result()
+		} else if s, ok := p.In[i].Expr.(String); ok {
+			out[i] = string(s)
+		} else {
+			out[i] = ToString(p.In[i].Expr)
+		}
+	}
+	return out
+}
+
+func (p *PivotTable) Tables() []Binding {
+	return p.From.Tables()
+}
+
+func (p *PivotTable) walk(v Visitor) {
+	Walk(v, p.From)
+	Walk(v, p.Agg)
+	Walk(v, p.For)
+	for i := range p.In {
+		walkbind(v, &p.In[i])
+	}
+}
+
+func (p *PivotTable) rewrite(r Rewriter) Node {
+	p.From = Rewrite(r, p.From).(From)
+	// the aggregate is rewritten only
+	// if it remains an aggregate
+	if agg, ok := Rewrite(r, p.Agg).(*Aggregate); ok {
+		p.Agg = agg
+	}
+	p.For = Rewrite(r, p.For)
+	for i := range p.In {
+		p.In[i] = rewritebind(r, &p.In[i])
+	}
+	return p
+}
+
+func (p *PivotTable) Equals(x Node) bool {
+	xp, ok := x.(*PivotTable)
+	return ok && p.From.Equals(xp.From) && p.Agg.Equals(xp.Agg) &&
+		p.For.Equals(xp.For) && slices.EqualFunc(p.In, xp.In, Binding.Equals)
+}
+
+func (p *PivotTable) Encode(dst *ion.Buffer, st *ion.Symtab) {
+	dst.BeginStruct(-1)
+	settype(dst, st, "pivot_table")
+	dst.BeginField(st.Intern("from"))
+	p.From.Encode(dst, st)
+	dst.BeginField(st.Intern("agg"))
+	p.Agg.Encode(dst, st)
+	dst.BeginField(st.Intern("for"))
+	p.For.Encode(dst, st)
+	dst.BeginField(st.Intern("in"))
+	EncodeBindings(p.In, dst, st)
+	dst.EndStruct()
+}
+
+func (p *PivotTable) SetField(f ion.Field) error {
+	var err error
+	switch f.Label {
+	case "from":
+		var e Node
+		e, err = Decode(f.Datum)
+		if err != nil {
+			return err
+		}
+		from, ok := e.(From)
+		if !ok {
+			return fmt.Errorf("%q not valid in FROM position", e)
+		}
+		p.From = from
+	case "agg":
+		var e Node
+		e, err = Decode(f.Datum)
+		if err != nil {
+			return err
+		}
+		agg, ok := e.(*Aggregate)
+		if !ok {
+			return fmt.Errorf("PIVOT expects an aggregate, but %q is provided", e)
+		}
+		p.Agg = agg
+	case "for":
+		p.For, err = Decode(f.Datum)
+	case "in":
+		p.In, err = decodeBindings(f.Datum)
+	default:
+		return errUnexpectedField
+	}
+	return err
+}
+
+func (p *PivotTable) text(dst *strings.Builder, redact bool) {
+	p.From.text(dst, redact)
+	dst.WriteString(" PIVOT (")
+	p.Agg.text(dst, redact)
+	dst.WriteString(" FOR ")
+	p.For.text(dst, redact)
+	dst.WriteString(" IN (")
+	fmtbinding(p.In, dst, redact)
+	dst.WriteString("))")
+}
+
 type Order struct {
 	Column          Node
 	Desc, NullsLast bool
@@ -375,6 +710,9 @@ type Select struct {
 	DistinctExpr []Node
 	// List of output columns
 	Columns []Binding
+	// PIVOT v AT k in place of
+	// the output columns, or nil
+	Pivot *Pivot
 	// FROM clause
 	From From
 	// WHERE clause
@@ -412,6 +750,10 @@ func (s *Select) walk(v Visitor) {
 	for i := range s.Columns {
 		walkbind(v, &s.Columns[i])
 	}
+	if s.Pivot != nil {
+		Walk(v, s.Pivot.Value)
+		Walk(v, s.Pivot.At)
+	}
 	for i := range s.OrderBy {
 		Walk(v, s.OrderBy[i].Column)
 	}
@@ -446,6 +788,10 @@ func (s *Select) rewrite(r Rewriter) Node {
 	for i := range s.Columns {
 		s.Columns[i] = rewritebind(r, &s.Columns[i])
 	}
+	if s.Pivot != nil {
+		s.Pivot.Value = Rewrite(r, s.Pivot.Value)
+		s.Pivot.At = Rewrite(r, s.Pivot.At)
+	}
 	s.Where = Rewrite(r, s.Where)
 	s.Having = Rewrite(r, s.Having)
 	for i := range s.GroupBy {
@@ -477,6 +823,9 @@ func (s *Select) Equals(x Node) bool {
 	if !slices.EqualFunc(s.Columns, xs.Columns, Binding.Equals) {
 		return false
 	}
+	if !s.Pivot.Equals(xs.Pivot) {
+		return false
+	}
 	if s.Where != nil && !s.Where.Equals(xs.Where) {
 		return false
 	}
@@ -542,6 +891,10 @@ func (s *Select) Encode(dst *ion.Buffer, st *ion.Symtab) {
 	settype(dst, st, "select")
 	dst.BeginField(st.Intern("cols"))
 	EncodeBindings(s.Columns, dst, st)
+	if s.Pivot != nil {
+		addfield(dst, st, "pivot_value", s.Pivot.Value)
+		addfield(dst, st, "pivot_at", s.Pivot.At)
+	}
 	addfield(dst, st, "from", s.From)
 	addfield(dst, st, "where", s.Where)
 	addfield(dst, st, "having", s.Having)
@@ -600,6 +953,19 @@ func (s *Select) Text() string {
 }
 
 func (s *Select) write(out *strings.Builder, redact bool, into Node) {
+	if s.Pivot != nil {
+		s.Pivot.text(out, redact)
+	} else {
+		s.writeColumns(out, redact)
+	}
+	if into != nil {
+		out.WriteString(" INTO ")
+		into.text(out, redact)
+	}
+	s.writeFrom(out, redact)
+}
+
+func (s *Select) writeColumns(out *strings.Builder, redact bool) {
 	out.WriteString("SELECT ")
 	if s.Distinct {
 		out.WriteString("DISTINCT ")
@@ -615,10 +981,9 @@ func (s *Select) write(out *strings.Builder, redact bool, into Node) {
 		out.WriteString(") ")
 	}
 	fmtbinding(s.Columns, out, redact)
-	if into != nil {
-		out.WriteString(" INTO ")
-		into.text(out, redact)
-	}
+}
+
+func (s *Select) writeFrom(out *strings.Builder, redact bool) {
 	if s.From != nil {
 		out.WriteString(" FROM ")
 		s.From.text(out, redact)
@@ -767,11 +1132,22 @@ func decodeDistinctExpr(d ion.Datum) ([]Node, error) {
 	return out, nil
 }
 
+func (s *Select) pivot() *Pivot {
+	if s.Pivot == nil {
+		s.Pivot = new(Pivot)
+	}
+	return s.Pivot
+}
+
 func (s *Select) SetField(f ion.Field) error {
 	var err error
 	switch f.Label {
 	case "cols":
 		s.Columns, err = decodeBindings(f.Datum)
+	case "pivot_value":
+		s.pivot().Value, err = Decode(f.Datum)
+	case "pivot_at":
+		s.pivot().At, err = Decode(f.Datum)
 	case "from":
 		var e Node
 		e, err = Decode(f.Datum)
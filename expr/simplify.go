@@ -60,6 +60,7 @@ const (
 	DecimalType TypeSet = (1 << ion.DecimalType)
 	SymbolType  TypeSet = (1 << ion.SymbolType)
 	NullType    TypeSet = (1 << ion.NullType)
+	BlobType    TypeSet = (1 << ion.BlobType)
 )
 
 // Only returns whether or not t
@@ -823,7 +824,11 @@ func (a *Aggregate) simplify(h Hint) Node {
 		variance := Sub(avgSQ, Mul(avgS, avgS))
 		stddev := Call(Sqrt, variance)
 		return IfThenElse(Compare(Equals, cnt, Integer(0)), Null{}, stddev)
-	case OpMin, OpMax, OpSum, OpAvg:
+	case OpCovarPop, OpCovarSamp, OpCorr, OpRegrSlope, OpRegrIntercept, OpRegrR2:
+		a.Inner = missingUnless(a.Inner, h, NumericType)
+		a.Arg = missingUnless(a.Arg, h, NumericType)
+	case OpMin, OpMax, OpSum, OpAvg,
+		OpVarianceSamp, OpStdDevSamp, OpSkewness, OpKurtosis:
 		a.Inner = missingUnless(a.Inner, h, NumericType)
 	}
 	// convert SUM(x) where 'x' is always an integer
@@ -833,7 +838,7 @@ func (a *Aggregate) simplify(h Hint) Node {
 	}
 
 	if a.Filter != nil {
-		iscount := (a.Op == OpCount || a.Op == OpCountDistinct || a.Op == OpApproxCountDistinct)
+		iscount := (a.Op == OpCount || a.Op == OpCountDistinct || a.Op == OpApproxCountDistinct || a.Op == OpHLLEstimate)
 		switch v := a.Filter.(type) {
 		case Null, Missing:
 			if iscount {
@@ -713,6 +713,154 @@ func TestSimplify(t *testing.T) {
 			Call(Lower, String("SNELLER")),
 			String("sneller"),
 		},
+		{
+			Call(MD5, String("sneller")),
+			String("0be837ae647925e15cbc0b36491150ef"),
+		},
+		{
+			Call(SHA1, String("sneller")),
+			String("08fe82b1fc12d68dc198eb4d6cd1ffc8f49e70c8"),
+		},
+		{
+			Call(SHA256, String("sneller")),
+			String("8ec7bf04c71ffb1ee05ea0a4899cef044779ff125fc0a8f9888830be6590502d"),
+		},
+		{
+			Call(XXHash64, String("sneller")),
+			Integer(-5919019606671992455),
+		},
+		{
+			Call(Fingerprint, String("sneller")),
+			Integer(3901918454249243114),
+		},
+		{
+			Call(Fingerprint, Integer(1)),
+			Integer(4224345907433601797),
+		},
+		{
+			Call(ToHex, String("sneller")),
+			String("736e656c6c6572"),
+		},
+		{
+			Call(FromHex, String("736E656C6C6572")),
+			String("sneller"),
+		},
+		{
+			Call(FromHex, String("736e6")),
+			Missing{},
+		},
+		{
+			// decodes to invalid UTF-8
+			Call(FromHex, String("c328")),
+			Missing{},
+		},
+		{
+			Call(ToBase64, String("sneller")),
+			String("c25lbGxlcg=="),
+		},
+		{
+			Call(FromBase64, String("c25lbGxlcg==")),
+			String("sneller"),
+		},
+		{
+			Call(FromBase64, String("c25lbGxlcg")),
+			Missing{},
+		},
+		{
+			// decodes to invalid UTF-8
+			Call(FromBase64, String("/w==")),
+			Missing{},
+		},
+		{
+			Call(URLExtractHost, String("https://user@www.example.com:443/index.html?q=1")),
+			String("www.example.com"),
+		},
+		{
+			Call(URLExtractHost, String("/index.html")),
+			Missing{},
+		},
+		{
+			Call(URLExtractPath, String("https://[2001:db8::1]/a/b?q=1#top")),
+			String("/a/b"),
+		},
+		{
+			Call(URLExtractQuery, String("https://example.com/?q=1&page=2#top")),
+			String("q=1&page=2"),
+		},
+		{
+			Call(URLExtractQueryParameter, String("https://example.com/?q=1&page=2"), String("page")),
+			String("2"),
+		},
+		{
+			Call(URLExtractQueryParameter, String("https://example.com/?q=1&page=2"), String("p")),
+			Missing{},
+		},
+		{
+			Call(URLDecode, String("a%20b+c%2F")),
+			String("a b c/"),
+		},
+		{
+			Call(URLDecode, String("a%2")),
+			Missing{},
+		},
+		{
+			Call(InetAton, String("10.1.2.3")),
+			Integer(0x0a010203),
+		},
+		{
+			Call(InetAton, String("10.01.2.3")),
+			Missing{},
+		},
+		{
+			Call(InetNtoa, Integer(0x0a010203)),
+			String("10.1.2.3"),
+		},
+		{
+			Call(InetNtoa, Integer(1<<32)),
+			Missing{},
+		},
+		{
+			Call(IPToCIDR, String("10.1.2.3"), Integer(20)),
+			String("10.1.0.0/20"),
+		},
+		{
+			Call(IPToCIDR, String("2001:db8:85a3::8a2e:370:7334"), Integer(100)),
+			String("2001:db8:85a3::8a2e:0:0/100"),
+		},
+		{
+			Call(IPToCIDR, String("::ffff:192.168.1.77"), Integer(120)),
+			String("::ffff:192.168.1.0/120"),
+		},
+		{
+			Call(IPToCIDR, String("10.1.2.3"), Integer(48)),
+			Missing{},
+		},
+		{
+			Call(IPToCIDR, String("fe80::1%eth0"), Integer(64)),
+			Missing{},
+		},
+		{
+			Call(IPToCIDR, path("ip"), Integer(8)),
+			IfThenElse(Is(Call(InetAton, path("ip")), IsNotMissing),
+				Call(Concat, Call(InetNtoa, BitAnd(Call(InetAton, path("ip")), Integer(0xff000000))), String("/8")),
+				Call(IP6ToCIDR, path("ip"), Integer(8))),
+		},
+		{
+			Call(IPToCIDR, path("ip"), Integer(64)),
+			Call(IP6ToCIDR, path("ip"), Integer(64)),
+		},
+		{
+			Call(IP6ToCIDR, String("10.1.2.3"), Integer(8)),
+			Missing{},
+		},
+		{
+			Call(IsSubnetOf, String("2001:db8::/32"), path("ip")),
+			Call(IsSubnetOf, String("2001:db8::"), String("2001:db8:ffff:ffff:ffff:ffff:ffff:ffff"), path("ip")),
+		},
+		{
+			Call(IsSubnetOf, String("10.0.0.2"), String("10.0.0.1"), path("ip")),
+			Bool(false),
+		},
 		{
 			// LOWER(s) == "fred"
 			Compare(Equals, Call(Lower, path("s")), String("fred")),
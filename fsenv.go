@@ -37,9 +37,18 @@ type CachedEnv interface {
 
 type savedIndex struct {
 	db, table string
+	etag      string
 	index     *blockfmt.Index
 }
 
+// IndexETag identifies the version of
+// the index of a table used by a query
+type IndexETag struct {
+	DB, Table string
+	// ETag is the ETag of the index file
+	ETag string
+}
+
 type savedList struct {
 	db   string
 	list []string
@@ -120,27 +129,41 @@ func (f *FSEnv) index(e expr.Node) (*blockfmt.Index, error) {
 			return f.recent[i].index, nil
 		}
 	}
-	index, err := db.OpenPartialIndex(f.Root, dbname, table, f.tenant.Key())
+	index, etag, err := db.OpenPartialIndexETag(f.Root, dbname, table, f.tenant.Key())
 	if err != nil {
 		return nil, err
 	}
 	f.recent = append(f.recent, savedIndex{
 		db:    dbname,
 		table: table,
+		etag:  etag,
 		index: index,
 	})
 	if f.modtime.IsZero() || f.modtime.Before(index.Created) {
 		f.modtime = index.Created
 	}
-	// FIXME: actually use the ETag of the index
-	//
-	// but, the modtime of the index plus its name
-	// ought to be unique per-input
 	io.WriteString(f.hash, path.Join(dbname, table))
-	io.WriteString(f.hash, index.Created.String())
+	io.WriteString(f.hash, etag)
 	return index, nil
 }
 
+// Indexes returns the tables whose indexes
+// have been opened by the environment
+// (i.e. the tables referenced by the queries
+// planned with it) along with the ETags
+// of the indexes.
+func (f *FSEnv) Indexes() []IndexETag {
+	out := make([]IndexETag, len(f.recent))
+	for i := range f.recent {
+		out[i] = IndexETag{
+			DB:    f.recent[i].db,
+			Table: f.recent[i].table,
+			ETag:  f.recent[i].etag,
+		}
+	}
+	return out
+}
+
 // MaxScanned returns the maximum number of
 // bytes that need to be scanned to satisfy this query.
 func (f *FSEnv) MaxScanned() int64 { return f.maxscan }
@@ -0,0 +1,100 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package xxhash provides a portable implementation
+// of the 64-bit xxHash function (XXH64).
+//
+// This is the reference implementation for the
+// XXHASH64 SQL function; the vectorized implementation
+// in the vm package must produce identical results.
+package xxhash
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	prime1 uint64 = 11400714785074694791
+	prime2 uint64 = 14029467366897019727
+	prime3 uint64 = 1609587929392839161
+	prime4 uint64 = 9650029242287828579
+	prime5 uint64 = 2870177450012600261
+)
+
+func round(acc, input uint64) uint64 {
+	acc += input * prime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * prime1
+}
+
+func mergeRound(acc, val uint64) uint64 {
+	acc ^= round(0, val)
+	return acc*prime1 + prime4
+}
+
+// Sum64 computes XXH64 of buf with a seed of zero.
+func Sum64(buf []byte) uint64 {
+	return Sum64Seed(buf, 0)
+}
+
+// Sum64Seed computes XXH64 of buf with the given seed.
+func Sum64Seed(buf []byte, seed uint64) uint64 {
+	n := len(buf)
+	var h uint64
+	if n >= 32 {
+		v1 := seed + prime1 + prime2
+		v2 := seed + prime2
+		v3 := seed
+		v4 := seed - prime1
+		for len(buf) >= 32 {
+			v1 = round(v1, binary.LittleEndian.Uint64(buf[0:]))
+			v2 = round(v2, binary.LittleEndian.Uint64(buf[8:]))
+			v3 = round(v3, binary.LittleEndian.Uint64(buf[16:]))
+			v4 = round(v4, binary.LittleEndian.Uint64(buf[24:]))
+			buf = buf[32:]
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) +
+			bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = mergeRound(h, v1)
+		h = mergeRound(h, v2)
+		h = mergeRound(h, v3)
+		h = mergeRound(h, v4)
+	} else {
+		h = seed + prime5
+	}
+
+	h += uint64(n)
+	for len(buf) >= 8 {
+		h ^= round(0, binary.LittleEndian.Uint64(buf))
+		h = bits.RotateLeft64(h, 27)*prime1 + prime4
+		buf = buf[8:]
+	}
+	if len(buf) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(buf)) * prime1
+		h = bits.RotateLeft64(h, 23)*prime2 + prime3
+		buf = buf[4:]
+	}
+	for i := range buf {
+		h ^= uint64(buf[i]) * prime5
+		h = bits.RotateLeft64(h, 11) * prime1
+	}
+
+	h ^= h >> 33
+	h *= prime2
+	h ^= h >> 29
+	h *= prime3
+	h ^= h >> 32
+	return h
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/tracing"
 )
 
 // Decode decodes an ion-encoded tree
@@ -49,6 +50,13 @@ func DecodeDatum(v ion.Datum) (*Tree, error) {
 			})
 		case "data":
 			t.Data = f.Datum.Clone()
+		case "traceparent":
+			str, err := f.String()
+			if err != nil {
+				return err
+			}
+			t.Trace, err = tracing.ParseTraceparent(str)
+			return err
 		case "root":
 			return t.Root.decode(f.Datum)
 		}
@@ -124,6 +132,8 @@ func empty(name string) (Op, bool) {
 		op = &SimpleAggregate{}
 	case "leaf":
 		op = &Leaf{}
+	case "profile":
+		op = &Profile{}
 	case "none":
 		op = NoOutput{}
 	case "dummy":
@@ -134,6 +144,8 @@ func empty(name string) (Op, bool) {
 		op = &CountStar{}
 	case "hashagg":
 		op = &HashAggregate{}
+	case "collectagg":
+		op = &CollectAggregate{}
 	case "order":
 		op = &OrderBy{}
 	case "distinct":
@@ -144,6 +156,8 @@ func empty(name string) (Op, bool) {
 		op = &Filter{}
 	case "unnest":
 		op = &Unnest{}
+	case "lateral":
+		op = &Lateral{}
 	case "unionmap":
 		op = &UnionMap{}
 	case "union_partition":
@@ -152,6 +166,8 @@ func empty(name string) (Op, bool) {
 		op = &OutputPart{}
 	case "outidx":
 		op = &OutputIndex{}
+	case "pivot":
+		op = &Pivot{}
 	case "unpivot":
 		op = &Unpivot{}
 	case "unpivotatdistinct":
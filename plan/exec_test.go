@@ -37,6 +37,8 @@ import (
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
 	"github.com/SnellerInc/sneller/vm"
+
+	"golang.org/x/exp/slices"
 )
 
 // testenv is an Env that
@@ -781,6 +783,16 @@ where out.Make = 'CHRY' and entry.BodyStyle = 'PA'
 				"AGGREGATE.*SUM_INT",
 			},
 		},
+		{
+			query: `select Make, array_agg(Fine order by Fine desc, Ticket limit 3) as fines, array_agg(Color order by Ticket limit 2) as colors from parking where Make in ('VOLK', 'MERZ') and BodyStyle = 'PA' group by Make order by Make`,
+			matchPlan: []string{
+				"COLLECT AGGREGATE.*ARRAY_AGG",
+			},
+			expectedRows: []string{
+				`{"Make": "MERZ", "fines": [163, 163, 93], "colors": ["BK", "BK"]}`,
+				`{"Make": "VOLK", "fines": [93, 93, 93], "colors": ["WH", "BL"]}`,
+			},
+		},
 		{
 			// with the integer schema information,
 			// this should yield a plan with SUM_INT()
@@ -1316,6 +1328,12 @@ where Make in (
 				`{"cols": "ViolationDescr"}`,
 			},
 		},
+		{
+			query: `PIVOT COUNT(*) AT Color FROM parking WHERE Color IN ('BK', 'BL', 'BN') GROUP BY Color`,
+			expectedRows: []string{
+				`{"BK": 221, "BL": 68, "BN": 5}`,
+			},
+		},
 		{
 			query: `EXPLAIN WITH main AS (SELECT * FROM nyc_taxi) SELECT COUNT(*) FROM main`,
 			rows:  1,
@@ -2204,3 +2222,218 @@ func dates(min, max string) [2]date.Time {
 	}
 	return [2]date.Time{dmin, dmax}
 }
+
+func TestSplitAggregateSpill(t *testing.T) {
+	env := &testenv{t: t}
+	defer func(mem int, dir string) {
+		vm.AggregateSpillMemory, vm.SpillDir = mem, dir
+	}(vm.AggregateSpillMemory, vm.SpillDir)
+	vm.SpillDir = t.TempDir()
+
+	run := func(text string, spill int) ([]string, error) {
+		vm.AggregateSpillMemory = spill
+		s, err := partiql.Parse([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		se := &splitEnv{
+			Env: env,
+			geom: &Geometry{
+				Peers: []Transport{&LocalTransport{}, &LocalTransport{}},
+			},
+		}
+		tree, err := NewSplit(s, se)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		ep := &ExecParams{
+			Plan:   tree,
+			Output: &out,
+			Runner: env,
+		}
+		if err := Exec(ep); err != nil {
+			return nil, err
+		}
+		if left, _ := os.ReadDir(vm.SpillDir); len(left) != 0 {
+			t.Errorf("%s: %d spill files were not removed", text, len(left))
+		}
+		var st ion.Symtab
+		var rows []string
+		buf := out.Bytes()
+		for len(buf) > 0 {
+			var d ion.Datum
+			d, buf, err = ion.ReadDatum(&st, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !d.IsEmpty() {
+				rows = append(rows, toJSON(&st, d))
+			}
+		}
+		return rows, nil
+	}
+	compare := func(text string, ordered bool) int {
+		want, err := run(text, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := run(text, 4096)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ordered {
+			slices.Sort(want)
+			slices.Sort(got)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %d rows, want %d", text, len(got), len(want))
+			for j := range got {
+				if j < len(want) && got[j] != want[j] {
+					t.Logf("row %d: got %s, want %s", j, got[j], want[j])
+					break
+				}
+			}
+		}
+		return len(want)
+	}
+
+	groups := compare(`SELECT tpep_pickup_datetime, COUNT(*), SUM(passenger_count)
+FROM nyc_taxi GROUP BY tpep_pickup_datetime`, false)
+	compare(`SELECT tpep_pickup_datetime, COUNT(*) AS c, MAX(total_amount)
+FROM nyc_taxi GROUP BY tpep_pickup_datetime ORDER BY c DESC, tpep_pickup_datetime LIMIT 20`, true)
+
+	// the aggregate state for APPROX_COUNT_DISTINCT
+	// does not fit in memory unless it is spilled
+	const approx = `SELECT tpep_pickup_datetime, APPROX_COUNT_DISTINCT(payment_type)
+FROM nyc_taxi GROUP BY tpep_pickup_datetime`
+	if _, err := run(approx, 0); err == nil {
+		t.Fatal("expected an error without spilling")
+	}
+	rows, err := run(approx, vm.MaxAggregateMemory/2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != groups {
+		t.Errorf("got %d rows, want %d", len(rows), groups)
+	}
+}
+
+func TestExecMemoryLimit(t *testing.T) {
+	env := &testenv{t: t}
+	run := func(text string, limit int64) (*vm.Budget, error) {
+		s, err := partiql.Parse([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := New(s, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ep := &ExecParams{
+			Plan:   tree,
+			Output: io.Discard,
+			Runner: env,
+			Memory: vm.NewBudget(limit),
+		}
+		return ep.Memory, Exec(ep)
+	}
+	const query = `SELECT DISTINCT tpep_pickup_datetime FROM nyc_taxi`
+	mem, err := run(query, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mem.Peak() == 0 {
+		t.Fatal("no memory was accounted for")
+	}
+	_, err = run(query, mem.Peak()/2)
+	if !errors.Is(err, vm.ErrMemoryLimit) {
+		t.Fatalf("expected vm.ErrMemoryLimit; got %v", err)
+	}
+}
+
+func TestExecTableSample(t *testing.T) {
+	env := &testenv{t: t}
+	const rows = 8560 // rows in nyc_taxi
+	run := func(t *testing.T, text string, split bool) ion.Datum {
+		s, err := partiql.Parse([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tree *Tree
+		if split {
+			tree, err = NewSplit(s, &splitEnv{
+				Env: env,
+				geom: &Geometry{
+					Peers: []Transport{&LocalTransport{}, &LocalTransport{}},
+				},
+			})
+		} else {
+			tree, err = New(s, env)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		err = Exec(&ExecParams{
+			Plan:   tree,
+			Output: &out,
+			Runner: env,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var st ion.Symtab
+		row, _, err := ion.ReadDatum(&st, out.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return row
+	}
+	num := func(t *testing.T, d ion.Datum) float64 {
+		if u, err := d.Uint(); err == nil {
+			return float64(u)
+		}
+		f, err := d.Float()
+		if err != nil {
+			t.Fatalf("%v is not a number", d)
+		}
+		return f
+	}
+
+	for _, split := range []bool{false, true} {
+		t.Run(fmt.Sprintf("split=%v", split), func(t *testing.T) {
+			row := run(t, `SELECT COUNT(*) AS n, SCALED_COUNT(*) AS s FROM nyc_taxi TABLESAMPLE BERNOULLI (50)`, split)
+			// the standard deviation of n is about 46
+			n := num(t, row.Field("n"))
+			if n < rows/2-500 || n > rows/2+500 {
+				t.Errorf("sampled %v of %d rows", n, rows)
+			}
+			s := row.Field("s")
+			est, lo, hi := num(t, s.Field("estimate")), num(t, s.Field("lower")), num(t, s.Field("upper"))
+			if est != 2*n {
+				t.Errorf("estimate %v of %v sampled rows", est, n)
+			}
+			if !(lo < est && est < hi) || hi-lo > 1000 {
+				t.Errorf("bad interval [%v, %v] around %v", lo, hi, est)
+			}
+
+			// the only block is always sampled
+			row = run(t, `SELECT COUNT(*) AS n FROM nyc_taxi TABLESAMPLE SYSTEM (100)`, split)
+			if n := num(t, row.Field("n")); n != rows {
+				t.Errorf("sampled %v of %d rows", n, rows)
+			}
+
+			// sampled blocks give no bounds
+			row = run(t, `SELECT COUNT(*) AS n, SCALED_COUNT(*) AS s FROM nyc_taxi TABLESAMPLE SYSTEM (50)`, split)
+			n = num(t, row.Field("n"))
+			s = row.Field("s")
+			if est := num(t, s.Field("estimate")); est != 2*n {
+				t.Errorf("estimate %v of %v sampled rows", est, n)
+			}
+			if !s.Field("lower").IsNull() || !s.Field("upper").IsNull() {
+				t.Errorf("unexpected bounds in %v", s)
+			}
+		})
+	}
+}
@@ -359,6 +359,49 @@ func (in *Input) HashSplit(n int) []*Input {
 	return ret
 }
 
+// Sample returns an equivalent of [in] which
+// contains a deterministic sample of roughly
+// [fraction] of the blocks referenced by [in].
+// Each block is selected based on the ETag of
+// its descriptor and its position, so the same
+// blocks are selected every time a particular
+// fraction is sampled. This method will not
+// mutate [in].
+func (in *Input) Sample(fraction float64) *Input {
+	const (
+		k0    = 0x2bd5f5a4a5c1f7e3
+		k1    = 0x6a09e667f3bcc908
+		clamp = ^uint64(0)
+	)
+	if fraction >= 1 {
+		return in
+	}
+	limit := uint64(fraction * float64(clamp))
+	ret := &Input{
+		Descs:  make([]Descriptor, 0, len(in.Descs)),
+		Fields: in.Fields,
+	}
+	var tmp []byte
+	for i := range in.Descs {
+		tmp = append(tmp[:0], in.Descs[i].ETag...)
+		cut := len(tmp)
+		var blocks []int
+		for _, off := range in.Descs[i].Blocks {
+			tmp = binary.LittleEndian.AppendUint32(tmp[:cut], uint32(off))
+			if siphash.Hash(k0, k1, tmp) < limit {
+				blocks = append(blocks, off)
+			}
+		}
+		if len(blocks) > 0 {
+			ret.Descs = append(ret.Descs, Descriptor{
+				Descriptor: in.Descs[i].Descriptor,
+				Blocks:     blocks,
+			})
+		}
+	}
+	return ret
+}
+
 // Append appends the contents of [other] to [in].
 func (in *Input) Append(other *Input) {
 	end := len(in.Descs)
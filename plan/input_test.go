@@ -15,12 +15,15 @@
 package plan
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
 	"github.com/SnellerInc/sneller/date"
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
+
+	"golang.org/x/exp/slices"
 )
 
 func TestInputFilter(t *testing.T) {
@@ -71,3 +74,60 @@ func TestInputFilter(t *testing.T) {
 		t.Fatal("not equal")
 	}
 }
+
+func TestInputSample(t *testing.T) {
+	const descs, blocks = 10, 100
+	orig := &Input{}
+	for i := 0; i < descs; i++ {
+		d := Descriptor{
+			Descriptor: blockfmt.Descriptor{
+				ObjectInfo: blockfmt.ObjectInfo{
+					Path: fmt.Sprintf("path/%d", i),
+					ETag: fmt.Sprintf("etag-%d", i),
+				},
+			},
+		}
+		for j := 0; j < blocks; j++ {
+			d.Blocks = append(d.Blocks, j)
+		}
+		orig.Descs = append(orig.Descs, d)
+	}
+	if got := orig.Sample(1); got != orig {
+		t.Fatal("sampling 100% copied the input")
+	}
+	got := orig.Sample(0.1)
+	n := got.Blocks()
+	if n < 50 || n > 150 {
+		t.Errorf("sampled %d of %d blocks; expected about 100", n, orig.Blocks())
+	}
+	for i := range got.Descs {
+		d := &got.Descs[i]
+		if d.Empty() {
+			t.Errorf("descriptor %s has no blocks", d.Path)
+		}
+		if !slices.IsSorted(d.Blocks) {
+			t.Errorf("blocks of %s are not sorted", d.Path)
+		}
+	}
+	// the sample is deterministic...
+	if again := orig.Sample(0.1); !reflect.DeepEqual(got, again) {
+		t.Error("different samples of the same input")
+	}
+	// ... and a smaller fraction selects a subset
+	small := orig.Sample(0.05)
+	for i := range small.Descs {
+		d := &small.Descs[i]
+		j := slices.IndexFunc(got.Descs, func(o Descriptor) bool { return o.Path == d.Path })
+		if j < 0 {
+			t.Fatalf("descriptor %s is not in the larger sample", d.Path)
+		}
+		for _, b := range d.Blocks {
+			if !slices.Contains(got.Descs[j].Blocks, b) {
+				t.Errorf("block %d of %s is not in the larger sample", b, d.Path)
+			}
+		}
+	}
+	if orig.Blocks() != descs*blocks {
+		t.Error("Sample modified the input")
+	}
+}
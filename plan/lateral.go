@@ -0,0 +1,99 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// Lateral evaluates a sub-query over an array
+// within each row and binds the result of the
+// sub-query to the row
+type Lateral struct {
+	Nonterminal // source op
+	// Query is the sub-query; its FROM
+	// clause is the array to evaluate
+	Query  *expr.Select
+	Kind   vm.LateralKind
+	Result string
+}
+
+func (l *Lateral) encode(dst *ion.Buffer, st *ion.Symtab, ep *ExecParams) error {
+	dst.BeginStruct(-1)
+	settype("lateral", dst, st)
+	dst.BeginField(st.Intern("query"))
+	ep.rewrite(l.Query).Encode(dst, st)
+	dst.BeginField(st.Intern("kind"))
+	dst.WriteInt(int64(l.Kind))
+	dst.BeginField(st.Intern("result"))
+	dst.WriteString(l.Result)
+	dst.EndStruct()
+	return nil
+}
+
+func (l *Lateral) SetField(f ion.Field) error {
+	switch f.Label {
+	case "query":
+		e, err := expr.Decode(f.Datum)
+		if err != nil {
+			return err
+		}
+		sel, ok := e.(*expr.Select)
+		if !ok {
+			return fmt.Errorf("lateral: unexpected query %s", expr.ToString(e))
+		}
+		l.Query = sel
+	case "kind":
+		i, err := f.Int()
+		if err != nil {
+			return err
+		}
+		l.Kind = vm.LateralKind(i)
+	case "result":
+		s, err := f.String()
+		if err != nil {
+			return err
+		}
+		l.Result = s
+	default:
+		return errUnexpectedField
+	}
+	return nil
+}
+
+func (l *Lateral) String() string {
+	var out strings.Builder
+	out.WriteString("LATERAL ")
+	out.WriteString(strings.ToUpper(l.Kind.String()))
+	out.WriteString(" ")
+	out.WriteString(expr.ToString(l.Query))
+	out.WriteString(" AS ")
+	out.WriteString(l.Result)
+	return out.String()
+}
+
+func (l *Lateral) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
+	op, err := vm.NewLateral(dst, ep.rewrite(l.Query).(*expr.Select), l.Kind, l.Result)
+	if err != nil {
+		return err
+	}
+	op.SetBudget(ep.Memory)
+	return l.From.exec(op, src, ep)
+}
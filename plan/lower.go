@@ -40,8 +40,20 @@ func lowerIterValue(in *pir.IterValue, from Op) (Op, error) {
 		Nonterminal: Nonterminal{
 			From: from,
 		},
-		Expr:   in.Value,
-		Result: in.Result,
+		Expr:     in.Value,
+		Result:   in.Result,
+		Zip:      in.Zip,
+		Position: in.Position,
+		Base:     in.Base,
+	}, nil
+}
+
+func lowerLateral(in *pir.Lateral, from Op) (Op, error) {
+	return &Lateral{
+		Nonterminal: Nonterminal{From: from},
+		Query:       in.Query,
+		Kind:        in.Kind,
+		Result:      in.Result,
 	}, nil
 }
 
@@ -110,6 +122,17 @@ func iscountstar(a vm.Aggregation) bool {
 	return isstar
 }
 
+// collects returns whether any of the aggregates
+// collects its input values
+func collects(a vm.Aggregation) bool {
+	for i := range a {
+		if a[i].Expr.Op.Collects() {
+			return true
+		}
+	}
+	return false
+}
+
 func splitWindows(lst vm.Aggregation) (agg vm.Aggregation, window vm.Aggregation) {
 	agg = lst[:0]
 	for i := range lst {
@@ -123,6 +146,19 @@ func splitWindows(lst vm.Aggregation) (agg vm.Aggregation, window vm.Aggregation
 }
 
 func lowerAggregate(in *pir.Aggregate, from Op) (Op, error) {
+	if collects(in.Agg) {
+		for i := range in.Agg {
+			if op := in.Agg[i].Expr.Op; !vm.CollectAccepts(op) {
+				return nil, reject(fmt.Sprintf("mixing %s with ARRAY_AGG, STRING_AGG, OBJECT_AGG, PERCENTILE_CONT, PERCENTILE_DISC, MODE or APPROX_TOP_K", op))
+			}
+		}
+		return &CollectAggregate{
+			Nonterminal: Nonterminal{From: from},
+			Agg:         in.Agg,
+			By:          in.GroupBy,
+			NonEmpty:    in.NonEmpty,
+		}, nil
+	}
 	if in.GroupBy == nil {
 		// simple aggregate; check for COUNT(*) first
 		if iscountstar(in.Agg) {
@@ -165,6 +201,19 @@ func makeOrdering(node expr.Order) vm.SortOrdering {
 	return ordering
 }
 
+// hashOrderable returns whether a HashAggregate
+// can order its groups by the result of agg
+func hashOrderable(agg *expr.Aggregate) bool {
+	switch agg.Op {
+	case expr.OpVarianceSamp, expr.OpStdDevSamp, expr.OpSkewness, expr.OpKurtosis,
+		expr.OpCovarPop, expr.OpCovarSamp, expr.OpCorr, expr.OpRegrSlope, expr.OpRegrIntercept, expr.OpRegrR2:
+		// the statistic is computed only
+		// when the result is written out
+		return false
+	}
+	return true
+}
+
 func lowerOrder(in *pir.Order, from Op) (Op, error) {
 	if ha, ok := from.(*HashAggregate); ok {
 		// hash aggregates can accept ORDER BY directly
@@ -175,6 +224,9 @@ func lowerOrder(in *pir.Order, from Op) (Op, error) {
 
 			for col := range ha.Agg {
 				if expr.IsIdentifier(ex, ha.Agg[col].Result) {
+					if !hashOrderable(ha.Agg[col].Expr) {
+						goto slowpath
+					}
 					ha.OrderBy = append(ha.OrderBy, HashOrder{
 						Column:   col,
 						Ordering: ordering,
@@ -342,6 +394,11 @@ func (i *input) finish(env Env) error {
 	if err != nil {
 		return err
 	}
+	// TABLESAMPLE SYSTEM samples whole blocks;
+	// BERNOULLI samples rows in Leaf.exec
+	if s := i.table.Sample; s != nil && s.Method == expr.SampleSystem {
+		input = input.Sample(s.Fraction())
+	}
 	i.contents = input
 	return nil
 }
@@ -447,7 +504,7 @@ outer:
 }
 
 func (i *input) merge(in *input) bool {
-	if !i.table.Expr.Equals(in.table.Expr) {
+	if !i.table.Expr.Equals(in.table.Expr) || !i.table.Sample.Equals(in.table.Sample) {
 		return false
 	}
 	if !mergeFilterHint(i, in) {
@@ -541,6 +598,8 @@ func (w *walker) walkBuild(in pir.Step, env Env) (Op, error) {
 	switch n := in.(type) {
 	case *pir.IterValue:
 		return lowerIterValue(n, input)
+	case *pir.Lateral:
+		return lowerLateral(n, input)
 	case *pir.Filter:
 		return lowerFilter(n, input)
 	case *pir.Distinct:
@@ -557,6 +616,8 @@ func (w *walker) walkBuild(in pir.Step, env Env) (Op, error) {
 		return lowerOutputIndex(n, env, input)
 	case *pir.OutputPart:
 		return lowerOutputPart(n, input)
+	case *pir.Pivot:
+		return lowerPivot(n, input)
 	case *pir.Unpivot:
 		return lowerUnpivot(n, input)
 	case *pir.UnpivotAtDistinct:
@@ -724,6 +785,14 @@ func newTree(q *expr.Query, env Env, split bool) (*Tree, error) {
 
 }
 
+func lowerPivot(in *pir.Pivot, from Op) (Op, error) {
+	p := &Pivot{
+		Nonterminal: Nonterminal{From: from},
+		Field:       in.Field,
+	}
+	return p, nil
+}
+
 func lowerUnpivot(in *pir.Unpivot, from Op) (Op, error) {
 	u := &Unpivot{
 		Nonterminal: Nonterminal{From: from},
@@ -26,6 +26,8 @@ import (
 	"sync"
 
 	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/tracing"
+	"github.com/SnellerInc/sneller/vm"
 )
 
 type frame uint32
@@ -67,8 +69,9 @@ func getframe(src []byte) frame {
 }
 
 type server struct {
-	run    Runner
-	initfs func(ion.Datum) (fs.FS, error)
+	run      Runner
+	initfs   func(ion.Datum) (fs.FS, error)
+	memlimit int64
 
 	pipe io.ReadWriteCloser
 	rd   *bufio.Reader
@@ -99,6 +102,10 @@ type Server struct {
 	// appropriate information necessary to access
 	// file system (e.g., credentials).
 	InitFS func(ion.Datum) (fs.FS, error)
+	// MemoryLimit, if positive, is the maximum
+	// number of bytes of memory that each query
+	// may use. See ExecParams.Memory.
+	MemoryLimit int64
 }
 
 // Serve serves queries from [rw] using [run] to
@@ -118,6 +125,7 @@ func (s *Server) Serve(rw io.ReadWriteCloser) error {
 	sv := serverPool.Get().(*server)
 	sv.run = s.Runner
 	sv.initfs = s.InitFS
+	sv.memlimit = s.MemoryLimit
 	sv.pipe = rw
 	sv.tmp = sv.tmp[:0]
 	sv.writeFail = false
@@ -267,7 +275,7 @@ func (s *server) writeframe(f frame) error {
 	return err
 }
 
-func (s *server) fin(stat *ExecStats) error {
+func (s *server) fin(stat *ExecStats, profile []OpProfile, spans []tracing.SpanData) error {
 	if s.writeFail {
 		// writes already failed;
 		// don't bother sending a fin
@@ -275,7 +283,7 @@ func (s *server) fin(stat *ExecStats) error {
 	}
 	var buf ion.Buffer
 	buf.Set(s.tmp[:framesize])
-	stat.Encode(&buf, &statsSymtab)
+	stat.encode(&buf, &statsSymtab, profile, spans)
 	out := buf.Bytes()
 	mkframe(framefin, buf.Size()-framesize).put(out)
 	_, err := s.pipe.Write(out)
@@ -303,12 +311,23 @@ func (s *server) runQuery(buf []byte, ctx context.Context, cancel func()) error
 		s.senderr(err.Error())
 		return s.ctxerr(ctx, err)
 	}
+	// if the caller is tracing the query, record
+	// the spans of this process and send them back
+	// along with the final statistics
+	var rec *tracing.Recorder
+	var span *tracing.Span
+	if t.Trace.Sampled() {
+		rec = new(tracing.Recorder)
+		ctx, span = tracing.Root(ctx, "remote execute", t.Trace, rec)
+		span.SetAttr("query_id", t.ID)
+	}
 	lp := LocalTransport{}
 	ep := ExecParams{
 		Plan:    t,
 		Output:  s,
 		Context: ctx,
 		Runner:  s.run,
+		Memory:  vm.NewBudget(s.memlimit),
 	}
 	if s.initfs != nil && !t.Data.IsEmpty() {
 		ep.FS, err = s.initfs(t.Data)
@@ -321,7 +340,13 @@ func (s *server) runQuery(buf []byte, ctx context.Context, cancel func()) error
 		s.senderr(err.Error())
 		return s.ctxerr(ctx, err)
 	}
-	return s.fin(&ep.Stats)
+	var spans []tracing.SpanData
+	if span != nil {
+		span.SetAttr("bytes_scanned", ep.Stats.BytesScanned)
+		span.End()
+		spans = rec.Take()
+	}
+	return s.fin(&ep.Stats, t.profiles(), spans)
 }
 
 // Client represents a connection to a "remote"
@@ -483,17 +508,19 @@ func (c *Client) queryerr(size int) error {
 	return errors.New(bld.String())
 }
 
-func (c *Client) decodestat(stat *ExecStats, size int) error {
+func (c *Client) decodestat(ep *ExecParams, size int) error {
 	var tmp ExecStats
 	buf, err := c.buffer(size)
 	if err != nil {
 		return err
 	}
-	err = tmp.UnmarshalBinary(buf)
+	var spans []tracing.SpanData
+	err = tmp.decode(buf, &statsSymtab, &ep.profile, &spans)
 	if err != nil {
 		return err
 	}
-	stat.atomicAdd(&tmp)
+	ep.Stats.atomicAdd(&tmp)
+	tracing.Import(ep.Context, spans)
 	return nil
 }
 
@@ -507,7 +534,6 @@ func closeOnCancel(pipe io.ReadWriteCloser, cancel, done <-chan struct{}) {
 
 func (c *Client) copyout(ep *ExecParams) error {
 	dst := ep.Output
-	stat := &ep.Stats
 	var done chan struct{}
 	if cancel := ep.Context.Done(); cancel != nil {
 		done = make(chan struct{})
@@ -528,7 +554,7 @@ func (c *Client) copyout(ep *ExecParams) error {
 		switch f.kind() {
 		case framefin:
 			// done!
-			return c.decodestat(stat, f.length())
+			return c.decodestat(ep, f.length())
 		case framedata:
 			err = c.output(dst, f.length())
 			if err != nil {
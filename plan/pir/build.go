@@ -99,13 +99,44 @@ func (b *Trace) walkFromJoin(f *expr.Join, e Env) error {
 	if err != nil {
 		return err
 	}
+	// a sub-query over an array within the rows
+	// on the left-hand side (usually written with
+	// LATERAL) is evaluated for each row
+	sel, ok := f.Right.Expr.(*expr.Select)
+	lateral := ok && b.arraySource(sel)
+	// an un-nesting with positions or of several
+	// arrays (see expr.Unnest) is a cross join
+	// and an INNER JOIN is filtered afterwards
+	if u, ok := f.Right.Expr.(*expr.Unnest); ok {
+		switch f.Kind {
+		case expr.CrossJoin:
+			return b.Unnest(u)
+		case expr.InnerJoin:
+			if err := b.Unnest(u); err != nil {
+				return err
+			}
+			return b.Where(f.On)
+		default:
+			return errorf(f, "join %q not yet supported", f.Kind)
+		}
+	}
 	switch f.Kind {
 	case expr.CrossJoin:
-		// FIXME: if the rhs expression is a SELECT,
-		// then this is almost certainly a correlated
+		if lateral {
+			return b.lateralJoin(&f.Right)
+		}
+		// FIXME: if the rhs expression is a SELECT
+		// over something other than an array, then
+		// this is almost certainly a correlated
 		// sub-query ...
 		return b.Iterate(&f.Right)
 	case expr.InnerJoin:
+		if lateral {
+			if err := b.lateralJoin(&f.Right); err != nil {
+				return err
+			}
+			return b.Where(f.On)
+		}
 		return b.innerJoin(&f.Right, f.On, e)
 	default:
 		return errorf(f, "join %q not yet supported", f.Kind)
@@ -814,15 +845,26 @@ func (b *Trace) hoistWindows(s *expr.Select, e Env) error {
 }
 
 func (b *Trace) walkSelect(s *expr.Select, e Env) error {
+	if s.Pivot != nil {
+		return b.walkPivot(s, e)
+	}
 	// perform normalizations
+	err := expandPivot(s)
+	if err != nil {
+		return err
+	}
 	pickOutputs(s)
 	selectall := isselectall(s)
 	s.Columns = flattenBind(s.Columns)
-	err := b.hoistWindows(s, e)
+	err = b.hoistWindows(s, e)
 	if err != nil {
 		return err
 	}
 	normalizeOrderBy(s)
+	err = scaleSampled(s)
+	if err != nil {
+		return err
+	}
 	err = aggdistinctpromote(s)
 	if err != nil {
 		return err
@@ -835,12 +877,24 @@ func (b *Trace) walkSelect(s *expr.Select, e Env) error {
 		return err
 	}
 
+	// sub-queries over arrays within the rows
+	// are evaluated by Lateral steps ahead of
+	// the steps that use their results
+	lr := &lateralRewriter{b: b}
 	if s.Where != nil {
+		s.Where = expr.Rewrite(lr, s.Where)
+		if lr.err != nil {
+			return lr.err
+		}
 		err = b.Where(s.Where)
 		if err != nil {
 			return err
 		}
 	}
+	err = b.laterals(lr, s)
+	if err != nil {
+		return err
+	}
 
 	// if we are doing aggregation anywhere, then split it:
 	if s.Having != nil || s.GroupBy != nil || anyHasAggregate(s.Columns) || anyOrderHasAggregate(s.OrderBy) {
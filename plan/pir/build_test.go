@@ -61,6 +61,22 @@ func TestBuildError(t *testing.T) {
 			input: "select t.x, y from table as t",
 			rx:    "undefined",
 		},
+		{
+			input: `select r.id, (select x from r.xs as x group by x) from foo as r`,
+			rx:    "GROUP BY, HAVING and DISTINCT are not supported",
+		},
+		{
+			input: `select r.id, (select count(*), x from r.xs as x) from foo as r`,
+			rx:    "cannot mix aggregates",
+		},
+		{
+			input: `select r.id, (select approx_count_distinct(x) from r.xs as x) from foo as r`,
+			rx:    "not supported in a sub-query over an array",
+		},
+		{
+			input: `select r.id, l.x from foo as r cross join lateral (select x from r.xs as x)`,
+			rx:    "needs an alias",
+		},
 		{
 			// test that the variable binding
 			// is tracked correctly here to refer
@@ -103,10 +119,6 @@ func TestBuildError(t *testing.T) {
 			input:  `select xthree+ythree from (select xtwo as xthree, ytwo as ythree from (select x as xtwo, y as ytwo from table))`,
 			rx:     `ill-typed`,
 		},
-		{
-			input: `select x, y, z from foo order by x`,
-			rx:    "requires a LIMIT",
-		},
 		{
 			input: `select * from tbl order by timestamp desc limit 100000000000`,
 			rx:    "LIMIT\\+OFFSET",
@@ -466,6 +478,174 @@ func TestBuild(t *testing.T) {
 				"PROJECT CASE WHEN $_1_0 = 0 THEN NULL ELSE \"avg\" / $_1_0 END AS \"avg\", y AS y",
 			},
 		},
+		{
+			input: `select session, array_agg(event order by ts desc limit 2) as ev, string_agg(event, ',') as s, object_agg(event, ts) as o from foo group by session`,
+			expect: []string{
+				"ITERATE foo FIELDS [event, session, ts]",
+				"AGGREGATE ARRAY_AGG(event ORDER BY ts DESC NULLS FIRST LIMIT 2) AS ev, STRING_AGG(event, ',') AS s, OBJECT_AGG(event, ts) AS o BY session AS session",
+			},
+			split: []string{
+				"UNION MAP foo (",
+				"	ITERATE PART foo FIELDS [event, session, ts]",
+				"	AGGREGATE ARRAY_AGG.PARTIAL(event ORDER BY ts DESC NULLS FIRST LIMIT 2) AS $_2_0, STRING_AGG(event, ',') AS $_2_1, OBJECT_AGG.PARTIAL(event, ts) AS $_2_2 BY session AS session)",
+				"AGGREGATE ARRAY_AGG.MERGE($_2_0 ORDER BY 0 DESC NULLS FIRST LIMIT 2) AS ev, STRING_AGG($_2_1, ',') AS s, OBJECT_AGG.MERGE($_2_2) AS o BY session AS session",
+			},
+		},
+		{
+			input: `select percentile_cont(0.5) within group (order by x) as p, percentile_disc(0.9) within group (order by x desc) as d, mode() within group (order by y) as m from foo`,
+			expect: []string{
+				"ITERATE foo FIELDS [x, y]",
+				"AGGREGATE PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY x ASC NULLS FIRST) AS p, PERCENTILE_DISC(0.9) WITHIN GROUP (ORDER BY x DESC NULLS FIRST) AS d, MODE() WITHIN GROUP (ORDER BY y ASC NULLS FIRST) AS m",
+			},
+			split: []string{
+				"UNION MAP foo (",
+				"	ITERATE PART foo FIELDS [x, y]",
+				"	AGGREGATE PERCENTILE_CONT.PARTIAL(0.5) WITHIN GROUP (ORDER BY x ASC NULLS FIRST) AS $_2_0, PERCENTILE_DISC.PARTIAL(0.9) WITHIN GROUP (ORDER BY x DESC NULLS FIRST) AS $_2_1, MODE.PARTIAL() WITHIN GROUP (ORDER BY y ASC NULLS FIRST) AS $_2_2)",
+				"AGGREGATE PERCENTILE_CONT.MERGE(0.5) WITHIN GROUP (ORDER BY $_2_0 ASC NULLS FIRST) AS p, PERCENTILE_DISC.MERGE(0.9) WITHIN GROUP (ORDER BY $_2_1 DESC NULLS FIRST) AS d, MODE.MERGE() WITHIN GROUP (ORDER BY $_2_2 ASC NULLS FIRST) AS m",
+			},
+		},
+		{
+			input: `select approx_top_k(x, 5) as top from foo where y > 0`,
+			expect: []string{
+				"ITERATE foo FIELDS [x, y] WHERE y > 0",
+				"AGGREGATE APPROX_TOP_K(x, 5) AS top",
+			},
+			split: []string{
+				"UNION MAP foo (",
+				"	ITERATE PART foo FIELDS [x, y] WHERE y > 0",
+				"	AGGREGATE APPROX_TOP_K.PARTIAL(x, 5) AS $_2_0)",
+				"AGGREGATE APPROX_TOP_K.MERGE($_2_0, 5) AS top",
+			},
+		},
+		{
+			input: `select approx_count_distinct_state(x) as s, hll_estimate(y, 10) as n from foo`,
+			expect: []string{
+				"ITERATE foo FIELDS [x, y]",
+				"AGGREGATE APPROX_COUNT_DISTINCT_STATE(x) AS s, HLL_ESTIMATE(y, 10) AS n",
+			},
+			split: []string{
+				"UNION MAP foo (",
+				"	ITERATE PART foo FIELDS [x, y]",
+				"	AGGREGATE APPROX_COUNT_DISTINCT_STATE(x) AS $_2_0, HLL_MERGE(y, 10) AS $_2_1)",
+				"AGGREGATE HLL_MERGE($_2_0) AS s, HLL_ESTIMATE($_2_1, 10) AS n",
+			},
+		},
+		{
+			input: `select scaled_count(*), scaled_sum(x) filter (where x > 0) as s from foo tablesample bernoulli (25) group by y`,
+			expect: []string{
+				"ITERATE foo TABLESAMPLE BERNOULLI (25) FIELDS [x, y]",
+				"AGGREGATE COUNT(*) AS $_0_0, SUM(x) FILTER (WHERE x > 0) AS $_0_1, SUM(x * x) FILTER (WHERE x > 0) AS $_0_2 BY y",
+				`PROJECT {'estimate': $_0_0 / 0.25, 'lower': $_0_0 / 0.25 - (SQRT($_0_0) * 6.789639165669999), 'upper': $_0_0 / 0.25 + (SQRT($_0_0) * 6.789639165669999)} AS "scaled_count", {'estimate': $_0_1 / 0.25, 'lower': $_0_1 / 0.25 - (SQRT($_0_2) * 6.789639165669999), 'upper': $_0_1 / 0.25 + (SQRT($_0_2) * 6.789639165669999)} AS s`,
+			},
+		},
+		{
+			// the whole table is counted
+			input: `select scaled_count(x) as c from foo`,
+			expect: []string{
+				"ITERATE foo FIELDS [x]",
+				"AGGREGATE COUNT(x) AS $_0_0",
+				"PROJECT {'estimate': $_0_0, 'lower': $_0_0, 'upper': $_0_0} AS c",
+			},
+		},
+		{
+			// a filtered subquery of a sample is still a sample;
+			// sampled blocks give no bounds
+			input: `select scaled_count(x) as c from (select x from foo tablesample system (50) where x > 0)`,
+			expect: []string{
+				"ITERATE foo TABLESAMPLE SYSTEM (50) FIELDS [x] WHERE x > 0",
+				"AGGREGATE COUNT(x) AS $_0_0",
+				"PROJECT {'estimate': $_0_0 / 0.5, 'lower': NULL, 'upper': NULL} AS c",
+			},
+			split: []string{
+				"UNION MAP foo TABLESAMPLE SYSTEM (50) (",
+				"	ITERATE PART foo TABLESAMPLE SYSTEM (50) FIELDS [x] WHERE x > 0",
+				"	AGGREGATE COUNT(x) AS $_2_0)",
+				"AGGREGATE SUM_COUNT($_2_0) AS $_0_0",
+				"PROJECT {'estimate': $_0_0 / 0.5, 'lower': NULL, 'upper': NULL} AS c",
+			},
+		},
+		{
+			input: `select approx_percentile_state(x) as s, tdigest_quantile(y, 0.25) as q from foo`,
+			expect: []string{
+				"ITERATE foo FIELDS [x, y]",
+				"AGGREGATE APPROX_PERCENTILE_STATE(x) AS s, TDIGEST_QUANTILE(y, 0.25) AS q",
+			},
+			split: []string{
+				"UNION MAP foo (",
+				"	ITERATE PART foo FIELDS [x, y]",
+				"	AGGREGATE APPROX_PERCENTILE_STATE(x) AS $_2_0, TDIGEST_MERGE(y) AS $_2_1)",
+				"AGGREGATE TDIGEST_MERGE($_2_0) AS s, TDIGEST_QUANTILE($_2_1, 0.25) AS q",
+			},
+		},
+		{
+			input: `select r.id, (select max(x) from r.xs as x where x > r.lo) as m from foo as r`,
+			expect: []string{
+				"ITERATE foo AS r FIELDS [id, lo, xs]",
+				`LATERAL SCALAR (SELECT MAX(x) AS "max" FROM xs AS x WHERE x > lo) AS $_4_0`,
+				"PROJECT id AS id, $_4_0 AS m",
+			},
+		},
+		{
+			input: `select r.id, l.y from foo as r cross join lateral (select x.y from r.xs as x order by x.y desc limit 2) as l`,
+			expect: []string{
+				"ITERATE foo AS r FIELDS [id, xs]",
+				"LATERAL LIST (SELECT x.y AS y FROM xs AS x ORDER BY x.y DESC NULLS FIRST LIMIT 2) AS $_4_0",
+				"ITERATE FIELD $_4_0 AS l",
+				"PROJECT id AS id, l.y AS y",
+			},
+		},
+		{
+			// only the conjunction that does not
+			// reference the position is pushed down
+			input: `select o.x, i, p from foo as o, o.field as i at p where o.x < 3 and p > 0`,
+			expect: []string{
+				"ITERATE foo AS o FIELDS [field, x] WHERE x < 3",
+				"ITERATE FIELD field AS i AT p",
+				"FILTER p > 0",
+				"PROJECT x AS x, i AS i, p AS p",
+			},
+		},
+		{
+			input: `select k, v, n from foo as o cross join unnest(o.keys, o.vals) with ordinality as (k, v, n)`,
+			expect: []string{
+				"ITERATE foo AS o FIELDS [keys, vals]",
+				"ITERATE FIELD keys AS k, vals AS v WITH ORDINALITY AS n",
+				"PROJECT k AS k, v AS v, n AS n",
+			},
+		},
+		{
+			input: `pivot v at k from foo where x > 0`,
+			expect: []string{
+				"ITERATE foo FIELDS [k, v, x] WHERE x > 0",
+				"AGGREGATE OBJECT_AGG(CASE WHEN CAST(k AS STRING) IS NOT MISSING THEN CAST(k AS STRING) WHEN k = CAST(k AS INTEGER) THEN CAST(CAST(k AS INTEGER) AS STRING) ELSE MISSING END, v) AS $__pivot",
+				"PIVOT $__pivot",
+			},
+		},
+		{
+			input: `pivot count(*) at status from foo group by status`,
+			expect: []string{
+				"ITERATE foo FIELDS [status]",
+				"AGGREGATE COUNT(*) AS $_0_1 BY status AS $_0_0",
+				"AGGREGATE OBJECT_AGG(CASE WHEN CAST($_0_0 AS STRING) IS NOT MISSING THEN CAST($_0_0 AS STRING) WHEN $_0_0 = CAST($_0_0 AS INTEGER) THEN CAST(CAST($_0_0 AS INTEGER) AS STRING) ELSE MISSING END, $_0_1) AS $__pivot",
+				"PIVOT $__pivot",
+			},
+		},
+		{
+			input: `select day, us, eu from foo pivot (sum(amount) for region in ('us' as us, 'eu' as eu)) where eu > 0`,
+			expect: []string{
+				"ITERATE foo FIELDS [amount, day, region]",
+				"AGGREGATE SUM(amount) FILTER (WHERE region = 'eu') AS $_0_0, SUM(amount) FILTER (WHERE region = 'us') AS $_0_2 BY day AS $_0_1",
+				"FILTER $_0_0 > 0",
+				"PROJECT $_0_1 AS day, $_0_2 AS us, $_0_0 AS eu",
+			},
+		},
+		{
+			input: `select * from foo pivot (count(*) for status in (200, 404 as not_found))`,
+			expect: []string{
+				"ITERATE foo FIELDS [status]",
+				"AGGREGATE COUNT(*) FILTER (WHERE status = 200) AS 200, COUNT(*) FILTER (WHERE status = 404) AS not_found",
+			},
+		},
 		{
 			input: "select o.x, i.y from foo as o, o.field as i where o.x <> i.y",
 			expect: []string{
@@ -706,6 +886,20 @@ where out.Make = 'CHRY' and entry.BodyStyle = 'PA'`,
 				"PROJECT x AS x, y AS y, z AS z",
 			},
 		},
+		{
+			input: `select x, y, z from t order by x`,
+			expect: []string{
+				"ITERATE t FIELDS [x, y, z]",
+				"ORDER BY x ASC NULLS FIRST",
+				"PROJECT x AS x, y AS y, z AS z",
+			},
+			split: []string{
+				"UNION MAP t (",
+				"	ITERATE PART t FIELDS [x, y, z])",
+				"ORDER BY x ASC NULLS FIRST",
+				"PROJECT x AS x, y AS y, z AS z",
+			},
+		},
 		{
 			input: `select count(x)+1 as x from table order by x`,
 			expect: []string{
@@ -16,6 +16,8 @@ package pir
 
 import (
 	"github.com/SnellerInc/sneller/expr"
+
+	"golang.org/x/exp/slices"
 )
 
 func filterelim(b *Trace) {
@@ -56,13 +58,18 @@ func conjoin(x, y expr.Node, at Step) expr.Node {
 // n.b. false negatives are okay here; they will
 // just end up inhibiting optimizations
 func doesNotReference(e expr.Node, bind string) bool {
+	return doesNotReferenceAny(e, []string{bind})
+}
+
+// determine if 'e' references none of the bindings
+func doesNotReferenceAny(e expr.Node, binds []string) bool {
 	ref := false
 	visit := expr.WalkFunc(func(e expr.Node) bool {
 		if ref {
 			return false
 		}
 		id, ok := e.(expr.Ident)
-		if ok && string(id) == bind {
+		if ok && slices.Contains(binds, string(id)) {
 			ref = true
 		}
 		return !ok
@@ -171,25 +178,34 @@ func push(f *Filter, dst Step, s *Trace) bool {
 
 	// this is an unusual case because we
 	// can only push down *part* of the filter:
-	if iv, ok := dst.(*IterValue); ok {
+	// the conjunctions that do not reference
+	// the result of the step
+	var results []string
+	switch dst := dst.(type) {
+	case *IterValue:
+		results = dst.results()
+	case *Lateral:
+		results = []string{dst.Result}
+	}
+	if len(results) > 0 {
 		conj := conjunctions(f.Where, nil)
-		par := iv.parent()
+		par := dst.parent()
 		newparent := false
 		var remaining expr.Node
 		for j := range conj {
-			if doesNotReference(conj[j], iv.Result) {
+			if doesNotReferenceAny(conj[j], results) {
 				par = forcepush(conj[j], par, s)
 				newparent = true
 			} else {
 				if remaining == nil {
 					remaining = conj[j]
 				} else {
-					remaining = conjoin(remaining, conj[j], iv)
+					remaining = conjoin(remaining, conj[j], dst)
 				}
 			}
 		}
 		if newparent {
-			iv.setparent(par)
+			dst.setparent(par)
 		}
 		if remaining == nil {
 			return true
@@ -0,0 +1,325 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pir
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// Lateral is a sub-query evaluated over
+// an array within each row; the result
+// of the sub-query is bound to Result
+type Lateral struct {
+	parented
+	// Query is the sub-query; its FROM clause
+	// is the array bound to each element.
+	// References to the enclosing rows have
+	// been resolved like in any other step.
+	Query  *expr.Select
+	Kind   vm.LateralKind
+	Result string
+}
+
+func (l *Lateral) get(x string) (Step, expr.Node) {
+	if x == l.Result {
+		return l, nil
+	}
+	return l.par.get(x)
+}
+
+// exprs calls fn for each expression
+// of the sub-query along with whether
+// the expression is a condition
+func (l *Lateral) exprs(fn func(e *expr.Node, logic bool)) {
+	q := l.Query
+	fn(&q.From.(*expr.Table).Expr, false)
+	for i := range q.Columns {
+		fn(&q.Columns[i].Expr, false)
+	}
+	if q.Where != nil {
+		fn(&q.Where, true)
+	}
+	for i := range q.OrderBy {
+		fn(&q.OrderBy[i].Column, false)
+	}
+}
+
+func (l *Lateral) walk(v expr.Visitor) {
+	l.exprs(func(e *expr.Node, _ bool) {
+		expr.Walk(v, *e)
+	})
+}
+
+func (l *Lateral) rewrite(rw func(expr.Node, bool) expr.Node) {
+	l.exprs(func(e *expr.Node, logic bool) {
+		*e = rw(*e, logic)
+	})
+}
+
+func (l *Lateral) equals(x Step) bool {
+	l2, ok := x.(*Lateral)
+	return ok && (l == l2 ||
+		expr.Equal(l.Query, l2.Query) && l.Kind == l2.Kind && l.Result == l2.Result)
+}
+
+func (l *Lateral) describe(dst io.Writer) {
+	fmt.Fprintf(dst, "LATERAL %s %s AS %s\n",
+		strings.ToUpper(l.Kind.String()), expr.ToString(l.Query), l.Result)
+}
+
+// arraySource returns whether the FROM clause
+// of s is an array within the rows of the
+// current trace, i.e. a path rooted at the
+// alias of the table or at another binding
+// like the element of an UNNEST
+func (b *Trace) arraySource(s *expr.Select) bool {
+	t, ok := s.From.(*expr.Table)
+	if !ok || b.top == nil {
+		return false
+	}
+	path, ok := expr.FlatPath(t.Expr)
+	if !ok {
+		return false
+	}
+	step, node := b.top.get(path[0])
+	switch step := step.(type) {
+	case nil:
+		return false
+	case *IterTable:
+		return node != nil && len(path) > 1 && step.Bind == path[0]
+	case *Lateral:
+		return true
+	default:
+		return node != nil
+	}
+}
+
+// lateralKind returns the kind of
+// value produced by a sub-query
+func lateralKind(s *expr.Select) vm.LateralKind {
+	if anyHasAggregate(s.Columns) || (s.Limit != nil && *s.Limit == 1) {
+		if len(s.Columns) == 1 {
+			return vm.LateralScalar
+		}
+		return vm.LateralStruct
+	}
+	return vm.LateralList
+}
+
+// checkLateral checks that a sub-query
+// over an array can be evaluated by vm.Lateral
+func checkLateral(s *expr.Select) error {
+	t := s.From.(*expr.Table)
+	if t.Sample != nil {
+		return errorf(s, "TABLESAMPLE of an array is not supported")
+	}
+	if s.GroupBy != nil || s.Having != nil || s.Distinct || len(s.DistinctExpr) > 0 {
+		return errorf(s, "GROUP BY, HAVING and DISTINCT are not supported in a sub-query over an array")
+	}
+	var err error
+	visit := expr.WalkFunc(func(e expr.Node) bool {
+		if err != nil {
+			return false
+		}
+		if sub, ok := e.(*expr.Select); ok && sub != s {
+			err = errorf(sub, "sub-queries are not supported within a sub-query over an array")
+			return false
+		}
+		return true
+	})
+	for i := range s.Columns {
+		if _, ok := s.Columns[i].Expr.(expr.Star); ok {
+			return errorf(s, "cannot use * in a sub-query over an array")
+		}
+		expr.Walk(visit, s.Columns[i].Expr)
+	}
+	if s.Where != nil {
+		expr.Walk(visit, s.Where)
+	}
+	for i := range s.OrderBy {
+		expr.Walk(visit, s.OrderBy[i].Column)
+	}
+	if err != nil {
+		return err
+	}
+	if !anyHasAggregate(s.Columns) {
+		if anyOrderHasAggregate(s.OrderBy) {
+			return errorf(s, "cannot ORDER BY an aggregate in a sub-query over an array")
+		}
+		return nil
+	}
+	for i := range s.Columns {
+		agg, ok := s.Columns[i].Expr.(*expr.Aggregate)
+		if !ok {
+			return errorf(s.Columns[i].Expr, "cannot mix aggregates and other expressions in a sub-query over an array")
+		}
+		if agg.Over != nil {
+			return errorf(agg, "window functions are not supported in a sub-query over an array")
+		}
+		switch agg.Op {
+		case expr.OpCount, expr.OpSum, expr.OpSumInt, expr.OpAvg,
+			expr.OpMin, expr.OpMax, expr.OpBoolAnd, expr.OpBoolOr:
+		default:
+			return errorf(agg, "aggregate %s is not supported in a sub-query over an array", agg.Op)
+		}
+	}
+	return nil
+}
+
+// lateral pushes a Lateral step that evaluates
+// the sub-query s over an array (see arraySource)
+// and returns the binding of its result
+func (b *Trace) lateral(s *expr.Select, kind vm.LateralKind) (string, error) {
+	if err := checkLateral(s); err != nil {
+		return "", err
+	}
+	s = expr.Copy(s).(*expr.Select)
+	pickOutputs(s)
+	normalizeOrderBy(s)
+	t := s.From.(*expr.Table)
+	elem := t.Result()
+	if elem == "" {
+		return "", errorf(t, "the array %s needs a name", expr.ToString(t.Expr))
+	}
+	// the array is resolved in the
+	// scope of the enclosing rows...
+	b.cur = b.top
+	arr, err := b.pathwalk(t.Expr)
+	if err != nil {
+		return "", err
+	}
+	t.Expr = arr
+	// ... and the rest of the query also
+	// sees the binding of the elements
+	iv := &IterValue{Value: arr, Result: elem}
+	iv.setparent(b.top)
+	b.cur = iv
+	n := 0
+	for st := b.top; st != nil; st = st.parent() {
+		if _, ok := st.(*Lateral); ok {
+			n++
+		}
+	}
+	l := &Lateral{Query: s, Kind: kind, Result: gensym(4, n)}
+	var errs []error
+	l.exprs(func(e *expr.Node, _ bool) {
+		if e == &t.Expr {
+			return
+		}
+		x, err := b.pathwalk(*e)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		*e = x
+	})
+	if len(errs) > 0 {
+		return "", errs[0]
+	}
+	b.cur = l
+	return l.Result, b.push()
+}
+
+// lateralRewriter replaces sub-queries over
+// arrays with the results of Lateral steps
+type lateralRewriter struct {
+	b    *Trace
+	seen []*expr.Select
+	res  []string
+	err  error
+}
+
+func (r *lateralRewriter) Walk(e expr.Node) expr.Rewriter {
+	if r.err != nil {
+		return nil
+	}
+	switch e := e.(type) {
+	case *expr.Select:
+		return nil
+	case *expr.Builtin:
+		if e.Func == expr.InSubquery {
+			return nil
+		}
+	}
+	return r
+}
+
+func (r *lateralRewriter) Rewrite(e expr.Node) expr.Node {
+	s, ok := e.(*expr.Select)
+	if !ok || r.err != nil || !r.b.arraySource(s) {
+		return e
+	}
+	// the same sub-query may appear more than once
+	// (e.g. in both SELECT and ORDER BY)
+	for i := range r.seen {
+		if expr.Equal(r.seen[i], s) {
+			return expr.Ident(r.res[i])
+		}
+	}
+	res, err := r.b.lateral(s, lateralKind(s))
+	if err != nil {
+		r.err = err
+		return e
+	}
+	r.seen = append(r.seen, s)
+	r.res = append(r.res, res)
+	return expr.Ident(res)
+}
+
+// laterals rewrites the sub-queries over arrays
+// in the clauses of s that follow WHERE
+func (b *Trace) laterals(lr *lateralRewriter, s *expr.Select) error {
+	rw := func(e expr.Node) expr.Node {
+		if e == nil {
+			return nil
+		}
+		return expr.Rewrite(lr, e)
+	}
+	for i := range s.Columns {
+		s.Columns[i].Expr = rw(s.Columns[i].Expr)
+	}
+	for i := range s.GroupBy {
+		s.GroupBy[i].Expr = rw(s.GroupBy[i].Expr)
+	}
+	for i := range s.DistinctExpr {
+		s.DistinctExpr[i] = rw(s.DistinctExpr[i])
+	}
+	for i := range s.OrderBy {
+		s.OrderBy[i].Column = rw(s.OrderBy[i].Column)
+	}
+	s.Having = rw(s.Having)
+	return lr.err
+}
+
+// lateralJoin handles a join with a
+// sub-query over an array; the rows
+// of the sub-query are bound to the
+// alias of the sub-query
+func (b *Trace) lateralJoin(right *expr.Binding) error {
+	if !right.Explicit() {
+		return errorf(right.Expr, "a sub-query in a join needs an alias")
+	}
+	res, err := b.lateral(right.Expr.(*expr.Select), vm.LateralList)
+	if err != nil {
+		return err
+	}
+	bind := expr.Bind(expr.Ident(res), right.Result())
+	return b.Iterate(&bind)
+}
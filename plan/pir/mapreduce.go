@@ -254,7 +254,11 @@ func reduceAggregate(a *Aggregate, mapping, reduce *Trace) error {
 	needsFinalProjection := false
 	for i := range a.Agg {
 		switch a.Agg[i].Expr.Op {
-		case expr.OpApproxCountDistinct, expr.OpSum, expr.OpApproxPercentile, expr.OpApproxMedian:
+		case expr.OpApproxCountDistinct, expr.OpSum, expr.OpApproxPercentile, expr.OpApproxMedian,
+			expr.OpArrayAgg, expr.OpObjectAgg, expr.OpPercentileCont, expr.OpPercentileDisc, expr.OpMode,
+			expr.OpApproxTopK,
+			expr.OpVarianceSamp, expr.OpStdDevSamp, expr.OpSkewness, expr.OpKurtosis,
+			expr.OpCovarPop, expr.OpCovarSamp, expr.OpCorr, expr.OpRegrSlope, expr.OpRegrIntercept, expr.OpRegrR2:
 			// Opcode becomes its partial counterpart
 			a.Agg[i].Expr.Role = expr.AggregateRolePartial
 
@@ -351,6 +355,12 @@ func reduceAggregate(a *Aggregate, mapping, reduce *Trace) error {
 			newagg = &expr.Aggregate{Op: age.Op, Inner: innerref}
 		case expr.OpSum:
 			newagg = &expr.Aggregate{Op: age.Op, Role: expr.AggregateRoleMerge, Inner: innerref}
+		case expr.OpVarianceSamp, expr.OpStdDevSamp, expr.OpSkewness, expr.OpKurtosis,
+			expr.OpCovarPop, expr.OpCovarSamp, expr.OpCorr, expr.OpRegrSlope, expr.OpRegrIntercept, expr.OpRegrR2:
+			// the partial results are the moments,
+			// which are merged before the statistic
+			// is computed
+			newagg = &expr.Aggregate{Op: age.Op, Role: expr.AggregateRoleMerge, Inner: innerref}
 		case expr.OpApproxPercentile:
 			newagg = &expr.Aggregate{
 				Op:    expr.OpApproxPercentile,
@@ -369,10 +379,81 @@ func reduceAggregate(a *Aggregate, mapping, reduce *Trace) error {
 				Role:      expr.AggregateRoleMerge,
 				Precision: age.Precision,
 				Inner:     innerref}
+		case expr.OpApproxCountDistinctState, expr.OpHLLMerge:
+			newagg = &expr.Aggregate{
+				Op:        expr.OpHLLMerge,
+				Precision: age.Precision,
+				Inner:     innerref}
+		case expr.OpHLLEstimate:
+			// merge the states in the mapping step
+			// and estimate from the merged states
+			age.Op = expr.OpHLLMerge
+			newagg = &expr.Aggregate{
+				Op:        expr.OpHLLEstimate,
+				Precision: age.Precision,
+				Inner:     innerref}
+		case expr.OpApproxPercentileState, expr.OpTDigestMerge:
+			newagg = &expr.Aggregate{
+				Op:    expr.OpTDigestMerge,
+				Inner: innerref}
+		case expr.OpTDigestQuantile:
+			age.Op = expr.OpTDigestMerge
+			newagg = &expr.Aggregate{
+				Op:    expr.OpTDigestQuantile,
+				Misc:  age.Misc,
+				Inner: innerref}
 		case expr.OpSystemDatashape:
 			newagg = &expr.Aggregate{
 				Op:    expr.OpSystemDatashapeMerge,
 				Inner: innerref}
+		case expr.OpArrayAgg:
+			// the partial results carry the ordering keys,
+			// so the ordering columns are just placeholders
+			var order []expr.Order
+			for i := range age.OrderBy {
+				order = append(order, expr.Order{
+					Column:    expr.Integer(i),
+					Desc:      age.OrderBy[i].Desc,
+					NullsLast: age.OrderBy[i].NullsLast,
+				})
+			}
+			newagg = &expr.Aggregate{
+				Op:      expr.OpArrayAgg,
+				Role:    expr.AggregateRoleMerge,
+				Inner:   innerref,
+				OrderBy: order,
+				Limit:   age.Limit}
+		case expr.OpStringAgg:
+			// partial strings are simply joined
+			newagg = &expr.Aggregate{
+				Op:    expr.OpStringAgg,
+				Inner: innerref,
+				Arg:   age.Arg}
+		case expr.OpObjectAgg:
+			newagg = &expr.Aggregate{
+				Op:    expr.OpObjectAgg,
+				Role:  expr.AggregateRoleMerge,
+				Inner: innerref}
+		case expr.OpPercentileCont, expr.OpPercentileDisc, expr.OpMode:
+			// the partial results are lists of values
+			// that are ordered again when merged
+			newagg = &expr.Aggregate{
+				Op:   age.Op,
+				Role: expr.AggregateRoleMerge,
+				Arg:  age.Arg,
+				OrderBy: []expr.Order{{
+					Column:    innerref,
+					Desc:      age.OrderBy[0].Desc,
+					NullsLast: age.OrderBy[0].NullsLast,
+				}}}
+		case expr.OpApproxTopK:
+			// the partial results are sketches
+			// that are merged into a single sketch
+			newagg = &expr.Aggregate{
+				Op:    expr.OpApproxTopK,
+				Role:  expr.AggregateRoleMerge,
+				Inner: innerref,
+				Arg:   age.Arg}
 		case expr.OpRowNumber, expr.OpRank, expr.OpDenseRank:
 			newagg = current[i].Expr
 			current[i].Expr = nil // delete this op
@@ -323,7 +323,7 @@ func steps(b *Trace) []Step {
 
 func trivialSplit(s Step) bool {
 	switch s.(type) {
-	case *Bind, *Filter, *IterValue: // not affected by grouping
+	case *Bind, *Filter, *IterValue, *Lateral: // not affected by grouping
 		return true
 	default:
 		return false
@@ -212,24 +212,64 @@ type IterValue struct {
 	parented
 	Value  expr.Node // the expression to be iterated
 	Result string    // the binding produced by iteration
+
+	// Zip are other arrays iterated in lockstep
+	// with Value; shorter arrays are padded with MISSING
+	Zip []expr.Binding
+	// Position, if non-empty, is the binding
+	// of the position of each element, which
+	// starts at Base
+	Position string
+	Base     int
 }
 
 func (i *IterValue) walk(v expr.Visitor) {
 	expr.Walk(v, i.Value)
+	for j := range i.Zip {
+		expr.Walk(v, i.Zip[j].Expr)
+	}
 }
 
 func (i *IterValue) equals(x Step) bool {
 	i2, ok := x.(*IterValue)
 	return ok && (i == i2 ||
-		(expr.Equal(i.Value, i2.Value) && i.Result == i2.Result))
+		(expr.Equal(i.Value, i2.Value) && i.Result == i2.Result &&
+			slices.EqualFunc(i.Zip, i2.Zip, expr.Binding.Equals) &&
+			i.Position == i2.Position && i.Base == i2.Base))
 }
 
 func (i *IterValue) describe(dst io.Writer) {
-	fmt.Fprintf(dst, "ITERATE FIELD %s AS %s\n", expr.ToString(i.Value), i.Result)
+	fmt.Fprintf(dst, "ITERATE FIELD %s AS %s", expr.ToString(i.Value), i.Result)
+	for j := range i.Zip {
+		fmt.Fprintf(dst, ", %s AS %s", expr.ToString(i.Zip[j].Expr), i.Zip[j].Result())
+	}
+	if i.Position != "" {
+		if i.Base == 0 {
+			fmt.Fprintf(dst, " AT %s", i.Position)
+		} else {
+			fmt.Fprintf(dst, " WITH ORDINALITY AS %s", i.Position)
+		}
+	}
+	io.WriteString(dst, "\n")
 }
 
 func (i *IterValue) rewrite(rw func(expr.Node, bool) expr.Node) {
 	i.Value = rw(i.Value, false)
+	for j := range i.Zip {
+		i.Zip[j].Expr = rw(i.Zip[j].Expr, false)
+	}
+}
+
+// results returns the bindings produced by iteration
+func (i *IterValue) results() []string {
+	out := []string{i.Result}
+	for j := range i.Zip {
+		out = append(out, i.Zip[j].Result())
+	}
+	if i.Position != "" {
+		out = append(out, i.Position)
+	}
+	return out
 }
 
 type EquiJoin struct {
@@ -385,6 +425,14 @@ func (i *IterValue) get(x string) (Step, expr.Node) {
 	if x == i.Result {
 		return i, i.Value
 	}
+	for j := range i.Zip {
+		if x == i.Zip[j].Result() {
+			return i, i.Zip[j].Expr
+		}
+	}
+	if x == i.Position {
+		return i, nil
+	}
 	return i.par.get(x)
 }
 
@@ -981,6 +1029,41 @@ func (b *Trace) Iterate(bind *expr.Binding) error {
 	return b.push()
 }
 
+// Unnest pushes an iteration over one or
+// more arrays in lockstep to the stack
+func (b *Trace) Unnest(u *expr.Unnest) error {
+	iv := &IterValue{
+		Value:    u.Arrays[0].Expr,
+		Result:   u.Arrays[0].Result(),
+		Zip:      slices.Clone(u.Arrays[1:]),
+		Position: u.At,
+	}
+	if u.Ordinality {
+		iv.Base = 1
+	}
+	// like Iterate, the arrays are resolved
+	// in the scope of the parent step
+	b.cur = b.top
+	val, err := b.pathwalk(iv.Value)
+	if err != nil {
+		return err
+	}
+	iv.Value = val
+	for i := range iv.Zip {
+		val, err := b.pathwalk(iv.Zip[i].Expr)
+		if err != nil {
+			return err
+		}
+		iv.Zip[i].Expr = val
+	}
+	b.cur = iv
+	b.final = append(b.final, u.Arrays...)
+	if u.At != "" {
+		b.final = append(b.final, expr.Identity(u.At))
+	}
+	return b.push()
+}
+
 // Distinct takes a sets of bindings
 // and produces only distinct sets of output tuples
 // of the given variable bindings
@@ -0,0 +1,240 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pir
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/SnellerInc/sneller/expr"
+
+	"golang.org/x/exp/slices"
+)
+
+// Pivot replaces each row with the
+// structure in the field Field; it
+// produces the result of PIVOT v AT k
+// from the OBJECT_AGG of k and v
+type Pivot struct {
+	parented
+	Field expr.Node
+}
+
+// the fields of the output rows
+// are not known ahead of time
+func (p *Pivot) get(x string) (Step, expr.Node) {
+	return p, nil
+}
+
+func (p *Pivot) walk(v expr.Visitor) {
+	expr.Walk(v, p.Field)
+}
+
+func (p *Pivot) rewrite(rw func(expr.Node, bool) expr.Node) {
+	p.Field = rw(p.Field, false)
+}
+
+func (p *Pivot) equals(x Step) bool {
+	xp, ok := x.(*Pivot)
+	return ok && p.Field.Equals(xp.Field)
+}
+
+func (p *Pivot) describe(dst io.Writer) {
+	fmt.Fprintf(dst, "PIVOT %s\n", expr.ToString(p.Field))
+}
+
+const (
+	pivotKey   = "$__key"
+	pivotValue = "$__value"
+	pivotField = "$__pivot"
+)
+
+// walkPivot builds PIVOT v AT k FROM ...
+// as the OBJECT_AGG of k and v over the
+// rows of the query, or over its groups
+// with GROUP BY, followed by a Pivot step
+func (b *Trace) walkPivot(s *expr.Select, e Env) error {
+	key := pivotName(s.Pivot.At)
+	val := s.Pivot.Value
+	agg := &expr.Select{
+		From:  s.From,
+		Where: s.Where,
+	}
+	if s.GroupBy != nil || s.Having != nil || hasAggregate(key) || hasAggregate(val) {
+		// the pairs are produced by the grouping
+		// and then aggregated into a structure
+		inner := &expr.Select{
+			Columns: []expr.Binding{expr.Bind(key, pivotKey), expr.Bind(val, pivotValue)},
+			From:    s.From,
+			Where:   s.Where,
+			GroupBy: s.GroupBy,
+			Having:  s.Having,
+		}
+		agg = &expr.Select{From: &expr.Table{Binding: expr.Bind(inner, "")}}
+		key, val = expr.Ident(pivotKey), expr.Ident(pivotValue)
+	}
+	agg.Columns = []expr.Binding{
+		expr.Bind(&expr.Aggregate{Op: expr.OpObjectAgg, Inner: key, Arg: val}, pivotField),
+	}
+	err := b.walkSelect(agg, e)
+	if err != nil {
+		return err
+	}
+	p := &Pivot{Field: expr.Ident(pivotField)}
+	p.setparent(b.top)
+	b.top = p
+	b.final = nil
+	return nil
+}
+
+// pivotName converts k into the name of a field;
+// OBJECT_AGG accepts only strings as names, so
+// integers are formatted (e.g. status codes
+// become fields like "200")
+func pivotName(k expr.Node) expr.Node {
+	str := &expr.Cast{From: k, To: expr.StringType}
+	num := &expr.Cast{From: expr.Copy(k), To: expr.IntegerType}
+	return &expr.Case{
+		Limbs: []expr.CaseLimb{{
+			When: expr.Is(expr.Copy(str), expr.IsNotMissing),
+			Then: str,
+		}, {
+			When: expr.Compare(expr.Equals, expr.Copy(k), num),
+			Then: &expr.Cast{From: expr.Copy(num), To: expr.StringType},
+		}},
+		Else: expr.Missing{},
+	}
+}
+
+// expandPivot rewrites a query over
+//
+//	FROM ... PIVOT (agg FOR col IN (values))
+//
+// into one aggregate per value that is
+// filtered by col = value, grouped by the
+// columns of the query that do not use
+// the columns produced by the pivot
+// (unless the query has a GROUP BY)
+func expandPivot(s *expr.Select) error {
+	p, ok := s.From.(*expr.PivotTable)
+	if !ok {
+		return nil
+	}
+	if _, ok := p.From.(*expr.PivotTable); ok {
+		return errorf(p, "PIVOT cannot be applied more than once")
+	}
+	pr := &pivotRewriter{names: p.Names()}
+	for i := range p.In {
+		agg := expr.Copy(p.Agg).(*expr.Aggregate)
+		cond := expr.Compare(expr.Equals, expr.Copy(p.For), p.In[i].Expr)
+		if agg.Filter != nil {
+			cond = expr.And(agg.Filter, cond)
+		}
+		agg.Filter = cond
+		pr.aggs = append(pr.aggs, agg)
+	}
+	s.From = p.From
+	if isselectall(s) {
+		cols := make([]expr.Binding, 0, len(s.GroupBy)+len(pr.names))
+		for i := range s.GroupBy {
+			cols = append(cols, expr.Bind(expr.Copy(s.GroupBy[i].Expr), s.GroupBy[i].Result()))
+		}
+		for i := range pr.names {
+			cols = append(cols, expr.Bind(pr.aggs[i], pr.names[i]))
+		}
+		s.Columns = cols
+	} else {
+		var groups []expr.Binding
+		for i := range s.Columns {
+			c := &s.Columns[i]
+			if !pr.uses(c.Expr) {
+				if s.GroupBy == nil && !hasAggregate(c.Expr) {
+					groups = append(groups, expr.Bind(expr.Copy(c.Expr), ""))
+				}
+				continue
+			}
+			// a column that is just a pivot
+			// column retains its name
+			if id, ok := c.Expr.(expr.Ident); ok && !c.Explicit() {
+				c.As(string(id))
+			}
+			c.Expr = expr.Rewrite(pr, c.Expr)
+		}
+		if s.GroupBy == nil {
+			s.GroupBy = groups
+		}
+	}
+	// conditions on the pivot columns
+	// apply to the groups
+	if s.Where != nil && pr.uses(s.Where) {
+		if s.Having != nil {
+			s.Having = expr.And(s.Where, s.Having)
+		} else {
+			s.Having = s.Where
+		}
+		s.Where = nil
+	}
+	if s.Having != nil {
+		s.Having = expr.Rewrite(pr, s.Having)
+	}
+	for i := range s.OrderBy {
+		s.OrderBy[i].Column = expr.Rewrite(pr, s.OrderBy[i].Column)
+	}
+	return nil
+}
+
+// pivotRewriter replaces references to
+// the columns produced by PIVOT with
+// the corresponding aggregates
+type pivotRewriter struct {
+	names []string
+	aggs  []*expr.Aggregate
+}
+
+func (p *pivotRewriter) column(e expr.Node) int {
+	if id, ok := e.(expr.Ident); ok {
+		return slices.Index(p.names, string(id))
+	}
+	return -1
+}
+
+func (p *pivotRewriter) uses(e expr.Node) bool {
+	found := false
+	expr.Walk(expr.WalkFunc(func(e expr.Node) bool {
+		if found {
+			return false
+		}
+		if _, ok := e.(*expr.Select); ok {
+			return false
+		}
+		found = p.column(e) >= 0
+		return !found
+	}), e)
+	return found
+}
+
+func (p *pivotRewriter) Rewrite(e expr.Node) expr.Node {
+	if i := p.column(e); i >= 0 {
+		return expr.Copy(p.aggs[i])
+	}
+	return e
+}
+
+func (p *pivotRewriter) Walk(e expr.Node) expr.Rewriter {
+	if _, ok := e.(*expr.Select); ok {
+		return nil
+	}
+	return p
+}
@@ -73,15 +73,9 @@ func checkSortSize(t *Trace) error {
 	if b, ok := final.(*Bind); ok {
 		final = b.parent()
 	}
-	f, ok := final.(*Order)
-	if ok {
-		if c := t.Class(); !c.Small() {
-			return errorf(f.Columns[0].Column,
-				"this ORDER BY requires a LIMIT statement (up to %d)",
-				LargeSize)
-		}
-		return nil
-	}
+	// an ORDER BY without a LIMIT
+	// can spill to disk, but the k-top
+	// sort for a LIMIT is held in memory
 	l, ok := final.(*Limit)
 	if !ok {
 		return nil
@@ -51,6 +51,17 @@ func columns(b []expr.Binding) []expr.Node {
 	return out
 }
 
+// anyUsed returns whether any of the
+// bindings appear in used
+func anyUsed(used map[string]struct{}, binds []string) bool {
+	for _, x := range binds {
+		if _, ok := used[x]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // for each Bind step, eliminate bindings
 // that are not referenced in subsequent steps
 func projectelim(b *Trace) {
@@ -146,11 +157,19 @@ loop:
 		case *IterTable:
 			s.trim(used)
 		case *IterValue:
-			if _, ok := used[s.Result]; !ok {
+			if !anyUsed(used, s.results()) {
 				// cross-join result isn't used
 				parent.setparent(s.parent())
 				continue loop
 			}
+		case *Lateral:
+			if _, ok := used[s.Result]; !ok {
+				parent.setparent(s.parent())
+				continue loop
+			}
+		case *Pivot:
+			// the outgoing fields all come from s.Field
+			maps.Clear(used)
 		case *Unpivot, *UnpivotAtDistinct:
 			return // all incoming fields are used
 		default:
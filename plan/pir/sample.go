@@ -0,0 +1,145 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pir
+
+import (
+	"math"
+
+	"github.com/SnellerInc/sneller/expr"
+)
+
+// sampleZ is the z-score of the 95% confidence
+// intervals reported by SCALED_COUNT and SCALED_SUM
+const sampleZ = 1.96
+
+// scaler is expr.Rewriter that replaces
+// SCALED_COUNT and SCALED_SUM with the
+// aggregates over the sampled rows scaled
+// by the fraction of the table that is sampled
+type scaler struct {
+	fraction float64
+	// blocks is set if whole blocks
+	// are sampled rather than rows
+	blocks bool
+	err    error
+}
+
+func (s *scaler) Walk(e expr.Node) expr.Rewriter {
+	// subqueries are scaled by
+	// the fraction of their own table
+	if _, ok := e.(*expr.Select); ok {
+		return nil
+	}
+	return s
+}
+
+func (s *scaler) Rewrite(e expr.Node) expr.Node {
+	agg, ok := e.(*expr.Aggregate)
+	if !ok || (agg.Op != expr.OpScaledCount && agg.Op != expr.OpScaledSum) {
+		return e
+	}
+	if agg.Over != nil {
+		if s.err == nil {
+			s.err = errorf(agg, "%s cannot be used as a window function", agg.Op)
+		}
+		return e
+	}
+	mkagg := func(op expr.AggregateOp, inner expr.Node) expr.Node {
+		return &expr.Aggregate{
+			Op:     op,
+			Inner:  expr.Copy(inner),
+			Filter: expr.Copy(agg.Filter),
+		}
+	}
+	// the sampled value and the sum of the squares
+	// that estimate the (Horvitz-Thompson) variance
+	// of the scaled value; the variance of a count
+	// is the count itself
+	var value, squares expr.Node
+	if agg.Op == expr.OpScaledCount {
+		value = mkagg(expr.OpCount, agg.Inner)
+		squares = mkagg(expr.OpCount, agg.Inner)
+	} else {
+		value = mkagg(expr.OpSum, agg.Inner)
+		squares = mkagg(expr.OpSum, expr.Mul(agg.Inner, agg.Inner))
+	}
+	var estimate, lower, upper expr.Node
+	switch {
+	case s.fraction == 1:
+		// the whole table: no error
+		estimate, lower, upper = value, expr.Copy(value), expr.Copy(value)
+	case s.blocks:
+		// the variance of an estimate from sampled
+		// blocks depends on the totals of the blocks,
+		// which the aggregates over the rows don't
+		// provide, so there are no bounds
+		estimate = expr.Div(value, expr.Float(s.fraction))
+		lower, upper = expr.Null{}, expr.Null{}
+	default:
+		f := expr.Float(s.fraction)
+		margin := expr.Float(sampleZ * math.Sqrt(1-s.fraction) / s.fraction)
+		estimate = expr.Div(value, f)
+		err := expr.Mul(margin, expr.Call(expr.Sqrt, squares))
+		lower = expr.Sub(expr.Copy(estimate), err)
+		upper = expr.Add(expr.Copy(estimate), expr.Copy(err))
+	}
+	return expr.Call(expr.MakeStruct,
+		expr.String("estimate"), estimate,
+		expr.String("lower"), lower,
+		expr.String("upper"), upper)
+}
+
+// sampleOf returns the TABLESAMPLE clause of the table
+// whose rows a FROM clause produces, or nil if the rows
+// are not sampled; the rows of a subquery that only
+// filters or projects the rows of a TABLESAMPLE are
+// still a sample of the table
+func sampleOf(from expr.From) *expr.TableSample {
+	t, ok := from.(*expr.Table)
+	if !ok {
+		return nil
+	}
+	if t.Sample != nil {
+		return t.Sample
+	}
+	sel, ok := t.Expr.(*expr.Select)
+	if !ok || sel.Distinct || len(sel.DistinctExpr) > 0 || sel.GroupBy != nil ||
+		sel.Having != nil || sel.Limit != nil || anyHasAggregate(sel.Columns) {
+		return nil
+	}
+	return sampleOf(sel.From)
+}
+
+// scaleSampled replaces SCALED_COUNT and
+// SCALED_SUM in the SELECT, ORDER BY and HAVING
+// clauses of s with estimates for the whole table
+// when s is computed from a TABLESAMPLE of the table
+func scaleSampled(s *expr.Select) error {
+	rw := &scaler{fraction: 1}
+	if ts := sampleOf(s.From); ts != nil {
+		rw.fraction = ts.Fraction()
+		rw.blocks = ts.Method == expr.SampleSystem
+	}
+	for i := range s.Columns {
+		s.Columns[i].Expr = expr.Rewrite(rw, s.Columns[i].Expr)
+	}
+	for i := range s.OrderBy {
+		s.OrderBy[i].Column = expr.Rewrite(rw, s.OrderBy[i].Column)
+	}
+	if s.Having != nil {
+		s.Having = expr.Rewrite(rw, s.Having)
+	}
+	return rw.err
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// Pivot replaces each row with the
+// structure in Field (see vm.Pivot)
+type Pivot struct {
+	Nonterminal // source op
+	Field       expr.Node
+}
+
+func (p *Pivot) encode(dst *ion.Buffer, st *ion.Symtab, ep *ExecParams) error {
+	dst.BeginStruct(-1)
+	settype("pivot", dst, st)
+	dst.BeginField(st.Intern("field"))
+	ep.rewrite(p.Field).Encode(dst, st)
+	dst.EndStruct()
+	return nil
+}
+
+func (p *Pivot) SetField(f ion.Field) error {
+	switch f.Label {
+	case "field":
+		e, err := expr.Decode(f.Datum)
+		if err != nil {
+			return err
+		}
+		p.Field = e
+	default:
+		return errUnexpectedField
+	}
+	return nil
+}
+
+func (p *Pivot) String() string {
+	return "PIVOT " + expr.ToString(p.Field)
+}
+
+func (p *Pivot) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
+	op, err := vm.NewPivot(ep.rewrite(p.Field), dst)
+	if err != nil {
+		return err
+	}
+	return p.From.exec(op, src, ep)
+}
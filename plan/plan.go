@@ -19,10 +19,12 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync/atomic"
 
 	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/plan/pir"
+	"github.com/SnellerInc/sneller/tracing"
 	"github.com/SnellerInc/sneller/vm"
 )
 
@@ -450,7 +452,12 @@ func (l *Leaf) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
 	}
 	// apply any last-minute filtering
 	if filt != nil {
+		before := src.Blocks()
 		src = src.Filter(filt)
+		atomic.AddInt64(&ep.Stats.BlocksPruned, int64(before-src.Blocks()))
+	}
+	if s := l.Orig.Sample; s != nil && s.Method == expr.SampleBernoulli {
+		dst = vm.NewSample(s.Fraction(), dst)
 	}
 	err := ep.Runner.Run(dst, src, ep)
 	if errors.Is(err, io.EOF) {
@@ -898,6 +905,7 @@ func (h *HashAggregate) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error
 		ha.Limit(h.Limit)
 	}
 	ha.SetSkipEmpty(h.NonEmpty)
+	ha.SetBudget(ep.Memory)
 	for i := range h.OrderBy {
 		col := h.OrderBy[i].Column
 		ordering := h.OrderBy[i].Ordering
@@ -913,6 +921,68 @@ func (h *HashAggregate) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error
 	return h.From.exec(ha, src, ep)
 }
 
+// CollectAggregate computes the aggregates
+// that collect their input values (ARRAY_AGG,
+// STRING_AGG and OBJECT_AGG), optionally grouped
+// by a list of expressions.
+type CollectAggregate struct {
+	Nonterminal
+	Agg      vm.Aggregation
+	By       vm.Selection
+	NonEmpty bool
+}
+
+func (c *CollectAggregate) String() string {
+	str := "COLLECT AGGREGATE " + c.Agg.String()
+	if len(c.By) > 0 {
+		str += " GROUP BY " + c.By.String()
+	}
+	if c.NonEmpty {
+		str += " NONEMPTY"
+	}
+	return str
+}
+
+func (c *CollectAggregate) encode(dst *ion.Buffer, st *ion.Symtab, ep *ExecParams) error {
+	dst.BeginStruct(-1)
+	settype("collectagg", dst, st)
+	dst.BeginField(st.Intern("agg"))
+	encodeAggregation(c.Agg, dst, st, ep)
+	if len(c.By) > 0 {
+		dst.BeginField(st.Intern("by"))
+		encodeBindings(c.By, dst, st, ep)
+	}
+	dst.BeginField(st.Intern("nonempty"))
+	dst.WriteBool(c.NonEmpty)
+	dst.EndStruct()
+	return nil
+}
+
+func (c *CollectAggregate) SetField(f ion.Field) error {
+	switch f.Label {
+	case "agg":
+		return decodeAggregation(&c.Agg, f.Datum)
+	case "by":
+		return decodeSel(&c.By, f.Datum)
+	case "nonempty":
+		var err error
+		c.NonEmpty, err = f.Bool()
+		return err
+	default:
+		return errUnexpectedField
+	}
+}
+
+func (c *CollectAggregate) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
+	ca, err := vm.NewCollectAggregate(ep.rewriteAgg(c.Agg), ep.rewriteBind(c.By), dst)
+	if err != nil {
+		return err
+	}
+	ca.SetSkipEmpty(c.NonEmpty)
+	ca.SetBudget(ep.Memory)
+	return c.From.exec(ca, src, ep)
+}
+
 // OrderBy implements ORDER BY clause (without GROUP BY).
 type OrderBy struct {
 	Nonterminal
@@ -973,6 +1043,7 @@ func (o *OrderBy) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
 	if err != nil {
 		return err
 	}
+	ord.SetBudget(ep.Memory)
 	// NOTE: vm.Order does not accept an
 	// io.WriteCloser and thus cannot close the
 	// passed writer, so we have to do it
@@ -1107,6 +1178,7 @@ func (d *Distinct) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
 	if d.Limit > 0 {
 		df.Limit(d.Limit)
 	}
+	df.SetBudget(ep.Memory)
 	return d.From.exec(df, src, ep)
 }
 
@@ -1262,6 +1334,16 @@ func (t *Tree) encode(dst *ion.Buffer, st *ion.Symtab, ep *ExecParams) error {
 		dst.BeginField(st.Intern("data"))
 		t.Data.Encode(dst, st)
 	}
+	// the current span, if any, is
+	// the parent of the remote spans
+	trace := t.Trace
+	if span := tracing.FromContext(ep.Context); span != nil {
+		trace = span.Context()
+	}
+	if trace.Sampled() {
+		dst.BeginField(st.Intern("traceparent"))
+		dst.WriteString(trace.String())
+	}
 	dst.BeginField(st.Intern("root"))
 	if err := t.Root.encode(dst, st, ep); err != nil {
 		return err
@@ -1344,7 +1426,9 @@ func (e *Explain) encode(dst *ion.Buffer, st *ion.Symtab, ep *ExecParams) error
 	// for presentation purposes only
 	e.Query.Encode(dst, st)
 	dst.BeginField(st.Intern("tree"))
-	e.Tree.encode(dst, st, ep)
+	if err := e.Tree.encode(dst, st, ep); err != nil {
+		return err
+	}
 	dst.EndStruct()
 	return nil
 }
@@ -1379,6 +1463,9 @@ func (e *Explain) SetField(f ion.Field) error {
 }
 
 func (e *Explain) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
+	if e.Format == expr.ExplainAnalyze {
+		return e.analyze(dst, ep)
+	}
 	var b ion.Buffer
 	var st ion.Symtab
 
@@ -0,0 +1,323 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/vm"
+)
+
+// Profile is an Op that collects statistics
+// about the rows produced by its input Op.
+//
+// Profile ops are inserted after every op
+// in a query plan executed by EXPLAIN ANALYZE.
+type Profile struct {
+	Nonterminal
+	// ID identifies this op within the plan tree
+	// so that statistics collected remotely can be
+	// attributed to the right op.
+	ID int
+
+	stats vm.SinkProfile
+}
+
+// OpProfile is the set of statistics
+// collected by a Profile op.
+type OpProfile struct {
+	// ID is the Profile.ID of the op.
+	ID int
+	vm.SinkProfile
+}
+
+func (p *Profile) String() string { return fmt.Sprintf("PROFILE %d", p.ID) }
+
+func (p *Profile) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
+	return p.From.exec(vm.NewProfile(dst, &p.stats), src, ep)
+}
+
+func (p *Profile) filter(e expr.Node) { push(e, p.From) }
+
+func (p *Profile) encode(dst *ion.Buffer, st *ion.Symtab, ep *ExecParams) error {
+	dst.BeginStruct(-1)
+	settype("profile", dst, st)
+	dst.BeginField(st.Intern("id"))
+	dst.WriteInt(int64(p.ID))
+	dst.EndStruct()
+	return nil
+}
+
+func (p *Profile) SetField(f ion.Field) error {
+	switch f.Label {
+	case "id":
+		id, err := f.Int()
+		if err != nil {
+			return err
+		}
+		p.ID = int(id)
+	default:
+		return errUnexpectedField
+	}
+	return nil
+}
+
+func (o *OpProfile) encode(dst *ion.Buffer, st *ion.Symtab) {
+	dst.BeginStruct(-1)
+	dst.BeginField(st.Intern("id"))
+	dst.WriteInt(int64(o.ID))
+	dst.BeginField(st.Intern("rows"))
+	dst.WriteInt(o.Rows)
+	dst.BeginField(st.Intern("bytes"))
+	dst.WriteInt(o.Bytes)
+	dst.BeginField(st.Intern("buckets"))
+	dst.WriteInt(o.Buckets)
+	dst.BeginField(st.Intern("skipped"))
+	dst.WriteInt(o.Skipped)
+	dst.BeginField(st.Intern("nanos"))
+	dst.WriteInt(o.Nanos)
+	dst.EndStruct()
+}
+
+func (o *OpProfile) decode(buf []byte, st *ion.Symtab) error {
+	_, err := ion.UnpackStruct(st, buf, func(name string, body []byte) error {
+		var err error
+		switch name {
+		case "id":
+			var id int64
+			id, _, err = ion.ReadInt(body)
+			o.ID = int(id)
+		case "rows":
+			o.Rows, _, err = ion.ReadInt(body)
+		case "bytes":
+			o.Bytes, _, err = ion.ReadInt(body)
+		case "buckets":
+			o.Buckets, _, err = ion.ReadInt(body)
+		case "skipped":
+			o.Skipped, _, err = ion.ReadInt(body)
+		case "nanos":
+			o.Nanos, _, err = ion.ReadInt(body)
+		default:
+			return errUnexpectedField
+		}
+		return err
+	})
+	return err
+}
+
+// walkProfiles calls fn on each Profile op
+// reachable from op, including those in
+// the inner nodes of Substitute ops
+func walkProfiles(op Op, fn func(p *Profile)) {
+	for ; op != nil; op = op.input() {
+		switch o := op.(type) {
+		case *Profile:
+			fn(o)
+		case *Substitute:
+			for i := range o.Inner {
+				walkProfiles(o.Inner[i].Op, fn)
+			}
+		}
+	}
+}
+
+// profiles returns the statistics collected
+// by all of the Profile ops in t, or nil if
+// t does not contain any Profile ops
+func (t *Tree) profiles() []OpProfile {
+	var out []OpProfile
+	walkProfiles(t.Root.Op, func(p *Profile) {
+		out = append(out, OpProfile{ID: p.ID, SinkProfile: p.stats.Load()})
+	})
+	return out
+}
+
+// addProfiles adds statistics collected remotely
+// into the matching Profile ops reachable from op
+func addProfiles(op Op, lst []OpProfile) {
+	if len(lst) == 0 {
+		return
+	}
+	walkProfiles(op, func(p *Profile) {
+		for i := range lst {
+			if lst[i].ID == p.ID {
+				p.stats.Add(&lst[i].SinkProfile)
+			}
+		}
+	})
+}
+
+// instrument inserts a Profile op
+// after every op in t
+func instrument(t *Tree) {
+	id := 0
+	var chain func(op Op) Op
+	chain = func(op Op) Op {
+		if from := op.input(); from != nil {
+			op.setinput(chain(from))
+		}
+		if s, ok := op.(*Substitute); ok {
+			for i := range s.Inner {
+				s.Inner[i].Op = chain(s.Inner[i].Op)
+			}
+		}
+		p := &Profile{Nonterminal: Nonterminal{From: op}, ID: id}
+		id++
+		return p
+	}
+	t.Root.Op = chain(t.Root.Op)
+}
+
+// peerProfile is the execution profile
+// of one peer of a UnionMap
+type peerProfile struct {
+	name    string
+	elapsed time.Duration
+	stats   ExecStats
+}
+
+func peerName(t Transport, i int) string {
+	if s, ok := t.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("peer %d", i)
+}
+
+func fmtNanos(n int64) string {
+	return time.Duration(n).Round(time.Microsecond).String()
+}
+
+// printprofile is the equivalent of printops
+// for a tree that has been instrumented
+func printprofile(dst *strings.Builder, indent int, op Op) {
+	p, ok := op.(*Profile)
+	if !ok {
+		printops(dst, indent, op)
+		return
+	}
+	inner := p.From
+	var in *Profile
+	if from := inner.input(); from != nil {
+		printprofile(dst, indent, from)
+		in, _ = from.(*Profile)
+	}
+	out := p.stats.Load()
+	switch o := inner.(type) {
+	case *Substitute:
+		for i := range o.Inner {
+			tabfprintf(dst, indent, "WITH REPLACEMENT(%d) AS (\n", i)
+			printprofile(dst, indent+1, o.Inner[i].Op)
+			tabline(dst, indent, ")")
+		}
+	case *Leaf:
+		line := fmt.Sprintf("%s [out: %d rows, decompressed: %d bytes", o.describe(), out.Rows, out.Bytes)
+		if out.Buckets > 0 {
+			line += fmt.Sprintf(", zion buckets skipped: %d/%d", out.Skipped, out.Buckets)
+		}
+		tabline(dst, indent, line+"]")
+	case *UnionMap:
+		tabline(dst, indent, fmt.Sprintf("%s [in: %d rows, out: %d rows]", o.String(), in.stats.Load().Rows, out.Rows))
+		for i := range o.peers {
+			pp := &o.peers[i]
+			if pp.name == "" {
+				continue // no input for this peer
+			}
+			tabfprintf(dst, indent+1, "%s [time: %s, scanned: %d bytes, cache hits: %d, cache misses: %d, blocks pruned: %d]\n",
+				pp.name, pp.elapsed.Round(time.Microsecond), pp.stats.BytesScanned,
+				pp.stats.CacheHits, pp.stats.CacheMisses, pp.stats.BlocksPruned)
+		}
+	default:
+		line := inner.String()
+		if in != nil {
+			// the time spent in this op is the time spent
+			// writing into its input sink, minus the time
+			// spent writing into its output sink
+			prev := in.stats.Load()
+			self := prev.Nanos - out.Nanos
+			if self < 0 {
+				self = 0
+			}
+			line += fmt.Sprintf(" [in: %d rows, out: %d rows, time: %s]", prev.Rows, out.Rows, fmtNanos(self))
+		} else {
+			line += fmt.Sprintf(" [out: %d rows]", out.Rows)
+		}
+		tabline(dst, indent, line)
+	}
+}
+
+// prunedBlocks returns the number of blocks that
+// were excluded from the descriptors in t.Inputs
+// while the query was being planned
+func (t *Tree) prunedBlocks() int64 {
+	n := int64(0)
+	for _, in := range t.Inputs {
+		for i := range in.Descs {
+			n += int64(len(in.Descs[i].Trailer.Blocks) - len(in.Descs[i].Blocks))
+		}
+	}
+	return n
+}
+
+// analyze executes e.Tree with a Profile op
+// after every op and writes the annotated plan
+func (e *Explain) analyze(dst vm.QuerySink, ep *ExecParams) error {
+	instrument(e.Tree)
+	subep := ep.clone()
+	subep.Plan = &Tree{
+		Inputs: e.Tree.Inputs,
+		Root:   e.Tree.Root,
+	}
+	if ep.Plan != nil {
+		subep.Plan.ID = ep.Plan.ID
+		subep.Plan.Data = ep.Plan.Data
+	}
+	var rows vm.Count
+	start := time.Now()
+	err := subep.Plan.exec(&rows, subep)
+	elapsed := time.Since(start)
+	ep.Stats.atomicAdd(&subep.Stats)
+	if err != nil {
+		return err
+	}
+	stats := subep.Stats
+	stats.BlocksPruned += e.Tree.prunedBlocks()
+
+	var text strings.Builder
+	printprofile(&text, 0, e.Tree.Root.Op)
+
+	var st ion.Symtab
+	var body ion.Buffer
+	body.BeginStruct(-1)
+	body.BeginField(st.Intern("query"))
+	body.WriteString(expr.ToString(e.Query))
+	body.BeginField(st.Intern("plan"))
+	body.WriteString(text.String())
+	body.BeginField(st.Intern("rows"))
+	body.WriteInt(rows.Value())
+	body.BeginField(st.Intern("elapsed"))
+	body.WriteFloat64(elapsed.Seconds())
+	body.BeginField(st.Intern("stats"))
+	stats.Encode(&body, &st)
+	body.EndStruct()
+
+	var b ion.Buffer
+	st.Marshal(&b, true)
+	b.UnsafeAppend(body.Bytes())
+	return writeIon(&b, dst)
+}
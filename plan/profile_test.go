@@ -0,0 +1,143 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"bytes"
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// pipeTransport executes queries with
+// a Client connected to a Server over a pipe
+type pipeTransport struct {
+	env *testenv
+}
+
+func (p *pipeTransport) Exec(ep *ExecParams) error {
+	local, remote := net.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Serve(remote, p.env)
+	}()
+	c := Client{Pipe: local}
+	err := c.Exec(ep)
+	c.Close()
+	if err2 := <-errc; err == nil {
+		err = err2
+	}
+	return err
+}
+
+func TestExplainAnalyze(t *testing.T) {
+	env := &testenv{t: t}
+	const text = `EXPLAIN ANALYZE SELECT COUNT(*) FROM parking WHERE Make = 'HOND'`
+	run := func(t *testing.T, tree *Tree) (string, int64) {
+		var out bytes.Buffer
+		ep := &ExecParams{
+			Plan:   tree,
+			Output: &out,
+			Runner: env,
+		}
+		err := Exec(ep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var st ion.Symtab
+		d, _, err := ion.ReadDatum(&st, out.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := d.Struct()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, ok := s.FieldByName("plan")
+		if !ok {
+			t.Fatalf("no plan in %s", toJSON(&st, d))
+		}
+		plan, err := f.String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, ok = s.FieldByName("rows")
+		if !ok {
+			t.Fatalf("no rows in %s", toJSON(&st, d))
+		}
+		rows, err := f.Int()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("%s", toJSON(&st, d))
+		return plan, rows
+	}
+	match := func(t *testing.T, plan string, patterns ...string) {
+		for _, p := range patterns {
+			if !regexp.MustCompile(p).MatchString(plan) {
+				t.Errorf("plan did not match %q", p)
+			}
+		}
+	}
+
+	parse := func(t *testing.T) *expr.Query {
+		q, err := partiql.Parse([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+	t.Run("local", func(t *testing.T) {
+		tree, err := New(parse(t), env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testPlanSerialize(t, tree)
+		plan, rows := run(t, tree)
+		if rows != 1 {
+			t.Errorf("got %d rows; expected 1", rows)
+		}
+		match(t, plan,
+			`parking \[out: 1023 rows, decompressed: [1-9][0-9]* bytes`,
+			`WHERE Make = 'HOND' \[in: 1023 rows, out: 122 rows, time: `,
+			`COUNT\(\*\) AS count \[in: 122 rows, out: 1 rows, time: `,
+		)
+	})
+	t.Run("split", func(t *testing.T) {
+		se := &splitEnv{
+			Env: env,
+			geom: &Geometry{
+				Peers: []Transport{&pipeTransport{env}, &pipeTransport{env}},
+			},
+		}
+		tree, err := NewSplit(parse(t), se)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan, _ := run(t, tree)
+		match(t, plan,
+			`parking \[out: 1023 rows`,
+			`WHERE Make = 'HOND' \[in: 1023 rows, out: 122 rows, time: `,
+			`COUNT\(\*\) AS \$_2_0 \[in: 122 rows, out: 1 rows, time: `,
+			`UNION MAP \[in: 1 rows, out: 1 rows\]`,
+			`(?m)^\tpeer [01] \[time: .*, scanned: [1-9][0-9]* bytes`,
+			`AGGREGATE SUM_COUNT\(\$_2_0\) AS "count" \[in: 1 rows, out: 1 rows, time: `,
+		)
+	})
+}
@@ -60,6 +60,7 @@ func (r *FSRunner) Run(dst vm.QuerySink, src *Input, ep *ExecParams) error {
 		fs:     r.FS,
 		in:     in,
 		fields: src.Fields,
+		mem:    ep.Memory,
 	}
 	// fast-path for local files: use mmap for reading
 	if dfs, ok := r.FS.(*blockfmt.DirFS); ok {
@@ -88,6 +89,7 @@ type readerTable struct {
 	fs       fs.FS
 	in       []readerInput
 	fields   []string
+	mem      *vm.Budget
 	idx, blk int
 	lock     sync.Mutex
 	scanned  int64
@@ -122,17 +124,17 @@ type IfMatcher interface {
 	IfMatch(etag string) error
 }
 
-func vmMalloc(size int) []byte {
+func (f *readerTable) malloc(size int) []byte {
 	if size > vm.PageSize {
 		panic("size > vm.PageSize")
 	}
-	return vm.Malloc()[:size]
+	return f.mem.Malloc()[:size]
 }
 
 func (f *readerTable) write(dst io.Writer) error {
 	var d blockfmt.Decoder
-	d.Malloc = vmMalloc
-	d.Free = vm.Free
+	d.Malloc = f.malloc
+	d.Free = f.mem.Free
 	d.Fields = f.fields
 	for {
 		in, off := f.next()
@@ -161,6 +163,9 @@ func (f *readerTable) write(dst io.Writer) error {
 		if err != nil {
 			return err
 		}
+		if err := f.mem.Check(); err != nil {
+			return err
+		}
 		atomic.AddInt64(&f.scanned, size)
 	}
 	return nil
@@ -208,8 +213,18 @@ type ExecParams struct {
 	// This may implement UploadFS, which is
 	// required to enable support for SELECT INTO.
 	FS fs.FS
+	// Memory, if non-nil, accounts for the memory
+	// used by the query and limits it to Memory.Limit().
+	// A query that exceeds the limit fails with an
+	// error wrapping vm.ErrMemoryLimit.
+	// Runners should allocate the vm pages used to
+	// decode their input with Memory.Malloc.
+	Memory *vm.Budget
 
 	get func(i int) *Input
+	// profile is the list of statistics collected
+	// by Profile ops when ep.Plan is executed remotely
+	profile []OpProfile
 }
 
 type multiRewriter struct {
@@ -310,6 +325,7 @@ func (ep *ExecParams) clone() *ExecParams {
 		Rewriter: ep.Rewriter,
 		Runner:   ep.Runner,
 		FS:       ep.FS,
+		Memory:   ep.Memory,
 		get:      ep.get,
 	}
 }
@@ -118,6 +118,25 @@ func TestSplit(t *testing.T) {
 				`PROJECT CASE WHEN $_0_0 = 0 THEN NULL ELSE SQRT($_0_1 / $_0_0 - ($_0_2 / $_0_0 * ($_0_2 / $_0_0))) END AS "stddev"`,
 			},
 		},
+		{
+			query: `SELECT VAR_SAMP(x) AS v FROM table`,
+			lines: []string{
+				`table`,
+				`AGGREGATE VAR_SAMP.PARTIAL(x) AS $_2_0`,
+				`UNION MAP`,
+				`AGGREGATE VAR_SAMP.MERGE($_2_0) AS v`,
+			},
+		},
+		{
+			query: `SELECT CORR(y, x) AS c FROM table GROUP BY g`,
+			lines: []string{
+				`table`,
+				`HASH AGGREGATE CORR.PARTIAL(y, x) AS $_2_0 GROUP BY g`,
+				`UNION MAP`,
+				`HASH AGGREGATE CORR.MERGE($_2_0) AS $_0_0 GROUP BY g AS g`,
+				`PROJECT $_0_0 AS c`,
+			},
+		},
 	}
 
 	for i := range tcs {
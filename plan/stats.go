@@ -19,6 +19,7 @@ import (
 	"sync/atomic"
 
 	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/tracing"
 	"github.com/SnellerInc/sneller/vm"
 )
 
@@ -38,6 +39,10 @@ type ExecStats struct {
 	// BytesScanned is the number
 	// of bytes scanned.
 	BytesScanned int64
+	// BlocksPruned is the number of blocks
+	// that were excluded from the query
+	// by a blockfmt.Filter during execution.
+	BlocksPruned int64
 }
 
 // CachedTable is an interface optionally
@@ -57,6 +62,18 @@ func (e *ExecStats) atomicAdd(tmp *ExecStats) {
 	atomic.AddInt64(&e.CacheHits, tmp.CacheHits)
 	atomic.AddInt64(&e.CacheMisses, tmp.CacheMisses)
 	atomic.AddInt64(&e.BytesScanned, tmp.BytesScanned)
+	atomic.AddInt64(&e.BlocksPruned, tmp.BlocksPruned)
+}
+
+// Load returns a copy of e that is consistent
+// with concurrent updates to e during query execution.
+func (e *ExecStats) Load() ExecStats {
+	return ExecStats{
+		CacheHits:    atomic.LoadInt64(&e.CacheHits),
+		CacheMisses:  atomic.LoadInt64(&e.CacheMisses),
+		BytesScanned: atomic.LoadInt64(&e.BytesScanned),
+		BlocksPruned: atomic.LoadInt64(&e.BlocksPruned),
+	}
 }
 
 func (e *ExecStats) Observe(table vm.Table) {
@@ -79,6 +96,13 @@ func (e *ExecStats) Marshal(dst *ion.Buffer) {
 // Encode encodes the stats to dst using
 // the provided symbol table.
 func (e *ExecStats) Encode(dst *ion.Buffer, st *ion.Symtab) {
+	e.encode(dst, st, nil, nil)
+}
+
+// encode encodes the stats along with the
+// statistics collected by Profile ops and
+// the spans recorded while tracing, if any
+func (e *ExecStats) encode(dst *ion.Buffer, st *ion.Symtab, profile []OpProfile, spans []tracing.SpanData) {
 	dst.BeginStruct(-1)
 	if e.CacheHits != 0 {
 		dst.BeginField(st.Intern("hits"))
@@ -92,10 +116,34 @@ func (e *ExecStats) Encode(dst *ion.Buffer, st *ion.Symtab) {
 		dst.BeginField(st.Intern("scanned"))
 		dst.WriteInt(e.BytesScanned)
 	}
+	if e.BlocksPruned != 0 {
+		dst.BeginField(st.Intern("pruned"))
+		dst.WriteInt(e.BlocksPruned)
+	}
+	if len(profile) > 0 {
+		dst.BeginField(st.Intern("profile"))
+		dst.BeginList(-1)
+		for i := range profile {
+			profile[i].encode(dst, st)
+		}
+		dst.EndList()
+	}
+	if len(spans) > 0 {
+		dst.BeginField(st.Intern("spans"))
+		tracing.Encode(dst, st, spans)
+	}
 	dst.EndStruct()
 }
 
 func (e *ExecStats) Decode(buf []byte, st *ion.Symtab) error {
+	return e.decode(buf, st, nil, nil)
+}
+
+// decode decodes the stats and appends the
+// statistics collected by Profile ops to *profile
+// and the spans recorded while tracing to *spans
+// if they are non-nil
+func (e *ExecStats) decode(buf []byte, st *ion.Symtab, profile *[]OpProfile, spans *[]tracing.SpanData) error {
 	_, err := ion.UnpackStruct(st, buf, func(name string, body []byte) error {
 		var err error
 		switch name {
@@ -105,6 +153,27 @@ func (e *ExecStats) Decode(buf []byte, st *ion.Symtab) error {
 			e.CacheMisses, _, err = ion.ReadInt(body)
 		case "scanned":
 			e.BytesScanned, _, err = ion.ReadInt(body)
+		case "pruned":
+			e.BlocksPruned, _, err = ion.ReadInt(body)
+		case "profile":
+			if profile == nil {
+				return errUnexpectedField
+			}
+			_, err = ion.UnpackList(body, func(body []byte) error {
+				var op OpProfile
+				if err := op.decode(body, st); err != nil {
+					return err
+				}
+				*profile = append(*profile, op)
+				return nil
+			})
+		case "spans":
+			if spans == nil {
+				return errUnexpectedField
+			}
+			var lst []tracing.SpanData
+			lst, err = tracing.Decode(st, body)
+			*spans = append(*spans, lst...)
 		default:
 			return errUnexpectedField
 		}
@@ -132,9 +201,21 @@ func init() {
 		"hits",
 		"misses",
 		"scanned",
+		"pruned",
+		"profile",
+		"id",
+		"rows",
+		"bytes",
+		"buckets",
+		"skipped",
+		"nanos",
+		"spans",
 	} {
 		statsSymtab.Intern(s)
 	}
+	for _, s := range tracing.Symbols {
+		statsSymtab.Intern(s)
+	}
 }
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler
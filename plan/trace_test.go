@@ -0,0 +1,107 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package plan
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr/partiql"
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/tracing"
+)
+
+func TestTraceSplit(t *testing.T) {
+	env := &testenv{t: t}
+	q, err := partiql.Parse([]byte(`SELECT COUNT(*) FROM parking WHERE Make = 'HOND'`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	se := &splitEnv{
+		Env: env,
+		geom: &Geometry{
+			Peers: []Transport{&pipeTransport{env}, &pipeTransport{env}},
+		},
+	}
+	tree, err := NewSplit(q, se)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := new(tracing.Recorder)
+	ctx, root := tracing.Root(context.Background(), "root", tracing.SpanContext{}, rec)
+	ep := &ExecParams{
+		Plan:    tree,
+		Output:  io.Discard,
+		Runner:  env,
+		Context: ctx,
+	}
+	if err := Exec(ep); err != nil {
+		t.Fatal(err)
+	}
+	root.End()
+
+	spans := rec.Take()
+	ids := make(map[tracing.SpanID]*tracing.SpanData)
+	count := make(map[string]int)
+	for i := range spans {
+		s := &spans[i]
+		if s.TraceID != root.Context().TraceID {
+			t.Errorf("span %q has trace ID %s", s.Name, s.TraceID)
+		}
+		ids[s.SpanID] = s
+		count[s.Name]++
+	}
+	if count["root"] != 1 || count["merge"] != 1 || count["subquery"] == 0 ||
+		count["remote execute"] != count["subquery"] {
+		t.Errorf("unexpected spans %v", count)
+	}
+	for i := range spans {
+		s := &spans[i]
+		var parent string
+		if p := ids[s.Parent]; p != nil {
+			parent = p.Name
+		}
+		switch s.Name {
+		case "subquery", "merge":
+			if parent != "root" {
+				t.Errorf("%q span has parent %q", s.Name, parent)
+			}
+		case "remote execute":
+			if parent != "subquery" {
+				t.Errorf("%q span has parent %q", s.Name, parent)
+			}
+		}
+	}
+
+	// the trace context is serialized with the plan
+	tree, err = New(q, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Trace = root.Context()
+	var buf ion.Buffer
+	var st ion.Symtab
+	if err := tree.Encode(&buf, &st); err != nil {
+		t.Fatal(err)
+	}
+	tree2, err := Decode(&st, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree2.Trace != tree.Trace {
+		t.Errorf("decoded trace %s; expected %s", tree2.Trace, tree.Trace)
+	}
+}
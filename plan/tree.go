@@ -22,6 +22,7 @@ import (
 
 	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/tracing"
 	"github.com/SnellerInc/sneller/vm"
 )
 
@@ -51,6 +52,10 @@ type Tree struct {
 	// Data is arbitrary data that can be included
 	// along with the tree during serialization.
 	Data ion.Datum
+	// Trace, if valid, identifies the span
+	// on whose behalf the tree is executed
+	// when the query is being traced.
+	Trace tracing.SpanContext
 	// Root is the root node of the plan tree.
 	Root Node
 
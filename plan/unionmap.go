@@ -20,9 +20,11 @@ import (
 	"io"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/tracing"
 	"github.com/SnellerInc/sneller/vm"
 )
 
@@ -36,6 +38,10 @@ type UnionMap struct {
 	// Geometry determines how table handle inputs
 	// are distributed onto the constituent partials
 	Geometry *Geometry
+
+	// peers is populated during execution
+	// when u.From is a Profile op
+	peers []peerProfile
 }
 
 var (
@@ -128,6 +134,12 @@ func (u *UnionMap) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
 	// does not benefit substantially from having
 	// parallelism, so we union all the output bytes
 	// into a single thread here
+	// when executing with EXPLAIN ANALYZE,
+	// record the time spent by each peer
+	_, profiling := u.From.(*Profile)
+	if profiling {
+		u.peers = make([]peerProfile, len(in))
+	}
 	errors := make([]error, len(in))
 	var wg sync.WaitGroup
 	for i := range in {
@@ -137,6 +149,7 @@ func (u *UnionMap) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
+			start := time.Now()
 			tp := u.Geometry.Peers[i]
 			// wrap the rest of the query in a Tree;
 			// this makes it look to the Transport
@@ -153,9 +166,23 @@ func (u *UnionMap) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
 				},
 			}
 			subep.Output = s
+			var span *tracing.Span
+			subep.Context, span = tracing.Start(ep.Context, "subquery")
+			span.SetAttr("peer", peerName(tp, i))
 			// subep.get will be clobbered by Exec here:
 			errors[i] = tp.Exec(subep)
 			ep.Stats.atomicAdd(&subep.Stats)
+			span.SetAttr("bytes_scanned", subep.Stats.BytesScanned)
+			span.Fail(errors[i])
+			span.End()
+			if profiling {
+				addProfiles(u.From, subep.profile)
+				u.peers[i] = peerProfile{
+					name:    peerName(tp, i),
+					elapsed: time.Since(start),
+					stats:   subep.Stats,
+				}
+			}
 		}(i)
 	}
 	wg.Wait()
@@ -165,6 +192,9 @@ func (u *UnionMap) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
 			break
 		}
 	}
+	// closing the outputs performs the final
+	// merge of the results of the peers
+	_, span := tracing.Start(ep.Context, "merge")
 	err2 := w.Close()
 	err3 := dst.Close()
 	if err == nil {
@@ -173,6 +203,8 @@ func (u *UnionMap) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
 	if err == nil {
 		err = err3
 	}
+	span.Fail(err)
+	span.End()
 	return err
 }
 
@@ -29,6 +29,14 @@ type Unnest struct {
 	Nonterminal // source op
 	Expr        expr.Node
 	Result      string
+	// Zip are other arrays un-nested
+	// in lockstep with Expr
+	Zip []expr.Binding
+	// Position, if non-empty, is the binding
+	// of the position of each element,
+	// which starts at Base
+	Position string
+	Base     int
 }
 
 func (u *Unnest) encode(dst *ion.Buffer, st *ion.Symtab, ep *ExecParams) error {
@@ -38,6 +46,20 @@ func (u *Unnest) encode(dst *ion.Buffer, st *ion.Symtab, ep *ExecParams) error {
 	ep.rewrite(u.Expr).Encode(dst, st)
 	dst.BeginField(st.Intern("result"))
 	dst.WriteString(u.Result)
+	if len(u.Zip) > 0 {
+		zip := make([]expr.Binding, len(u.Zip))
+		for i := range u.Zip {
+			zip[i] = expr.Bind(ep.rewrite(u.Zip[i].Expr), u.Zip[i].Result())
+		}
+		dst.BeginField(st.Intern("zip"))
+		expr.EncodeBindings(zip, dst, st)
+	}
+	if u.Position != "" {
+		dst.BeginField(st.Intern("position"))
+		dst.WriteString(u.Position)
+		dst.BeginField(st.Intern("base"))
+		dst.WriteInt(int64(u.Base))
+	}
 	dst.EndStruct()
 	return nil
 }
@@ -65,6 +87,24 @@ func (u *Unnest) SetField(f ion.Field) error {
 			return err
 		}
 		u.Expr = e
+	case "zip":
+		zip, err := expr.DecodeBindings(f.Datum)
+		if err != nil {
+			return err
+		}
+		u.Zip = zip
+	case "position":
+		s, err := f.String()
+		if err != nil {
+			return err
+		}
+		u.Position = s
+	case "base":
+		i, err := f.Int()
+		if err != nil {
+			return err
+		}
+		u.Base = int(i)
 	default:
 		return errUnexpectedField
 	}
@@ -77,13 +117,37 @@ func (u *Unnest) String() string {
 	out.WriteString(expr.ToString(u.Expr))
 	out.WriteString(" AS ")
 	out.WriteString(u.Result)
+	for i := range u.Zip {
+		out.WriteString(", ")
+		out.WriteString(expr.ToString(u.Zip[i].Expr))
+		out.WriteString(" AS ")
+		out.WriteString(u.Zip[i].Result())
+	}
+	if u.Position != "" {
+		if u.Base == 0 {
+			out.WriteString(" AT ")
+		} else {
+			out.WriteString(" WITH ORDINALITY AS ")
+		}
+		out.WriteString(u.Position)
+	}
 	return out.String()
 }
 
 func (u *Unnest) exec(dst vm.QuerySink, src *Input, ep *ExecParams) error {
-	op, err := vm.NewUnnest(dst, ep.rewrite(u.Expr), u.Result)
+	fields := []expr.Node{ep.rewrite(u.Expr)}
+	results := []string{u.Result}
+	for i := range u.Zip {
+		fields = append(fields, ep.rewrite(u.Zip[i].Expr))
+		results = append(results, u.Zip[i].Result())
+	}
+	op, err := vm.NewZipUnnest(dst, fields, results)
 	if err != nil {
 		return err
 	}
+	if u.Position != "" {
+		op.SetPosition(u.Position, u.Base)
+	}
+	op.SetBudget(ep.Memory)
 	return u.From.exec(op, src, ep)
 }
@@ -91,6 +91,7 @@ func (r *TenantRunner) Run(dst vm.QuerySink, in *plan.Input, ep *plan.ExecParams
 				desc:   in.Descs[i].Descriptor,
 				block:  off,
 				fields: in.Fields,
+				mem:    ep.Memory,
 			}
 			segs = append(segs, seg)
 		}
@@ -115,6 +116,7 @@ type tenantSegment struct {
 	desc   blockfmt.Descriptor
 	block  int
 	fields []string
+	mem    *vm.Budget
 }
 
 // merge two sorted slices
@@ -204,21 +206,24 @@ func (s *tenantSegment) Ephemeral() bool {
 	return s.desc.Size < db.DefaultMinMerge
 }
 
-func vmMalloc(size int) []byte {
+func (s *tenantSegment) malloc(size int) []byte {
 	if size > vm.PageSize {
 		panic("cannot allocate page with size > vm.PageSize")
 	}
-	return vm.Malloc()[:size]
+	return s.mem.Malloc()[:size]
 }
 
 // Decode implements dcache.Segment.Decode
 func (s *tenantSegment) Decode(dst io.Writer, src []byte) error {
 	defer trace.StartRegion(s.ctx, "decode-segment").End()
 	var dec blockfmt.Decoder
-	dec.Malloc = vmMalloc
-	dec.Free = vm.Free
+	dec.Malloc = s.malloc
+	dec.Free = s.mem.Free
 	dec.Fields = s.fields
 	dec.Set(&s.desc.Trailer)
 	_, err := dec.CopyBytes(dst, src)
-	return err
+	if err != nil {
+		return err
+	}
+	return s.mem.Check()
 }
@@ -49,7 +49,7 @@ func CanSandbox() bool {
 	return bwrapPath() != ""
 }
 
-func (m *Manager) sandboxStart(cmd *exec.Cmd, cg cgroup.Dir, cachedir string) error {
+func (m *Manager) sandboxStart(cmd *exec.Cmd, cg cgroup.Dir, cachedir, spilldir string) error {
 	bw := bwrapPath()
 	// pipe for --block-fd
 	blockr, blockw, err := os.Pipe()
@@ -65,7 +65,8 @@ func (m *Manager) sandboxStart(cmd *exec.Cmd, cg cgroup.Dir, cachedir string) er
 	}
 
 	// mount / as read-only,
-	// and bind-mount CACHEDIR over /tmp
+	// bind-mount CACHEDIR over /tmp,
+	// and bind-mount SPILLDIR (if any) in place
 	//
 	// TODO: maybe don't bind all of /
 	// and instead make a template for a
@@ -85,8 +86,14 @@ func (m *Manager) sandboxStart(cmd *exec.Cmd, cg cgroup.Dir, cachedir string) er
 		"--setenv", "CACHEDIR", "/tmp",
 		"--block-fd", strconv.Itoa(len(cmd.ExtraFiles) + 3),
 		"--info-fd", strconv.Itoa(len(cmd.ExtraFiles) + 4),
-		"--",
 	}
+	if spilldir != "" {
+		// keep spill files out of the cache directory;
+		// the spill directory is mounted at its own path,
+		// so SPILLDIR remains valid inside the sandbox
+		args = append(args, "--bind", spilldir, spilldir)
+	}
+	args = append(args, "--")
 	cmd.ExtraFiles = append(cmd.ExtraFiles, blockr, infow)
 	cmd.Path = bw
 	cmd.Args = append(args, cmd.Args...)
@@ -24,6 +24,7 @@
 package dcache
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -525,7 +526,7 @@ func (c *Cache) Table(s Segment, f Flag) *Table {
 
 func (t *Table) write(w io.Writer) error {
 	ret := make(chan error, 1)
-	t.cache.queue.send(t.seg, w, t.flags, &t.Stats, ret)
+	t.cache.queue.send(context.Background(), t.seg, w, t.flags, &t.Stats, ret)
 	return <-ret
 }
 
@@ -76,7 +76,7 @@ func (m *MultiTable) write(w io.Writer) error {
 		if ret == nil {
 			ret = make(chan error, 1)
 		}
-		t.cache.queue.send(t.seg, w, t.flags, &m.Stats, ret)
+		t.cache.queue.send(m.ctx, t.seg, w, t.flags, &m.Stats, ret)
 		err := <-ret
 		if err != nil {
 			return err
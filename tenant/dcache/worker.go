@@ -15,9 +15,11 @@
 package dcache
 
 import (
+	"context"
 	"io"
 	"sync"
 
+	"github.com/SnellerInc/sneller/tracing"
 	"github.com/SnellerInc/sneller/vm"
 )
 
@@ -26,6 +28,10 @@ type reservation struct {
 	etag    string
 	out     *vm.TeeWriter
 	primary *Stats
+	// ctx is the context of the query that
+	// made the reservation; a cache fill is
+	// traced as part of that query
+	ctx context.Context
 
 	// guarded by queue.lock
 	// until the reservation has
@@ -53,7 +59,7 @@ func (q *queue) tryBackground() bool {
 	}
 }
 
-func (q *queue) send(seg Segment, dst io.Writer, flags Flag, stats *Stats, ret chan<- error) {
+func (q *queue) send(ctx context.Context, seg Segment, dst io.Writer, flags Flag, stats *Stats, ret chan<- error) {
 	etag := seg.ETag()
 	done := func(pos int64, e error) {
 		stats.addBytes(pos)
@@ -83,6 +89,7 @@ func (q *queue) send(seg Segment, dst io.Writer, flags Flag, stats *Stats, ret c
 		etag:    etag,
 		out:     vm.NewTeeWriter(dst, done),
 		primary: stats,
+		ctx:     ctx,
 		flags:   flags,
 	}
 	q.reserved[etag] = res
@@ -133,16 +140,34 @@ func (c *Cache) asyncReadThrough(res *reservation, mp *mapping) bool {
 	}
 	go func() {
 		defer c.queue.endBackground()
+		span := startFill(res, mp)
 		pop, err := readThrough(res.seg, mp, res)
 		if mp != nil {
 			c.finalize(mp, pop)
 			c.unmap(mp)
 		}
+		endFill(span, pop, err)
 		res.close(err)
 	}()
 	return true
 }
 
+// startFill starts the span of a cache miss
+// if the query that caused it is being traced
+func startFill(res *reservation, mp *mapping) *tracing.Span {
+	_, span := tracing.Start(res.ctx, "cache fill")
+	span.SetAttr("etag", res.etag)
+	span.SetAttr("size", res.seg.Size())
+	span.SetAttr("cacheable", mp != nil)
+	return span
+}
+
+func endFill(span *tracing.Span, populated bool, err error) {
+	span.SetAttr("populated", populated)
+	span.Fail(err)
+	span.End()
+}
+
 func (c *Cache) worker() {
 	defer c.wg.Done()
 	q := &c.queue
@@ -168,11 +193,13 @@ outer:
 				// res.close() will be called elsewhere
 				continue outer
 			}
+			span := startFill(res, mp)
 			pop, err = readThrough(res.seg, mp, res)
 			if mp != nil {
 				c.finalize(mp, pop)
 				c.unmap(mp)
 			}
+			endFill(span, pop, err)
 		}
 		res.close(err)
 	}
@@ -171,6 +171,8 @@ func (m *Manager) evict(t *totalHeap, size int64) {
 			if os.Remove(f.path) == nil {
 				t.files++         // track files evicted
 				t.bytes += f.size // track bytes evicted
+				m.evictedFiles.Add(1)
+				m.evictedBytes.Add(f.size)
 				size -= f.size
 				if f.atime > t.maxatime {
 					t.maxatime = f.atime
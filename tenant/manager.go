@@ -79,6 +79,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -86,6 +87,7 @@ import (
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/plan"
 	"github.com/SnellerInc/sneller/tenant/tnproto"
+	"github.com/SnellerInc/sneller/tracing"
 	"github.com/SnellerInc/sneller/usock"
 
 	"github.com/dchest/siphash"
@@ -114,6 +116,17 @@ type Manager struct {
 	// CacheDir is the root of the directory
 	// tree used for caching data.
 	CacheDir string
+	// SpillDir is the root of the directory
+	// tree in which tenant processes write
+	// the temporary files of queries that
+	// exceed their memory budget.
+	// Each tenant process receives its own
+	// subdirectory in the SPILLDIR environment variable.
+	// It is kept separate from CacheDir so that
+	// these files are not accounted or evicted
+	// as cached data. If it is empty, tenant
+	// processes use their default temporary directory.
+	SpillDir string
 	// Sandbox determines if Manager
 	// launches the tenant process
 	// with bwrap(1)
@@ -147,6 +160,8 @@ type Manager struct {
 
 	// cg maps tenants to cgroups
 	cg func(id tnproto.ID) cgroup.Dir
+	// cgsetup configures new cgroups
+	cgsetup func(id tnproto.ID, dir cgroup.Dir) error
 
 	// gcInterval is the interval at which
 	// processes that have been inactive for
@@ -169,6 +184,14 @@ type Manager struct {
 	lock sync.Mutex // guards live
 	live map[procID]*child
 
+	qlock   sync.Mutex // guards queries
+	queries map[string]*running
+
+	// counters reported by Stats
+	launches, exits, idleKills atomic.Int64
+	remoteErrors               atomic.Int64
+	evictedFiles, evictedBytes atomic.Int64
+
 	eventfd *os.File
 
 	// candidates for cached files to
@@ -207,6 +230,8 @@ func WithGCInterval(interval time.Duration) Option {
 
 const DefaultCacheDir = "/tmp/tenant-cache"
 
+const DefaultSpillDir = "/tmp/tenant-spill"
+
 // DefaultEnv is the default
 // environment-generating function
 // for the tenant process.
@@ -227,13 +252,14 @@ const DefaultCacheDir = "/tmp/tenant-cache"
 //	HOME=$HOME
 //	LANG=C.UTF-8
 //	CACHEDIR=<cache>
+//	QUERY_MEMORY_LIMIT=$QUERY_MEMORY_LIMIT
 func DefaultEnv(cache string, id tnproto.ID) []string {
 	x := []string{
 		"LANG=C.UTF-8",
 		"CACHEDIR=" + cache,
 	}
 	for _, evar := range []string{
-		"PATH", "SHELL", "LANG", "HOME",
+		"PATH", "SHELL", "LANG", "HOME", "QUERY_MEMORY_LIMIT",
 	} {
 		if val := os.Getenv(evar); val != "" {
 			x = append(x, fmt.Sprintf("%s=%s", evar, val))
@@ -289,6 +315,21 @@ func WithCgroup(fn func(id tnproto.ID) cgroup.Dir) Option {
 	}
 }
 
+// WithCgroupSetup sets a function that is called
+// to configure the cgroup of a tenant process (for
+// example, to set resource limits) after the cgroup
+// has been created and before the tenant process is
+// started in it. If the function returns an error,
+// the tenant process is not started.
+//
+// The function is only called if cgroups are
+// in use; see WithCgroup.
+func WithCgroupSetup(fn func(id tnproto.ID, dir cgroup.Dir) error) Option {
+	return func(m *Manager) {
+		m.cgsetup = fn
+	}
+}
+
 // NewManager makes a new Manager from the
 // list of command-line arguments provided
 // and the list of additional options.
@@ -304,6 +345,7 @@ func NewManager(cmd []string, opt ...Option) *Manager {
 		gcInterval: DefaultReapInterval,
 		envfn:      DefaultEnv,
 		CacheDir:   DefaultCacheDir,
+		SpillDir:   DefaultSpillDir,
 	}
 	for i := range opt {
 		opt[i](m)
@@ -317,6 +359,12 @@ func (m *Manager) init() {
 		if err != nil {
 			m.errorf("cleaning cache dir: %s", err)
 		}
+		if m.SpillDir != "" {
+			err = m.clean(m.SpillDir)
+			if err != nil {
+				m.errorf("cleaning spill dir: %s", err)
+			}
+		}
 		m.eventfd, err = eventfd()
 		if err != nil {
 			m.errorf("eventfd: %s", err)
@@ -430,6 +478,7 @@ func (m *Manager) reap(c *child, pid procID) {
 		panic(err)
 	}
 	m.errorf("child pid %d exited: %s", c.proc.Pid, state)
+	m.exits.Add(1)
 	m.lock.Lock()
 	// only delete this child if it
 	// precisely the same child instance
@@ -442,7 +491,7 @@ func (m *Manager) reap(c *child, pid procID) {
 	// and don't remove the cache directory here
 	if m.live != nil && m.live[pid] == c {
 		delete(m.live, pid)
-		os.RemoveAll(m.cacheDir(pid))
+		m.removeDirs(pid)
 		if !c.cg.IsZero() {
 			c.cg.Remove()
 		}
@@ -491,12 +540,13 @@ func (m *Manager) gc() {
 			for id, c := range m.live {
 				if idle := time.Since(c.touched); idle >= interval {
 					m.errorf("pid %d idle for %s; killing", c.proc.Pid, idle)
+					m.idleKills.Add(1)
 					c.proc.Kill()
 					if !c.cg.IsZero() {
 						c.cg.Kill()
 					}
 					delete(m.live, id)
-					os.RemoveAll(m.cacheDir(id))
+					m.removeDirs(id)
 				}
 			}
 			m.lock.Unlock()
@@ -531,6 +581,23 @@ func (m *Manager) cacheDir(pid procID) string {
 	return filepath.Join(m.CacheDir, pid.cacheDir())
 }
 
+// spillDir returns the spill directory
+// of a tenant process, or the empty string
+// if m.SpillDir is not set
+func (m *Manager) spillDir(pid procID) string {
+	if m.SpillDir == "" {
+		return ""
+	}
+	return filepath.Join(m.SpillDir, pid.cacheDir())
+}
+
+func (m *Manager) removeDirs(pid procID) {
+	os.RemoveAll(m.cacheDir(pid))
+	if dir := m.spillDir(pid); dir != "" {
+		os.RemoveAll(dir)
+	}
+}
+
 func (m *Manager) clean(dir string) error {
 	err := os.RemoveAll(dir)
 	if err != nil {
@@ -578,6 +645,12 @@ func (m *Manager) launch(id tnproto.ID, key tnproto.Key) (*child, error) {
 	if err := m.clean(m.cacheDir(pid)); err != nil {
 		return nil, err
 	}
+	spilldir := m.spillDir(pid)
+	if spilldir != "" {
+		if err := m.clean(spilldir); err != nil {
+			return nil, err
+		}
+	}
 	local, remote, err := usock.SocketPair()
 	if err != nil {
 		return nil, err
@@ -605,6 +678,9 @@ func (m *Manager) launch(id tnproto.ID, key tnproto.Key) (*child, error) {
 	cmd := exec.Command(m.execPath, append(m.execArgs, "-t", id.String(), "-c", "3", "-e", "4")...)
 	// note: sandboxing will override
 	cmd.Env = m.envfn(m.cacheDir(pid), id)
+	if spilldir != "" {
+		cmd.Env = append(cmd.Env, "SPILLDIR="+spilldir)
+	}
 	cmd.Stdin = nil
 	if m.logger == nil {
 		cmd.Stdout = os.Stderr
@@ -633,8 +709,14 @@ func (m *Manager) launch(id tnproto.ID, key tnproto.Key) (*child, error) {
 			if err != nil {
 				return nil, err
 			}
+			if m.cgsetup != nil {
+				err = m.cgsetup(id, cg)
+				if err != nil {
+					return nil, err
+				}
+			}
 		}
-		err = m.sandboxStart(cmd, cg, m.cacheDir(pid))
+		err = m.sandboxStart(cmd, cg, m.cacheDir(pid), spilldir)
 	} else {
 		if m.Sandbox {
 			m.warnOnce.Do(func() {
@@ -688,6 +770,7 @@ func (m *Manager) get(id tnproto.ID, key tnproto.Key) (*child, error) {
 		m.live = make(map[procID]*child)
 	}
 	m.live[pid] = c
+	m.launches.Add(1)
 	go m.reap(c, pid)
 	return c, nil
 }
@@ -720,12 +803,79 @@ func (m *Manager) get(id tnproto.ID, key tnproto.Key) (*child, error) {
 // so closing 'into' immediately after a call
 // to Do will not close the connection from
 // the perspective of the tenant process.)
+//
+// While the query is running, it can be
+// canceled with Cancel using the ID of the plan.
 func (m *Manager) Do(id tnproto.ID, key tnproto.Key, t *plan.Tree, ofmt tnproto.OutputFormat, into net.Conn) (io.ReadCloser, error) {
 	c, err := m.get(id, key)
 	if err != nil {
 		return nil, err
 	}
-	return c.directExec(t, ofmt, into)
+	rc, err := c.directExec(t, ofmt, into)
+	if err != nil || t.ID == "" {
+		return rc, err
+	}
+	return m.track(t.ID, rc), nil
+}
+
+// running is the error pipe of a query
+// started with Manager.Do; closing it
+// cancels the query
+type running struct {
+	io.ReadCloser
+	m    *Manager
+	id   string
+	once sync.Once
+}
+
+func (r *running) Close() error {
+	r.once.Do(func() {
+		r.m.qlock.Lock()
+		defer r.m.qlock.Unlock()
+		if r.m.queries[r.id] == r {
+			delete(r.m.queries, r.id)
+		}
+	})
+	return r.ReadCloser.Close()
+}
+
+// SetReadDeadline sets the read deadline
+// of the underlying pipe, if it has one
+func (r *running) SetReadDeadline(t time.Time) error {
+	rd, ok := r.ReadCloser.(interface {
+		SetReadDeadline(time.Time) error
+	})
+	if !ok {
+		return os.ErrNoDeadline
+	}
+	return rd.SetReadDeadline(t)
+}
+
+func (m *Manager) track(queryID string, rc io.ReadCloser) io.ReadCloser {
+	r := &running{ReadCloser: rc, m: m, id: queryID}
+	m.qlock.Lock()
+	defer m.qlock.Unlock()
+	if m.queries == nil {
+		m.queries = make(map[string]*running)
+	}
+	m.queries[queryID] = r
+	return r
+}
+
+// Cancel cancels the query with the given ID
+// that was started with Do. Sub-queries that
+// the query sent to tenants on other machines
+// (see tnproto.Remote) are canceled along with it.
+// Cancel returns false if no such query is running.
+func (m *Manager) Cancel(queryID string) bool {
+	m.qlock.Lock()
+	r := m.queries[queryID]
+	m.qlock.Unlock()
+	if r == nil {
+		return false
+	}
+	r.Close()
+	return true
 }
 
 // Quit sends a SIGQUIT to the tenant process
@@ -749,10 +899,63 @@ func (m *Manager) Quit(id tnproto.ID, key tnproto.Key) bool {
 // Check blocks until the other end of the pipe
 // has been closed, and then closes this end of the pipe.
 func Check(rc io.ReadCloser, stats *plan.ExecStats) error {
+	return CheckProgress(rc, stats, nil)
+}
+
+// CheckProgress is identical to Check, except that
+// it calls progress (if it is non-nil) with the
+// statistics that the tenant reports periodically
+// while the query is running.
+func CheckProgress(rc io.ReadCloser, stats *plan.ExecStats, progress func(*plan.ExecStats)) error {
+	return CheckTrace(rc, stats, progress, nil)
+}
+
+// CheckTrace is identical to CheckProgress, except
+// that the spans recorded by the tenant while executing
+// a traced query (see plan.Tree.Trace) are added to rec
+// (if it is non-nil).
+func CheckTrace(rc io.ReadCloser, stats *plan.ExecStats, progress func(*plan.ExecStats), rec *tracing.Recorder) error {
 	defer rc.Close()
-	msg, err := io.ReadAll(rc)
-	if err != nil {
-		return err
+	r := bufio.NewReader(rc)
+	var msg []byte
+	for {
+		_, size, err := ion.Peek(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		msg = make([]byte, size)
+		_, err = io.ReadFull(r, msg)
+		if err != nil {
+			return err
+		}
+		if ok, spans, err := tnproto.DecodeSpans(msg); ok {
+			if err != nil {
+				return err
+			}
+			if rec != nil {
+				rec.Add(spans...)
+			}
+			msg = nil
+			continue
+		}
+		var cur plan.ExecStats
+		ok, err := tnproto.DecodeProgress(msg, &cur)
+		if !ok || err != nil {
+			// the final status
+			rest, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			msg = append(msg, rest...)
+			break
+		}
+		if progress != nil {
+			progress(&cur)
+		}
+		msg = nil
 	}
 	if len(msg) == 0 {
 		return &tnproto.RemoteError{Text: "tenant crashed"}
@@ -764,8 +967,7 @@ func Check(rc io.ReadCloser, stats *plan.ExecStats) error {
 		}
 		return &tnproto.RemoteError{Text: "(malformed error response)"}
 	}
-	err = stats.UnmarshalBinary(msg)
-	if err == nil {
+	if stats.UnmarshalBinary(msg) == nil {
 		return nil
 	}
 	return &tnproto.RemoteError{Text: "(malformed OK response)"}
@@ -784,6 +986,7 @@ func (m *Manager) handleRemote(conn net.Conn) {
 	defer conn.Close()
 	id, key, err := tnproto.ReadHeader(conn)
 	if err != nil {
+		m.remoteErrors.Add(1)
 		m.errorf("connection: %s", err)
 		return
 	}
@@ -792,15 +995,61 @@ func (m *Manager) handleRemote(conn net.Conn) {
 	}
 	c, err := m.get(id, key)
 	if err != nil {
+		m.remoteErrors.Add(1)
 		m.errorf("couldn't spawn %x: %s", id, err)
 		return
 	}
 	err = c.proxyExec(conn)
 	if err != nil {
+		m.remoteErrors.Add(1)
 		m.errorf("id %s: proxy-exec: %s", id, err)
 	}
 }
 
+// ManagerStats is a snapshot of the
+// activity of a Manager; see Manager.Stats.
+type ManagerStats struct {
+	// Live is the number of tenant
+	// processes that are currently running.
+	Live int
+	// Launches, Exits and IdleKills are the
+	// number of tenant processes that have
+	// been launched, that have exited, and
+	// that have been killed due to inactivity,
+	// respectively.
+	Launches, Exits, IdleKills int64
+	// RemoteErrors is the number of requests
+	// from peers that could not be served.
+	RemoteErrors int64
+	// CacheUsed and CacheSize are the number
+	// of bytes used and the total number of bytes
+	// of the file system holding the cache directory.
+	CacheUsed, CacheSize int64
+	// EvictedFiles and EvictedBytes are the number
+	// of files and bytes evicted from the cache.
+	EvictedFiles, EvictedBytes int64
+}
+
+// Stats returns a snapshot of the activity
+// of the Manager since it was created.
+func (m *Manager) Stats() ManagerStats {
+	m.lock.Lock()
+	live := len(m.live)
+	m.lock.Unlock()
+	used, size := usage(m.CacheDir)
+	return ManagerStats{
+		Live:         live,
+		Launches:     m.launches.Load(),
+		Exits:        m.exits.Load(),
+		IdleKills:    m.idleKills.Load(),
+		RemoteErrors: m.remoteErrors.Load(),
+		CacheUsed:    used,
+		CacheSize:    size,
+		EvictedFiles: m.evictedFiles.Load(),
+		EvictedBytes: m.evictedBytes.Load(),
+	}
+}
+
 // Stop performs a graceful cleanup
 // of all of the tenant manager subprocesses.
 //
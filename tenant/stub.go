@@ -97,6 +97,21 @@ func testCgroupOK() {
 	}
 }
 
+// check that we were given a writable
+// spill directory (see Manager.SpillDir)
+func testSpillDirOK() {
+	dir := os.Getenv("SPILLDIR")
+	if dir == "" {
+		die(errors.New("no SPILLDIR variable set"))
+	}
+	f, err := os.CreateTemp(dir, "spill-*")
+	if err != nil {
+		die(fmt.Errorf("spill dir not writable: %s", err))
+	}
+	f.Close()
+	os.Remove(f.Name())
+}
+
 func main() {
 	if len(os.Args) < 2 || os.Args[1] != "worker" {
 		die(errors.New("expected to run in worker mode"))
@@ -138,6 +153,7 @@ func main() {
 	if cachedir == "" {
 		die(errors.New("no CACHEDIR variable set"))
 	}
+	testSpillDirOK()
 
 	defer uc.Close()
 	env := Env{eventfd: evfd}
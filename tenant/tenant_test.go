@@ -232,6 +232,7 @@ func TestExec(t *testing.T) {
 	// test that sandboxing works
 	m.Sandbox = CanSandbox()
 	m.CacheDir = t.TempDir()
+	m.SpillDir = t.TempDir()
 
 	nfds := func() int {
 		dirents, err := os.ReadDir("/proc/self/fd")
@@ -625,6 +626,7 @@ func BenchmarkSendPlan(b *testing.B) {
 	// test that sandboxing works
 	m.Sandbox = CanSandbox()
 	m.CacheDir = b.TempDir()
+	m.SpillDir = b.TempDir()
 	defer m.Stop()
 	blocks := []int{
 		1, 100, 10000, 100000,
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/ion/blockfmt"
 	"github.com/SnellerInc/sneller/plan"
 	"github.com/SnellerInc/sneller/usock"
@@ -143,3 +144,45 @@ func TestDirectExecHugeBody(t *testing.T) {
 	p.Close()
 	outerwg.Wait()
 }
+
+func TestProgress(t *testing.T) {
+	here, there, err := usock.SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer here.Close()
+	defer there.Close()
+
+	var stats plan.ExecStats
+	stats.BytesScanned = 1234
+	stop := reportProgress(there, &stats)
+	defer stop()
+
+	var buf [64]byte
+	here.SetReadDeadline(time.Now().Add(5 * progressInterval))
+	n, err := here.Read(buf[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got plan.ExecStats
+	ok, err := DecodeProgress(buf[:n], &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("%x is not a progress report", buf[:n])
+	}
+	if got != stats {
+		t.Fatalf("got %+v, want %+v", got, stats)
+	}
+	stop()
+
+	// the final status is not a progress report
+	var final plan.ExecStats
+	var out ion.Buffer
+	stats.Marshal(&out)
+	ok, err = DecodeProgress(out.Bytes(), &final)
+	if ok || err != nil {
+		t.Fatalf("final status decoded as progress: %v %v", ok, err)
+	}
+}
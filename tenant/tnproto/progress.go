@@ -0,0 +1,154 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tnproto
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/tracing"
+)
+
+// progressInterval is the interval at which
+// a tenant reports the statistics of a query
+// started with DirectExec while it is running
+const progressInterval = time.Second
+
+// progress reports are written to the error pipe
+// ahead of the final query status as
+//
+//	progress::{hits: ..., misses: ..., scanned: ...}
+//
+// using a static symbol table, just like the
+// final statistics written on success
+//
+// When a query is traced, the spans recorded by
+// the tenant are written ahead of the final status as
+//
+//	spans::[{trace_id: ..., span_id: ..., ...}, ...]
+var progressSymtab ion.Symtab
+
+func init() {
+	for _, s := range []string{
+		"progress",
+		"hits",
+		"misses",
+		"scanned",
+		"pruned",
+		"spans",
+	} {
+		progressSymtab.Intern(s)
+	}
+	for _, s := range tracing.Symbols {
+		progressSymtab.Intern(s)
+	}
+}
+
+func encodeProgress(dst *ion.Buffer, stats *plan.ExecStats) {
+	dst.BeginAnnotation(1)
+	dst.BeginField(progressSymtab.Intern("progress"))
+	stats.Encode(dst, &progressSymtab)
+	dst.EndAnnotation()
+}
+
+// DecodeProgress decodes a message read from the
+// error pipe returned by DirectExec into stats
+// if it is a progress report. DecodeProgress
+// returns false if msg is not a progress report,
+// in which case it is the final status of the query.
+func DecodeProgress(msg []byte, stats *plan.ExecStats) (bool, error) {
+	if ion.TypeOf(msg) != ion.AnnotationType {
+		return false, nil
+	}
+	sym, body, _, err := ion.ReadAnnotation(msg)
+	if err != nil {
+		return false, err
+	}
+	if progressSymtab.Get(sym) != "progress" {
+		return false, nil
+	}
+	return true, stats.Decode(body, &progressSymtab)
+}
+
+func encodeSpans(dst *ion.Buffer, spans []tracing.SpanData) {
+	dst.BeginAnnotation(1)
+	dst.BeginField(progressSymtab.Intern("spans"))
+	tracing.Encode(dst, &progressSymtab, spans)
+	dst.EndAnnotation()
+}
+
+// DecodeSpans decodes a message read from the
+// error pipe returned by DirectExec if it
+// contains the spans recorded by the tenant
+// while executing a traced query. DecodeSpans
+// returns false if msg does not contain spans.
+func DecodeSpans(msg []byte) (bool, []tracing.SpanData, error) {
+	if ion.TypeOf(msg) != ion.AnnotationType {
+		return false, nil, nil
+	}
+	sym, body, _, err := ion.ReadAnnotation(msg)
+	if err != nil {
+		return false, nil, err
+	}
+	if progressSymtab.Get(sym) != "spans" {
+		return false, nil, nil
+	}
+	spans, err := tracing.Decode(&progressSymtab, body)
+	return true, spans, err
+}
+
+// reportProgress writes the statistics of a query
+// into errpipe every progressInterval until the
+// returned function is called
+//
+// A report is always written in full, since the final
+// status follows it on the same pipe; if the reader isn't
+// keeping up, the ticks that pass while a report is being
+// written are dropped, and the returned function waits
+// for the report to be written before it returns.
+func reportProgress(errpipe io.Writer, stats *plan.ExecStats) func() {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		t := time.NewTicker(progressInterval)
+		defer t.Stop()
+		var buf ion.Buffer
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			cur := stats.Load()
+			buf.Reset()
+			encodeProgress(&buf, &cur)
+			_, err := errpipe.Write(buf.Bytes())
+			if err != nil {
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+		})
+	}
+}
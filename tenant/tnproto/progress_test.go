@@ -0,0 +1,88 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tnproto
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/plan"
+)
+
+// TestProgressSlowReader checks that a reader
+// that stalls part way through a progress report
+// still reads whole reports followed by the final status
+func TestProgressSlowReader(t *testing.T) {
+	r, w := net.Pipe()
+	defer r.Close()
+
+	var stats plan.ExecStats
+	stats.BytesScanned = 12345
+	stop := reportProgress(w, &stats)
+
+	// read the start of the first report
+	// and then stall for longer than
+	// the reporting interval
+	var first [1]byte
+	if _, err := io.ReadFull(r, first[:]); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(progressInterval + progressInterval/2)
+
+	go func() {
+		stop()
+		var final ion.Buffer
+		final.WriteString("done")
+		w.Write(final.Bytes())
+		w.Close()
+	}()
+
+	rd := bufio.NewReader(io.MultiReader(bytes.NewReader(first[:]), r))
+	reports := 0
+	for {
+		_, size, err := ion.Peek(rd)
+		if err != nil {
+			t.Fatalf("after %d reports: %s", reports, err)
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(rd, msg); err != nil {
+			t.Fatal(err)
+		}
+		var cur plan.ExecStats
+		ok, err := DecodeProgress(msg, &cur)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			str, _, err := ion.ReadString(msg)
+			if err != nil || str != "done" {
+				t.Fatalf("unexpected final status %x", msg)
+			}
+			break
+		}
+		if cur.BytesScanned != stats.BytesScanned {
+			t.Fatalf("report %d: scanned %d", reports, cur.BytesScanned)
+		}
+		reports++
+	}
+	if reports == 0 {
+		t.Fatal("no progress reports")
+	}
+}
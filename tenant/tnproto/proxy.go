@@ -29,7 +29,9 @@ import (
 
 	"github.com/SnellerInc/sneller/ion"
 	"github.com/SnellerInc/sneller/plan"
+	"github.com/SnellerInc/sneller/tracing"
 	"github.com/SnellerInc/sneller/usock"
+	"github.com/SnellerInc/sneller/vm"
 )
 
 // OutputFormat selects an output format
@@ -430,6 +432,24 @@ func (s *Server) serveDirect(t *plan.Tree, conn io.WriteCloser, errpipe net.Conn
 	defer errpipe.Close() // cancels ctx
 	ctx := pipectx(errpipe)
 
+	var rec *tracing.Recorder
+	var span *tracing.Span
+	if t.Trace.Sampled() {
+		rec = new(tracing.Recorder)
+		ctx, span = tracing.Root(ctx, "tenant execute", t.Trace, rec)
+		span.SetAttr("query_id", t.ID)
+	}
+
+	pl := plan.LocalTransport{}
+	ep := plan.ExecParams{
+		Plan:    t,
+		Output:  conn,
+		Context: ctx,
+		Runner:  s.Runner,
+		Memory:  vm.NewBudget(s.MemoryLimit),
+	}
+	stop := reportProgress(errpipe, &ep.Stats)
+
 	// if we encounter a panic, we don't
 	// want to close the errpipe with no output;
 	// instead, just write a notification
@@ -438,6 +458,7 @@ func (s *Server) serveDirect(t *plan.Tree, conn io.WriteCloser, errpipe net.Conn
 	var outbuf ion.Buffer
 	defer func() {
 		if e := recover(); e != nil {
+			stop()
 			conn.Close()
 			outbuf.Reset()
 			outbuf.WriteString("panic!")
@@ -446,16 +467,10 @@ func (s *Server) serveDirect(t *plan.Tree, conn io.WriteCloser, errpipe net.Conn
 			panic(e)
 		}
 	}()
-	pl := plan.LocalTransport{}
-	ep := plan.ExecParams{
-		Plan:    t,
-		Output:  conn,
-		Context: ctx,
-		Runner:  s.Runner,
-	}
 	if s.InitFS != nil && !t.Data.IsEmpty() {
 		fs, err := s.InitFS(t.Data)
 		if err != nil {
+			stop()
 			sendError(conn, err)
 			conn.Close()
 			return
@@ -463,12 +478,21 @@ func (s *Server) serveDirect(t *plan.Tree, conn io.WriteCloser, errpipe net.Conn
 		ep.FS = fs
 	}
 	err := pl.Exec(&ep)
+	stop()
 	if err != nil {
 		sendError(conn, err)
 	}
 	// must close the connection before
 	// indicating the query status to the caller
 	conn.Close()
+	if span != nil {
+		span.SetAttr("bytes_scanned", ep.Stats.BytesScanned)
+		span.Fail(err)
+		span.End()
+		encodeSpans(&outbuf, rec.Take())
+		errpipe.Write(outbuf.Bytes())
+		outbuf.Reset()
+	}
 	if err != nil {
 		outbuf.WriteString(err.Error())
 	} else {
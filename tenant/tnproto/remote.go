@@ -111,6 +111,9 @@ var clientPool = sync.Pool{
 	},
 }
 
+// String returns the address of the remote tenant.
+func (r *Remote) String() string { return r.Addr }
+
 // Exec implements plan.Transport.Exec
 // by dialing the address given by r.Net and r.Addr
 // and sending it an Attach message, followed
@@ -0,0 +1,235 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Exporter exports spans.
+type Exporter interface {
+	Export(spans []SpanData) error
+}
+
+// the OTLP trace data model, as it is encoded in
+// JSON (see opentelemetry-proto/.../trace/v1/trace.proto);
+// IDs are hex-encoded and 64-bit integers are strings
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttr `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID      string     `json:"traceId"`
+	SpanID       string     `json:"spanId"`
+	ParentSpanID string     `json:"parentSpanId,omitempty"`
+	Name         string     `json:"name"`
+	Kind         int        `json:"kind"`
+	Start        string     `json:"startTimeUnixNano"`
+	End          string     `json:"endTimeUnixNano"`
+	Attributes   []otlpAttr `json:"attributes,omitempty"`
+	Status       otlpStatus `json:"status"`
+}
+
+type otlpAttr struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	String *string  `json:"stringValue,omitempty"`
+	Int    *string  `json:"intValue,omitempty"`
+	Double *float64 `json:"doubleValue,omitempty"`
+	Bool   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	otlpKindInternal = 1
+	otlpStatusError  = 2
+)
+
+func otlpValueOf(v any) otlpValue {
+	switch v := v.(type) {
+	case string:
+		return otlpValue{String: &v}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return otlpValue{Int: &s}
+	case float64:
+		return otlpValue{Double: &v}
+	case bool:
+		return otlpValue{Bool: &v}
+	default:
+		s := fmt.Sprint(v)
+		return otlpValue{String: &s}
+	}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// MarshalOTLP encodes spans as an OTLP
+// ExportTraceServiceRequest in JSON, with the
+// given service name as the resource service.name.
+func MarshalOTLP(service string, spans []SpanData) ([]byte, error) {
+	out := make([]otlpSpan, len(spans))
+	for i := range spans {
+		s := &spans[i]
+		o := &out[i]
+		o.TraceID = s.TraceID.String()
+		o.SpanID = s.SpanID.String()
+		if s.Parent != (SpanID{}) {
+			o.ParentSpanID = s.Parent.String()
+		}
+		o.Name = s.Name
+		o.Kind = otlpKindInternal
+		o.Start = unixNano(s.Start)
+		o.End = unixNano(s.End)
+		for j := range s.Attrs {
+			o.Attributes = append(o.Attributes, otlpAttr{
+				Key:   s.Attrs[j].Key,
+				Value: otlpValueOf(s.Attrs[j].Value),
+			})
+		}
+		if s.Error != "" {
+			o.Status = otlpStatus{Code: otlpStatusError, Message: s.Error}
+		}
+	}
+	req := otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttr{{Key: "service.name", Value: otlpValueOf(service)}},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/SnellerInc/sneller"},
+				Spans: out,
+			}},
+		}},
+	}
+	return json.Marshal(&req)
+}
+
+// FileExporter is an Exporter that writes
+// each batch of spans to W as one line of
+// OTLP JSON, which is the format read by
+// the OpenTelemetry Collector's otlpjsonfile receiver.
+type FileExporter struct {
+	// Service is the service.name
+	// of the exported spans.
+	Service string
+
+	lock sync.Mutex
+	W    io.Writer
+}
+
+// Export implements Exporter.Export
+func (f *FileExporter) Export(spans []SpanData) error {
+	buf, err := MarshalOTLP(f.Service, spans)
+	if err != nil {
+		return err
+	}
+	buf = append(buf, '\n')
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	_, err = f.W.Write(buf)
+	return err
+}
+
+// HTTPExporter is an Exporter that sends
+// spans to an OTLP/HTTP endpoint using
+// the JSON encoding.
+type HTTPExporter struct {
+	// Service is the service.name
+	// of the exported spans.
+	Service string
+	// URL is the URL of the traces endpoint
+	// (typically http://collector:4318/v1/traces).
+	URL string
+	// Client is the client used to send spans;
+	// http.DefaultClient is used if it is nil.
+	Client *http.Client
+}
+
+// Export implements Exporter.Export
+func (h *HTTPExporter) Export(spans []SpanData) error {
+	buf, err := MarshalOTLP(h.Service, spans)
+	if err != nil {
+		return err
+	}
+	cl := h.Client
+	if cl == nil {
+		cl = http.DefaultClient
+	}
+	res, err := cl.Post(h.URL, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("exporting spans to %s: %s", h.URL, res.Status)
+	}
+	return nil
+}
+
+// TracesURL returns the URL of the OTLP/HTTP traces
+// endpoint for endpoint, which is either the URL of
+// the traces endpoint itself or the base URL of
+// the collector (in which case /v1/traces is appended).
+func TracesURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("tracing: unsupported endpoint %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
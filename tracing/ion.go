@@ -0,0 +1,190 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// Symbols are the symbols used by Encode.
+//
+// Spans are typically sent along with query
+// statistics that are encoded with a static
+// symbol table, so these symbols must be
+// interned in that symbol table ahead of time.
+var Symbols = []string{
+	"trace_id",
+	"span_id",
+	"parent_id",
+	"name",
+	"start",
+	"end",
+	"attrs",
+	"key",
+	"value",
+	"error",
+}
+
+// Encode encodes a list of spans into dst.
+func Encode(dst *ion.Buffer, st *ion.Symtab, spans []SpanData) {
+	dst.BeginList(-1)
+	for i := range spans {
+		s := &spans[i]
+		dst.BeginStruct(-1)
+		dst.BeginField(st.Intern("trace_id"))
+		dst.WriteBlob(s.TraceID[:])
+		dst.BeginField(st.Intern("span_id"))
+		dst.WriteBlob(s.SpanID[:])
+		if s.Parent != (SpanID{}) {
+			dst.BeginField(st.Intern("parent_id"))
+			dst.WriteBlob(s.Parent[:])
+		}
+		dst.BeginField(st.Intern("name"))
+		dst.WriteString(s.Name)
+		dst.BeginField(st.Intern("start"))
+		dst.WriteInt(s.Start.UnixNano())
+		dst.BeginField(st.Intern("end"))
+		dst.WriteInt(s.End.UnixNano())
+		if len(s.Attrs) > 0 {
+			dst.BeginField(st.Intern("attrs"))
+			dst.BeginList(-1)
+			for j := range s.Attrs {
+				dst.BeginStruct(-1)
+				dst.BeginField(st.Intern("key"))
+				dst.WriteString(s.Attrs[j].Key)
+				dst.BeginField(st.Intern("value"))
+				switch v := s.Attrs[j].Value.(type) {
+				case string:
+					dst.WriteString(v)
+				case int64:
+					dst.WriteInt(v)
+				case float64:
+					dst.WriteFloat64(v)
+				case bool:
+					dst.WriteBool(v)
+				default:
+					dst.WriteString(fmt.Sprint(v))
+				}
+				dst.EndStruct()
+			}
+			dst.EndList()
+		}
+		if s.Error != "" {
+			dst.BeginField(st.Intern("error"))
+			dst.WriteString(s.Error)
+		}
+		dst.EndStruct()
+	}
+	dst.EndList()
+}
+
+func readID(dst []byte, body []byte) error {
+	buf, _, err := ion.ReadBytesShared(body)
+	if err != nil {
+		return err
+	}
+	if len(buf) != len(dst) {
+		return fmt.Errorf("unexpected ID length %d", len(buf))
+	}
+	copy(dst, buf)
+	return nil
+}
+
+func readTime(body []byte) (time.Time, error) {
+	n, _, err := ion.ReadInt(body)
+	return time.Unix(0, n), err
+}
+
+func decodeAttr(st *ion.Symtab, body []byte) (Attr, error) {
+	var a Attr
+	_, err := ion.UnpackStruct(st, body, func(name string, body []byte) error {
+		var err error
+		switch name {
+		case "key":
+			a.Key, _, err = ion.ReadString(body)
+		case "value":
+			switch ion.TypeOf(body) {
+			case ion.StringType:
+				a.Value, _, err = ion.ReadString(body)
+			case ion.IntType, ion.UintType:
+				a.Value, _, err = ion.ReadInt(body)
+			case ion.FloatType:
+				a.Value, _, err = ion.ReadFloat64(body)
+			case ion.BoolType:
+				a.Value, _, err = ion.ReadBool(body)
+			default:
+				err = fmt.Errorf("unexpected attribute type %s", ion.TypeOf(body))
+			}
+		default:
+			err = fmt.Errorf("unexpected field %q", name)
+		}
+		return err
+	})
+	return a, err
+}
+
+func decodeSpan(st *ion.Symtab, body []byte) (SpanData, error) {
+	var s SpanData
+	_, err := ion.UnpackStruct(st, body, func(name string, body []byte) error {
+		var err error
+		switch name {
+		case "trace_id":
+			err = readID(s.TraceID[:], body)
+		case "span_id":
+			err = readID(s.SpanID[:], body)
+		case "parent_id":
+			err = readID(s.Parent[:], body)
+		case "name":
+			s.Name, _, err = ion.ReadString(body)
+		case "start":
+			s.Start, err = readTime(body)
+		case "end":
+			s.End, err = readTime(body)
+		case "attrs":
+			_, err = ion.UnpackList(body, func(body []byte) error {
+				a, err := decodeAttr(st, body)
+				if err == nil {
+					s.Attrs = append(s.Attrs, a)
+				}
+				return err
+			})
+		case "error":
+			s.Error, _, err = ion.ReadString(body)
+		default:
+			err = fmt.Errorf("unexpected field %q", name)
+		}
+		return err
+	})
+	return s, err
+}
+
+// Decode decodes a list of spans encoded with Encode.
+func Decode(st *ion.Symtab, body []byte) ([]SpanData, error) {
+	var spans []SpanData
+	_, err := ion.UnpackList(body, func(body []byte) error {
+		s, err := decodeSpan(st, body)
+		if err == nil {
+			spans = append(spans, s)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("tracing.Decode: %w", err)
+	}
+	return spans, nil
+}
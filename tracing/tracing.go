@@ -0,0 +1,321 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing implements distributed tracing
+// of queries in a way that is compatible with
+// OpenTelemetry.
+//
+// Trace context is propagated between processes
+// in the W3C traceparent format (see SpanContext).
+// Rather than exporting spans from every process,
+// each process records the spans of a query in a
+// Recorder, and the spans are sent back to the caller
+// along with the final statistics of the query, so
+// that the process that started the trace can export
+// all of its spans at once (see Exporter).
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TraceID is the ID of a trace
+type TraceID [16]byte
+
+// SpanID is the ID of a span within a trace
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// FlagSampled is the trace flag
+// indicating that a trace is recorded
+const FlagSampled = 0x01
+
+// SpanContext identifies a span
+// across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Flags are the W3C trace flags.
+	Flags byte
+}
+
+// IsValid returns whether s identifies a span.
+func (s SpanContext) IsValid() bool {
+	return s.TraceID != TraceID{} && s.SpanID != SpanID{}
+}
+
+// Sampled returns whether the trace
+// that s belongs to is recorded.
+func (s SpanContext) Sampled() bool {
+	return s.IsValid() && s.Flags&FlagSampled != 0
+}
+
+// String returns s in the format
+// of the traceparent HTTP header.
+func (s SpanContext) String() string {
+	return fmt.Sprintf("00-%s-%s-%02x", s.TraceID, s.SpanID, s.Flags)
+}
+
+func unhex(dst []byte, src string) bool {
+	if hex.EncodedLen(len(dst)) != len(src) {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(src))
+	return err == nil
+}
+
+// ParseTraceparent parses the value of
+// a W3C traceparent HTTP header.
+func ParseTraceparent(str string) (SpanContext, error) {
+	var sc SpanContext
+	var version, flags [1]byte
+	// version-traceid-spanid-flags
+	if len(str) < 55 || str[2] != '-' || str[35] != '-' || str[52] != '-' ||
+		!unhex(version[:], str[:2]) || version[0] == 0xff ||
+		(version[0] == 0 && len(str) != 55) || (len(str) > 55 && str[55] != '-') ||
+		!unhex(sc.TraceID[:], str[3:35]) ||
+		!unhex(sc.SpanID[:], str[36:52]) ||
+		!unhex(flags[:], str[53:55]) {
+		return SpanContext{}, fmt.Errorf("tracing: invalid traceparent %q", str)
+	}
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("tracing: invalid traceparent %q", str)
+	}
+	return sc, nil
+}
+
+// Attr is an attribute of a span.
+// The value is a string, int64, float64 or bool.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// SpanData is a span that has ended.
+type SpanData struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Parent is the ID of the parent span;
+	// it is zero for the root span of a trace
+	Parent     SpanID
+	Name       string
+	Start, End time.Time
+	Attrs      []Attr
+	// Error is the text of the error
+	// that caused the span to fail, if any
+	Error string
+}
+
+// Recorder collects the spans that
+// have ended in one process.
+// The zero value of Recorder is ready to use.
+type Recorder struct {
+	lock  sync.Mutex
+	spans []SpanData
+}
+
+// Add adds spans to r.
+func (r *Recorder) Add(spans ...SpanData) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.spans = append(r.spans, spans...)
+}
+
+// Take returns the spans recorded
+// so far and removes them from r.
+func (r *Recorder) Take() []SpanData {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	spans := r.spans
+	r.spans = nil
+	return spans
+}
+
+// Span is a span that is in progress.
+//
+// The methods of Span may be called
+// on a nil *Span, in which case they do nothing,
+// so callers do not need to check whether
+// or not a query is being traced.
+type Span struct {
+	rec   *Recorder
+	flags byte
+
+	lock  sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func randomID(dst []byte) {
+	if _, err := rand.Read(dst); err != nil {
+		panic(err)
+	}
+}
+
+func newSpan(rec *Recorder, trace TraceID, parent SpanID, flags byte, name string) *Span {
+	s := &Span{
+		rec:   rec,
+		flags: flags,
+		data: SpanData{
+			TraceID: trace,
+			Parent:  parent,
+			Name:    name,
+			Start:   time.Now(),
+		},
+	}
+	randomID(s.data.SpanID[:])
+	return s
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx
+// in which s is the current span.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// FromContext returns the current span
+// of ctx, or nil if ctx is not being traced.
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Root starts a span that is recorded in rec.
+// If parent is valid, then the span is a child
+// of the (remote) span that parent identifies;
+// otherwise, the span is the root of a new trace.
+func Root(ctx context.Context, name string, parent SpanContext, rec *Recorder) (context.Context, *Span) {
+	var s *Span
+	if parent.IsValid() {
+		s = newSpan(rec, parent.TraceID, parent.SpanID, parent.Flags|FlagSampled, name)
+	} else {
+		var trace TraceID
+		randomID(trace[:])
+		s = newSpan(rec, trace, SpanID{}, FlagSampled, name)
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+// Start starts a span that is a child of
+// the current span of ctx. If ctx is not
+// being traced, then Start returns ctx
+// and a nil *Span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	s := newSpan(parent.rec, parent.data.TraceID, parent.data.SpanID, parent.flags, name)
+	return ContextWithSpan(ctx, s), s
+}
+
+// Import adds spans that were recorded
+// in another process to the recorder of
+// the current span of ctx, if there is one.
+func Import(ctx context.Context, spans []SpanData) {
+	if s := FromContext(ctx); s != nil && len(spans) > 0 {
+		s.rec.Add(spans...)
+	}
+}
+
+// Context returns the SpanContext
+// that identifies s in other processes.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID, Flags: s.flags}
+}
+
+// Recorder returns the recorder
+// in which s is recorded.
+func (s *Span) Recorder() *Recorder {
+	if s == nil {
+		return nil
+	}
+	return s.rec
+}
+
+// SetAttr sets an attribute of s.
+// The value must be a string, an integer,
+// a float64 or a bool; integers are
+// converted to int64.
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	switch v := value.(type) {
+	case int:
+		value = int64(v)
+	case int32:
+		value = int64(v)
+	case uint32:
+		value = int64(v)
+	case uint64:
+		value = int64(v)
+	case string, int64, float64, bool:
+	default:
+		value = fmt.Sprint(v)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := range s.data.Attrs {
+		if s.data.Attrs[i].Key == key {
+			s.data.Attrs[i].Value = value
+			return
+		}
+	}
+	s.data.Attrs = append(s.data.Attrs, Attr{Key: key, Value: value})
+}
+
+// Fail marks s as failed if err is non-nil.
+func (s *Span) Fail(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data.Error = err.Error()
+}
+
+// End ends s and adds it to its recorder.
+// Calls to End after the first one do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.lock.Unlock()
+	s.rec.Add(data)
+}
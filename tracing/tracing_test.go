@@ -0,0 +1,255 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+func TestTraceparent(t *testing.T) {
+	const text = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Sampled() || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	if got := sc.String(); got != text {
+		t.Errorf("round-trip: got %q", got)
+	}
+	sc, err = ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	if err != nil || sc.Sampled() {
+		t.Errorf("unsampled: %+v %v", sc, err)
+	}
+	// future versions may append fields
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-xyz"); err != nil {
+		t.Error(err)
+	}
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	// nothing is recorded without a trace
+	ctx, span := Start(context.Background(), "untraced")
+	if span != nil || FromContext(ctx) != nil {
+		t.Fatal("span started without a trace")
+	}
+	span.SetAttr("key", "value")
+	span.Fail(errors.New("error"))
+	span.End()
+	if span.Context().IsValid() || span.Recorder() != nil {
+		t.Fatal("nil span has a context")
+	}
+
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := new(Recorder)
+	ctx, root := Root(context.Background(), "root", parent, rec)
+	cctx, child := Start(ctx, "child")
+	child.SetAttr("n", 3)
+	child.SetAttr("n", 4)
+	child.SetAttr("ok", true)
+	_, grandchild := Start(cctx, "grandchild")
+	grandchild.Fail(errors.New("failed"))
+	grandchild.End()
+	child.End()
+	child.End() // no-op
+	Import(ctx, []SpanData{{Name: "imported"}})
+	root.End()
+
+	spans := rec.Take()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	if len(rec.Take()) != 0 {
+		t.Fatal("spans not removed from recorder")
+	}
+	byName := make(map[string]*SpanData)
+	for i := range spans {
+		byName[spans[i].Name] = &spans[i]
+	}
+	r, c, g := byName["root"], byName["child"], byName["grandchild"]
+	if r.TraceID != parent.TraceID || r.Parent != parent.SpanID {
+		t.Errorf("root %+v is not a child of %+v", r, parent)
+	}
+	if c.TraceID != parent.TraceID || c.Parent != r.SpanID {
+		t.Errorf("child %+v is not a child of the root", c)
+	}
+	if g.Parent != c.SpanID || g.Error != "failed" {
+		t.Errorf("unexpected grandchild %+v", g)
+	}
+	want := []Attr{{"n", int64(4)}, {"ok", true}}
+	if !reflect.DeepEqual(c.Attrs, want) {
+		t.Errorf("attrs %v", c.Attrs)
+	}
+	if c.End.Before(c.Start) || r.End.Before(c.End) {
+		t.Error("unexpected span times")
+	}
+
+	// a new trace
+	_, root = Root(context.Background(), "root", SpanContext{}, rec)
+	if !root.Context().Sampled() || root.Context().TraceID == parent.TraceID {
+		t.Errorf("unexpected new trace %+v", root.Context())
+	}
+}
+
+func testSpans() []SpanData {
+	start := time.Unix(1700000000, 123456789)
+	return []SpanData{{
+		TraceID: TraceID{1, 2, 3},
+		SpanID:  SpanID{4, 5, 6},
+		Name:    "root",
+		Start:   start,
+		End:     start.Add(time.Second),
+		Attrs: []Attr{
+			{"str", "value"},
+			{"int", int64(-5)},
+			{"float", 1.5},
+			{"bool", true},
+		},
+	}, {
+		TraceID: TraceID{1, 2, 3},
+		SpanID:  SpanID{7, 8, 9},
+		Parent:  SpanID{4, 5, 6},
+		Name:    "child",
+		Start:   start,
+		End:     start.Add(time.Millisecond),
+		Error:   "oops",
+	}}
+}
+
+func TestEncode(t *testing.T) {
+	spans := testSpans()
+	var st ion.Symtab
+	for _, s := range Symbols {
+		st.Intern(s)
+	}
+	var buf ion.Buffer
+	Encode(&buf, &st, spans)
+	before := st.MaxID()
+	out, err := Decode(&st, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.MaxID() != before {
+		t.Error("Encode interned new symbols")
+	}
+	for i := range spans {
+		if !spans[i].Start.Equal(out[i].Start) || !spans[i].End.Equal(out[i].End) {
+			t.Errorf("span %d: times %s %s", i, out[i].Start, out[i].End)
+		}
+		out[i].Start, out[i].End = spans[i].Start, spans[i].End
+	}
+	if !reflect.DeepEqual(spans, out) {
+		t.Errorf("got %+v", out)
+	}
+}
+
+func TestExport(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	u, err := TracesURL(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &HTTPExporter{Service: "test", URL: u}
+	if err := h.Export(testSpans()); err != nil {
+		t.Fatal(err)
+	}
+	var req otlpRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	rs := req.ResourceSpans[0]
+	if *rs.Resource.Attributes[0].Value.String != "test" {
+		t.Errorf("unexpected resource %+v", rs.Resource)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	root, child := &spans[0], &spans[1]
+	if root.TraceID != "01020300000000000000000000000000" || root.SpanID != "0405060000000000" ||
+		root.ParentSpanID != "" || root.Start != "1700000000123456789" || root.End != "1700000001123456789" {
+		t.Errorf("unexpected root %+v", root)
+	}
+	if *root.Attributes[1].Value.Int != "-5" || *root.Attributes[2].Value.Double != 1.5 ||
+		!*root.Attributes[3].Value.Bool {
+		t.Errorf("unexpected attributes %+v", root.Attributes)
+	}
+	if child.ParentSpanID != root.SpanID || child.Status.Code != otlpStatusError || child.Status.Message != "oops" {
+		t.Errorf("unexpected child %+v", child)
+	}
+
+	h.URL = srv.URL + "/other"
+	if err := h.Export(testSpans()); err == nil {
+		t.Error("expected an error")
+	}
+
+	var out bytes.Buffer
+	f := &FileExporter{Service: "test", W: &out}
+	f.Export(testSpans())
+	f.Export(testSpans())
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != string(body) {
+		t.Errorf("unexpected file output %q", out.String())
+	}
+
+	for in, want := range map[string]string{
+		"http://collector:4318":            "http://collector:4318/v1/traces",
+		"https://collector/":               "https://collector/v1/traces",
+		"http://collector:4318/custom/url": "http://collector:4318/custom/url",
+	} {
+		if got, err := TracesURL(in); err != nil || got != want {
+			t.Errorf("TracesURL(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := TracesURL("/var/log/traces.json"); err == nil {
+		t.Error("expected an error for a file name")
+	}
+}
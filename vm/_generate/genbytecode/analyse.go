@@ -649,6 +649,7 @@ type registerContent struct {
 var ignoredMacrosList = []string{
 	"BC_AGGREGATE_SLOT_COUNT_OP",
 	"BC_AGGREGATE_SLOT_MARK_OP",
+	"BC_AGGSLOT_LANES",
 	"BC_ALLOC_SLICE",
 	"BC_ARITH_OP_F64_IMM_IMPL",
 	"BC_ARITH_OP_F64_IMM_IMPL_K",
@@ -666,6 +667,11 @@ var ignoredMacrosList = []string{
 	"BC_CMP_OP_F64_IMM",
 	"BC_CMP_OP_I64",
 	"BC_CMP_OP_I64_IMM",
+	"BC_COMOMENTS_GATHER",
+	"BC_COMOMENTS_LOAD",
+	"BC_COMOMENTS_SCATTER",
+	"BC_COMOMENTS_STORE",
+	"BC_COMOMENTS_UPDATE",
 	"BC_COMPOSE_YEAR_TO_DAYS",
 	"BC_DECOMPOSE_TIMESTAMP_PARTS",
 	"BC_DIV_FLOOR_I64VEC_BY_U64IMM",
@@ -691,6 +697,12 @@ var ignoredMacrosList = []string{
 	"BC_MOD_TRUNC_F64",
 	"BC_MODI64_IMPL",
 	"BC_MOD_U32_RCP_2X_MASKED",
+	"BC_MOMENTS_CONSTANTS",
+	"BC_MOMENTS_GATHER",
+	"BC_MOMENTS_LOAD",
+	"BC_MOMENTS_SCATTER",
+	"BC_MOMENTS_STORE",
+	"BC_MOMENTS_UPDATE",
 	"BC_NEUMAIER_SUM",
 	"BC_NEUMAIER_SUM_LANE",
 	"BC_POWINT",
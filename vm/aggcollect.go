@@ -0,0 +1,1162 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// CollectAggregate is a QuerySink that computes
+// the aggregates that collect their input values
+// (ARRAY_AGG, STRING_AGG, OBJECT_AGG, the
+// ordered-set aggregates PERCENTILE_CONT,
+// PERCENTILE_DISC and MODE, and APPROX_TOP_K),
+// optionally grouped by a list of expressions.
+// The simple aggregates accepted by CollectAccepts
+// can be computed alongside them.
+//
+// The input rows are projected by the VM;
+// the values are collected in Go.
+type CollectAggregate struct {
+	aggs      Aggregation
+	by        Selection
+	dst       QuerySink
+	proj      *Projection
+	skipEmpty bool
+
+	names []string       // projected field names
+	slots []collectSlots // aggregate inputs in names
+	order [][]SortOrdering
+
+	lock  sync.Mutex
+	final *collectGroups
+	rows  int64
+
+	budget *Budget
+	mem    memacct // charge for final
+}
+
+// collectSlots describes the positions of
+// the inputs of an aggregate in the projected row;
+// unused inputs are set to -1
+type collectSlots struct {
+	value  int
+	arg    int
+	filter int
+	order  []int
+}
+
+// CollectAccepts returns whether or not a CollectAggregate
+// can compute op, i.e. whether op is a collecting aggregate
+// (see expr.AggregateOp.Collects) or one of the simple
+// aggregates that it computes alongside them
+func CollectAccepts(op expr.AggregateOp) bool {
+	switch op {
+	case expr.OpCount, expr.OpSum, expr.OpSumInt, expr.OpSumCount,
+		expr.OpAvg, expr.OpMin, expr.OpMax:
+		return true
+	}
+	return op.Collects()
+}
+
+// NewCollectAggregate constructs a CollectAggregate
+// computing aggs grouped by the expressions in by
+// and writing the results into dst.
+// All the aggregates in aggs have to be accepted
+// by CollectAccepts.
+func NewCollectAggregate(aggs Aggregation, by Selection, dst QuerySink) (*CollectAggregate, error) {
+	c := &CollectAggregate{
+		aggs: aggs,
+		by:   by,
+		dst:  dst,
+	}
+	var sel Selection
+	bind := func(e expr.Node) int {
+		n := len(sel)
+		sel = append(sel, expr.Bind(e, fmt.Sprintf("$c%d", n)))
+		return n
+	}
+	for i := range by {
+		bind(by[i].Expr)
+	}
+	// collecting is the collecting aggregate
+	// that the other aggregates are mixed with
+	var collecting expr.AggregateOp
+	for i := range aggs {
+		if aggs[i].Expr.Op.Collects() {
+			collecting = aggs[i].Expr.Op
+			break
+		}
+	}
+	c.slots = make([]collectSlots, len(aggs))
+	c.order = make([][]SortOrdering, len(aggs))
+	for i := range aggs {
+		agg := aggs[i].Expr
+		if !CollectAccepts(agg.Op) {
+			return nil, fmt.Errorf("aggregate %s cannot be mixed with %s", agg.Op, collecting)
+		}
+		if agg.Over != nil {
+			return nil, fmt.Errorf("%s cannot be used as a window function", agg.Op)
+		}
+		s := &c.slots[i]
+		s.arg = -1
+		s.filter = -1
+		if agg.Op.OrderedSet() {
+			// the ordered values are the input
+			if len(agg.OrderBy) != 1 {
+				return nil, fmt.Errorf("%s: expected exactly one WITHIN GROUP ordering", agg.Op)
+			}
+			s.value = bind(agg.OrderBy[0].Column)
+		} else if _, ok := agg.Inner.(expr.Star); ok {
+			s.value = -1 // COUNT(*)
+		} else {
+			s.value = bind(agg.Inner)
+		}
+		switch agg.Op {
+		case expr.OpStringAgg:
+			if _, ok := agg.Arg.(expr.String); !ok {
+				return nil, fmt.Errorf("STRING_AGG: separator %s is not a constant string", expr.ToString(agg.Arg))
+			}
+		case expr.OpObjectAgg:
+			if agg.Role != expr.AggregateRoleMerge {
+				if agg.Arg == nil {
+					return nil, fmt.Errorf("OBJECT_AGG: missing value")
+				}
+				s.arg = bind(agg.Arg)
+			}
+		case expr.OpPercentileCont, expr.OpPercentileDisc:
+			p, ok := agg.Arg.(expr.Float)
+			if !ok || p < 0 || p > 1 {
+				return nil, fmt.Errorf("%s: percentile %s is not a constant in range [0.0, 1.0]", agg.Op, expr.ToString(agg.Arg))
+			}
+		case expr.OpApproxTopK:
+			k, ok := agg.Arg.(expr.Integer)
+			if !ok || k < 1 || k > expr.ApproxTopKMax {
+				return nil, fmt.Errorf("APPROX_TOP_K: k=%s is not a constant in range [1, %d]", expr.ToString(agg.Arg), expr.ApproxTopKMax)
+			}
+		}
+		if agg.Filter != nil {
+			s.filter = bind(expr.Is(agg.Filter, expr.IsTrue))
+		}
+		for j := range agg.OrderBy {
+			ordering := SortOrdering{
+				Direction:  SortAscending,
+				NullsOrder: SortNullsFirst,
+			}
+			if agg.OrderBy[j].Desc {
+				ordering.Direction = SortDescending
+			}
+			if agg.OrderBy[j].NullsLast {
+				ordering.NullsOrder = SortNullsLast
+			}
+			c.order[i] = append(c.order[i], ordering)
+			// when merging, the ordering keys
+			// are a part of the partial results;
+			// the ordered-set aggregates order
+			// the collected values themselves
+			if agg.Role != expr.AggregateRoleMerge && !agg.Op.OrderedSet() {
+				s.order = append(s.order, bind(agg.OrderBy[j].Column))
+			}
+		}
+	}
+	c.names = make([]string, len(sel))
+	for i := range sel {
+		c.names[i] = sel[i].Result()
+	}
+	var err error
+	c.proj, err = NewProjection(sel, (*collectInput)(c))
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetSkipEmpty configures the aggregate
+// to produce no output when there are no input rows.
+func (c *CollectAggregate) SetSkipEmpty(skip bool) {
+	c.skipEmpty = skip
+}
+
+// SetBudget sets the Budget to which the memory
+// occupied by the collected values is charged.
+func (c *CollectAggregate) SetBudget(b *Budget) {
+	c.budget = b
+	c.mem.budget = b
+}
+
+func (c *CollectAggregate) Open() (io.WriteCloser, error) {
+	return c.proj.Open()
+}
+
+func (c *CollectAggregate) Close() error {
+	defer c.mem.release()
+	err := c.proj.Close()
+	if err != nil {
+		return err
+	}
+	if c.skipEmpty && c.rows == 0 {
+		return flushEmpty(c.dst)
+	}
+	final := c.final
+	c.final = nil
+	if final == nil {
+		final = newCollectGroups()
+	}
+	if len(c.by) == 0 && len(final.groups) == 0 {
+		final.groups = append(final.groups, collectGroup{
+			state: make([]collectState, len(c.aggs)),
+		})
+	}
+
+	var st ion.Symtab
+	var outbuf ion.Buffer
+	var data ion.Buffer
+	bysyms := make([]ion.Symbol, len(c.by))
+	for i := range c.by {
+		bysyms[i] = st.Intern(c.by[i].Result())
+	}
+	aggsyms := make([]ion.Symbol, len(c.aggs))
+	for i := range c.aggs {
+		aggsyms[i] = st.Intern(c.aggs[i].Result)
+	}
+	for i := range final.groups {
+		g := &final.groups[i]
+		data.BeginStruct(-1)
+		for j, sym := range bysyms {
+			data.BeginField(sym)
+			g.key[j].Encode(&data, &st)
+		}
+		for j, sym := range aggsyms {
+			data.BeginField(sym)
+			c.write(j, &g.state[j], &data, &st)
+		}
+		data.EndStruct()
+	}
+	st.Marshal(&outbuf, true)
+	outbuf.UnsafeAppend(data.Bytes())
+	return writeIon(&outbuf, c.dst)
+}
+
+// write outputs the state of the i'th aggregate
+func (c *CollectAggregate) write(i int, s *collectState, dst *ion.Buffer, st *ion.Symtab) {
+	agg := c.aggs[i].Expr
+	switch agg.Op {
+	case expr.OpArrayAgg:
+		if len(s.items) == 0 {
+			dst.WriteNull()
+			return
+		}
+		c.trim(i, s, true)
+		keys := len(c.order[i]) > 0 && agg.Role == expr.AggregateRolePartial
+		dst.BeginList(-1)
+		for j := range s.items {
+			if keys {
+				// partial results carry the ordering keys,
+				// so that the merging aggregate can sort
+				// the values from all the partial results
+				dst.BeginList(-1)
+				s.items[j].value.Encode(dst, st)
+				dst.UnsafeAppend(s.items[j].order)
+				dst.EndList()
+			} else {
+				s.items[j].value.Encode(dst, st)
+			}
+		}
+		dst.EndList()
+	case expr.OpStringAgg:
+		if !s.valid {
+			dst.WriteNull()
+			return
+		}
+		dst.WriteStringBytes(s.str)
+	case expr.OpObjectAgg:
+		if len(s.fields) == 0 {
+			dst.WriteNull()
+			return
+		}
+		dst.WriteStruct(st, s.fields)
+	case expr.OpPercentileCont, expr.OpPercentileDisc, expr.OpMode:
+		if len(s.items) == 0 {
+			dst.WriteNull()
+			return
+		}
+		c.trim(i, s, true)
+		if agg.Role == expr.AggregateRolePartial {
+			// the merging aggregate needs all
+			// the values, so send them sorted
+			dst.BeginList(-1)
+			for j := range s.items {
+				s.items[j].value.Encode(dst, st)
+			}
+			dst.EndList()
+			return
+		}
+		orderedSetResult(agg, s.items).Encode(dst, st)
+	case expr.OpApproxTopK:
+		if s.topk == nil || len(s.topk.counters) == 0 {
+			dst.WriteNull()
+			return
+		}
+		if agg.Role == expr.AggregateRolePartial {
+			// the merging aggregate needs
+			// all the counters of the sketch
+			s.topk.writePartial(dst, st)
+			return
+		}
+		s.topk.writeFinal(dst, st)
+	case expr.OpCount:
+		dst.WriteUint(uint64(s.n))
+	case expr.OpSumCount:
+		dst.WriteInt(s.isum)
+	case expr.OpSumInt:
+		if s.n == 0 {
+			dst.WriteNull()
+			return
+		}
+		dst.WriteInt(s.isum)
+	case expr.OpSum, expr.OpAvg:
+		if s.n == 0 {
+			dst.WriteNull()
+			return
+		}
+		// the partial sums are plain numbers,
+		// since they are merged by another
+		// CollectAggregate rather than a vm aggregate
+		sum := s.sum + s.comp
+		if agg.Op == expr.OpAvg {
+			sum /= float64(s.n)
+		}
+		dst.WriteCanonicalFloat(sum)
+	case expr.OpMin, expr.OpMax:
+		if s.n == 0 {
+			dst.WriteNull()
+			return
+		}
+		dst.WriteCanonicalFloat(s.ext)
+	}
+}
+
+// orderedSetResult computes the result of
+// PERCENTILE_CONT, PERCENTILE_DISC or MODE
+// from the sorted, non-empty list of values
+func orderedSetResult(agg *expr.Aggregate, items []collectItem) ion.Datum {
+	switch agg.Op {
+	case expr.OpPercentileCont:
+		// linear interpolation between
+		// the two closest values
+		p := float64(agg.Arg.(expr.Float))
+		pos := p * float64(len(items)-1)
+		lo := int(math.Floor(pos))
+		hi := int(math.Ceil(pos))
+		x, _ := items[lo].value.CoerceFloat()
+		y, _ := items[hi].value.CoerceFloat()
+		return ion.Float(x + (y-x)*(pos-float64(lo)))
+	case expr.OpPercentileDisc:
+		// the first value whose cumulative
+		// distribution is at least p
+		p := float64(agg.Arg.(expr.Float))
+		pos := int(math.Ceil(p*float64(len(items)))) - 1
+		if pos < 0 {
+			pos = 0
+		} else if pos >= len(items) {
+			pos = len(items) - 1
+		}
+		return items[pos].value
+	default:
+		// the most frequent value; the ties
+		// are resolved by the ordering
+		best, bestn := 0, 0
+		for i := 0; i < len(items); {
+			j := i + 1
+			for j < len(items) && items[i].value.Equal(items[j].value) {
+				j++
+			}
+			if j-i > bestn {
+				best, bestn = i, j-i
+			}
+			i = j
+		}
+		return items[best].value
+	}
+}
+
+// trim sorts the values collected by the i'th
+// aggregate (ARRAY_AGG) and drops the values
+// past its LIMIT; if force is false, the values
+// are trimmed only when there are many more
+// values than the limit; it returns the number
+// of bytes released
+func (c *CollectAggregate) trim(i int, s *collectState, force bool) int {
+	agg := c.aggs[i].Expr
+	order := c.order[i]
+	limit := agg.Limit
+	if !force && (limit == 0 || len(s.items) < 2*limit) {
+		return 0
+	}
+	if len(order) > 0 {
+		sort.SliceStable(s.items, func(x, y int) bool {
+			return compareOrderKeys(order, s.items[x].order, s.items[y].order) < 0
+		})
+	}
+	if limit == 0 || len(s.items) <= limit {
+		return 0
+	}
+	released := 0
+	for j := range s.items[limit:] {
+		released += s.items[limit+j].size()
+		s.items[limit+j] = collectItem{}
+	}
+	s.items = s.items[:limit]
+	return released
+}
+
+// compareOrderKeys compares two lists of
+// concatenated ordering keys
+func compareOrderKeys(order []SortOrdering, a, b []byte) int {
+	for i := range order {
+		na := ion.SizeOf(a)
+		nb := ion.SizeOf(b)
+		dir := order[i].Compare(a[:na], b[:nb])
+		if dir != 0 {
+			return dir
+		}
+		a = a[na:]
+		b = b[nb:]
+	}
+	return 0
+}
+
+// collectInput is the QuerySink
+// consuming the projected input rows
+type collectInput CollectAggregate
+
+func (c *collectInput) Open() (io.WriteCloser, error) {
+	return splitter(&collectTable{
+		parent: (*CollectAggregate)(c),
+		groups: newCollectGroups(),
+		mem:    memacct{budget: c.budget},
+	}), nil
+}
+
+// Close is a no-op; the results are
+// written by CollectAggregate.Close
+func (c *collectInput) Close() error { return nil }
+
+// collectGroups is the set of groups
+// and the values collected for them
+type collectGroups struct {
+	index  map[string]int // canonical key -> position in groups
+	groups []collectGroup
+	size   int // memory occupied by collected values
+}
+
+func newCollectGroups() *collectGroups {
+	return &collectGroups{index: make(map[string]int)}
+}
+
+type collectGroup struct {
+	repr    string // canonical key
+	key     []ion.Datum
+	keysize int // memory occupied by key
+	state   []collectState
+}
+
+// size returns the memory occupied by the group
+func (g *collectGroup) size() int {
+	n := len(g.repr) + g.keysize
+	for i := range g.state {
+		s := &g.state[i]
+		for j := range s.items {
+			n += s.items[j].size()
+		}
+		n += len(s.str)
+		for j := range s.fields {
+			n += len(s.fields[j].Label) + s.fieldsize[j]
+		}
+		if s.topk != nil {
+			n += s.topk.size
+		}
+	}
+	return n
+}
+
+// collectState is the state of a single aggregate
+type collectState struct {
+	items  []collectItem  // ARRAY_AGG, PERCENTILE_*, MODE
+	str    []byte         // STRING_AGG
+	valid  bool           // STRING_AGG: str is not NULL
+	fields []ion.Field    // OBJECT_AGG
+	labels map[string]int // OBJECT_AGG: label -> position in fields
+	// OBJECT_AGG: encoded size of fields[i].Datum
+	fieldsize []int
+	topk      *topkSketch // APPROX_TOP_K
+
+	// COUNT, SUM, SUM_INT, SUM_COUNT, AVG, MIN and MAX
+	n    int64   // number of values
+	isum int64   // SUM_INT, SUM_COUNT: the sum
+	sum  float64 // SUM, AVG: the sum...
+	comp float64 // ... and its compensation (see neumaierSummation)
+	ext  float64 // MIN, MAX: the extreme value
+}
+
+type collectItem struct {
+	value ion.Datum
+	order []byte // concatenated ordering keys
+	n     int    // encoded size of value
+}
+
+func (c *collectItem) size() int {
+	return c.n + len(c.order)
+}
+
+// collectTable is the per-goroutine
+// component of CollectAggregate
+type collectTable struct {
+	parent *CollectAggregate
+	st     *symtab
+	syms   []ion.Symbol // symbols of parent.names
+	vals   [][]byte     // current row, by slot
+	key    []byte
+	vkey   []byte // APPROX_TOP_K: canonical key of a value
+	tmp    ion.Buffer
+	groups *collectGroups
+	rows   int64
+	mem    memacct // charge for groups
+}
+
+var _ rowConsumer = &collectTable{}
+
+func (t *collectTable) symbolize(st *symtab, aux *auxbindings) error {
+	t.st = st
+	names := t.parent.names
+	if len(t.syms) != len(names) {
+		t.syms = make([]ion.Symbol, len(names))
+		t.vals = make([][]byte, len(names))
+	}
+	for i := range names {
+		// interned by the projection
+		t.syms[i], _ = st.Symbolize(names[i])
+	}
+	return nil
+}
+
+func (t *collectTable) next() rowConsumer { return nil }
+
+func (t *collectTable) writeRows(delims []vmref, params *rowParams) error {
+	c := t.parent
+	for i := range delims {
+		t.rows++
+		for j := range t.vals {
+			t.vals[j] = nil
+		}
+		body := delims[i].mem()
+		for len(body) > 0 {
+			var sym ion.Symbol
+			var err error
+			sym, body, err = ion.ReadLabel(body)
+			if err != nil {
+				return err
+			}
+			size := ion.SizeOf(body)
+			if size <= 0 || size > len(body) {
+				return fmt.Errorf("collect aggregate: invalid ion value")
+			}
+			for j := range t.syms {
+				if t.syms[j] == sym {
+					t.vals[j] = body[:size]
+					break
+				}
+			}
+			body = body[size:]
+		}
+		g, err := t.group()
+		if err != nil {
+			return err
+		}
+		if g == nil {
+			continue
+		}
+		for j := range c.aggs {
+			err := t.add(j, &g.state[j])
+			if err != nil {
+				return err
+			}
+		}
+		if t.groups.size > MaxAggregateMemory {
+			return errCollectMemory(t.groups.size)
+		}
+		if err := t.mem.resize(t.groups.size); err != nil {
+			return fmt.Errorf("collect aggregate: %w", err)
+		}
+	}
+	return nil
+}
+
+// group returns the group of the current row,
+// or nil if any of the grouping values is MISSING
+func (t *collectTable) group() (*collectGroup, error) {
+	by := len(t.parent.by)
+	t.key = t.key[:0]
+	for i := 0; i < by; i++ {
+		if t.vals[i] == nil {
+			return nil, nil
+		}
+		t.key = t.appendKey(t.key, t.vals[i])
+	}
+	if n, ok := t.groups.index[string(t.key)]; ok {
+		return &t.groups.groups[n], nil
+	}
+	if len(t.groups.groups) >= MaxAggregateBuckets {
+		return nil, fmt.Errorf("cannot create more than %d aggregate groups", MaxAggregateBuckets)
+	}
+	g := collectGroup{
+		repr:  string(t.key),
+		key:   make([]ion.Datum, by),
+		state: make([]collectState, len(t.parent.aggs)),
+	}
+	for i := range g.key {
+		d, err := t.datum(t.vals[i])
+		if err != nil {
+			return nil, err
+		}
+		g.key[i] = d
+		g.keysize += len(t.vals[i])
+	}
+	t.groups.size += g.size()
+	t.groups.index[g.repr] = len(t.groups.groups)
+	t.groups.groups = append(t.groups.groups, g)
+	return &t.groups.groups[len(t.groups.groups)-1], nil
+}
+
+// appendKey appends an encoding of the ion value
+// that does not depend on the current symbol table;
+// symbols are encoded as strings
+func (t *collectTable) appendKey(dst, val []byte) []byte {
+	switch ion.TypeOf(val) {
+	case ion.SymbolType:
+		if str, ok := t.text(val); ok {
+			t.tmp.Reset()
+			t.tmp.WriteStringBytes(str)
+			return append(dst, t.tmp.Bytes()...)
+		}
+	case ion.StructType, ion.ListType, ion.SexpType:
+		d, _, err := ion.ReadDatum(&t.st.Symtab, val)
+		if err != nil {
+			break
+		}
+		var st ion.Symtab
+		t.tmp.Reset()
+		d.Encode(&t.tmp, &st)
+		st.Marshal(&t.tmp, true)
+		return append(dst, t.tmp.Bytes()...)
+	}
+	return append(dst, val...)
+}
+
+// orderKey appends a copy of the ordering key val to dst;
+// the keys are compared as raw ion values, so symbols
+// are converted to strings and the values that
+// cannot be ordered are replaced with empty values
+func (t *collectTable) orderKey(dst, val []byte) []byte {
+	if val == nil {
+		return append(dst, 0x0f) // MISSING -> NULL
+	}
+	switch typ := ion.TypeOf(val); typ {
+	case ion.SymbolType:
+		str, ok := t.text(val)
+		if !ok {
+			return append(dst, 0x0f)
+		}
+		t.tmp.Reset()
+		t.tmp.WriteStringBytes(str)
+		return append(dst, t.tmp.Bytes()...)
+	case ion.StructType, ion.ListType, ion.SexpType:
+		return append(dst, byte(typ)<<4)
+	case ion.AnnotationType:
+		return append(dst, 0x0f)
+	}
+	return append(dst, val...)
+}
+
+// datum returns a copy of the ion value
+func (t *collectTable) datum(val []byte) (ion.Datum, error) {
+	if ion.TypeOf(val) == ion.AnnotationType {
+		return ion.Empty, fmt.Errorf("cannot collect annotated values")
+	}
+	d, _, err := ion.ReadDatum(&t.st.Symtab, val)
+	if err != nil {
+		return ion.Empty, err
+	}
+	return d.Clone(), nil
+}
+
+// add updates the state of the j'th aggregate
+// with the values of the current row
+func (t *collectTable) add(j int, s *collectState) error {
+	c := t.parent
+	agg := c.aggs[j].Expr
+	slots := &c.slots[j]
+	if slots.filter >= 0 {
+		f := t.vals[slots.filter]
+		if len(f) == 0 || f[0] != 0x11 { // not TRUE
+			return nil
+		}
+	}
+	if slots.value < 0 {
+		s.n++ // COUNT(*)
+		return nil
+	}
+	val := t.vals[slots.value]
+	if val == nil {
+		return nil
+	}
+	switch agg.Op {
+	case expr.OpCount:
+		s.n++
+	case expr.OpSum, expr.OpSumInt, expr.OpSumCount, expr.OpAvg, expr.OpMin, expr.OpMax:
+		s.addNumber(agg.Op, val)
+	case expr.OpArrayAgg:
+		if agg.Role != expr.AggregateRoleMerge {
+			if len(c.order[j]) == 0 && agg.Limit > 0 && len(s.items) >= agg.Limit {
+				return nil
+			}
+			d, err := t.datum(val)
+			if err != nil {
+				return err
+			}
+			item := collectItem{value: d, n: len(val)}
+			for _, k := range slots.order {
+				item.order = t.orderKey(item.order, t.vals[k])
+			}
+			return t.addItem(j, s, item)
+		}
+		if ion.TypeOf(val) != ion.ListType {
+			return nil // NULL partial result
+		}
+		_, err := ion.UnpackList(val, func(v []byte) error {
+			var item collectItem
+			if len(c.order[j]) == 0 {
+				if agg.Limit > 0 && len(s.items) >= agg.Limit {
+					return nil
+				}
+				d, err := t.datum(v)
+				if err != nil {
+					return err
+				}
+				item.value = d
+				item.n = len(v)
+				return t.addItem(j, s, item)
+			}
+			// [value, key0, key1, ...]
+			first := true
+			_, err := ion.UnpackList(v, func(v []byte) error {
+				if first {
+					d, err := t.datum(v)
+					if err != nil {
+						return err
+					}
+					item.value = d
+					item.n = len(v)
+					first = false
+					return nil
+				}
+				item.order = t.orderKey(item.order, v)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return t.addItem(j, s, item)
+		})
+		return err
+	case expr.OpPercentileCont, expr.OpPercentileDisc, expr.OpMode:
+		if agg.Role != expr.AggregateRoleMerge {
+			return t.addOrdered(j, s, val)
+		}
+		if ion.TypeOf(val) != ion.ListType {
+			return nil // NULL partial result
+		}
+		_, err := ion.UnpackList(val, func(v []byte) error {
+			return t.addOrdered(j, s, v)
+		})
+		return err
+	case expr.OpApproxTopK:
+		if agg.Role != expr.AggregateRoleMerge {
+			return t.addTopK(j, s, val)
+		}
+		return t.mergeTopK(j, s, val)
+	case expr.OpStringAgg:
+		str, ok := t.text(val)
+		if !ok {
+			return nil
+		}
+		t.groups.size += s.appendString(agg, str)
+	case expr.OpObjectAgg:
+		if agg.Role != expr.AggregateRoleMerge {
+			v := t.vals[slots.arg]
+			if v == nil {
+				return nil
+			}
+			label, ok := t.text(val)
+			if !ok {
+				return nil
+			}
+			d, err := t.datum(v)
+			if err != nil {
+				return err
+			}
+			t.groups.size += s.setField(string(label), d, len(v))
+			return nil
+		}
+		if ion.TypeOf(val) != ion.StructType {
+			return nil // NULL partial result
+		}
+		_, err := ion.UnpackStruct(&t.st.Symtab, val, func(label string, v []byte) error {
+			d, err := t.datum(v)
+			if err != nil {
+				return err
+			}
+			t.groups.size += s.setField(label, d, len(v))
+			return nil
+		})
+		return err
+	}
+	return nil
+}
+
+// text returns the contents of a string or a symbol
+func (t *collectTable) text(val []byte) ([]byte, bool) {
+	switch ion.TypeOf(val) {
+	case ion.StringType:
+		str, _, err := ion.ReadStringShared(val)
+		return str, err == nil
+	case ion.SymbolType:
+		sym, _, err := ion.ReadSymbol(val)
+		if err != nil {
+			return nil, false
+		}
+		str, ok := t.st.Lookup(sym)
+		return []byte(str), ok
+	}
+	return nil, false
+}
+
+// addOrdered adds a value to the state of
+// an ordered-set aggregate; NULL values and,
+// for PERCENTILE_CONT, non-numeric values are ignored
+func (t *collectTable) addOrdered(j int, s *collectState, val []byte) error {
+	agg := t.parent.aggs[j].Expr
+	switch typ := ion.TypeOf(val); typ {
+	case ion.NullType:
+		return nil
+	case ion.IntType, ion.UintType, ion.FloatType:
+		// ok
+	case ion.SymbolType:
+		if agg.Op == expr.OpPercentileCont {
+			return nil
+		}
+		// compare symbols and strings equal
+		str, ok := t.text(val)
+		if !ok {
+			return nil
+		}
+		t.tmp.Reset()
+		t.tmp.WriteStringBytes(str)
+		val = t.tmp.Bytes()
+	default:
+		if agg.Op == expr.OpPercentileCont {
+			return nil
+		}
+	}
+	if len(val) > 0 && val[0]&0x0f == 0x0f {
+		return nil // typed NULL
+	}
+	d, err := t.datum(val)
+	if err != nil {
+		return err
+	}
+	item := collectItem{value: d, n: len(val)}
+	item.order = t.orderKey(nil, val)
+	return t.addItem(j, s, item)
+}
+
+// sketch returns the APPROX_TOP_K sketch of s
+func (t *collectTable) sketch(j int, s *collectState) *topkSketch {
+	if s.topk == nil {
+		s.topk = newTopkSketch(int(t.parent.aggs[j].Expr.Arg.(expr.Integer)))
+	}
+	return s.topk
+}
+
+// addTopK counts a value in the state
+// of APPROX_TOP_K; NULL values are ignored
+func (t *collectTable) addTopK(j int, s *collectState, val []byte) error {
+	switch ion.TypeOf(val) {
+	case ion.NullType:
+		return nil
+	case ion.SymbolType:
+		// count symbols and strings together
+		str, ok := t.text(val)
+		if !ok {
+			return nil
+		}
+		t.tmp.Reset()
+		t.tmp.WriteStringBytes(str)
+		val = t.tmp.Bytes()
+	}
+	if len(val) > 0 && val[0]&0x0f == 0x0f {
+		return nil // typed NULL
+	}
+	sk := t.sketch(j, s)
+	t.vkey = t.appendKey(t.vkey[:0], val)
+	if i, ok := sk.lookup(t.vkey); ok {
+		sk.inc(i, 1, 0)
+		return nil
+	}
+	d, err := t.datum(val)
+	if err != nil {
+		return err
+	}
+	t.groups.size += sk.insert(topkCounter{
+		key:   string(t.vkey),
+		value: d,
+		n:     len(val),
+		count: 1,
+	})
+	return nil
+}
+
+// mergeTopK merges a partial result of
+// APPROX_TOP_K (see topkSketch.writePartial)
+// into the state of the aggregate
+func (t *collectTable) mergeTopK(j int, s *collectState, val []byte) error {
+	if ion.TypeOf(val) != ion.ListType {
+		return nil // NULL partial result
+	}
+	k := int(t.parent.aggs[j].Expr.Arg.(expr.Integer))
+	from := newTopkSketch(k)
+	_, err := ion.UnpackList(val, func(v []byte) error {
+		var c topkCounter
+		field := 0
+		_, err := ion.UnpackList(v, func(v []byte) error {
+			var err error
+			switch field {
+			case 0:
+				c.value, err = t.datum(v)
+				c.n = len(v)
+				t.vkey = t.appendKey(t.vkey[:0], v)
+				c.key = string(t.vkey)
+			case 1:
+				c.count, _, err = ion.ReadInt(v)
+			case 2:
+				c.err, _, err = ion.ReadInt(v)
+			}
+			field++
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if field != 3 {
+			return fmt.Errorf("APPROX_TOP_K: invalid partial result")
+		}
+		from.counters = append(from.counters, c)
+		from.size += c.size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	from.rebuild()
+	t.groups.size += t.sketch(j, s).merge(from)
+	return nil
+}
+
+func (t *collectTable) addItem(j int, s *collectState, item collectItem) error {
+	s.items = append(s.items, item)
+	t.groups.size += item.size()
+	t.groups.size -= t.parent.trim(j, s, false)
+	return nil
+}
+
+// addNumber updates the state of a simple aggregate
+// with val; the values that are not numbers are ignored
+func (s *collectState) addNumber(op expr.AggregateOp, val []byte) {
+	if val[0]&0x0f == 0x0f {
+		return // typed NULL
+	}
+	var i int64
+	var f float64
+	var err error
+	switch ion.TypeOf(val) {
+	case ion.UintType, ion.IntType:
+		i, _, err = ion.ReadInt(val)
+		f = float64(i)
+	case ion.FloatType:
+		f, _, err = ion.ReadFloat64(val)
+		i = int64(f)
+	default:
+		return
+	}
+	if err != nil {
+		return
+	}
+	switch op {
+	case expr.OpSumInt, expr.OpSumCount:
+		s.isum += i
+	case expr.OpSum, expr.OpAvg:
+		s.sum, s.comp = neumaierSummation(s.sum, f, s.comp)
+	case expr.OpMin:
+		if s.n == 0 || f < s.ext {
+			s.ext = f
+		}
+	case expr.OpMax:
+		if s.n == 0 || f > s.ext {
+			s.ext = f
+		}
+	}
+	s.n++
+}
+
+// appendString appends str to the result of STRING_AGG
+// and returns the number of bytes added
+func (s *collectState) appendString(agg *expr.Aggregate, str []byte) int {
+	n := len(s.str)
+	if s.valid {
+		s.str = append(s.str, agg.Arg.(expr.String)...)
+	}
+	s.str = append(s.str, str...)
+	s.valid = true
+	return len(s.str) - n
+}
+
+// setField sets a field of the result of OBJECT_AGG
+// and returns the number of bytes added
+func (s *collectState) setField(label string, d ion.Datum, size int) int {
+	if s.labels == nil {
+		s.labels = make(map[string]int)
+	}
+	if n, ok := s.labels[label]; ok {
+		old := s.fieldsize[n]
+		s.fields[n].Datum = d
+		s.fieldsize[n] = size
+		return size - old
+	}
+	s.labels[label] = len(s.fields)
+	s.fields = append(s.fields, ion.Field{Label: label, Datum: d})
+	s.fieldsize = append(s.fieldsize, size)
+	return len(label) + size
+}
+
+// merge merges the state of the j'th aggregate
+// from another table into s; it returns
+// the number of bytes added
+func (c *CollectAggregate) merge(j int, s, from *collectState) int {
+	agg := c.aggs[j].Expr
+	size := 0
+	switch agg.Op {
+	case expr.OpArrayAgg, expr.OpPercentileCont, expr.OpPercentileDisc, expr.OpMode:
+		for i := range from.items {
+			if len(c.order[j]) == 0 && agg.Limit > 0 && len(s.items) >= agg.Limit {
+				break
+			}
+			s.items = append(s.items, from.items[i])
+			size += from.items[i].size()
+		}
+		size -= c.trim(j, s, false)
+	case expr.OpStringAgg:
+		if from.valid {
+			size += s.appendString(agg, from.str)
+		}
+	case expr.OpObjectAgg:
+		for i := range from.fields {
+			size += s.setField(from.fields[i].Label, from.fields[i].Datum, from.fieldsize[i])
+		}
+	case expr.OpApproxTopK:
+		if from.topk == nil {
+			break
+		}
+		if s.topk == nil {
+			s.topk = from.topk
+			size += from.topk.size
+			break
+		}
+		size += s.topk.merge(from.topk)
+	case expr.OpSum, expr.OpAvg:
+		s.sum, s.comp = neumaierSummation(s.sum, from.sum, s.comp)
+		s.sum, s.comp = neumaierSummation(s.sum, from.comp, s.comp)
+	case expr.OpMin:
+		if from.n > 0 && (s.n == 0 || from.ext < s.ext) {
+			s.ext = from.ext
+		}
+	case expr.OpMax:
+		if from.n > 0 && (s.n == 0 || from.ext > s.ext) {
+			s.ext = from.ext
+		}
+	}
+	s.n += from.n
+	s.isum += from.isum
+	return size
+}
+
+func (t *collectTable) Close() error {
+	c := t.parent
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rows += t.rows
+	if c.final == nil {
+		c.final = t.groups
+		t.groups = nil
+		t.mem.moveTo(&c.mem)
+		return nil
+	}
+	final := c.final
+	for i := range t.groups.groups {
+		g := &t.groups.groups[i]
+		n, ok := final.index[g.repr]
+		if !ok {
+			if len(final.groups) >= MaxAggregateBuckets {
+				return fmt.Errorf("cannot create more than %d aggregate groups", MaxAggregateBuckets)
+			}
+			final.index[g.repr] = len(final.groups)
+			final.groups = append(final.groups, *g)
+			final.size += g.size()
+			continue
+		}
+		for j := range g.state {
+			final.size += c.merge(j, &final.groups[n].state[j], &g.state[j])
+		}
+	}
+	t.groups = nil
+	t.mem.release()
+	if final.size > MaxAggregateMemory {
+		return errCollectMemory(final.size)
+	}
+	if err := c.mem.resize(final.size); err != nil {
+		return fmt.Errorf("collect aggregate: %w", err)
+	}
+	return nil
+}
+
+func errCollectMemory(size int) error {
+	return fmt.Errorf("collected aggregate values (%d bytes) exceed limit (%d bytes)", size, MaxAggregateMemory)
+}
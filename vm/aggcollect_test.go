@@ -0,0 +1,46 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+)
+
+func TestCollectAggregateMixed(t *testing.T) {
+	mode := mkagg(expr.OpMode, "x", "mode")
+	mode.Expr.OrderBy = []expr.Order{{Column: mode.Expr.Inner}}
+	mode.Expr.Inner = nil
+	testcases := []struct {
+		aggs Aggregation
+		want string
+	}{
+		{
+			aggs: Aggregation{mkagg(expr.OpArrayAgg, "x", "xs"), mkagg(expr.OpVariancePop, "x", "var")},
+			want: "aggregate VARIANCE_POP cannot be mixed with ARRAY_AGG",
+		},
+		{
+			aggs: Aggregation{mkagg(expr.OpCount, "x", "count"), mode, mkagg(expr.OpBitAnd, "x", "and")},
+			want: "aggregate BIT_AND cannot be mixed with MODE",
+		},
+	}
+	for i := range testcases {
+		_, err := NewCollectAggregate(testcases[i].aggs, nil, &QueryBuffer{})
+		if err == nil || err.Error() != testcases[i].want {
+			t.Errorf("case %d: got error %v, want %q", i, err, testcases[i].want)
+		}
+	}
+}
@@ -59,6 +59,8 @@ const (
 	AggregateOpMaxTS
 	AggregateOpCount
 	AggregateOpApproxCountDistinct
+	AggregateOpMomentsF
+	AggregateOpCoMomentsF
 )
 
 func (o AggregateOpFn) String() string {
@@ -103,6 +105,10 @@ func (o AggregateOpFn) String() string {
 		return "AggregateOpApproxCountDistinct"
 	case AggregateOpTDigest:
 		return "AggregateOpTDigest"
+	case AggregateOpMomentsF:
+		return "AggregateOpMomentsF"
+	case AggregateOpCoMomentsF:
+		return "AggregateOpCoMomentsF"
 	default:
 		return fmt.Sprintf("<AggregateOpFn=%d>", int(o))
 	}
@@ -122,6 +128,24 @@ const aggregateOpMergeBufferSize = aggregateOpMergeBufferRowsCount * aggregateOp
 // aggtable.writeRows implementations for the item's interpretation.
 const aggregateOpMergeBufferItemSize = 3 * 4
 
+// aggMergeBuffer is the layout of the extra buffer, as written
+// by opaggslotmergestate: the bucket offset of each row,
+// followed by a copy of the register holding the states of
+// the rows (saggslotmergestate) or, for the ops with mergevalues,
+// the float64 values of the rows (sAggSlotTDigest)
+type aggMergeBuffer struct {
+	buckets [aggregateOpMergeBufferRowsCount]uint32
+	states  sRegData
+}
+
+// assert that the extra buffer matches aggMergeBuffer, and that
+// a float64 register fits exactly in place of the states
+const (
+	_ = ^uintptr(0) + (uintptr(aggregateOpMergeBufferSize) - unsafe.Sizeof(aggMergeBuffer{}))
+	_ = ^uintptr(0) + (unsafe.Sizeof(sRegData{}) - unsafe.Sizeof(f64RegData{}))
+	_ = ^uintptr(0) + (uintptr(aggregateOpMergeBufferRowsCount) - uintptr(bcLaneCount))
+)
+
 // AggregateOp describes aggregate operation
 type AggregateOp struct {
 	fn AggregateOpFn
@@ -134,6 +158,21 @@ type AggregateOp struct {
 
 	// misc used by AggregateOpTDigest to contain the percentile values p
 	misc float32
+
+	// stat is the statistic that AggregateOpMomentsF
+	// and AggregateOpCoMomentsF compute from their moments
+	stat expr.AggregateOp
+
+	// keepstate makes an op with AggregateRoleMerge
+	// write its merged state rather than its final
+	// value (see expr.OpHLLMerge and expr.OpTDigestMerge)
+	keepstate bool
+
+	// mergevalues makes an op with AggregateRoleMerge
+	// add the float64 values in its merge buffer to
+	// its state rather than merging the states that
+	// the merge buffer refers to (see tDigestAdd)
+	mergevalues bool
 }
 
 // The operation needs to pass its whole internal state to the master
@@ -143,7 +182,7 @@ func (a AggregateOp) mergestate() bool {
 }
 
 func (a AggregateOp) savestate() bool {
-	return a.role == expr.AggregateRolePartial
+	return a.role == expr.AggregateRolePartial || a.keepstate
 }
 
 type aggregateOpInfo struct {
@@ -183,6 +222,9 @@ var aggregateOpInfoTable = [...]aggregateOpInfo{
 
 	AggregateOpTDigest:             {isAtomic: false, initFunc: tDigestInit},
 	AggregateOpApproxCountDistinct: {isAtomic: false, initFunc: aggApproxCountDistinctInit},
+
+	AggregateOpMomentsF:   {isAtomic: false, isFloat: true, finalizeFunc: momentsFinalize},
+	AggregateOpCoMomentsF: {isAtomic: false, isFloat: true, finalizeFunc: comomentsFinalize},
 }
 
 func (a *AggregateOp) dataSize() int {
@@ -231,6 +273,11 @@ func (a *AggregateOp) dataSize() int {
 
 	case AggregateOpApproxCountDistinct:
 		return 1 << a.precision
+
+	case AggregateOpMomentsF:
+		return aggregateOpMomentsFDataSize
+	case AggregateOpCoMomentsF:
+		return aggregateOpCoMomentsFDataSize
 	}
 
 	return 0
@@ -238,155 +285,151 @@ func (a *AggregateOp) dataSize() int {
 
 func initAggregateValues(data []byte, aggregateOps []AggregateOp) {
 	for i := range aggregateOps {
-		// First value is initialized to `initUInt64`.
 		op := &aggregateOps[i]
-		info := &aggregateOpInfoTable[op.fn]
-		dataSize := op.dataSize()
 		if op.mergestate() {
 			data = data[aggregateOpMergeBufferSize:]
 		}
+		initAggregateValue(data, op)
+		data = data[op.dataSize():]
+	}
+}
 
-		if info.initFunc != nil {
-			info.initFunc(data[:dataSize])
-		} else {
-			binary.LittleEndian.PutUint64(data, info.initUInt64)
-		}
-
-		// All succeeding values were already zero initialized.
-		data = data[dataSize:]
+// initAggregateValue initializes the state of a single op
+func initAggregateValue(data []byte, op *AggregateOp) {
+	// First value is initialized to `initUInt64`.
+	info := &aggregateOpInfoTable[op.fn]
+	if info.initFunc != nil {
+		info.initFunc(data[:op.dataSize()])
+	} else {
+		binary.LittleEndian.PutUint64(data, info.initUInt64)
 	}
+	// All succeeding values were already zero initialized.
 }
 
-func mergeAggregateBuffers(dst, src []byte, op AggregateOp) bool {
+// mergeAggregateBuffers merges the state src
+// of an op with AggregateRoleMerge into dst;
+// the states may come from the input of the
+// query, so their size is checked and src
+// is never modified
+func mergeAggregateBuffers(dst, src []byte, op AggregateOp) error {
+	if len(src) == 0 {
+		return nil // no state in this lane
+	}
+	n := op.dataSize()
 	switch op.fn {
 	case AggregateOpApproxCountDistinct:
-		n := op.dataSize()
+		if len(src) != n {
+			return fmt.Errorf("cannot merge HyperLogLog state of %d bytes with precision %d (%d bytes)", len(src), op.precision, n)
+		}
 		aggApproxCountDistinctUpdateBuckets(n, dst, src)
-		return true
 
 	case AggregateOpSumF:
+		if len(src) != n {
+			return fmt.Errorf("cannot merge SUM state of %d bytes", len(src))
+		}
 		neumaierSummationMerge(dst, src)
-		return true
 
 	case AggregateOpTDigest:
-		tDigestMerge(dst, src)
-		return true
-	}
+		if len(src) != n {
+			return fmt.Errorf("cannot merge t-digest state of %d bytes (expected %d bytes)", len(src), n)
+		}
+		return tDigestMergeInto(dst, src)
 
-	return false
+	case AggregateOpMomentsF:
+		if len(src) != n {
+			return fmt.Errorf("cannot merge %s state of %d bytes", op.stat, len(src))
+		}
+		momentsMerge(dst, src)
+
+	case AggregateOpCoMomentsF:
+		if len(src) != n {
+			return fmt.Errorf("cannot merge %s state of %d bytes", op.stat, len(src))
+		}
+		comomentsMerge(dst, src)
+
+	default:
+		panic(fmt.Sprintf("aggregate %s expected to merge its buffer", op.fn))
+	}
+	return nil
 }
 
 func mergeAggregatedValues(dst, src []byte, aggregateOps []AggregateOp) {
-	for i, op := range aggregateOps {
+	for i := range aggregateOps {
+		op := &aggregateOps[i]
 		if op.mergestate() {
 			dst = dst[aggregateOpMergeBufferSize:]
 			src = src[aggregateOpMergeBufferSize:]
 		}
-		switch op.fn {
-		case AggregateOpSumF, AggregateOpAvgF:
-			neumaierSummationMerge(dst, src)
-			dst = dst[aggregateOpSumFDataSize:]
-			src = src[aggregateOpSumFDataSize:]
+		mergeAggregatedValue(dst, src, op)
+		n := op.dataSize()
+		dst = dst[n:]
+		src = src[n:]
+	}
+}
 
-		case AggregateOpTDigest:
-			tDigestMerge(dst, src)
-			dst = dst[tDigestDataSize:]
-			src = src[tDigestDataSize:]
+// mergeAggregatedValue merges the state of a single op
+func mergeAggregatedValue(dst, src []byte, op *AggregateOp) {
+	switch op.fn {
+	case AggregateOpSumF, AggregateOpAvgF:
+		neumaierSummationMerge(dst, src)
 
-		case AggregateOpMinF:
-			bufferMinFloat64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
-			bufferOrInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
+	case AggregateOpTDigest:
+		tDigestMerge(dst, src)
 
-		case AggregateOpMaxF:
-			bufferMaxFloat64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
-			bufferOrInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
+	case AggregateOpMinF:
+		bufferMinFloat64(dst, src)
+		bufferOrInt64(dst[8:], src[8:])
 
-		case AggregateOpSumI:
-			bufferAddInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
-			bufferOrInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
+	case AggregateOpMaxF:
+		bufferMaxFloat64(dst, src)
+		bufferOrInt64(dst[8:], src[8:])
 
-		case AggregateOpSumC:
-			bufferAddInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
-			bufferOrInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
+	case AggregateOpSumI:
+		bufferAddInt64(dst, src)
+		bufferOrInt64(dst[8:], src[8:])
 
-		case AggregateOpAvgI:
-			bufferAddInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
-			bufferAddInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
+	case AggregateOpSumC:
+		bufferAddInt64(dst, src)
+		bufferOrInt64(dst[8:], src[8:])
 
-		case AggregateOpMinI, AggregateOpMinTS:
-			bufferMinInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
-			bufferOrInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
+	case AggregateOpAvgI:
+		bufferAddInt64(dst, src)
+		bufferAddInt64(dst[8:], src[8:])
 
-		case AggregateOpMaxI, AggregateOpMaxTS:
-			bufferMaxInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
-			bufferOrInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
+	case AggregateOpMinI, AggregateOpMinTS:
+		bufferMinInt64(dst, src)
+		bufferOrInt64(dst[8:], src[8:])
 
-		case AggregateOpAndI, AggregateOpAndK:
-			bufferAndInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
-			bufferOrInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
+	case AggregateOpMaxI, AggregateOpMaxTS:
+		bufferMaxInt64(dst, src)
+		bufferOrInt64(dst[8:], src[8:])
 
-		case AggregateOpOrI, AggregateOpOrK:
-			bufferOrInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
-			bufferOrInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
+	case AggregateOpAndI, AggregateOpAndK:
+		bufferAndInt64(dst, src)
+		bufferOrInt64(dst[8:], src[8:])
 
-		case AggregateOpXorI:
-			bufferXorInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
-			bufferOrInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
+	case AggregateOpOrI, AggregateOpOrK:
+		bufferOrInt64(dst, src)
+		bufferOrInt64(dst[8:], src[8:])
 
-		case AggregateOpCount:
-			bufferAddInt64(dst, src)
-			dst = dst[8:]
-			src = src[8:]
+	case AggregateOpXorI:
+		bufferXorInt64(dst, src)
+		bufferOrInt64(dst[8:], src[8:])
+
+	case AggregateOpCount:
+		bufferAddInt64(dst, src)
 
-		case AggregateOpApproxCountDistinct:
-			n := aggregateOps[i].dataSize()
-			aggApproxCountDistinctUpdateBuckets(n, dst, src)
-			dst = dst[n:]
-			src = src[n:]
+	case AggregateOpApproxCountDistinct:
+		aggApproxCountDistinctUpdateBuckets(op.dataSize(), dst, src)
 
-		default:
-			panic(fmt.Sprintf("unsupported operation %s", aggregateOps[i].fn))
-		}
+	case AggregateOpMomentsF:
+		momentsMerge(dst, src)
+
+	case AggregateOpCoMomentsF:
+		comomentsMerge(dst, src)
+
+	default:
+		panic(fmt.Sprintf("unsupported operation %s", op.fn))
 	}
 }
 
@@ -559,6 +602,14 @@ func writeAggregatedValue(b *ion.Buffer, data []byte, op AggregateOp) int {
 		b.WriteCanonicalFloat(float64(percentiles[0]))
 		return tDigestDataSize
 
+	case AggregateOpMomentsF:
+		writeMoments(b, data, op.stat)
+		return aggregateOpMomentsFDataSize
+
+	case AggregateOpCoMomentsF:
+		writeCoMoments(b, data, op.stat)
+		return aggregateOpCoMomentsFDataSize
+
 	default:
 		panic(fmt.Sprintf("Invalid aggregate op: %v", op.fn))
 	}
@@ -755,13 +806,19 @@ func (p *aggregateLocal) writeRows(delims []vmref, rp *rowParams) error {
 					positions := dst[:aggregateOpMergeBufferSize]
 					dst = dst[aggregateOpMergeBufferSize:]
 					for i := range chunk {
-						offset := binary.LittleEndian.Uint32(positions[8*i:])
-						size := binary.LittleEndian.Uint32(positions[8*i+64:])
+						offset := binary.LittleEndian.Uint32(positions[4*i:])
+						size := binary.LittleEndian.Uint32(positions[4*i+64:])
 						v := vmref{offset, size}
-						if !mergeAggregateBuffers(dst, v.mem(), op) {
-							panic(fmt.Sprintf("aggregate %s expected to merge its buffer", op.fn))
+						if err := mergeAggregateBuffers(dst, v.mem(), op); err != nil {
+							return err
 						}
 					}
+					// the lanes without a state are not
+					// written, so they must not see the
+					// positions from this chunk
+					for i := range positions {
+						positions[i] = 0
+					}
 				}
 				dst = dst[n:]
 			}
@@ -861,7 +918,7 @@ func (q *Aggregate) compileAggregate(aggregates Aggregation) error {
 			}
 			ops[i].fn = AggregateOpCount
 
-		case expr.OpApproxCountDistinct:
+		case expr.OpApproxCountDistinct, expr.OpApproxCountDistinctState, expr.OpHLLMerge, expr.OpHLLEstimate:
 			v, err := compile(p, agg.Inner)
 			if err != nil {
 				return fmt.Errorf("don't know how to aggregate %q: %w", agg.Inner, err)
@@ -869,14 +926,64 @@ func (q *Aggregate) compileAggregate(aggregates Aggregation) error {
 
 			ops[i].fn = AggregateOpApproxCountDistinct
 			ops[i].precision = agg.Precision
-			ops[i].role = agg.Role
-			switch agg.Role {
+			ops[i].role, ops[i].keepstate = stateRole(agg)
+			switch ops[i].role {
 			case expr.AggregateRoleFinal, expr.AggregateRolePartial:
 				mem[i] = p.aggregateApproxCountDistinct(v, filter, offset, agg.Precision)
 
 			case expr.AggregateRoleMerge:
-				mem[i] = p.aggregateMergeState(v, offset)
+				mem[i] = p.aggregateMergeState(v, filter, offset)
+			}
+
+		case expr.OpTDigestMerge, expr.OpTDigestQuantile:
+			v, err := compile(p, agg.Inner)
+			if err != nil {
+				return fmt.Errorf("don't know how to aggregate %q: %w", agg.Inner, err)
+			}
+			ops[i].fn = AggregateOpTDigest
+			ops[i].misc = agg.Misc
+			ops[i].role, ops[i].keepstate = stateRole(agg)
+			mem[i] = p.aggregateMergeState(v, filter, offset)
+
+		case expr.OpVarianceSamp, expr.OpStdDevSamp, expr.OpSkewness, expr.OpKurtosis:
+			ops[i].fn = AggregateOpMomentsF
+			ops[i].stat = op
+			ops[i].role = agg.Role
+			if agg.Role == expr.AggregateRoleMerge {
+				state, err := compile(p, agg.Inner)
+				if err != nil {
+					return fmt.Errorf("don't know how to aggregate %q: %w", agg.Inner, err)
+				}
+				mem[i] = p.aggregateMergeState(state, nil, offset)
+				break
+			}
+			argv, err := p.compileAsNumber(agg.Inner)
+			if err != nil {
+				return fmt.Errorf("don't know how to aggregate %q: %w", agg.Inner, err)
 			}
+			mem[i] = p.aggregateMoments(argv, filter, offset)
+
+		case expr.OpCovarPop, expr.OpCovarSamp, expr.OpCorr, expr.OpRegrSlope, expr.OpRegrIntercept, expr.OpRegrR2:
+			ops[i].fn = AggregateOpCoMomentsF
+			ops[i].stat = op
+			ops[i].role = agg.Role
+			if agg.Role == expr.AggregateRoleMerge {
+				state, err := compile(p, agg.Inner)
+				if err != nil {
+					return fmt.Errorf("don't know how to aggregate %q: %w", agg.Inner, err)
+				}
+				mem[i] = p.aggregateMergeState(state, nil, offset)
+				break
+			}
+			y, err := p.compileAsNumber(agg.Inner)
+			if err != nil {
+				return fmt.Errorf("don't know how to aggregate %q: %w", agg.Inner, err)
+			}
+			x, err := p.compileAsNumber(agg.Arg)
+			if err != nil {
+				return fmt.Errorf("don't know how to aggregate %q: %w", agg.Arg, err)
+			}
+			mem[i] = p.aggregateCoMoments(y, x, filter, offset)
 
 		case expr.OpBoolAnd, expr.OpBoolOr:
 			argv, err := compile(p, agg.Inner)
@@ -905,7 +1012,7 @@ func (q *Aggregate) compileAggregate(aggregates Aggregation) error {
 					ops[i].fn = AggregateOpSumF
 					ops[i].role = agg.Role
 					if agg.Role == expr.AggregateRoleMerge {
-						mem[i] = p.aggregateMergeState(argv, offset)
+						mem[i] = p.aggregateMergeState(argv, nil, offset)
 					}
 				} else {
 					ops[i].fn = AggregateOpSumI
@@ -957,7 +1064,7 @@ func (q *Aggregate) compileAggregate(aggregates Aggregation) error {
 				ops[i].misc = .5
 				ops[i].role = agg.Role
 				if agg.Role == expr.AggregateRoleMerge {
-					mem[i] = p.aggregateMergeState(argv, offset)
+					mem[i] = p.aggregateMergeState(argv, nil, offset)
 				} else {
 					mem[i] = p.aggregateTDigest(argv, filter, offset)
 				}
@@ -966,10 +1073,14 @@ func (q *Aggregate) compileAggregate(aggregates Aggregation) error {
 				ops[i].misc = agg.Misc
 				ops[i].role = agg.Role
 				if agg.Role == expr.AggregateRoleMerge {
-					mem[i] = p.aggregateMergeState(argv, offset)
+					mem[i] = p.aggregateMergeState(argv, nil, offset)
 				} else {
 					mem[i] = p.aggregateTDigest(argv, filter, offset)
 				}
+			case expr.OpApproxPercentileState:
+				ops[i].fn = AggregateOpTDigest
+				ops[i].role = expr.AggregateRolePartial
+				mem[i] = p.aggregateTDigest(argv, filter, offset)
 			default:
 				return fmt.Errorf("unsupported aggregate operation: %s", agg.Op)
 			}
@@ -1000,6 +1111,22 @@ func (q *Aggregate) compileAggregate(aggregates Aggregation) error {
 	return nil
 }
 
+// stateRole returns the role of the AggregateOp
+// computing an aggregate that produces or consumes
+// the state of APPROX_COUNT_DISTINCT or APPROX_PERCENTILE
+// and whether the op writes its state (see AggregateOp.keepstate)
+func stateRole(agg *expr.Aggregate) (expr.AggregateRole, bool) {
+	switch agg.Op {
+	case expr.OpApproxCountDistinctState, expr.OpApproxPercentileState:
+		return expr.AggregateRolePartial, false
+	case expr.OpHLLMerge, expr.OpTDigestMerge:
+		return expr.AggregateRoleMerge, true
+	case expr.OpHLLEstimate, expr.OpTDigestQuantile:
+		return expr.AggregateRoleMerge, false
+	}
+	return agg.Role, false
+}
+
 // mergestate returns true if any aggregate needs state merge
 func mergestate(ops []AggregateOp) bool {
 	for i := range ops {
@@ -0,0 +1,273 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+// This file contains all supporting functions for handling
+// the central moments used by the statistical aggregates.
+//
+// The moments are updated with the one-pass algorithm of Welford[1]
+// (see evalbc_aggmoments.h), and two states are merged with the
+// parallel formulas of Chan et al.[2] and Pébay[3], so that the
+// statistics do not suffer from the catastrophic cancellation of
+// the naive power sums when the inputs have a large mean.
+//
+// [1] https://en.wikipedia.org/wiki/Algorithms_for_calculating_variance#Welford's_online_algorithm
+// [2] https://en.wikipedia.org/wiki/Algorithms_for_calculating_variance#Parallel_algorithm
+// [3] https://www.osti.gov/biblio/1028931
+
+import (
+	"math"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// Memory layout: 16 x float64 for each of: count, mean, M2, M3, M4
+const aggregateOpMomentsFDataSize = 5 * 16 * 8
+
+// Memory layout: 16 x float64 for each of: count, mean x, mean y, M2 x, M2 y, C xy
+const aggregateOpCoMomentsFDataSize = 6 * 16 * 8
+
+// moments are the count, the mean and
+// the central moments M2, M3 and M4 of a set of values
+type moments struct {
+	n, mean, m2, m3, m4 float64
+}
+
+func getmoments(b []byte, lane int) moments {
+	const k = 16
+	return moments{
+		n:    getfloat64(b, lane),
+		mean: getfloat64(b, k+lane),
+		m2:   getfloat64(b, 2*k+lane),
+		m3:   getfloat64(b, 3*k+lane),
+		m4:   getfloat64(b, 4*k+lane),
+	}
+}
+
+func setmoments(b []byte, lane int, m *moments) {
+	const k = 16
+	setfloat64(b, lane, m.n)
+	setfloat64(b, k+lane, m.mean)
+	setfloat64(b, 2*k+lane, m.m2)
+	setfloat64(b, 3*k+lane, m.m3)
+	setfloat64(b, 4*k+lane, m.m4)
+}
+
+// merge combines the moments of two disjoint sets of values
+func (a *moments) merge(b *moments) {
+	if b.n == 0 {
+		return
+	}
+	if a.n == 0 {
+		*a = *b
+		return
+	}
+
+	na, nb := a.n, b.n
+	n := na + nb
+	delta := b.mean - a.mean
+	delta2 := delta * delta
+
+	m4 := a.m4 + b.m4 +
+		delta2*delta2*na*nb*(na*na-na*nb+nb*nb)/(n*n*n) +
+		6*delta2*(na*na*b.m2+nb*nb*a.m2)/(n*n) +
+		4*delta*(na*b.m3-nb*a.m3)/n
+	m3 := a.m3 + b.m3 +
+		delta2*delta*na*nb*(na-nb)/(n*n) +
+		3*delta*(na*b.m2-nb*a.m2)/n
+	m2 := a.m2 + b.m2 + delta2*na*nb/n
+
+	a.n = n
+	a.mean += delta * nb / n
+	a.m2 = m2
+	a.m3 = m3
+	a.m4 = m4
+}
+
+// comoments are the count, the means, the central moments
+// M2 and the co-moment C of a set of pairs (y, x)
+type comoments struct {
+	n, meanx, meany, m2x, m2y, cxy float64
+}
+
+func getcomoments(b []byte, lane int) comoments {
+	const k = 16
+	return comoments{
+		n:     getfloat64(b, lane),
+		meanx: getfloat64(b, k+lane),
+		meany: getfloat64(b, 2*k+lane),
+		m2x:   getfloat64(b, 3*k+lane),
+		m2y:   getfloat64(b, 4*k+lane),
+		cxy:   getfloat64(b, 5*k+lane),
+	}
+}
+
+func setcomoments(b []byte, lane int, m *comoments) {
+	const k = 16
+	setfloat64(b, lane, m.n)
+	setfloat64(b, k+lane, m.meanx)
+	setfloat64(b, 2*k+lane, m.meany)
+	setfloat64(b, 3*k+lane, m.m2x)
+	setfloat64(b, 4*k+lane, m.m2y)
+	setfloat64(b, 5*k+lane, m.cxy)
+}
+
+// merge combines the co-moments of two disjoint sets of pairs
+func (a *comoments) merge(b *comoments) {
+	if b.n == 0 {
+		return
+	}
+	if a.n == 0 {
+		*a = *b
+		return
+	}
+
+	na, nb := a.n, b.n
+	n := na + nb
+	dx := b.meanx - a.meanx
+	dy := b.meany - a.meany
+	f := na * nb / n
+
+	a.n = n
+	a.meanx += dx * nb / n
+	a.meany += dy * nb / n
+	a.m2x += b.m2x + dx*dx*f
+	a.m2y += b.m2y + dy*dy*f
+	a.cxy += b.cxy + dx*dy*f
+}
+
+// momentsMerge merges two states of 16 independent moments lane by lane.
+func momentsMerge(dst, src []byte) {
+	for i := 0; i < 16; i++ {
+		a := getmoments(dst, i)
+		b := getmoments(src, i)
+		a.merge(&b)
+		setmoments(dst, i, &a)
+	}
+}
+
+// momentsFinalize folds 16 moments into the first lane.
+func momentsFinalize(data []byte) {
+	a := getmoments(data, 0)
+	for i := 1; i < 16; i++ {
+		b := getmoments(data, i)
+		a.merge(&b)
+	}
+	setmoments(data, 0, &a)
+}
+
+// comomentsMerge merges two states of 16 independent co-moments lane by lane.
+func comomentsMerge(dst, src []byte) {
+	for i := 0; i < 16; i++ {
+		a := getcomoments(dst, i)
+		b := getcomoments(src, i)
+		a.merge(&b)
+		setcomoments(dst, i, &a)
+	}
+}
+
+// comomentsFinalize folds 16 co-moments into the first lane.
+func comomentsFinalize(data []byte) {
+	a := getcomoments(data, 0)
+	for i := 1; i < 16; i++ {
+		b := getcomoments(data, i)
+		a.merge(&b)
+	}
+	setcomoments(data, 0, &a)
+}
+
+// writeMoments writes the statistic op
+// of the finalized moments in data
+func writeMoments(b *ion.Buffer, data []byte, op expr.AggregateOp) {
+	m := getmoments(data, 0)
+	switch op {
+	case expr.OpVarianceSamp, expr.OpStdDevSamp:
+		if m.n < 2 {
+			b.WriteNull()
+			return
+		}
+		variance := m.m2 / (m.n - 1)
+		if op == expr.OpStdDevSamp {
+			b.WriteCanonicalFloat(math.Sqrt(variance))
+		} else {
+			b.WriteCanonicalFloat(variance)
+		}
+	case expr.OpSkewness:
+		if m.n == 0 || m.m2 <= 0 {
+			b.WriteNull()
+			return
+		}
+		b.WriteCanonicalFloat(math.Sqrt(m.n) * m.m3 / math.Pow(m.m2, 1.5))
+	case expr.OpKurtosis:
+		if m.n == 0 || m.m2 <= 0 {
+			b.WriteNull()
+			return
+		}
+		b.WriteCanonicalFloat(m.n*m.m4/(m.m2*m.m2) - 3)
+	default:
+		b.WriteNull()
+	}
+}
+
+// writeCoMoments writes the statistic op
+// of the finalized co-moments in data
+func writeCoMoments(b *ion.Buffer, data []byte, op expr.AggregateOp) {
+	m := getcomoments(data, 0)
+	if m.n == 0 {
+		b.WriteNull()
+		return
+	}
+	switch op {
+	case expr.OpCovarPop:
+		b.WriteCanonicalFloat(m.cxy / m.n)
+	case expr.OpCovarSamp:
+		if m.n < 2 {
+			b.WriteNull()
+			return
+		}
+		b.WriteCanonicalFloat(m.cxy / (m.n - 1))
+	case expr.OpCorr:
+		if m.m2x <= 0 || m.m2y <= 0 {
+			b.WriteNull()
+			return
+		}
+		b.WriteCanonicalFloat(m.cxy / math.Sqrt(m.m2x*m.m2y))
+	case expr.OpRegrSlope, expr.OpRegrIntercept:
+		if m.m2x <= 0 {
+			b.WriteNull()
+			return
+		}
+		slope := m.cxy / m.m2x
+		if op == expr.OpRegrIntercept {
+			b.WriteCanonicalFloat(m.meany - slope*m.meanx)
+		} else {
+			b.WriteCanonicalFloat(slope)
+		}
+	case expr.OpRegrR2:
+		if m.m2x <= 0 {
+			b.WriteNull()
+			return
+		}
+		// the fit is perfect when y is constant
+		if m.m2y <= 0 {
+			b.WriteCanonicalFloat(1)
+			return
+		}
+		b.WriteCanonicalFloat(m.cxy * m.cxy / (m.m2x * m.m2y))
+	default:
+		b.WriteNull()
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math"
+	"testing"
+)
+
+// momentsOf computes the moments of x directly
+func momentsOf(x []float64) moments {
+	var m moments
+	m.n = float64(len(x))
+	for i := range x {
+		m.mean += x[i]
+	}
+	m.mean /= m.n
+	for i := range x {
+		d := x[i] - m.mean
+		m.m2 += d * d
+		m.m3 += d * d * d
+		m.m4 += d * d * d * d
+	}
+	return m
+}
+
+func TestMomentsMerge(t *testing.T) {
+	const offset = 1e9
+	x := []float64{1, 2, 4, 8, 16, 3, 5, 7, 9}
+	for i := range x {
+		x[i] += offset
+	}
+	want := momentsOf(x)
+	close := func(a, b float64) bool {
+		return math.Abs(a-b) <= 1e-6*math.Max(1, math.Abs(b))
+	}
+	for split := 0; split <= len(x); split++ {
+		var a, b moments
+		if split > 0 {
+			a = momentsOf(x[:split])
+		}
+		if split < len(x) {
+			b = momentsOf(x[split:])
+		}
+		a.merge(&b)
+		if a.n != want.n || !close(a.mean, want.mean) || !close(a.m2, want.m2) ||
+			!close(a.m3, want.m3) || !close(a.m4, want.m4) {
+			t.Errorf("split %d: got %+v, want %+v", split, a, want)
+		}
+	}
+}
+
+func TestCoMomentsMerge(t *testing.T) {
+	const offset = 1e9
+	x := []float64{1, 2, 4, 8, 16, 3}
+	y := []float64{2, 3, 9, 15, 33, 7}
+	comomentsOf := func(y, x []float64) comoments {
+		var m comoments
+		m.n = float64(len(x))
+		for i := range x {
+			m.meanx += x[i]
+			m.meany += y[i]
+		}
+		m.meanx /= m.n
+		m.meany /= m.n
+		for i := range x {
+			dx, dy := x[i]-m.meanx, y[i]-m.meany
+			m.m2x += dx * dx
+			m.m2y += dy * dy
+			m.cxy += dx * dy
+		}
+		return m
+	}
+	for i := range x {
+		x[i] += offset
+		y[i] += offset
+	}
+	want := comomentsOf(y, x)
+	close := func(a, b float64) bool {
+		return math.Abs(a-b) <= 1e-6*math.Max(1, math.Abs(b))
+	}
+	for split := 1; split < len(x); split++ {
+		a := comomentsOf(y[:split], x[:split])
+		b := comomentsOf(y[split:], x[split:])
+		a.merge(&b)
+		if a.n != want.n || !close(a.meanx, want.meanx) || !close(a.meany, want.meany) ||
+			!close(a.m2x, want.m2x) || !close(a.m2y, want.m2y) || !close(a.cxy, want.cxy) {
+			t.Errorf("split %d: got %+v, want %+v", split, a, want)
+		}
+	}
+}
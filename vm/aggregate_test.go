@@ -19,6 +19,7 @@ import (
 	"math"
 	"os"
 	"testing"
+	"unsafe"
 
 	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/ion"
@@ -108,3 +109,112 @@ func TestAggregateSSANYCQueries(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeAggregateBuffersSize(t *testing.T) {
+	hll := AggregateOp{fn: AggregateOpApproxCountDistinct, precision: 8}
+	dst := make([]byte, hll.dataSize())
+	if err := mergeAggregateBuffers(dst, nil, hll); err != nil {
+		t.Fatalf("missing state: %v", err)
+	}
+	if err := mergeAggregateBuffers(dst, make([]byte, hll.dataSize()), hll); err != nil {
+		t.Fatal(err)
+	}
+	// a state of another precision is rejected
+	if err := mergeAggregateBuffers(dst, make([]byte, 1<<10), hll); err == nil {
+		t.Error("merged a HyperLogLog state of precision 10 with precision 8")
+	}
+
+	td := AggregateOp{fn: AggregateOpTDigest}
+	dst = make([]byte, td.dataSize())
+	if err := mergeAggregateBuffers(dst, []byte("not a t-digest"), td); err == nil {
+		t.Error("merged an invalid t-digest state")
+	}
+}
+
+func TestMergeAggregateBuffersTDigest(t *testing.T) {
+	td := AggregateOp{fn: AggregateOpTDigest}
+	src := tDigestDS(make([]byte, td.dataSize()))
+	src.putLen(2)
+	src.putWeightSum(3)
+	src.putMeanMin(1)
+	src.putMeanMax(5)
+	src.putWeight(1, 0)
+	src.putMean(1, 0)
+	src.putWeight(2, 1)
+	src.putMean(5, 1)
+	orig := bytes.Clone(src)
+
+	// the same state is merged twice, as it is
+	// when two rows of the input hold the same state
+	dst := make([]byte, td.dataSize())
+	for i := 0; i < 2; i++ {
+		if err := mergeAggregateBuffers(dst, src, td); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(src, orig) {
+		t.Error("the merged state was modified")
+	}
+	if w := tDigestDS(dst).getWeightSum(); w != 6 {
+		t.Errorf("got total weight %g, want 6", w)
+	}
+}
+
+func TestTDigestAdd(t *testing.T) {
+	// values are added in batches of varying
+	// size when APPROX_PERCENTILE_STATE is
+	// used with GROUP BY
+	dst := tDigestDS(make([]byte, tDigestDataSize))
+	var batch []float32
+	for i := 1000; i > 0; i-- {
+		batch = append(batch, float32(i))
+		if i%7 != 0 && i > 1 {
+			continue
+		}
+		if err := tDigestAdd(dst, batch); err != nil {
+			t.Fatal(err)
+		}
+		batch = batch[:0]
+	}
+	if w := dst.getWeightSum(); w != 1000 {
+		t.Errorf("got total weight %g, want 1000", w)
+	}
+	if n := dst.getLen(); n > 32 {
+		t.Fatalf("%d centroids", n)
+	}
+	p, err := calcPercentiles(dst, []float32{0, 0.5, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p[0] != 1 || p[2] != 1000 {
+		t.Errorf("got minimum %g and maximum %g", p[0], p[2])
+	}
+	if p[1] < 450 || p[1] > 550 {
+		t.Errorf("got median %g", p[1])
+	}
+}
+
+func TestAggSlotTDigestLayout(t *testing.T) {
+	// sAggSlotTDigest passes float64 values to the
+	// bytecode op of saggslotmergestate, which copies
+	// its scalar argument into the merge buffer as-is
+	// (see aggMergeBuffer)
+	td, ms := &ssainfo[sAggSlotTDigest], &ssainfo[saggslotmergestate]
+	if td.bc != ms.bc {
+		t.Fatalf("%s uses %s, but %s uses %s", td.text, opinfo[td.bc].text, ms.text, opinfo[ms.bc].text)
+	}
+	if len(td.argtypes) != len(ms.argtypes) || td.argtypes[1] != stFloat {
+		t.Fatalf("unexpected arguments %v of %s", td.argtypes, td.text)
+	}
+	in := opinfo[td.bc].in
+	if len(in) != 4 || in[2] != bcS {
+		t.Fatalf("unexpected arguments %v of %s", in, opinfo[td.bc].text)
+	}
+	var buf aggMergeBuffer
+	if unsafe.Sizeof(buf.states) != unsafe.Sizeof(f64RegData{}) {
+		t.Fatal("the values do not fit in place of the states")
+	}
+	if off := unsafe.Offsetof(buf.states); off != 1*64 {
+		t.Fatalf("states at offset %d, but aggtable.writeRows reads them at offset 64", off)
+	}
+}
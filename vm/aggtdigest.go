@@ -142,8 +142,19 @@ func createDs(tDigest *percentile.TDigest, data tDigestDS) {
 	}
 }
 
-// tDigestMerge merges src with dst buffer
+// tDigestMerge merges src with dst buffer and clears src
 func tDigestMerge(dst, src tDigestDS) error {
+	if err := tDigestMergeInto(dst, src); err != nil {
+		return err
+	}
+	src.clear()
+	return nil
+}
+
+// tDigestMergeInto merges src with dst buffer;
+// unlike tDigestMerge, it leaves src untouched,
+// as it may be a state that is part of the input
+func tDigestMergeInto(dst, src tDigestDS) error {
 	lenSrc := src.getLen()
 	if lenSrc > 0 {
 		lenDst := dst.getLen()
@@ -163,12 +174,43 @@ func tDigestMerge(dst, src tDigestDS) error {
 			t1.Merge(t2, 16)
 			createDs(t1, dst)
 		}
-		src.clear()
 	}
 	// else: source is empty: do nothing
 	return nil
 }
 
+// tDigestAdd adds the values v to the dst buffer,
+// decoding and encoding dst only once
+func tDigestAdd(dst tDigestDS, v []float32) error {
+	var t *percentile.TDigest
+	if dst.getLen() != 0 {
+		var err error
+		t, err = createTDigest(dst)
+		if err != nil {
+			return err
+		}
+	}
+	// a t-digest can be built from
+	// at most 16 values at a time
+	for len(v) > 0 {
+		n := len(v)
+		if n > 16 {
+			n = 16
+		}
+		src := percentile.NewTDigest(v[:n], 16)
+		if t == nil {
+			t = src
+		} else {
+			t.Merge(src, 16)
+		}
+		v = v[n:]
+	}
+	if t != nil {
+		createDs(t, dst)
+	}
+	return nil
+}
+
 // calcPercentiles calculates approximate percentiles using the tDigest data
 func calcPercentiles(data tDigestDS, p []float32) ([]float32, error) {
 	t, err := createTDigest(data)
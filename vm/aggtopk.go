@@ -0,0 +1,271 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"sort"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// approxTopKCounters is the number of counters
+// kept by APPROX_TOP_K(x, k) for each of the k values
+const approxTopKCounters = 10
+
+// topkSketch is the Space-Saving sketch
+// (Metwally, Agrawal and El Abbadi, 2005)
+// used by APPROX_TOP_K.
+//
+// The sketch is updated by CollectAggregate
+// rather than by an aggregate slot in the bytecode
+// (like the HyperLogLog of APPROX_COUNT_DISTINCT),
+// since its counters hold arbitrary values rather
+// than fixed-size state; the partial sketches are
+// merged with topkSketch.merge.
+//
+// Each counter overestimates the number of
+// occurrences of its value by at most err;
+// once all the counters are in use, a new value
+// replaces the value with the smallest count
+// and inherits its count as the error.
+type topkSketch struct {
+	k        int
+	counters []topkCounter  // min-heap ordered by count
+	index    map[string]int // canonical key -> position in counters
+	size     int            // memory occupied by counters
+}
+
+type topkCounter struct {
+	key   string // canonical key of value
+	value ion.Datum
+	n     int // encoded size of value
+	count int64
+	err   int64
+}
+
+func (c *topkCounter) size() int {
+	return len(c.key) + c.n
+}
+
+func newTopkSketch(k int) *topkSketch {
+	return &topkSketch{
+		k:     k,
+		index: make(map[string]int),
+	}
+}
+
+func (s *topkSketch) capacity() int {
+	return s.k * approxTopKCounters
+}
+
+// min returns the largest count of
+// a value that has no counter
+func (s *topkSketch) min() int64 {
+	if len(s.counters) < s.capacity() {
+		return 0
+	}
+	return s.counters[0].count
+}
+
+// lookup returns the position of
+// the counter with the canonical key
+func (s *topkSketch) lookup(key []byte) (int, bool) {
+	i, ok := s.index[string(key)]
+	return i, ok
+}
+
+// inc adds count occurrences of the value
+// of the i'th counter, overestimated by err
+func (s *topkSketch) inc(i int, count, err int64) {
+	s.counters[i].count += count
+	s.counters[i].err += err
+	s.down(i)
+}
+
+// insert adds a counter for a value that
+// is not counted yet; it returns the number
+// of bytes added
+func (s *topkSketch) insert(c topkCounter) int {
+	size := s.size
+	if len(s.counters) < s.capacity() {
+		s.index[c.key] = len(s.counters)
+		s.counters = append(s.counters, c)
+		s.size += c.size()
+		s.up(len(s.counters) - 1)
+		return s.size - size
+	}
+	// replace the least frequent value
+	old := &s.counters[0]
+	delete(s.index, old.key)
+	s.size -= old.size()
+	c.count += old.count
+	c.err += old.count
+	*old = c
+	s.index[c.key] = 0
+	s.size += c.size()
+	s.down(0)
+	return s.size - size
+}
+
+func (s *topkSketch) swap(i, j int) {
+	s.counters[i], s.counters[j] = s.counters[j], s.counters[i]
+	s.index[s.counters[i].key] = i
+	s.index[s.counters[j].key] = j
+}
+
+func (s *topkSketch) up(i int) {
+	for i > 0 {
+		p := (i - 1) / 2
+		if s.counters[p].count <= s.counters[i].count {
+			break
+		}
+		s.swap(i, p)
+		i = p
+	}
+}
+
+func (s *topkSketch) down(i int) {
+	n := len(s.counters)
+	for {
+		least := i
+		if l := 2*i + 1; l < n && s.counters[l].count < s.counters[least].count {
+			least = l
+		}
+		if r := 2*i + 2; r < n && s.counters[r].count < s.counters[least].count {
+			least = r
+		}
+		if least == i {
+			return
+		}
+		s.swap(i, least)
+		i = least
+	}
+}
+
+// merge merges another sketch into s
+// following Agarwal et al., "Mergeable Summaries":
+// a value without a counter in one of the sketches
+// may have occurred as many times as that sketch's
+// smallest count; it returns the number of bytes added
+func (s *topkSketch) merge(o *topkSketch) int {
+	size := s.size
+	smin, omin := s.min(), o.min()
+	for i := range s.counters {
+		c := &s.counters[i]
+		if j, ok := o.index[c.key]; ok {
+			c.count += o.counters[j].count
+			c.err += o.counters[j].err
+		} else {
+			c.count += omin
+			c.err += omin
+		}
+	}
+	for i := range o.counters {
+		c := o.counters[i]
+		if _, ok := s.index[c.key]; ok {
+			continue
+		}
+		c.count += smin
+		c.err += smin
+		s.counters = append(s.counters, c)
+		s.size += c.size()
+	}
+	s.rebuild()
+	return s.size - size
+}
+
+// rebuild restores the heap and the index
+// after the counters have been modified,
+// dropping the least frequent values
+// beyond the capacity of the sketch
+func (s *topkSketch) rebuild() {
+	// an ascending order is a valid heap
+	sort.Slice(s.counters, func(i, j int) bool {
+		return s.counters[j].less(&s.counters[i])
+	})
+	if drop := len(s.counters) - s.capacity(); drop > 0 {
+		for i := range s.counters[:drop] {
+			s.size -= s.counters[i].size()
+		}
+		n := copy(s.counters, s.counters[drop:])
+		for i := range s.counters[n:] {
+			s.counters[n+i] = topkCounter{}
+		}
+		s.counters = s.counters[:n]
+	}
+	for k := range s.index {
+		delete(s.index, k)
+	}
+	for i := range s.counters {
+		s.index[s.counters[i].key] = i
+	}
+}
+
+// less orders the counters from the most
+// frequent value; the ties are resolved
+// by the keys, so that the order is stable
+func (c *topkCounter) less(o *topkCounter) bool {
+	if c.count != o.count {
+		return c.count > o.count
+	}
+	return c.key < o.key
+}
+
+// top returns the counters ordered
+// from the most frequent value
+func (s *topkSketch) top() []topkCounter {
+	top := make([]topkCounter, len(s.counters))
+	copy(top, s.counters)
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].less(&top[j])
+	})
+	return top
+}
+
+// writePartial writes all the counters as
+// a list of [value, count, err] lists,
+// which is the partial result of APPROX_TOP_K
+func (s *topkSketch) writePartial(dst *ion.Buffer, st *ion.Symtab) {
+	dst.BeginList(-1)
+	for _, c := range s.top() {
+		dst.BeginList(-1)
+		c.value.Encode(dst, st)
+		dst.WriteInt(c.count)
+		dst.WriteInt(c.err)
+		dst.EndList()
+	}
+	dst.EndList()
+}
+
+// writeFinal writes the k most frequent values
+// as a list of {value, count} structures
+func (s *topkSketch) writeFinal(dst *ion.Buffer, st *ion.Symtab) {
+	top := s.top()
+	if len(top) > s.k {
+		top = top[:s.k]
+	}
+	value := st.Intern("value")
+	count := st.Intern("count")
+	dst.BeginList(-1)
+	for i := range top {
+		dst.BeginStruct(-1)
+		dst.BeginField(value)
+		top[i].value.Encode(dst, st)
+		dst.BeginField(count)
+		dst.WriteInt(top[i].count)
+		dst.EndStruct()
+	}
+	dst.EndList()
+}
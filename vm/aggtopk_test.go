@@ -0,0 +1,118 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+func TestTopkSketch(t *testing.T) {
+	const k = 3
+	const rows = 100000
+
+	add := func(s *topkSketch, key string) {
+		if i, ok := s.lookup([]byte(key)); ok {
+			s.inc(i, 1, 0)
+			return
+		}
+		s.insert(topkCounter{key: key, value: ion.String(key), n: len(key), count: 1})
+	}
+	check := func(t *testing.T, s *topkSketch, exact map[string]int64, total int64) {
+		if len(s.counters) > s.capacity() {
+			t.Fatalf("%d counters exceed capacity %d", len(s.counters), s.capacity())
+		}
+		for i := 1; i < len(s.counters); i++ {
+			if s.counters[(i-1)/2].count > s.counters[i].count {
+				t.Fatalf("counter %d violates the heap order", i)
+			}
+		}
+		for i := range s.counters {
+			c := &s.counters[i]
+			if s.index[c.key] != i {
+				t.Fatalf("index of %q is %d, not %d", c.key, s.index[c.key], i)
+			}
+			real := exact[c.key]
+			if c.count < real || c.count-c.err > real {
+				t.Errorf("%q: count %d (error %d) does not bound %d", c.key, c.count, c.err, real)
+			}
+		}
+		// every value occurring more than
+		// total/capacity times has a counter
+		bound := total / int64(s.capacity())
+		for key, n := range exact {
+			if _, ok := s.index[key]; n > bound && !ok {
+				t.Errorf("heavy hitter %q (%d of %d) is not counted", key, n, total)
+			}
+		}
+		top := s.top()
+		for i := 0; i < k; i++ {
+			if want := fmt.Sprintf("heavy%d", i); top[i].key != want {
+				t.Errorf("top value %d is %q, not %q", i, top[i].key, want)
+			}
+		}
+	}
+
+	// the heavy hitters occur in 20%, 10% and 5%
+	// of the rows and the rest are mostly unique
+	rnd := rand.New(rand.NewSource(0))
+	value := func() string {
+		p := rnd.Intn(100)
+		switch {
+		case p < 20:
+			return "heavy0"
+		case p < 30:
+			return "heavy1"
+		case p < 35:
+			return "heavy2"
+		}
+		return fmt.Sprintf("v%d", rnd.Intn(rows))
+	}
+
+	exact := make(map[string]int64)
+	single := newTopkSketch(k)
+	parts := []*topkSketch{newTopkSketch(k), newTopkSketch(k), newTopkSketch(k)}
+	for i := 0; i < rows; i++ {
+		v := value()
+		exact[v]++
+		add(single, v)
+		add(parts[i%len(parts)], v)
+	}
+	t.Run("single", func(t *testing.T) {
+		check(t, single, exact, rows)
+	})
+	for _, s := range parts {
+		if len(s.counters) != s.capacity() {
+			t.Fatalf("expected a full sketch; got %d counters", len(s.counters))
+		}
+	}
+	t.Run("merge", func(t *testing.T) {
+		s := parts[0]
+		for _, o := range parts[1:] {
+			s.merge(o)
+		}
+		check(t, s, exact, rows)
+		size := 0
+		for i := range s.counters {
+			size += s.counters[i].size()
+		}
+		if size != s.size {
+			t.Errorf("size is %d; expected %d", s.size, size)
+		}
+	})
+}
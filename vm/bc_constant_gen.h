@@ -37,10 +37,12 @@ CONST_DATA_U64(constpool, 56, $12) // 0x000000000000000c
 CONST_DATA_U64(constpool, 64, $24) // 0x0000000000000018
 
 #define CONSTB_32() CONST_GET_PTR(constpool, 72)
+#define CONSTD_0x20() CONST_GET_PTR(constpool, 72)
 #define CONSTD_32() CONST_GET_PTR(constpool, 72)
 #define CONSTQ_32() CONST_GET_PTR(constpool, 72)
 CONST_DATA_U64(constpool, 72, $32) // 0x0000000000000020
 
+#define CONSTD_0x30() CONST_GET_PTR(constpool, 80)
 #define CONSTD_48() CONST_GET_PTR(constpool, 80)
 #define CONSTQ_48() CONST_GET_PTR(constpool, 80)
 CONST_DATA_U64(constpool, 80, $48) // 0x0000000000000030
@@ -235,209 +237,288 @@ CONST_DATA_U64(constpool, 536, $9223372036854775808) // 0x8000000000000000
 CONST_DATA_U64(constpool, 544, $18446744073709551615) // 0xffffffffffffffff
 
 // uint32 constants
-#define CONSTD_6() CONST_GET_PTR(constpool, 552)
-CONST_DATA_U32(constpool, 552, $6) // 0x00000006
+#define CONSTD_5() CONST_GET_PTR(constpool, 552)
+CONST_DATA_U32(constpool, 552, $5) // 0x00000005
 
-#define CONSTD_0x0B() CONST_GET_PTR(constpool, 556)
-CONST_DATA_U32(constpool, 556, $11) // 0x0000000b
+#define CONSTD_6() CONST_GET_PTR(constpool, 556)
+CONST_DATA_U32(constpool, 556, $6) // 0x00000006
 
-#define CONSTD_0x0D() CONST_GET_PTR(constpool, 560)
-#define CONSTD_13() CONST_GET_PTR(constpool, 560)
-CONST_DATA_U32(constpool, 560, $13) // 0x0000000d
+#define CONSTD_0x0B() CONST_GET_PTR(constpool, 560)
+CONST_DATA_U32(constpool, 560, $11) // 0x0000000b
 
-#define CONSTD_0x0E() CONST_GET_PTR(constpool, 564)
-#define CONSTD_14() CONST_GET_PTR(constpool, 564)
-CONST_DATA_U32(constpool, 564, $14) // 0x0000000e
+#define CONSTD_0x0D() CONST_GET_PTR(constpool, 564)
+#define CONSTD_13() CONST_GET_PTR(constpool, 564)
+CONST_DATA_U32(constpool, 564, $13) // 0x0000000d
 
-#define CONSTD_0x0F() CONST_GET_PTR(constpool, 568)
-#define CONSTD_15() CONST_GET_PTR(constpool, 568)
-CONST_DATA_U32(constpool, 568, $15) // 0x0000000f
+#define CONSTD_0x0E() CONST_GET_PTR(constpool, 568)
+#define CONSTD_14() CONST_GET_PTR(constpool, 568)
+CONST_DATA_U32(constpool, 568, $14) // 0x0000000e
 
-#define CONSTD_16() CONST_GET_PTR(constpool, 572)
-#define CONSTD_FALSE_BYTE() CONST_GET_PTR(constpool, 572)
-CONST_DATA_U32(constpool, 572, $16) // 0x00000010
+#define CONSTD_0x0F() CONST_GET_PTR(constpool, 572)
+#define CONSTD_15() CONST_GET_PTR(constpool, 572)
+CONST_DATA_U32(constpool, 572, $15) // 0x0000000f
 
-#define CONSTD_TRUE_BYTE() CONST_GET_PTR(constpool, 576)
-CONST_DATA_U32(constpool, 576, $17) // 0x00000011
+#define CONSTD_16() CONST_GET_PTR(constpool, 576)
+#define CONSTD_FALSE_BYTE() CONST_GET_PTR(constpool, 576)
+CONST_DATA_U32(constpool, 576, $16) // 0x00000010
 
-#define CONSTD_0x2E() CONST_GET_PTR(constpool, 580)
-CONST_DATA_U32(constpool, 580, $46) // 0x0000002e
+#define CONSTD_TRUE_BYTE() CONST_GET_PTR(constpool, 580)
+CONST_DATA_U32(constpool, 580, $17) // 0x00000011
 
-#define CONSTD_131() CONST_GET_PTR(constpool, 584)
-CONST_DATA_U32(constpool, 584, $131) // 0x00000083
+#define CONSTD_40() CONST_GET_PTR(constpool, 584)
+CONST_DATA_U32(constpool, 584, $40) // 0x00000028
 
-#define CONSTD_0xB0() CONST_GET_PTR(constpool, 588)
-CONST_DATA_U32(constpool, 588, $176) // 0x000000b0
+#define CONSTD_0x2E() CONST_GET_PTR(constpool, 588)
+CONST_DATA_U32(constpool, 588, $46) // 0x0000002e
 
-#define CONSTD_0b11000000() CONST_GET_PTR(constpool, 592)
-CONST_DATA_U32(constpool, 592, $192) // 0x000000c0
+#define CONSTD_72() CONST_GET_PTR(constpool, 592)
+CONST_DATA_U32(constpool, 592, $72) // 0x00000048
 
-#define CONSTD_0xD0() CONST_GET_PTR(constpool, 596)
-CONST_DATA_U32(constpool, 596, $208) // 0x000000d0
+#define CONSTB_97() CONST_GET_PTR(constpool, 596)
+#define CONSTD_0x61() CONST_GET_PTR(constpool, 596)
+CONST_DATA_U32(constpool, 596, $97) // 0x00000061
 
-#define CONSTD_0b11100000() CONST_GET_PTR(constpool, 600)
-CONST_DATA_U32(constpool, 600, $224) // 0x000000e0
+#define CONSTD_131() CONST_GET_PTR(constpool, 600)
+CONST_DATA_U32(constpool, 600, $131) // 0x00000083
 
-#define CONSTD_0b11110000() CONST_GET_PTR(constpool, 604)
-CONST_DATA_U32(constpool, 604, $240) // 0x000000f0
+#define CONSTD_0xB0() CONST_GET_PTR(constpool, 604)
+CONST_DATA_U32(constpool, 604, $176) // 0x000000b0
 
-#define CONSTD_0b11111000() CONST_GET_PTR(constpool, 608)
-CONST_DATA_U32(constpool, 608, $248) // 0x000000f8
+#define CONSTD_0b11000000() CONST_GET_PTR(constpool, 608)
+CONST_DATA_U32(constpool, 608, $192) // 0x000000c0
 
-#define CONSTD_0xFF() CONST_GET_PTR(constpool, 612)
-CONST_DATA_U32(constpool, 612, $255) // 0x000000ff
+#define CONSTD_0xD0() CONST_GET_PTR(constpool, 612)
+CONST_DATA_U32(constpool, 612, $208) // 0x000000d0
 
-#define CONSTD_5243() CONST_GET_PTR(constpool, 616)
-CONST_DATA_U32(constpool, 616, $5243) // 0x0000147b
+#define CONSTD_0b11100000() CONST_GET_PTR(constpool, 616)
+CONST_DATA_U32(constpool, 616, $224) // 0x000000e0
 
-#define CONSTD_6554() CONST_GET_PTR(constpool, 620)
-CONST_DATA_U32(constpool, 620, $6554) // 0x0000199a
+#define CONSTD_0b11110000() CONST_GET_PTR(constpool, 620)
+CONST_DATA_U32(constpool, 620, $240) // 0x000000f0
 
-#define CONSTD_0x3FFF() CONST_GET_PTR(constpool, 624)
-CONST_DATA_U32(constpool, 624, $16383) // 0x00003fff
+#define CONSTD_0b11111000() CONST_GET_PTR(constpool, 624)
+CONST_DATA_U32(constpool, 624, $248) // 0x000000f8
 
-#define CONSTD_16388() CONST_GET_PTR(constpool, 628)
-CONST_DATA_U32(constpool, 628, $16388) // 0x00004004
+#define CONSTD_0xFF() CONST_GET_PTR(constpool, 628)
+CONST_DATA_U32(constpool, 628, $255) // 0x000000ff
 
-#define CONSTD_0x10101() CONST_GET_PTR(constpool, 632)
-CONST_DATA_U32(constpool, 632, $65793) // 0x00010101
+#define CONSTD_5243() CONST_GET_PTR(constpool, 632)
+CONST_DATA_U32(constpool, 632, $5243) // 0x0000147b
 
-#define CONSTD_0x10801() CONST_GET_PTR(constpool, 636)
-CONST_DATA_U32(constpool, 636, $67585) // 0x00010801
+#define CONSTD_6554() CONST_GET_PTR(constpool, 636)
+CONST_DATA_U32(constpool, 636, $6554) // 0x0000199a
 
-#define CONSTD_0x400001() CONST_GET_PTR(constpool, 640)
-CONST_DATA_U32(constpool, 640, $4194305) // 0x00400001
+#define CONSTD_0x3FFF() CONST_GET_PTR(constpool, 640)
+CONST_DATA_U32(constpool, 640, $16383) // 0x00003fff
 
-#define CONSTD_0x007F007F() CONST_GET_PTR(constpool, 644)
-CONST_DATA_U32(constpool, 644, $8323199) // 0x007f007f
+#define CONSTD_16388() CONST_GET_PTR(constpool, 644)
+CONST_DATA_U32(constpool, 644, $16388) // 0x00004004
 
-#define CONSTD_0x01010101() CONST_GET_PTR(constpool, 648)
-CONST_DATA_U32(constpool, 648, $16843009) // 0x01010101
+#define CONSTD_0x10101() CONST_GET_PTR(constpool, 648)
+CONST_DATA_U32(constpool, 648, $65793) // 0x00010101
 
-#define CONSTD_134217727() CONST_GET_PTR(constpool, 652)
-CONST_DATA_U32(constpool, 652, $134217727) // 0x07ffffff
+#define CONSTD_0x10801() CONST_GET_PTR(constpool, 652)
+CONST_DATA_U32(constpool, 652, $67585) // 0x00010801
 
-#define CONSTD_0x0F0F0F0F() CONST_GET_PTR(constpool, 656)
-CONST_DATA_U32(constpool, 656, $252645135) // 0x0f0f0f0f
+#define CONSTD_0x400001() CONST_GET_PTR(constpool, 656)
+CONST_DATA_U32(constpool, 656, $4194305) // 0x00400001
 
-#define CONSTD_0x3FFFFFFF() CONST_GET_PTR(constpool, 660)
-CONST_DATA_U32(constpool, 660, $1073741823) // 0x3fffffff
+#define CONSTD_0x007F007F() CONST_GET_PTR(constpool, 660)
+CONST_DATA_U32(constpool, 660, $8323199) // 0x007f007f
 
-#define CONSTD_UTF8_4B_MASK() CONST_GET_PTR(constpool, 664)
-CONST_DATA_U32(constpool, 664, $2155905264) // 0x808080f0
+#define CONSTD_0x01010101() CONST_GET_PTR(constpool, 664)
+CONST_DATA_U32(constpool, 664, $16843009) // 0x01010101
 
-#define CONSTD_UTF8_3B_MASK() CONST_GET_PTR(constpool, 668)
-CONST_DATA_U32(constpool, 668, $2155929600) // 0x8080e000
+#define CONSTD_0x01100110() CONST_GET_PTR(constpool, 668)
+CONST_DATA_U32(constpool, 668, $17826064) // 0x01100110
 
-#define CONSTD_UTF8_2B_MASK() CONST_GET_PTR(constpool, 672)
-CONST_DATA_U32(constpool, 672, $2160066560) // 0x80c00000
+#define CONSTD_134217727() CONST_GET_PTR(constpool, 672)
+CONST_DATA_U32(constpool, 672, $134217727) // 0x07ffffff
 
-#define CONSTD_0b11001110_01110011_10011100_11100111() CONST_GET_PTR(constpool, 676)
-CONST_DATA_U32(constpool, 676, $3463683303) // 0xce739ce7
+#define CONSTD_0x0F000F00() CONST_GET_PTR(constpool, 676)
+CONST_DATA_U32(constpool, 676, $251662080) // 0x0f000f00
 
-#define CONSTD_0xFFFF0000() CONST_GET_PTR(constpool, 680)
-CONST_DATA_U32(constpool, 680, $4294901760) // 0xffff0000
+#define CONSTD_0x0F0F0F0F() CONST_GET_PTR(constpool, 680)
+CONST_DATA_U32(constpool, 680, $252645135) // 0x0f0f0f0f
 
-// uint8 constants
-#define CONSTB_97() CONST_GET_PTR(constpool, 684)
-CONST_DATA_U8(constpool, 684, $97) // 0x61
+#define CONSTD_0x10325476() CONST_GET_PTR(constpool, 684)
+CONST_DATA_U32(constpool, 684, $271733878) // 0x10325476
+
+#define CONSTD_0x1F83D9AB() CONST_GET_PTR(constpool, 688)
+CONST_DATA_U32(constpool, 688, $528734635) // 0x1f83d9ab
+
+#define CONSTD_0x3C6EF372() CONST_GET_PTR(constpool, 692)
+CONST_DATA_U32(constpool, 692, $1013904242) // 0x3c6ef372
+
+#define CONSTD_0x3FFFFFFF() CONST_GET_PTR(constpool, 696)
+CONST_DATA_U32(constpool, 696, $1073741823) // 0x3fffffff
+
+#define CONSTD_0x510E527F() CONST_GET_PTR(constpool, 700)
+CONST_DATA_U32(constpool, 700, $1359893119) // 0x510e527f
+
+#define CONSTD_0x5A827999() CONST_GET_PTR(constpool, 704)
+CONST_DATA_U32(constpool, 704, $1518500249) // 0x5a827999
+
+#define CONSTD_0x5BE0CD19() CONST_GET_PTR(constpool, 708)
+CONST_DATA_U32(constpool, 708, $1541459225) // 0x5be0cd19
+
+#define CONSTD_0x67452301() CONST_GET_PTR(constpool, 712)
+CONST_DATA_U32(constpool, 712, $1732584193) // 0x67452301
+
+#define CONSTD_0x6A09E667() CONST_GET_PTR(constpool, 716)
+CONST_DATA_U32(constpool, 716, $1779033703) // 0x6a09e667
+
+#define CONSTD_0x6ED9EBA1() CONST_GET_PTR(constpool, 720)
+CONST_DATA_U32(constpool, 720, $1859775393) // 0x6ed9eba1
+
+#define CONSTD_UTF8_4B_MASK() CONST_GET_PTR(constpool, 724)
+CONST_DATA_U32(constpool, 724, $2155905264) // 0x808080f0
+
+#define CONSTD_UTF8_3B_MASK() CONST_GET_PTR(constpool, 728)
+CONST_DATA_U32(constpool, 728, $2155929600) // 0x8080e000
+
+#define CONSTD_UTF8_2B_MASK() CONST_GET_PTR(constpool, 732)
+CONST_DATA_U32(constpool, 732, $2160066560) // 0x80c00000
+
+#define CONSTD_0x8F1BBCDC() CONST_GET_PTR(constpool, 736)
+CONST_DATA_U32(constpool, 736, $2400959708) // 0x8f1bbcdc
 
-#define CONSTB_122() CONST_GET_PTR(constpool, 685)
-CONST_DATA_U8(constpool, 685, $122) // 0x7a
+#define CONSTD_0x98BADCFE() CONST_GET_PTR(constpool, 740)
+CONST_DATA_U32(constpool, 740, $2562383102) // 0x98badcfe
+
+#define CONSTD_0x9B05688C() CONST_GET_PTR(constpool, 744)
+CONST_DATA_U32(constpool, 744, $2600822924) // 0x9b05688c
+
+#define CONSTD_0xA54FF53A() CONST_GET_PTR(constpool, 748)
+CONST_DATA_U32(constpool, 748, $2773480762) // 0xa54ff53a
+
+#define CONSTD_0xAAAAAAAB() CONST_GET_PTR(constpool, 752)
+CONST_DATA_U32(constpool, 752, $2863311531) // 0xaaaaaaab
+
+#define CONSTD_0xBB67AE85() CONST_GET_PTR(constpool, 756)
+CONST_DATA_U32(constpool, 756, $3144134277) // 0xbb67ae85
+
+#define CONSTD_0xC3D2E1F0() CONST_GET_PTR(constpool, 760)
+CONST_DATA_U32(constpool, 760, $3285377520) // 0xc3d2e1f0
+
+#define CONSTD_0xCA62C1D6() CONST_GET_PTR(constpool, 764)
+CONST_DATA_U32(constpool, 764, $3395469782) // 0xca62c1d6
+
+#define CONSTD_0b11001110_01110011_10011100_11100111() CONST_GET_PTR(constpool, 768)
+CONST_DATA_U32(constpool, 768, $3463683303) // 0xce739ce7
+
+#define CONSTD_0xEFCDAB89() CONST_GET_PTR(constpool, 772)
+CONST_DATA_U32(constpool, 772, $4023233417) // 0xefcdab89
+
+#define CONSTD_0xFFFF0000() CONST_GET_PTR(constpool, 776)
+CONST_DATA_U32(constpool, 776, $4294901760) // 0xffff0000
+
+// uint8 constants
+#define CONSTB_122() CONST_GET_PTR(constpool, 780)
+CONST_DATA_U8(constpool, 780, $122) // 0x7a
 
 // float32 constants
-#define CONSTF32_16_RECI() CONST_GET_PTR(constpool, 686)
-CONST_DATA_U32(constpool, 686, $0x000000003d800000) // float32(0.062500)
+#define CONSTF32_16_RECI() CONST_GET_PTR(constpool, 781)
+CONST_DATA_U32(constpool, 781, $0x000000003d800000) // float32(0.062500)
 
-#define CONSTF32_PI_TIMES_16_RECI() CONST_GET_PTR(constpool, 690)
-CONST_DATA_U32(constpool, 690, $0x000000003e490fdb) // float32(0.196350)
+#define CONSTF32_PI_TIMES_16_RECI() CONST_GET_PTR(constpool, 785)
+CONST_DATA_U32(constpool, 785, $0x000000003e490fdb) // float32(0.196350)
 
-#define CONSTF32_PI_RECI() CONST_GET_PTR(constpool, 694)
-CONST_DATA_U32(constpool, 694, $0x000000003ea2f983) // float32(0.318310)
+#define CONSTF32_PI_RECI() CONST_GET_PTR(constpool, 789)
+CONST_DATA_U32(constpool, 789, $0x000000003ea2f983) // float32(0.318310)
 
-#define CONSTF32_2_RECI() CONST_GET_PTR(constpool, 698)
-CONST_DATA_U32(constpool, 698, $0x000000003f000000) // float32(0.500000)
+#define CONSTF32_2_RECI() CONST_GET_PTR(constpool, 793)
+CONST_DATA_U32(constpool, 793, $0x000000003f000000) // float32(0.500000)
 
-#define CONSTF32_1() CONST_GET_PTR(constpool, 702)
-CONST_DATA_U32(constpool, 702, $0x000000003f800000) // float32(1.000000)
+#define CONSTF32_1() CONST_GET_PTR(constpool, 797)
+CONST_DATA_U32(constpool, 797, $0x000000003f800000) // float32(1.000000)
 
-#define CONSTF32_HALF_PI() CONST_GET_PTR(constpool, 706)
-CONST_DATA_U32(constpool, 706, $0x000000003fc90fdb) // float32(1.570796)
+#define CONSTF32_HALF_PI() CONST_GET_PTR(constpool, 801)
+CONST_DATA_U32(constpool, 801, $0x000000003fc90fdb) // float32(1.570796)
 
-#define CONSTF32_2() CONST_GET_PTR(constpool, 710)
-CONST_DATA_U32(constpool, 710, $0x0000000040000000) // float32(2.000000)
+#define CONSTF32_2() CONST_GET_PTR(constpool, 805)
+CONST_DATA_U32(constpool, 805, $0x0000000040000000) // float32(2.000000)
 
-#define CONSTF32_16_TIMES_PI_RECI() CONST_GET_PTR(constpool, 714)
-CONST_DATA_U32(constpool, 714, $0x0000000040a2f983) // float32(5.092958)
+#define CONSTF32_16_TIMES_PI_RECI() CONST_GET_PTR(constpool, 809)
+CONST_DATA_U32(constpool, 809, $0x0000000040a2f983) // float32(5.092958)
 
-#define CONSTF32_16() CONST_GET_PTR(constpool, 718)
-CONST_DATA_U32(constpool, 718, $0x0000000041800000) // float32(16.000000)
+#define CONSTF32_16() CONST_GET_PTR(constpool, 813)
+CONST_DATA_U32(constpool, 813, $0x0000000041800000) // float32(16.000000)
 
-#define CONSTF32_POSITIVE_INF() CONST_GET_PTR(constpool, 722)
-CONST_DATA_U32(constpool, 722, $0x000000007f800000) // float32(+Inf)
+#define CONSTF32_POSITIVE_INF() CONST_GET_PTR(constpool, 817)
+CONST_DATA_U32(constpool, 817, $0x000000007f800000) // float32(+Inf)
 
-#define CONSTF32_NEGATIVE_INF() CONST_GET_PTR(constpool, 726)
-CONST_DATA_U32(constpool, 726, $0x00000000ff800000) // float32(-Inf)
+#define CONSTF32_NEGATIVE_INF() CONST_GET_PTR(constpool, 821)
+CONST_DATA_U32(constpool, 821, $0x00000000ff800000) // float32(-Inf)
 
 // float64 constants
-#define CONSTF64_PI_DIV_180() CONST_GET_PTR(constpool, 730)
-CONST_DATA_U64(constpool, 730, $0x3f91df46a2529d39) // float64(0.017453)
+#define CONSTF64_PI_DIV_180() CONST_GET_PTR(constpool, 825)
+CONST_DATA_U64(constpool, 825, $0x3f91df46a2529d39) // float64(0.017453)
+
+#define CONSTF64_HALF() CONST_GET_PTR(constpool, 833)
+CONST_DATA_U64(constpool, 833, $0x3fe0000000000000) // float64(0.500000)
+
+#define CONSTF64_0p9999() CONST_GET_PTR(constpool, 841)
+CONST_DATA_U64(constpool, 841, $0x3fefff2e48e8a71e) // float64(0.999900)
+
+#define CONSTF64_1() CONST_GET_PTR(constpool, 849)
+CONST_DATA_U64(constpool, 849, $0x3ff0000000000000) // float64(1.000000)
 
-#define CONSTF64_HALF() CONST_GET_PTR(constpool, 738)
-CONST_DATA_U64(constpool, 738, $0x3fe0000000000000) // float64(0.500000)
+#define CONSTF64_2() CONST_GET_PTR(constpool, 857)
+CONST_DATA_U64(constpool, 857, $0x4000000000000000) // float64(2.000000)
 
-#define CONSTF64_0p9999() CONST_GET_PTR(constpool, 746)
-CONST_DATA_U64(constpool, 746, $0x3fefff2e48e8a71e) // float64(0.999900)
+#define CONSTF64_3() CONST_GET_PTR(constpool, 865)
+CONST_DATA_U64(constpool, 865, $0x4008000000000000) // float64(3.000000)
 
-#define CONSTF64_1() CONST_GET_PTR(constpool, 754)
-CONST_DATA_U64(constpool, 754, $0x3ff0000000000000) // float64(1.000000)
+#define CONSTF64_4() CONST_GET_PTR(constpool, 873)
+CONST_DATA_U64(constpool, 873, $0x4010000000000000) // float64(4.000000)
 
-#define CONSTF64_4() CONST_GET_PTR(constpool, 762)
-CONST_DATA_U64(constpool, 762, $0x4010000000000000) // float64(4.000000)
+#define CONSTF64_6() CONST_GET_PTR(constpool, 881)
+CONST_DATA_U64(constpool, 881, $0x4018000000000000) // float64(6.000000)
 
-#define CONSTF64_7() CONST_GET_PTR(constpool, 770)
-CONST_DATA_U64(constpool, 770, $0x401c000000000000) // float64(7.000000)
+#define CONSTF64_7() CONST_GET_PTR(constpool, 889)
+CONST_DATA_U64(constpool, 889, $0x401c000000000000) // float64(7.000000)
 
-#define CONSTF64_11() CONST_GET_PTR(constpool, 778)
-CONST_DATA_U64(constpool, 778, $0x4026000000000000) // float64(11.000000)
+#define CONSTF64_11() CONST_GET_PTR(constpool, 897)
+CONST_DATA_U64(constpool, 897, $0x4026000000000000) // float64(11.000000)
 
-#define CONSTF64_12() CONST_GET_PTR(constpool, 786)
-CONST_DATA_U64(constpool, 786, $0x4028000000000000) // float64(12.000000)
+#define CONSTF64_12() CONST_GET_PTR(constpool, 905)
+CONST_DATA_U64(constpool, 905, $0x4028000000000000) // float64(12.000000)
 
-#define CONSTF64_65536() CONST_GET_PTR(constpool, 794)
-CONST_DATA_U64(constpool, 794, $0x40f0000000000000) // float64(65536.000000)
+#define CONSTF64_65536() CONST_GET_PTR(constpool, 913)
+CONST_DATA_U64(constpool, 913, $0x40f0000000000000) // float64(65536.000000)
 
-#define CONSTF64_MICROSECONDS_IN_1_DAY_SHR_13() CONST_GET_PTR(constpool, 802)
-CONST_DATA_U64(constpool, 802, $0x41641dd760000000) // float64(10546875.000000)
+#define CONSTF64_MICROSECONDS_IN_1_DAY_SHR_13() CONST_GET_PTR(constpool, 921)
+CONST_DATA_U64(constpool, 921, $0x41641dd760000000) // float64(10546875.000000)
 
-#define CONSTF64_12742000() CONST_GET_PTR(constpool, 810)
-CONST_DATA_U64(constpool, 810, $0x41684dae00000000) // float64(12742000.000000)
+#define CONSTF64_12742000() CONST_GET_PTR(constpool, 929)
+CONST_DATA_U64(constpool, 929, $0x41684dae00000000) // float64(12742000.000000)
 
-#define CONSTF64_100000000() CONST_GET_PTR(constpool, 818)
-CONST_DATA_U64(constpool, 818, $0x4197d78400000000) // float64(100000000.000000)
+#define CONSTF64_100000000() CONST_GET_PTR(constpool, 937)
+CONST_DATA_U64(constpool, 937, $0x4197d78400000000) // float64(100000000.000000)
 
-#define CONSTF64_152587890625() CONST_GET_PTR(constpool, 826)
-CONST_DATA_U64(constpool, 826, $0x4241c37937e08000) // float64(152587890625.000000)
+#define CONSTF64_152587890625() CONST_GET_PTR(constpool, 945)
+CONST_DATA_U64(constpool, 945, $0x4241c37937e08000) // float64(152587890625.000000)
 
-#define CONSTF64_281474976710656_DIV_360() CONST_GET_PTR(constpool, 834)
-CONST_DATA_U64(constpool, 834, $0x4266c16c16c16c17) // float64(781874935307.377808)
+#define CONSTF64_281474976710656_DIV_360() CONST_GET_PTR(constpool, 953)
+CONST_DATA_U64(constpool, 953, $0x4266c16c16c16c17) // float64(781874935307.377808)
 
-#define CONSTF64_281474976710656_DIV_4PI() CONST_GET_PTR(constpool, 842)
-CONST_DATA_U64(constpool, 842, $0x42b45f306dc9c883) // float64(22399066950088.511719)
+#define CONSTF64_281474976710656_DIV_4PI() CONST_GET_PTR(constpool, 961)
+CONST_DATA_U64(constpool, 961, $0x42b45f306dc9c883) // float64(22399066950088.511719)
 
-#define CONSTF64_140737488355328() CONST_GET_PTR(constpool, 850)
-CONST_DATA_U64(constpool, 850, $0x42e0000000000000) // float64(140737488355328.000000)
+#define CONSTF64_140737488355328() CONST_GET_PTR(constpool, 969)
+CONST_DATA_U64(constpool, 969, $0x42e0000000000000) // float64(140737488355328.000000)
 
-#define CONSTF64_POSITIVE_INF() CONST_GET_PTR(constpool, 858)
-CONST_DATA_U64(constpool, 858, $0x7ff0000000000000) // float64(+Inf)
+#define CONSTF64_POSITIVE_INF() CONST_GET_PTR(constpool, 977)
+CONST_DATA_U64(constpool, 977, $0x7ff0000000000000) // float64(+Inf)
 
-#define CONSTF64_NAN() CONST_GET_PTR(constpool, 866)
-CONST_DATA_U64(constpool, 866, $0x7ff8000000000001) // float64(NaN)
+#define CONSTF64_NAN() CONST_GET_PTR(constpool, 985)
+CONST_DATA_U64(constpool, 985, $0x7ff8000000000001) // float64(NaN)
 
-#define CONSTF64_MINUS_0p9999() CONST_GET_PTR(constpool, 874)
-CONST_DATA_U64(constpool, 874, $0xbfefff2e48e8a71e) // float64(-0.999900)
+#define CONSTF64_MINUS_0p9999() CONST_GET_PTR(constpool, 993)
+CONST_DATA_U64(constpool, 993, $0xbfefff2e48e8a71e) // float64(-0.999900)
 
-#define CONSTF64_NEGATIVE_INF() CONST_GET_PTR(constpool, 882)
-CONST_DATA_U64(constpool, 882, $0xfff0000000000000) // float64(-Inf)
+#define CONSTF64_NEGATIVE_INF() CONST_GET_PTR(constpool, 1001)
+CONST_DATA_U64(constpool, 1001, $0xfff0000000000000) // float64(-Inf)
 
-CONST_GLOBAL(constpool, $890)
+CONST_GLOBAL(constpool, $1009)
@@ -0,0 +1,193 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrMemoryLimit is wrapped by the errors
+// returned when a query exceeds the memory
+// limit of its Budget.
+var ErrMemoryLimit = errors.New("query memory limit exceeded")
+
+// Budget accounts for the memory used by one query.
+//
+// The vm pages that a query uses to decode its input
+// (see Budget.Malloc) and the memory that HashAggregate,
+// CollectAggregate, Order, DistinctFilter and Unnest
+// allocate for their hash tables, collected values,
+// sorted rows and buffers are charged to the Budget
+// passed to their SetBudget methods.
+// Operators that can spill to disk (see AggregateSpillMemory
+// and SortMemory) spill instead of failing once the
+// Budget is exhausted.
+//
+// A nil *Budget is valid; it accounts
+// for nothing and never fails.
+type Budget struct {
+	limit int64
+	used  int64 // atomic
+	peak  int64 // atomic
+}
+
+// NewBudget returns a Budget that limits a query
+// to limit bytes of memory. If limit is zero or
+// negative, memory is accounted for but not limited.
+func NewBudget(limit int64) *Budget {
+	return &Budget{limit: limit}
+}
+
+// Limit returns the limit passed to NewBudget.
+func (b *Budget) Limit() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.limit
+}
+
+// Used returns the number of bytes currently charged to b.
+func (b *Budget) Used() int64 {
+	if b == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&b.used)
+}
+
+// Peak returns the largest number of bytes
+// that have been charged to b at once.
+func (b *Budget) Peak() int64 {
+	if b == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&b.peak)
+}
+
+func (b *Budget) add(n int64) {
+	b.setPeak(atomic.AddInt64(&b.used, n))
+}
+
+func (b *Budget) setPeak(used int64) {
+	for {
+		peak := atomic.LoadInt64(&b.peak)
+		if used <= peak || atomic.CompareAndSwapInt64(&b.peak, peak, used) {
+			return
+		}
+	}
+}
+
+func (b *Budget) errorf(n int64) error {
+	return fmt.Errorf("%w: cannot use %d more bytes with %d of %d bytes in use",
+		ErrMemoryLimit, n, atomic.LoadInt64(&b.used), b.limit)
+}
+
+// Reserve charges n bytes to b.
+// If the charge would exceed the limit, nothing
+// is charged and an error wrapping ErrMemoryLimit
+// is returned.
+func (b *Budget) Reserve(n int64) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+	for {
+		used := atomic.LoadInt64(&b.used)
+		if b.limit > 0 && used+n > b.limit {
+			return b.errorf(n)
+		}
+		if atomic.CompareAndSwapInt64(&b.used, used, used+n) {
+			b.setPeak(used + n)
+			return nil
+		}
+	}
+}
+
+// Charge is like Reserve, but it always
+// charges n bytes. Charge is used for memory
+// that cannot be refused; the caller should
+// use Check to fail the query at the next
+// opportunity.
+func (b *Budget) Charge(n int64) {
+	if b != nil {
+		b.add(n)
+	}
+}
+
+// Release releases n bytes charged
+// with Reserve or Charge.
+func (b *Budget) Release(n int64) {
+	if b != nil && n > 0 {
+		atomic.AddInt64(&b.used, -n)
+	}
+}
+
+// Check returns an error wrapping ErrMemoryLimit
+// if more memory is charged to b than its limit.
+func (b *Budget) Check() error {
+	if b == nil || b.limit <= 0 || atomic.LoadInt64(&b.used) <= b.limit {
+		return nil
+	}
+	return b.errorf(0)
+}
+
+// Malloc allocates a page with the package-level
+// Malloc and charges PageSize bytes to b.
+func (b *Budget) Malloc() []byte {
+	b.Charge(PageSize)
+	return Malloc()
+}
+
+// Free frees a page returned by b.Malloc.
+func (b *Budget) Free(buf []byte) {
+	Free(buf)
+	b.Release(PageSize)
+}
+
+// memacct tracks the memory charged
+// to a Budget by one data structure
+type memacct struct {
+	budget *Budget
+	size   int64
+}
+
+// resize updates the charge to size bytes;
+// growing the charge may fail, but shrinking
+// it never does
+func (m *memacct) resize(size int) error {
+	n := int64(size)
+	if n > m.size {
+		if err := m.budget.Reserve(n - m.size); err != nil {
+			return err
+		}
+	} else {
+		m.budget.Release(m.size - n)
+	}
+	m.size = n
+	return nil
+}
+
+// release releases the whole charge
+func (m *memacct) release() {
+	m.budget.Release(m.size)
+	m.size = 0
+}
+
+// moveTo moves the charge to dst
+// (which must have the same budget)
+func (m *memacct) moveTo(dst *memacct) {
+	dst.size += m.size
+	m.size = 0
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+)
+
+func TestBudget(t *testing.T) {
+	b := NewBudget(100)
+	if err := b.Reserve(60); err != nil {
+		t.Fatal(err)
+	}
+	err := b.Reserve(60)
+	if !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("expected ErrMemoryLimit; got %v", err)
+	}
+	if b.Used() != 60 {
+		t.Fatalf("failed Reserve charged the budget: %d bytes used", b.Used())
+	}
+	b.Charge(60)
+	if err := b.Check(); !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("expected ErrMemoryLimit from Check; got %v", err)
+	}
+	b.Release(60)
+	if err := b.Check(); err != nil {
+		t.Fatal(err)
+	}
+	b.Release(60)
+	if b.Used() != 0 || b.Peak() != 120 {
+		t.Fatalf("used = %d, peak = %d", b.Used(), b.Peak())
+	}
+
+	// a nil budget accounts for nothing
+	var none *Budget
+	if err := none.Reserve(1 << 40); err != nil {
+		t.Fatal(err)
+	}
+	none.Free(none.Malloc())
+	if none.Used() != 0 {
+		t.Fatal("nil budget used memory")
+	}
+}
+
+func TestBudgetLimit(t *testing.T) {
+	buf, err := os.ReadFile("../testdata/nyc-taxi.block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(agg, sort int, dir string) {
+		AggregateSpillMemory, SortMemory, SpillDir = agg, sort, dir
+	}(AggregateSpillMemory, SortMemory, SpillDir)
+	SpillDir = t.TempDir()
+
+	const limit = 64 * 1024
+	group := Selection{{Expr: path(t, "tpep_pickup_datetime")}}
+	agg := Aggregation{mkagg(expr.OpCount, "VendorID", "count")}
+
+	// run writes the input into dst and closes it
+	run := func(dst QuerySink) error {
+		intable := &looptable{chunk: buf, count: 4}
+		err := intable.WriteChunks(dst, int(intable.count))
+		if err != nil {
+			return err
+		}
+		return dst.Close()
+	}
+
+	t.Run("aggregate", func(t *testing.T) {
+		AggregateSpillMemory = 0
+		b := NewBudget(limit)
+		ha, err := NewHashAggregate(agg, nil, group, &QueryBuffer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ha.SetBudget(b)
+		err = run(ha)
+		if !errors.Is(err, ErrMemoryLimit) {
+			t.Fatalf("expected ErrMemoryLimit; got %v", err)
+		}
+	})
+	t.Run("aggregate-spill", func(t *testing.T) {
+		AggregateSpillMemory = MaxAggregateMemory / 2
+		b := NewBudget(limit)
+		var qb QueryBuffer
+		ha, err := NewHashAggregate(agg, nil, group, &qb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ha.SetBudget(b)
+		if err := run(ha); err != nil {
+			t.Fatal(err)
+		}
+		if b.Used() != 0 {
+			t.Errorf("%d bytes still charged after Close", b.Used())
+		}
+		if b.Peak() == 0 {
+			t.Error("nothing was charged")
+		}
+	})
+	t.Run("aggregate-spill-overhead", func(t *testing.T) {
+		// spilling cannot make room for the
+		// fixed overhead of the tables
+		AggregateSpillMemory = MaxAggregateMemory / 2
+		b := NewBudget(256)
+		ha, err := NewHashAggregate(agg, nil, group, &QueryBuffer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ha.SetBudget(b)
+		err = run(ha)
+		if !errors.Is(err, ErrMemoryLimit) {
+			t.Fatalf("expected ErrMemoryLimit; got %v", err)
+		}
+		if b.Peak() > 256 {
+			t.Errorf("peak %d exceeds limit %d", b.Peak(), 256)
+		}
+	})
+	t.Run("collect", func(t *testing.T) {
+		b := NewBudget(limit)
+		collect := Aggregation{mkagg(expr.OpArrayAgg, "tpep_pickup_datetime", "times")}
+		ca, err := NewCollectAggregate(collect, nil, &QueryBuffer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ca.SetBudget(b)
+		err = run(ca)
+		if !errors.Is(err, ErrMemoryLimit) {
+			t.Fatalf("expected ErrMemoryLimit; got %v", err)
+		}
+		ca.Close()
+		if b.Used() != 0 {
+			t.Errorf("%d bytes still charged after Close", b.Used())
+		}
+	})
+	t.Run("distinct", func(t *testing.T) {
+		b := NewBudget(1024)
+		df, err := NewDistinct([]expr.Node{path(t, "tpep_pickup_datetime")}, &QueryBuffer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		df.SetBudget(b)
+		err = run(df)
+		if !errors.Is(err, ErrMemoryLimit) {
+			t.Fatalf("expected ErrMemoryLimit; got %v", err)
+		}
+	})
+	t.Run("order", func(t *testing.T) {
+		SortMemory = 64 << 20
+		b := NewBudget(limit)
+		var qb QueryBuffer
+		orderBy := []SortColumn{makeOrdering("tpep_pickup_datetime", SortAscending, SortNullsFirst)}
+		ord, err := NewOrder(&qb, orderBy, nil, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ord.SetBudget(b)
+		// the rows are spilled rather
+		// than failing the query
+		if err := run(ord); err != nil {
+			t.Fatal(err)
+		}
+		if b.Used() != 0 {
+			t.Errorf("%d bytes still charged after Close", b.Used())
+		}
+		if b.Peak() > limit {
+			t.Errorf("peak %d exceeds limit %d", b.Peak(), limit)
+		}
+	})
+}
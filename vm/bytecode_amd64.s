@@ -333,29 +333,29 @@ DATA opaddrs+0xa38(SB)/8, $bcslower(SB)
 DATA opaddrs+0xa40(SB)/8, $bcsupper(SB)
 DATA opaddrs+0xa48(SB)/8, $bcaggapproxcount(SB)
 DATA opaddrs+0xa50(SB)/8, $bcaggslotapproxcount(SB)
-DATA opaddrs+0xa58(SB)/8, $bcpowuintf64(SB)
-DATA opaddrs+0xa60(SB)/8, $bctrap(SB)
-DATA opaddrs+0xa68(SB)/8, $bctrap(SB)
-DATA opaddrs+0xa70(SB)/8, $bctrap(SB)
-DATA opaddrs+0xa78(SB)/8, $bctrap(SB)
-DATA opaddrs+0xa80(SB)/8, $bctrap(SB)
-DATA opaddrs+0xa88(SB)/8, $bctrap(SB)
-DATA opaddrs+0xa90(SB)/8, $bctrap(SB)
-DATA opaddrs+0xa98(SB)/8, $bctrap(SB)
-DATA opaddrs+0xaa0(SB)/8, $bctrap(SB)
-DATA opaddrs+0xaa8(SB)/8, $bctrap(SB)
-DATA opaddrs+0xab0(SB)/8, $bctrap(SB)
-DATA opaddrs+0xab8(SB)/8, $bctrap(SB)
-DATA opaddrs+0xac0(SB)/8, $bctrap(SB)
-DATA opaddrs+0xac8(SB)/8, $bctrap(SB)
-DATA opaddrs+0xad0(SB)/8, $bctrap(SB)
-DATA opaddrs+0xad8(SB)/8, $bctrap(SB)
-DATA opaddrs+0xae0(SB)/8, $bctrap(SB)
-DATA opaddrs+0xae8(SB)/8, $bctrap(SB)
-DATA opaddrs+0xaf0(SB)/8, $bctrap(SB)
-DATA opaddrs+0xaf8(SB)/8, $bctrap(SB)
-DATA opaddrs+0xb00(SB)/8, $bctrap(SB)
-DATA opaddrs+0xb08(SB)/8, $bctrap(SB)
+DATA opaddrs+0xa58(SB)/8, $bcmd5str(SB)
+DATA opaddrs+0xa60(SB)/8, $bcsha1str(SB)
+DATA opaddrs+0xa68(SB)/8, $bcsha256str(SB)
+DATA opaddrs+0xa70(SB)/8, $bcxxhash64str(SB)
+DATA opaddrs+0xa78(SB)/8, $bchashtoi64(SB)
+DATA opaddrs+0xa80(SB)/8, $bctohexstr(SB)
+DATA opaddrs+0xa88(SB)/8, $bcfromhexstr(SB)
+DATA opaddrs+0xa90(SB)/8, $bctobase64str(SB)
+DATA opaddrs+0xa98(SB)/8, $bcfrombase64str(SB)
+DATA opaddrs+0xaa0(SB)/8, $bcurlhost(SB)
+DATA opaddrs+0xaa8(SB)/8, $bcurlpath(SB)
+DATA opaddrs+0xab0(SB)/8, $bcurlquery(SB)
+DATA opaddrs+0xab8(SB)/8, $bcurlqueryparam(SB)
+DATA opaddrs+0xac0(SB)/8, $bcurldecode(SB)
+DATA opaddrs+0xac8(SB)/8, $bcinetaton(SB)
+DATA opaddrs+0xad0(SB)/8, $bcinetntoa(SB)
+DATA opaddrs+0xad8(SB)/8, $bcIsSubnetOfIP6(SB)
+DATA opaddrs+0xae0(SB)/8, $bcip6tocidr(SB)
+DATA opaddrs+0xae8(SB)/8, $bcaggmomentsf(SB)
+DATA opaddrs+0xaf0(SB)/8, $bcaggslotmomentsf(SB)
+DATA opaddrs+0xaf8(SB)/8, $bcaggcomomentsf(SB)
+DATA opaddrs+0xb00(SB)/8, $bcaggslotcomomentsf(SB)
+DATA opaddrs+0xb08(SB)/8, $bcpowuintf64(SB)
 DATA opaddrs+0xb10(SB)/8, $bctrap(SB)
 DATA opaddrs+0xb18(SB)/8, $bctrap(SB)
 DATA opaddrs+0xb20(SB)/8, $bctrap(SB)
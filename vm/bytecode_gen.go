@@ -13,39 +13,39 @@ var opinfo = [_maxbcop]bcopinfo{
 	opbitcounti64:             {text: "bitcount.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opbitcounti64v2:           {text: "bitcount.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opaddi64:                  {text: "add.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opaddi64imm:               {text: "add.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opaddi64imm:               {text: "add.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opsubi64:                  {text: "sub.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opsubi64imm:               {text: "sub.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
-	oprsubi64imm:              {text: "rsub.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opsubi64imm:               {text: "sub.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
+	oprsubi64imm:              {text: "rsub.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opmuli64:                  {text: "mul.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opmuli64imm:               {text: "mul.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opmuli64imm:               {text: "mul.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opdivi64:                  {text: "div.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opdivi64imm:               {text: "div.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
-	oprdivi64imm:              {text: "rdiv.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opdivi64imm:               {text: "div.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
+	oprdivi64imm:              {text: "rdiv.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opmodi64:                  {text: "mod.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opmodi64imm:               {text: "mod.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
-	oprmodi64imm:              {text: "rmod.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opmodi64imm:               {text: "mod.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
+	oprmodi64imm:              {text: "rmod.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	oppmodi64:                 {text: "pmod.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	oppmodi64imm:              {text: "pmod.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
-	oprpmodi64imm:             {text: "rpmod.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
-	opaddmuli64imm:            {text: "addmul.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[25:29] /* {bcS, bcS, bcImmI64, bcK} */},
+	oppmodi64imm:              {text: "pmod.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
+	oprpmodi64imm:             {text: "rpmod.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
+	opaddmuli64imm:            {text: "addmul.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[38:42] /* {bcS, bcS, bcImmI64, bcK} */},
 	opminvaluei64:             {text: "minvalue.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opminvaluei64imm:          {text: "minvalue.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opminvaluei64imm:          {text: "minvalue.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opmaxvaluei64:             {text: "maxvalue.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opmaxvaluei64imm:          {text: "maxvalue.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opmaxvaluei64imm:          {text: "maxvalue.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opandi64:                  {text: "and.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opandi64imm:               {text: "and.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opandi64imm:               {text: "and.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opori64:                   {text: "or.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opori64imm:                {text: "or.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opori64imm:                {text: "or.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opxori64:                  {text: "xor.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opxori64imm:               {text: "xor.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opxori64imm:               {text: "xor.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opslli64:                  {text: "sll.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opslli64imm:               {text: "sll.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opslli64imm:               {text: "sll.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opsrai64:                  {text: "sra.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opsrai64imm:               {text: "sra.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opsrai64imm:               {text: "sra.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opsrli64:                  {text: "srl.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opsrli64imm:               {text: "srl.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
-	opbroadcastf64:            {text: "broadcast.f64", out: bcargs[1:2] /* {bcS} */, in: bcargs[19:20] /* {bcImmF64} */},
+	opsrli64imm:               {text: "srl.i64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
+	opbroadcastf64:            {text: "broadcast.f64", out: bcargs[1:2] /* {bcS} */, in: bcargs[36:37] /* {bcImmF64} */},
 	opabsf64:                  {text: "abs.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opnegf64:                  {text: "neg.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opsignf64:                 {text: "sign.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
@@ -56,25 +56,25 @@ var opinfo = [_maxbcop]bcopinfo{
 	opfloorf64:                {text: "floor.f64", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opceilf64:                 {text: "ceil.f64", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opaddf64:                  {text: "add.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opaddf64imm:               {text: "add.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opaddf64imm:               {text: "add.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opsubf64:                  {text: "sub.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opsubf64imm:               {text: "sub.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
-	oprsubf64imm:              {text: "rsub.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opsubf64imm:               {text: "sub.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
+	oprsubf64imm:              {text: "rsub.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opmulf64:                  {text: "mul.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opmulf64imm:               {text: "mul.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opmulf64imm:               {text: "mul.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opdivf64:                  {text: "div.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opdivf64imm:               {text: "div.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
-	oprdivf64imm:              {text: "rdiv.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opdivf64imm:               {text: "div.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
+	oprdivf64imm:              {text: "rdiv.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opmodf64:                  {text: "mod.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opmodf64imm:               {text: "mod.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
-	oprmodf64imm:              {text: "rmod.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opmodf64imm:               {text: "mod.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
+	oprmodf64imm:              {text: "rmod.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	oppmodf64:                 {text: "pmod.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	oppmodf64imm:              {text: "pmod.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
-	oprpmodf64imm:             {text: "rpmod.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	oppmodf64imm:              {text: "pmod.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
+	oprpmodf64imm:             {text: "rpmod.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opminvaluef64:             {text: "minvalue.f64", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opminvaluef64imm:          {text: "minvalue.f64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opminvaluef64imm:          {text: "minvalue.f64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opmaxvaluef64:             {text: "maxvalue.f64", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opmaxvaluef64imm:          {text: "maxvalue.f64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opmaxvaluef64imm:          {text: "maxvalue.f64@imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opsqrtf64:                 {text: "sqrt.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opcbrtf64:                 {text: "cbrt.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opexpf64:                  {text: "exp.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
@@ -96,19 +96,19 @@ var opinfo = [_maxbcop]bcopinfo{
 	oppowf64:                  {text: "pow.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
 	opret:                     {text: "ret"},
 	opretk:                    {text: "ret.k", in: bcargs[3:4] /* {bcK} */},
-	opretbk:                   {text: "ret.b.k", in: bcargs[51:53] /* {bcB, bcK} */},
+	opretbk:                   {text: "ret.b.k", in: bcargs[90:92] /* {bcB, bcK} */},
 	opretsk:                   {text: "ret.s.k", in: bcargs[2:4] /* {bcS, bcK} */},
 	opretbhk:                  {text: "ret.b.h.k", in: bcargs[11:14] /* {bcB, bcH, bcK} */},
-	opinit:                    {text: "init", out: bcargs[51:53] /* {bcB, bcK} */},
+	opinit:                    {text: "init", out: bcargs[90:92] /* {bcB, bcK} */},
 	opbroadcast0k:             {text: "broadcast0.k", out: bcargs[3:4] /* {bcK} */},
 	opbroadcast1k:             {text: "broadcast1.k", out: bcargs[3:4] /* {bcK} */},
-	opfalse:                   {text: "false.k", out: bcargs[9:11] /* {bcV, bcK} */},
+	opfalse:                   {text: "false.k", out: bcargs[5:7] /* {bcV, bcK} */},
 	opnotk:                    {text: "not.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[3:4] /* {bcK} */},
-	opandk:                    {text: "and.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[6:8] /* {bcK, bcK} */},
-	opandnk:                   {text: "andn.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[6:8] /* {bcK, bcK} */},
-	opork:                     {text: "or.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[6:8] /* {bcK, bcK} */},
-	opxork:                    {text: "xor.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[6:8] /* {bcK, bcK} */},
-	opxnork:                   {text: "xnor.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[6:8] /* {bcK, bcK} */},
+	opandk:                    {text: "and.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[9:11] /* {bcK, bcK} */},
+	opandnk:                   {text: "andn.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[9:11] /* {bcK, bcK} */},
+	opork:                     {text: "or.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[9:11] /* {bcK, bcK} */},
+	opxork:                    {text: "xor.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[9:11] /* {bcK, bcK} */},
+	opxnork:                   {text: "xnor.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[9:11] /* {bcK, bcK} */},
 	opcvtktof64:               {text: "cvt.ktof64", out: bcargs[1:2] /* {bcS} */, in: bcargs[3:4] /* {bcK} */},
 	opcvtktoi64:               {text: "cvt.ktoi64", out: bcargs[1:2] /* {bcS} */, in: bcargs[3:4] /* {bcK} */},
 	opcvti64tok:               {text: "cvt.i64tok", out: bcargs[3:4] /* {bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
@@ -118,65 +118,65 @@ var opinfo = [_maxbcop]bcopinfo{
 	opcvtfloorf64toi64:        {text: "cvtfloor.f64toi64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opcvtceilf64toi64:         {text: "cvtceil.f64toi64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opcvti64tostr:             {text: "cvt.i64tostr", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: 20 * 16},
-	opcmpv:                    {text: "cmpv", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[75:78] /* {bcV, bcV, bcK} */},
-	opsortcmpvnf:              {text: "sortcmpv@nf", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[75:78] /* {bcV, bcV, bcK} */},
-	opsortcmpvnl:              {text: "sortcmpv@nl", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[75:78] /* {bcV, bcV, bcK} */},
-	opcmpvk:                   {text: "cmpv.k", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[69:72] /* {bcV, bcK, bcK} */},
-	opcmpvkimm:                {text: "cmpv.k@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[58:61] /* {bcV, bcImmU16, bcK} */},
-	opcmpvi64:                 {text: "cmpv.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[72:75] /* {bcV, bcS, bcK} */},
-	opcmpvi64imm:              {text: "cmpv.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[93:96] /* {bcV, bcImmI64, bcK} */},
-	opcmpvf64:                 {text: "cmpv.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[72:75] /* {bcV, bcS, bcK} */},
-	opcmpvf64imm:              {text: "cmpv.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[90:93] /* {bcV, bcImmF64, bcK} */},
+	opcmpv:                    {text: "cmpv", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[110:113] /* {bcV, bcV, bcK} */},
+	opsortcmpvnf:              {text: "sortcmpv@nf", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[110:113] /* {bcV, bcV, bcK} */},
+	opsortcmpvnl:              {text: "sortcmpv@nl", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[110:113] /* {bcV, bcV, bcK} */},
+	opcmpvk:                   {text: "cmpv.k", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[66:69] /* {bcV, bcK, bcK} */},
+	opcmpvkimm:                {text: "cmpv.k@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[92:95] /* {bcV, bcImmU16, bcK} */},
+	opcmpvi64:                 {text: "cmpv.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[84:87] /* {bcV, bcS, bcK} */},
+	opcmpvi64imm:              {text: "cmpv.i64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[48:51] /* {bcV, bcImmI64, bcK} */},
+	opcmpvf64:                 {text: "cmpv.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[84:87] /* {bcV, bcS, bcK} */},
+	opcmpvf64imm:              {text: "cmpv.f64@imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[35:38] /* {bcV, bcImmF64, bcK} */},
 	opcmpltstr:                {text: "cmplt.str", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
 	opcmplestr:                {text: "cmple.str", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
 	opcmpgtstr:                {text: "cmpgt.str", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
 	opcmpgestr:                {text: "cmpge.str", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmpltk:                  {text: "cmplt.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[37:40] /* {bcK, bcK, bcK} */},
-	opcmpltkimm:               {text: "cmplt.k@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[39:42] /* {bcK, bcImmU16, bcK} */},
-	opcmplek:                  {text: "cmple.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[37:40] /* {bcK, bcK, bcK} */},
-	opcmplekimm:               {text: "cmple.k@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[39:42] /* {bcK, bcImmU16, bcK} */},
-	opcmpgtk:                  {text: "cmpgt.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[37:40] /* {bcK, bcK, bcK} */},
-	opcmpgtkimm:               {text: "cmpgt.k@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[39:42] /* {bcK, bcImmU16, bcK} */},
-	opcmpgek:                  {text: "cmpge.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[37:40] /* {bcK, bcK, bcK} */},
-	opcmpgekimm:               {text: "cmpge.k@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[39:42] /* {bcK, bcImmU16, bcK} */},
+	opcmpltk:                  {text: "cmplt.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[30:33] /* {bcK, bcK, bcK} */},
+	opcmpltkimm:               {text: "cmplt.k@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[32:35] /* {bcK, bcImmU16, bcK} */},
+	opcmplek:                  {text: "cmple.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[30:33] /* {bcK, bcK, bcK} */},
+	opcmplekimm:               {text: "cmple.k@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[32:35] /* {bcK, bcImmU16, bcK} */},
+	opcmpgtk:                  {text: "cmpgt.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[30:33] /* {bcK, bcK, bcK} */},
+	opcmpgtkimm:               {text: "cmpgt.k@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[32:35] /* {bcK, bcImmU16, bcK} */},
+	opcmpgek:                  {text: "cmpge.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[30:33] /* {bcK, bcK, bcK} */},
+	opcmpgekimm:               {text: "cmpge.k@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[32:35] /* {bcK, bcImmU16, bcK} */},
 	opcmpeqf64:                {text: "cmpeq.f64", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmpeqf64imm:             {text: "cmpeq.f64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opcmpeqf64imm:             {text: "cmpeq.f64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opcmpltf64:                {text: "cmplt.f64", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmpltf64imm:             {text: "cmplt.f64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opcmpltf64imm:             {text: "cmplt.f64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opcmplef64:                {text: "cmple.f64", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmplef64imm:             {text: "cmple.f64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opcmplef64imm:             {text: "cmple.f64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opcmpgtf64:                {text: "cmpgt.f64", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmpgtf64imm:             {text: "cmpgt.f64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opcmpgtf64imm:             {text: "cmpgt.f64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opcmpgef64:                {text: "cmpge.f64", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmpgef64imm:             {text: "cmpge.f64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[18:21] /* {bcS, bcImmF64, bcK} */},
+	opcmpgef64imm:             {text: "cmpge.f64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[87:90] /* {bcS, bcImmF64, bcK} */},
 	opcmpeqi64:                {text: "cmpeq.i64", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmpeqi64imm:             {text: "cmpeq.i64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opcmpeqi64imm:             {text: "cmpeq.i64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opcmplti64:                {text: "cmplt.i64", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmplti64imm:             {text: "cmplt.i64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opcmplti64imm:             {text: "cmplt.i64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opcmplei64:                {text: "cmple.i64", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmplei64imm:             {text: "cmple.i64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opcmplei64imm:             {text: "cmple.i64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opcmpgti64:                {text: "cmpgt.i64", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmpgti64imm:             {text: "cmpgt.i64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opcmpgti64imm:             {text: "cmpgt.i64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opcmpgei64:                {text: "cmpge.i64", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmpgei64imm:             {text: "cmpge.i64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opcmpgei64imm:             {text: "cmpge.i64@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opisnanf:                  {text: "isnan.f", out: bcargs[3:4] /* {bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
-	opchecktag:                {text: "checktag", out: bcargs[9:11] /* {bcV, bcK} */, in: bcargs[58:61] /* {bcV, bcImmU16, bcK} */},
-	optypebits:                {text: "typebits", out: bcargs[1:2] /* {bcS} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opisnullv:                 {text: "isnull.v", out: bcargs[3:4] /* {bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opisnotnullv:              {text: "isnotnull.v", out: bcargs[3:4] /* {bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opistruev:                 {text: "istrue.v", out: bcargs[3:4] /* {bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opisfalsev:                {text: "isfalse.v", out: bcargs[3:4] /* {bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
+	opchecktag:                {text: "checktag", out: bcargs[5:7] /* {bcV, bcK} */, in: bcargs[92:95] /* {bcV, bcImmU16, bcK} */},
+	optypebits:                {text: "typebits", out: bcargs[1:2] /* {bcS} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opisnullv:                 {text: "isnull.v", out: bcargs[3:4] /* {bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opisnotnullv:              {text: "isnotnull.v", out: bcargs[3:4] /* {bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opistruev:                 {text: "istrue.v", out: bcargs[3:4] /* {bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opisfalsev:                {text: "isfalse.v", out: bcargs[3:4] /* {bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
 	opcmpeqslice:              {text: "cmpeq.slice", out: bcargs[3:4] /* {bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opcmpeqv:                  {text: "cmpeq.v", out: bcargs[3:4] /* {bcK} */, in: bcargs[75:78] /* {bcV, bcV, bcK} */},
-	opcmpeqvimm:               {text: "cmpeq.v@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[33:36] /* {bcV, bcLitRef, bcK} */},
+	opcmpeqv:                  {text: "cmpeq.v", out: bcargs[3:4] /* {bcK} */, in: bcargs[110:113] /* {bcV, bcV, bcK} */},
+	opcmpeqvimm:               {text: "cmpeq.v@imm", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcV, bcLitRef, bcK} */},
 	opdateaddmonth:            {text: "dateaddmonth", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opdateaddmonthimm:         {text: "dateaddmonth.imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opdateaddmonthimm:         {text: "dateaddmonth.imm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 	opdateaddyear:             {text: "dateaddyear", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
 	opdateaddquarter:          {text: "dateaddquarter", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
 	opdatebin:                 {text: "datebin", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[0:4] /* {bcImmI64, bcS, bcS, bcK} */},
 	opdatediffmicrosecond:     {text: "datediffmicrosecond", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opdatediffparam:           {text: "datediffparam", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[78:82] /* {bcS, bcS, bcImmU64, bcK} */},
-	opdatediffmqy:             {text: "datediffmqy", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[14:18] /* {bcS, bcS, bcImmU16, bcK} */},
+	opdatediffparam:           {text: "datediffparam", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[51:55] /* {bcS, bcS, bcImmU64, bcK} */},
+	opdatediffmqy:             {text: "datediffmqy", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[18:22] /* {bcS, bcS, bcImmU16, bcK} */},
 	opdateextractmicrosecond:  {text: "dateextractmicrosecond", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opdateextractmillisecond:  {text: "dateextractmillisecond", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opdateextractsecond:       {text: "dateextractsecond", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
@@ -195,159 +195,182 @@ var opinfo = [_maxbcop]bcopinfo{
 	opdatetruncminute:         {text: "datetruncminute", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opdatetrunchour:           {text: "datetrunchour", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opdatetruncday:            {text: "datetruncday", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
-	opdatetruncdow:            {text: "datetruncdow", out: bcargs[1:2] /* {bcS} */, in: bcargs[15:18] /* {bcS, bcImmU16, bcK} */},
+	opdatetruncdow:            {text: "datetruncdow", out: bcargs[1:2] /* {bcS} */, in: bcargs[19:22] /* {bcS, bcImmU16, bcK} */},
 	opdatetruncmonth:          {text: "datetruncmonth", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opdatetruncquarter:        {text: "datetruncquarter", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opdatetruncyear:           {text: "datetruncyear", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
-	opunboxts:                 {text: "unboxts", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opboxts:                   {text: "boxts", out: bcargs[9:10] /* {bcV} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: 16 * 16},
+	opunboxts:                 {text: "unboxts", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opboxts:                   {text: "boxts", out: bcargs[5:6] /* {bcV} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: 16 * 16},
 	opwidthbucketf64:          {text: "widthbucket.f64", out: bcargs[1:2] /* {bcS} */, in: bcargs[42:47] /* {bcS, bcS, bcS, bcS, bcK} */},
 	opwidthbucketi64:          {text: "widthbucket.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[42:47] /* {bcS, bcS, bcS, bcS, bcK} */},
 	optimebucketts:            {text: "timebucket.ts", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
 	opgeohash:                 {text: "geohash", out: bcargs[1:2] /* {bcS} */, in: bcargs[43:47] /* {bcS, bcS, bcS, bcK} */, scratch: 16 * 16},
-	opgeohashimm:              {text: "geohashimm", out: bcargs[1:2] /* {bcS} */, in: bcargs[14:18] /* {bcS, bcS, bcImmU16, bcK} */, scratch: 16 * 16},
+	opgeohashimm:              {text: "geohashimm", out: bcargs[1:2] /* {bcS} */, in: bcargs[18:22] /* {bcS, bcS, bcImmU16, bcK} */, scratch: 16 * 16},
 	opgeotilex:                {text: "geotilex", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
 	opgeotiley:                {text: "geotiley", out: bcargs[1:2] /* {bcS} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
 	opgeotilees:               {text: "geotilees", out: bcargs[1:2] /* {bcS} */, in: bcargs[43:47] /* {bcS, bcS, bcS, bcK} */, scratch: 32 * 16},
-	opgeotileesimm:            {text: "geotilees.imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[14:18] /* {bcS, bcS, bcImmU16, bcK} */, scratch: 32 * 16},
+	opgeotileesimm:            {text: "geotilees.imm", out: bcargs[1:2] /* {bcS} */, in: bcargs[18:22] /* {bcS, bcS, bcImmU16, bcK} */, scratch: 32 * 16},
 	opgeodistance:             {text: "geodistance", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[42:47] /* {bcS, bcS, bcS, bcS, bcK} */},
 	opalloc:                   {text: "alloc", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
 	opconcatstr:               {text: "concatstr", out: bcargs[2:4] /* {bcS, bcK} */, va: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
-	opfindsym:                 {text: "findsym", out: bcargs[9:11] /* {bcV, bcK} */, in: bcargs[96:99] /* {bcB, bcSymbolID, bcK} */},
-	opfindsym2:                {text: "findsym2", out: bcargs[9:11] /* {bcV, bcK} */, in: bcargs[53:58] /* {bcB, bcV, bcK, bcSymbolID, bcK} */},
-	opblendv:                  {text: "blend.v", out: bcargs[9:11] /* {bcV, bcK} */, in: bcargs[67:71] /* {bcV, bcK, bcV, bcK} */},
-	opblendf64:                {text: "blend.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[86:90] /* {bcS, bcK, bcS, bcK} */},
-	opunpack:                  {text: "unpack", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[58:61] /* {bcV, bcImmU16, bcK} */},
-	opunsymbolize:             {text: "unsymbolize", out: bcargs[9:10] /* {bcV} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opunboxktoi64:             {text: "unbox.k@i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opunboxcoercef64:          {text: "unbox.coerce.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opunboxcoercei64:          {text: "unbox.coerce.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opunboxcvtf64:             {text: "unbox.cvt.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opunboxcvti64:             {text: "unbox.cvt.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opboxf64:                  {text: "box.f64", out: bcargs[9:10] /* {bcV} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: 9 * 16},
-	opboxi64:                  {text: "box.i64", out: bcargs[9:10] /* {bcV} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: 9 * 16},
-	opboxk:                    {text: "box.k", out: bcargs[9:10] /* {bcV} */, in: bcargs[6:8] /* {bcK, bcK} */, scratch: 16},
-	opboxstr:                  {text: "box.str", out: bcargs[9:10] /* {bcV} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
-	opboxlist:                 {text: "box.list", out: bcargs[9:10] /* {bcV} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
-	opmakelist:                {text: "makelist", out: bcargs[9:11] /* {bcV, bcK} */, in: bcargs[3:4] /* {bcK} */, va: bcargs[9:11] /* {bcV, bcK} */, scratch: PageSize},
-	opmakestruct:              {text: "makestruct", out: bcargs[9:11] /* {bcV, bcK} */, in: bcargs[3:4] /* {bcK} */, va: bcargs[82:85] /* {bcSymbolID, bcV, bcK} */, scratch: PageSize},
-	ophashvalue:               {text: "hashvalue", out: bcargs[8:9] /* {bcH} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	ophashvalueplus:           {text: "hashvalue+", out: bcargs[8:9] /* {bcH} */, in: bcargs[8:11] /* {bcH, bcV, bcK} */},
-	ophashmember:              {text: "hashmember", out: bcargs[3:4] /* {bcK} */, in: bcargs[30:33] /* {bcH, bcImmU16, bcK} */},
-	ophashlookup:              {text: "hashlookup", out: bcargs[9:11] /* {bcV, bcK} */, in: bcargs[30:33] /* {bcH, bcImmU16, bcK} */},
-	opaggandk:                 {text: "aggand.k", in: bcargs[36:39] /* {bcAggSlot, bcK, bcK} */},
-	opaggork:                  {text: "aggor.k", in: bcargs[36:39] /* {bcAggSlot, bcK, bcK} */},
-	opaggslotsumf:             {text: "aggslotsum.f64", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggsumf:                 {text: "aggsum.f64", in: bcargs[85:88] /* {bcAggSlot, bcS, bcK} */},
-	opaggsumi:                 {text: "aggsum.i64", in: bcargs[85:88] /* {bcAggSlot, bcS, bcK} */},
-	opaggminf:                 {text: "aggmin.f64", in: bcargs[85:88] /* {bcAggSlot, bcS, bcK} */},
-	opaggmini:                 {text: "aggmin.i64", in: bcargs[85:88] /* {bcAggSlot, bcS, bcK} */},
-	opaggmaxf:                 {text: "aggmax.f64", in: bcargs[85:88] /* {bcAggSlot, bcS, bcK} */},
-	opaggmaxi:                 {text: "aggmax.i64", in: bcargs[85:88] /* {bcAggSlot, bcS, bcK} */},
-	opaggandi:                 {text: "aggand.i64", in: bcargs[85:88] /* {bcAggSlot, bcS, bcK} */},
-	opaggori:                  {text: "aggor.i64", in: bcargs[85:88] /* {bcAggSlot, bcS, bcK} */},
-	opaggxori:                 {text: "aggxor.i64", in: bcargs[85:88] /* {bcAggSlot, bcS, bcK} */},
-	opaggcount:                {text: "aggcount", in: bcargs[36:38] /* {bcAggSlot, bcK} */},
-	opaggmergestate:           {text: "aggmergestate", in: bcargs[85:88] /* {bcAggSlot, bcS, bcK} */},
-	opaggbucket:               {text: "aggbucket", out: bcargs[5:6] /* {bcL} */, in: bcargs[12:14] /* {bcH, bcK} */},
-	opaggslotandk:             {text: "aggslotand.k", in: bcargs[4:8] /* {bcAggSlot, bcL, bcK, bcK} */},
-	opaggslotork:              {text: "aggslotor.k", in: bcargs[4:8] /* {bcAggSlot, bcL, bcK, bcK} */},
-	opaggslotsumi:             {text: "aggslotsum.i64", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotavgf:             {text: "aggslotavg.f64", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotavgi:             {text: "aggslotavg.i64", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotminf:             {text: "aggslotmin.f64", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotmini:             {text: "aggslotmin.i64", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotmaxf:             {text: "aggslotmax.f64", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotmaxi:             {text: "aggslotmax.i64", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotandi:             {text: "aggslotand.i64", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotori:              {text: "aggslotor.i64", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotxori:             {text: "aggslotxor.i64", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	opaggslotcount:            {text: "aggslotcount", in: bcargs[4:7] /* {bcAggSlot, bcL, bcK} */},
-	opaggslotcountv2:          {text: "aggslotcount", in: bcargs[4:7] /* {bcAggSlot, bcL, bcK} */},
-	opaggslotmergestate:       {text: "aggslotmergestate", in: bcargs[61:65] /* {bcAggSlot, bcL, bcS, bcK} */},
-	oplitref:                  {text: "litref", out: bcargs[9:10] /* {bcV} */, in: bcargs[34:35] /* {bcLitRef} */},
-	opauxval:                  {text: "auxval", out: bcargs[9:11] /* {bcV, bcK} */, in: bcargs[65:66] /* {bcAuxSlot} */},
-	opsplit:                   {text: "split", out: bcargs[72:75] /* {bcV, bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
-	optuple:                   {text: "tuple", out: bcargs[51:53] /* {bcB, bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
+	opfindsym:                 {text: "findsym", out: bcargs[5:7] /* {bcV, bcK} */, in: bcargs[81:84] /* {bcB, bcSymbolID, bcK} */},
+	opfindsym2:                {text: "findsym2", out: bcargs[5:7] /* {bcV, bcK} */, in: bcargs[55:60] /* {bcB, bcV, bcK, bcSymbolID, bcK} */},
+	opblendv:                  {text: "blend.v", out: bcargs[5:7] /* {bcV, bcK} */, in: bcargs[64:68] /* {bcV, bcK, bcV, bcK} */},
+	opblendf64:                {text: "blend.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[106:110] /* {bcS, bcK, bcS, bcK} */},
+	opunpack:                  {text: "unpack", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[92:95] /* {bcV, bcImmU16, bcK} */},
+	opunsymbolize:             {text: "unsymbolize", out: bcargs[5:6] /* {bcV} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opunboxktoi64:             {text: "unbox.k@i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opunboxcoercef64:          {text: "unbox.coerce.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opunboxcoercei64:          {text: "unbox.coerce.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opunboxcvtf64:             {text: "unbox.cvt.f64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opunboxcvti64:             {text: "unbox.cvt.i64", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opboxf64:                  {text: "box.f64", out: bcargs[5:6] /* {bcV} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: 9 * 16},
+	opboxi64:                  {text: "box.i64", out: bcargs[5:6] /* {bcV} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: 9 * 16},
+	opboxk:                    {text: "box.k", out: bcargs[5:6] /* {bcV} */, in: bcargs[9:11] /* {bcK, bcK} */, scratch: 16},
+	opboxstr:                  {text: "box.str", out: bcargs[5:6] /* {bcV} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
+	opboxlist:                 {text: "box.list", out: bcargs[5:6] /* {bcV} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
+	opmakelist:                {text: "makelist", out: bcargs[5:7] /* {bcV, bcK} */, in: bcargs[3:4] /* {bcK} */, va: bcargs[5:7] /* {bcV, bcK} */, scratch: PageSize},
+	opmakestruct:              {text: "makestruct", out: bcargs[5:7] /* {bcV, bcK} */, in: bcargs[3:4] /* {bcK} */, va: bcargs[73:76] /* {bcSymbolID, bcV, bcK} */, scratch: PageSize},
+	ophashvalue:               {text: "hashvalue", out: bcargs[4:5] /* {bcH} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	ophashvalueplus:           {text: "hashvalue+", out: bcargs[4:5] /* {bcH} */, in: bcargs[4:7] /* {bcH, bcV, bcK} */},
+	ophashmember:              {text: "hashmember", out: bcargs[3:4] /* {bcK} */, in: bcargs[26:29] /* {bcH, bcImmU16, bcK} */},
+	ophashlookup:              {text: "hashlookup", out: bcargs[5:7] /* {bcV, bcK} */, in: bcargs[26:29] /* {bcH, bcImmU16, bcK} */},
+	opaggandk:                 {text: "aggand.k", in: bcargs[29:32] /* {bcAggSlot, bcK, bcK} */},
+	opaggork:                  {text: "aggor.k", in: bcargs[29:32] /* {bcAggSlot, bcK, bcK} */},
+	opaggslotsumf:             {text: "aggslotsum.f64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggsumf:                 {text: "aggsum.f64", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
+	opaggsumi:                 {text: "aggsum.i64", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
+	opaggminf:                 {text: "aggmin.f64", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
+	opaggmini:                 {text: "aggmin.i64", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
+	opaggmaxf:                 {text: "aggmax.f64", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
+	opaggmaxi:                 {text: "aggmax.i64", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
+	opaggandi:                 {text: "aggand.i64", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
+	opaggori:                  {text: "aggor.i64", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
+	opaggxori:                 {text: "aggxor.i64", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
+	opaggcount:                {text: "aggcount", in: bcargs[29:31] /* {bcAggSlot, bcK} */},
+	opaggmergestate:           {text: "aggmergestate", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
+	opaggbucket:               {text: "aggbucket", out: bcargs[8:9] /* {bcL} */, in: bcargs[12:14] /* {bcH, bcK} */},
+	opaggslotandk:             {text: "aggslotand.k", in: bcargs[7:11] /* {bcAggSlot, bcL, bcK, bcK} */},
+	opaggslotork:              {text: "aggslotor.k", in: bcargs[7:11] /* {bcAggSlot, bcL, bcK, bcK} */},
+	opaggslotsumi:             {text: "aggslotsum.i64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotavgf:             {text: "aggslotavg.f64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotavgi:             {text: "aggslotavg.i64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotminf:             {text: "aggslotmin.f64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotmini:             {text: "aggslotmin.i64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotmaxf:             {text: "aggslotmax.f64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotmaxi:             {text: "aggslotmax.i64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotandi:             {text: "aggslotand.i64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotori:              {text: "aggslotor.i64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotxori:             {text: "aggslotxor.i64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggslotcount:            {text: "aggslotcount", in: bcargs[7:10] /* {bcAggSlot, bcL, bcK} */},
+	opaggslotcountv2:          {text: "aggslotcount", in: bcargs[7:10] /* {bcAggSlot, bcL, bcK} */},
+	opaggslotmergestate:       {text: "aggslotmergestate", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	oplitref:                  {text: "litref", out: bcargs[5:6] /* {bcV} */, in: bcargs[23:24] /* {bcLitRef} */},
+	opauxval:                  {text: "auxval", out: bcargs[5:7] /* {bcV, bcK} */, in: bcargs[47:48] /* {bcAuxSlot} */},
+	opsplit:                   {text: "split", out: bcargs[84:87] /* {bcV, bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
+	optuple:                   {text: "tuple", out: bcargs[90:92] /* {bcB, bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
 	opmovk:                    {text: "mov.k", out: bcargs[3:4] /* {bcK} */, in: bcargs[3:4] /* {bcK} */},
-	opzerov:                   {text: "zero.v", out: bcargs[9:10] /* {bcV} */},
-	opmovv:                    {text: "mov.v", out: bcargs[9:10] /* {bcV} */, in: bcargs[9:11] /* {bcV, bcK} */},
-	opmovvk:                   {text: "mov.v.k", out: bcargs[9:11] /* {bcV, bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
+	opzerov:                   {text: "zero.v", out: bcargs[5:6] /* {bcV} */},
+	opmovv:                    {text: "mov.v", out: bcargs[5:6] /* {bcV} */, in: bcargs[5:7] /* {bcV, bcK} */},
+	opmovvk:                   {text: "mov.v.k", out: bcargs[5:7] /* {bcV, bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
 	opmovf64:                  {text: "mov.f64", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opmovi64:                  {text: "mov.i64", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
-	opobjectsize:              {text: "objectsize", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[9:11] /* {bcV, bcK} */},
+	opobjectsize:              {text: "objectsize", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[5:7] /* {bcV, bcK} */},
 	oparraysize:               {text: "arraysize", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
-	oparrayposition:           {text: "arrayposition", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[66:69] /* {bcS, bcV, bcK} */},
+	oparrayposition:           {text: "arrayposition", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[63:66] /* {bcS, bcV, bcK} */},
 	oparraysum:                {text: "arraysum", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opvectorinnerproduct:      {text: "vectorinnerproduct", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opvectorinnerproductimm:   {text: "bcvectorinnerproductimm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
+	opvectorinnerproductimm:   {text: "bcvectorinnerproductimm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
 	opvectorl1distance:        {text: "vectorl1distance", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opvectorl1distanceimm:     {text: "vectorl1distanceimm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
+	opvectorl1distanceimm:     {text: "vectorl1distanceimm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
 	opvectorl2distance:        {text: "vectorl2distance", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opvectorl2distanceimm:     {text: "vectorl2distanceimm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
+	opvectorl2distanceimm:     {text: "vectorl2distanceimm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
 	opvectorcosinedistance:    {text: "vectorcosinedistance", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
-	opvectorcosinedistanceimm: {text: "vectorcosinedistanceimm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opCmpStrEqCs:              {text: "cmp_str_eq_cs", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opCmpStrEqCi:              {text: "cmp_str_eq_ci", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opCmpStrEqUTF8Ci:          {text: "cmp_str_eq_utf8_ci", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opCmpStrFuzzyA3:           {text: "cmp_str_fuzzy_A3", out: bcargs[3:4] /* {bcK} */, in: bcargs[21:25] /* {bcS, bcS, bcDictSlot, bcK} */},
-	opCmpStrFuzzyUnicodeA3:    {text: "cmp_str_fuzzy_unicode_A3", out: bcargs[3:4] /* {bcK} */, in: bcargs[21:25] /* {bcS, bcS, bcDictSlot, bcK} */},
-	opHasSubstrFuzzyA3:        {text: "contains_fuzzy_A3", out: bcargs[3:4] /* {bcK} */, in: bcargs[21:25] /* {bcS, bcS, bcDictSlot, bcK} */},
-	opHasSubstrFuzzyUnicodeA3: {text: "contains_fuzzy_unicode_A3", out: bcargs[3:4] /* {bcK} */, in: bcargs[21:25] /* {bcS, bcS, bcDictSlot, bcK} */},
+	opvectorcosinedistanceimm: {text: "vectorcosinedistanceimm", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opCmpStrEqCs:              {text: "cmp_str_eq_cs", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opCmpStrEqCi:              {text: "cmp_str_eq_ci", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opCmpStrEqUTF8Ci:          {text: "cmp_str_eq_utf8_ci", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opCmpStrFuzzyA3:           {text: "cmp_str_fuzzy_A3", out: bcargs[3:4] /* {bcK} */, in: bcargs[14:18] /* {bcS, bcS, bcDictSlot, bcK} */},
+	opCmpStrFuzzyUnicodeA3:    {text: "cmp_str_fuzzy_unicode_A3", out: bcargs[3:4] /* {bcK} */, in: bcargs[14:18] /* {bcS, bcS, bcDictSlot, bcK} */},
+	opHasSubstrFuzzyA3:        {text: "contains_fuzzy_A3", out: bcargs[3:4] /* {bcK} */, in: bcargs[14:18] /* {bcS, bcS, bcDictSlot, bcK} */},
+	opHasSubstrFuzzyUnicodeA3: {text: "contains_fuzzy_unicode_A3", out: bcargs[3:4] /* {bcK} */, in: bcargs[14:18] /* {bcS, bcS, bcDictSlot, bcK} */},
 	opSkip1charLeft:           {text: "skip_1char_left", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opSkip1charRight:          {text: "skip_1char_right", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opSkipNcharLeft:           {text: "skip_nchar_left", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
 	opSkipNcharRight:          {text: "skip_nchar_right", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[1:4] /* {bcS, bcS, bcK} */},
 	opTrimWsLeft:              {text: "trim_ws_left", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opTrimWsRight:             {text: "trim_ws_right", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
-	opTrim4charLeft:           {text: "trim_char_left", out: bcargs[1:2] /* {bcS} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opTrim4charRight:          {text: "trim_char_right", out: bcargs[1:2] /* {bcS} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
+	opTrim4charLeft:           {text: "trim_char_left", out: bcargs[1:2] /* {bcS} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opTrim4charRight:          {text: "trim_char_right", out: bcargs[1:2] /* {bcS} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
 	opoctetlength:             {text: "octetlength", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opcharlength:              {text: "characterlength", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
 	opSubstr:                  {text: "substr", out: bcargs[1:2] /* {bcS} */, in: bcargs[43:47] /* {bcS, bcS, bcS, bcK} */},
-	opSplitPart:               {text: "split_part", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[47:51] /* {bcS, bcDictSlot, bcS, bcK} */},
-	opContainsPrefixCs:        {text: "contains_prefix_cs", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opContainsPrefixCi:        {text: "contains_prefix_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opContainsPrefixUTF8Ci:    {text: "contains_prefix_utf8_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSuffixCs:        {text: "contains_suffix_cs", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSuffixCi:        {text: "contains_suffix_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSuffixUTF8Ci:    {text: "contains_suffix_utf8_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSubstrCs:        {text: "contains_substr_cs", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSubstrCi:        {text: "contains_substr_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opContainsSubstrUTF8Ci:    {text: "contains_substr_utf8_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opEqPatternCs:             {text: "eq_pattern_cs", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opEqPatternCi:             {text: "eq_pattern_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opEqPatternUTF8Ci:         {text: "eq_pattern_utf8_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opContainsPatternCs:       {text: "contains_pattern_cs", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opContainsPatternCi:       {text: "contains_pattern_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opContainsPatternUTF8Ci:   {text: "contains_pattern_utf8_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opIsSubnetOfIP4:           {text: "is_subnet_of_ip4", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT6:                   {text: "dfa_tiny6", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT7:                   {text: "dfa_tiny7", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT8:                   {text: "dfa_tiny8", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT6Z:                  {text: "dfa_tiny6Z", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT7Z:                  {text: "dfa_tiny7Z", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opDfaT8Z:                  {text: "dfa_tiny8Z", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opDfaLZ:                   {text: "dfa_largeZ", out: bcargs[3:4] /* {bcK} */, in: bcargs[22:25] /* {bcS, bcDictSlot, bcK} */},
-	opAggTDigest:              {text: "aggtdigest.f64", in: bcargs[85:88] /* {bcAggSlot, bcS, bcK} */},
+	opSplitPart:               {text: "split_part", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[95:99] /* {bcS, bcDictSlot, bcS, bcK} */},
+	opContainsPrefixCs:        {text: "contains_prefix_cs", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opContainsPrefixCi:        {text: "contains_prefix_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opContainsPrefixUTF8Ci:    {text: "contains_prefix_utf8_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSuffixCs:        {text: "contains_suffix_cs", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSuffixCi:        {text: "contains_suffix_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSuffixUTF8Ci:    {text: "contains_suffix_utf8_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSubstrCs:        {text: "contains_substr_cs", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSubstrCi:        {text: "contains_substr_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opContainsSubstrUTF8Ci:    {text: "contains_substr_utf8_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opEqPatternCs:             {text: "eq_pattern_cs", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opEqPatternCi:             {text: "eq_pattern_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opEqPatternUTF8Ci:         {text: "eq_pattern_utf8_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opContainsPatternCs:       {text: "contains_pattern_cs", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opContainsPatternCi:       {text: "contains_pattern_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opContainsPatternUTF8Ci:   {text: "contains_pattern_utf8_ci", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opIsSubnetOfIP4:           {text: "is_subnet_of_ip4", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT6:                   {text: "dfa_tiny6", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT7:                   {text: "dfa_tiny7", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT8:                   {text: "dfa_tiny8", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT6Z:                  {text: "dfa_tiny6Z", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT7Z:                  {text: "dfa_tiny7Z", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opDfaT8Z:                  {text: "dfa_tiny8Z", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opDfaLZ:                   {text: "dfa_largeZ", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opAggTDigest:              {text: "aggtdigest.f64", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
 	opslower:                  {text: "slower", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
 	opsupper:                  {text: "supper", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
-	opaggapproxcount:          {text: "aggapproxcount", in: bcargs[29:33] /* {bcAggSlot, bcH, bcImmU16, bcK} */},
+	opaggapproxcount:          {text: "aggapproxcount", in: bcargs[25:29] /* {bcAggSlot, bcH, bcImmU16, bcK} */},
 	opaggslotapproxcount:      {text: "aggslotapproxcount", in: bcargs[99:104] /* {bcAggSlot, bcL, bcH, bcImmU16, bcK} */},
-	oppowuintf64:              {text: "powuint.f64", out: bcargs[1:2] /* {bcS} */, in: bcargs[26:29] /* {bcS, bcImmI64, bcK} */},
+	opmd5str:                  {text: "md5str", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: 16 * 32},
+	opsha1str:                 {text: "sha1str", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: 16 * 40},
+	opsha256str:               {text: "sha256str", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: 16 * 64},
+	opxxhash64str:             {text: "xxhash64str", out: bcargs[1:2] /* {bcS} */, in: bcargs[2:4] /* {bcS, bcK} */},
+	ophashtoi64:               {text: "hashtoi64", out: bcargs[1:2] /* {bcS} */, in: bcargs[12:14] /* {bcH, bcK} */},
+	optohexstr:                {text: "tohexstr", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
+	opfromhexstr:              {text: "fromhexstr", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
+	optobase64str:             {text: "tobase64str", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
+	opfrombase64str:           {text: "frombase64str", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
+	opurlhost:                 {text: "urlhost", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
+	opurlpath:                 {text: "urlpath", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
+	opurlquery:                {text: "urlquery", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
+	opurlqueryparam:           {text: "urlqueryparam", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opurldecode:               {text: "urldecode", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: PageSize},
+	opinetaton:                {text: "inetaton", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */},
+	opinetntoa:                {text: "inetntoa", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[2:4] /* {bcS, bcK} */, scratch: 16 * 16},
+	opIsSubnetOfIP6:           {text: "is_subnet_of_ip6", out: bcargs[3:4] /* {bcK} */, in: bcargs[15:18] /* {bcS, bcDictSlot, bcK} */},
+	opip6tocidr:               {text: "ip6tocidr", out: bcargs[2:4] /* {bcS, bcK} */, in: bcargs[19:22] /* {bcS, bcImmU16, bcK} */, scratch: 48 * 16},
+	opaggmomentsf:             {text: "aggmoments.f64", in: bcargs[60:63] /* {bcAggSlot, bcS, bcK} */},
+	opaggslotmomentsf:         {text: "aggslotmoments.f64", in: bcargs[69:73] /* {bcAggSlot, bcL, bcS, bcK} */},
+	opaggcomomentsf:           {text: "aggcomoments.f64", in: bcargs[104:108] /* {bcAggSlot, bcS, bcS, bcK} */},
+	opaggslotcomomentsf:       {text: "aggslotcomoments.f64", in: bcargs[76:81] /* {bcAggSlot, bcL, bcS, bcS, bcK} */},
+	oppowuintf64:              {text: "powuint.f64", out: bcargs[1:2] /* {bcS} */, in: bcargs[39:42] /* {bcS, bcImmI64, bcK} */},
 }
 
-var bcargs = [104]bcArgType{bcImmI64, bcS, bcS, bcK, bcAggSlot, bcL, bcK,
-	bcK, bcH, bcV, bcK, bcB, bcH, bcK, bcS, bcS, bcImmU16, bcK, bcS,
-	bcImmF64, bcK, bcS, bcS, bcDictSlot, bcK, bcS, bcS, bcImmI64, bcK,
-	bcAggSlot, bcH, bcImmU16, bcK, bcV, bcLitRef, bcK, bcAggSlot, bcK,
-	bcK, bcK, bcImmU16, bcK, bcS, bcS, bcS, bcS, bcK, bcS, bcDictSlot,
-	bcS, bcK, bcB, bcK, bcB, bcV, bcK, bcSymbolID, bcK, bcV, bcImmU16,
-	bcK, bcAggSlot, bcL, bcS, bcK, bcAuxSlot, bcS, bcV, bcK, bcV, bcK,
-	bcK, bcV, bcS, bcK, bcV, bcV, bcK, bcS, bcS, bcImmU64, bcK,
-	bcSymbolID, bcV, bcK, bcAggSlot, bcS, bcK, bcS, bcK, bcV, bcImmF64,
-	bcK, bcV, bcImmI64, bcK, bcB, bcSymbolID, bcK, bcAggSlot, bcL, bcH,
-	bcImmU16, bcK}
+var bcargs = [113]bcArgType{bcImmI64, bcS, bcS, bcK, bcH, bcV, bcK,
+	bcAggSlot, bcL, bcK, bcK, bcB, bcH, bcK, bcS, bcS, bcDictSlot, bcK,
+	bcS, bcS, bcImmU16, bcK, bcV, bcLitRef, bcK, bcAggSlot, bcH,
+	bcImmU16, bcK, bcAggSlot, bcK, bcK, bcK, bcImmU16, bcK, bcV,
+	bcImmF64, bcK, bcS, bcS, bcImmI64, bcK, bcS, bcS, bcS, bcS, bcK,
+	bcAuxSlot, bcV, bcImmI64, bcK, bcS, bcS, bcImmU64, bcK, bcB, bcV,
+	bcK, bcSymbolID, bcK, bcAggSlot, bcS, bcK, bcS, bcV, bcK, bcV, bcK,
+	bcK, bcAggSlot, bcL, bcS, bcK, bcSymbolID, bcV, bcK, bcAggSlot,
+	bcL, bcS, bcS, bcK, bcB, bcSymbolID, bcK, bcV, bcS, bcK, bcS,
+	bcImmF64, bcK, bcB, bcK, bcV, bcImmU16, bcK, bcS, bcDictSlot, bcS,
+	bcK, bcAggSlot, bcL, bcH, bcImmU16, bcK, bcAggSlot, bcS, bcS, bcK,
+	bcS, bcK, bcV, bcV, bcK}
 
 const (
 	optrap                    bcop = 0
@@ -681,8 +704,30 @@ const (
 	opsupper                  bcop = 328
 	opaggapproxcount          bcop = 329
 	opaggslotapproxcount      bcop = 330
-	oppowuintf64              bcop = 331
-	_maxbcop                       = 332
+	opmd5str                  bcop = 331
+	opsha1str                 bcop = 332
+	opsha256str               bcop = 333
+	opxxhash64str             bcop = 334
+	ophashtoi64               bcop = 335
+	optohexstr                bcop = 336
+	opfromhexstr              bcop = 337
+	optobase64str             bcop = 338
+	opfrombase64str           bcop = 339
+	opurlhost                 bcop = 340
+	opurlpath                 bcop = 341
+	opurlquery                bcop = 342
+	opurlqueryparam           bcop = 343
+	opurldecode               bcop = 344
+	opinetaton                bcop = 345
+	opinetntoa                bcop = 346
+	opIsSubnetOfIP6           bcop = 347
+	opip6tocidr               bcop = 348
+	opaggmomentsf             bcop = 349
+	opaggslotmomentsf         bcop = 350
+	opaggcomomentsf           bcop = 351
+	opaggslotcomomentsf       bcop = 352
+	oppowuintf64              bcop = 353
+	_maxbcop                       = 354
 )
 
 type opreplace struct{ from, to bcop }
@@ -692,4 +737,4 @@ var patchAVX512Level2 []opreplace = []opreplace{
 	{from: opaggslotcountv2, to: opaggslotcount},
 }
 
-// checksum: b14d1d5a711062c44a41c305c22ce2f2
+// checksum: a529922b672b40d4246c892b6a0629ad
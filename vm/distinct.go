@@ -46,6 +46,10 @@ type DistinctFilter struct {
 	dedup     *radixTree64
 	limit     int64
 	remaining int64
+
+	// budget and the charge for dedup
+	budget *Budget
+	mem    memacct
 }
 
 // NewDistinct creates a new DistinctFilter
@@ -94,6 +98,13 @@ func (d *DistinctFilter) Limit(n int64) {
 	d.remaining = n
 }
 
+// SetBudget sets the Budget to which the memory
+// used to track distinct rows is charged.
+func (d *DistinctFilter) SetBudget(b *Budget) {
+	d.budget = b
+	d.mem.budget = b
+}
+
 func (d *DistinctFilter) Open() (io.WriteCloser, error) {
 	dst, err := d.out.Open()
 	if err != nil {
@@ -102,11 +113,13 @@ func (d *DistinctFilter) Open() (io.WriteCloser, error) {
 	return splitter(&deduper{
 		parent: d,
 		dst:    asRowConsumer(dst),
+		mem:    memacct{budget: d.budget},
 	}), nil
 }
 
 func (d *DistinctFilter) Close() error {
 	d.prog.reset()
+	d.mem.release()
 	return d.out.Close()
 }
 
@@ -130,6 +143,8 @@ type deduper struct {
 	// if we reach the limit
 	// set by the parent
 	closed bool
+	// charge for local
+	mem memacct
 }
 
 func (d *deduper) symbolize(st *symtab, aux *auxbindings) error {
@@ -210,6 +225,9 @@ func (d *deduper) writeRows(delims []vmref, rp *rowParams) error {
 	for j := range aux {
 		aux[j] = aux[j][:outpos]
 	}
+	if err := d.mem.resize(d.local.size()); err != nil {
+		return fmt.Errorf("distinct: %w", err)
+	}
 
 	// we may not insert len(delims) entries
 	// (due to duplicates), but we should have
@@ -237,6 +255,10 @@ func (d *deduper) writeRows(delims []vmref, rp *rowParams) error {
 			outpos++
 		}
 	}
+	if err := d.parent.mem.resize(all.size()); err != nil {
+		d.parent.lock.Unlock()
+		return fmt.Errorf("distinct: %w", err)
+	}
 	if d.parent.limit > 0 {
 		c := int64(outpos)
 		if c >= d.parent.remaining {
@@ -262,5 +284,6 @@ func (d *deduper) writeRows(delims []vmref, rp *rowParams) error {
 
 func (d *deduper) Close() error {
 	d.bc.reset()
+	d.mem.release()
 	return d.dst.Close()
 }
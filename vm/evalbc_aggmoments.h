@@ -0,0 +1,349 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// This file contains the opcodes that update the central moments
+// used by the statistical aggregates (VAR_SAMP, SKEWNESS, CORR, ...).
+//
+// Each of the 16 lanes updates its own moments with the
+// one-pass algorithm of Welford, extended to the third and
+// the fourth moment by Terriberry; the lanes are merged
+// with the parallel formulas of Chan et al. (see aggregate_moments.go).
+
+/*
+Algorithm (univariate):
+
+    n1 := n
+    n = n + 1
+    delta := x - mean
+    dn := delta / n
+    dn2 := dn * dn
+    t := delta * dn * n1
+    mean = mean + dn
+    m4 = m4 + t * dn2 * (n*n - 3*n + 3) + 6 * dn2 * m2 - 4 * dn * m3
+    m3 = m3 + t * dn * (n - 2) - 3 * dn * m2
+    m2 = m2 + t
+
+Algorithm (bivariate):
+
+    n = n + 1
+    dx := x - meanx
+    meanx = meanx + dx / n
+    dy := y - meany
+    meany = meany + dy / n
+    m2x = m2x + dx * (x - meanx)
+    m2y = m2y + dy * (y - meany)
+    cxy = cxy + dx * (y - meany)
+*/
+
+// univariate memory layout: 16 x float64 for each of the moments
+#define MOMENTS_N_OFFSET     (0*128)
+#define MOMENTS_MEAN_OFFSET  (1*128)
+#define MOMENTS_M2_OFFSET    (2*128)
+#define MOMENTS_M3_OFFSET    (3*128)
+#define MOMENTS_M4_OFFSET    (4*128)
+
+// bivariate memory layout: 16 x float64 for each of the co-moments
+#define COMOMENTS_N_OFFSET     (0*128)
+#define COMOMENTS_MEANX_OFFSET (1*128)
+#define COMOMENTS_MEANY_OFFSET (2*128)
+#define COMOMENTS_M2X_OFFSET   (3*128)
+#define COMOMENTS_M2Y_OFFSET   (4*128)
+#define COMOMENTS_CXY_OFFSET   (5*128)
+
+// Input:
+// - x                      - input float64 values of 8 lanes
+// - n, mean, m2, m3, m4    - the moments of the 8 lanes (updated)
+// - one, two, three, four, six - the respective float64 constants
+// - the remaining arguments are temporaries
+#define BC_MOMENTS_UPDATE(x, n, mean, m2, m3, m4, one, two, three, four, six, delta, dn, dn2, t, u, v) \
+  VSUBPD  mean, x, delta      /* delta = x - mean */             \
+  VMOVAPD n, t                /* t = n1 */                       \
+  VADDPD  one, n, n           /* n = n + 1 */                    \
+  VDIVPD  n, delta, dn        /* dn = delta / n */               \
+  VMULPD  dn, dn, dn2         /* dn2 = dn * dn */                \
+  VADDPD  dn, mean, mean      /* mean = mean + dn */             \
+  VMULPD  dn, delta, delta    /* delta = delta * dn */           \
+  VMULPD  delta, t, t         /* t = delta * dn * n1 */          \
+                                                                 \
+  VSUBPD  three, n, u         /* u = n - 3 */                    \
+  VMULPD  n, u, u             /* u = n*n - 3*n */                \
+  VADDPD  three, u, u         /* u = n*n - 3*n + 3 */            \
+  VMULPD  dn2, u, u                                              \
+  VMULPD  t, u, u             /* u = t * dn2 * (n*n - 3*n + 3) */ \
+  VMULPD  m2, dn2, v                                             \
+  VMULPD  six, v, v           /* v = 6 * dn2 * m2 */             \
+  VADDPD  v, u, u                                                \
+  VMULPD  m3, dn, v                                              \
+  VMULPD  four, v, v          /* v = 4 * dn * m3 */              \
+  VSUBPD  v, u, u                                                \
+  VADDPD  u, m4, m4           /* m4 = m4 + u */                  \
+                                                                 \
+  VSUBPD  two, n, u           /* u = n - 2 */                    \
+  VMULPD  dn, u, u                                               \
+  VMULPD  t, u, u             /* u = t * dn * (n - 2) */         \
+  VMULPD  m2, dn, v                                              \
+  VMULPD  three, v, v         /* v = 3 * dn * m2 */              \
+  VSUBPD  v, u, u                                                \
+  VADDPD  u, m3, m3           /* m3 = m3 + u */                  \
+                                                                 \
+  VADDPD  t, m2, m2           /* m2 = m2 + t */
+
+// Input:
+// - y, x                   - input float64 values of 8 lanes
+// - n, meanx, meany, m2x, m2y, cxy - the co-moments of the 8 lanes (updated)
+// - one                    - float64 1.0
+// - the remaining arguments are temporaries
+#define BC_COMOMENTS_UPDATE(y, x, n, meanx, meany, m2x, m2y, cxy, one, dx, dy, t) \
+  VADDPD  one, n, n           /* n = n + 1 */                    \
+  VSUBPD  meanx, x, dx        /* dx = x - meanx */               \
+  VDIVPD  n, dx, t                                               \
+  VADDPD  t, meanx, meanx     /* meanx = meanx + dx / n */       \
+  VSUBPD  meany, y, dy        /* dy = y - meany */               \
+  VDIVPD  n, dy, t                                               \
+  VADDPD  t, meany, meany     /* meany = meany + dy / n */       \
+  VSUBPD  meanx, x, t                                            \
+  VMULPD  dx, t, t                                               \
+  VADDPD  t, m2x, m2x         /* m2x = m2x + dx * (x - meanx) */ \
+  VSUBPD  meany, y, t         /* t = y - meany */                \
+  VMULPD  t, dx, dx                                              \
+  VADDPD  dx, cxy, cxy        /* cxy = cxy + dx * (y - meany) */ \
+  VMULPD  dy, t, t                                               \
+  VADDPD  t, m2y, m2y         /* m2y = m2y + dy * (y - meany) */
+
+#define BC_MOMENTS_CONSTANTS(one, two, three, four, six) \
+  VBROADCASTSD CONSTF64_1(), one                         \
+  VBROADCASTSD CONSTF64_2(), two                         \
+  VBROADCASTSD CONSTF64_3(), three                       \
+  VBROADCASTSD CONSTF64_4(), four                        \
+  VBROADCASTSD CONSTF64_6(), six
+
+// Loads the moments of 8 lanes at `base`+`off`
+#define BC_MOMENTS_LOAD(off, base, n, mean, m2, m3, m4) \
+  VMOVUPD (MOMENTS_N_OFFSET+off)(base), n                \
+  VMOVUPD (MOMENTS_MEAN_OFFSET+off)(base), mean          \
+  VMOVUPD (MOMENTS_M2_OFFSET+off)(base), m2              \
+  VMOVUPD (MOMENTS_M3_OFFSET+off)(base), m3              \
+  VMOVUPD (MOMENTS_M4_OFFSET+off)(base), m4
+
+// Stores the moments of the active lanes of 8 lanes at `base`+`off`
+#define BC_MOMENTS_STORE(off, base, mask, n, mean, m2, m3, m4) \
+  VMOVUPD n, mask, (MOMENTS_N_OFFSET+off)(base)                 \
+  VMOVUPD mean, mask, (MOMENTS_MEAN_OFFSET+off)(base)           \
+  VMOVUPD m2, mask, (MOMENTS_M2_OFFSET+off)(base)               \
+  VMOVUPD m3, mask, (MOMENTS_M3_OFFSET+off)(base)               \
+  VMOVUPD m4, mask, (MOMENTS_M4_OFFSET+off)(base)
+
+// Loads the co-moments of 8 lanes at `base`+`off`
+#define BC_COMOMENTS_LOAD(off, base, n, meanx, meany, m2x, m2y, cxy) \
+  VMOVUPD (COMOMENTS_N_OFFSET+off)(base), n                           \
+  VMOVUPD (COMOMENTS_MEANX_OFFSET+off)(base), meanx                   \
+  VMOVUPD (COMOMENTS_MEANY_OFFSET+off)(base), meany                   \
+  VMOVUPD (COMOMENTS_M2X_OFFSET+off)(base), m2x                       \
+  VMOVUPD (COMOMENTS_M2Y_OFFSET+off)(base), m2y                       \
+  VMOVUPD (COMOMENTS_CXY_OFFSET+off)(base), cxy
+
+// Stores the co-moments of the active lanes of 8 lanes at `base`+`off`
+#define BC_COMOMENTS_STORE(off, base, mask, n, meanx, meany, m2x, m2y, cxy) \
+  VMOVUPD n, mask, (COMOMENTS_N_OFFSET+off)(base)                            \
+  VMOVUPD meanx, mask, (COMOMENTS_MEANX_OFFSET+off)(base)                    \
+  VMOVUPD meany, mask, (COMOMENTS_MEANY_OFFSET+off)(base)                    \
+  VMOVUPD m2x, mask, (COMOMENTS_M2X_OFFSET+off)(base)                        \
+  VMOVUPD m2y, mask, (COMOMENTS_M2Y_OFFSET+off)(base)                        \
+  VMOVUPD cxy, mask, (COMOMENTS_CXY_OFFSET+off)(base)
+
+// Gathers the moments of 8 lanes of the buckets at `base`+`index`
+#define BC_MOMENTS_GATHER(base, index, mask, tmpmask, n, mean, m2, m3, m4) \
+  KMOVB mask, tmpmask                                                      \
+  VGATHERDPD (MOMENTS_N_OFFSET)(base)(index*1), tmpmask, n                 \
+  KMOVB mask, tmpmask                                                      \
+  VGATHERDPD (MOMENTS_MEAN_OFFSET)(base)(index*1), tmpmask, mean           \
+  KMOVB mask, tmpmask                                                      \
+  VGATHERDPD (MOMENTS_M2_OFFSET)(base)(index*1), tmpmask, m2               \
+  KMOVB mask, tmpmask                                                      \
+  VGATHERDPD (MOMENTS_M3_OFFSET)(base)(index*1), tmpmask, m3               \
+  KMOVB mask, tmpmask                                                      \
+  VGATHERDPD (MOMENTS_M4_OFFSET)(base)(index*1), tmpmask, m4
+
+// Scatters the moments of 8 lanes to the buckets at `base`+`index`
+#define BC_MOMENTS_SCATTER(base, index, mask, tmpmask, n, mean, m2, m3, m4) \
+  KMOVB mask, tmpmask                                                       \
+  VSCATTERDPD n, tmpmask, (MOMENTS_N_OFFSET)(base)(index*1)                 \
+  KMOVB mask, tmpmask                                                       \
+  VSCATTERDPD mean, tmpmask, (MOMENTS_MEAN_OFFSET)(base)(index*1)           \
+  KMOVB mask, tmpmask                                                       \
+  VSCATTERDPD m2, tmpmask, (MOMENTS_M2_OFFSET)(base)(index*1)               \
+  KMOVB mask, tmpmask                                                       \
+  VSCATTERDPD m3, tmpmask, (MOMENTS_M3_OFFSET)(base)(index*1)               \
+  KMOVB mask, tmpmask                                                       \
+  VSCATTERDPD m4, tmpmask, (MOMENTS_M4_OFFSET)(base)(index*1)
+
+// Gathers the co-moments of 8 lanes of the buckets at `base`+`index`
+#define BC_COMOMENTS_GATHER(base, index, mask, tmpmask, n, meanx, meany, m2x, m2y, cxy) \
+  KMOVB mask, tmpmask                                                                   \
+  VGATHERDPD (COMOMENTS_N_OFFSET)(base)(index*1), tmpmask, n                            \
+  KMOVB mask, tmpmask                                                                   \
+  VGATHERDPD (COMOMENTS_MEANX_OFFSET)(base)(index*1), tmpmask, meanx                    \
+  KMOVB mask, tmpmask                                                                   \
+  VGATHERDPD (COMOMENTS_MEANY_OFFSET)(base)(index*1), tmpmask, meany                    \
+  KMOVB mask, tmpmask                                                                   \
+  VGATHERDPD (COMOMENTS_M2X_OFFSET)(base)(index*1), tmpmask, m2x                        \
+  KMOVB mask, tmpmask                                                                   \
+  VGATHERDPD (COMOMENTS_M2Y_OFFSET)(base)(index*1), tmpmask, m2y                        \
+  KMOVB mask, tmpmask                                                                   \
+  VGATHERDPD (COMOMENTS_CXY_OFFSET)(base)(index*1), tmpmask, cxy
+
+// Scatters the co-moments of 8 lanes to the buckets at `base`+`index`
+#define BC_COMOMENTS_SCATTER(base, index, mask, tmpmask, n, meanx, meany, m2x, m2y, cxy) \
+  KMOVB mask, tmpmask                                                                    \
+  VSCATTERDPD n, tmpmask, (COMOMENTS_N_OFFSET)(base)(index*1)                            \
+  KMOVB mask, tmpmask                                                                    \
+  VSCATTERDPD meanx, tmpmask, (COMOMENTS_MEANX_OFFSET)(base)(index*1)                    \
+  KMOVB mask, tmpmask                                                                    \
+  VSCATTERDPD meany, tmpmask, (COMOMENTS_MEANY_OFFSET)(base)(index*1)                    \
+  KMOVB mask, tmpmask                                                                    \
+  VSCATTERDPD m2x, tmpmask, (COMOMENTS_M2X_OFFSET)(base)(index*1)                        \
+  KMOVB mask, tmpmask                                                                    \
+  VSCATTERDPD m2y, tmpmask, (COMOMENTS_M2Y_OFFSET)(base)(index*1)                        \
+  KMOVB mask, tmpmask                                                                    \
+  VSCATTERDPD cxy, tmpmask, (COMOMENTS_CXY_OFFSET)(base)(index*1)
+
+// Computes the offsets of the bucket states of 16 lanes; the lanes
+// that share a bucket are assigned distinct lanes of its state
+//
+// Input:
+// - slot                   - the slot of the buckets
+// - mask                   - the active lanes
+// - base                   - the offsets of the states of the lanes 0..7 (output)
+// - hi                     - the offsets of the states of the lanes 8..15 (output)
+#define BC_AGGSLOT_LANES(slot, mask, base, hi, tmp0, tmp1) \
+  VMOVDQU32     0(VIRT_VALUES)(slot*1), mask, base          \
+  VPCONFLICTD.Z base, mask, tmp0                            \
+  VPBROADCASTD  CONSTD_32(), tmp1                           \
+  VPLZCNTD      tmp0, tmp0                                  \
+  VPSUBD        tmp0, tmp1, tmp0                            \
+  VPSLLD        $3, tmp0, tmp0                              \
+  VPADDD        tmp0, base, base                            \
+  VEXTRACTI32X8 $1, base, hi
+
+// _ = aggmoments.f64(a[0], s[1]).k[2]
+TEXT bcaggmomentsf(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_AGGSLOT_SIZE, OUT(BX), OUT(R8))
+  BC_LOAD_K1_K2_FROM_SLOT(OUT(K1), OUT(K2), IN(R8))
+  BC_LOAD_F64_FROM_SLOT_MASKED(OUT(Z4), OUT(Z5), IN(BX), IN(K1), IN(K2))
+
+  BC_UNPACK_RU32(0, OUT(DX))
+  ADDQ VIRT_AGG_BUFFER, DX
+
+  BC_MOMENTS_CONSTANTS(Z26, Z27, Z28, Z29, Z30)
+
+  // lanes 0..7
+  BC_MOMENTS_LOAD(0, DX, Z16, Z17, Z18, Z19, Z20)
+  BC_MOMENTS_UPDATE(Z4, Z16, Z17, Z18, Z19, Z20, Z26, Z27, Z28, Z29, Z30, Z21, Z22, Z23, Z24, Z25, Z31)
+  BC_MOMENTS_STORE(0, DX, K1, Z16, Z17, Z18, Z19, Z20)
+
+  // lanes 8..15
+  BC_MOMENTS_LOAD(64, DX, Z16, Z17, Z18, Z19, Z20)
+  BC_MOMENTS_UPDATE(Z5, Z16, Z17, Z18, Z19, Z20, Z26, Z27, Z28, Z29, Z30, Z21, Z22, Z23, Z24, Z25, Z31)
+  BC_MOMENTS_STORE(64, DX, K2, Z16, Z17, Z18, Z19, Z20)
+
+  NEXT_ADVANCE(BC_SLOT_SIZE*2 + BC_AGGSLOT_SIZE)
+
+// _ = aggslotmoments.f64(a[0], l[1], s[2]).k[3]
+TEXT bcaggslotmomentsf(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_3xSLOT(BC_AGGSLOT_SIZE, OUT(DX), OUT(BX), OUT(CX))
+  BC_LOAD_K1_K2_FROM_SLOT(OUT(K1), OUT(K6), IN(CX))
+  BC_LOAD_F64_FROM_SLOT_MASKED(OUT(Z4), OUT(Z5), IN(BX), IN(K1), IN(K6))
+
+  // Load the aggregation data pointer.
+  BC_UNPACK_RU32(0, OUT(R15))
+  ADDQ $const_aggregateTagSize, R15
+  ADDQ radixTree64_values(VIRT_AGG_BUFFER), R15
+
+  BC_AGGSLOT_LANES(DX, K1, Z6, Y7, Z8, Z9)
+  BC_MOMENTS_CONSTANTS(Z26, Z27, Z28, Z29, Z30)
+
+  // lanes 0..7
+  BC_MOMENTS_GATHER(R15, Y6, K1, K2, Z16, Z17, Z18, Z19, Z20)
+  BC_MOMENTS_UPDATE(Z4, Z16, Z17, Z18, Z19, Z20, Z26, Z27, Z28, Z29, Z30, Z21, Z22, Z23, Z24, Z25, Z31)
+  BC_MOMENTS_SCATTER(R15, Y6, K1, K2, Z16, Z17, Z18, Z19, Z20)
+
+  // lanes 8..15
+  BC_MOMENTS_GATHER(R15, Y7, K6, K2, Z16, Z17, Z18, Z19, Z20)
+  BC_MOMENTS_UPDATE(Z5, Z16, Z17, Z18, Z19, Z20, Z26, Z27, Z28, Z29, Z30, Z21, Z22, Z23, Z24, Z25, Z31)
+  BC_MOMENTS_SCATTER(R15, Y7, K6, K2, Z16, Z17, Z18, Z19, Z20)
+
+  NEXT_ADVANCE(BC_SLOT_SIZE*3 + BC_AGGSLOT_SIZE)
+
+// _ = aggcomoments.f64(a[0], s[1], s[2]).k[3]
+TEXT bcaggcomomentsf(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_3xSLOT(BC_AGGSLOT_SIZE, OUT(BX), OUT(CX), OUT(R8))
+  BC_LOAD_K1_K2_FROM_SLOT(OUT(K1), OUT(K2), IN(R8))
+  BC_LOAD_F64_FROM_SLOT_MASKED(OUT(Z4), OUT(Z5), IN(BX), IN(K1), IN(K2))
+  BC_LOAD_F64_FROM_SLOT_MASKED(OUT(Z6), OUT(Z7), IN(CX), IN(K1), IN(K2))
+
+  BC_UNPACK_RU32(0, OUT(DX))
+  ADDQ VIRT_AGG_BUFFER, DX
+
+  VBROADCASTSD CONSTF64_1(), Z26
+
+  // lanes 0..7
+  BC_COMOMENTS_LOAD(0, DX, Z16, Z17, Z18, Z19, Z20, Z21)
+  BC_COMOMENTS_UPDATE(Z4, Z6, Z16, Z17, Z18, Z19, Z20, Z21, Z26, Z22, Z23, Z24)
+  BC_COMOMENTS_STORE(0, DX, K1, Z16, Z17, Z18, Z19, Z20, Z21)
+
+  // lanes 8..15
+  BC_COMOMENTS_LOAD(64, DX, Z16, Z17, Z18, Z19, Z20, Z21)
+  BC_COMOMENTS_UPDATE(Z5, Z7, Z16, Z17, Z18, Z19, Z20, Z21, Z26, Z22, Z23, Z24)
+  BC_COMOMENTS_STORE(64, DX, K2, Z16, Z17, Z18, Z19, Z20, Z21)
+
+  NEXT_ADVANCE(BC_SLOT_SIZE*3 + BC_AGGSLOT_SIZE)
+
+// _ = aggslotcomoments.f64(a[0], l[1], s[2], s[3]).k[4]
+TEXT bcaggslotcomomentsf(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_4xSLOT(BC_AGGSLOT_SIZE, OUT(DX), OUT(BX), OUT(CX), OUT(R8))
+  BC_LOAD_K1_K2_FROM_SLOT(OUT(K1), OUT(K6), IN(R8))
+  BC_LOAD_F64_FROM_SLOT_MASKED(OUT(Z4), OUT(Z5), IN(BX), IN(K1), IN(K6))
+  BC_LOAD_F64_FROM_SLOT_MASKED(OUT(Z12), OUT(Z13), IN(CX), IN(K1), IN(K6))
+
+  // Load the aggregation data pointer.
+  BC_UNPACK_RU32(0, OUT(R15))
+  ADDQ $const_aggregateTagSize, R15
+  ADDQ radixTree64_values(VIRT_AGG_BUFFER), R15
+
+  BC_AGGSLOT_LANES(DX, K1, Z6, Y7, Z8, Z9)
+  VBROADCASTSD CONSTF64_1(), Z26
+
+  // lanes 0..7
+  BC_COMOMENTS_GATHER(R15, Y6, K1, K2, Z16, Z17, Z18, Z19, Z20, Z21)
+  BC_COMOMENTS_UPDATE(Z4, Z12, Z16, Z17, Z18, Z19, Z20, Z21, Z26, Z22, Z23, Z24)
+  BC_COMOMENTS_SCATTER(R15, Y6, K1, K2, Z16, Z17, Z18, Z19, Z20, Z21)
+
+  // lanes 8..15
+  BC_COMOMENTS_GATHER(R15, Y7, K6, K2, Z16, Z17, Z18, Z19, Z20, Z21)
+  BC_COMOMENTS_UPDATE(Z5, Z13, Z16, Z17, Z18, Z19, Z20, Z21, Z26, Z22, Z23, Z24)
+  BC_COMOMENTS_SCATTER(R15, Y7, K6, K2, Z16, Z17, Z18, Z19, Z20, Z21)
+
+  NEXT_ADVANCE(BC_SLOT_SIZE*4 + BC_AGGSLOT_SIZE)
+
+#undef MOMENTS_N_OFFSET
+#undef MOMENTS_MEAN_OFFSET
+#undef MOMENTS_M2_OFFSET
+#undef MOMENTS_M3_OFFSET
+#undef MOMENTS_M4_OFFSET
+#undef COMOMENTS_N_OFFSET
+#undef COMOMENTS_MEANX_OFFSET
+#undef COMOMENTS_MEANY_OFFSET
+#undef COMOMENTS_M2X_OFFSET
+#undef COMOMENTS_M2Y_OFFSET
+#undef COMOMENTS_CXY_OFFSET
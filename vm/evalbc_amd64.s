@@ -12768,6 +12768,21 @@ next:
 
 #include "evalbc_approxcount.h"
 
+// Hash and encoding functions
+// --------------------------------------------------
+
+#include "evalbc_hash.h"
+
+// URL and network address functions
+// --------------------------------------------------
+
+#include "evalbc_net.h"
+
+// Statistical aggregates
+// --------------------------------------------------
+
+#include "evalbc_aggmoments.h"
+
 // POW(x, intpow) implementation
 
 // BC_POWINT generates specialisation for either for floats
@@ -0,0 +1,1621 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// MD5/SHA1/SHA256 functions
+// --------------------------------------------------
+//
+// All three digests use the same Merkle-Damgard construction: the input
+// is padded with 0x80, zeros, and its length in bits and then compressed
+// in 64-byte blocks. Each instruction compresses all lanes at once; the
+// message words of the current block are gathered one 32-bit word at a
+// time and the padding is applied on the fly, so lanes having less blocks
+// than others are simply masked out of the state update.
+//
+// Register usage shared by all the digests:
+//
+//   Z0       - 0x80 in all lanes (the first padding byte)
+//   Z1       - byte-swap predicate for VPSHUFB (SHA only)
+//   Z2       - offset of the current block
+//   Z3       - number of input bytes remaining at the current block (signed)
+//   Z4       - number of blocks remaining (signed)
+//   Z5..Z7   - temporaries
+//   Z8..Z15  - working variables
+//   Z16..Z31 - message block / schedule
+//   K1       - active lanes
+//   K2       - lanes that compress the current block
+//   R8       - offset of the output buffer (relative to VIRT_BASE)
+//   R15      - address of the output buffer
+//
+// The output buffer is used to keep the hash state until all the blocks
+// have been compressed; after that it's overwritten by the hex-encoded
+// digest of each lane.
+
+// HASH_PROLOGUE loads the input strings, allocates OutputSize bytes
+// of the output buffer and calculates the number of blocks of each lane.
+#define HASH_PROLOGUE(OutputSize)                                              \
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*1, OUT(BX), OUT(R8))                           \
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))                                        \
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))             \
+                                                                               \
+  BC_CHECK_SCRATCH_CAPACITY($(OutputSize), R8, error_handler_more_scratch)     \
+  BC_GET_SCRATCH_BASE_GP(R8)                                                   \
+  ADDQ $(OutputSize), bytecode_scratch+8(VIRT_BCPTR)                           \
+  LEAQ 0(VIRT_BASE)(R8*1), R15                                                 \
+                                                                               \
+  VPADDD.BCST CONSTD_72(), Z3, Z4        /* Z4 <- length + 1 + 8 + 63 */        \
+  VPSRLD $6, Z4, Z4                      /* Z4 <- number of blocks */           \
+  VPSLLD $3, Z3, Z5                                                            \
+  VMOVDQU32 Z5, BC_SPILL_AREA(0)         /* [] <- length in bits (low) */       \
+  VPSRLD $29, Z3, Z5                                                           \
+  VMOVDQU32 Z5, BC_SPILL_AREA(64)        /* [] <- length in bits (high) */      \
+  VPBROADCASTD CONSTD_0x80(), Z0
+
+// HASH_LOAD_WORD gathers the message word Index of the current block
+// and applies the padding; Z5 must contain the number of bytes remaining
+// at the word on input and it's decremented by 4 on output.
+#define HASH_LOAD_WORD(Index, DstZ)                                            \
+  VPCMPD.BCST $VPCMP_IMM_GE, CONSTD_1(), Z5, K2, K3                            \
+  VPXORD DstZ, DstZ, DstZ                                                      \
+  VPGATHERDD (4*Index)(VIRT_BASE)(Z2*1), K3, DstZ                              \
+  VPSLLD $3, Z5, Z6                                                            \
+  VPSLLVD Z6, Z0, Z7                     /* Z7 <- 0x80 at the end of input */   \
+  VPSRLD $7, Z7, Z6                                                            \
+  VPSUBD.BCST CONSTD_1(), Z6, Z6         /* Z6 <- mask of the remaining bytes */ \
+  VPTERNLOGD $0xEA, Z7, Z6, DstZ         /* DstZ <- (DstZ & Z6) | Z7 */         \
+  VPSUBD.BCST CONSTD_4(), Z5, Z5
+
+#define HASH_LOAD_BLOCK()                                                      \
+  VMOVDQA32 Z3, Z5                                                             \
+  HASH_LOAD_WORD(0, Z16)                                                       \
+  HASH_LOAD_WORD(1, Z17)                                                       \
+  HASH_LOAD_WORD(2, Z18)                                                       \
+  HASH_LOAD_WORD(3, Z19)                                                       \
+  HASH_LOAD_WORD(4, Z20)                                                       \
+  HASH_LOAD_WORD(5, Z21)                                                       \
+  HASH_LOAD_WORD(6, Z22)                                                       \
+  HASH_LOAD_WORD(7, Z23)                                                       \
+  HASH_LOAD_WORD(8, Z24)                                                       \
+  HASH_LOAD_WORD(9, Z25)                                                       \
+  HASH_LOAD_WORD(10, Z26)                                                      \
+  HASH_LOAD_WORD(11, Z27)                                                      \
+  HASH_LOAD_WORD(12, Z28)                                                      \
+  HASH_LOAD_WORD(13, Z29)                                                      \
+  HASH_LOAD_WORD(14, Z30)                                                      \
+  HASH_LOAD_WORD(15, Z31)
+
+#define HASH_BSWAP_BLOCK()                                                     \
+  VPSHUFB Z1, Z16, Z16                                                         \
+  VPSHUFB Z1, Z17, Z17                                                         \
+  VPSHUFB Z1, Z18, Z18                                                         \
+  VPSHUFB Z1, Z19, Z19                                                         \
+  VPSHUFB Z1, Z20, Z20                                                         \
+  VPSHUFB Z1, Z21, Z21                                                         \
+  VPSHUFB Z1, Z22, Z22                                                         \
+  VPSHUFB Z1, Z23, Z23                                                         \
+  VPSHUFB Z1, Z24, Z24                                                         \
+  VPSHUFB Z1, Z25, Z25                                                         \
+  VPSHUFB Z1, Z26, Z26                                                         \
+  VPSHUFB Z1, Z27, Z27                                                         \
+  VPSHUFB Z1, Z28, Z28                                                         \
+  VPSHUFB Z1, Z29, Z29                                                         \
+  VPSHUFB Z1, Z30, Z30                                                         \
+  VPSHUFB Z1, Z31, Z31
+
+// HASH_BEGIN_BLOCK sets K2 to the lanes having a block to compress
+// and jumps to Done if there are no such lanes.
+#define HASH_BEGIN_BLOCK(Done)                                                 \
+  VPCMPD.BCST $VPCMP_IMM_GE, CONSTD_1(), Z4, K1, K2                            \
+  KTESTW K2, K2                                                                \
+  JZ Done
+
+// HASH_LENGTH_MASK sets K3 to the lanes compressing their last block
+#define HASH_LENGTH_MASK()                                                     \
+  VPCMPD.BCST $VPCMP_IMM_EQ, CONSTD_1(), Z4, K2, K3
+
+#define HASH_NEXT_BLOCK()                                                      \
+  VPADDD.BCST CONSTD_64(), Z2, Z2                                              \
+  VPSUBD.BCST CONSTD_64(), Z3, Z3                                              \
+  VPSUBD.BCST CONSTD_1(), Z4, Z4
+
+#define HASH_INIT_STATE(Offset, Value)                                         \
+  VPBROADCASTD Value, Z5                                                       \
+  VMOVDQU32 Z5, Offset(R15)
+
+#define HASH_UPDATE_STATE(Offset, SrcZ)                                        \
+  VPADDD Offset(R15), SrcZ, SrcZ                                               \
+  VMOVDQU32 SrcZ, K2, Offset(R15)
+
+// HASH_HEX_PREPARE loads the constants used by HASH_HEX_WORD;
+// Scale is the length of the hex-encoded digest divided by 8.
+#define HASH_HEX_PREPARE(Scale)                                                \
+  VMOVDQU32 CONST_GET_PTR(consts_offsets_d_8, 0), Z20                          \
+  VPMULLD.BCST Scale, Z20, Z20           /* Z20 <- offsets of each lane */      \
+  VEXTRACTI32X8 $1, Z20, Y21                                                   \
+  VBROADCASTI32X4 CONST_GET_PTR(hash_hex_spread, 0), Z17                       \
+  VPBROADCASTD CONSTD_0x0F0F0F0F(), Z18                                        \
+  VBROADCASTI32X4 CONST_GET_PTR(hash_hex_chars, 0), Z19                        \
+  MOVQ $0xAAAAAAAAAAAAAAAA, BX                                                 \
+  KMOVQ BX, K4                           /* K4 <- odd bytes */
+
+// HASH_HEX_QWORDS converts the low 32 bits of each quadword to 8 hex chars
+#define HASH_HEX_QWORDS(Z)                                                     \
+  VPSHUFB Z17, Z, Z                      /* Z <- each byte twice */             \
+  VPSRLW $4, Z, Z7                                                             \
+  VPBLENDMB Z, Z7, K4, Z                 /* Z <- (b >> 4, b) pairs */           \
+  VPANDD Z18, Z, Z                                                             \
+  VPSHUFB Z, Z19, Z
+
+// HASH_HEX_WORD stores the hex-encoded word of each lane
+// at the given offset of the output of that lane.
+#define HASH_HEX_WORD(Offset, SrcZ, SrcY)                                      \
+  VPMOVZXDQ SrcY, Z5                                                           \
+  VEXTRACTI32X8 $1, SrcZ, Y6                                                   \
+  VPMOVZXDQ Y6, Z6                                                             \
+  HASH_HEX_QWORDS(Z5)                                                          \
+  HASH_HEX_QWORDS(Z6)                                                          \
+  KMOVB K1, K3                                                                 \
+  VPSCATTERDQ Z5, K3, Offset(R15)(Y20*1)                                       \
+  KSHIFTRW $8, K1, K3                                                          \
+  VPSCATTERDQ Z6, K3, Offset(R15)(Y21*1)
+
+#define HASH_EPILOGUE(DigestLength)                                            \
+  BC_UNPACK_SLOT(0, OUT(DX))                                                   \
+  VPBROADCASTD.Z R8, K1, Z2                                                    \
+  VPADDD Z20, Z2, K1, Z2                                                       \
+  VPBROADCASTD.Z DigestLength, K1, Z3                                          \
+  BC_STORE_SLICE_TO_SLOT(IN(Z2), IN(Z3), IN(DX))                               \
+  NEXT_ADVANCE(BC_SLOT_SIZE*3)
+
+#define MD5_STEP(Fn, A, B, C, D, M, Index, Shift)                              \
+  VMOVDQA32 B, Z6                                                              \
+  VPTERNLOGD Fn, D, C, Z6                                                      \
+  VPADDD M, A, A                                                               \
+  VPADDD.BCST CONST_GET_PTR(md5_consts, 4*Index), A, A                         \
+  VPADDD Z6, A, A                                                              \
+  VPROLD $Shift, A, A                                                          \
+  VPADDD B, A, A
+
+#define SHA1_SCHEDULE(W, W3, W8, W14)                                          \
+  VPTERNLOGD $0x96, W8, W3, W                                                  \
+  VPXORD W14, W, W                                                             \
+  VPROLD $1, W, W
+
+#define SHA1_STEP(Fn, K, A, B, C, D, E, W)                                     \
+  VPROLD $5, A, Z6                                                             \
+  VMOVDQA32 B, Z7                                                              \
+  VPTERNLOGD Fn, D, C, Z7                                                      \
+  VPADDD Z6, E, E                                                              \
+  VPADDD Z7, E, E                                                              \
+  VPADDD.BCST K, E, E                                                          \
+  VPADDD W, E, E                                                               \
+  VPROLD $30, B, B
+
+#define SHA256_SCHEDULE(W, W2, W7, W15)                                        \
+  VPRORD $7, W15, Z5                                                           \
+  VPRORD $18, W15, Z6                                                          \
+  VPSRLD $3, W15, Z7                                                           \
+  VPTERNLOGD $0x96, Z7, Z6, Z5                                                 \
+  VPADDD Z5, W, W                                                              \
+  VPADDD W7, W, W                                                              \
+  VPRORD $17, W2, Z5                                                           \
+  VPRORD $19, W2, Z6                                                           \
+  VPSRLD $10, W2, Z7                                                           \
+  VPTERNLOGD $0x96, Z7, Z6, Z5                                                 \
+  VPADDD Z5, W, W
+
+#define SHA256_STEP(Index, A, B, C, D, E, F, G, H, W)                          \
+  VPRORD $6, E, Z5                                                             \
+  VPRORD $11, E, Z6                                                            \
+  VPRORD $25, E, Z7                                                            \
+  VPTERNLOGD $0x96, Z7, Z6, Z5                                                 \
+  VPADDD Z5, H, H                                                              \
+  VMOVDQA32 E, Z6                                                              \
+  VPTERNLOGD $0xCA, G, F, Z6                                                   \
+  VPADDD Z6, H, H                                                              \
+  VPADDD.BCST CONST_GET_PTR(sha256_consts, 4*Index), H, H                      \
+  VPADDD W, H, H                                                               \
+  VPADDD H, D, D                                                               \
+  VPRORD $2, A, Z5                                                             \
+  VPRORD $13, A, Z6                                                            \
+  VPRORD $22, A, Z7                                                            \
+  VPTERNLOGD $0x96, Z7, Z6, Z5                                                 \
+  VPADDD Z5, H, H                                                              \
+  VMOVDQA32 A, Z6                                                              \
+  VPTERNLOGD $0xE8, C, B, Z6                                                   \
+  VPADDD Z6, H, H
+
+// s[0] = md5str(slice[1]).k[2]
+//
+// scratch: 16 * 32
+TEXT bcmd5str(SB), NOSPLIT|NOFRAME, $0
+  HASH_PROLOGUE(16 * 32)
+  HASH_INIT_STATE(0, CONSTD_0x67452301())
+  HASH_INIT_STATE(64, CONSTD_0xEFCDAB89())
+  HASH_INIT_STATE(128, CONSTD_0x98BADCFE())
+  HASH_INIT_STATE(192, CONSTD_0x10325476())
+
+block_loop:
+  HASH_BEGIN_BLOCK(blocks_done)
+  HASH_LOAD_BLOCK()
+
+  // the last block ends with the length in bits (little-endian)
+  HASH_LENGTH_MASK()
+  VMOVDQU32 BC_SPILL_AREA(0), K3, Z30
+  VMOVDQU32 BC_SPILL_AREA(64), K3, Z31
+
+  VMOVDQU32 0(R15), Z8
+  VMOVDQU32 64(R15), Z9
+  VMOVDQU32 128(R15), Z10
+  VMOVDQU32 192(R15), Z11
+
+  MD5_STEP($0xCA, Z8, Z9, Z10, Z11, Z16, 0, 7)
+  MD5_STEP($0xCA, Z11, Z8, Z9, Z10, Z17, 1, 12)
+  MD5_STEP($0xCA, Z10, Z11, Z8, Z9, Z18, 2, 17)
+  MD5_STEP($0xCA, Z9, Z10, Z11, Z8, Z19, 3, 22)
+  MD5_STEP($0xCA, Z8, Z9, Z10, Z11, Z20, 4, 7)
+  MD5_STEP($0xCA, Z11, Z8, Z9, Z10, Z21, 5, 12)
+  MD5_STEP($0xCA, Z10, Z11, Z8, Z9, Z22, 6, 17)
+  MD5_STEP($0xCA, Z9, Z10, Z11, Z8, Z23, 7, 22)
+  MD5_STEP($0xCA, Z8, Z9, Z10, Z11, Z24, 8, 7)
+  MD5_STEP($0xCA, Z11, Z8, Z9, Z10, Z25, 9, 12)
+  MD5_STEP($0xCA, Z10, Z11, Z8, Z9, Z26, 10, 17)
+  MD5_STEP($0xCA, Z9, Z10, Z11, Z8, Z27, 11, 22)
+  MD5_STEP($0xCA, Z8, Z9, Z10, Z11, Z28, 12, 7)
+  MD5_STEP($0xCA, Z11, Z8, Z9, Z10, Z29, 13, 12)
+  MD5_STEP($0xCA, Z10, Z11, Z8, Z9, Z30, 14, 17)
+  MD5_STEP($0xCA, Z9, Z10, Z11, Z8, Z31, 15, 22)
+  MD5_STEP($0xE4, Z8, Z9, Z10, Z11, Z17, 16, 5)
+  MD5_STEP($0xE4, Z11, Z8, Z9, Z10, Z22, 17, 9)
+  MD5_STEP($0xE4, Z10, Z11, Z8, Z9, Z27, 18, 14)
+  MD5_STEP($0xE4, Z9, Z10, Z11, Z8, Z16, 19, 20)
+  MD5_STEP($0xE4, Z8, Z9, Z10, Z11, Z21, 20, 5)
+  MD5_STEP($0xE4, Z11, Z8, Z9, Z10, Z26, 21, 9)
+  MD5_STEP($0xE4, Z10, Z11, Z8, Z9, Z31, 22, 14)
+  MD5_STEP($0xE4, Z9, Z10, Z11, Z8, Z20, 23, 20)
+  MD5_STEP($0xE4, Z8, Z9, Z10, Z11, Z25, 24, 5)
+  MD5_STEP($0xE4, Z11, Z8, Z9, Z10, Z30, 25, 9)
+  MD5_STEP($0xE4, Z10, Z11, Z8, Z9, Z19, 26, 14)
+  MD5_STEP($0xE4, Z9, Z10, Z11, Z8, Z24, 27, 20)
+  MD5_STEP($0xE4, Z8, Z9, Z10, Z11, Z29, 28, 5)
+  MD5_STEP($0xE4, Z11, Z8, Z9, Z10, Z18, 29, 9)
+  MD5_STEP($0xE4, Z10, Z11, Z8, Z9, Z23, 30, 14)
+  MD5_STEP($0xE4, Z9, Z10, Z11, Z8, Z28, 31, 20)
+  MD5_STEP($0x96, Z8, Z9, Z10, Z11, Z21, 32, 4)
+  MD5_STEP($0x96, Z11, Z8, Z9, Z10, Z24, 33, 11)
+  MD5_STEP($0x96, Z10, Z11, Z8, Z9, Z27, 34, 16)
+  MD5_STEP($0x96, Z9, Z10, Z11, Z8, Z30, 35, 23)
+  MD5_STEP($0x96, Z8, Z9, Z10, Z11, Z17, 36, 4)
+  MD5_STEP($0x96, Z11, Z8, Z9, Z10, Z20, 37, 11)
+  MD5_STEP($0x96, Z10, Z11, Z8, Z9, Z23, 38, 16)
+  MD5_STEP($0x96, Z9, Z10, Z11, Z8, Z26, 39, 23)
+  MD5_STEP($0x96, Z8, Z9, Z10, Z11, Z29, 40, 4)
+  MD5_STEP($0x96, Z11, Z8, Z9, Z10, Z16, 41, 11)
+  MD5_STEP($0x96, Z10, Z11, Z8, Z9, Z19, 42, 16)
+  MD5_STEP($0x96, Z9, Z10, Z11, Z8, Z22, 43, 23)
+  MD5_STEP($0x96, Z8, Z9, Z10, Z11, Z25, 44, 4)
+  MD5_STEP($0x96, Z11, Z8, Z9, Z10, Z28, 45, 11)
+  MD5_STEP($0x96, Z10, Z11, Z8, Z9, Z31, 46, 16)
+  MD5_STEP($0x96, Z9, Z10, Z11, Z8, Z18, 47, 23)
+  MD5_STEP($0x39, Z8, Z9, Z10, Z11, Z16, 48, 6)
+  MD5_STEP($0x39, Z11, Z8, Z9, Z10, Z23, 49, 10)
+  MD5_STEP($0x39, Z10, Z11, Z8, Z9, Z30, 50, 15)
+  MD5_STEP($0x39, Z9, Z10, Z11, Z8, Z21, 51, 21)
+  MD5_STEP($0x39, Z8, Z9, Z10, Z11, Z28, 52, 6)
+  MD5_STEP($0x39, Z11, Z8, Z9, Z10, Z19, 53, 10)
+  MD5_STEP($0x39, Z10, Z11, Z8, Z9, Z26, 54, 15)
+  MD5_STEP($0x39, Z9, Z10, Z11, Z8, Z17, 55, 21)
+  MD5_STEP($0x39, Z8, Z9, Z10, Z11, Z24, 56, 6)
+  MD5_STEP($0x39, Z11, Z8, Z9, Z10, Z31, 57, 10)
+  MD5_STEP($0x39, Z10, Z11, Z8, Z9, Z22, 58, 15)
+  MD5_STEP($0x39, Z9, Z10, Z11, Z8, Z29, 59, 21)
+  MD5_STEP($0x39, Z8, Z9, Z10, Z11, Z20, 60, 6)
+  MD5_STEP($0x39, Z11, Z8, Z9, Z10, Z27, 61, 10)
+  MD5_STEP($0x39, Z10, Z11, Z8, Z9, Z18, 62, 15)
+  MD5_STEP($0x39, Z9, Z10, Z11, Z8, Z25, 63, 21)
+  HASH_UPDATE_STATE(0, Z8)
+  HASH_UPDATE_STATE(64, Z9)
+  HASH_UPDATE_STATE(128, Z10)
+  HASH_UPDATE_STATE(192, Z11)
+  HASH_NEXT_BLOCK()
+  JMP block_loop
+
+blocks_done:
+  VMOVDQU32 0(R15), Z8
+  VMOVDQU32 64(R15), Z9
+  VMOVDQU32 128(R15), Z10
+  VMOVDQU32 192(R15), Z11
+
+  HASH_HEX_PREPARE(CONSTD_4())
+  HASH_HEX_WORD(0, Z8, Y8)
+  HASH_HEX_WORD(8, Z9, Y9)
+  HASH_HEX_WORD(16, Z10, Y10)
+  HASH_HEX_WORD(24, Z11, Y11)
+  HASH_EPILOGUE(CONSTD_32())
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+// s[0] = sha1str(slice[1]).k[2]
+//
+// scratch: 16 * 40
+TEXT bcsha1str(SB), NOSPLIT|NOFRAME, $0
+  HASH_PROLOGUE(16 * 40)
+  HASH_INIT_STATE(0, CONSTD_0x67452301())
+  HASH_INIT_STATE(64, CONSTD_0xEFCDAB89())
+  HASH_INIT_STATE(128, CONSTD_0x98BADCFE())
+  HASH_INIT_STATE(192, CONSTD_0x10325476())
+  HASH_INIT_STATE(256, CONSTD_0xC3D2E1F0())
+  VBROADCASTI32X4 CONST_GET_PTR(bswap32, 0), Z1
+
+block_loop:
+  HASH_BEGIN_BLOCK(blocks_done)
+  HASH_LOAD_BLOCK()
+  HASH_BSWAP_BLOCK()
+
+  // the last block ends with the length in bits (big-endian)
+  HASH_LENGTH_MASK()
+  VMOVDQU32 BC_SPILL_AREA(64), K3, Z30
+  VMOVDQU32 BC_SPILL_AREA(0), K3, Z31
+
+  VMOVDQU32 0(R15), Z8
+  VMOVDQU32 64(R15), Z9
+  VMOVDQU32 128(R15), Z10
+  VMOVDQU32 192(R15), Z11
+  VMOVDQU32 256(R15), Z12
+
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z8, Z9, Z10, Z11, Z12, Z16)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z12, Z8, Z9, Z10, Z11, Z17)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z11, Z12, Z8, Z9, Z10, Z18)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z10, Z11, Z12, Z8, Z9, Z19)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z9, Z10, Z11, Z12, Z8, Z20)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z8, Z9, Z10, Z11, Z12, Z21)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z12, Z8, Z9, Z10, Z11, Z22)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z11, Z12, Z8, Z9, Z10, Z23)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z10, Z11, Z12, Z8, Z9, Z24)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z9, Z10, Z11, Z12, Z8, Z25)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z8, Z9, Z10, Z11, Z12, Z26)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z12, Z8, Z9, Z10, Z11, Z27)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z11, Z12, Z8, Z9, Z10, Z28)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z10, Z11, Z12, Z8, Z9, Z29)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z9, Z10, Z11, Z12, Z8, Z30)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z8, Z9, Z10, Z11, Z12, Z31)
+  SHA1_SCHEDULE(Z16, Z29, Z24, Z18)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z12, Z8, Z9, Z10, Z11, Z16)
+  SHA1_SCHEDULE(Z17, Z30, Z25, Z19)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z11, Z12, Z8, Z9, Z10, Z17)
+  SHA1_SCHEDULE(Z18, Z31, Z26, Z20)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z10, Z11, Z12, Z8, Z9, Z18)
+  SHA1_SCHEDULE(Z19, Z16, Z27, Z21)
+  SHA1_STEP($0xCA, CONSTD_0x5A827999(), Z9, Z10, Z11, Z12, Z8, Z19)
+  SHA1_SCHEDULE(Z20, Z17, Z28, Z22)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z8, Z9, Z10, Z11, Z12, Z20)
+  SHA1_SCHEDULE(Z21, Z18, Z29, Z23)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z12, Z8, Z9, Z10, Z11, Z21)
+  SHA1_SCHEDULE(Z22, Z19, Z30, Z24)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z11, Z12, Z8, Z9, Z10, Z22)
+  SHA1_SCHEDULE(Z23, Z20, Z31, Z25)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z10, Z11, Z12, Z8, Z9, Z23)
+  SHA1_SCHEDULE(Z24, Z21, Z16, Z26)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z9, Z10, Z11, Z12, Z8, Z24)
+  SHA1_SCHEDULE(Z25, Z22, Z17, Z27)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z8, Z9, Z10, Z11, Z12, Z25)
+  SHA1_SCHEDULE(Z26, Z23, Z18, Z28)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z12, Z8, Z9, Z10, Z11, Z26)
+  SHA1_SCHEDULE(Z27, Z24, Z19, Z29)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z11, Z12, Z8, Z9, Z10, Z27)
+  SHA1_SCHEDULE(Z28, Z25, Z20, Z30)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z10, Z11, Z12, Z8, Z9, Z28)
+  SHA1_SCHEDULE(Z29, Z26, Z21, Z31)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z9, Z10, Z11, Z12, Z8, Z29)
+  SHA1_SCHEDULE(Z30, Z27, Z22, Z16)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z8, Z9, Z10, Z11, Z12, Z30)
+  SHA1_SCHEDULE(Z31, Z28, Z23, Z17)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z12, Z8, Z9, Z10, Z11, Z31)
+  SHA1_SCHEDULE(Z16, Z29, Z24, Z18)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z11, Z12, Z8, Z9, Z10, Z16)
+  SHA1_SCHEDULE(Z17, Z30, Z25, Z19)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z10, Z11, Z12, Z8, Z9, Z17)
+  SHA1_SCHEDULE(Z18, Z31, Z26, Z20)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z9, Z10, Z11, Z12, Z8, Z18)
+  SHA1_SCHEDULE(Z19, Z16, Z27, Z21)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z8, Z9, Z10, Z11, Z12, Z19)
+  SHA1_SCHEDULE(Z20, Z17, Z28, Z22)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z12, Z8, Z9, Z10, Z11, Z20)
+  SHA1_SCHEDULE(Z21, Z18, Z29, Z23)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z11, Z12, Z8, Z9, Z10, Z21)
+  SHA1_SCHEDULE(Z22, Z19, Z30, Z24)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z10, Z11, Z12, Z8, Z9, Z22)
+  SHA1_SCHEDULE(Z23, Z20, Z31, Z25)
+  SHA1_STEP($0x96, CONSTD_0x6ED9EBA1(), Z9, Z10, Z11, Z12, Z8, Z23)
+  SHA1_SCHEDULE(Z24, Z21, Z16, Z26)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z8, Z9, Z10, Z11, Z12, Z24)
+  SHA1_SCHEDULE(Z25, Z22, Z17, Z27)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z12, Z8, Z9, Z10, Z11, Z25)
+  SHA1_SCHEDULE(Z26, Z23, Z18, Z28)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z11, Z12, Z8, Z9, Z10, Z26)
+  SHA1_SCHEDULE(Z27, Z24, Z19, Z29)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z10, Z11, Z12, Z8, Z9, Z27)
+  SHA1_SCHEDULE(Z28, Z25, Z20, Z30)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z9, Z10, Z11, Z12, Z8, Z28)
+  SHA1_SCHEDULE(Z29, Z26, Z21, Z31)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z8, Z9, Z10, Z11, Z12, Z29)
+  SHA1_SCHEDULE(Z30, Z27, Z22, Z16)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z12, Z8, Z9, Z10, Z11, Z30)
+  SHA1_SCHEDULE(Z31, Z28, Z23, Z17)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z11, Z12, Z8, Z9, Z10, Z31)
+  SHA1_SCHEDULE(Z16, Z29, Z24, Z18)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z10, Z11, Z12, Z8, Z9, Z16)
+  SHA1_SCHEDULE(Z17, Z30, Z25, Z19)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z9, Z10, Z11, Z12, Z8, Z17)
+  SHA1_SCHEDULE(Z18, Z31, Z26, Z20)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z8, Z9, Z10, Z11, Z12, Z18)
+  SHA1_SCHEDULE(Z19, Z16, Z27, Z21)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z12, Z8, Z9, Z10, Z11, Z19)
+  SHA1_SCHEDULE(Z20, Z17, Z28, Z22)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z11, Z12, Z8, Z9, Z10, Z20)
+  SHA1_SCHEDULE(Z21, Z18, Z29, Z23)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z10, Z11, Z12, Z8, Z9, Z21)
+  SHA1_SCHEDULE(Z22, Z19, Z30, Z24)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z9, Z10, Z11, Z12, Z8, Z22)
+  SHA1_SCHEDULE(Z23, Z20, Z31, Z25)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z8, Z9, Z10, Z11, Z12, Z23)
+  SHA1_SCHEDULE(Z24, Z21, Z16, Z26)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z12, Z8, Z9, Z10, Z11, Z24)
+  SHA1_SCHEDULE(Z25, Z22, Z17, Z27)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z11, Z12, Z8, Z9, Z10, Z25)
+  SHA1_SCHEDULE(Z26, Z23, Z18, Z28)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z10, Z11, Z12, Z8, Z9, Z26)
+  SHA1_SCHEDULE(Z27, Z24, Z19, Z29)
+  SHA1_STEP($0xE8, CONSTD_0x8F1BBCDC(), Z9, Z10, Z11, Z12, Z8, Z27)
+  SHA1_SCHEDULE(Z28, Z25, Z20, Z30)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z8, Z9, Z10, Z11, Z12, Z28)
+  SHA1_SCHEDULE(Z29, Z26, Z21, Z31)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z12, Z8, Z9, Z10, Z11, Z29)
+  SHA1_SCHEDULE(Z30, Z27, Z22, Z16)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z11, Z12, Z8, Z9, Z10, Z30)
+  SHA1_SCHEDULE(Z31, Z28, Z23, Z17)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z10, Z11, Z12, Z8, Z9, Z31)
+  SHA1_SCHEDULE(Z16, Z29, Z24, Z18)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z9, Z10, Z11, Z12, Z8, Z16)
+  SHA1_SCHEDULE(Z17, Z30, Z25, Z19)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z8, Z9, Z10, Z11, Z12, Z17)
+  SHA1_SCHEDULE(Z18, Z31, Z26, Z20)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z12, Z8, Z9, Z10, Z11, Z18)
+  SHA1_SCHEDULE(Z19, Z16, Z27, Z21)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z11, Z12, Z8, Z9, Z10, Z19)
+  SHA1_SCHEDULE(Z20, Z17, Z28, Z22)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z10, Z11, Z12, Z8, Z9, Z20)
+  SHA1_SCHEDULE(Z21, Z18, Z29, Z23)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z9, Z10, Z11, Z12, Z8, Z21)
+  SHA1_SCHEDULE(Z22, Z19, Z30, Z24)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z8, Z9, Z10, Z11, Z12, Z22)
+  SHA1_SCHEDULE(Z23, Z20, Z31, Z25)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z12, Z8, Z9, Z10, Z11, Z23)
+  SHA1_SCHEDULE(Z24, Z21, Z16, Z26)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z11, Z12, Z8, Z9, Z10, Z24)
+  SHA1_SCHEDULE(Z25, Z22, Z17, Z27)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z10, Z11, Z12, Z8, Z9, Z25)
+  SHA1_SCHEDULE(Z26, Z23, Z18, Z28)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z9, Z10, Z11, Z12, Z8, Z26)
+  SHA1_SCHEDULE(Z27, Z24, Z19, Z29)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z8, Z9, Z10, Z11, Z12, Z27)
+  SHA1_SCHEDULE(Z28, Z25, Z20, Z30)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z12, Z8, Z9, Z10, Z11, Z28)
+  SHA1_SCHEDULE(Z29, Z26, Z21, Z31)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z11, Z12, Z8, Z9, Z10, Z29)
+  SHA1_SCHEDULE(Z30, Z27, Z22, Z16)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z10, Z11, Z12, Z8, Z9, Z30)
+  SHA1_SCHEDULE(Z31, Z28, Z23, Z17)
+  SHA1_STEP($0x96, CONSTD_0xCA62C1D6(), Z9, Z10, Z11, Z12, Z8, Z31)
+  HASH_UPDATE_STATE(0, Z8)
+  HASH_UPDATE_STATE(64, Z9)
+  HASH_UPDATE_STATE(128, Z10)
+  HASH_UPDATE_STATE(192, Z11)
+  HASH_UPDATE_STATE(256, Z12)
+  HASH_NEXT_BLOCK()
+  JMP block_loop
+
+
+blocks_done:
+  VMOVDQU32 0(R15), Z8
+  VMOVDQU32 64(R15), Z9
+  VMOVDQU32 128(R15), Z10
+  VMOVDQU32 192(R15), Z11
+  VMOVDQU32 256(R15), Z12
+  VPSHUFB Z1, Z8, Z8
+  VPSHUFB Z1, Z9, Z9
+  VPSHUFB Z1, Z10, Z10
+  VPSHUFB Z1, Z11, Z11
+  VPSHUFB Z1, Z12, Z12
+
+  HASH_HEX_PREPARE(CONSTD_5())
+  HASH_HEX_WORD(0, Z8, Y8)
+  HASH_HEX_WORD(8, Z9, Y9)
+  HASH_HEX_WORD(16, Z10, Y10)
+  HASH_HEX_WORD(24, Z11, Y11)
+  HASH_HEX_WORD(32, Z12, Y12)
+  HASH_EPILOGUE(CONSTD_40())
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+// s[0] = sha256str(slice[1]).k[2]
+//
+// scratch: 16 * 64
+TEXT bcsha256str(SB), NOSPLIT|NOFRAME, $0
+  HASH_PROLOGUE(16 * 64)
+  HASH_INIT_STATE(0, CONSTD_0x6A09E667())
+  HASH_INIT_STATE(64, CONSTD_0xBB67AE85())
+  HASH_INIT_STATE(128, CONSTD_0x3C6EF372())
+  HASH_INIT_STATE(192, CONSTD_0xA54FF53A())
+  HASH_INIT_STATE(256, CONSTD_0x510E527F())
+  HASH_INIT_STATE(320, CONSTD_0x9B05688C())
+  HASH_INIT_STATE(384, CONSTD_0x1F83D9AB())
+  HASH_INIT_STATE(448, CONSTD_0x5BE0CD19())
+  VBROADCASTI32X4 CONST_GET_PTR(bswap32, 0), Z1
+
+block_loop:
+  HASH_BEGIN_BLOCK(blocks_done)
+  HASH_LOAD_BLOCK()
+  HASH_BSWAP_BLOCK()
+
+  // the last block ends with the length in bits (big-endian)
+  HASH_LENGTH_MASK()
+  VMOVDQU32 BC_SPILL_AREA(64), K3, Z30
+  VMOVDQU32 BC_SPILL_AREA(0), K3, Z31
+
+  VMOVDQU32 0(R15), Z8
+  VMOVDQU32 64(R15), Z9
+  VMOVDQU32 128(R15), Z10
+  VMOVDQU32 192(R15), Z11
+  VMOVDQU32 256(R15), Z12
+  VMOVDQU32 320(R15), Z13
+  VMOVDQU32 384(R15), Z14
+  VMOVDQU32 448(R15), Z15
+
+  SHA256_STEP(0, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z16)
+  SHA256_STEP(1, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z17)
+  SHA256_STEP(2, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z18)
+  SHA256_STEP(3, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z19)
+  SHA256_STEP(4, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z20)
+  SHA256_STEP(5, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z21)
+  SHA256_STEP(6, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z22)
+  SHA256_STEP(7, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z23)
+  SHA256_STEP(8, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z24)
+  SHA256_STEP(9, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z25)
+  SHA256_STEP(10, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z26)
+  SHA256_STEP(11, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z27)
+  SHA256_STEP(12, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z28)
+  SHA256_STEP(13, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z29)
+  SHA256_STEP(14, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z30)
+  SHA256_STEP(15, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z31)
+  SHA256_SCHEDULE(Z16, Z30, Z25, Z17)
+  SHA256_STEP(16, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z16)
+  SHA256_SCHEDULE(Z17, Z31, Z26, Z18)
+  SHA256_STEP(17, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z17)
+  SHA256_SCHEDULE(Z18, Z16, Z27, Z19)
+  SHA256_STEP(18, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z18)
+  SHA256_SCHEDULE(Z19, Z17, Z28, Z20)
+  SHA256_STEP(19, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z19)
+  SHA256_SCHEDULE(Z20, Z18, Z29, Z21)
+  SHA256_STEP(20, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z20)
+  SHA256_SCHEDULE(Z21, Z19, Z30, Z22)
+  SHA256_STEP(21, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z21)
+  SHA256_SCHEDULE(Z22, Z20, Z31, Z23)
+  SHA256_STEP(22, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z22)
+  SHA256_SCHEDULE(Z23, Z21, Z16, Z24)
+  SHA256_STEP(23, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z23)
+  SHA256_SCHEDULE(Z24, Z22, Z17, Z25)
+  SHA256_STEP(24, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z24)
+  SHA256_SCHEDULE(Z25, Z23, Z18, Z26)
+  SHA256_STEP(25, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z25)
+  SHA256_SCHEDULE(Z26, Z24, Z19, Z27)
+  SHA256_STEP(26, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z26)
+  SHA256_SCHEDULE(Z27, Z25, Z20, Z28)
+  SHA256_STEP(27, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z27)
+  SHA256_SCHEDULE(Z28, Z26, Z21, Z29)
+  SHA256_STEP(28, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z28)
+  SHA256_SCHEDULE(Z29, Z27, Z22, Z30)
+  SHA256_STEP(29, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z29)
+  SHA256_SCHEDULE(Z30, Z28, Z23, Z31)
+  SHA256_STEP(30, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z30)
+  SHA256_SCHEDULE(Z31, Z29, Z24, Z16)
+  SHA256_STEP(31, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z31)
+  SHA256_SCHEDULE(Z16, Z30, Z25, Z17)
+  SHA256_STEP(32, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z16)
+  SHA256_SCHEDULE(Z17, Z31, Z26, Z18)
+  SHA256_STEP(33, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z17)
+  SHA256_SCHEDULE(Z18, Z16, Z27, Z19)
+  SHA256_STEP(34, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z18)
+  SHA256_SCHEDULE(Z19, Z17, Z28, Z20)
+  SHA256_STEP(35, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z19)
+  SHA256_SCHEDULE(Z20, Z18, Z29, Z21)
+  SHA256_STEP(36, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z20)
+  SHA256_SCHEDULE(Z21, Z19, Z30, Z22)
+  SHA256_STEP(37, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z21)
+  SHA256_SCHEDULE(Z22, Z20, Z31, Z23)
+  SHA256_STEP(38, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z22)
+  SHA256_SCHEDULE(Z23, Z21, Z16, Z24)
+  SHA256_STEP(39, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z23)
+  SHA256_SCHEDULE(Z24, Z22, Z17, Z25)
+  SHA256_STEP(40, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z24)
+  SHA256_SCHEDULE(Z25, Z23, Z18, Z26)
+  SHA256_STEP(41, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z25)
+  SHA256_SCHEDULE(Z26, Z24, Z19, Z27)
+  SHA256_STEP(42, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z26)
+  SHA256_SCHEDULE(Z27, Z25, Z20, Z28)
+  SHA256_STEP(43, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z27)
+  SHA256_SCHEDULE(Z28, Z26, Z21, Z29)
+  SHA256_STEP(44, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z28)
+  SHA256_SCHEDULE(Z29, Z27, Z22, Z30)
+  SHA256_STEP(45, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z29)
+  SHA256_SCHEDULE(Z30, Z28, Z23, Z31)
+  SHA256_STEP(46, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z30)
+  SHA256_SCHEDULE(Z31, Z29, Z24, Z16)
+  SHA256_STEP(47, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z31)
+  SHA256_SCHEDULE(Z16, Z30, Z25, Z17)
+  SHA256_STEP(48, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z16)
+  SHA256_SCHEDULE(Z17, Z31, Z26, Z18)
+  SHA256_STEP(49, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z17)
+  SHA256_SCHEDULE(Z18, Z16, Z27, Z19)
+  SHA256_STEP(50, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z18)
+  SHA256_SCHEDULE(Z19, Z17, Z28, Z20)
+  SHA256_STEP(51, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z19)
+  SHA256_SCHEDULE(Z20, Z18, Z29, Z21)
+  SHA256_STEP(52, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z20)
+  SHA256_SCHEDULE(Z21, Z19, Z30, Z22)
+  SHA256_STEP(53, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z21)
+  SHA256_SCHEDULE(Z22, Z20, Z31, Z23)
+  SHA256_STEP(54, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z22)
+  SHA256_SCHEDULE(Z23, Z21, Z16, Z24)
+  SHA256_STEP(55, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z23)
+  SHA256_SCHEDULE(Z24, Z22, Z17, Z25)
+  SHA256_STEP(56, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z24)
+  SHA256_SCHEDULE(Z25, Z23, Z18, Z26)
+  SHA256_STEP(57, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z14, Z25)
+  SHA256_SCHEDULE(Z26, Z24, Z19, Z27)
+  SHA256_STEP(58, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z13, Z26)
+  SHA256_SCHEDULE(Z27, Z25, Z20, Z28)
+  SHA256_STEP(59, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z12, Z27)
+  SHA256_SCHEDULE(Z28, Z26, Z21, Z29)
+  SHA256_STEP(60, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z11, Z28)
+  SHA256_SCHEDULE(Z29, Z27, Z22, Z30)
+  SHA256_STEP(61, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z10, Z29)
+  SHA256_SCHEDULE(Z30, Z28, Z23, Z31)
+  SHA256_STEP(62, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z9, Z30)
+  SHA256_SCHEDULE(Z31, Z29, Z24, Z16)
+  SHA256_STEP(63, Z9, Z10, Z11, Z12, Z13, Z14, Z15, Z8, Z31)
+  HASH_UPDATE_STATE(0, Z8)
+  HASH_UPDATE_STATE(64, Z9)
+  HASH_UPDATE_STATE(128, Z10)
+  HASH_UPDATE_STATE(192, Z11)
+  HASH_UPDATE_STATE(256, Z12)
+  HASH_UPDATE_STATE(320, Z13)
+  HASH_UPDATE_STATE(384, Z14)
+  HASH_UPDATE_STATE(448, Z15)
+  HASH_NEXT_BLOCK()
+  JMP block_loop
+
+blocks_done:
+  VMOVDQU32 0(R15), Z8
+  VMOVDQU32 64(R15), Z9
+  VMOVDQU32 128(R15), Z10
+  VMOVDQU32 192(R15), Z11
+  VMOVDQU32 256(R15), Z12
+  VMOVDQU32 320(R15), Z13
+  VMOVDQU32 384(R15), Z14
+  VMOVDQU32 448(R15), Z15
+  VPSHUFB Z1, Z8, Z8
+  VPSHUFB Z1, Z9, Z9
+  VPSHUFB Z1, Z10, Z10
+  VPSHUFB Z1, Z11, Z11
+  VPSHUFB Z1, Z12, Z12
+  VPSHUFB Z1, Z13, Z13
+  VPSHUFB Z1, Z14, Z14
+  VPSHUFB Z1, Z15, Z15
+
+  HASH_HEX_PREPARE(CONSTD_8())
+  HASH_HEX_WORD(0, Z8, Y8)
+  HASH_HEX_WORD(8, Z9, Y9)
+  HASH_HEX_WORD(16, Z10, Y10)
+  HASH_HEX_WORD(24, Z11, Y11)
+  HASH_HEX_WORD(32, Z12, Y12)
+  HASH_HEX_WORD(40, Z13, Y13)
+  HASH_HEX_WORD(48, Z14, Y14)
+  HASH_HEX_WORD(56, Z15, Y15)
+  HASH_EPILOGUE(CONSTD_64())
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+CONST_DATA_U32(md5_consts, 0, $0xd76aa478)
+CONST_DATA_U32(md5_consts, 4, $0xe8c7b756)
+CONST_DATA_U32(md5_consts, 8, $0x242070db)
+CONST_DATA_U32(md5_consts, 12, $0xc1bdceee)
+CONST_DATA_U32(md5_consts, 16, $0xf57c0faf)
+CONST_DATA_U32(md5_consts, 20, $0x4787c62a)
+CONST_DATA_U32(md5_consts, 24, $0xa8304613)
+CONST_DATA_U32(md5_consts, 28, $0xfd469501)
+CONST_DATA_U32(md5_consts, 32, $0x698098d8)
+CONST_DATA_U32(md5_consts, 36, $0x8b44f7af)
+CONST_DATA_U32(md5_consts, 40, $0xffff5bb1)
+CONST_DATA_U32(md5_consts, 44, $0x895cd7be)
+CONST_DATA_U32(md5_consts, 48, $0x6b901122)
+CONST_DATA_U32(md5_consts, 52, $0xfd987193)
+CONST_DATA_U32(md5_consts, 56, $0xa679438e)
+CONST_DATA_U32(md5_consts, 60, $0x49b40821)
+CONST_DATA_U32(md5_consts, 64, $0xf61e2562)
+CONST_DATA_U32(md5_consts, 68, $0xc040b340)
+CONST_DATA_U32(md5_consts, 72, $0x265e5a51)
+CONST_DATA_U32(md5_consts, 76, $0xe9b6c7aa)
+CONST_DATA_U32(md5_consts, 80, $0xd62f105d)
+CONST_DATA_U32(md5_consts, 84, $0x02441453)
+CONST_DATA_U32(md5_consts, 88, $0xd8a1e681)
+CONST_DATA_U32(md5_consts, 92, $0xe7d3fbc8)
+CONST_DATA_U32(md5_consts, 96, $0x21e1cde6)
+CONST_DATA_U32(md5_consts, 100, $0xc33707d6)
+CONST_DATA_U32(md5_consts, 104, $0xf4d50d87)
+CONST_DATA_U32(md5_consts, 108, $0x455a14ed)
+CONST_DATA_U32(md5_consts, 112, $0xa9e3e905)
+CONST_DATA_U32(md5_consts, 116, $0xfcefa3f8)
+CONST_DATA_U32(md5_consts, 120, $0x676f02d9)
+CONST_DATA_U32(md5_consts, 124, $0x8d2a4c8a)
+CONST_DATA_U32(md5_consts, 128, $0xfffa3942)
+CONST_DATA_U32(md5_consts, 132, $0x8771f681)
+CONST_DATA_U32(md5_consts, 136, $0x6d9d6122)
+CONST_DATA_U32(md5_consts, 140, $0xfde5380c)
+CONST_DATA_U32(md5_consts, 144, $0xa4beea44)
+CONST_DATA_U32(md5_consts, 148, $0x4bdecfa9)
+CONST_DATA_U32(md5_consts, 152, $0xf6bb4b60)
+CONST_DATA_U32(md5_consts, 156, $0xbebfbc70)
+CONST_DATA_U32(md5_consts, 160, $0x289b7ec6)
+CONST_DATA_U32(md5_consts, 164, $0xeaa127fa)
+CONST_DATA_U32(md5_consts, 168, $0xd4ef3085)
+CONST_DATA_U32(md5_consts, 172, $0x04881d05)
+CONST_DATA_U32(md5_consts, 176, $0xd9d4d039)
+CONST_DATA_U32(md5_consts, 180, $0xe6db99e5)
+CONST_DATA_U32(md5_consts, 184, $0x1fa27cf8)
+CONST_DATA_U32(md5_consts, 188, $0xc4ac5665)
+CONST_DATA_U32(md5_consts, 192, $0xf4292244)
+CONST_DATA_U32(md5_consts, 196, $0x432aff97)
+CONST_DATA_U32(md5_consts, 200, $0xab9423a7)
+CONST_DATA_U32(md5_consts, 204, $0xfc93a039)
+CONST_DATA_U32(md5_consts, 208, $0x655b59c3)
+CONST_DATA_U32(md5_consts, 212, $0x8f0ccc92)
+CONST_DATA_U32(md5_consts, 216, $0xffeff47d)
+CONST_DATA_U32(md5_consts, 220, $0x85845dd1)
+CONST_DATA_U32(md5_consts, 224, $0x6fa87e4f)
+CONST_DATA_U32(md5_consts, 228, $0xfe2ce6e0)
+CONST_DATA_U32(md5_consts, 232, $0xa3014314)
+CONST_DATA_U32(md5_consts, 236, $0x4e0811a1)
+CONST_DATA_U32(md5_consts, 240, $0xf7537e82)
+CONST_DATA_U32(md5_consts, 244, $0xbd3af235)
+CONST_DATA_U32(md5_consts, 248, $0x2ad7d2bb)
+CONST_DATA_U32(md5_consts, 252, $0xeb86d391)
+CONST_GLOBAL(md5_consts, $256)
+
+CONST_DATA_U32(sha256_consts, 0, $0x428a2f98)
+CONST_DATA_U32(sha256_consts, 4, $0x71374491)
+CONST_DATA_U32(sha256_consts, 8, $0xb5c0fbcf)
+CONST_DATA_U32(sha256_consts, 12, $0xe9b5dba5)
+CONST_DATA_U32(sha256_consts, 16, $0x3956c25b)
+CONST_DATA_U32(sha256_consts, 20, $0x59f111f1)
+CONST_DATA_U32(sha256_consts, 24, $0x923f82a4)
+CONST_DATA_U32(sha256_consts, 28, $0xab1c5ed5)
+CONST_DATA_U32(sha256_consts, 32, $0xd807aa98)
+CONST_DATA_U32(sha256_consts, 36, $0x12835b01)
+CONST_DATA_U32(sha256_consts, 40, $0x243185be)
+CONST_DATA_U32(sha256_consts, 44, $0x550c7dc3)
+CONST_DATA_U32(sha256_consts, 48, $0x72be5d74)
+CONST_DATA_U32(sha256_consts, 52, $0x80deb1fe)
+CONST_DATA_U32(sha256_consts, 56, $0x9bdc06a7)
+CONST_DATA_U32(sha256_consts, 60, $0xc19bf174)
+CONST_DATA_U32(sha256_consts, 64, $0xe49b69c1)
+CONST_DATA_U32(sha256_consts, 68, $0xefbe4786)
+CONST_DATA_U32(sha256_consts, 72, $0x0fc19dc6)
+CONST_DATA_U32(sha256_consts, 76, $0x240ca1cc)
+CONST_DATA_U32(sha256_consts, 80, $0x2de92c6f)
+CONST_DATA_U32(sha256_consts, 84, $0x4a7484aa)
+CONST_DATA_U32(sha256_consts, 88, $0x5cb0a9dc)
+CONST_DATA_U32(sha256_consts, 92, $0x76f988da)
+CONST_DATA_U32(sha256_consts, 96, $0x983e5152)
+CONST_DATA_U32(sha256_consts, 100, $0xa831c66d)
+CONST_DATA_U32(sha256_consts, 104, $0xb00327c8)
+CONST_DATA_U32(sha256_consts, 108, $0xbf597fc7)
+CONST_DATA_U32(sha256_consts, 112, $0xc6e00bf3)
+CONST_DATA_U32(sha256_consts, 116, $0xd5a79147)
+CONST_DATA_U32(sha256_consts, 120, $0x06ca6351)
+CONST_DATA_U32(sha256_consts, 124, $0x14292967)
+CONST_DATA_U32(sha256_consts, 128, $0x27b70a85)
+CONST_DATA_U32(sha256_consts, 132, $0x2e1b2138)
+CONST_DATA_U32(sha256_consts, 136, $0x4d2c6dfc)
+CONST_DATA_U32(sha256_consts, 140, $0x53380d13)
+CONST_DATA_U32(sha256_consts, 144, $0x650a7354)
+CONST_DATA_U32(sha256_consts, 148, $0x766a0abb)
+CONST_DATA_U32(sha256_consts, 152, $0x81c2c92e)
+CONST_DATA_U32(sha256_consts, 156, $0x92722c85)
+CONST_DATA_U32(sha256_consts, 160, $0xa2bfe8a1)
+CONST_DATA_U32(sha256_consts, 164, $0xa81a664b)
+CONST_DATA_U32(sha256_consts, 168, $0xc24b8b70)
+CONST_DATA_U32(sha256_consts, 172, $0xc76c51a3)
+CONST_DATA_U32(sha256_consts, 176, $0xd192e819)
+CONST_DATA_U32(sha256_consts, 180, $0xd6990624)
+CONST_DATA_U32(sha256_consts, 184, $0xf40e3585)
+CONST_DATA_U32(sha256_consts, 188, $0x106aa070)
+CONST_DATA_U32(sha256_consts, 192, $0x19a4c116)
+CONST_DATA_U32(sha256_consts, 196, $0x1e376c08)
+CONST_DATA_U32(sha256_consts, 200, $0x2748774c)
+CONST_DATA_U32(sha256_consts, 204, $0x34b0bcb5)
+CONST_DATA_U32(sha256_consts, 208, $0x391c0cb3)
+CONST_DATA_U32(sha256_consts, 212, $0x4ed8aa4a)
+CONST_DATA_U32(sha256_consts, 216, $0x5b9cca4f)
+CONST_DATA_U32(sha256_consts, 220, $0x682e6ff3)
+CONST_DATA_U32(sha256_consts, 224, $0x748f82ee)
+CONST_DATA_U32(sha256_consts, 228, $0x78a5636f)
+CONST_DATA_U32(sha256_consts, 232, $0x84c87814)
+CONST_DATA_U32(sha256_consts, 236, $0x8cc70208)
+CONST_DATA_U32(sha256_consts, 240, $0x90befffa)
+CONST_DATA_U32(sha256_consts, 244, $0xa4506ceb)
+CONST_DATA_U32(sha256_consts, 248, $0xbef9a3f7)
+CONST_DATA_U32(sha256_consts, 252, $0xc67178f2)
+CONST_GLOBAL(sha256_consts, $256)
+
+CONST_DATA_U64(hash_hex_spread, 0, $0x0303020201010000)
+CONST_DATA_U64(hash_hex_spread, 8, $0x0B0B0A0A09090808)
+CONST_GLOBAL(hash_hex_spread, $16)
+
+CONST_DATA_U64(hash_hex_chars, 0, $0x3736353433323130) // "01234567"
+CONST_DATA_U64(hash_hex_chars, 8, $0x6665646362613938) // "89abcdef"
+CONST_GLOBAL(hash_hex_chars, $16)
+
+// XXHASH64 function
+// --------------------------------------------------
+
+// i64[0] = xxhash64str(slice[1]).k[2]
+TEXT bcxxhash64str(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*1, OUT(BX), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z28), OUT(Z29), IN(BX), IN(K1))
+
+  KMOVW         K1, K6           // save current predicate
+  MOVQ          VIRT_BASE, R15
+  VMOVDQA32     Y28, Y10         // Y10 = lo 8 offsets
+  VMOVDQA32     Y29, Y11         // Y11 = lo 8 lengths
+  CALL          xxhash64x8(SB)   // eval first 8
+  VMOVDQA64     Z9, Z26
+  VEXTRACTI32X8 $1, Z28, Y10     // Y10 = hi 8 offsets
+  VEXTRACTI32X8 $1, Z29, Y11     // Y11 = hi 8 lengths
+  KSHIFTRW      $8, K6, K1       // shift lanes
+  CALL          xxhash64x8(SB)   // eval second 8
+  KMOVW         K6, K1           // restore original lanes
+  KSHIFTRW      $8, K1, K2
+
+  BC_UNPACK_SLOT(0, OUT(DX))
+  VMOVDQA64.Z   Z26, K1, Z26
+  VMOVDQA64.Z   Z9, K2, Z9
+  BC_STORE_I64_TO_SLOT(IN(Z26), IN(Z9), IN(DX))
+  NEXT_ADVANCE(BC_SLOT_SIZE*3)
+
+// one XXH64 round of 8 lanes: acc = rotl(acc + input * prime2, 31) * prime1
+#define XXH64_ROUND(mask, acc, input) \
+  VPMULLQ Z21, input, input           \
+  VPADDQ  input, acc, mask, acc       \
+  VPROLQ  $31, acc, mask, acc         \
+  VPMULLQ Z20, acc, mask, acc
+
+#define XXH64_MERGE_ROUND(acc, val) \
+  VPMULLQ Z21, val, val             \
+  VPROLQ  $31, val, val             \
+  VPMULLQ Z20, val, val             \
+  VPXORQ  val, acc, acc             \
+  VPMULLQ Z20, acc, acc             \
+  VPADDQ  Z23, acc, acc
+
+// inputs: K1 = active, R15 = base, Y10:Y11 = offset:length
+// outputs: Z9 = 64-bit hash x 8
+// clobbers: K1-K3, Z9-Z24
+//
+// the result must match internal/xxhash.Sum64()
+TEXT xxhash64x8(SB), NOFRAME|NOSPLIT, $0
+  VPBROADCASTQ  CONST_GET_PTR(xxhash64_primes, 0), Z20  // Z20 = prime1
+  VPBROADCASTQ  CONST_GET_PTR(xxhash64_primes, 8), Z21  // Z21 = prime2
+  VPBROADCASTQ  CONST_GET_PTR(xxhash64_primes, 16), Z22 // Z22 = prime3
+  VPBROADCASTQ  CONST_GET_PTR(xxhash64_primes, 24), Z23 // Z23 = prime4
+  VPBROADCASTQ  CONST_GET_PTR(xxhash64_primes, 32), Z24 // Z24 = prime5
+  VPMOVZXDQ     Y11, Z19         // Z19 = input length
+  VPADDQ        Z20, Z21, Z13    // v1 = prime1 + prime2
+  VMOVDQA64     Z21, Z14         // v2 = prime2
+  VPXORQ        Z15, Z15, Z15    // v3 = 0
+  VPSUBQ        Z20, Z15, Z16    // v4 = -prime1
+  JMP           stripe_loop_tail
+
+stripe_loop:
+  KMOVB         K2, K3
+  VPGATHERDQ    0(R15)(Y10*1), K3, Z17
+  XXH64_ROUND(K2, Z13, Z17)
+  KMOVB         K2, K3
+  VPGATHERDQ    8(R15)(Y10*1), K3, Z17
+  XXH64_ROUND(K2, Z14, Z17)
+  KMOVB         K2, K3
+  VPGATHERDQ    16(R15)(Y10*1), K3, Z17
+  XXH64_ROUND(K2, Z15, Z17)
+  KMOVB         K2, K3
+  VPGATHERDQ    24(R15)(Y10*1), K3, Z17
+  XXH64_ROUND(K2, Z16, Z17)
+  VPADDD.BCST   CONSTD_32(), Y10, K2, Y10 // offset += 32
+  VPSUBD.BCST   CONSTD_32(), Y11, K2, Y11 // len -= 32
+stripe_loop_tail:
+  VPCMPUD.BCST  $VPCMP_IMM_GE, CONSTD_32(), Y11, K1, K2
+  KTESTB        K2, K2           // K2 = lanes where len(input)>=32
+  JNZ           stripe_loop
+
+  // h = len(input) >= 32 ? merge(v1, v2, v3, v4) : prime5
+  VMOVDQA64     Z24, Z9
+  VPCMPUQ.BCST  $VPCMP_IMM_GE, CONSTQ_32(), Z19, K1, K2
+  KTESTB        K2, K2
+  JZ            skip_merge
+  VPROLQ        $1, Z13, Z17
+  VPROLQ        $7, Z14, Z18
+  VPADDQ        Z18, Z17, Z17
+  VPROLQ        $12, Z15, Z18
+  VPADDQ        Z18, Z17, Z17
+  VPROLQ        $18, Z16, Z18
+  VPADDQ        Z18, Z17, Z17
+  XXH64_MERGE_ROUND(Z17, Z13)
+  XXH64_MERGE_ROUND(Z17, Z14)
+  XXH64_MERGE_ROUND(Z17, Z15)
+  XXH64_MERGE_ROUND(Z17, Z16)
+  VMOVDQA64     Z17, K2, Z9
+skip_merge:
+  VPADDQ        Z19, Z9, Z9      // h += len(input)
+  JMP           tail8_loop_tail
+
+tail8_loop:
+  KMOVB         K2, K3
+  VPGATHERDQ    0(R15)(Y10*1), K3, Z17
+  VPMULLQ       Z21, Z17, Z17
+  VPROLQ        $31, Z17, Z17
+  VPMULLQ       Z20, Z17, Z17
+  VPXORQ        Z17, Z9, K2, Z9  // h ^= round(0, load64(ptr))
+  VPROLQ        $27, Z9, K2, Z9
+  VPMULLQ       Z20, Z9, K2, Z9
+  VPADDQ        Z23, Z9, K2, Z9  // h = rotl(h, 27) * prime1 + prime4
+  VPADDD.BCST   CONSTD_8(), Y10, K2, Y10
+  VPSUBD.BCST   CONSTD_8(), Y11, K2, Y11
+tail8_loop_tail:
+  VPCMPUD.BCST  $VPCMP_IMM_GE, CONSTD_8(), Y11, K1, K2
+  KTESTB        K2, K2           // K2 = lanes where len(input)>=8
+  JNZ           tail8_loop
+
+  VPCMPUD.BCST  $VPCMP_IMM_GE, CONSTD_4(), Y11, K1, K2
+  KTESTB        K2, K2           // K2 = lanes where len(input)>=4
+  JZ            tail1_loop_tail
+  KMOVB         K2, K3
+  VPXORD        Y17, Y17, Y17
+  VPGATHERDD    0(R15)(Y10*1), K3, Y17
+  VPMOVZXDQ     Y17, Z17
+  VPMULLQ       Z20, Z17, Z17
+  VPXORQ        Z17, Z9, K2, Z9  // h ^= load32(ptr) * prime1
+  VPROLQ        $23, Z9, K2, Z9
+  VPMULLQ       Z21, Z9, K2, Z9
+  VPADDQ        Z22, Z9, K2, Z9  // h = rotl(h, 23) * prime2 + prime3
+  VPADDD.BCST   CONSTD_4(), Y10, K2, Y10
+  VPSUBD.BCST   CONSTD_4(), Y11, K2, Y11
+  JMP           tail1_loop_tail
+
+tail1_loop:
+  KMOVB         K2, K3
+  VPGATHERDD    0(R15)(Y10*1), K3, Y17
+  VPANDD.BCST   CONSTD_0xFF(), Y17, Y17
+  VPMOVZXDQ     Y17, Z17
+  VPMULLQ       Z24, Z17, Z17
+  VPXORQ        Z17, Z9, K2, Z9  // h ^= load8(ptr) * prime5
+  VPROLQ        $11, Z9, K2, Z9
+  VPMULLQ       Z20, Z9, K2, Z9  // h = rotl(h, 11) * prime1
+  VPADDD.BCST   CONSTD_1(), Y10, K2, Y10
+  VPSUBD.BCST   CONSTD_1(), Y11, K2, Y11
+tail1_loop_tail:
+  VPTESTMD      Y11, Y11, K1, K2
+  KTESTB        K2, K2           // K2 = lanes where len(input)>0
+  JNZ           tail1_loop
+
+  // final avalanche
+  VPSRLQ        $33, Z9, Z17
+  VPXORQ        Z17, Z9, Z9
+  VPMULLQ       Z21, Z9, Z9
+  VPSRLQ        $29, Z9, Z17
+  VPXORQ        Z17, Z9, Z9
+  VPMULLQ       Z22, Z9, Z9
+  VPSRLQ        $32, Z9, Z17
+  VPXORQ        Z17, Z9, Z9
+  RET
+
+CONST_DATA_U64(xxhash64_primes, 0, $0x9E3779B185EBCA87)
+CONST_DATA_U64(xxhash64_primes, 8, $0xC2B2AE3D27D4EB4F)
+CONST_DATA_U64(xxhash64_primes, 16, $0x165667B19E3779F9)
+CONST_DATA_U64(xxhash64_primes, 24, $0x85EBCA77C2B2AE63)
+CONST_DATA_U64(xxhash64_primes, 32, $0x27D4EB2F165667C5)
+CONST_GLOBAL(xxhash64_primes, $40)
+
+// FINGERPRINT function
+// --------------------------------------------------
+
+// i64[0] = hashtoi64(h[1]).k[2]
+//
+// hashtoi64 extracts the low 64 bits of the hash of each lane
+TEXT bchashtoi64(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*1, OUT(BX), OUT(R8))
+  BC_LOAD_K1_K2_FROM_SLOT(OUT(K1), OUT(K2), IN(R8))
+
+  VMOVDQU64.Z 0(VIRT_VALUES)(BX*1), K1, Z2
+  VMOVDQU64.Z 64(VIRT_VALUES)(BX*1), K2, Z3
+
+  BC_UNPACK_SLOT(0, OUT(DX))
+  BC_STORE_I64_TO_SLOT(IN(Z2), IN(Z3), IN(DX))
+  NEXT_ADVANCE(BC_SLOT_SIZE*3)
+
+// TO_HEX/FROM_HEX functions
+// --------------------------------------------------
+
+// TO_HEX_BYTES converts 32 bytes (zero-extended to 16-bit units) to 64 hex characters;
+// expects Z10 to contain the hex alphabet and Z11 to contain 0x0F00 in each word.
+#define TO_HEX_BYTES(DataZ, TmpZ)                                              \
+  VPSRLW $4, DataZ, TmpZ                 /* TmpZ <- high nibbles (first char) */ \
+  VPSLLW $8, DataZ, DataZ                                                      \
+  VPTERNLOGD $0xEA, TmpZ, Z11, DataZ     /* DataZ <- low nibbles (second char) | TmpZ */ \
+  VPSHUFB DataZ, Z10, DataZ
+
+// s[0].k[1] = tohexstr(slice[2]).k[3]
+//
+// scratch: PageSize
+TEXT bctohexstr(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+  VPADDD Z3, Z3, Z4                                    // Z4 <- output lengths
+
+  // R15 (DstSum), Z5 (DstOff), Z7 (DstLen), Z6 (DstEnd), K2 (DstMask)
+  BC_HORIZONTAL_LENGTH_SUM(OUT(R15), OUT(Z5), OUT(Z7), OUT(Z6), OUT(K2), IN(Z4), IN(K1), X8, K3)
+
+  BC_ALLOC_SLICE(OUT(Z8), IN(R15), CX, R8)             // Z8 <- Offset of the beginning of the allocated buffer
+  VPADDD.Z Z5, Z8, K2, Z8                              // Z8 <- Offsets of each output string
+
+  VMOVDQU32 Z8, BC_SPILL_AREA(0)                       // [] <- output offsets
+  VMOVDQU32 Z2, BC_SPILL_AREA(64)                      // [] <- input offsets
+  VMOVDQU32 Z3, BC_SPILL_AREA(128)                     // [] <- input lengths
+
+  VBROADCASTI32X4 CONST_GET_PTR(hash_hex_chars, 0), Z10
+  VPBROADCASTD CONSTD_0x0F000F00(), Z11
+
+  KMOVW K2, R8
+  TESTL R8, R8
+  JZ done
+
+lane_loop:
+  TZCNTL R8, BX                                        // BX <- Index of the lane to process
+  BLSRL R8, R8                                         // R8 <- Clear the index of the iterator
+
+  MOVL BC_SPILL_AREA_INDEX(128, BX*4), CX              // CX <- Input length
+  MOVL BC_SPILL_AREA_INDEX(0, BX*4), R15               // R15 <- Output index
+  MOVL BC_SPILL_AREA_INDEX(64, BX*4), R14              // R14 <- Input index
+  ADDQ VIRT_BASE, R15
+  ADDQ VIRT_BASE, R14
+
+  SUBL $32, CX
+  JCS lane_tail
+
+  // Main loop that encodes 32 bytes at once
+lane_32b_iter:
+  VPMOVZXBW 0(R14), Z12
+  TO_HEX_BYTES(Z12, Z13)
+  VMOVDQU8 Z12, 0(R15)
+  ADDQ $32, R14
+  ADDQ $64, R15
+
+  SUBL $32, CX
+  JCC lane_32b_iter
+
+lane_tail:
+  ADDL $32, CX                                         // CX <- Remaining bytes [0, 31]
+  JZ lane_next
+
+  MOVQ $-1, DX
+  SHLQ CL, DX
+  NOTQ DX
+  KMOVD DX, K3                                         // K3 <- input bytes
+
+  ADDL CX, CX
+  MOVQ $-1, DX
+  SHLQ CL, DX
+  NOTQ DX
+  KMOVQ DX, K4                                         // K4 <- output bytes
+
+  VMOVDQU8.Z 0(R14), K3, Y12
+  VPMOVZXBW Y12, Z12
+  TO_HEX_BYTES(Z12, Z13)
+  VMOVDQU8 Z12, K4, 0(R15)
+
+lane_next:
+  TESTL R8, R8
+  JNE lane_loop
+
+done:
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z8), IN(Z7), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K2), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4)
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+// VALIDATE_UTF8 jumps to Invalid unless the Len bytes at Ptr are
+// well-formed UTF-8 (no overlong encodings, surrogates or code points
+// above U+10FFFF); clobbers Ptr, Len, Byte and Count.
+#define VALIDATE_UTF8(Ptr, Len, Byte, Count, Invalid)                          \
+utf8_loop:                                                                     \
+  TESTL Len, Len                                                               \
+  JZ utf8_done                                                                 \
+  MOVBLZX 0(Ptr), Byte                                                         \
+  INCQ Ptr                                                                     \
+  DECL Len                                                                     \
+  CMPL Byte, $0x80                                                             \
+  JB utf8_loop                           /* ASCII */                           \
+  CMPL Byte, $0xC2                                                             \
+  JB Invalid                             /* continuation or overlong lead */   \
+  MOVL $1, Count                         /* Count <- continuation bytes */     \
+  CMPL Byte, $0xE0                                                             \
+  JB utf8_length                                                               \
+  INCL Count                                                                   \
+  CMPL Byte, $0xF0                                                             \
+  JB utf8_length                                                               \
+  INCL Count                                                                   \
+  CMPL Byte, $0xF4                                                             \
+  JA Invalid                             /* above U+10FFFF */                  \
+utf8_length:                                                                   \
+  CMPL Len, Count                                                              \
+  JB Invalid                             /* truncated sequence */              \
+  SUBL Count, Len                                                              \
+  CMPL Byte, $0xE0                                                             \
+  JE utf8_e0                                                                   \
+  CMPL Byte, $0xED                                                             \
+  JE utf8_ed                                                                   \
+  CMPL Byte, $0xF0                                                             \
+  JE utf8_f0                                                                   \
+  CMPL Byte, $0xF4                                                             \
+  JE utf8_f4                                                                   \
+  JMP utf8_cont                                                                \
+utf8_e0:                                                                       \
+  CMPB 0(Ptr), $0xA0                                                           \
+  JB Invalid                             /* overlong 3-byte sequence */        \
+  JMP utf8_cont                                                                \
+utf8_ed:                                                                       \
+  CMPB 0(Ptr), $0xA0                                                           \
+  JAE Invalid                            /* surrogate */                       \
+  JMP utf8_cont                                                                \
+utf8_f0:                                                                       \
+  CMPB 0(Ptr), $0x90                                                           \
+  JB Invalid                             /* overlong 4-byte sequence */        \
+  JMP utf8_cont                                                                \
+utf8_f4:                                                                       \
+  CMPB 0(Ptr), $0x90                                                           \
+  JAE Invalid                            /* above U+10FFFF */                  \
+utf8_cont:                                                                     \
+  MOVBLZX 0(Ptr), Byte                                                         \
+  ANDL $0xC0, Byte                                                             \
+  CMPL Byte, $0x80                                                             \
+  JNE Invalid                            /* not a continuation byte */         \
+  INCQ Ptr                                                                     \
+  DECL Count                                                                   \
+  JNZ utf8_cont                                                                \
+  JMP utf8_loop                                                                \
+utf8_done:
+
+// FROM_HEX_CHARS converts 64 hex characters to 32 bytes stored in 16-bit units
+// and sets K4 to the characters that are valid; clobbers K5.
+#define FROM_HEX_CHARS(DataZ, TmpZ)                                            \
+  VPSUBB Z10, DataZ, TmpZ                /* TmpZ <- c - '0' */                  \
+  VPCMPUB $VPCMP_IMM_LT, Z13, TmpZ, K4   /* K4 <- decimal digits */             \
+  VPORD Z11, DataZ, DataZ                                                      \
+  VPSUBB Z12, DataZ, DataZ               /* DataZ <- (c | 0x20) - 'a' */        \
+  VPCMPUB $VPCMP_IMM_LT, Z14, DataZ, K5  /* K5 <- letters [a-fA-F] */           \
+  VPADDB Z13, DataZ, DataZ                                                     \
+  VMOVDQU8 TmpZ, K4, DataZ               /* DataZ <- value of each character */ \
+  KORQ K4, K5, K4                                                              \
+  VPMADDUBSW Z15, DataZ, DataZ           /* DataZ <- (hi << 4) + lo */
+
+// s[0].k[1] = fromhexstr(slice[2]).k[3]
+//
+// scratch: PageSize
+TEXT bcfromhexstr(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+  VPTESTNMD.BCST CONSTD_1(), Z3, K1, K1                // K1 <- lanes having an even number of characters
+  VPSRLD $1, Z3, Z4                                    // Z4 <- output lengths
+
+  // R15 (DstSum), Z5 (DstOff), Z7 (DstLen), Z6 (DstEnd), K2 (DstMask)
+  BC_HORIZONTAL_LENGTH_SUM(OUT(R15), OUT(Z5), OUT(Z7), OUT(Z6), OUT(K2), IN(Z4), IN(K1), X8, K3)
+
+  BC_ALLOC_SLICE(OUT(Z8), IN(R15), CX, R8)             // Z8 <- Offset of the beginning of the allocated buffer
+  VPADDD.Z Z5, Z8, K2, Z8                              // Z8 <- Offsets of each output string
+
+  VMOVDQU32 Z8, BC_SPILL_AREA(0)                       // [] <- output offsets
+  VMOVDQU32 Z2, BC_SPILL_AREA(64)                      // [] <- input offsets
+  VMOVDQU32 Z7, BC_SPILL_AREA(128)                     // [] <- output lengths
+
+  VPBROADCASTB CONSTD_0x30(), Z10                      // Z10 <- '0'
+  VPBROADCASTB CONSTD_0x20(), Z11                      // Z11 <- lowercase bit
+  VPBROADCASTB CONSTD_0x61(), Z12                      // Z12 <- 'a'
+  VPBROADCASTB CONSTD_10(), Z13
+  VPBROADCASTB CONSTD_6(), Z14
+  VPBROADCASTD CONSTD_0x01100110(), Z15                // Z15 <- (16, 1) byte pairs for VPMADDUBSW
+
+  XORL R13, R13                                        // R13 <- lanes having invalid characters
+  KMOVW K2, R8
+  TESTL R8, R8
+  JZ done
+
+lane_loop:
+  TZCNTL R8, BX                                        // BX <- Index of the lane to process
+  BLSRL R8, R8                                         // R8 <- Clear the index of the iterator
+
+  MOVL BC_SPILL_AREA_INDEX(128, BX*4), CX              // CX <- Output length
+  MOVL BC_SPILL_AREA_INDEX(0, BX*4), R15               // R15 <- Output index
+  MOVL BC_SPILL_AREA_INDEX(64, BX*4), R14              // R14 <- Input index
+  ADDQ VIRT_BASE, R15
+  ADDQ VIRT_BASE, R14
+
+  SUBL $32, CX
+  JCS lane_tail
+
+  // Main loop that decodes 64 characters at once
+lane_32b_iter:
+  VMOVDQU8 0(R14), Z16
+  FROM_HEX_CHARS(Z16, Z17)
+  KORTESTQ K4, K4
+  JCC lane_invalid                                     // CF is only set if all the characters are valid
+  VPMOVWB Z16, 0(R15)
+  ADDQ $64, R14
+  ADDQ $32, R15
+
+  SUBL $32, CX
+  JCC lane_32b_iter
+
+lane_tail:
+  ADDL $32, CX                                         // CX <- Remaining output bytes [0, 31]
+  JZ lane_check
+
+  MOVQ $-1, DX
+  SHLQ CL, DX
+  NOTQ DX
+  KMOVD DX, K6                                         // K6 <- output bytes
+
+  ADDL CX, CX
+  MOVQ $-1, DX
+  SHLQ CL, DX
+  KMOVQ DX, K3                                         // K3 <- bytes past the input
+  KNOTQ K3, K5
+
+  VMOVDQU8.Z 0(R14), K5, Z16
+  FROM_HEX_CHARS(Z16, Z17)
+  KORQ K3, K4, K4
+  KORTESTQ K4, K4
+  JCC lane_invalid
+  VPMOVWB Z16, K6, 0(R15)
+
+lane_check:
+  MOVL BC_SPILL_AREA_INDEX(128, BX*4), CX              // CX <- Output length
+  MOVL BC_SPILL_AREA_INDEX(0, BX*4), R14               // R14 <- Output index
+  ADDQ VIRT_BASE, R14
+  VALIDATE_UTF8(R14, CX, DX, R15, lane_invalid)
+
+lane_next:
+  TESTL R8, R8
+  JNE lane_loop
+
+done:
+  KMOVW R13, K3
+  KANDNW K2, K3, K2                                    // K2 <- valid output lanes
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z8), IN(Z7), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K2), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4)
+
+lane_invalid:
+  BTSL BX, R13
+  JMP lane_next
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+// TO_BASE64/FROM_BASE64 functions
+// --------------------------------------------------
+//
+// The base64 functions encode and decode each lane
+// with scalar code; the standard alphabet is used
+// and the output is always padded.
+
+#define BASE64_ENCODE_CHAR(Shift, Offset)                                      \
+  MOVL DX, R13                                                                 \
+  SHRL $Shift, R13                                                             \
+  ANDL $63, R13                                                                \
+  MOVBLZX 0(R11)(R13*1), R13                                                   \
+  MOVB R13, Offset(R15)
+
+// s[0].k[1] = tobase64str(slice[2]).k[3]
+//
+// scratch: PageSize
+TEXT bctobase64str(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+
+  // output length is 4 * ((n + 2) / 3), the division is done as (x * 0xAAAAAAAB) >> 33
+  VPADDD.BCST CONSTD_2(), Z3, Z4
+  VPBROADCASTD CONSTD_0xAAAAAAAB(), Z5
+  VPSRLQ $32, Z4, Z6
+  VPMULUDQ Z5, Z4, Z4
+  VPMULUDQ Z5, Z6, Z6
+  VPSRLQ $33, Z4, Z4
+  VPSRLQ $33, Z6, Z6
+  VPSLLQ $32, Z6, Z6
+  VPORD Z6, Z4, Z4
+  VPSLLD $2, Z4, Z4                                    // Z4 <- output lengths
+
+  // R15 (DstSum), Z5 (DstOff), Z7 (DstLen), Z6 (DstEnd), K2 (DstMask)
+  BC_HORIZONTAL_LENGTH_SUM(OUT(R15), OUT(Z5), OUT(Z7), OUT(Z6), OUT(K2), IN(Z4), IN(K1), X8, K3)
+
+  BC_ALLOC_SLICE(OUT(Z8), IN(R15), CX, R8)             // Z8 <- Offset of the beginning of the allocated buffer
+  VPADDD.Z Z5, Z8, K2, Z8                              // Z8 <- Offsets of each output string
+
+  VMOVDQU32 Z8, BC_SPILL_AREA(0)                       // [] <- output offsets
+  VMOVDQU32 Z2, BC_SPILL_AREA(64)                      // [] <- input offsets
+  VMOVDQU32 Z3, BC_SPILL_AREA(128)                     // [] <- input lengths
+
+  LEAQ CONST_GET_PTR(base64_encode_chars, 0), R11      // R11 <- alphabet
+  KMOVW K2, R8
+  TESTL R8, R8
+  JZ done
+
+lane_loop:
+  TZCNTL R8, BX                                        // BX <- Index of the lane to process
+  BLSRL R8, R8                                         // R8 <- Clear the index of the iterator
+
+  MOVL BC_SPILL_AREA_INDEX(128, BX*4), CX              // CX <- Input length
+  MOVL BC_SPILL_AREA_INDEX(0, BX*4), R15               // R15 <- Output index
+  MOVL BC_SPILL_AREA_INDEX(64, BX*4), R14              // R14 <- Input index
+  ADDQ VIRT_BASE, R15
+  ADDQ VIRT_BASE, R14
+
+  SUBL $3, CX
+  JCS lane_tail
+
+lane_3b_iter:
+  MOVBLZX 0(R14), DX
+  MOVBLZX 1(R14), R13
+  SHLL $16, DX
+  SHLL $8, R13
+  ORL R13, DX
+  MOVBLZX 2(R14), R13
+  ORL R13, DX                                          // DX <- next 24 bits of input
+  ADDQ $3, R14
+
+  BASE64_ENCODE_CHAR(18, 0)
+  BASE64_ENCODE_CHAR(12, 1)
+  BASE64_ENCODE_CHAR(6, 2)
+  BASE64_ENCODE_CHAR(0, 3)
+  ADDQ $4, R15
+
+  SUBL $3, CX
+  JCC lane_3b_iter
+
+lane_tail:
+  ADDL $3, CX                                          // CX <- Remaining bytes [0, 2]
+  JZ lane_next
+
+  MOVBLZX 0(R14), DX
+  SHLL $16, DX
+  MOVB $0x3D, 2(R15)                                   // '=' padding
+  MOVB $0x3D, 3(R15)
+  CMPL CX, $1
+  JEQ lane_tail_1
+
+  MOVBLZX 1(R14), R13
+  SHLL $8, R13
+  ORL R13, DX
+  BASE64_ENCODE_CHAR(6, 2)
+
+lane_tail_1:
+  BASE64_ENCODE_CHAR(18, 0)
+  BASE64_ENCODE_CHAR(12, 1)
+
+lane_next:
+  TESTL R8, R8
+  JNE lane_loop
+
+done:
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z8), IN(Z7), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K2), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4)
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+// loads the next character and decodes it into Dst;
+// the result is 0x80 for '=' and 0xFF for invalid characters
+#define BASE64_DECODE_CHAR(Offset, Dst)                                        \
+  MOVBLZX Offset(R14), Dst                                                     \
+  MOVBLZX 0(R11)(Dst*1), Dst
+
+// s[0].k[1] = frombase64str(slice[2]).k[3]
+//
+// scratch: PageSize
+TEXT bcfrombase64str(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+  VPTESTNMD.BCST CONSTD_3(), Z3, K1, K1                // K1 <- lanes having a multiple of 4 characters
+  VPSRLD $2, Z3, Z4
+  VPMULLD.BCST CONSTD_3(), Z4, Z4                      // Z4 <- maximum output lengths
+
+  // R15 (DstSum), Z5 (DstOff), Z7 (DstLen), Z6 (DstEnd), K2 (DstMask)
+  BC_HORIZONTAL_LENGTH_SUM(OUT(R15), OUT(Z5), OUT(Z7), OUT(Z6), OUT(K2), IN(Z4), IN(K1), X8, K3)
+
+  BC_ALLOC_SLICE(OUT(Z8), IN(R15), CX, R8)             // Z8 <- Offset of the beginning of the allocated buffer
+  VPADDD.Z Z5, Z8, K2, Z8                              // Z8 <- Offsets of each output string
+
+  VMOVDQU32 Z8, BC_SPILL_AREA(0)                       // [] <- output offsets
+  VMOVDQU32 Z2, BC_SPILL_AREA(64)                      // [] <- input offsets
+  VMOVDQU32 Z3, BC_SPILL_AREA(128)                     // [] <- input lengths
+
+  LEAQ CONST_GET_PTR(base64_decode_table, 0), R11      // R11 <- decoding table
+  KMOVW K2, R8
+  TESTL R8, R8
+  JZ done
+
+lane_loop:
+  TZCNTL R8, BX                                        // BX <- Index of the lane to process
+  BLSRL R8, R8                                         // R8 <- Clear the index of the iterator
+
+  MOVL BC_SPILL_AREA_INDEX(128, BX*4), CX              // CX <- Input length
+  MOVL BC_SPILL_AREA_INDEX(0, BX*4), R15               // R15 <- Output index
+  MOVL BC_SPILL_AREA_INDEX(64, BX*4), R14              // R14 <- Input index
+  ADDQ VIRT_BASE, R15
+  ADDQ VIRT_BASE, R14
+
+lane_4b_iter:
+  SUBL $4, CX
+  JCS lane_done
+
+  BASE64_DECODE_CHAR(0, DX)
+  BASE64_DECODE_CHAR(1, R13)
+  CMPL DX, $64
+  JAE lane_invalid
+  CMPL R13, $64
+  JAE lane_invalid
+  SHLL $6, DX
+  ORL R13, DX
+
+  BASE64_DECODE_CHAR(2, R13)
+  CMPL R13, $64
+  JAE lane_pad2
+  SHLL $6, DX
+  ORL R13, DX
+
+  BASE64_DECODE_CHAR(3, R13)
+  CMPL R13, $64
+  JAE lane_pad1
+  SHLL $6, DX
+  ORL R13, DX                                          // DX <- 24 bits of output
+
+  MOVB DX, 2(R15)
+  SHRL $8, DX
+  MOVB DX, 1(R15)
+  SHRL $8, DX
+  MOVB DX, 0(R15)
+  ADDQ $4, R14
+  ADDQ $3, R15
+  JMP lane_4b_iter
+
+lane_pad2:                                             // the last 4 characters must end with "=="
+  CMPL R13, $0x80
+  JNE lane_invalid
+  TESTL CX, CX
+  JNZ lane_invalid
+  BASE64_DECODE_CHAR(3, R13)
+  CMPL R13, $0x80
+  JNE lane_invalid
+  SHRL $4, DX                                          // DX <- 8 bits of output
+  MOVB DX, 0(R15)
+  ADDQ $1, R15
+  JMP lane_done
+
+lane_pad1:                                             // the last 4 characters must end with "="
+  CMPL R13, $0x80
+  JNE lane_invalid
+  TESTL CX, CX
+  JNZ lane_invalid
+  SHRL $2, DX                                          // DX <- 16 bits of output
+  MOVB DX, 1(R15)
+  SHRL $8, DX
+  MOVB DX, 0(R15)
+  ADDQ $2, R15
+
+lane_done:
+  SUBQ VIRT_BASE, R15
+  SUBL BC_SPILL_AREA_INDEX(0, BX*4), R15
+  MOVL R15, BC_SPILL_AREA_INDEX(192, BX*4)             // [] <- output length
+  MOVL R15, CX                                         // CX <- Output length
+  MOVL BC_SPILL_AREA_INDEX(0, BX*4), R14               // R14 <- Output index
+  ADDQ VIRT_BASE, R14
+  VALIDATE_UTF8(R14, CX, DX, R13, lane_invalid)
+  JMP lane_next
+
+lane_invalid:
+  MOVL $-1, BC_SPILL_AREA_INDEX(192, BX*4)             // [] <- invalid output
+
+lane_next:
+  TESTL R8, R8
+  JNE lane_loop
+
+done:
+  VMOVDQU32.Z BC_SPILL_AREA(192), K2, Z7               // Z7 <- output lengths
+  VPXORD X6, X6, X6
+  VPCMPD $VPCMP_IMM_GE, Z6, Z7, K2, K2                 // K2 <- valid output lanes
+  VMOVDQA32.Z Z7, K2, Z7
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z8), IN(Z7), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K2), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4)
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+CONST_DATA_U64(base64_encode_chars, 0, $0x4847464544434241)
+CONST_DATA_U64(base64_encode_chars, 8, $0x504f4e4d4c4b4a49)
+CONST_DATA_U64(base64_encode_chars, 16, $0x5857565554535251)
+CONST_DATA_U64(base64_encode_chars, 24, $0x6665646362615a59)
+CONST_DATA_U64(base64_encode_chars, 32, $0x6e6d6c6b6a696867)
+CONST_DATA_U64(base64_encode_chars, 40, $0x767574737271706f)
+CONST_DATA_U64(base64_encode_chars, 48, $0x333231307a797877)
+CONST_DATA_U64(base64_encode_chars, 56, $0x2f2b393837363534)
+CONST_GLOBAL(base64_encode_chars, $64)
+
+CONST_DATA_U64(base64_decode_table, 0, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 8, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 16, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 24, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 32, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 40, $0x3fffffff3effffff)
+CONST_DATA_U64(base64_decode_table, 48, $0x3b3a393837363534)
+CONST_DATA_U64(base64_decode_table, 56, $0xffff80ffffff3d3c)
+CONST_DATA_U64(base64_decode_table, 64, $0x06050403020100ff)
+CONST_DATA_U64(base64_decode_table, 72, $0x0e0d0c0b0a090807)
+CONST_DATA_U64(base64_decode_table, 80, $0x161514131211100f)
+CONST_DATA_U64(base64_decode_table, 88, $0xffffffffff191817)
+CONST_DATA_U64(base64_decode_table, 96, $0x201f1e1d1c1b1aff)
+CONST_DATA_U64(base64_decode_table, 104, $0x2827262524232221)
+CONST_DATA_U64(base64_decode_table, 112, $0x302f2e2d2c2b2a29)
+CONST_DATA_U64(base64_decode_table, 120, $0xffffffffff333231)
+CONST_DATA_U64(base64_decode_table, 128, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 136, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 144, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 152, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 160, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 168, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 176, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 184, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 192, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 200, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 208, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 216, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 224, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 232, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 240, $0xffffffffffffffff)
+CONST_DATA_U64(base64_decode_table, 248, $0xffffffffffffffff)
+CONST_GLOBAL(base64_decode_table, $256)
@@ -0,0 +1,1286 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// URL functions
+// --------------------------------------------------
+//
+// URLs are split according to the generic syntax of RFC 3986:
+//
+//   [scheme:][//[userinfo@]host[:port]]path[?query][#fragment]
+//
+// The components are found by a scalar scan of each active lane; the
+// results are sub-slices of the input, so no output buffer is needed.
+// The portable implementation is splitURL() in expr/net.go.
+//
+// Register usage of the lane loops:
+//
+//   R8  - active lanes that have not been processed yet
+//   BX  - index of the current lane
+//   R14 - pointer to the input string of the current lane
+//   CX  - length of the input string of the current lane
+//   R11 - pointer to url_char_class
+//   R15 - the scan position (relative to R14)
+//   R13 - the start of the result (relative to R14)
+
+#define URL_CLASS_ALPHA         1 // [A-Za-z]
+#define URL_CLASS_SCHEME        2 // [A-Za-z0-9+.-]
+#define URL_CLASS_AUTHORITY_END 4 // [/?#]
+#define URL_CLASS_PATH_END      8 // [?#]
+
+// URL_SKIP_SCHEME sets R15 to the position following "scheme:" or to zero
+// if the URL does not start with a scheme.
+#define URL_SKIP_SCHEME()                                                      \
+  XORL R15, R15                                                                \
+url_scheme_loop:                                                               \
+  CMPL R15, CX                                                                 \
+  JAE url_no_scheme                                                            \
+  MOVBLZX 0(R14)(R15*1), DX                                                    \
+  CMPL DX, $0x3a                          /* ':' */                            \
+  JEQ url_scheme_end                                                           \
+  MOVBLZX 0(R11)(DX*1), DX                                                     \
+  TESTL R15, R15                                                               \
+  JNZ url_scheme_char                                                          \
+  SHLL $1, DX                             /* the first char must be a letter */ \
+url_scheme_char:                                                               \
+  TESTL $URL_CLASS_SCHEME, DX                                                  \
+  JZ url_no_scheme                                                             \
+  INCL R15                                                                     \
+  JMP url_scheme_loop                                                          \
+url_scheme_end:                                                                \
+  TESTL R15, R15                                                               \
+  JZ url_no_scheme                                                             \
+  INCL R15                                                                     \
+  JMP url_scheme_done                                                          \
+url_no_scheme:                                                                 \
+  XORL R15, R15                                                                \
+url_scheme_done:
+
+// URL_SKIP_AUTHORITY advances R15 past "//authority" if the URL has one.
+#define URL_SKIP_AUTHORITY()                                                   \
+  LEAL 2(R15), DX                                                              \
+  CMPL DX, CX                                                                  \
+  JA url_authority_done                                                        \
+  CMPW 0(R14)(R15*1), $0x2f2f             /* "//" */                           \
+  JNE url_authority_done                                                       \
+  MOVL DX, R15                                                                 \
+url_authority_loop:                                                            \
+  CMPL R15, CX                                                                 \
+  JAE url_authority_done                                                       \
+  MOVBLZX 0(R14)(R15*1), DX                                                    \
+  TESTB $URL_CLASS_AUTHORITY_END, 0(R11)(DX*1)                                 \
+  JNZ url_authority_done                                                       \
+  INCL R15                                                                     \
+  JMP url_authority_loop                                                       \
+url_authority_done:
+
+// URL_LANE_LOOP_BEGIN starts the iteration over the active
+// lanes K1 of the input slices Z2:Z3.
+#define URL_LANE_LOOP_BEGIN()                                                  \
+  VMOVDQU32 Z2, BC_SPILL_AREA(0)          /* [] <- input offsets */            \
+  VMOVDQU32 Z3, BC_SPILL_AREA(64)         /* [] <- input lengths */            \
+  LEAQ CONST_GET_PTR(url_char_class, 0), R11                                   \
+  KMOVW K1, R8                                                                 \
+  TESTL R8, R8                                                                 \
+  JZ done                                                                      \
+lane_loop:                                                                     \
+  TZCNTL R8, BX                           /* BX <- index of the lane */        \
+  BLSRL R8, R8                            /* R8 <- clear the index */          \
+  MOVL BC_SPILL_AREA_INDEX(64, BX*4), CX  /* CX <- input length */             \
+  MOVL BC_SPILL_AREA_INDEX(0, BX*4), R14                                       \
+  ADDQ VIRT_BASE, R14                     /* R14 <- input pointer */
+
+// URL_LANE_LOOP_END records the sub-slice [R13, R15) of the current lane,
+// or marks the lane as invalid, and loads the output slices into Z2:Z3
+// and the valid lanes into K1 once all lanes have been processed.
+#define URL_LANE_LOOP_END()                                                    \
+lane_valid:                                                                    \
+  ADDL R13, BC_SPILL_AREA_INDEX(0, BX*4)  /* [] <- output offset */            \
+  SUBL R13, R15                                                                \
+  MOVL R15, BC_SPILL_AREA_INDEX(128, BX*4) /* [] <- output length */           \
+  JMP lane_next                                                                \
+lane_invalid:                                                                  \
+  MOVL $-1, BC_SPILL_AREA_INDEX(128, BX*4)                                     \
+lane_next:                                                                     \
+  TESTL R8, R8                                                                 \
+  JNZ lane_loop                                                                \
+done:                                                                          \
+  VMOVDQU32.Z BC_SPILL_AREA(128), K1, Z3  /* Z3 <- output lengths */           \
+  VPXORD X4, X4, X4                                                            \
+  VPCMPD $VPCMP_IMM_GE, Z4, Z3, K1, K1    /* K1 <- valid output lanes */       \
+  VMOVDQU32.Z BC_SPILL_AREA(0), K1, Z2    /* Z2 <- output offsets */           \
+  VMOVDQA32.Z Z3, K1, Z3
+
+// s[0].k[1] = urlhost(slice[2]).k[3]
+TEXT bcurlhost(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+
+  URL_LANE_LOOP_BEGIN()
+  URL_SKIP_SCHEME()
+
+  LEAL 2(R15), DX
+  CMPL DX, CX
+  JA lane_invalid
+  CMPW 0(R14)(R15*1), $0x2f2f                          // the host requires "//"
+  JNE lane_invalid
+  MOVL DX, R15
+  MOVL DX, R13                                         // R13 <- start of the host
+
+authority_loop:
+  CMPL R15, CX
+  JAE authority_done
+  MOVBLZX 0(R14)(R15*1), DX
+  TESTB $URL_CLASS_AUTHORITY_END, 0(R11)(DX*1)
+  JNZ authority_done
+  INCL R15
+  CMPL DX, $0x40                                       // '@'
+  JNE authority_loop
+  MOVL R15, R13                                        // R13 <- skip the userinfo
+  JMP authority_loop
+
+authority_done:
+  MOVL R15, CX                                         // CX <- end of the authority
+  MOVL R13, R15
+  CMPL R15, CX
+  JAE lane_invalid
+  CMPB 0(R14)(R15*1), $0x5b                            // '['
+  JEQ ipv6_literal
+
+host_loop:
+  CMPL R15, CX
+  JAE host_done
+  CMPB 0(R14)(R15*1), $0x3a                            // ':' starts the port
+  JEQ host_done
+  INCL R15
+  JMP host_loop
+
+ipv6_literal:
+  INCL R15
+  MOVL R15, R13                                        // R13 <- skip '['
+
+ipv6_literal_loop:
+  CMPL R15, CX
+  JAE lane_invalid                                     // missing ']'
+  CMPB 0(R14)(R15*1), $0x5d                            // ']'
+  JEQ host_done
+  INCL R15
+  JMP ipv6_literal_loop
+
+host_done:
+  CMPL R15, R13
+  JEQ lane_invalid                                     // empty host
+  JMP lane_valid
+
+  URL_LANE_LOOP_END()
+
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z2), IN(Z3), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K1), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4)
+
+// s[0].k[1] = urlpath(slice[2]).k[3]
+TEXT bcurlpath(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+
+  URL_LANE_LOOP_BEGIN()
+  URL_SKIP_SCHEME()
+  URL_SKIP_AUTHORITY()
+  MOVL R15, R13                                        // R13 <- start of the path
+
+path_loop:
+  CMPL R15, CX
+  JAE lane_valid
+  MOVBLZX 0(R14)(R15*1), DX
+  TESTB $URL_CLASS_PATH_END, 0(R11)(DX*1)
+  JNZ lane_valid
+  INCL R15
+  JMP path_loop
+
+  URL_LANE_LOOP_END()
+
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z2), IN(Z3), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K1), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4)
+
+// s[0].k[1] = urlquery(slice[2]).k[3]
+TEXT bcurlquery(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+
+  URL_LANE_LOOP_BEGIN()
+  URL_SKIP_SCHEME()
+  URL_SKIP_AUTHORITY()
+
+path_loop:
+  CMPL R15, CX
+  JAE lane_invalid                                     // no query
+  MOVBLZX 0(R14)(R15*1), DX
+  TESTB $URL_CLASS_PATH_END, 0(R11)(DX*1)
+  JNZ path_done
+  INCL R15
+  JMP path_loop
+
+path_done:
+  CMPL DX, $0x3f                                       // '?'
+  JNE lane_invalid
+  INCL R15
+  MOVL R15, R13                                        // R13 <- start of the query
+
+query_loop:
+  CMPL R15, CX
+  JAE lane_valid
+  CMPB 0(R14)(R15*1), $0x23                            // '#'
+  JEQ lane_valid
+  INCL R15
+  JMP query_loop
+
+  URL_LANE_LOOP_END()
+
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z2), IN(Z3), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K1), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4)
+
+// s[0].k[1] = urlqueryparam(slice[2], dict[3]).k[4]
+//
+// Finds the value of the first parameter of the query string having
+// the name given by the dictionary entry; the name must not contain
+// '&' or '=' characters. The value is returned still encoded.
+TEXT bcurlqueryparam(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_SLOT(BC_SLOT_SIZE*2, OUT(BX))
+  BC_UNPACK_DICT(BC_SLOT_SIZE*3, OUT(R11))
+  BC_UNPACK_SLOT(BC_SLOT_SIZE*3+BC_DICT_SIZE, OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+
+  VMOVDQU32 Z2, BC_SPILL_AREA(0)                       // [] <- input offsets
+  VMOVDQU32 Z3, BC_SPILL_AREA(64)                      // [] <- input lengths
+  MOVQ 8(R11), R13                                     // R13 <- name length
+  MOVQ 0(R11), R11                                     // R11 <- name pointer
+
+  KMOVW K1, R8
+  TESTL R8, R8
+  JZ done
+
+lane_loop:
+  TZCNTL R8, BX                                        // BX <- Index of the lane to process
+  BLSRL R8, R8                                         // R8 <- Clear the index of the iterator
+  MOVL BC_SPILL_AREA_INDEX(64, BX*4), CX               // CX <- Input length
+  MOVL BC_SPILL_AREA_INDEX(0, BX*4), R14
+  ADDQ VIRT_BASE, R14                                  // R14 <- Input pointer
+
+param_loop:                                            // R14 points to the beginning of a parameter
+  CMPL CX, R13
+  JB lane_invalid                                      // the rest of the query is shorter than the name
+  XORL R15, R15
+
+param_name_loop:
+  CMPL R15, R13
+  JAE param_name_end
+  MOVBLZX 0(R14)(R15*1), DX
+  CMPB DX, 0(R11)(R15*1)
+  JNE param_skip
+  INCL R15
+  JMP param_name_loop
+
+param_name_end:                                        // the name must be followed by '=', '&' or the end
+  CMPL R15, CX
+  JAE param_empty
+  MOVBLZX 0(R14)(R15*1), DX
+  CMPL DX, $0x26                                       // '&'
+  JEQ param_empty
+  CMPL DX, $0x3d                                       // '='
+  JNE param_skip
+
+  INCL R15
+  ADDQ R15, R14                                        // R14 <- beginning of the value
+  SUBL R15, CX
+  XORL R15, R15
+
+param_value_loop:
+  CMPL R15, CX
+  JAE param_found
+  CMPB 0(R14)(R15*1), $0x26                            // '&'
+  JEQ param_found
+  INCL R15
+  JMP param_value_loop
+
+param_empty:
+  ADDQ R15, R14
+  XORL R15, R15
+
+param_found:
+  SUBQ VIRT_BASE, R14
+  MOVL R14, BC_SPILL_AREA_INDEX(0, BX*4)               // [] <- output offset
+  MOVL R15, BC_SPILL_AREA_INDEX(128, BX*4)             // [] <- output length
+  JMP lane_next
+
+param_skip:                                            // skip to the parameter after the next '&'
+  CMPL R15, CX
+  JAE lane_invalid
+  CMPB 0(R14)(R15*1), $0x26                            // '&'
+  JEQ param_next
+  INCL R15
+  JMP param_skip
+
+param_next:
+  INCL R15
+  ADDQ R15, R14
+  SUBL R15, CX
+  JMP param_loop
+
+lane_invalid:
+  MOVL $-1, BC_SPILL_AREA_INDEX(128, BX*4)
+
+lane_next:
+  TESTL R8, R8
+  JNZ lane_loop
+
+done:
+  VMOVDQU32.Z BC_SPILL_AREA(128), K1, Z3               // Z3 <- output lengths
+  VPXORD X4, X4, X4
+  VPCMPD $VPCMP_IMM_GE, Z4, Z3, K1, K1                 // K1 <- valid output lanes
+  VMOVDQU32.Z BC_SPILL_AREA(0), K1, Z2                 // Z2 <- output offsets
+  VMOVDQA32.Z Z3, K1, Z3
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z2), IN(Z3), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K1), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4 + BC_DICT_SIZE)
+
+// s[0].k[1] = urldecode(slice[2]).k[3]
+//
+// Decodes "%XX" sequences and replaces '+' with a space; lanes
+// containing an invalid escape sequence are not valid outputs.
+//
+// scratch: PageSize
+TEXT bcurldecode(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+
+  // R15 (DstSum), Z5 (DstOff), Z7 (DstLen), Z6 (DstEnd), K2 (DstMask)
+  BC_HORIZONTAL_LENGTH_SUM(OUT(R15), OUT(Z5), OUT(Z7), OUT(Z6), OUT(K2), IN(Z3), IN(K1), X8, K3)
+
+  BC_ALLOC_SLICE(OUT(Z8), IN(R15), CX, R8)             // Z8 <- Offset of the beginning of the allocated buffer
+  VPADDD.Z Z5, Z8, K2, Z8                              // Z8 <- Offsets of each output string
+
+  VMOVDQU32 Z8, BC_SPILL_AREA(0)                       // [] <- output offsets
+  VMOVDQU32 Z2, BC_SPILL_AREA(64)                      // [] <- input offsets
+  VMOVDQU32 Z3, BC_SPILL_AREA(128)                     // [] <- input lengths
+
+  LEAQ CONST_GET_PTR(url_hex_table, 0), R11            // R11 <- hex digit values
+  KMOVW K2, R8
+  TESTL R8, R8
+  JZ done
+
+lane_loop:
+  TZCNTL R8, BX                                        // BX <- Index of the lane to process
+  BLSRL R8, R8                                         // R8 <- Clear the index of the iterator
+
+  MOVL BC_SPILL_AREA_INDEX(128, BX*4), CX              // CX <- Input length
+  MOVL BC_SPILL_AREA_INDEX(0, BX*4), R15               // R15 <- Output index
+  MOVL BC_SPILL_AREA_INDEX(64, BX*4), R14              // R14 <- Input index
+  ADDQ VIRT_BASE, R15
+  ADDQ VIRT_BASE, R14
+
+char_loop:
+  SUBL $1, CX
+  JCS lane_done
+  MOVBLZX 0(R14), DX
+  INCQ R14
+  CMPL DX, $0x2b                                       // '+'
+  JEQ char_space
+  CMPL DX, $0x25                                       // '%'
+  JEQ char_escape
+
+char_store:
+  MOVB DX, 0(R15)
+  INCQ R15
+  JMP char_loop
+
+char_space:
+  MOVL $0x20, DX
+  JMP char_store
+
+char_escape:
+  SUBL $2, CX
+  JCS lane_invalid
+  MOVBLZX 0(R14), DX
+  MOVBLZX 0(R11)(DX*1), DX
+  MOVBLZX 1(R14), R13
+  MOVBLZX 0(R11)(R13*1), R13
+  ADDQ $2, R14
+  CMPL DX, $16
+  JAE lane_invalid
+  CMPL R13, $16
+  JAE lane_invalid
+  SHLL $4, DX
+  ORL R13, DX
+  JMP char_store
+
+lane_done:
+  SUBQ VIRT_BASE, R15
+  SUBL BC_SPILL_AREA_INDEX(0, BX*4), R15
+  MOVL R15, BC_SPILL_AREA_INDEX(192, BX*4)             // [] <- output length
+  JMP lane_next
+
+lane_invalid:
+  MOVL $-1, BC_SPILL_AREA_INDEX(192, BX*4)             // [] <- invalid output
+
+lane_next:
+  TESTL R8, R8
+  JNE lane_loop
+
+done:
+  VMOVDQU32.Z BC_SPILL_AREA(192), K2, Z7               // Z7 <- output lengths
+  VPXORD X6, X6, X6
+  VPCMPD $VPCMP_IMM_GE, Z6, Z7, K2, K2                 // K2 <- valid output lanes
+  VMOVDQA32.Z Z7, K2, Z7
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z8), IN(Z7), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K2), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4)
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+CONST_DATA_U64(url_char_class, 0, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 8, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 16, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 24, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 32, $0x000000000c000000)
+CONST_DATA_U64(url_char_class, 40, $0x0402020002000000)
+CONST_DATA_U64(url_char_class, 48, $0x0202020202020202)
+CONST_DATA_U64(url_char_class, 56, $0x0c00000000000202)
+CONST_DATA_U64(url_char_class, 64, $0x0303030303030300)
+CONST_DATA_U64(url_char_class, 72, $0x0303030303030303)
+CONST_DATA_U64(url_char_class, 80, $0x0303030303030303)
+CONST_DATA_U64(url_char_class, 88, $0x0000000000030303)
+CONST_DATA_U64(url_char_class, 96, $0x0303030303030300)
+CONST_DATA_U64(url_char_class, 104, $0x0303030303030303)
+CONST_DATA_U64(url_char_class, 112, $0x0303030303030303)
+CONST_DATA_U64(url_char_class, 120, $0x0000000000030303)
+CONST_DATA_U64(url_char_class, 128, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 136, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 144, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 152, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 160, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 168, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 176, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 184, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 192, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 200, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 208, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 216, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 224, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 232, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 240, $0x0000000000000000)
+CONST_DATA_U64(url_char_class, 248, $0x0000000000000000)
+CONST_GLOBAL(url_char_class, $256)
+
+CONST_DATA_U64(url_hex_table, 0, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 8, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 16, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 24, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 32, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 40, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 48, $0x0706050403020100)
+CONST_DATA_U64(url_hex_table, 56, $0xffffffffffff0908)
+CONST_DATA_U64(url_hex_table, 64, $0xff0f0e0d0c0b0aff)
+CONST_DATA_U64(url_hex_table, 72, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 80, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 88, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 96, $0xff0f0e0d0c0b0aff)
+CONST_DATA_U64(url_hex_table, 104, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 112, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 120, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 128, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 136, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 144, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 152, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 160, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 168, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 176, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 184, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 192, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 200, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 208, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 216, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 224, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 232, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 240, $0xffffffffffffffff)
+CONST_DATA_U64(url_hex_table, 248, $0xffffffffffffffff)
+CONST_GLOBAL(url_hex_table, $256)
+
+// IP address functions
+// --------------------------------------------------
+//
+// The parsers follow the rules of net/netip.ParseAddr: IPv4 octets
+// must not have leading zeros, IPv6 groups have at most four hex
+// digits, "::" may appear at most once, and an IPv6 address may end
+// with an embedded IPv4 address. Zones are not accepted.
+
+// IPV4_PARSE parses the dotted-quad address at Ptr[0:Len] into Out,
+// jumping to Invalid if the string is not a valid IPv4 address; Ptr
+// and Len are consumed.
+#define IPV4_PARSE(Ptr, Len, Out, Val, Chr, Invalid)                           \
+  MOVL $1, Out                            /* Out <- sentinel shifted out after 4 octets */ \
+  MOVL $-1, Val                           /* Val <- no digits in the octet yet */ \
+ipv4_loop:                                                                     \
+  SUBL $1, Len                                                                 \
+  JCS ipv4_end                                                                 \
+  MOVBLZX 0(Ptr), Chr                                                          \
+  INCQ Ptr                                                                     \
+  CMPL Chr, $0x2e                         /* '.' */                            \
+  JEQ ipv4_dot                                                                 \
+  SUBL $0x30, Chr                                                              \
+  CMPL Chr, $9                                                                 \
+  JA Invalid                                                                   \
+  TESTL Val, Val                                                               \
+  JZ Invalid                              /* leading zero */                   \
+  JNS ipv4_digit                                                               \
+  XORL Val, Val                                                                \
+ipv4_digit:                                                                    \
+  IMUL3L $10, Val, Val                                                         \
+  ADDL Chr, Val                                                                \
+  CMPL Val, $255                                                               \
+  JA Invalid                                                                   \
+  JMP ipv4_loop                                                                \
+ipv4_dot:                                                                      \
+  TESTL Val, Val                                                               \
+  JS Invalid                              /* empty octet */                    \
+  CMPL Out, $0x1000000                                                         \
+  JAE Invalid                             /* more than 4 octets */             \
+  SHLL $8, Out                                                                 \
+  ORL Val, Out                                                                 \
+  MOVL $-1, Val                                                                \
+  JMP ipv4_loop                                                                \
+ipv4_end:                                                                      \
+  TESTL Val, Val                                                               \
+  JS Invalid                              /* empty octet */                    \
+  CMPL Out, $0x1000000                                                         \
+  JB Invalid                              /* less than 4 octets */             \
+  SHLL $8, Out                                                                 \
+  ORL Val, Out
+
+// i64[0].k[1] = inetaton(slice[2]).k[3]
+TEXT bcinetaton(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+
+  VMOVDQU32 Z2, BC_SPILL_AREA(0)                       // [] <- input offsets
+  VMOVDQU32 Z3, BC_SPILL_AREA(64)                      // [] <- input lengths
+  XORL R15, R15                                        // R15 <- valid lanes
+  KMOVW K1, R8
+  TESTL R8, R8
+  JZ done
+
+lane_loop:
+  TZCNTL R8, BX                                        // BX <- Index of the lane to process
+  BLSRL R8, R8                                         // R8 <- Clear the index of the iterator
+  MOVL BC_SPILL_AREA_INDEX(64, BX*4), CX               // CX <- Input length
+  MOVL BC_SPILL_AREA_INDEX(0, BX*4), R14
+  ADDQ VIRT_BASE, R14                                  // R14 <- Input pointer
+
+  IPV4_PARSE(R14, CX, R13, DX, R11, lane_next)
+  MOVQ R13, BC_SPILL_AREA_INDEX(128, BX*8)             // [] <- address
+  BTSL BX, R15
+
+lane_next:
+  TESTL R8, R8
+  JNZ lane_loop
+
+done:
+  KMOVW R15, K1
+  KSHIFTRW $8, K1, K2
+  VMOVDQU64.Z BC_SPILL_AREA(128), K1, Z2
+  VMOVDQU64.Z BC_SPILL_AREA(192), K2, Z3
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_I64_TO_SLOT(IN(Z2), IN(Z3), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K1), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4)
+
+// s[0].k[1] = inetntoa(i64[2]).k[3]
+//
+// Each lane gets a 16-byte chunk of the output buffer; the octets
+// are converted using inet_ntoa_table, which holds the decimal digits
+// followed by '.' (4 bytes per octet value) and then the lengths of
+// these strings (1 byte per octet value).
+//
+// scratch: 16 * 16
+TEXT bcinetntoa(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_2xSLOT(BC_SLOT_SIZE*2, OUT(BX), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  KSHIFTRW $8, K1, K2
+  BC_LOAD_I64_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1), IN(K2))
+
+  VPBROADCASTQ CONSTQ_0xFFFFFFFF(), Z4
+  VPCMPUQ $VPCMP_IMM_LE, Z4, Z2, K1, K1                // K1 <- lanes in [0, 2^32) (low)
+  VPCMPUQ $VPCMP_IMM_LE, Z4, Z3, K2, K2                // K2 <- lanes in [0, 2^32) (high)
+  KUNPCKBW K1, K2, K1
+  VMOVDQU64 Z2, BC_SPILL_AREA(0)                       // [] <- addresses
+  VMOVDQU64 Z3, BC_SPILL_AREA(64)
+
+  MOVL $(16*16), R15
+  BC_ALLOC_SLICE(OUT(Z8), IN(R15), CX, R8)             // Z8 <- Offset of the beginning of the allocated buffer
+  VMOVD X8, R13                                        // R13 <- Offset of the beginning of the allocated buffer
+
+  LEAQ CONST_GET_PTR(inet_ntoa_table, 0), R15          // R15 <- octet table
+  KMOVW K1, R8
+  TESTL R8, R8
+  JZ done
+
+#define INET_NTOA_OCTET(Shift)                                                 \
+  MOVL DX, R11                                                                 \
+  SHRL $Shift, R11                                                             \
+  ANDL $0xff, R11                                                              \
+  MOVL 0(R15)(R11*4), CX                                                       \
+  MOVL CX, 0(R14)                                                              \
+  MOVBLZX 1024(R15)(R11*1), CX                                                 \
+  ADDQ CX, R14
+
+lane_loop:
+  TZCNTL R8, BX                                        // BX <- Index of the lane to process
+  BLSRL R8, R8                                         // R8 <- Clear the index of the iterator
+  MOVL BC_SPILL_AREA_INDEX(0, BX*8), DX                // DX <- IPv4 address
+  MOVL BX, R14
+  SHLL $4, R14
+  ADDL R13, R14
+  MOVL R14, BC_SPILL_AREA_INDEX(128, BX*4)             // [] <- output offset
+  ADDQ VIRT_BASE, R14                                  // R14 <- output pointer
+
+  INET_NTOA_OCTET(24)
+  INET_NTOA_OCTET(16)
+  INET_NTOA_OCTET(8)
+  INET_NTOA_OCTET(0)
+
+  SUBQ VIRT_BASE, R14
+  SUBL BC_SPILL_AREA_INDEX(128, BX*4), R14
+  DECL R14                                             // R14 <- output length (without the last '.')
+  MOVL R14, BC_SPILL_AREA_INDEX(192, BX*4)             // [] <- output length
+
+  TESTL R8, R8
+  JNZ lane_loop
+
+done:
+  VMOVDQU32.Z BC_SPILL_AREA(128), K1, Z2               // Z2 <- output offsets
+  VMOVDQU32.Z BC_SPILL_AREA(192), K1, Z3               // Z3 <- output lengths
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z2), IN(Z3), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K1), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4)
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+#undef INET_NTOA_OCTET
+
+// IPV6_PARSE parses the address of the current lane (R14[0:CX]) into
+// 8 big-endian groups at BC_SPILL_AREA(16), jumping to Invalid if the
+// string is not a valid IPv6 address. Clobbers CX, DX, R8, R11, R13,
+// R14 and R15.
+#define IPV6_PARSE(Invalid)                                                    \
+  XORL R15, R15                           /* R15 <- number of groups */        \
+  MOVL $-1, R11                           /* R11 <- position of "::" (none) */ \
+                                                                               \
+  CMPL CX, $2                                                                  \
+  JB ipv6_group_loop                                                           \
+  CMPW 0(R14), $0x3a3a                    /* leading "::" */                   \
+  JNE ipv6_group_loop                                                          \
+  XORL R11, R11                                                                \
+  ADDQ $2, R14                                                                 \
+  SUBL $2, CX                                                                  \
+  JZ ipv6_parsed                                                               \
+                                                                               \
+ipv6_group_loop:                                                               \
+  CMPL R15, $8                                                                 \
+  JAE Invalid                             /* too many groups */                \
+  XORL DX, DX                             /* DX <- group value */              \
+  XORL R13, R13                           /* R13 <- number of digits */        \
+                                                                               \
+ipv6_hex_loop:                                                                 \
+  CMPL R13, CX                                                                 \
+  JAE ipv6_hex_done                                                            \
+  MOVBLZX 0(R14)(R13*1), R8                                                    \
+  CMPL R8, $0x39                                                               \
+  JA ipv6_hex_alpha                                                            \
+  SUBL $0x30, R8                                                               \
+  JCS ipv6_hex_done                                                            \
+  JMP ipv6_hex_digit                                                           \
+                                                                               \
+ipv6_hex_alpha:                                                                \
+  ORL $0x20, R8                                                                \
+  SUBL $0x61, R8                                                               \
+  JCS ipv6_hex_done                                                            \
+  CMPL R8, $6                                                                  \
+  JAE ipv6_hex_done                                                            \
+  ADDL $10, R8                                                                 \
+                                                                               \
+ipv6_hex_digit:                                                                \
+  CMPL R13, $4                                                                 \
+  JAE Invalid                             /* more than 4 digits */             \
+  SHLL $4, DX                                                                  \
+  ORL R8, DX                                                                   \
+  INCL R13                                                                     \
+  JMP ipv6_hex_loop                                                            \
+                                                                               \
+ipv6_hex_done:                                                                 \
+  TESTL R13, R13                                                               \
+  JZ Invalid                              /* a group needs at least one digit */ \
+  CMPL R13, CX                                                                 \
+  JAE ipv6_store_group                                                         \
+  CMPB 0(R14)(R13*1), $0x2e               /* '.' starts an embedded IPv4 address */ \
+  JEQ ipv6_embedded_ipv4                                                       \
+                                                                               \
+ipv6_store_group:                                                              \
+  ROLW $8, DX                                                                  \
+  MOVW DX, BC_SPILL_AREA_INDEX(16, R15*2) /* [] <- group (big-endian) */       \
+  INCL R15                                                                     \
+  ADDQ R13, R14                                                                \
+  SUBL R13, CX                                                                 \
+  JZ ipv6_parsed                                                               \
+  CMPB 0(R14), $0x3a                      /* groups are separated by ':' */    \
+  JNE Invalid                                                                  \
+  CMPL CX, $1                                                                  \
+  JEQ Invalid                             /* trailing ':' */                   \
+  INCQ R14                                                                     \
+  DECL CX                                                                      \
+  CMPB 0(R14), $0x3a                                                           \
+  JNE ipv6_group_loop                                                          \
+  TESTL R11, R11                                                               \
+  JNS Invalid                             /* more than one "::" */             \
+  MOVL R15, R11                                                                \
+  INCQ R14                                                                     \
+  DECL CX                                                                      \
+  JZ ipv6_parsed                                                               \
+  JMP ipv6_group_loop                                                          \
+                                                                               \
+ipv6_embedded_ipv4:                                                            \
+  TESTL R11, R11                                                               \
+  JNS ipv6_embedded_ipv4_room                                                  \
+  CMPL R15, $6                                                                 \
+  JNE Invalid                             /* IPv4 must be the last 32 bits */  \
+                                                                               \
+ipv6_embedded_ipv4_room:                                                       \
+  CMPL R15, $6                                                                 \
+  JA Invalid                                                                   \
+  IPV4_PARSE(R14, CX, R13, DX, R8, Invalid)                                    \
+  BSWAPL R13                                                                   \
+  MOVL R13, BC_SPILL_AREA_INDEX(16, R15*2) /* [] <- 2 groups (big-endian) */   \
+  ADDL $2, R15                                                                 \
+                                                                               \
+ipv6_parsed:                                                                   \
+  CMPL R15, $8                                                                 \
+  JAE ipv6_parsed_all_groups                                                   \
+  TESTL R11, R11                                                               \
+  JS Invalid                              /* too few groups without "::" */    \
+                                                                               \
+  /* expand "::" by moving groups [R11, R15) to the end of the address */      \
+  MOVL $8, R13                                                                 \
+  SUBL R15, R13                           /* R13 <- number of zero groups */   \
+                                                                               \
+ipv6_expand_move:                                                              \
+  CMPL R15, R11                                                                \
+  JBE ipv6_expand_zero                                                         \
+  DECL R15                                                                     \
+  MOVWLZX BC_SPILL_AREA_INDEX(16, R15*2), R8                                   \
+  LEAL 0(R15)(R13*1), CX                                                       \
+  MOVW R8, BC_SPILL_AREA_INDEX(16, CX*2)                                       \
+  JMP ipv6_expand_move                                                         \
+                                                                               \
+ipv6_expand_zero:                                                              \
+  ADDL R11, R13                                                                \
+                                                                               \
+ipv6_expand_zero_loop:                                                         \
+  CMPL R11, R13                                                                \
+  JAE ipv6_done                                                                \
+  MOVW $0, BC_SPILL_AREA_INDEX(16, R11*2)                                      \
+  INCL R11                                                                     \
+  JMP ipv6_expand_zero_loop                                                    \
+                                                                               \
+ipv6_parsed_all_groups:                                                        \
+  TESTL R11, R11                                                               \
+  JNS Invalid                             /* "::" must stand for at least one group */ \
+                                                                               \
+ipv6_done:
+
+// k[0] = is_subnet_of_ip6(slice[1], dict[2]).k[3]
+//
+// The dictionary entry contains the (inclusive) minimum and maximum
+// addresses as four 64-bit integers: min[0:8], min[8:16], max[0:8]
+// and max[8:16]. Each lane is parsed into 16 bytes (in network byte
+// order) that are then compared with these values.
+//
+// Spill area layout:
+//
+//   0   - lanes that have not been processed yet (uint32)
+//   4   - matching lanes (uint32)
+//   8   - the dictionary entry pointer
+//   16  - the parsed address (8 x uint16)
+//   64  - input offsets
+//   128 - input lengths
+TEXT bcIsSubnetOfIP6(SB), NOSPLIT|NOFRAME, $0
+  BC_UNPACK_SLOT_DICT_SLOT(BC_SLOT_SIZE*1, OUT(BX), OUT(R14), OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+
+  VMOVDQU32 Z2, BC_SPILL_AREA(64)                      // [] <- input offsets
+  VMOVDQU32 Z3, BC_SPILL_AREA(128)                     // [] <- input lengths
+  MOVQ 0(R14), R14
+  MOVQ R14, BC_SPILL_AREA(8)                           // [] <- min/max pointer
+  MOVL $0, BC_SPILL_AREA(4)                            // [] <- no matching lanes yet
+  KMOVW K1, R8
+  TESTL R8, R8
+  JZ done
+
+lane_loop:
+  TZCNTL R8, BX                                        // BX <- Index of the lane to process
+  BLSRL R8, R8                                         // R8 <- Clear the index of the iterator
+  MOVL R8, BC_SPILL_AREA(0)                            // R8 is used as a temporary from now on
+  MOVL BC_SPILL_AREA_INDEX(128, BX*4), CX              // CX <- Input length
+  MOVL BC_SPILL_AREA_INDEX(64, BX*4), R14
+  ADDQ VIRT_BASE, R14                                  // R14 <- Input pointer
+
+  IPV6_PARSE(lane_next)
+
+  MOVQ BC_SPILL_AREA(16), DX
+  BSWAPQ DX                                            // DX <- high 64 bits of the address
+  MOVQ BC_SPILL_AREA(24), R13
+  BSWAPQ R13                                           // R13 <- low 64 bits of the address
+  MOVQ BC_SPILL_AREA(8), R14                           // R14 <- min/max
+
+  CMPQ DX, 0(R14)
+  JB lane_next
+  JA compare_max
+  CMPQ R13, 8(R14)
+  JB lane_next
+
+compare_max:
+  CMPQ DX, 16(R14)
+  JA lane_next
+  JB lane_match
+  CMPQ R13, 24(R14)
+  JA lane_next
+
+lane_match:
+  MOVL BC_SPILL_AREA(4), R8
+  BTSL BX, R8
+  MOVL R8, BC_SPILL_AREA(4)                            // [] <- add the lane to the matching lanes
+
+lane_next:
+  MOVL BC_SPILL_AREA(0), R8
+  TESTL R8, R8
+  JNZ lane_loop
+
+done:
+  KMOVW BC_SPILL_AREA(4), K1
+  BC_UNPACK_SLOT(0, OUT(DX))
+  BC_STORE_K_TO_SLOT(IN(K1), IN(DX))
+  NEXT_ADVANCE(BC_SLOT_SIZE*3 + BC_DICT_SIZE)
+
+// s[0].k[1] = ip6tocidr(slice[2], i16@imm[3]).k[4]
+//
+// Parses each lane as an IPv6 address, clears the bits following the
+// first imm[3] bits and formats the result followed by "/imm[3]" the
+// way net/netip does: the first longest run of two or more zero groups
+// is replaced by "::", the groups are written in lowercase without
+// leading zeros and IPv4-mapped addresses end with a dotted quad.
+// Each lane gets a 48-byte chunk of the output buffer.
+//
+// Spill area layout:
+//
+//   0   - lanes that have not been processed yet (uint32)
+//   4   - valid lanes (uint32)
+//   8   - the "/n" suffix (up to 8 bytes)
+//   16  - the parsed address (8 x uint16)
+//   64  - input offsets, then output offsets
+//   128 - input lengths, then output lengths
+//   192 - the mask of the high 64 bits of the address
+//   200 - the mask of the low 64 bits of the address
+//   208 - the length of the suffix (uint32)
+//   212 - the offset of the output buffer (uint32)
+//
+// scratch: 48 * 16
+TEXT bcip6tocidr(SB), NOSPLIT|NOFRAME, $0
+  MOVL $(48*16), R15
+  BC_ALLOC_SLICE(OUT(Z8), IN(R15), CX, R8)             // Z8 <- Offset of the beginning of the allocated buffer
+  VMOVD X8, BC_SPILL_AREA(212)                         // [] <- Offset of the beginning of the allocated buffer
+
+  BC_UNPACK_SLOT(BC_SLOT_SIZE*2, OUT(BX))
+  BC_UNPACK_SLOT(BC_SLOT_SIZE*3 + BC_IMM16_SIZE, OUT(R8))
+  BC_LOAD_K1_FROM_SLOT(OUT(K1), IN(R8))
+  BC_LOAD_SLICE_FROM_SLOT_MASKED(OUT(Z2), OUT(Z3), IN(BX), IN(K1))
+  VMOVDQU32 Z2, BC_SPILL_AREA(64)                      // [] <- input offsets
+  VMOVDQU32 Z3, BC_SPILL_AREA(128)                     // [] <- input lengths
+  MOVL $0, BC_SPILL_AREA(4)                            // [] <- no valid lanes yet
+
+  BC_UNPACK_RU16(BC_SLOT_SIZE*3, OUT(R15))             // R15 <- prefix length
+  XORL DX, DX                                          // DX <- high mask
+  XORL R13, R13                                        // R13 <- low mask
+  MOVL $64, CX
+  SUBL R15, CX
+  JCS mask_low
+  TESTL R15, R15
+  JZ mask_done
+  MOVQ $-1, DX
+  SHLQ CX, DX                                          // DX <- -1 << (64 - n)
+  JMP mask_done
+
+mask_low:
+  MOVQ $-1, DX
+  MOVQ $-1, R13
+  MOVL $128, CX
+  SUBL R15, CX
+  SHLQ CX, R13                                         // R13 <- -1 << (128 - n)
+
+mask_done:
+  MOVQ DX, BC_SPILL_AREA(192)                          // [] <- high mask
+  MOVQ R13, BC_SPILL_AREA(200)                         // [] <- low mask
+
+  LEAQ CONST_GET_PTR(inet_ntoa_table, 0), R11
+  MOVL 0(R11)(R15*4), DX
+  SHLQ $8, DX
+  ORQ $0x2f, DX                                        // DX <- '/' followed by the digits of n
+  MOVQ DX, BC_SPILL_AREA(8)                            // [] <- suffix
+  MOVBLZX 1024(R11)(R15*1), DX                         // DX <- length of the digits and '.', which is the length of the suffix
+  MOVL DX, BC_SPILL_AREA(208)                          // [] <- suffix length
+
+  KMOVW K1, R8
+  TESTL R8, R8
+  JZ done
+
+#define IP6_TO_CIDR_OCTET(Shift)                                               \
+  MOVL R13, DX                                                                 \
+  SHRL $Shift, DX                                                              \
+  ANDL $0xff, DX                                                               \
+  MOVL 0(R15)(DX*4), CX                                                        \
+  MOVL CX, 0(R14)                                                              \
+  MOVBLZX 1024(R15)(DX*1), CX                                                  \
+  ADDQ CX, R14
+
+lane_loop:
+  TZCNTL R8, BX                                        // BX <- Index of the lane to process
+  BLSRL R8, R8                                         // R8 <- Clear the index of the iterator
+  MOVL R8, BC_SPILL_AREA(0)                            // R8 is used as a temporary from now on
+  MOVL BC_SPILL_AREA_INDEX(128, BX*4), CX              // CX <- Input length
+  MOVL BC_SPILL_AREA_INDEX(64, BX*4), R14
+  ADDQ VIRT_BASE, R14                                  // R14 <- Input pointer
+
+  IPV6_PARSE(lane_next)
+
+  MOVQ BC_SPILL_AREA(16), DX
+  BSWAPQ DX
+  ANDQ BC_SPILL_AREA(192), DX                          // DX <- high 64 bits of the network address
+  MOVQ BC_SPILL_AREA(24), R13
+  BSWAPQ R13
+  ANDQ BC_SPILL_AREA(200), R13                         // R13 <- low 64 bits of the network address
+
+  IMUL3L $48, BX, R14
+  ADDL BC_SPILL_AREA(212), R14
+  MOVL R14, BC_SPILL_AREA_INDEX(64, BX*4)              // [] <- output offset
+  ADDQ VIRT_BASE, R14                                  // R14 <- output pointer
+
+  TESTQ DX, DX
+  JNZ format_ip6
+  MOVQ R13, R11
+  SHRQ $32, R11
+  CMPL R11, $0xffff
+  JNE format_ip6
+
+  // IPv4-mapped address: "::ffff:a.b.c.d"
+  MOVQ $0x003a666666663a3a, R11
+  MOVQ R11, 0(R14)
+  ADDQ $7, R14
+  LEAQ CONST_GET_PTR(inet_ntoa_table, 0), R15          // R15 <- octet table
+  IP6_TO_CIDR_OCTET(24)
+  IP6_TO_CIDR_OCTET(16)
+  IP6_TO_CIDR_OCTET(8)
+  IP6_TO_CIDR_OCTET(0)
+  DECQ R14                                             // R14 <- end of the address (without the last '.')
+  JMP suffix
+
+format_ip6:
+  BSWAPQ DX
+  MOVQ DX, BC_SPILL_AREA(16)
+  BSWAPQ R13
+  MOVQ R13, BC_SPILL_AREA(24)                          // [] <- network address (big-endian)
+
+  // find the first longest run of two or more zero groups
+  MOVL $-1, R11                                        // R11 <- start of the longest zero run (none)
+  MOVL $1, R15                                         // R15 <- length of the longest zero run
+  XORL R8, R8                                          // R8 <- length of the current zero run
+  XORL CX, CX
+
+zero_run_loop:
+  CMPW BC_SPILL_AREA_INDEX(16, CX*2), $0
+  JNE zero_run_end
+  INCL R8
+  CMPL R8, R15
+  JBE zero_run_next
+  MOVL R8, R15
+  LEAL 1(CX), R11
+  SUBL R8, R11                                         // R11 <- start of the current zero run
+  JMP zero_run_next
+
+zero_run_end:
+  XORL R8, R8
+
+zero_run_next:
+  INCL CX
+  CMPL CX, $8
+  JB zero_run_loop
+
+  XORL CX, CX                                          // CX <- index of the group
+
+group_loop:
+  CMPL CX, R11
+  JNE group_separator
+  MOVW $0x3a3a, 0(R14)                                 // "::"
+  ADDQ $2, R14
+  ADDL R15, CX                                         // skip the zero run
+  CMPL CX, $8
+  JAE suffix
+  JMP group_hex
+
+group_separator:
+  TESTL CX, CX
+  JZ group_hex
+  MOVB $0x3a, 0(R14)                                   // ':'
+  INCQ R14
+
+group_hex:
+  MOVWLZX BC_SPILL_AREA_INDEX(16, CX*2), DX
+  ROLW $8, DX                                          // DX <- group value
+  MOVL DX, R8
+  ORL $1, R8
+  BSRL R8, R8
+  SHRL $2, R8                                          // R8 <- number of digits - 1
+  LEAQ 1(R14)(R8*1), R14                               // R14 <- end of the group
+  MOVQ R14, R13
+
+group_digit:
+  DECQ R13
+  MOVL DX, R8
+  ANDL $15, R8
+  ADDL $0x30, R8
+  CMPL R8, $0x39
+  JBE group_digit_store
+  ADDL $0x27, R8                                       // 'a' - '0' - 10
+
+group_digit_store:
+  MOVB R8, 0(R13)
+  SHRL $4, DX
+  JNZ group_digit
+
+  INCL CX
+  CMPL CX, $8
+  JB group_loop
+
+suffix:
+  MOVQ BC_SPILL_AREA(8), DX
+  MOVQ DX, 0(R14)
+  MOVL BC_SPILL_AREA(208), DX
+  ADDQ DX, R14
+  SUBQ VIRT_BASE, R14
+  SUBL BC_SPILL_AREA_INDEX(64, BX*4), R14
+  MOVL R14, BC_SPILL_AREA_INDEX(128, BX*4)             // [] <- output length
+  MOVL BC_SPILL_AREA(4), R8
+  BTSL BX, R8
+  MOVL R8, BC_SPILL_AREA(4)                            // [] <- add the lane to the valid lanes
+
+lane_next:
+  MOVL BC_SPILL_AREA(0), R8
+  TESTL R8, R8
+  JNZ lane_loop
+
+done:
+  KMOVW BC_SPILL_AREA(4), K1
+  VMOVDQU32.Z BC_SPILL_AREA(64), K1, Z2                // Z2 <- output offsets
+  VMOVDQU32.Z BC_SPILL_AREA(128), K1, Z3               // Z3 <- output lengths
+  BC_UNPACK_2xSLOT(0, OUT(DX), OUT(R8))
+  BC_STORE_SLICE_TO_SLOT(IN(Z2), IN(Z3), IN(DX))
+  BC_STORE_K_TO_SLOT(IN(K1), IN(R8))
+  NEXT_ADVANCE(BC_SLOT_SIZE*4 + BC_IMM16_SIZE)
+
+  _BC_ERROR_HANDLER_MORE_SCRATCH()
+
+#undef IP6_TO_CIDR_OCTET
+#undef IPV6_PARSE
+#undef IPV4_PARSE
+
+CONST_DATA_U64(inet_ntoa_table, 0, $0x00002e3100002e30)
+CONST_DATA_U64(inet_ntoa_table, 8, $0x00002e3300002e32)
+CONST_DATA_U64(inet_ntoa_table, 16, $0x00002e3500002e34)
+CONST_DATA_U64(inet_ntoa_table, 24, $0x00002e3700002e36)
+CONST_DATA_U64(inet_ntoa_table, 32, $0x00002e3900002e38)
+CONST_DATA_U64(inet_ntoa_table, 40, $0x002e3131002e3031)
+CONST_DATA_U64(inet_ntoa_table, 48, $0x002e3331002e3231)
+CONST_DATA_U64(inet_ntoa_table, 56, $0x002e3531002e3431)
+CONST_DATA_U64(inet_ntoa_table, 64, $0x002e3731002e3631)
+CONST_DATA_U64(inet_ntoa_table, 72, $0x002e3931002e3831)
+CONST_DATA_U64(inet_ntoa_table, 80, $0x002e3132002e3032)
+CONST_DATA_U64(inet_ntoa_table, 88, $0x002e3332002e3232)
+CONST_DATA_U64(inet_ntoa_table, 96, $0x002e3532002e3432)
+CONST_DATA_U64(inet_ntoa_table, 104, $0x002e3732002e3632)
+CONST_DATA_U64(inet_ntoa_table, 112, $0x002e3932002e3832)
+CONST_DATA_U64(inet_ntoa_table, 120, $0x002e3133002e3033)
+CONST_DATA_U64(inet_ntoa_table, 128, $0x002e3333002e3233)
+CONST_DATA_U64(inet_ntoa_table, 136, $0x002e3533002e3433)
+CONST_DATA_U64(inet_ntoa_table, 144, $0x002e3733002e3633)
+CONST_DATA_U64(inet_ntoa_table, 152, $0x002e3933002e3833)
+CONST_DATA_U64(inet_ntoa_table, 160, $0x002e3134002e3034)
+CONST_DATA_U64(inet_ntoa_table, 168, $0x002e3334002e3234)
+CONST_DATA_U64(inet_ntoa_table, 176, $0x002e3534002e3434)
+CONST_DATA_U64(inet_ntoa_table, 184, $0x002e3734002e3634)
+CONST_DATA_U64(inet_ntoa_table, 192, $0x002e3934002e3834)
+CONST_DATA_U64(inet_ntoa_table, 200, $0x002e3135002e3035)
+CONST_DATA_U64(inet_ntoa_table, 208, $0x002e3335002e3235)
+CONST_DATA_U64(inet_ntoa_table, 216, $0x002e3535002e3435)
+CONST_DATA_U64(inet_ntoa_table, 224, $0x002e3735002e3635)
+CONST_DATA_U64(inet_ntoa_table, 232, $0x002e3935002e3835)
+CONST_DATA_U64(inet_ntoa_table, 240, $0x002e3136002e3036)
+CONST_DATA_U64(inet_ntoa_table, 248, $0x002e3336002e3236)
+CONST_DATA_U64(inet_ntoa_table, 256, $0x002e3536002e3436)
+CONST_DATA_U64(inet_ntoa_table, 264, $0x002e3736002e3636)
+CONST_DATA_U64(inet_ntoa_table, 272, $0x002e3936002e3836)
+CONST_DATA_U64(inet_ntoa_table, 280, $0x002e3137002e3037)
+CONST_DATA_U64(inet_ntoa_table, 288, $0x002e3337002e3237)
+CONST_DATA_U64(inet_ntoa_table, 296, $0x002e3537002e3437)
+CONST_DATA_U64(inet_ntoa_table, 304, $0x002e3737002e3637)
+CONST_DATA_U64(inet_ntoa_table, 312, $0x002e3937002e3837)
+CONST_DATA_U64(inet_ntoa_table, 320, $0x002e3138002e3038)
+CONST_DATA_U64(inet_ntoa_table, 328, $0x002e3338002e3238)
+CONST_DATA_U64(inet_ntoa_table, 336, $0x002e3538002e3438)
+CONST_DATA_U64(inet_ntoa_table, 344, $0x002e3738002e3638)
+CONST_DATA_U64(inet_ntoa_table, 352, $0x002e3938002e3838)
+CONST_DATA_U64(inet_ntoa_table, 360, $0x002e3139002e3039)
+CONST_DATA_U64(inet_ntoa_table, 368, $0x002e3339002e3239)
+CONST_DATA_U64(inet_ntoa_table, 376, $0x002e3539002e3439)
+CONST_DATA_U64(inet_ntoa_table, 384, $0x002e3739002e3639)
+CONST_DATA_U64(inet_ntoa_table, 392, $0x002e3939002e3839)
+CONST_DATA_U64(inet_ntoa_table, 400, $0x2e3130312e303031)
+CONST_DATA_U64(inet_ntoa_table, 408, $0x2e3330312e323031)
+CONST_DATA_U64(inet_ntoa_table, 416, $0x2e3530312e343031)
+CONST_DATA_U64(inet_ntoa_table, 424, $0x2e3730312e363031)
+CONST_DATA_U64(inet_ntoa_table, 432, $0x2e3930312e383031)
+CONST_DATA_U64(inet_ntoa_table, 440, $0x2e3131312e303131)
+CONST_DATA_U64(inet_ntoa_table, 448, $0x2e3331312e323131)
+CONST_DATA_U64(inet_ntoa_table, 456, $0x2e3531312e343131)
+CONST_DATA_U64(inet_ntoa_table, 464, $0x2e3731312e363131)
+CONST_DATA_U64(inet_ntoa_table, 472, $0x2e3931312e383131)
+CONST_DATA_U64(inet_ntoa_table, 480, $0x2e3132312e303231)
+CONST_DATA_U64(inet_ntoa_table, 488, $0x2e3332312e323231)
+CONST_DATA_U64(inet_ntoa_table, 496, $0x2e3532312e343231)
+CONST_DATA_U64(inet_ntoa_table, 504, $0x2e3732312e363231)
+CONST_DATA_U64(inet_ntoa_table, 512, $0x2e3932312e383231)
+CONST_DATA_U64(inet_ntoa_table, 520, $0x2e3133312e303331)
+CONST_DATA_U64(inet_ntoa_table, 528, $0x2e3333312e323331)
+CONST_DATA_U64(inet_ntoa_table, 536, $0x2e3533312e343331)
+CONST_DATA_U64(inet_ntoa_table, 544, $0x2e3733312e363331)
+CONST_DATA_U64(inet_ntoa_table, 552, $0x2e3933312e383331)
+CONST_DATA_U64(inet_ntoa_table, 560, $0x2e3134312e303431)
+CONST_DATA_U64(inet_ntoa_table, 568, $0x2e3334312e323431)
+CONST_DATA_U64(inet_ntoa_table, 576, $0x2e3534312e343431)
+CONST_DATA_U64(inet_ntoa_table, 584, $0x2e3734312e363431)
+CONST_DATA_U64(inet_ntoa_table, 592, $0x2e3934312e383431)
+CONST_DATA_U64(inet_ntoa_table, 600, $0x2e3135312e303531)
+CONST_DATA_U64(inet_ntoa_table, 608, $0x2e3335312e323531)
+CONST_DATA_U64(inet_ntoa_table, 616, $0x2e3535312e343531)
+CONST_DATA_U64(inet_ntoa_table, 624, $0x2e3735312e363531)
+CONST_DATA_U64(inet_ntoa_table, 632, $0x2e3935312e383531)
+CONST_DATA_U64(inet_ntoa_table, 640, $0x2e3136312e303631)
+CONST_DATA_U64(inet_ntoa_table, 648, $0x2e3336312e323631)
+CONST_DATA_U64(inet_ntoa_table, 656, $0x2e3536312e343631)
+CONST_DATA_U64(inet_ntoa_table, 664, $0x2e3736312e363631)
+CONST_DATA_U64(inet_ntoa_table, 672, $0x2e3936312e383631)
+CONST_DATA_U64(inet_ntoa_table, 680, $0x2e3137312e303731)
+CONST_DATA_U64(inet_ntoa_table, 688, $0x2e3337312e323731)
+CONST_DATA_U64(inet_ntoa_table, 696, $0x2e3537312e343731)
+CONST_DATA_U64(inet_ntoa_table, 704, $0x2e3737312e363731)
+CONST_DATA_U64(inet_ntoa_table, 712, $0x2e3937312e383731)
+CONST_DATA_U64(inet_ntoa_table, 720, $0x2e3138312e303831)
+CONST_DATA_U64(inet_ntoa_table, 728, $0x2e3338312e323831)
+CONST_DATA_U64(inet_ntoa_table, 736, $0x2e3538312e343831)
+CONST_DATA_U64(inet_ntoa_table, 744, $0x2e3738312e363831)
+CONST_DATA_U64(inet_ntoa_table, 752, $0x2e3938312e383831)
+CONST_DATA_U64(inet_ntoa_table, 760, $0x2e3139312e303931)
+CONST_DATA_U64(inet_ntoa_table, 768, $0x2e3339312e323931)
+CONST_DATA_U64(inet_ntoa_table, 776, $0x2e3539312e343931)
+CONST_DATA_U64(inet_ntoa_table, 784, $0x2e3739312e363931)
+CONST_DATA_U64(inet_ntoa_table, 792, $0x2e3939312e383931)
+CONST_DATA_U64(inet_ntoa_table, 800, $0x2e3130322e303032)
+CONST_DATA_U64(inet_ntoa_table, 808, $0x2e3330322e323032)
+CONST_DATA_U64(inet_ntoa_table, 816, $0x2e3530322e343032)
+CONST_DATA_U64(inet_ntoa_table, 824, $0x2e3730322e363032)
+CONST_DATA_U64(inet_ntoa_table, 832, $0x2e3930322e383032)
+CONST_DATA_U64(inet_ntoa_table, 840, $0x2e3131322e303132)
+CONST_DATA_U64(inet_ntoa_table, 848, $0x2e3331322e323132)
+CONST_DATA_U64(inet_ntoa_table, 856, $0x2e3531322e343132)
+CONST_DATA_U64(inet_ntoa_table, 864, $0x2e3731322e363132)
+CONST_DATA_U64(inet_ntoa_table, 872, $0x2e3931322e383132)
+CONST_DATA_U64(inet_ntoa_table, 880, $0x2e3132322e303232)
+CONST_DATA_U64(inet_ntoa_table, 888, $0x2e3332322e323232)
+CONST_DATA_U64(inet_ntoa_table, 896, $0x2e3532322e343232)
+CONST_DATA_U64(inet_ntoa_table, 904, $0x2e3732322e363232)
+CONST_DATA_U64(inet_ntoa_table, 912, $0x2e3932322e383232)
+CONST_DATA_U64(inet_ntoa_table, 920, $0x2e3133322e303332)
+CONST_DATA_U64(inet_ntoa_table, 928, $0x2e3333322e323332)
+CONST_DATA_U64(inet_ntoa_table, 936, $0x2e3533322e343332)
+CONST_DATA_U64(inet_ntoa_table, 944, $0x2e3733322e363332)
+CONST_DATA_U64(inet_ntoa_table, 952, $0x2e3933322e383332)
+CONST_DATA_U64(inet_ntoa_table, 960, $0x2e3134322e303432)
+CONST_DATA_U64(inet_ntoa_table, 968, $0x2e3334322e323432)
+CONST_DATA_U64(inet_ntoa_table, 976, $0x2e3534322e343432)
+CONST_DATA_U64(inet_ntoa_table, 984, $0x2e3734322e363432)
+CONST_DATA_U64(inet_ntoa_table, 992, $0x2e3934322e383432)
+CONST_DATA_U64(inet_ntoa_table, 1000, $0x2e3135322e303532)
+CONST_DATA_U64(inet_ntoa_table, 1008, $0x2e3335322e323532)
+CONST_DATA_U64(inet_ntoa_table, 1016, $0x2e3535322e343532)
+CONST_DATA_U64(inet_ntoa_table, 1024, $0x0202020202020202)
+CONST_DATA_U64(inet_ntoa_table, 1032, $0x0303030303030202)
+CONST_DATA_U64(inet_ntoa_table, 1040, $0x0303030303030303)
+CONST_DATA_U64(inet_ntoa_table, 1048, $0x0303030303030303)
+CONST_DATA_U64(inet_ntoa_table, 1056, $0x0303030303030303)
+CONST_DATA_U64(inet_ntoa_table, 1064, $0x0303030303030303)
+CONST_DATA_U64(inet_ntoa_table, 1072, $0x0303030303030303)
+CONST_DATA_U64(inet_ntoa_table, 1080, $0x0303030303030303)
+CONST_DATA_U64(inet_ntoa_table, 1088, $0x0303030303030303)
+CONST_DATA_U64(inet_ntoa_table, 1096, $0x0303030303030303)
+CONST_DATA_U64(inet_ntoa_table, 1104, $0x0303030303030303)
+CONST_DATA_U64(inet_ntoa_table, 1112, $0x0303030303030303)
+CONST_DATA_U64(inet_ntoa_table, 1120, $0x0404040403030303)
+CONST_DATA_U64(inet_ntoa_table, 1128, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1136, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1144, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1152, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1160, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1168, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1176, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1184, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1192, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1200, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1208, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1216, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1224, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1232, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1240, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1248, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1256, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1264, $0x0404040404040404)
+CONST_DATA_U64(inet_ntoa_table, 1272, $0x0404040404040404)
+CONST_GLOBAL(inet_ntoa_table, $1280)
@@ -242,6 +242,9 @@ tail:
   SUBQ    R9, CX
   JNZ     loop
 ret:
+  // DI includes the lanes of a partially-projected
+  // block, which are already counted in R9
+  MOVQ    DI, ret+80(FP)
   MOVQ    dst+32(FP), DI
   SUBQ    DI, ret+80(FP)
   MOVQ    R9, ret1+88(FP)
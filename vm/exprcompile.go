@@ -849,9 +849,15 @@ func compilefuncaux(p *prog, b *expr.Builtin, args []expr.Node) (*value, error)
 		maxStr, _ := args[1].(expr.String)
 		lhs := v[2]
 
+		minIP := net.ParseIP(string(minStr))
+		maxIP := net.ParseIP(string(maxStr))
+		if minIP.To4() == nil {
+			return p.isSubnetOfIP6(lhs, *(*[16]byte)(minIP.To16()), *(*[16]byte)(maxIP.To16())), nil
+		}
+
 		// the min/max are byte wise min/max values encoded as a string with dot as a separator.
-		min := (*[4]byte)(net.ParseIP(string(minStr)).To4())
-		max := (*[4]byte)(net.ParseIP(string(maxStr)).To4())
+		min := (*[4]byte)(minIP.To4())
+		max := (*[4]byte)(maxIP.To4())
 
 		return p.isSubnetOfIP4(lhs, *min, *max), nil
 
@@ -1032,6 +1038,89 @@ func compilefuncaux(p *prog, b *expr.Builtin, args []expr.Node) (*value, error)
 		}
 		return v, nil
 
+	case expr.MD5, expr.SHA1, expr.SHA256, expr.XXHash64,
+		expr.ToHex, expr.FromHex, expr.ToBase64, expr.FromBase64:
+		vals, err := compileargs(p, args, compileString)
+		if err != nil {
+			return nil, err
+		}
+
+		var op ssaop
+		switch fn {
+		case expr.MD5:
+			op = smd5str
+		case expr.SHA1:
+			op = ssha1str
+		case expr.SHA256:
+			op = ssha256str
+		case expr.XXHash64:
+			op = sxxhash64str
+		case expr.ToHex:
+			op = stohexstr
+		case expr.FromHex:
+			op = sfromhexstr
+		case expr.ToBase64:
+			op = stobase64str
+		case expr.FromBase64:
+			op = sfrombase64str
+		}
+		return p.strfunc(op, vals[0]), nil
+
+	case expr.URLExtractHost, expr.URLExtractPath, expr.URLExtractQuery, expr.URLDecode, expr.InetAton:
+		vals, err := compileargs(p, args, compileString)
+		if err != nil {
+			return nil, err
+		}
+
+		var op ssaop
+		switch fn {
+		case expr.URLExtractHost:
+			op = surlhost
+		case expr.URLExtractPath:
+			op = surlpath
+		case expr.URLExtractQuery:
+			op = surlquery
+		case expr.URLDecode:
+			op = surldecode
+		case expr.InetAton:
+			op = sinetaton
+		}
+		return p.strfunc(op, vals[0]), nil
+
+	case expr.URLExtractQueryParameter:
+		vals, err := compileargs(p, args, compileString, literalString)
+		if err != nil {
+			return nil, err
+		}
+		return p.urlQueryParam(vals[0], string(args[1].(expr.String))), nil
+
+	case expr.InetNtoa:
+		vals, err := compileargs(p, args, compileNumber)
+		if err != nil {
+			return nil, err
+		}
+		return p.inetNtoa(vals[0]), nil
+
+	case expr.IP6ToCIDR:
+		vals, err := compileargs(p, args, compileString, constInteger)
+		if err != nil {
+			return nil, err
+		}
+		n := int(args[1].(expr.Integer))
+		if n < 0 || n > 128 {
+			return nil, fmt.Errorf("prefix length %d is out of range [0, 128]", n)
+		}
+		return p.ip6ToCIDR(vals[0], n), nil
+
+	case expr.Fingerprint:
+		vals, err := compileargs(p, args, compileValue)
+		if err != nil {
+			return nil, err
+		}
+		// lists and structures do not have a fingerprint
+		v := p.checkTag(vals[0], expr.NullType|expr.BoolType|expr.NumericType|expr.TimeType|expr.StringType|expr.SymbolType)
+		return p.fingerprint(v), nil
+
 	case expr.MakeList:
 		if len(args) == 0 {
 			return nil, fmt.Errorf("%s failed to perform constant propagation (empty list must be a constant)", fn)
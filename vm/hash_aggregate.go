@@ -38,18 +38,24 @@ type HashAggregate struct {
 	by        Selection
 	dst       QuerySink
 	skipEmpty bool
+	budget    *Budget
 
 	aggregateOps []AggregateOp
 	initialData  []byte
 
-	lock  sync.Mutex
-	final *aggtable
-	limit int
+	lock   sync.Mutex
+	final  *aggtable
+	spills []*aggspill // partial aggregates spilled by each thread
+	limit  int
 
 	// ordering functions;
 	// applied in order to determine
 	// the total ordering
 	order []aggOrderFn
+	// ordercols is the equivalent ordering
+	// of the output rows, used when the
+	// aggregate has been spilled
+	ordercols []SortColumn
 
 	windows []window
 }
@@ -156,6 +162,7 @@ func (h *HashAggregate) OrderByGroup(n int, ordering SortOrdering) error {
 		return fmt.Errorf("group %d doesn't exist", n)
 	}
 	h.order = append(h.order, h.groupFn(n, ordering))
+	h.ordercols = append(h.ordercols, SortColumn{Node: expr.Ident(h.by[n].Result()), Ordering: ordering})
 	return nil
 }
 
@@ -168,6 +175,7 @@ func (h *HashAggregate) OrderByAggregate(n int, ordering SortOrdering) error {
 		NullsOrder: SortNullsFirst,
 	}
 	h.order = append(h.order, h.aggFn(n, o))
+	h.ordercols = append(h.ordercols, SortColumn{Node: expr.Ident(h.agg[n].Result), Ordering: o})
 	return nil
 }
 
@@ -187,6 +195,12 @@ func (h *HashAggregate) SetSkipEmpty(skip bool) {
 	h.skipEmpty = skip
 }
 
+// SetBudget sets the Budget to which the memory
+// used by the aggregate's tables is charged.
+func (h *HashAggregate) SetBudget(b *Budget) {
+	h.budget = b
+}
+
 func NewHashAggregate(agg, windows Aggregation, by Selection, dst QuerySink) (*HashAggregate, error) {
 	if len(by) == 0 {
 		return nil, fmt.Errorf("cannot aggregate an empty selection")
@@ -278,7 +292,7 @@ func NewHashAggregate(agg, windows Aggregation, by Selection, dst QuerySink) (*H
 			out[i] = prog.aggregateSlotCount(mem, bucket, mask, offset)
 			ops[i].fn = AggregateOpCount
 
-		case expr.OpApproxCountDistinct:
+		case expr.OpApproxCountDistinct, expr.OpApproxCountDistinctState, expr.OpHLLMerge, expr.OpHLLEstimate:
 			argv, err := compile(prog, a.Inner)
 			if err != nil {
 				return nil, fmt.Errorf("cannot compile %q: %w", a.Inner, err)
@@ -287,14 +301,78 @@ func NewHashAggregate(agg, windows Aggregation, by Selection, dst QuerySink) (*H
 
 			ops[i].precision = precision
 			ops[i].fn = AggregateOpApproxCountDistinct
-			ops[i].role = a.Role
-			switch a.Role {
+			ops[i].role, ops[i].keepstate = stateRole(a)
+			switch ops[i].role {
 			case expr.AggregateRoleFinal, expr.AggregateRolePartial:
 				out[i] = prog.aggregateSlotApproxCountDistinct(mem, bucket, argv, mask, offset, precision)
 			case expr.AggregateRoleMerge:
 				out[i] = prog.aggregateSlotMergeState(bucket, argv, mask, offset+aggregateslot(ops[i].dataSize()))
 			}
 
+		case expr.OpApproxPercentileState:
+			argv, err := prog.compileAsNumber(a.Inner)
+			if err != nil {
+				return nil, fmt.Errorf("don't know how to aggregate %q: %w", a.Inner, err)
+			}
+			// the values are added to the state of each
+			// group one at a time, so the state is written
+			// out like the merged state of TDIGEST_MERGE
+			ops[i].fn = AggregateOpTDigest
+			ops[i].role = expr.AggregateRoleMerge
+			ops[i].keepstate = true
+			ops[i].mergevalues = true
+			out[i] = prog.aggregateSlotTDigest(bucket, argv, mask, offset+aggregateslot(ops[i].dataSize()))
+
+		case expr.OpTDigestMerge, expr.OpTDigestQuantile:
+			argv, err := compile(prog, a.Inner)
+			if err != nil {
+				return nil, fmt.Errorf("cannot compile %q: %w", a.Inner, err)
+			}
+			ops[i].fn = AggregateOpTDigest
+			ops[i].misc = a.Misc
+			ops[i].role, ops[i].keepstate = stateRole(a)
+			out[i] = prog.aggregateSlotMergeState(bucket, argv, mask, offset+aggregateslot(ops[i].dataSize()))
+
+		case expr.OpVarianceSamp, expr.OpStdDevSamp, expr.OpSkewness, expr.OpKurtosis:
+			ops[i].fn = AggregateOpMomentsF
+			ops[i].stat = op
+			ops[i].role = a.Role
+			if a.Role == expr.AggregateRoleMerge {
+				state, err := compile(prog, a.Inner)
+				if err != nil {
+					return nil, fmt.Errorf("cannot compile %q: %w", a.Inner, err)
+				}
+				out[i] = prog.aggregateSlotMergeState(bucket, state, mask, offset+aggregateslot(ops[i].dataSize()))
+				break
+			}
+			argv, err := prog.compileAsNumber(a.Inner)
+			if err != nil {
+				return nil, fmt.Errorf("don't know how to aggregate %q: %w", a.Inner, err)
+			}
+			out[i] = prog.aggregateSlotMoments(mem, bucket, argv, mask, offset)
+
+		case expr.OpCovarPop, expr.OpCovarSamp, expr.OpCorr, expr.OpRegrSlope, expr.OpRegrIntercept, expr.OpRegrR2:
+			ops[i].fn = AggregateOpCoMomentsF
+			ops[i].stat = op
+			ops[i].role = a.Role
+			if a.Role == expr.AggregateRoleMerge {
+				state, err := compile(prog, a.Inner)
+				if err != nil {
+					return nil, fmt.Errorf("cannot compile %q: %w", a.Inner, err)
+				}
+				out[i] = prog.aggregateSlotMergeState(bucket, state, mask, offset+aggregateslot(ops[i].dataSize()))
+				break
+			}
+			y, err := prog.compileAsNumber(a.Inner)
+			if err != nil {
+				return nil, fmt.Errorf("don't know how to aggregate %q: %w", a.Inner, err)
+			}
+			x, err := prog.compileAsNumber(a.Arg)
+			if err != nil {
+				return nil, fmt.Errorf("don't know how to aggregate %q: %w", a.Arg, err)
+			}
+			out[i] = prog.aggregateSlotCoMoments(mem, bucket, y, x, mask, offset)
+
 		case expr.OpBoolAnd, expr.OpBoolOr:
 			argv, err := compile(prog, h.agg[i].Expr.Inner)
 			if err != nil {
@@ -391,7 +469,7 @@ func NewHashAggregate(agg, windows Aggregation, by Selection, dst QuerySink) (*H
 	}
 
 	initialData := make([]byte, offset)
-	initAggregateValues(initialData, ops)
+	initHashAggregateValues(initialData, ops)
 
 	h.aggregateOps = ops
 	h.initialData = initialData
@@ -400,14 +478,48 @@ func NewHashAggregate(agg, windows Aggregation, by Selection, dst QuerySink) (*H
 	return h, nil
 }
 
-func (h *HashAggregate) Open() (io.WriteCloser, error) {
-	at := &aggtable{
+// initHashAggregateValues is initAggregateValues
+// for the HashAggregate layout, where the merge buffer
+// of an op follows its state rather than preceding it
+func initHashAggregateValues(data []byte, ops []AggregateOp) {
+	for i := range ops {
+		op := &ops[i]
+		initAggregateValue(data, op)
+		data = data[op.dataSize():]
+		if op.mergestate() {
+			data = data[aggregateOpMergeBufferSize:]
+		}
+	}
+}
+
+// mergeHashAggregatedValues is mergeAggregatedValues
+// for the HashAggregate layout (see initHashAggregateValues)
+func mergeHashAggregatedValues(dst, src []byte, ops []AggregateOp) {
+	for i := range ops {
+		op := &ops[i]
+		mergeAggregatedValue(dst, src, op)
+		n := op.dataSize()
+		if op.mergestate() {
+			n += aggregateOpMergeBufferSize
+		}
+		dst = dst[n:]
+		src = src[n:]
+	}
+}
+
+func (h *HashAggregate) newTable() *aggtable {
+	return &aggtable{
 		parent:       h,
 		tree:         newRadixTree(len(h.initialData)),
 		aggregateOps: h.aggregateOps,
 		mergestate:   mergestate(h.aggregateOps),
+		values:       make(map[int][]float32),
+		mem:          memacct{budget: h.budget},
 	}
+}
 
+func (h *HashAggregate) Open() (io.WriteCloser, error) {
+	at := h.newTable()
 	atomic.AddInt64(&h.children, 1)
 	return splitter(at), nil
 }
@@ -449,46 +561,15 @@ func (h *HashAggregate) Close() error {
 		return flushEmpty(h.dst)
 	}
 
-	var outst ion.Symtab
-	var outbuf ion.Buffer
-
-	var aggsyms []ion.Symbol
-	var bysyms []ion.Symbol
-	var windowsyms []ion.Symbol
-
-	for i := range h.by {
-		bysyms = append(bysyms, outst.Intern(h.by[i].Result()))
-	}
-	for i := range h.agg {
-		aggsyms = append(aggsyms, outst.Intern(h.agg[i].Result))
-	}
-	for i := range h.windows {
-		windowsyms = append(windowsyms, outst.Intern(h.windows[i].result))
+	if len(h.spills) > 0 {
+		return h.closeSpilled()
 	}
-	outst.Marshal(&outbuf, true)
 
-	hasfinalize := false
-	for i := range h.final.pairs {
-		p := &h.final.pairs[i]
-		valmem := h.final.valueof(p)
-		offset := 0
-		for j := range h.aggregateOps {
-			op := h.aggregateOps[j]
-			if finalize := aggregateOpInfoTable[op.fn].finalizeFunc; finalize != nil && !op.savestate() {
-				buf := valmem[offset:]
-				finalize(buf)
-				hasfinalize = true
-			}
-			offset += op.dataSize()
-			if op.mergestate() {
-				offset += aggregateOpMergeBufferSize
-			}
-		}
+	out := h.output()
+	var outbuf ion.Buffer
+	out.st.Marshal(&outbuf, true)
 
-		if !hasfinalize {
-			break // no finalize found in the first iteration, exit early
-		}
-	}
+	h.finalize(h.final)
 
 	// compute final window results
 	for i := range h.windows {
@@ -499,38 +580,11 @@ func (h *HashAggregate) Close() error {
 	if h.limit > 0 && len(order) > h.limit {
 		order = order[:h.limit]
 	}
-
-	// turn the i'th 'agg' output
-	// into an offset
-	offset := make([]int, len(h.aggregateOps))
-	off := 0
-	for i, op := range h.aggregateOps {
-		offset[i] = off
-		if op.mergestate() {
-			off += aggregateOpMergeBufferSize
-		}
-		off += op.dataSize()
-	}
-
 	for _, n := range order {
-		p := &h.final.pairs[n]
-		outbuf.BeginStruct(-1)
-		valmem := h.final.valueof(p)
-		for j, sym := range bysyms {
-			outbuf.BeginField(sym)
-			outbuf.UnsafeAppend(h.final.repridx(p, j))
-		}
-		for j, sym := range aggsyms {
-			outbuf.BeginField(sym)
-			writeAggregatedValue(&outbuf, valmem[offset[j]:], h.aggregateOps[j])
-		}
-		for j, sym := range windowsyms {
-			outbuf.BeginField(sym)
-			outbuf.WriteUint(uint64(h.windows[j].final[n]))
-		}
-		outbuf.EndStruct()
+		out.write(&outbuf, h.final, n)
 	}
 
+	h.final.mem.release()
 	h.final = nil
 	// finally, write the output...
 	dst, err := h.dst.Open()
@@ -559,6 +613,94 @@ func (h *HashAggregate) Close() error {
 	return err
 }
 
+// aggoutput is the symbol table and
+// field symbols for the output rows
+type aggoutput struct {
+	parent *HashAggregate
+
+	st         ion.Symtab
+	bysyms     []ion.Symbol
+	aggsyms    []ion.Symbol
+	windowsyms []ion.Symbol
+
+	// offset[i] is the offset of the
+	// i'th aggregate within the value memory
+	offset []int
+}
+
+func (h *HashAggregate) output() *aggoutput {
+	out := &aggoutput{parent: h}
+	for i := range h.by {
+		out.bysyms = append(out.bysyms, out.st.Intern(h.by[i].Result()))
+	}
+	for i := range h.agg {
+		out.aggsyms = append(out.aggsyms, out.st.Intern(h.agg[i].Result))
+	}
+	for i := range h.windows {
+		out.windowsyms = append(out.windowsyms, out.st.Intern(h.windows[i].result))
+	}
+	// turn the i'th 'agg' output
+	// into an offset
+	out.offset = make([]int, len(h.aggregateOps))
+	off := 0
+	for i, op := range h.aggregateOps {
+		out.offset[i] = off
+		if op.mergestate() {
+			off += aggregateOpMergeBufferSize
+		}
+		off += op.dataSize()
+	}
+	return out
+}
+
+// write writes the n'th group of t as a row
+func (o *aggoutput) write(dst *ion.Buffer, t *aggtable, n int) {
+	h := o.parent
+	p := &t.pairs[n]
+	dst.BeginStruct(-1)
+	valmem := t.valueof(p)
+	for j, sym := range o.bysyms {
+		dst.BeginField(sym)
+		dst.UnsafeAppend(t.repridx(p, j))
+	}
+	for j, sym := range o.aggsyms {
+		dst.BeginField(sym)
+		writeAggregatedValue(dst, valmem[o.offset[j]:], h.aggregateOps[j])
+	}
+	for j, sym := range o.windowsyms {
+		dst.BeginField(sym)
+		dst.WriteUint(uint64(h.windows[j].final[n]))
+	}
+	dst.EndStruct()
+}
+
+// finalize applies the final step of each
+// aggregate operation to the values in t
+func (h *HashAggregate) finalize(t *aggtable) {
+	hasfinalize := false
+	for i := range t.pairs {
+		p := &t.pairs[i]
+		valmem := t.valueof(p)
+		offset := 0
+		for j := range h.aggregateOps {
+			op := h.aggregateOps[j]
+			if finalize := aggregateOpInfoTable[op.fn].finalizeFunc; finalize != nil && !op.savestate() {
+				buf := valmem[offset:]
+				finalize(buf)
+				hasfinalize = true
+			}
+			offset += op.dataSize()
+			if op.mergestate() {
+				offset += aggregateOpMergeBufferSize
+			}
+		}
+
+		if !hasfinalize {
+			break // no finalize found in the first iteration, exit early
+		}
+	}
+}
+
 // open a stream on dst, write 0 rows into it, and then close it
 func flushEmpty(dst QuerySink) error {
 	var b ion.Buffer
@@ -0,0 +1,335 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"unsafe"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// AggregateSpillMemory is the number of bytes of
+// groups and values that each thread of a hash aggregate
+// (GROUP BY) holds in memory before it spills its partial
+// aggregates to temporary files in SpillDir.
+// Spilled aggregates are re-aggregated one partition
+// at a time when the aggregate is closed.
+// Zero disables spilling.
+//
+// Aggregates with window functions are never spilled.
+var AggregateSpillMemory = MaxAggregateMemory / 2
+
+const (
+	// spilled groups are partitioned
+	// by the top aggPartitionBits bits of
+	// their (rotation-invariant) hash
+	aggPartitionBits = 6
+	aggPartitions    = 1 << aggPartitionBits
+
+	// size of the buffered writer
+	// for each spilled partition
+	aggSpillBuffer = 16 * 1024
+)
+
+// aggPartition returns the partition for a hash;
+// the radix tree stores either the hash or the hash
+// rotated by 32 bits, so the partition must be the same
+// for both
+func aggPartition(h uint64) int {
+	return int((h ^ bits.RotateLeft64(h, 32)) >> (64 - aggPartitionBits))
+}
+
+// aggspill is a set of partitioned
+// partial aggregates in temporary files
+//
+// Each group is written as a 64-bit hash,
+// a 32-bit length, the grouping columns and
+// then the aggregate value memory.
+type aggspill struct {
+	files [aggPartitions]*os.File
+	w     [aggPartitions]*bufio.Writer
+	hdr   [12]byte
+}
+
+func (s *aggspill) write(hash uint64, repr, value []byte) error {
+	part := aggPartition(hash)
+	w := s.w[part]
+	if w == nil {
+		f, err := createSpill("aggregate")
+		if err != nil {
+			return err
+		}
+		s.files[part] = f
+		w = bufio.NewWriterSize(f, aggSpillBuffer)
+		s.w[part] = w
+	}
+	binary.LittleEndian.PutUint64(s.hdr[:], hash)
+	binary.LittleEndian.PutUint32(s.hdr[8:], uint32(len(repr)))
+	w.Write(s.hdr[:])
+	w.Write(repr)
+	_, err := w.Write(value)
+	return err
+}
+
+func (s *aggspill) flush() error {
+	for _, w := range s.w {
+		if w == nil {
+			continue
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read calls fn for each group spilled to a partition
+func (s *aggspill) read(part, valsize int, fn func(hash uint64, repr, value []byte)) error {
+	f := s.files[part]
+	if f == nil {
+		return nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReaderSize(f, aggSpillBuffer)
+	var buf []byte
+	for {
+		_, err := io.ReadFull(r, s.hdr[:])
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading spilled aggregate: %w", err)
+		}
+		hash := binary.LittleEndian.Uint64(s.hdr[:])
+		size := int(binary.LittleEndian.Uint32(s.hdr[8:]))
+		buf = slices.Grow(buf[:0], size+valsize)[:size+valsize]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return fmt.Errorf("reading spilled aggregate: %w", err)
+		}
+		fn(hash, buf[:size], buf[size:])
+	}
+}
+
+// release removes the temporary files
+func (s *aggspill) release() {
+	for i, f := range s.files {
+		if f != nil {
+			removeSpill(f)
+			s.files[i] = nil
+		}
+	}
+}
+
+// canSpill returns true if the table can be spilled
+func (a *aggtable) canSpill() bool {
+	return AggregateSpillMemory > 0 && len(a.parent.windows) == 0
+}
+
+// overBudget returns true if the table
+// should be spilled
+func (a *aggtable) overBudget() bool {
+	if !a.canSpill() {
+		return false
+	}
+	return len(a.repr)+len(a.tree.values) >= AggregateSpillMemory ||
+		len(a.pairs) >= MaxAggregateBuckets/2
+}
+
+// size returns the memory used by the table
+func (a *aggtable) size() int {
+	return cap(a.repr) + a.tree.size() + cap(a.pairs)*int(unsafe.Sizeof(hpair{}))
+}
+
+// account charges the memory used
+// by the table to the query Budget
+func (a *aggtable) account() error {
+	if err := a.mem.resize(a.size()); err != nil {
+		return fmt.Errorf("hash aggregate: %w", err)
+	}
+	return nil
+}
+
+// accountOrSpill is like account, but it
+// spills the table rather than failing
+// if the table can be spilled; the query
+// still fails if the empty table does
+// not fit in the budget
+func (a *aggtable) accountOrSpill() error {
+	err := a.account()
+	if err == nil || !a.canSpill() || len(a.pairs) == 0 {
+		return err
+	}
+	return a.spillTable()
+}
+
+// spillTable writes every group in the table
+// to a.spill and then resets the table
+func (a *aggtable) spillTable() error {
+	if a.spill == nil {
+		a.spill = new(aggspill)
+	}
+	valsize := len(a.parent.initialData)
+	columns := len(a.parent.by)
+	for i := range a.pairs {
+		p := &a.pairs[i]
+		err := a.spill.write(a.hashof(p), a.fullrepr(p, columns), a.valueof(p)[:valsize])
+		if err != nil {
+			return fmt.Errorf("spilling aggregate: %w", err)
+		}
+	}
+	if err := a.spill.flush(); err != nil {
+		return fmt.Errorf("spilling aggregate: %w", err)
+	}
+	// drop the buffers rather than truncating them
+	// so that the memory is actually given back
+	a.tree = newRadixTree(valsize)
+	a.repr = nil
+	a.pairs = nil
+	return a.account()
+}
+
+// closeSpilled re-aggregates the spilled
+// partial aggregates (plus h.final) one partition
+// at a time and writes the results to h.dst
+func (h *HashAggregate) closeSpilled() error {
+	spills := h.spills
+	h.spills = nil
+	defer func() {
+		for _, s := range spills {
+			s.release()
+		}
+	}()
+
+	dst, err := h.dst.Open()
+	if err != nil {
+		return err
+	}
+	if len(h.ordercols) == 0 {
+		err = h.writePartitions(dst, spills, h.limit)
+	} else {
+		// the groups are not all in memory at once,
+		// so sort the output rows instead
+		var limit *SortLimit
+		if h.limit > 0 {
+			limit = &SortLimit{Limit: h.limit}
+		}
+		err = h.writeOrdered(dst, spills, limit)
+	}
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	// close the threading context
+	// *and* the destination query sink
+	err = dst.Close()
+	err2 := h.dst.Close()
+	if err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (h *HashAggregate) writeOrdered(dst io.Writer, spills []*aggspill, limit *SortLimit) error {
+	ord, err := NewOrder(dst, h.ordercols, limit, 1)
+	if err != nil {
+		return err
+	}
+	w, err := ord.Open()
+	if err != nil {
+		return err
+	}
+	err = h.writePartitions(w, spills, 0)
+	err2 := w.Close()
+	if err == nil {
+		err = err2
+	}
+	err2 = ord.Close()
+	if err == nil {
+		err = err2
+	}
+	return err
+}
+
+// writePartitions writes the aggregated groups
+// for each partition as a separate chunk to dst,
+// stopping after limit rows if limit is positive
+func (h *HashAggregate) writePartitions(dst io.Writer, spills []*aggspill, limit int) error {
+	final := h.final
+	h.final = nil
+	defer final.mem.release()
+	valsize := len(h.initialData)
+	columns := len(h.by)
+
+	out := h.output()
+	var outbuf ion.Buffer
+	rows := 0
+	for part := 0; part < aggPartitions; part++ {
+		t := h.newTable()
+		for _, s := range spills {
+			if err := s.read(part, valsize, t.mergeEntry); err != nil {
+				return err
+			}
+		}
+		for i := range final.pairs {
+			p := &final.pairs[i]
+			hash := final.hashof(p)
+			if aggPartition(hash) == part {
+				t.mergeEntry(hash, final.fullrepr(p, columns), final.valueof(p)[:valsize])
+			}
+		}
+		if len(t.pairs) == 0 {
+			continue
+		}
+		if err := t.account(); err != nil {
+			t.mem.release()
+			return err
+		}
+		h.finalize(t)
+		outbuf.Reset()
+		out.st.Marshal(&outbuf, true)
+		for i := range t.pairs {
+			if limit > 0 && rows >= limit {
+				break
+			}
+			out.write(&outbuf, t, i)
+			rows++
+		}
+		t.mem.release()
+		if _, err := dst.Write(outbuf.Bytes()); err != nil {
+			return err
+		}
+		if limit > 0 && rows >= limit {
+			break
+		}
+	}
+	if rows == 0 {
+		// symbol table + no data
+		outbuf.Reset()
+		out.st.Marshal(&outbuf, true)
+		_, err := dst.Write(outbuf.Bytes())
+		return err
+	}
+	return nil
+}
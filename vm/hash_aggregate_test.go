@@ -25,6 +25,8 @@ import (
 	"strings"
 	"testing"
 
+	"golang.org/x/exp/slices"
+
 	"github.com/SnellerInc/sneller/expr"
 	"github.com/SnellerInc/sneller/ion"
 )
@@ -183,6 +185,317 @@ func TestHashAggregate(t *testing.T) {
 	}
 }
 
+// TestHashAggregateMergeState merges the partial
+// states produced by one aggregate in another one,
+// writing them to separate tables so that the tables
+// have to be merged as well
+func TestHashAggregateMergeState(t *testing.T) {
+	buf, err := os.ReadFile("../testdata/nyc-taxi.block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	group := Selection{{Expr: path(t, "VendorID")}}
+	aggs := func(role expr.AggregateRole, sum, count string) Aggregation {
+		return Aggregation{
+			{Expr: &expr.Aggregate{Op: expr.OpSum, Inner: path(t, sum), Role: role}, Result: "sum"},
+			{Expr: &expr.Aggregate{Op: expr.OpApproxCountDistinct, Inner: path(t, count), Role: role,
+				Precision: expr.ApproxCountDistinctDefaultPrecision}, Result: "count"},
+		}
+	}
+	rows := func(qb *QueryBuffer) map[string]string {
+		ret := make(map[string]string)
+		var st ion.Symtab
+		var d ion.Datum
+		outbuf := qb.Bytes()
+		for len(outbuf) > 0 {
+			if ion.TypeOf(outbuf) == ion.NullType && ion.SizeOf(outbuf) > 1 {
+				outbuf = outbuf[ion.SizeOf(outbuf):]
+				continue
+			}
+			d, outbuf, err = ion.ReadDatum(&st, outbuf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := d.Struct()
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, ok := s.FieldByName("VendorID")
+			if !ok {
+				t.Fatalf("no VendorID in %s", toJSON(&st, d))
+			}
+			vendor, _ := f.String()
+			ret[vendor] = toJSON(&st, d)
+		}
+		return ret
+	}
+	run := func(agg Aggregation, inputs ...[]byte) *QueryBuffer {
+		var qb QueryBuffer
+		ha, err := NewHashAggregate(agg, nil, group, &qb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// each input is written to a
+		// table of its own
+		var tables []io.WriteCloser
+		for range inputs {
+			w, err := ha.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tables = append(tables, w)
+		}
+		for i := range inputs {
+			if _, err := tables[i].Write(inputs[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := range tables {
+			if err := tables[i].Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := ha.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return &qb
+	}
+
+	partial := run(aggs(expr.AggregateRolePartial, "total_amount", "tpep_pickup_datetime"), buf)
+	want := rows(run(aggs(expr.AggregateRoleFinal, "total_amount", "tpep_pickup_datetime"), buf, buf))
+	got := rows(run(aggs(expr.AggregateRoleMerge, "sum", "count"), partial.Bytes(), partial.Bytes()))
+	if len(want) != 3 {
+		t.Fatalf("got %d groups", len(want))
+	}
+	if !reflect.DeepEqual(got, want) {
+		for k := range want {
+			t.Logf("got  %s", got[k])
+			t.Logf("want %s", want[k])
+		}
+		t.Fatal("results are not equal")
+	}
+}
+
+func TestHashAggregateTDigestValues(t *testing.T) {
+	buf, err := os.ReadFile("../testdata/nyc-taxi.block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(mem int, dir string) {
+		AggregateSpillMemory, SpillDir = mem, dir
+	}(AggregateSpillMemory, SpillDir)
+	SpillDir = t.TempDir()
+
+	// the values of each group are buffered and
+	// added to its state once per batch of rows,
+	// so every value must be accounted for exactly once
+	agg := Aggregation{
+		mkagg(expr.OpApproxPercentileState, "total_amount", "s"),
+		mkagg(expr.OpCount, "total_amount", "n"),
+		mkagg(expr.OpMin, "total_amount", "lo"),
+		mkagg(expr.OpMax, "total_amount", "hi"),
+	}
+	for _, tc := range []struct {
+		group  string
+		spill  bool
+		groups int
+	}{
+		// few groups with many values each
+		{group: "VendorID", groups: 3},
+		// many groups, spilled to disk
+		{group: "tpep_pickup_datetime", spill: true},
+	} {
+		spill := tc.spill
+		AggregateSpillMemory = 0
+		if spill {
+			AggregateSpillMemory = 4096
+		}
+		var qb QueryBuffer
+		ha, err := NewHashAggregate(agg, nil, Selection{{Expr: path(t, tc.group)}}, &qb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		intable := &looptable{chunk: buf, count: 4}
+		if err := intable.WriteChunks(ha, int(intable.count)); err != nil {
+			t.Fatal(err)
+		}
+		spilled, err := os.ReadDir(SpillDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spill != (len(spilled) > 0) {
+			t.Fatalf("spill=%v, but %d files were spilled", spill, len(spilled))
+		}
+		if err := ha.Close(); err != nil {
+			t.Fatal(err)
+		}
+		var st ion.Symtab
+		var d ion.Datum
+		groups := 0
+		outbuf := qb.Bytes()
+		for len(outbuf) > 0 {
+			if ion.TypeOf(outbuf) == ion.NullType && ion.SizeOf(outbuf) > 1 {
+				outbuf = outbuf[ion.SizeOf(outbuf):]
+				continue
+			}
+			d, outbuf, err = ion.ReadDatum(&st, outbuf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.IsEmpty() {
+				continue
+			}
+			s, err := d.Struct()
+			if err != nil {
+				t.Fatal(err)
+			}
+			field := func(name string) ion.Datum {
+				f, ok := s.FieldByName(name)
+				if !ok {
+					t.Fatalf("no %s in %s", name, toJSON(&st, d))
+				}
+				return f.Datum
+			}
+			state, err := field("s").Blob()
+			if err != nil {
+				t.Fatal(err)
+			}
+			number := func(name string) float32 {
+				d := field(name)
+				if f, err := d.Float(); err == nil {
+					return float32(f)
+				}
+				i, _ := d.Int()
+				return float32(i)
+			}
+			n, lo, hi := number("n"), number("lo"), number("hi")
+			td := tDigestDS(state)
+			if w := td.getWeightSum(); w != n {
+				t.Errorf("%s: total weight %g, want %g", toJSON(&st, d), w, n)
+			}
+			if td.getMeanMin() != lo || td.getMeanMax() != hi {
+				t.Errorf("%s: got range [%g, %g]", toJSON(&st, d), td.getMeanMin(), td.getMeanMax())
+			}
+			groups++
+		}
+		if groups == 0 || tc.groups != 0 && groups != tc.groups {
+			t.Errorf("GROUP BY %s: got %d groups", tc.group, groups)
+		}
+	}
+}
+
+func TestHashAggregateSpill(t *testing.T) {
+	buf, err := os.ReadFile("../testdata/nyc-taxi.block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(mem int, dir string) {
+		AggregateSpillMemory, SpillDir = mem, dir
+	}(AggregateSpillMemory, SpillDir)
+	SpillDir = t.TempDir()
+
+	agg := Aggregation{
+		mkagg(expr.OpCount, "VendorID", "count"),
+		mkagg(expr.OpSum, "passenger_count", "passengers"),
+		mkagg(expr.OpMin, "trip_distance", "min"),
+		mkagg(expr.OpMax, "total_amount", "max"),
+	}
+	group := Selection{{Expr: path(nil, "tpep_pickup_datetime")}}
+
+	// run the aggregate and return the rows as JSON,
+	// checking whether or not it spilled
+	run := func(spill, order bool, limit int) []string {
+		AggregateSpillMemory = 0
+		if spill {
+			AggregateSpillMemory = 4096
+		}
+		var qb QueryBuffer
+		ha, err := NewHashAggregate(agg, nil, group, &qb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if order {
+			ha.OrderByAggregate(0, SortOrdering{Direction: SortDescending})
+			ha.OrderByGroup(0, defaultSortOrdering)
+		}
+		ha.Limit(limit)
+		intable := &looptable{chunk: buf, count: 4}
+		err = intable.WriteChunks(ha, int(intable.count))
+		if err != nil {
+			t.Fatal(err)
+		}
+		spilled, err := os.ReadDir(SpillDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spill != (len(spilled) > 0) {
+			t.Fatalf("spill=%v, but %d files were spilled", spill, len(spilled))
+		}
+		err = ha.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		left, err := os.ReadDir(SpillDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(left) != 0 {
+			t.Fatalf("%d spill files were not removed", len(left))
+		}
+
+		var st ion.Symtab
+		var d ion.Datum
+		var rows []string
+		outbuf := qb.Bytes()
+		for len(outbuf) > 0 {
+			if ion.TypeOf(outbuf) == ion.NullType && ion.SizeOf(outbuf) > 1 {
+				// nop pad
+				outbuf = outbuf[ion.SizeOf(outbuf):]
+				continue
+			}
+			d, outbuf, err = ion.ReadDatum(&st, outbuf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !d.IsEmpty() {
+				rows = append(rows, toJSON(&st, d))
+			}
+		}
+		return rows
+	}
+
+	t.Run("unordered", func(t *testing.T) {
+		want := run(false, false, 0)
+		got := run(true, false, 0)
+		if len(want) < 1000 {
+			t.Fatalf("only %d groups", len(want))
+		}
+		slices.Sort(want)
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("got %d rows, want %d", len(got), len(want))
+		}
+		if got := run(true, false, 100); len(got) != 100 {
+			t.Errorf("got %d rows with LIMIT 100", len(got))
+		}
+	})
+	t.Run("ordered", func(t *testing.T) {
+		for _, limit := range []int{0, 25} {
+			want := run(false, true, limit)
+			got := run(true, true, limit)
+			if !slices.Equal(got, want) {
+				t.Errorf("limit %d: results differ", limit)
+				for i := range got {
+					if i < len(want) && got[i] != want[i] {
+						t.Logf("row %d: got %s want %s", i, got[i], want[i])
+						break
+					}
+				}
+			}
+		}
+	})
+}
+
 type nopSink struct{}
 
 func (n nopSink) Open() (io.WriteCloser, error) {
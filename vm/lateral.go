@@ -0,0 +1,813 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"unsafe"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+
+	"golang.org/x/exp/slices"
+)
+
+// LateralKind describes the value that
+// a Lateral sub-query binds to each row
+type LateralKind int
+
+const (
+	// LateralScalar binds the only column
+	// of the first row of the sub-query
+	LateralScalar LateralKind = iota
+	// LateralStruct binds the first row
+	// of the sub-query as a structure
+	LateralStruct
+	// LateralList binds a list of
+	// all the rows of the sub-query
+	LateralList
+)
+
+func (k LateralKind) String() string {
+	switch k {
+	case LateralScalar:
+		return "scalar"
+	case LateralStruct:
+		return "struct"
+	case LateralList:
+		return "list"
+	default:
+		return fmt.Sprintf("<LateralKind: %d>", int(k))
+	}
+}
+
+// lateralChunk is the maximum number of
+// array elements evaluated at once
+const lateralChunk = 1024
+
+// Lateral evaluates a sub-query over the
+// elements of an array in each row and binds
+// the result of the sub-query to the row as
+// an auxiliary binding
+type Lateral struct {
+	dst    QuerySink
+	kind   LateralKind
+	result string
+	elem   string // binding of each array element
+	budget *Budget
+
+	splat prog // produces the array
+	find  prog // stores the slots of each element
+
+	nslots int      // number of slots stored by find
+	names  []string // output columns
+	// cols[i] is the slot of names[i]
+	// when the sub-query does not aggregate
+	cols []int
+	// aggs[i] computes names[i]
+	// when the sub-query aggregates
+	aggs  []lateralAgg
+	where int // slot of the WHERE clause, or -1
+	// order[i] is the ordering of slot
+	// keys[i]; the sort is stable
+	order  []SortOrdering
+	keys   []int
+	limit  int // -1 means no limit
+	offset int
+}
+
+// lateralAgg is an aggregate
+// computed over the elements
+type lateralAgg struct {
+	op     expr.AggregateOp
+	inner  int // slot of the argument, or -1 for COUNT(*)
+	filter int // slot of the FILTER clause, or -1
+}
+
+// NewLateral creates a Lateral that binds the result
+// of q to each row as an auxiliary binding with the
+// given name; the FROM clause of q must be a single
+// array-valued expression, and the binding of the
+// array elements is visible to the rest of q along
+// with the bindings of the input rows.
+//
+// The sub-query may filter, order, limit and
+// project the elements, or it may compute
+// COUNT, SUM, SUM_INT, AVG, MIN, MAX, BOOL_AND
+// and BOOL_OR over them. Outer rows for which
+// the sub-query produces no rows are bound
+// to MISSING, except for LateralList, which
+// binds them to an empty list.
+func NewLateral(dst QuerySink, q *expr.Select, kind LateralKind, result string) (*Lateral, error) {
+	t, ok := q.From.(*expr.Table)
+	if !ok {
+		return nil, fmt.Errorf("lateral: unexpected FROM clause %s", expr.ToString(q.From))
+	}
+	l := &Lateral{
+		dst:    dst,
+		kind:   kind,
+		result: result,
+		elem:   t.Result(),
+		where:  -1,
+		limit:  -1,
+	}
+	if l.elem == "" {
+		return nil, fmt.Errorf("lateral: the array %s needs a binding", expr.ToString(t.Expr))
+	}
+	if q.Limit != nil {
+		l.limit = int(*q.Limit)
+	}
+	if q.Offset != nil {
+		l.offset = int(*q.Offset)
+	}
+
+	p := &l.splat
+	p.begin()
+	v, err := compile(p, t.Expr)
+	if err != nil {
+		return nil, err
+	}
+	list := p.ssa2(stolist, v, p.mask(v))
+	p.returnScalar(p.initMem(), list, p.mask(list))
+	p.Renumber()
+
+	p = &l.find
+	p.begin()
+	mem0 := p.initMem()
+	var mem []*value
+	var stored []expr.Node
+	store := func(e expr.Node) (int, error) {
+		// an expression that appears more than once
+		// (e.g. in both SELECT and ORDER BY) is stored once
+		for i := range stored {
+			if expr.Equal(stored[i], e) {
+				return i, nil
+			}
+		}
+		slot := len(mem)
+		v, err := p.compileStore(mem0, e, stackSlotFromIndex(regV, slot), true)
+		if err != nil {
+			return -1, err
+		}
+		mem = append(mem, v)
+		stored = append(stored, e)
+		return slot, nil
+	}
+	for i := range q.Columns {
+		name := q.Columns[i].Result()
+		if name == "" {
+			return nil, fmt.Errorf("lateral: column %s needs a name", expr.ToString(q.Columns[i].Expr))
+		}
+		l.names = append(l.names, name)
+	}
+	aggregate := slices.IndexFunc(q.Columns, func(b expr.Binding) bool {
+		_, ok := b.Expr.(*expr.Aggregate)
+		return ok
+	}) >= 0
+	if aggregate {
+		for i := range q.Columns {
+			agg, ok := q.Columns[i].Expr.(*expr.Aggregate)
+			if !ok {
+				return nil, fmt.Errorf("lateral: cannot compute %s", expr.ToString(q.Columns[i].Expr))
+			}
+			switch agg.Op {
+			case expr.OpCount, expr.OpSum, expr.OpSumInt, expr.OpAvg,
+				expr.OpMin, expr.OpMax, expr.OpBoolAnd, expr.OpBoolOr:
+			default:
+				return nil, fmt.Errorf("lateral: aggregate %s is not supported", agg.Op)
+			}
+			a := lateralAgg{op: agg.Op, inner: -1, filter: -1}
+			if _, ok := agg.Inner.(expr.Star); !ok {
+				if a.inner, err = store(agg.Inner); err != nil {
+					return nil, err
+				}
+			}
+			if agg.Filter != nil {
+				if a.filter, err = store(expr.Is(agg.Filter, expr.IsTrue)); err != nil {
+					return nil, err
+				}
+			}
+			l.aggs = append(l.aggs, a)
+		}
+	} else {
+		for i := range q.Columns {
+			slot, err := store(q.Columns[i].Expr)
+			if err != nil {
+				return nil, err
+			}
+			l.cols = append(l.cols, slot)
+		}
+		for i := range q.OrderBy {
+			slot, err := store(q.OrderBy[i].Column)
+			if err != nil {
+				return nil, err
+			}
+			l.keys = append(l.keys, slot)
+			o := SortOrdering{Direction: SortAscending, NullsOrder: SortNullsFirst}
+			if q.OrderBy[i].Desc {
+				o.Direction = SortDescending
+			}
+			if q.OrderBy[i].NullsLast {
+				o.NullsOrder = SortNullsLast
+			}
+			l.order = append(l.order, o)
+		}
+	}
+	if q.Where != nil {
+		if l.where, err = store(expr.Is(q.Where, expr.IsTrue)); err != nil {
+			return nil, err
+		}
+	}
+	l.nslots = len(mem)
+	if l.nslots > 0 {
+		p.returnValue(p.mergeMem(mem...))
+	}
+	return l, nil
+}
+
+// SetBudget sets the Budget to which the memory
+// used to evaluate the sub-query is charged.
+func (l *Lateral) SetBudget(b *Budget) {
+	l.budget = b
+}
+
+func (l *Lateral) Open() (io.WriteCloser, error) {
+	dst, err := l.dst.Open()
+	if err != nil {
+		return nil, err
+	}
+	lt := &lateral{parent: l, dstrc: asRowConsumer(dst)}
+	lt.mem.budget = l.budget
+	return splitter(lt), nil
+}
+
+func (l *Lateral) Close() error {
+	l.splat.reset()
+	l.find.reset()
+	return l.dst.Close()
+}
+
+// lateralSpan is the position of
+// a value copied to lateral.arena
+type lateralSpan struct {
+	off, size int32
+}
+
+type lateral struct {
+	parent *Lateral
+	dstrc  rowConsumer
+	auxnum int
+
+	splatprog, findprog prog
+	splat, find         bytecode
+	// size of the stack of find
+	// without the stored slots
+	findbase int
+	inner    auxbindings
+
+	// splatted elements and the
+	// outer lane of each element
+	elems []vmref
+	perms []int32
+	// rows and bindings of the elements
+	rows    []vmref
+	iparams rowParams
+	cparams rowParams
+	// the stored slots of each element
+	vals  []lateralSpan
+	arena []byte
+	// index[starts[i]:starts[i+1]] are the
+	// elements of outer lane i in array order
+	starts []int32
+	index  []int32
+	sel    []int32
+	argv   [][]byte
+
+	// results for each input row
+	// and the page holding them
+	results []vmref
+	page    []byte
+	off     int
+	tmp     ion.Buffer
+	syms    []ion.Symbol
+	params  rowParams
+
+	// charge for the buffers
+	mem memacct
+}
+
+func (l *lateral) next() rowConsumer { return l.dstrc }
+
+func (l *lateral) EndSegment() {
+	l.splat.dropScratch()
+	l.find.dropScratch()
+}
+
+func (l *lateral) symbolize(st *symtab, aux *auxbindings) error {
+	p := l.parent
+	err := recompile(st, &p.splat, &l.splatprog, &l.splat, aux, "lateral")
+	if err != nil {
+		return err
+	}
+	if p.nslots > 0 {
+		l.inner.set(aux)
+		l.inner.push(p.elem)
+		stale := l.findprog.isStale(st, &l.inner)
+		err = recompile(st, &p.find, &l.findprog, &l.find, &l.inner, "lateral findbc")
+		if err != nil {
+			return err
+		}
+		if stale {
+			l.findbase = l.find.vstacksize
+		}
+		l.find.ensureVStackSize(l.findbase + (lateralChunk/bcLaneCount)*p.nslots*vRegSize)
+		l.find.allocStacks()
+	}
+	l.syms = l.syms[:0]
+	for i := range p.names {
+		l.syms = append(l.syms, st.Intern(p.names[i]))
+	}
+	l.auxnum = aux.push(p.result)
+	return l.dstrc.symbolize(st, aux)
+}
+
+func (l *lateral) writeRows(delims []vmref, rp *rowParams) error {
+	if len(delims) == 0 {
+		return nil
+	}
+	if l.splat.compiled == nil {
+		panic("WriteRows() called before Symbolize()")
+	}
+	if cap(l.elems) == 0 {
+		l.elems = make([]vmref, 1024)
+		l.perms = make([]int32, 1024)
+	}
+	if l.page == nil {
+		l.page = l.parent.budget.Malloc()
+		l.off = 0
+	}
+	pos, ok := vmdispl(l.page)
+	if !ok {
+		panic("lateral: result page is not vm memory")
+	}
+	l.results = shrink(l.results, len(delims))
+	l.splat.prepare(rp)
+	consumed, flushed := 0, 0
+	for consumed < len(delims) {
+		l.elems = l.elems[:cap(l.elems)]
+		l.perms = l.perms[:cap(l.perms)]
+		in, out := evalsplat(&l.splat, delims[consumed:], l.elems, l.perms)
+		if l.splat.err != 0 {
+			return bytecodeerror("lateral", &l.splat)
+		}
+		l.splat.auxpos = consumed + in
+		if in == 0 {
+			// not enough room for the elements
+			// of a single row; see unnesting.writeRows
+			l.elems = slices.Grow(l.elems, len(l.elems))
+			l.perms = slices.Grow(l.perms, len(l.perms))
+			if err := l.mem.resize(l.size()); err != nil {
+				return fmt.Errorf("lateral: %w", err)
+			}
+			continue
+		}
+		// the elements are emitted lane by lane,
+		// but those of a lane that did not fit
+		// entirely may still have been emitted
+		for out > 0 && int(l.perms[out-1]) >= in {
+			out--
+		}
+		if err := l.eval(delims, rp, consumed, out); err != nil {
+			return err
+		}
+		l.group(in, out)
+		for i := 0; i < in; i++ {
+			row := consumed + i
+			l.tmp.Reset()
+			if !l.result(l.index[l.starts[i]:l.starts[i+1]]) {
+				l.results[row] = vmref{}
+				continue
+			}
+			buf := l.tmp.Bytes()
+			if len(buf) > len(l.page)-l.off {
+				if err := l.flush(delims, rp, flushed, row); err != nil {
+					return err
+				}
+				flushed = row
+				if len(buf) > len(l.page) {
+					return fmt.Errorf("lateral: result of %d bytes exceeds the page size", len(buf))
+				}
+			}
+			copy(l.page[l.off:], buf)
+			l.results[row] = vmref{pos + uint32(l.off), uint32(len(buf))}
+			l.off += len(buf)
+		}
+		consumed += in
+	}
+	return l.flush(delims, rp, flushed, len(delims))
+}
+
+// eval evaluates the slots of the first n
+// splatted elements of the rows starting at
+// delims[consumed] and copies them to l.arena
+func (l *lateral) eval(delims []vmref, rp *rowParams, consumed, n int) error {
+	nslots := l.parent.nslots
+	l.vals = shrink(l.vals, n*nslots)
+	l.arena = l.arena[:0]
+	if n == 0 || nslots == 0 {
+		return nil
+	}
+	l.rows = shrink(l.rows, n)
+	perms := l.perms[:n]
+	for j, p := range perms {
+		l.rows[j] = delims[consumed+int(p)]
+	}
+	naux := len(rp.auxbound)
+	l.iparams.auxbound = shrink(l.iparams.auxbound, naux+1)
+	for k := range rp.auxbound {
+		aux := sanitizeAux(l.iparams.auxbound[k], n)
+		for j, p := range perms {
+			aux[j] = rp.auxbound[k][consumed+int(p)]
+		}
+		l.iparams.auxbound[k] = aux
+	}
+	l.elems = sanitizeAux(l.elems, n)
+	l.iparams.auxbound[naux] = l.elems
+	l.cparams.auxbound = shrink(l.cparams.auxbound, naux+1)
+
+	chunk := lateralChunk
+	for start := 0; start < n; {
+		size := n - start
+		if size > chunk {
+			size = chunk
+		}
+		for k := range l.iparams.auxbound {
+			l.cparams.auxbound[k] = l.iparams.auxbound[k][start : start+size]
+		}
+		l.find.prepare(&l.cparams)
+		evalfindbc(&l.find, l.rows[start:start+size], nslots*vRegSize)
+		if l.find.err == bcerrMoreScratch && chunk > bcLaneCount {
+			// evaluate fewer elements at once;
+			// the chunks always start at a lane
+			// boundary so that the padding of
+			// the bindings stays valid
+			chunk = (chunk / 2) &^ bcLaneCountMask
+			continue
+		}
+		if l.find.err != 0 {
+			return bytecodeerror("lateral", &l.find)
+		}
+		blocks := (size + bcLaneCount - 1) / bcLaneCount
+		view := vRegDataFromVStackCast(&l.find.vstack, blocks*nslots)
+		for j := 0; j < size; j++ {
+			for s := 0; s < nslots; s++ {
+				mem := getdelim(view, j, s, nslots).mem()
+				l.vals[(start+j)*nslots+s] = lateralSpan{off: int32(len(l.arena)), size: int32(len(mem))}
+				l.arena = append(l.arena, mem...)
+			}
+		}
+		start += size
+	}
+	if err := l.mem.resize(l.size()); err != nil {
+		return fmt.Errorf("lateral: %w", err)
+	}
+	return nil
+}
+
+// group groups the first n splatted
+// elements by the outer lane in [0, in)
+// to which they belong
+func (l *lateral) group(in, n int) {
+	l.starts = shrink(l.starts, in+1)
+	for i := range l.starts {
+		l.starts[i] = 0
+	}
+	perms := l.perms[:n]
+	for _, p := range perms {
+		l.starts[p+1]++
+	}
+	for i := 1; i < len(l.starts); i++ {
+		l.starts[i] += l.starts[i-1]
+	}
+	l.index = shrink(l.index, n)
+	l.sel = shrink(l.sel, in)
+	pos := l.sel // insertion position of each lane
+	copy(pos, l.starts[:in])
+	for j, p := range perms {
+		l.index[pos[p]] = int32(j)
+		pos[p]++
+	}
+}
+
+// value returns the value of a slot
+// of an element; MISSING is empty
+func (l *lateral) value(elem int32, slot int) []byte {
+	s := l.vals[int(elem)*l.parent.nslots+slot]
+	return l.arena[s.off : s.off+s.size]
+}
+
+func (l *lateral) istrue(elem int32, slot int) bool {
+	v := l.value(elem, slot)
+	return len(v) == 1 && v[0] == 0x11
+}
+
+// result writes the result of the sub-query
+// over the given elements of a row to l.tmp;
+// it returns false if the result is MISSING
+func (l *lateral) result(elems []int32) bool {
+	p := l.parent
+	sel := l.sel[:0]
+	for _, j := range elems {
+		if p.where < 0 || l.istrue(j, p.where) {
+			sel = append(sel, j)
+		}
+	}
+	l.sel = sel
+	if p.aggs != nil {
+		if p.kind == LateralScalar {
+			l.aggregate(&p.aggs[0], sel)
+			return true
+		}
+		// one row, which is a list
+		// of one row for LateralList
+		if p.kind == LateralList {
+			l.tmp.BeginList(-1)
+		}
+		l.tmp.BeginStruct(-1)
+		for i := range p.aggs {
+			l.tmp.BeginField(l.syms[i])
+			l.aggregate(&p.aggs[i], sel)
+		}
+		l.tmp.EndStruct()
+		if p.kind == LateralList {
+			l.tmp.EndList()
+		}
+		return true
+	}
+	if len(p.order) > 0 {
+		slices.SortStableFunc(sel, func(a, b int32) bool {
+			return l.compare(a, b) < 0
+		})
+	}
+	if p.offset >= len(sel) {
+		sel = sel[:0]
+	} else {
+		sel = sel[p.offset:]
+	}
+	if p.limit >= 0 && p.limit < len(sel) {
+		sel = sel[:p.limit]
+	}
+	switch p.kind {
+	case LateralScalar:
+		if len(sel) == 0 {
+			return false
+		}
+		v := l.value(sel[0], p.cols[0])
+		if len(v) == 0 {
+			return false
+		}
+		l.tmp.UnsafeAppend(v)
+	case LateralStruct:
+		if len(sel) == 0 {
+			return false
+		}
+		l.row(sel[0])
+	default:
+		l.tmp.BeginList(-1)
+		for _, j := range sel {
+			l.row(j)
+		}
+		l.tmp.EndList()
+	}
+	return true
+}
+
+// row writes the columns of an element as
+// a structure; MISSING columns are omitted
+func (l *lateral) row(elem int32) {
+	l.tmp.BeginStruct(-1)
+	for i, slot := range l.parent.cols {
+		v := l.value(elem, slot)
+		if len(v) == 0 {
+			continue
+		}
+		l.tmp.BeginField(l.syms[i])
+		l.tmp.UnsafeAppend(v)
+	}
+	l.tmp.EndStruct()
+}
+
+// compare compares the ORDER BY keys of two elements
+func (l *lateral) compare(a, b int32) int {
+	p := l.parent
+	for i := range p.order {
+		va := l.value(a, p.keys[i])
+		vb := l.value(b, p.keys[i])
+		// MISSING sorts as NULL
+		if len(va) == 0 {
+			va = []byte{0x0f}
+		}
+		if len(vb) == 0 {
+			vb = []byte{0x0f}
+		}
+		if dir := p.order[i].Compare(va, vb); dir != 0 {
+			return dir
+		}
+	}
+	return 0
+}
+
+// args collects the non-NULL arguments of
+// an aggregate that pass its FILTER clause
+func (l *lateral) args(a *lateralAgg, sel []int32) [][]byte {
+	argv := l.argv[:0]
+	for _, j := range sel {
+		if a.filter >= 0 && !l.istrue(j, a.filter) {
+			continue
+		}
+		if a.inner < 0 {
+			argv = append(argv, nil)
+			continue
+		}
+		v := l.value(j, a.inner)
+		if len(v) == 0 || v[0]&0x0f == 0x0f {
+			continue // MISSING or NULL
+		}
+		argv = append(argv, v)
+	}
+	l.argv = argv
+	return argv
+}
+
+// aggregate writes the value of an
+// aggregate over the elements to l.tmp
+func (l *lateral) aggregate(a *lateralAgg, sel []int32) {
+	argv := l.args(a, sel)
+	switch a.op {
+	case expr.OpCount:
+		l.tmp.WriteInt(int64(len(argv)))
+	case expr.OpSum, expr.OpSumInt, expr.OpAvg:
+		var isum int64
+		var fsum float64
+		n, ints := 0, true
+		for _, v := range argv {
+			i, f, isint, ok := lateralNumber(v)
+			if !ok || (a.op == expr.OpSumInt && !isint) {
+				continue
+			}
+			n++
+			fsum += f
+			if ints && isint {
+				s := isum + i
+				if (s > isum) == (i > 0) {
+					isum = s
+					continue
+				}
+			}
+			ints = false
+		}
+		switch {
+		case n == 0:
+			l.tmp.WriteNull()
+		case a.op == expr.OpAvg:
+			l.tmp.WriteCanonicalFloat(fsum / float64(n))
+		case ints:
+			l.tmp.WriteInt(isum)
+		default:
+			l.tmp.WriteCanonicalFloat(fsum)
+		}
+	case expr.OpMin, expr.OpMax:
+		var best []byte
+		var bestf float64
+		order := SortOrdering{Direction: SortAscending}
+		if a.op == expr.OpMax {
+			order.Direction = SortDescending
+		}
+		for _, v := range argv {
+			_, f, _, ok := lateralNumber(v)
+			if ok {
+				if best == nil || ion.TypeOf(best) == ion.TimestampType ||
+					(a.op == expr.OpMin && f < bestf) || (a.op == expr.OpMax && f > bestf) {
+					best, bestf = v, f
+				}
+				continue
+			}
+			if ion.TypeOf(v) != ion.TimestampType {
+				continue
+			}
+			// numbers take precedence over timestamps
+			if best == nil || (ion.TypeOf(best) == ion.TimestampType && order.Compare(v, best) < 0) {
+				best = v
+			}
+		}
+		switch {
+		case best == nil:
+			l.tmp.WriteNull()
+		case ion.TypeOf(best) == ion.TimestampType:
+			l.tmp.UnsafeAppend(best)
+		default:
+			i, f, isint, _ := lateralNumber(best)
+			if isint {
+				l.tmp.WriteInt(i)
+			} else {
+				l.tmp.WriteCanonicalFloat(f)
+			}
+		}
+	case expr.OpBoolAnd, expr.OpBoolOr:
+		n := 0
+		res := a.op == expr.OpBoolAnd
+		for _, v := range argv {
+			b, _, err := ion.ReadBool(v)
+			if err != nil {
+				continue
+			}
+			n++
+			if a.op == expr.OpBoolAnd {
+				res = res && b
+			} else {
+				res = res || b
+			}
+		}
+		if n == 0 {
+			l.tmp.WriteNull()
+		} else {
+			l.tmp.WriteBool(res)
+		}
+	}
+}
+
+// lateralNumber reads a numeric value
+func lateralNumber(v []byte) (i int64, f float64, isint, ok bool) {
+	switch ion.TypeOf(v) {
+	case ion.IntType:
+		i, _, err := ion.ReadInt(v)
+		return i, float64(i), true, err == nil
+	case ion.UintType:
+		u, _, err := ion.ReadUint(v)
+		if u > math.MaxInt64 {
+			return 0, float64(u), false, err == nil
+		}
+		return int64(u), float64(u), true, err == nil
+	case ion.FloatType:
+		f, _, err := ion.ReadFloat64(v)
+		return 0, f, false, err == nil
+	}
+	return 0, 0, false, false
+}
+
+// flush writes the rows delims[from:to]
+// with their results to the next rowConsumer
+// and makes the result page available again
+func (l *lateral) flush(delims []vmref, rp *rowParams, from, to int) error {
+	l.off = 0
+	if from == to {
+		return nil
+	}
+	n := to - from
+	l.params.auxbound = shrink(l.params.auxbound, l.auxnum+1)
+	for k := range rp.auxbound {
+		aux := sanitizeAux(l.params.auxbound[k], n)
+		copy(aux, rp.auxbound[k][from:to])
+		l.params.auxbound[k] = aux
+	}
+	aux := sanitizeAux(l.params.auxbound[l.auxnum], n)
+	copy(aux, l.results[from:to])
+	l.params.auxbound[l.auxnum] = aux
+	return l.dstrc.writeRows(delims[from:to], &l.params)
+}
+
+// size returns the memory used by the buffers
+func (l *lateral) size() int {
+	refs := cap(l.elems) + cap(l.rows) + cap(l.results)
+	for _, aux := range l.iparams.auxbound {
+		refs += cap(aux)
+	}
+	ints := cap(l.perms) + cap(l.starts) + cap(l.index) + cap(l.sel)
+	return refs*int(unsafe.Sizeof(vmref{})) + ints*4 +
+		cap(l.vals)*int(unsafe.Sizeof(lateralSpan{})) + cap(l.arena)
+}
+
+func (l *lateral) Close() error {
+	l.splat.reset()
+	l.find.reset()
+	if l.page != nil {
+		l.parent.budget.Free(l.page)
+		l.page = nil
+	}
+	l.mem.release()
+	return l.dstrc.Close()
+}
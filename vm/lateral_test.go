@@ -0,0 +1,153 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// TestLateralLargeArrays tests arrays that do not
+// fit in the default splat buffers and results
+// that do not fit in a single output page
+func TestLateralLargeArrays(t *testing.T) {
+	const (
+		nrows = 250
+		elems = 1500
+	)
+	var st ion.Symtab
+	var buf ion.Buffer
+	id := st.Intern("id")
+	xs := st.Intern("xs")
+	for i := 0; i < nrows; i++ {
+		buf.BeginStruct(-1)
+		buf.BeginField(id)
+		buf.WriteInt(int64(i))
+		buf.BeginField(xs)
+		buf.BeginList(-1)
+		for j := 0; j < elems; j++ {
+			buf.WriteInt(int64((i + j) % 100))
+		}
+		buf.EndList()
+		buf.EndStruct()
+	}
+	var chunk ion.Buffer
+	st.Marshal(&chunk, true)
+	chunk.UnsafeAppend(buf.Bytes())
+	if chunk.Size() > PageSize {
+		t.Fatalf("input of %d bytes does not fit in a page", chunk.Size())
+	}
+
+	q := &expr.Select{
+		Columns: []expr.Binding{expr.Bind(expr.Mul(expr.Ident("x"), expr.Integer(2)), "y")},
+		From:    &expr.Table{Binding: expr.Bind(expr.Ident("xs"), "x")},
+	}
+	var dst QueryBuffer
+	sel := Selection{expr.Bind(expr.Ident("id"), "id"), expr.Bind(expr.Ident("res"), "res")}
+	pro, err := NewProjection(sel, &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lt, err := NewLateral(pro, q, LateralList, "res")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b Budget
+	lt.SetBudget(&b)
+	err = CopyRows(lt, buftbl(chunk.Bytes()), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Used() != 0 {
+		t.Errorf("%d bytes still charged to the budget", b.Used())
+	}
+
+	rows := bufferRows(t, dst.Bytes())
+	if len(rows) != nrows {
+		t.Fatalf("got %d rows, want %d", len(rows), nrows)
+	}
+	for _, s := range rows {
+		f, ok := s.FieldByName("id")
+		if !ok {
+			t.Fatal("no id")
+		}
+		i, err := f.Int()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, ok = s.FieldByName("res")
+		if !ok {
+			t.Fatalf("row %d: no result", i)
+		}
+		lst, err := f.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		err = lst.Each(func(d ion.Datum) error {
+			s, err := d.Struct()
+			if err != nil {
+				return err
+			}
+			y, _ := s.FieldByName("y")
+			v, _ := y.Int()
+			if want := 2 * ((i + int64(n)) % 100); v != want {
+				t.Fatalf("row %d element %d: got %d, want %d", i, n, v, want)
+			}
+			n++
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != elems {
+			t.Fatalf("row %d: %d elements, want %d", i, n, elems)
+		}
+	}
+}
+
+// bufferRows returns the rows in the
+// output of a QueryBuffer
+func bufferRows(t testing.TB, buf []byte) []ion.Struct {
+	var st ion.Symtab
+	var out []ion.Struct
+	for len(buf) > 0 {
+		var err error
+		if ion.IsBVM(buf) {
+			buf, err = st.Unmarshal(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if ion.TypeOf(buf) == ion.NullType && buf[0] != 0x0f {
+			buf = buf[ion.SizeOf(buf):] // padding
+			continue
+		}
+		var d ion.Datum
+		d, buf, err = ion.ReadDatum(&st, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := d.Struct()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, s)
+	}
+	return out
+}
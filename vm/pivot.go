@@ -0,0 +1,127 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// Pivot is a QuerySink that replaces each
+// row with the structure in one of its fields,
+// so that the fields of the structure become
+// the fields of the row. A row where the field
+// is not a structure becomes an empty row.
+//
+// Pivot is the inverse of Unpivot: the rows
+// of PIVOT v AT k are produced by a Pivot of
+// the OBJECT_AGG of k and v.
+type Pivot struct {
+	dst  QuerySink
+	prog prog
+}
+
+// NewPivot constructs a Pivot that replaces
+// the rows written to it with the structure
+// that field evaluates to.
+func NewPivot(field expr.Node, dst QuerySink) (*Pivot, error) {
+	p := &Pivot{dst: dst}
+	prg := &p.prog
+	prg.begin()
+	mem, err := prg.compileStore(prg.initMem(), field, stackSlotFromIndex(regV, 0), false)
+	if err != nil {
+		return nil, err
+	}
+	prg.returnValue(mem)
+	return p, nil
+}
+
+// Open implements QuerySink.Open
+func (p *Pivot) Open() (io.WriteCloser, error) {
+	dst, err := p.dst.Open()
+	if err != nil {
+		return nil, err
+	}
+	return splitter(&pivot{parent: p, dst: asRowConsumer(dst)}), nil
+}
+
+// Close implements io.Closer
+func (p *Pivot) Close() error {
+	p.prog.reset()
+	return p.dst.Close()
+}
+
+type pivot struct {
+	parent *Pivot
+	dst    rowConsumer
+	prog   prog
+	bc     bytecode
+	params rowParams   // always empty
+	aux    auxbindings // always empty
+}
+
+func (p *pivot) symbolize(st *symtab, aux *auxbindings) error {
+	err := recompile(st, &p.parent.prog, &p.prog, &p.bc, aux, "pivot")
+	if err != nil {
+		return err
+	}
+	// the bindings of the input rows
+	// do not survive the pivot
+	p.aux.reset()
+	return p.dst.symbolize(st, &p.aux)
+}
+
+func (p *pivot) next() rowConsumer { return p.dst }
+
+func (p *pivot) EndSegment() {
+	p.bc.dropScratch() // restored in recompile()
+}
+
+func (p *pivot) writeRows(delims []vmref, rp *rowParams) error {
+	if p.bc.compiled == nil {
+		panic("pivot.writeRows() called before symbolize()")
+	}
+	blocks := (len(delims) + bcLaneCount - 1) / bcLaneCount
+	p.bc.ensureVStackSize(p.bc.vstacksize + blocks*vRegSize)
+	p.bc.allocStacks()
+	p.bc.prepare(rp)
+	err := evalfind(&p.bc, delims, 1)
+	if err != nil {
+		return fmt.Errorf("pivot: %w", err)
+	}
+	// each row becomes the contents
+	// of the structure, which remains
+	// in place in vm memory
+	view := vRegDataFromVStackCast(&p.bc.vstack, blocks)
+	for i := range delims {
+		v := getdelim(view, i, 0, 1)
+		mem := v.mem()
+		if len(mem) == 0 || ion.TypeOf(mem) != ion.StructType {
+			delims[i] = vmref{}
+			continue
+		}
+		hdr := uint32(ion.HeaderSizeOf(mem))
+		delims[i] = vmref{v[0] + hdr, v[1] - hdr}
+	}
+	return p.dst.writeRows(delims, &p.params)
+}
+
+func (p *pivot) Close() error {
+	p.bc.reset()
+	return p.dst.Close()
+}
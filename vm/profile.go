@@ -0,0 +1,180 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/SnellerInc/sneller/ion/zion/zll"
+)
+
+// SinkProfile is a collection of statistics
+// about the rows written into a QuerySink.
+//
+// See NewProfile.
+type SinkProfile struct {
+	// Rows is the number of rows written.
+	Rows int64
+	// Bytes is the number of bytes of row data written.
+	// When the input is zion-encoded, this is the
+	// number of bytes that had to be decompressed.
+	Bytes int64
+	// Buckets is the number of zion buckets
+	// presented to the sink, and Skipped is the
+	// number of those buckets that did not need
+	// to be decompressed.
+	Buckets, Skipped int64
+	// Nanos is the cumulative time, in nanoseconds,
+	// spent inside the destination QuerySink
+	// across all of its streams.
+	Nanos int64
+}
+
+// Add atomically adds the statistics in o to p.
+func (p *SinkProfile) Add(o *SinkProfile) {
+	atomic.AddInt64(&p.Rows, o.Rows)
+	atomic.AddInt64(&p.Bytes, o.Bytes)
+	atomic.AddInt64(&p.Buckets, o.Buckets)
+	atomic.AddInt64(&p.Skipped, o.Skipped)
+	atomic.AddInt64(&p.Nanos, o.Nanos)
+}
+
+// Load returns a copy of p that is consistent
+// with concurrent calls to p.Add.
+func (p *SinkProfile) Load() SinkProfile {
+	return SinkProfile{
+		Rows:    atomic.LoadInt64(&p.Rows),
+		Bytes:   atomic.LoadInt64(&p.Bytes),
+		Buckets: atomic.LoadInt64(&p.Buckets),
+		Skipped: atomic.LoadInt64(&p.Skipped),
+		Nanos:   atomic.LoadInt64(&p.Nanos),
+	}
+}
+
+// Profile is a QuerySink that passes rows
+// through to another QuerySink unchanged
+// while collecting a SinkProfile.
+//
+// See NewProfile.
+type Profile struct {
+	dst  QuerySink
+	prof *SinkProfile
+}
+
+// NewProfile constructs a Profile that writes
+// rows into dst and accumulates statistics into prof.
+func NewProfile(dst QuerySink, prof *SinkProfile) *Profile {
+	return &Profile{dst: dst, prof: prof}
+}
+
+func (p *Profile) Open() (io.WriteCloser, error) {
+	w, err := p.dst.Open()
+	if err != nil {
+		return nil, err
+	}
+	return splitter(&profiler{
+		parent: p,
+		dst:    asRowConsumer(w),
+	}), nil
+}
+
+func (p *Profile) Close() error {
+	start := time.Now()
+	err := p.dst.Close()
+	atomic.AddInt64(&p.prof.Nanos, int64(time.Since(start)))
+	return err
+}
+
+// profiler accumulates statistics locally
+// and flushes them to the parent Profile on Close
+type profiler struct {
+	parent *Profile
+	dst    rowConsumer
+	zdst   zionConsumer // set by zionOk
+	local  SinkProfile
+	done   bool
+}
+
+var _ zionConsumer = &profiler{}
+
+func (p *profiler) symbolize(st *symtab, aux *auxbindings) error {
+	start := time.Now()
+	var err error
+	if p.zdst != nil && st.flags&sfZion != 0 {
+		err = p.zdst.symbolize(st, aux)
+	} else {
+		err = p.dst.symbolize(st, aux)
+	}
+	p.local.Nanos += int64(time.Since(start))
+	return err
+}
+
+func (p *profiler) next() rowConsumer { return p.dst }
+
+func (p *profiler) writeRows(delims []vmref, params *rowParams) error {
+	p.local.Rows += int64(len(delims))
+	for i := range delims {
+		p.local.Bytes += int64(delims[i][1])
+	}
+	start := time.Now()
+	err := p.dst.writeRows(delims, params)
+	p.local.Nanos += int64(time.Since(start))
+	return err
+}
+
+func (p *profiler) zionOk(fields []string) bool {
+	z, ok := p.dst.(zionConsumer)
+	if !ok {
+		z = &zionFlattener{rowConsumer: p.dst}
+	}
+	if !z.zionOk(fields) {
+		return false
+	}
+	p.zdst = z
+	return true
+}
+
+func (p *profiler) writeZion(state *zionState) error {
+	n, err := state.shape.Count()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = p.zdst.writeZion(state)
+	p.local.Nanos += int64(time.Since(start))
+	p.local.Rows += int64(n)
+	p.local.Bytes += int64(len(state.buckets.Decompressed))
+	p.local.Buckets += zll.NumBuckets
+	for i := range state.buckets.Pos {
+		if state.buckets.Pos[i] < 0 {
+			p.local.Skipped++
+		}
+	}
+	return err
+}
+
+func (p *profiler) Close() error {
+	if p.done {
+		return nil
+	}
+	p.done = true
+	start := time.Now()
+	err := p.dst.Close()
+	p.local.Nanos += int64(time.Since(start))
+	p.parent.prof.Add(&p.local)
+	return err
+}
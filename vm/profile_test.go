@@ -0,0 +1,121 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/SnellerInc/sneller/ion"
+	"github.com/SnellerInc/sneller/ion/zion"
+	"github.com/SnellerInc/sneller/ion/zion/zll"
+)
+
+func TestProfile(t *testing.T) {
+	var st ion.Symtab
+	var buf ion.Buffer
+	for i := 0; i < 10; i++ {
+		s := ion.NewStruct(nil, []ion.Field{{
+			Label: "row",
+			Datum: ion.Int(int64(i)),
+		}, {
+			Label: "not_projected",
+			Datum: ion.String("a string!"),
+		}})
+		s.Encode(&buf, &st)
+	}
+	pos := buf.Size()
+	st.Marshal(&buf, true)
+	body := append(buf.Bytes()[pos:], buf.Bytes()[:pos]...)
+
+	t.Run("ion", func(t *testing.T) {
+		var prof SinkProfile
+		var c Count
+		p := NewProfile(&c, &prof)
+		w, err := p.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(body); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if c.Value() != 10 || prof.Rows != 10 {
+			t.Errorf("count %d, profile rows %d", c.Value(), prof.Rows)
+		}
+		if prof.Bytes == 0 {
+			t.Error("no bytes recorded")
+		}
+		if prof.Buckets != 0 || prof.Skipped != 0 {
+			t.Errorf("unexpected buckets %d (skipped %d)", prof.Buckets, prof.Skipped)
+		}
+	})
+	t.Run("zion", func(t *testing.T) {
+		var enc zion.Encoder
+		encoded, err := enc.Encode(body, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		run := func(t *testing.T, dst QuerySink, skipped int64) {
+			var prof SinkProfile
+			p := NewProfile(dst, &prof)
+			w, err := p.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !w.(*rowSplitter).ConfigureZion(PageSize, []string{"row"}) {
+				t.Fatal("ConfigureZion failed")
+			}
+			if _, err := w.Write(encoded); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if prof.Rows != 10 {
+				t.Errorf("profile rows %d", prof.Rows)
+			}
+			if prof.Buckets != zll.NumBuckets {
+				t.Errorf("got %d buckets", prof.Buckets)
+			}
+			if prof.Skipped != skipped {
+				t.Errorf("skipped %d buckets; expected %d", prof.Skipped, skipped)
+			}
+		}
+		t.Run("count", func(t *testing.T) {
+			// counting does not need to decompress any buckets
+			var c Count
+			run(t, &c, zll.NumBuckets)
+			if c.Value() != 10 {
+				t.Errorf("count %d", c.Value())
+			}
+		})
+		t.Run("flatten", func(t *testing.T) {
+			// only the bucket for "row" should be decompressed
+			var c Count
+			run(t, NewLimit(100, &c), zll.NumBuckets-1)
+			if c.Value() != 10 {
+				t.Errorf("count %d", c.Value())
+			}
+		})
+	})
+}
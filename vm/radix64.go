@@ -63,6 +63,11 @@ func newRadixTree(datasize int) *radixTree64 {
 	return rt
 }
 
+// size returns the memory used by the tree
+func (t *radixTree64) size() int {
+	return cap(t.index)*tabsize*4 + cap(t.values)
+}
+
 // use 'h' to find the leaf index pointer
 // returns (pointer, radix)
 func (t *radixTree64) find(h uint64) (*int32, int) {
@@ -330,6 +335,11 @@ type aggtable struct {
 	aggregateOps []AggregateOp
 	mergestate   bool
 
+	// values buffered for the ops with mergevalues,
+	// keyed by the offset of the state in tree.values;
+	// they are added to each state at once by flushValues
+	values map[int][]float32
+
 	// distinct ion values, concatenated;
 	// pointed to by pairs[].reprloc
 	//
@@ -342,6 +352,13 @@ type aggtable struct {
 	// has an hpair entry that holds
 	// the representation of each value
 	pairs []hpair
+
+	// partial aggregates spilled
+	// to disk, if any
+	spill *aggspill
+
+	// memory charged to the query Budget
+	mem memacct
 }
 
 // for an aggtable, get the hash of the value
@@ -635,6 +652,17 @@ func (a *aggtable) writeRows(delims []vmref, rp *rowParams) error {
 		}
 		delims = delims[n:]
 		if abort != 0 {
+			// spill only once we've made progress,
+			// since the new nodes must survive
+			// until the aborted rows are evaluated
+			if n > 0 && a.overBudget() {
+				if err := a.flushValues(); err != nil {
+					return err
+				}
+				if err := a.spillTable(); err != nil {
+					return err
+				}
+			}
 			if err := createNodes(abort); err != nil {
 				return err
 			}
@@ -651,11 +679,13 @@ func (a *aggtable) writeRows(delims []vmref, rp *rowParams) error {
 		}
 
 		dst := a.tree.values[aggregateTagSize:]
+		base := aggregateTagSize
 		for i := range a.aggregateOps {
 			op := a.aggregateOps[i]
 			n := op.dataSize()
 			if !op.mergestate() {
 				dst = dst[n:]
+				base += n
 				continue
 			}
 
@@ -665,17 +695,46 @@ func (a *aggtable) writeRows(delims []vmref, rp *rowParams) error {
 				if int32(bucket) == -1 {
 					continue
 				}
+				if op.mergevalues {
+					// the value of each lane takes the
+					// place of its offset and size
+					f := math.Float64frombits(binary.LittleEndian.Uint64(positions[8*i+1*64:]))
+					off := base + int(bucket)
+					a.values[off] = append(a.values[off], float32(f))
+					continue
+				}
 				offset := binary.LittleEndian.Uint32(positions[4*i+1*64:])
 				size := binary.LittleEndian.Uint32(positions[4*i+2*64:])
 				v := vmref{offset, size}
-				if !mergeAggregateBuffers(dst[bucket:], v.mem(), op) {
-					panic(fmt.Sprintf("aggregate %s expected to merge its buffer", op.fn))
+				if err := mergeAggregateBuffers(dst[bucket:], v.mem(), op); err != nil {
+					return err
 				}
 			}
 			dst = dst[n+aggregateOpMergeBufferSize:]
+			base += n + aggregateOpMergeBufferSize
 		}
 	}
+	if err := a.flushValues(); err != nil {
+		return err
+	}
+	if a.overBudget() {
+		return a.spillTable()
+	}
+	return a.accountOrSpill()
+}
 
+// flushValues adds the buffered values
+// to the t-digest state of each group,
+// so that each state is decoded and
+// encoded once per batch of rows
+// rather than once per value
+func (a *aggtable) flushValues() error {
+	for off, v := range a.values {
+		delete(a.values, off)
+		if err := tDigestAdd(a.tree.values[off:], v); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -684,6 +743,14 @@ func (a *aggtable) Close() error {
 	parent := a.parent
 	atomic.AddInt64(&parent.rowcount, a.rows)
 	a.rows = 0
+
+	// once this table has spilled,
+	// spill everything so that no partition
+	// is held in memory more than once
+	var err error
+	if a.spill != nil {
+		err = a.spillTable()
+	}
 	parent.lock.Lock()
 
 	// a little clever:
@@ -704,15 +771,26 @@ func (a *aggtable) Close() error {
 		parent.final = nil
 		parent.lock.Unlock()
 		a.merge(tmp)
+		tmp.mem.release()
 		parent.lock.Lock()
 	}
+	if err == nil && a.overBudget() {
+		err = a.spillTable()
+	}
+	if err == nil {
+		err = a.accountOrSpill()
+	}
+	if a.spill != nil {
+		parent.spills = append(parent.spills, a.spill)
+		a.spill = nil
+	}
 
 	parent.final = a
 	if atomic.AddInt64(&parent.children, -1) < 0 {
 		panic("duplicate aggtable.Close()")
 	}
 	parent.lock.Unlock()
-	return nil
+	return err
 }
 
 // merge the right-hand-side table into
@@ -723,22 +801,25 @@ func (a *aggtable) merge(r *aggtable) {
 	for i := range r.pairs {
 		p := &r.pairs[i]
 		// get value from rhs
-		hash := r.hashof(p)
-		repr := r.fullrepr(p, len(a.parent.by))
-		value := r.valueof(p)
-
-		// regular insert slow path for lhs
-		off, ok := a.tree.insertSlow(hash)
-		if ok {
-			reprloc := int32(len(a.repr))
-			a.repr = append(a.repr, repr...)
-			a.pairs = append(a.pairs, hpair{
-				reprloc: reprloc,
-				hloc:    off,
-			})
-			a.initentry(a.tree.values[off+8:])
-		}
+		a.mergeEntry(r.hashof(p), r.fullrepr(p, len(a.parent.by)), r.valueof(p))
+	}
+}
 
-		mergeAggregatedValues(a.tree.values[off+8:], value, a.aggregateOps)
+// mergeEntry merges a single group with the given
+// hash, grouping columns and aggregate value
+// into the table
+func (a *aggtable) mergeEntry(hash uint64, repr, value []byte) {
+	// regular insert slow path for lhs
+	off, ok := a.tree.insertSlow(hash)
+	if ok {
+		reprloc := int32(len(a.repr))
+		a.repr = append(a.repr, repr...)
+		a.pairs = append(a.pairs, hpair{
+			reprloc: reprloc,
+			hloc:    off,
+		})
+		a.initentry(a.tree.values[off+8:])
 	}
+
+	mergeHashAggregatedValues(a.tree.values[off+8:], value, a.aggregateOps)
 }
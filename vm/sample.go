@@ -0,0 +1,97 @@
+// Copyright (C) 2023 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"io"
+	"math/rand"
+)
+
+// Sample is a QuerySink that writes
+// a random sample of the rows it receives
+// to the next QuerySink.
+//
+// See NewSample
+type Sample struct {
+	fraction float64
+	dst      QuerySink
+}
+
+type sampler struct {
+	parent *Sample
+	dst    rowConsumer
+	rand   *rand.Rand
+	params rowParams
+}
+
+// NewSample constructs a Sample that writes
+// each row to dst independently with the
+// probability fraction (Bernoulli sampling).
+func NewSample(fraction float64, dst QuerySink) *Sample {
+	return &Sample{
+		fraction: fraction,
+		dst:      dst,
+	}
+}
+
+func (s *Sample) Open() (io.WriteCloser, error) {
+	w, err := s.dst.Open()
+	if err != nil {
+		return nil, err
+	}
+	return splitter(&sampler{
+		parent: s,
+		dst:    asRowConsumer(w),
+		rand:   rand.New(rand.NewSource(rand.Int63())),
+	}), nil
+}
+
+func (s *Sample) Close() error {
+	return s.dst.Close()
+}
+
+func (s *sampler) symbolize(st *symtab, aux *auxbindings) error {
+	s.params.auxbound = shrink(s.params.auxbound, len(aux.bound))
+	return s.dst.symbolize(st, aux)
+}
+
+func (s *sampler) next() rowConsumer { return s.dst }
+
+func (s *sampler) writeRows(rows []vmref, rp *rowParams) error {
+	// compress the sampled rows
+	// (and aux values) in place
+	n := 0
+	for i := range rows {
+		if s.rand.Float64() >= s.parent.fraction {
+			continue
+		}
+		rows[n] = rows[i]
+		for j := range rp.auxbound {
+			rp.auxbound[j][n] = rp.auxbound[j][i]
+		}
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	for j := range s.params.auxbound {
+		s.params.auxbound[j] = sanitizeAux(rp.auxbound[j], n)
+	}
+	return s.dst.writeRows(rows[:n], &s.params)
+}
+
+func (s *sampler) Close() error {
+	return s.dst.Close()
+}
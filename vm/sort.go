@@ -101,16 +101,19 @@ type Order struct {
 	parallelism int          // number of threads
 	prog        prog         // program for capturing fields
 
-	// symbol table for the whole input
-	symtab *ion.Symtab
-
-	// lock for writing to `symtab`
-	symtabLock sync.Mutex
-
 	// collection of k-top rows
 	kheap kheap
 
-	// lock for writing to the heap
+	// sorted runs produced by each thread
+	// when there is no limit
+	runs []*sortrun
+
+	// budget for the in-memory runs;
+	// mem is the charge for s.runs
+	budget *Budget
+	mem    memacct
+
+	// lock for writing to the heap or runs
 	recordsLock sync.Mutex
 }
 
@@ -118,9 +121,11 @@ type Order struct {
 // sorts the provided columns (in left-to-right order).
 // If limit is non-nil, then the number of rows output
 // by the Order will be less than or equal to the limit.
+// Otherwise every row is sorted, and rows that do not
+// fit in SortMemory are spilled to temporary files.
 func NewOrder(dst io.Writer, columns []SortColumn, limit *SortLimit, parallelism int) (*Order, error) {
-	if limit == nil {
-		limit = &SortLimit{Limit: 100000}
+	if parallelism < 1 {
+		parallelism = 1
 	}
 	s := &Order{
 		columns:     columns,
@@ -143,25 +148,6 @@ func NewOrder(dst io.Writer, columns []SortColumn, limit *SortLimit, parallelism
 	return s, nil
 }
 
-// setSymbolTable sets symbol table for the sorted data.
-//
-// It's expected that all input chunks have exactly the same symtab.
-// Otherwise we either have to:
-// 1. align all symtabs into a common one and recode all records;
-// 2. preprend possibliy each record with symtab.
-func (s *Order) setSymbolTable(st *symtab) error {
-	if s.symtab == nil {
-		s.symtab = new(ion.Symtab)
-		st.Symtab.CloneInto(s.symtab)
-		return nil
-	}
-	_, ok := s.symtab.Merge(&st.Symtab)
-	if !ok {
-		return fmt.Errorf("symtab changed, can't sort data")
-	}
-	return nil
-}
-
 func (s *Order) orderList() []SortOrdering {
 	orders := make([]SortOrdering, len(s.columns))
 	for i := range s.columns {
@@ -170,8 +156,24 @@ func (s *Order) orderList() []SortOrdering {
 	return orders
 }
 
+// SetBudget sets the Budget to which the memory
+// used by the rows buffered for sorting is charged.
+// (Rows sorted with a limit are not charged,
+// as there are at most LIMIT+OFFSET of them.)
+func (s *Order) SetBudget(b *Budget) {
+	s.budget = b
+	s.mem.budget = b
+}
+
 // Open implements QuerySink.Open
 func (s *Order) Open() (io.WriteCloser, error) {
+	if s.limit == nil {
+		ss := &sortstateSpill{parent: s, run: new(sortrun)}
+		ss.mem.budget = s.budget
+		ss.fields = s.orderList()
+		ss.memory = SortMemory / s.parallelism
+		return splitter(ss), nil
+	}
 	kt := &sortstateKtop{parent: s}
 	kt.kheap.fields = s.orderList()
 	// we'll trim this later:
@@ -188,36 +190,53 @@ func (s *Order) Close() error {
 	// s.sub safely
 	// s.wg.Wait()
 
+	if s.limit == nil {
+		return s.finalizeSpill()
+	}
 	return s.finalizeKtop()
 }
 
-func (s *Order) finalizeKtop() error {
-	var globalst ion.Symtab
-	var tmp ion.Buffer
-
-	// once we have accumulated this many data bytes,
-	// flush the output buffer:
-	const flushAt = PageSize / 2
-
-	// temporary buffer for flushing:
-	var out []byte
-	flush := func() error {
-		slice := tmp.Size()
-		if slice == 0 {
-			return nil
-		}
-		globalst.Marshal(&tmp, true)
-		out = append(out[:0], tmp.Bytes()[slice:]...)
-		out = append(out, tmp.Bytes()[:slice]...)
-		globalst.Reset()
-		tmp.Reset()
-		_, err := s.dst.Write(out)
-		return err
+// orderOutput buffers the sorted rows
+// and writes them to the destination
+// in chunks with their own symbol table
+type orderOutput struct {
+	dst io.Writer
+	st  ion.Symtab
+	tmp ion.Buffer
+	out []byte // temporary buffer for flushing
+}
+
+// once we have accumulated this many data bytes,
+// flush the output buffer:
+const orderFlushAt = PageSize / 2
+
+func (o *orderOutput) write(d ion.Datum) error {
+	d.Encode(&o.tmp, &o.st)
+	if o.tmp.Size() >= orderFlushAt {
+		return o.flush()
 	}
+	return nil
+}
 
+func (o *orderOutput) flush() error {
+	slice := o.tmp.Size()
+	if slice == 0 {
+		return nil
+	}
+	o.st.Marshal(&o.tmp, true)
+	o.out = append(o.out[:0], o.tmp.Bytes()[slice:]...)
+	o.out = append(o.out, o.tmp.Bytes()[:slice]...)
+	o.st.Reset()
+	o.tmp.Reset()
+	_, err := o.dst.Write(o.out)
+	return err
+}
+
+func (s *Order) finalizeKtop() error {
+	out := orderOutput{dst: s.dst}
 	off := s.limit.Offset
 	if off >= len(s.kheap.heaporder) {
-		return flush() // symbol table + no data
+		return out.flush() // symbol table + no data
 	}
 	// reverse the max-heap ordering
 	// to end up with the final desired ordering,
@@ -235,36 +254,17 @@ func (s *Order) finalizeKtop() error {
 		i--
 	}
 	for i := range final {
-		rec := &final[i]
-		rec.data.Encode(&tmp, &globalst)
-		if tmp.Size() >= flushAt {
-			err := flush()
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return flush()
-}
-
-// ----------------------------------------------------------------------
-
-func symbolize(sort *Order, dst *prog, findbc *bytecode, st *symtab, aux *auxbindings, global bool) error {
-	if global {
-		sort.symtabLock.Lock()
-		defer sort.symtabLock.Unlock()
-		err := sort.setSymbolTable(st)
+		err := out.write(final[i].data)
 		if err != nil {
 			return err
 		}
-
-		return symbolizeLocal(sort, dst, findbc, st, aux)
-	} else {
-		return symbolizeLocal(sort, dst, findbc, st, aux)
 	}
+	return out.flush()
 }
 
-func symbolizeLocal(sort *Order, dst *prog, findbc *bytecode, st *symtab, aux *auxbindings) error {
+// ----------------------------------------------------------------------
+
+func symbolize(sort *Order, dst *prog, findbc *bytecode, st *symtab, aux *auxbindings) error {
 	err := recompile(st, &sort.prog, dst, findbc, aux, "sort findbc")
 	if err != nil {
 		return fmt.Errorf("sortstate.symbolize(): %w", err)
@@ -355,7 +355,7 @@ func (s *sortstateKtop) symbolize(st *symtab, aux *auxbindings) error {
 	for i := range s.aux.bound {
 		s.auxsyms = append(s.auxsyms, st.Intern(s.aux.bound[i]))
 	}
-	return symbolize(s.parent, &s.prog, &s.findbc, st, aux, false)
+	return symbolize(s.parent, &s.prog, &s.findbc, st, aux)
 }
 
 func (s *sortstateKtop) bcfind(delims []vmref, rp *rowParams) ([]vRegData, error) {
@@ -454,7 +454,8 @@ func (s *sortstateKtop) maybePrefilter() error {
 			return nil
 		}
 
-		v, err := compile(p, s.parent.columns[colnum].Node)
+		col := &s.parent.columns[colnum]
+		v, err := compile(p, col.Node)
 		if err != nil {
 			return err
 		}
@@ -464,13 +465,13 @@ func (s *sortstateKtop) maybePrefilter() error {
 
 		// v[i] < recent[i]
 		var less *value
-		switch s.parent.columns[0].Ordering.Direction {
+		switch col.Ordering.Direction {
 		case SortAscending:
 			less = p.and(validtype, cmplt(v, imm))
 		case SortDescending:
 			less = p.and(validtype, cmplt(imm, v))
 		default:
-			return fmt.Errorf("unrecognized sort direction %d", s.parent.columns[0].Ordering.Direction)
+			return fmt.Errorf("unrecognized sort direction %d", col.Ordering.Direction)
 		}
 
 		// v[i] == recent[i]
@@ -556,10 +557,15 @@ func (k *kheap) greater(left, right int) bool {
 }
 
 func (k *kheap) reccmp(lr, rr *krecord) int {
-	lrdata, rrdata := lr.order, rr.order
-	for i := range k.fields {
+	return compareOrder(k.fields, lr.order, rr.order)
+}
+
+// compareOrder compares two sets of
+// flattened ordering fields
+func compareOrder(fields []SortOrdering, lrdata, rrdata []byte) int {
+	for i := range fields {
 		ls, rs := ion.SizeOf(lrdata), ion.SizeOf(rrdata)
-		cmp := k.fields[i].Compare(lrdata[:ls], rrdata[:rs])
+		cmp := fields[i].Compare(lrdata[:ls], rrdata[:rs])
 		if cmp != 0 {
 			return cmp
 		}
@@ -620,27 +626,7 @@ outer:
 		if datptr == nil {
 			continue
 		}
-		s.scratch.Reset()
-		s.scratch.BeginStruct(-1)
-		// TODO: speed up the transcoding process here:
-		data := delims[rowID].mem()
-		for len(data) > 0 {
-			var sym ion.Symbol
-			sym, data, _ = ion.ReadLabel(data)
-			s.scratch.BeginField(sym)
-			size := ion.SizeOf(data)
-			s.scratch.UnsafeAppend(data[:size])
-			data = data[size:]
-		}
-		for j := range s.auxsyms {
-			mem := rp.auxbound[j][rowID].mem()
-			if len(mem) == 0 {
-				continue
-			}
-			s.scratch.BeginField(s.auxsyms[j])
-			s.scratch.UnsafeAppend(mem)
-		}
-		s.scratch.EndStruct()
+		captureRow(&s.scratch, delims, rowID, s.auxsyms, rp)
 		dat, _, _ := ion.ReadDatum(&s.st.Symtab, s.scratch.Bytes())
 		dat.CloneInto(datptr)
 		s.invalidatePrefilter()
@@ -657,6 +643,32 @@ outer:
 	return nil
 }
 
+// captureRow writes delims[rowID] plus
+// its aux bindings into dst as a structure
+func captureRow(dst *ion.Buffer, delims []vmref, rowID int, auxsyms []ion.Symbol, rp *rowParams) {
+	dst.Reset()
+	dst.BeginStruct(-1)
+	// TODO: speed up the transcoding process here:
+	data := delims[rowID].mem()
+	for len(data) > 0 {
+		var sym ion.Symbol
+		sym, data, _ = ion.ReadLabel(data)
+		dst.BeginField(sym)
+		size := ion.SizeOf(data)
+		dst.UnsafeAppend(data[:size])
+		data = data[size:]
+	}
+	for j := range auxsyms {
+		mem := rp.auxbound[j][rowID].mem()
+		if len(mem) == 0 {
+			continue
+		}
+		dst.BeginField(auxsyms[j])
+		dst.UnsafeAppend(mem)
+	}
+	dst.EndStruct()
+}
+
 func (s *sortstateKtop) Close() error {
 	if s.parentNotified {
 		return nil
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"fmt"
 	"math/rand"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
@@ -517,6 +518,58 @@ func TestSortWithLimit(t *testing.T) {
 	compareIonWithExpectations(t, output.Bytes(), expected)
 }
 
+func TestSortWithLimitMixedDirections(t *testing.T) {
+	// the prefilter for the top-k rows has to
+	// respect the direction of each column
+	orderBy := []SortColumn{
+		makeOrdering("a", SortAscending, SortNullsFirst),
+		makeOrdering("b", SortDescending, SortNullsFirst),
+	}
+
+	const rowsCount = 50000
+	ints := rand.Perm(rowsCount)
+
+	var buf ion.Buffer
+	var st ion.Symtab
+	idSym := st.Intern("id")
+	aSym := st.Intern("a")
+	bSym := st.Intern("b")
+	buf.StartChunk(&st)
+	for i, n := range ints {
+		buf.BeginStruct(-1)
+		buf.BeginField(idSym)
+		buf.WriteInt(int64(i))
+		buf.BeginField(aSym)
+		buf.WriteInt(int64(n % 10))
+		buf.BeginField(bSym)
+		buf.WriteInt(int64(n))
+		buf.EndStruct()
+	}
+
+	limit := SortLimit{Limit: 5}
+
+	const parallelism = 4
+
+	output := new(bytes.Buffer)
+	sorter, err := NewOrder(output, orderBy, &limit, parallelism)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CopyRows(sorter, buftbl(buf.Bytes()), parallelism)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sorter.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"0, 49990", "0, 49980", "0, 49970", "0, 49960", "0, 49950",
+	}
+	compareIonWithExpectations(t, output.Bytes(), expected)
+}
+
 func limitTestIon(rowsCount int) (result []byte, err error) {
 	var buf ion.Buffer
 	var st ion.Symtab
@@ -545,6 +598,249 @@ func limitTestIon(rowsCount int) (result []byte, err error) {
 	return buf.Bytes(), nil
 }
 
+func TestSortSpill(t *testing.T) {
+	const (
+		chunks    = 8
+		perChunk  = 500
+		align     = 32 * 1024
+		rowsCount = chunks * perChunk
+	)
+	keys := make([]int, rowsCount)
+	for i := range keys {
+		keys[i] = i
+	}
+	rand.Shuffle(rowsCount, func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+
+	// each chunk has a different symbol table
+	input := make([]byte, chunks*align)
+	var buf ion.Buffer
+	var st ion.Symtab
+	for c := 0; c < chunks; c++ {
+		buf.Reset()
+		st.Reset()
+		for i := 0; i < c; i++ {
+			st.Intern(fmt.Sprintf("unused-%d", i))
+		}
+		keySym := st.Intern("key")
+		nameSym := st.Intern("name")
+		var names [7]ion.Symbol
+		for i := range names {
+			names[i] = st.Intern(fmt.Sprintf("row-%d", i))
+		}
+		buf.StartChunk(&st)
+		for _, k := range keys[c*perChunk : (c+1)*perChunk] {
+			buf.BeginStruct(-1)
+			buf.BeginField(keySym)
+			buf.WriteInt(int64(k))
+			buf.BeginField(nameSym)
+			buf.WriteSymbol(names[k%7])
+			buf.EndStruct()
+		}
+		mem := input[c*align : (c+1)*align]
+		noppad(mem[copy(mem, buf.Bytes()):])
+	}
+
+	defer func(mem, fanin int, dir string) {
+		SortMemory, spillFanIn, SpillDir = mem, fanin, dir
+	}(SortMemory, spillFanIn, SpillDir)
+	SortMemory = 16 * 1024
+
+	// with a fan-in of 2, the runs are
+	// merged into longer runs in several passes
+	for _, fanin := range []int{spillFanIn, 2} {
+		t.Run(fmt.Sprintf("fanin=%d", fanin), func(t *testing.T) {
+			spillFanIn = fanin
+			SpillDir = t.TempDir()
+			testSortSpill(t, input, align, rowsCount)
+		})
+	}
+}
+
+func testSortSpill(t *testing.T, input []byte, align, rowsCount int) {
+	const parallelism = 4
+	orderBy := []SortColumn{makeOrdering("key", SortAscending, SortNullsFirst)}
+	output := new(bytes.Buffer)
+	sorter, err := NewOrder(output, orderBy, nil, parallelism)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CopyRows(sorter, BufferTable(input, align), parallelism)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// check that some runs were spilled
+	spilled, err := os.ReadDir(SpillDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spilled) == 0 {
+		t.Fatal("no runs were spilled")
+	}
+	err = sorter.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, err := os.ReadDir(SpillDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 0 {
+		t.Errorf("%d spill files were not removed", len(left))
+	}
+
+	var outst ion.Symtab
+	rest := output.Bytes()
+	n := 0
+	for len(rest) > 0 {
+		var d ion.Datum
+		d, rest, err = ion.ReadDatum(&outst, rest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.IsEmpty() {
+			continue
+		}
+		s, err := d.Struct()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var key int64
+		var name string
+		err = s.Each(func(f ion.Field) error {
+			var err error
+			switch f.Label {
+			case "key":
+				key, err = f.Int()
+			case "name":
+				name, err = f.String()
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != int64(n) {
+			t.Fatalf("row %d: got key %d", n, key)
+		}
+		if want := fmt.Sprintf("row-%d", key%7); name != want {
+			t.Fatalf("row %d: got name %q, want %q", n, name, want)
+		}
+		n++
+	}
+	if n != rowsCount {
+		t.Errorf("got %d rows, want %d", n, rowsCount)
+	}
+}
+
+// TestSortProjectedRows sorts rows that were
+// projected into a buffer, as the rows of a split
+// query are before the final ORDER BY. Each batch
+// of projected rows is larger than the projection's
+// output buffer, so the projection has to stop
+// part way through a block of rows.
+func TestSortProjectedRows(t *testing.T) {
+	const rowsCount = 400
+
+	keys := make([]int, rowsCount)
+	for i := range keys {
+		keys[i] = i
+	}
+	rand.Shuffle(rowsCount, func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+
+	var buf ion.Buffer
+	var st ion.Symtab
+	keySym := st.Intern("key")
+	padSym := st.Intern("pad")
+	buf.StartChunk(&st)
+	for _, k := range keys {
+		buf.BeginStruct(-1)
+		buf.BeginField(keySym)
+		buf.WriteInt(int64(k))
+		buf.BeginField(padSym)
+		buf.WriteString(strings.Repeat(fmt.Sprintf("%c", 'a'+k%26), 2000+k%37))
+		buf.EndStruct()
+	}
+	input := make([]byte, defaultAlign)
+	if buf.Size() > len(input) {
+		t.Fatalf("input is %d bytes", buf.Size())
+	}
+	noppad(input[copy(input, buf.Bytes()):])
+
+	var projected QueryBuffer
+	proj, err := NewProjection(selection("key, pad as a, pad as b, pad as c"), &projected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CopyRows(proj, BufferTable(input, defaultAlign), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if projected.Size() <= int64(PageSize) {
+		t.Fatalf("projected only %d bytes", projected.Size())
+	}
+
+	orderBy := []SortColumn{makeOrdering("key", SortAscending, SortNullsFirst)}
+	output := new(bytes.Buffer)
+	sorter, err := NewOrder(output, orderBy, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CopyRows(sorter, projected.Table(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sorter.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var outst ion.Symtab
+	rest := output.Bytes()
+	n := 0
+	for len(rest) > 0 {
+		var d ion.Datum
+		d, rest, err = ion.ReadDatum(&outst, rest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.IsEmpty() {
+			continue
+		}
+		s, err := d.Struct()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := int64(-1)
+		var pad []string
+		err = s.Each(func(f ion.Field) error {
+			var err error
+			switch f.Label {
+			case "key":
+				key, err = f.Int()
+			case "a", "b", "c":
+				var str string
+				str, err = f.String()
+				pad = append(pad, str)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != int64(n) {
+			t.Fatalf("row %d: got key %d", n, key)
+		}
+		want := strings.Repeat(fmt.Sprintf("%c", 'a'+key%26), 2000+int(key%37))
+		if len(pad) != 3 || pad[0] != want || pad[1] != want || pad[2] != want {
+			t.Fatalf("row %d: unexpected projected fields", n)
+		}
+		n++
+	}
+	if n != rowsCount {
+		t.Errorf("got %d rows, want %d", n, rowsCount)
+	}
+}
+
 // --------------------------------------------------
 
 func parseIonRecords(bytes []byte) (result []string, err error) {
@@ -0,0 +1,541 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/SnellerInc/sneller/heap"
+	"github.com/SnellerInc/sneller/ion"
+)
+
+// SortMemory is the approximate number of bytes
+// that an ORDER BY without a LIMIT buffers in memory
+// (across all threads) before it spills sorted runs
+// of rows to temporary files in SpillDir.
+var SortMemory = 64 << 20
+
+// once a run being spilled has accumulated
+// this many bytes, write it to the file:
+const spillFlushAt = 64 * 1024
+
+// spillFanIn is the maximum number of runs that are
+// merged at once; each spilled run being merged has
+// an open file and a read buffer of spillFlushAt bytes.
+// When there are more runs, groups of runs are first
+// merged into longer runs in temporary files.
+var spillFanIn = 64
+
+// sortrec is the location of one row
+// and its ordering fields within a sortrun
+type sortrec struct {
+	keyoff, keyend int
+	rowoff, rowend int
+}
+
+// sortrun is a sorted run of rows;
+// the rows are either held in memory
+// or spilled to a temporary file
+//
+// Each run has its own symbol table, so
+// input chunks with different symbol tables
+// can be sorted together.
+type sortrun struct {
+	st ion.Symtab
+
+	// in-memory rows:
+	keys []byte
+	rows ion.Buffer
+	recs []sortrec
+	pos  int
+
+	// spilled rows; each row is stored
+	// as a list of [ordering fields blob, row]
+	// following the symbol table
+	f   *os.File
+	r   *bufio.Reader
+	buf []byte
+
+	// current row during merging; see next()
+	key, row []byte
+	// raw is set if the rows can be copied
+	// as-is into the output of the merge
+	raw bool
+}
+
+// add adds a row encoded with the symbol table st
+func (r *sortrun) add(cols [][]byte, st *ion.Symtab, row []byte) error {
+	rec := sortrec{keyoff: len(r.keys), rowoff: r.rows.Size()}
+	for i := range cols {
+		r.keys = append(r.keys, cols[i]...)
+	}
+	// the input chunks usually have the same
+	// symbol table, or each extends the previous
+	// one, so the rows can be copied as-is
+	switch {
+	case r.st.Contains(st):
+		r.rows.UnsafeAppend(row)
+	case st.Contains(&r.st):
+		st.CloneInto(&r.st)
+		r.rows.UnsafeAppend(row)
+	default:
+		dat, _, err := ion.ReadDatum(st, row)
+		if err != nil {
+			return err
+		}
+		dat.Encode(&r.rows, &r.st)
+	}
+	rec.keyend, rec.rowend = len(r.keys), r.rows.Size()
+	r.recs = append(r.recs, rec)
+	return nil
+}
+
+// size returns the approximate memory used by the run
+func (r *sortrun) size() int {
+	return len(r.keys) + r.rows.Size() + len(r.recs)*int(unsafe.Sizeof(sortrec{}))
+}
+
+func (r *sortrun) reckey(i int) []byte {
+	return r.keys[r.recs[i].keyoff:r.recs[i].keyend]
+}
+
+func (r *sortrun) recrow(i int) []byte {
+	return r.rows.Bytes()[r.recs[i].rowoff:r.recs[i].rowend]
+}
+
+func (r *sortrun) sort(fields []SortOrdering) {
+	keys := r.keys
+	slices.SortFunc(r.recs, func(a, b sortrec) bool {
+		return compareOrder(fields, keys[a.keyoff:a.keyend], keys[b.keyoff:b.keyend]) < 0
+	})
+}
+
+func (r *sortrun) reset() {
+	r.st.Reset()
+	r.keys = r.keys[:0]
+	r.rows.Reset()
+	r.recs = r.recs[:0]
+}
+
+// writeTo writes the sorted rows to w
+func (r *sortrun) writeTo(w io.Writer) error {
+	var buf ion.Buffer
+	r.st.Marshal(&buf, true)
+	for i := range r.recs {
+		buf.BeginList(-1)
+		buf.WriteBlob(r.reckey(i))
+		buf.UnsafeAppend(r.recrow(i))
+		buf.EndList()
+		if buf.Size() >= spillFlushAt {
+			if _, err := w.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// open prepares the run for merging
+func (r *sortrun) open() error {
+	r.pos = 0
+	if r.f == nil {
+		return nil
+	}
+	if _, err := r.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r.r = bufio.NewReaderSize(r.f, spillFlushAt)
+	ok, err := r.read()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("sort: spilled run %s is empty", r.f.Name())
+	}
+	_, err = r.st.Unmarshal(r.buf)
+	return err
+}
+
+// read reads the next ion value from the spilled run
+func (r *sortrun) read() (bool, error) {
+	_, size, err := ion.Peek(r.r)
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	r.buf = slices.Grow(r.buf[:0], size)[:size]
+	_, err = io.ReadFull(r.r, r.buf)
+	return err == nil, err
+}
+
+// next advances r.key and r.row to the next row,
+// returning false once the run is exhausted
+func (r *sortrun) next() (bool, error) {
+	if r.f == nil {
+		if r.pos >= len(r.recs) {
+			return false, nil
+		}
+		r.key, r.row = r.reckey(r.pos), r.recrow(r.pos)
+		r.pos++
+		return true, nil
+	}
+	ok, err := r.read()
+	if !ok || err != nil {
+		return false, err
+	}
+	body, _ := ion.Contents(r.buf)
+	if body == nil {
+		return false, fmt.Errorf("sort: corrupt spilled run %s", r.f.Name())
+	}
+	r.key, r.row, err = ion.ReadBytesShared(body)
+	if err != nil {
+		return false, fmt.Errorf("sort: reading spilled run %s: %w", r.f.Name(), err)
+	}
+	return true, nil
+}
+
+// release removes the temporary file backing the run
+func (r *sortrun) release() {
+	if r.f != nil {
+		removeSpill(r.f)
+		r.f = nil
+	}
+}
+
+// appendRow appends the current row to dst
+// encoded with the symbol table of the merge
+// (see mergeSymtab)
+func (r *sortrun) appendRow(dst *ion.Buffer, st *ion.Symtab) error {
+	if r.raw {
+		dst.UnsafeAppend(r.row)
+		return nil
+	}
+	dat, _, err := ion.ReadDatum(&r.st, r.row)
+	if err != nil {
+		return err
+	}
+	dat.Encode(dst, st)
+	return nil
+}
+
+// mergeSymtab opens runs for merging and returns
+// the symbol table of the merged rows; the rows of
+// the runs whose symbol table is a prefix of it are
+// copied as-is, and the other rows are resymbolized,
+// which does not add symbols to the symbol table
+func mergeSymtab(runs []*sortrun) (*ion.Symtab, error) {
+	st := new(ion.Symtab)
+	for _, r := range runs {
+		if err := r.open(); err != nil {
+			return nil, err
+		}
+		switch {
+		case st.Contains(&r.st):
+			r.raw = true
+		case r.st.Contains(st):
+			r.st.CloneInto(st)
+			r.raw = true
+		default:
+			r.raw = false
+		}
+	}
+	for _, r := range runs {
+		if !r.raw {
+			for i := 0; i < r.st.MaxID(); i++ {
+				st.Intern(r.st.Get(ion.Symbol(i)))
+			}
+		}
+	}
+	return st, nil
+}
+
+// merge calls emit with each of the runs (which
+// must have been opened) positioned at the next
+// row in the order of fields
+func merge(runs []*sortrun, fields []SortOrdering, emit func(r *sortrun) error) error {
+	less := func(x, y *sortrun) bool {
+		return compareOrder(fields, x.key, y.key) < 0
+	}
+	var heaped []*sortrun
+	for _, r := range runs {
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.PushSlice(&heaped, r, less)
+		}
+	}
+	for len(heaped) > 0 {
+		r := heaped[0]
+		if err := emit(r); err != nil {
+			return err
+		}
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.FixSlice(heaped, 0, less)
+		} else {
+			heap.PopSlice(&heaped, less)
+		}
+	}
+	return nil
+}
+
+// mergeSpill merges runs into a new spilled run
+func mergeSpill(runs []*sortrun, fields []SortOrdering) (*sortrun, error) {
+	st, err := mergeSymtab(runs)
+	if err != nil {
+		return nil, err
+	}
+	f, err := createSpill("sort")
+	if err != nil {
+		return nil, fmt.Errorf("sort: creating spill file: %w", err)
+	}
+	out := &sortrun{f: f}
+	w := bufio.NewWriterSize(f, spillFlushAt)
+	var buf ion.Buffer
+	st.Marshal(&buf, true)
+	err = merge(runs, fields, func(r *sortrun) error {
+		buf.BeginList(-1)
+		buf.WriteBlob(r.key)
+		if err := r.appendRow(&buf, st); err != nil {
+			return err
+		}
+		buf.EndList()
+		if buf.Size() < spillFlushAt {
+			return nil
+		}
+		_, err := w.Write(buf.Bytes())
+		buf.Reset()
+		return err
+	})
+	if err == nil {
+		_, err = w.Write(buf.Bytes())
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		out.release()
+		return nil, fmt.Errorf("sort: merging spilled runs: %w", err)
+	}
+	return out, nil
+}
+
+// finalizeSpill merges all the sorted runs
+// into the destination, merging at most
+// spillFanIn runs at a time
+func (s *Order) finalizeSpill() error {
+	runs := s.runs
+	s.runs = nil
+	defer func() {
+		for i := range runs {
+			runs[i].release()
+		}
+		s.mem.release()
+	}()
+
+	fields := s.orderList()
+	for len(runs) > spillFanIn {
+		// merge the runs from the front into
+		// a run at the back so that every
+		// run is merged once per pass
+		n := spillFanIn
+		if n > len(runs)-spillFanIn+1 {
+			n = len(runs) - spillFanIn + 1
+		}
+		merged, err := mergeSpill(runs[:n], fields)
+		if err != nil {
+			return err
+		}
+		for i := range runs[:n] {
+			runs[i].release()
+		}
+		runs = append(runs[n:], merged)
+	}
+
+	st, err := mergeSymtab(runs)
+	if err != nil {
+		return err
+	}
+	var rows, out ion.Buffer
+	flush := func() error {
+		if rows.Size() == 0 {
+			return nil
+		}
+		out.Reset()
+		st.Marshal(&out, true)
+		out.UnsafeAppend(rows.Bytes())
+		rows.Reset()
+		_, err := s.dst.Write(out.Bytes())
+		return err
+	}
+	err = merge(runs, fields, func(r *sortrun) error {
+		if err := r.appendRow(&rows, st); err != nil {
+			return err
+		}
+		if rows.Size() >= orderFlushAt {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// ----------------------------------------------------------------------
+
+// sortstateSpill sorts all of its input rows,
+// spilling sorted runs to temporary files
+// once they exceed its share of SortMemory
+type sortstateSpill struct {
+	// the parent context for this sorting operation
+	parent *Order
+	fields []SortOrdering
+
+	// most recent aux bindings
+	// passed to symbolize()
+	aux *auxbindings
+	// auxyms[i] corresponds to aux.bound[i]
+	// for the most recent symbol table
+	auxsyms []ion.Symbol
+
+	parentNotified bool
+
+	// bytecode for locating columns
+	findbc bytecode
+	prog   prog
+	// most recent symbolize() symtab
+	st *symtab
+
+	scratch ion.Buffer
+	colbuf  [][]byte
+
+	run     *sortrun   // rows buffered in memory
+	spilled []*sortrun // runs written to files
+	memory  int        // max size of run
+	mem     memacct    // charge for run
+}
+
+func (s *sortstateSpill) next() rowConsumer { return nil }
+
+func (s *sortstateSpill) EndSegment() {
+	s.findbc.dropScratch() // restored in symbolize()
+}
+
+func (s *sortstateSpill) symbolize(st *symtab, aux *auxbindings) error {
+	s.st = st
+	s.aux = aux
+	s.auxsyms = s.auxsyms[:0]
+	for i := range s.aux.bound {
+		s.auxsyms = append(s.auxsyms, st.Intern(s.aux.bound[i]))
+	}
+	return symbolize(s.parent, &s.prog, &s.findbc, st, aux)
+}
+
+func (s *sortstateSpill) writeRows(delims []vmref, rp *rowParams) error {
+	if len(delims) == 0 {
+		return nil
+	}
+	fieldsView, err := bcfind(s.parent, &s.findbc, delims, rp)
+	if err != nil {
+		return err
+	}
+	cols := shrink(s.colbuf, len(s.fields))
+outer:
+	for rowID := 0; rowID < len(delims); rowID++ {
+		for j := 0; j < len(cols); j++ {
+			delim := getdelim(fieldsView, rowID, j, len(cols))
+			cols[j] = delim.mem()
+			if len(cols[j]) == 0 {
+				continue outer // MISSING
+			}
+		}
+		captureRow(&s.scratch, delims, rowID, s.auxsyms, rp)
+		if err := s.run.add(cols, &s.st.Symtab, s.scratch.Bytes()); err != nil {
+			return err
+		}
+		// spill early rather than failing
+		// if the query budget is exhausted
+		if s.run.size() >= s.memory || s.mem.resize(s.run.size()) != nil {
+			if err := s.spill(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// spill sorts the in-memory run
+// and writes it to a temporary file
+func (s *sortstateSpill) spill() error {
+	s.run.sort(s.fields)
+	f, err := createSpill("sort")
+	if err != nil {
+		return fmt.Errorf("sort: creating spill file: %w", err)
+	}
+	// the parent removes the file
+	// even if writing fails
+	s.spilled = append(s.spilled, &sortrun{f: f})
+	w := bufio.NewWriterSize(f, spillFlushAt)
+	err = s.run.writeTo(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("sort: spilling rows: %w", err)
+	}
+	s.run.reset()
+	s.mem.release()
+	return nil
+}
+
+func (s *sortstateSpill) Close() error {
+	if s.parentNotified {
+		return nil
+	}
+	s.parentNotified = true
+
+	s.findbc.reset()
+	runs := s.spilled
+	if len(s.run.recs) > 0 {
+		s.run.sort(s.fields)
+		runs = append(runs, s.run)
+	}
+	s.run, s.spilled = nil, nil
+	if len(runs) == 0 {
+		return nil
+	}
+	s.parent.recordsLock.Lock()
+	s.parent.runs = append(s.parent.runs, runs...)
+	s.mem.moveTo(&s.parent.mem)
+	s.parent.recordsLock.Unlock()
+	return nil
+}
@@ -0,0 +1,39 @@
+// Copyright (C) 2022 Sneller, Inc.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"os"
+)
+
+// SpillDir is the directory in which query
+// operators that exceed their memory budget
+// (see SortMemory and AggregateSpillMemory)
+// write temporary files. If it is empty,
+// os.TempDir() is used.
+var SpillDir = ""
+
+// createSpill creates a new temporary file
+// for spilling the state of the named operator
+func createSpill(name string) (*os.File, error) {
+	return os.CreateTemp(SpillDir, "sneller-"+name+"-*")
+}
+
+// removeSpill closes and removes a file
+// created with createSpill
+func removeSpill(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
@@ -1501,6 +1501,17 @@ func (p *prog) isSubnetOfIP4(str *value, min, max [4]byte) *value {
 	return p.ssa2imm(sIsSubnetOfIP4, str, p.mask(str), stringext.ToBCD(&min, &max))
 }
 
+// IsSubnetOfIP6 returns whether the give value is an IPv6 address between (and including) min and max
+func (p *prog) isSubnetOfIP6(str *value, min, max [16]byte) *value {
+	str = p.coerceStr(str)
+	var enc [32]byte
+	binary.LittleEndian.PutUint64(enc[0:], binary.BigEndian.Uint64(min[0:]))
+	binary.LittleEndian.PutUint64(enc[8:], binary.BigEndian.Uint64(min[8:]))
+	binary.LittleEndian.PutUint64(enc[16:], binary.BigEndian.Uint64(max[0:]))
+	binary.LittleEndian.PutUint64(enc[24:], binary.BigEndian.Uint64(max[8:]))
+	return p.ssa2imm(sIsSubnetOfIP6, str, p.mask(str), string(enc[:]))
+}
+
 // SkipCharLeftConst skips a constant number of UTF-8 code-points from the left side of a string
 func (p *prog) skipCharLeftConst(str *value, nChars int) *value {
 	str = p.coerceStr(str)
@@ -2574,6 +2585,39 @@ func (p *prog) upper(s *value) *value {
 	return p.ssa2(supperstr, s, p.mask(s))
 }
 
+// strfunc applies a unary string function to s
+func (p *prog) strfunc(op ssaop, s *value) *value {
+	return p.ssa2(op, s, p.mask(s))
+}
+
+// fingerprint computes the low 64 bits
+// of the hash of the value v
+func (p *prog) fingerprint(v *value) *value {
+	h := p.hash(v)
+	return p.ssa2(shashtoi64, h, p.mask(h))
+}
+
+// urlQueryParam returns the (still encoded) value
+// of the query string parameter called name
+func (p *prog) urlQueryParam(s *value, name string) *value {
+	q := p.strfunc(surlquery, s)
+	return p.ssa2imm(surlqueryparam, q, p.mask(q), name)
+}
+
+// inetNtoa formats an integer in [0, 2^32)
+// as a dotted-quad IPv4 address
+func (p *prog) inetNtoa(v *value) *value {
+	i, k := p.coerceI64(v)
+	return p.ssa2(sinetntoa, i, k)
+}
+
+// ip6ToCIDR formats the network of the IPv6 address s
+// having the given prefix length in CIDR notation
+func (p *prog) ip6ToCIDR(s *value, n int) *value {
+	s = p.coerceStr(s)
+	return p.ssa2imm(sip6tocidr, s, p.mask(s), int64(n))
+}
+
 func (p *prog) objectSize(v *value) *value {
 	return p.ssa2(sobjectsize, v, p.mask(v))
 }
@@ -2826,6 +2870,28 @@ func (p *prog) aggregateCount(child, filter *value, slot aggregateslot) *value {
 	return p.ssa2imm(saggcount, p.initMem(), mask, slot)
 }
 
+// aggregateMoments accumulates the running count, mean
+// and central moments (see evalbc_aggmoments.h) of child
+func (p *prog) aggregateMoments(child, filter *value, slot aggregateslot) *value {
+	v, m := p.coerceF64(child)
+	if filter != nil {
+		m = p.and(m, filter)
+	}
+	return p.ssa3imm(saggmomentsf, p.initMem(), v, m, slot)
+}
+
+// aggregateCoMoments accumulates the running count, means
+// and co-moments of the (y, x) pairs where both are numbers
+func (p *prog) aggregateCoMoments(y, x, filter *value, slot aggregateslot) *value {
+	yv, ym := p.coerceF64(y)
+	xv, xm := p.coerceF64(x)
+	m := p.and(ym, xm)
+	if filter != nil {
+		m = p.and(m, filter)
+	}
+	return p.ssaimm(saggcomomentsf, slot, p.initMem(), yv, xv, m)
+}
+
 func (p *prog) aggregateApproxCountDistinct(child, filter *value, slot aggregateslot, precision uint8) *value {
 	mask := p.mask(child)
 	if filter != nil {
@@ -2836,9 +2902,13 @@ func (p *prog) aggregateApproxCountDistinct(child, filter *value, slot aggregate
 	return p.ssa2imm(saggapproxcount, h, mask, (uint64(slot)<<8)|uint64(precision))
 }
 
-func (p *prog) aggregateMergeState(child *value, slot aggregateslot) *value {
+func (p *prog) aggregateMergeState(child, filter *value, slot aggregateslot) *value {
 	blob := p.ssa2(stoblob, child, p.mask(child))
-	return p.ssa2imm(saggmergestate, blob, p.mask(blob), slot)
+	mask := p.mask(blob)
+	if filter != nil {
+		mask = p.and(mask, filter)
+	}
+	return p.ssa2imm(saggmergestate, blob, mask, slot)
 }
 
 // Slot aggregate operations
@@ -2933,6 +3003,24 @@ func (p *prog) aggregateSlotCount(mem, bucket, mask *value, offset aggregateslot
 	return p.ssa3imm(saggslotcount, mem, bucket, mask, offset)
 }
 
+func (p *prog) aggregateSlotMoments(mem, bucket, value, mask *value, offset aggregateslot) *value {
+	v, m := p.coerceF64(value)
+	if mask != nil {
+		m = p.and(m, mask)
+	}
+	return p.ssa4imm(saggslotmomentsf, mem, bucket, v, m, offset)
+}
+
+func (p *prog) aggregateSlotCoMoments(mem, bucket, y, x, mask *value, offset aggregateslot) *value {
+	yv, ym := p.coerceF64(y)
+	xv, xm := p.coerceF64(x)
+	m := p.and(ym, xm)
+	if mask != nil {
+		m = p.and(m, mask)
+	}
+	return p.ssaimm(saggslotcomomentsf, offset, mem, bucket, yv, xv, m)
+}
+
 func (p *prog) aggregateSlotApproxCountDistinct(mem, bucket, argv, mask *value, offset aggregateslot, precision uint8) *value {
 	k := p.mask(argv)
 	if mask != nil {
@@ -2942,6 +3030,17 @@ func (p *prog) aggregateSlotApproxCountDistinct(mem, bucket, argv, mask *value,
 	return p.ssa4imm(saggslotapproxcount, mem, bucket, h, k, (uint64(offset)<<8)|uint64(precision))
 }
 
+// aggregateSlotTDigest passes the values of argv as
+// float64 values to the merge buffer at offset
+// (see AggregateOp.mergevalues)
+func (p *prog) aggregateSlotTDigest(bucket, argv, mask *value, offset aggregateslot) *value {
+	v, m := p.coerceF64(argv)
+	if mask != nil {
+		m = p.and(m, mask)
+	}
+	return p.ssa3imm(sAggSlotTDigest, bucket, v, m, offset)
+}
+
 func (p *prog) aggregateSlotMergeState(bucket, argv, mask *value, offset aggregateslot) *value {
 	blob := p.ssa2(stoblob, argv, mask)
 	return p.ssa3imm(saggslotmergestate, bucket, blob, p.mask(blob), offset)
@@ -4149,7 +4248,7 @@ func (p *prog) compile(dst *bytecode, st *symtab, callerName string) error {
 	dst.compiled = c.asm.grabCode()
 
 	reserve := c.asm.scratchuse + len(c.litbuf)
-	if reserve > PageSize || strings.HasSuffix(callerName, "sort findbc") {
+	if reserve > PageSize || strings.HasSuffix(callerName, "findbc") {
 		reserve = PageSize
 	}
 	dst.savedlit = c.litbuf
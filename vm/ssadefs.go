@@ -124,6 +124,24 @@ const (
 	slowerstr
 	supperstr
 
+	smd5str        // hex-encoded MD5 digest of a string
+	ssha1str       // hex-encoded SHA1 digest of a string
+	ssha256str     // hex-encoded SHA256 digest of a string
+	sxxhash64str   // XXH64 hash of a string
+	stohexstr      // hex-encode a string
+	sfromhexstr    // hex-decode a string
+	stobase64str   // base64-encode a string
+	sfrombase64str // base64-decode a string
+
+	surlhost       // host of a URL
+	surlpath       // path of a URL
+	surlquery      // query string of a URL
+	surlqueryparam // value of a query string parameter
+	surldecode     // decode a percent-encoded string
+	sinetaton      // parse an IPv4 address into an integer
+	sinetntoa      // format an integer as an IPv4 address
+	sip6tocidr     // format the network of an IPv6 address in CIDR notation
+
 	// #region raw string comparison
 	sStrCmpEqCs              // Ascii string compare equality case-sensitive
 	sStrCmpEqCi              // Ascii string compare equality case-insensitive
@@ -155,6 +173,7 @@ const (
 	sStrContainsPatternUTF8Ci // String contains pattern case-insensitive
 
 	sIsSubnetOfIP4 // IP subnet matching
+	sIsSubnetOfIP6 // IPv6 subnet matching
 
 	sStrSkip1CharLeft  // String skip 1 unicode code-point from left
 	sStrSkip1CharRight // String skip 1 unicode code-point from right
@@ -188,6 +207,7 @@ const (
 	shashvaluep // hash a value and add it to the current hash
 	shashmember // look up a hash in a tree for existence; returns predicate
 	shashlookup // look up a hash in a tree for a value; returns boxed
+	shashtoi64  // extract the low 64 bits of a hash
 
 	sstorev // copy a value from one slot to another
 
@@ -324,6 +344,8 @@ const (
 	saggori
 	saggxori
 	saggcount
+	saggmomentsf
+	saggcomomentsf
 	saggmergestate
 
 	saggbucket
@@ -343,6 +365,8 @@ const (
 	saggslotori
 	saggslotxori
 	saggslotcount
+	saggslotmomentsf
+	saggslotcomomentsf
 
 	sbroadcastts
 	sunix
@@ -422,8 +446,8 @@ const (
 	saggapproxcount     // APPROX_COUNT_DISTINCT
 	saggslotapproxcount // APPROX_COUNT_DISTINCT aggregate in GROUP BY
 
-	sAggTDigest // tDigest aggregator used for percentile, median approximation
-	sAggSlotTDigest
+	sAggTDigest     // tDigest aggregator used for percentile, median approximation
+	sAggSlotTDigest // tDigest aggregator in GROUP BY
 
 	saggslotmergestate
 
@@ -782,6 +806,24 @@ var _ssainfo = [_ssamax]ssaopinfo{
 	slowerstr: {text: "lower.str", argtypes: str1Args, rettype: stStringMasked, bc: opslower},
 	supperstr: {text: "upper.str", argtypes: str1Args, rettype: stStringMasked, bc: opsupper},
 
+	smd5str:        {text: "md5.str", cost: costHeavy, argtypes: str1Args, rettype: stString, bc: opmd5str},
+	ssha1str:       {text: "sha1.str", cost: costHeavy, argtypes: str1Args, rettype: stString, bc: opsha1str},
+	ssha256str:     {text: "sha256.str", cost: costHeavy, argtypes: str1Args, rettype: stString, bc: opsha256str},
+	sxxhash64str:   {text: "xxhash64.str", cost: costHeavy, argtypes: str1Args, rettype: stInt, bc: opxxhash64str},
+	stohexstr:      {text: "tohex.str", argtypes: str1Args, rettype: stStringMasked, bc: optohexstr},
+	sfromhexstr:    {text: "fromhex.str", argtypes: str1Args, rettype: stStringMasked, bc: opfromhexstr},
+	stobase64str:   {text: "tobase64.str", argtypes: str1Args, rettype: stStringMasked, bc: optobase64str},
+	sfrombase64str: {text: "frombase64.str", argtypes: str1Args, rettype: stStringMasked, bc: opfrombase64str},
+
+	surlhost:       {text: "urlhost", argtypes: str1Args, rettype: stStringMasked, bc: opurlhost},
+	surlpath:       {text: "urlpath", argtypes: str1Args, rettype: stStringMasked, bc: opurlpath},
+	surlquery:      {text: "urlquery", argtypes: str1Args, rettype: stStringMasked, bc: opurlquery},
+	surlqueryparam: {text: "urlqueryparam", cost: costMedium, argtypes: str1Args, rettype: stStringMasked, immfmt: fmtdict, bc: opurlqueryparam},
+	surldecode:     {text: "urldecode", argtypes: str1Args, rettype: stStringMasked, bc: opurldecode},
+	sinetaton:      {text: "inetaton", argtypes: str1Args, rettype: stIntMasked, bc: opinetaton},
+	sinetntoa:      {text: "inetntoa", argtypes: int1Args, rettype: stStringMasked, bc: opinetntoa},
+	sip6tocidr:     {text: "ip6tocidr", cost: costHeavy, argtypes: str1Args, rettype: stStringMasked, immfmt: fmti64, bc: opip6tocidr},
+
 	sStrCmpEqCs:      {text: "cmp_str_eq_cs", argtypes: str1Args, rettype: stBool, immfmt: fmtdict, bc: opCmpStrEqCs},
 	sStrCmpEqCi:      {text: "cmp_str_eq_ci", argtypes: str1Args, rettype: stBool, immfmt: fmtdict, bc: opCmpStrEqCi},
 	sStrCmpEqUTF8Ci:  {text: "cmp_str_eq_utf8_ci", argtypes: str1Args, rettype: stBool, immfmt: fmtdict, bc: opCmpStrEqUTF8Ci},
@@ -821,6 +863,7 @@ var _ssainfo = [_ssamax]ssaopinfo{
 
 	// ip matching
 	sIsSubnetOfIP4: {text: "is_subnet_of_ip4", cost: costMedium, argtypes: str1Args, rettype: stBool, immfmt: fmtdict, bc: opIsSubnetOfIP4},
+	sIsSubnetOfIP6: {text: "is_subnet_of_ip6", cost: costHeavy, argtypes: str1Args, rettype: stBool, immfmt: fmtdict, bc: opIsSubnetOfIP6},
 
 	// s, k = skip_1char_left s, k -- skip one unicode character at the beginning (left) of a string slice
 	sStrSkip1CharLeft: {text: "skip_1char_left", argtypes: str1Args, rettype: stStringMasked, bc: opSkip1charLeft},
@@ -868,6 +911,7 @@ var _ssainfo = [_ssamax]ssaopinfo{
 
 	shashmember: {text: "hashmember", argtypes: []ssatype{stHash, stBool}, rettype: stBool, immfmt: fmtother, bc: ophashmember, emit: emithashmember},
 	shashlookup: {text: "hashlookup", argtypes: []ssatype{stHash, stBool}, rettype: stValueMasked, immfmt: fmtother, bc: ophashlookup, emit: emithashlookup},
+	shashtoi64:  {text: "hashtoi64", argtypes: []ssatype{stHash, stBool}, rettype: stInt, bc: ophashtoi64},
 
 	sliteral: {text: "literal", rettype: stValue, immfmt: fmtother, bc: oplitref, safeValueMask: true}, // yields <value>.kinit
 
@@ -1006,6 +1050,9 @@ var _ssainfo = [_ssamax]ssaopinfo{
 	saggxori:  {text: "aggxor.i", rettype: stMem, argtypes: []ssatype{stMem, stInt, stBool}, immfmt: fmtaggslot, bc: opaggxori, priority: prioMem},
 	saggcount: {text: "aggcount", rettype: stMem, argtypes: []ssatype{stMem, stBool}, immfmt: fmtaggslot, bc: opaggcount, priority: prioMem + 1},
 
+	saggmomentsf:   {text: "aggmoments.f", rettype: stMem, argtypes: []ssatype{stMem, stFloat, stBool}, immfmt: fmtaggslot, bc: opaggmomentsf, priority: prioMem},
+	saggcomomentsf: {text: "aggcomoments.f", rettype: stMem, argtypes: []ssatype{stMem, stFloat, stFloat, stBool}, immfmt: fmtaggslot, bc: opaggcomomentsf, priority: prioMem},
+
 	sAggTDigest: {text: "agg.tdigest", rettype: stMem, argtypes: []ssatype{stMem, stFloat, stBool}, immfmt: fmtaggslot, bc: opAggTDigest, priority: prioMem},
 
 	// compute hash aggregate bucket location; encoded immediate will be input hash slot to use
@@ -1029,6 +1076,9 @@ var _ssainfo = [_ssamax]ssaopinfo{
 	saggslotxori:  {text: "aggslotxor.i", argtypes: []ssatype{stMem, stBucket, stInt, stBool}, rettype: stMem, immfmt: fmtslot, bc: opaggslotxori, priority: prioMem},
 	saggslotcount: {text: "aggslotcount", argtypes: []ssatype{stMem, stBucket, stBool}, rettype: stMem, immfmt: fmtslot, bc: opaggslotcount, priority: prioMem},
 
+	saggslotmomentsf:   {text: "aggslotmoments.f", argtypes: []ssatype{stMem, stBucket, stFloat, stBool}, rettype: stMem, immfmt: fmtslot, bc: opaggslotmomentsf, priority: prioMem},
+	saggslotcomomentsf: {text: "aggslotcomoments.f", argtypes: []ssatype{stMem, stBucket, stFloat, stFloat, stBool}, rettype: stMem, immfmt: fmtslot, bc: opaggslotcomomentsf, priority: prioMem},
+
 	// boxing ops
 	//
 	// turn two masks into TRUE/FALSE/MISSING according to 3VL
@@ -1117,6 +1167,17 @@ var _ssainfo = [_ssamax]ssaopinfo{
 		bc:       opaggmergestate,
 		immfmt:   fmtaggslot,
 	},
+	// the values are passed to the merge buffer in place of
+	// states (see aggMergeBuffer) and added to the t-digests
+	// by aggtable.flushValues
+	sAggSlotTDigest: {
+		text:     "aggslot.tdigest",
+		argtypes: []ssatype{stBucket, stFloat, stBool},
+		rettype:  stMem,
+		bc:       opaggslotmergestate,
+		immfmt:   fmtaggslot,
+		priority: prioMem,
+	},
 	saggslotmergestate: {
 		text:     "aggslotmergestate",
 		argtypes: []ssatype{stBucket, stBlob, stBool},
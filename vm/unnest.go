@@ -15,9 +15,12 @@
 package vm
 
 import (
+	"fmt"
 	"io"
+	"unsafe"
 
 	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
 	"golang.org/x/exp/slices"
 )
 
@@ -25,74 +28,146 @@ import (
 // that have their contents cross-joined with
 // the array contents as an auxiliary binding
 type Unnest struct {
-	dst    QuerySink
-	field  expr.Node
-	prog   prog
-	result string
+	dst      QuerySink
+	fields   []expr.Node
+	progs    []prog
+	results  []string
+	position string
+	base     int
+	budget   *Budget
 }
 
 // NewUnnest creates an Unnest QuerySink that cross-joins
 // the given field (which should be an array) into the
 // input stream as an auxiliary binding with the given name.
 func NewUnnest(dst QuerySink, field expr.Node, result string) (*Unnest, error) {
+	return NewZipUnnest(dst, []expr.Node{field}, []string{result})
+}
+
+// NewZipUnnest is like NewUnnest, but it un-nests
+// several arrays in lockstep, so that the i-th row
+// produced from an input row binds results[j] to the
+// i-th element of fields[j]. Arrays that are shorter
+// than the longest one are padded with MISSING.
+func NewZipUnnest(dst QuerySink, fields []expr.Node, results []string) (*Unnest, error) {
+	if len(fields) == 0 || len(fields) != len(results) {
+		return nil, fmt.Errorf("unnest: %d fields and %d results", len(fields), len(results))
+	}
 	u := &Unnest{
-		dst:    dst,
-		field:  field,
-		result: result,
-	}
-	p := &u.prog
-	p.begin()
-	// produce a program that sticks the slice
-	// to be splat-ed into Z2:Z3
-	v, err := compile(p, u.field)
-	if err != nil {
-		return nil, err
+		dst:     dst,
+		fields:  fields,
+		progs:   make([]prog, len(fields)),
+		results: results,
+	}
+	for i := range fields {
+		p := &u.progs[i]
+		p.begin()
+		// produce a program that sticks the slice
+		// to be splat-ed into Z2:Z3
+		v, err := compile(p, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		list := p.ssa2(stolist, v, p.mask(v))
+		p.returnScalar(p.initMem(), list, p.mask(list))
+		p.Renumber()
 	}
-	list := p.ssa2(stolist, v, p.mask(v))
-	p.returnScalar(p.initMem(), list, p.mask(list))
-	p.Renumber()
 	return u, nil
 }
 
+// SetPosition binds result to the position of
+// each element within its array, counting from base.
+func (u *Unnest) SetPosition(result string, base int) {
+	u.position = result
+	u.base = base
+}
+
+// SetBudget sets the Budget to which the memory
+// used to buffer unnested rows is charged.
+func (u *Unnest) SetBudget(b *Budget) {
+	u.budget = b
+}
+
 func (u *Unnest) Open() (io.WriteCloser, error) {
 	dst, err := u.dst.Open()
 	if err != nil {
 		return nil, err
 	}
-	unnest := &unnesting{parent: u, dstrc: asRowConsumer(dst)}
+	n := len(u.fields)
+	unnest := &unnesting{
+		parent: u,
+		dstrc:  asRowConsumer(dst),
+		progs:  make([]prog, n),
+		splats: make([]bytecode, n),
+		elems:  make([][]vmref, n),
+		perms:  make([][]int32, n),
+		outs:   make([]int, n),
+	}
+	unnest.mem.budget = u.budget
 	return splitter(unnest), nil
 }
 
 func (u *Unnest) Close() error {
-	u.prog.reset()
+	for i := range u.progs {
+		u.progs[i].reset()
+	}
 	return u.dst.Close()
 }
 
 type unnesting struct {
 	parent *Unnest
-	prog   prog
-	splat  bytecode
-	perms  []int32
+	progs  []prog
+	splats []bytecode
 	dstrc  rowConsumer
 	params rowParams
 	auxnum int
 
-	// cached buffers for inner and outer refs
-	inner, outer []vmref
+	// splatted elements of each array
+	// and the lanes to which they belong
+	elems [][]vmref
+	perms [][]int32
+	outs  []int
+
+	// the rows produced for each splat:
+	// lane is the input lane of each row,
+	// index its position within the lane
+	// and cols the elements of each array
+	lane, index []int32
+	cols        [][]vmref
+	inner       []vmref
+	cursor      []int
+
+	// page holds the encoded positions
+	page []byte
+	off  int
+	tmp  ion.Buffer
+
+	// charge for the buffers
+	mem memacct
 }
 
 func (u *unnesting) next() rowConsumer { return u.dstrc }
 
 func (u *unnesting) EndSegment() {
-	u.splat.dropScratch()
+	for i := range u.splats {
+		u.splats[i].dropScratch()
+	}
 }
 
 func (u *unnesting) symbolize(st *symtab, aux *auxbindings) error {
-	err := recompile(st, &u.parent.prog, &u.prog, &u.splat, aux, "unnesting")
-	if err != nil {
-		return err
+	for i := range u.splats {
+		err := recompile(st, &u.parent.progs[i], &u.progs[i], &u.splats[i], aux, "unnesting")
+		if err != nil {
+			return err
+		}
+	}
+	u.auxnum = aux.push(u.parent.results[0])
+	for _, r := range u.parent.results[1:] {
+		aux.push(r)
+	}
+	if u.parent.position != "" {
+		aux.push(u.parent.position)
 	}
-	u.auxnum = aux.push(u.parent.result)
 	return u.dstrc.symbolize(st, aux)
 }
 
@@ -127,82 +202,237 @@ func sanitizeAux(aux []vmref, size int) []vmref {
 	return aux
 }
 
-func (u *unnesting) splatParams(in *rowParams, consumed int, perm []int32, inner []vmref) *rowParams {
-	if len(perm) != len(inner) {
-		panic("???")
+// splat splats the elements of each array of
+// the rows starting at delims[consumed] and
+// returns the number of rows that were handled
+func (u *unnesting) splat(delims []vmref, consumed int) (int, error) {
+	for {
+		in := len(delims) - consumed
+		grow := -1
+		for k := range u.splats {
+			if cap(u.elems[k]) == 0 {
+				u.elems[k] = make([]vmref, 1024)
+				u.perms[k] = make([]int32, 1024)
+			}
+			// provide as much space as possible:
+			elems := u.elems[k][:cap(u.elems[k])]
+			perms := u.perms[k][:cap(u.perms[k])]
+			u.splats[k].auxpos = consumed
+			n, out := evalsplat(&u.splats[k], delims[consumed:], elems, perms)
+			if u.splats[k].err != 0 {
+				return 0, bytecodeerror("unnest", &u.splats[k])
+			}
+			u.elems[k], u.perms[k], u.outs[k] = elems, perms, out
+			if n == 0 {
+				grow = k
+				break
+			}
+			if n < in {
+				in = n
+			}
+		}
+		if grow < 0 {
+			// the elements of the lane that did not
+			// fit entirely may have been emitted, and
+			// the arrays may not have handled the same
+			// number of lanes, so drop the extra elements
+			for k := range u.outs {
+				for u.outs[k] > 0 && int(u.perms[k][u.outs[k]-1]) >= in {
+					u.outs[k]--
+				}
+			}
+			return in, nil
+		}
+		// there wasn't enough room to splat a single
+		// lane's array members! we need more space,
+		// so double the slice sizes here
+		u.elems[grow] = slices.Grow(u.elems[grow], len(u.elems[grow]))
+		u.perms[grow] = slices.Grow(u.perms[grow], len(u.perms[grow]))
+		if err := u.mem.resize(u.size()); err != nil {
+			return 0, fmt.Errorf("unnest: %w", err)
+		}
 	}
-	if len(in.auxbound) != u.auxnum {
-		panic("unexpected auxilliary inputs")
+}
+
+// zip computes the rows produced by the first in
+// lanes from the splatted elements and returns
+// the number of rows
+func (u *unnesting) zip(in int) int {
+	n := len(u.splats)
+	u.cols = shrink(u.cols, n)
+	if n == 1 {
+		// the common case: every element is a row
+		out := u.outs[0]
+		u.lane = u.perms[0][:out]
+		u.cols[0] = u.elems[0][:out]
+		if u.parent.position != "" {
+			u.index = shrink(u.index, out)
+			for j := range u.lane {
+				if j > 0 && u.lane[j] == u.lane[j-1] {
+					u.index[j] = u.index[j-1] + 1
+				} else {
+					u.index[j] = 0
+				}
+			}
+		}
+		return out
+	}
+	rows := 0
+	u.cursor = shrink(u.cursor, n)
+	pos := u.cursor // next element of each array
+	for k := range pos {
+		pos[k] = 0
 	}
-	// splat existing row-oriented bindings
-	u.params.auxbound = shrink(u.params.auxbound, u.auxnum+1)
-	for i := range in.auxbound {
-		u.params.auxbound[i] = sanitizeAux(u.params.auxbound[i], len(inner))
-		for j, n := range perm {
-			u.params.auxbound[i][j] = in.auxbound[i][consumed+int(n)]
+	u.lane, u.index = u.lane[:0], u.index[:0]
+	for k := range u.cols {
+		u.cols[k] = u.cols[k][:0]
+	}
+	for l := int32(0); int(l) < in; l++ {
+		// the elements of each lane are contiguous
+		for i := int32(0); ; i++ {
+			more := false
+			for k := range pos {
+				if pos[k] < u.outs[k] && u.perms[k][pos[k]] == l {
+					more = true
+				}
+			}
+			if !more {
+				break
+			}
+			for k := range pos {
+				ref := vmref{}
+				if pos[k] < u.outs[k] && u.perms[k][pos[k]] == l {
+					ref = u.elems[k][pos[k]]
+					pos[k]++
+				}
+				u.cols[k] = append(u.cols[k], ref)
+			}
+			u.lane = append(u.lane, l)
+			u.index = append(u.index, i)
+			rows++
 		}
 	}
-	// add new bindings
-	u.params.auxbound[u.auxnum] = inner
-	return &u.params
+	return rows
+}
+
+// positions writes the positions of the rows
+// starting at start to the page until the page
+// is full and returns the end of those rows
+func (u *unnesting) positions(start, end int, aux []vmref) int {
+	if u.page == nil {
+		u.page = u.parent.budget.Malloc()
+	}
+	pos, ok := vmdispl(u.page)
+	if !ok {
+		panic("unnest: position page is not vm memory")
+	}
+	u.off = 0
+	for j := start; j < end; j++ {
+		u.tmp.Reset()
+		u.tmp.WriteInt(int64(u.index[j]) + int64(u.parent.base))
+		buf := u.tmp.Bytes()
+		if len(buf) > len(u.page)-u.off {
+			return j
+		}
+		copy(u.page[u.off:], buf)
+		aux[j-start] = vmref{pos + uint32(u.off), uint32(len(buf))}
+		u.off += len(buf)
+	}
+	return end
 }
 
 func (u *unnesting) writeRows(delims []vmref, rp *rowParams) error {
 	if len(delims) == 0 {
 		return nil
 	}
-	if cap(u.outer) == 0 {
-		u.outer = make([]vmref, 1024)
-		u.perms = make([]int32, 1024)
-	}
-	if u.splat.compiled == nil {
+	if u.splats[0].compiled == nil {
 		panic("WriteRows() called before Symbolize()")
 	}
-
-	u.splat.prepare(rp)
+	if len(rp.auxbound) != u.auxnum {
+		panic("unexpected auxilliary inputs")
+	}
+	for k := range u.splats {
+		u.splats[k].prepare(rp)
+	}
+	naux := u.auxnum + len(u.splats)
+	if u.parent.position != "" {
+		naux++
+	}
+	u.params.auxbound = shrink(u.params.auxbound, naux)
 	consumed := 0
 	for consumed < len(delims) {
-		// provide as much space as possible:
-		u.outer = u.outer[:cap(u.outer)]
-		u.perms = u.perms[:cap(u.perms)]
-		in, out := evalsplat(&u.splat, delims[consumed:], u.outer, u.perms)
-		if u.splat.err != 0 {
-			return bytecodeerror("unnest", &u.splat)
-		}
-		// adjust this to take into account the fact
-		// that we may not have actually handled all the lanes
-		u.splat.auxpos = consumed + in
-		if in == 0 {
-			// there wasn't enough room to splat a single
-			// lane's array members! we need more space,
-			// so double the slice sizes here
-			u.outer = slices.Grow(u.outer, len(u.outer))
-			u.perms = slices.Grow(u.perms, len(u.perms))
-			continue
-		}
-		if out == 0 {
-			consumed += in
-			continue
-		}
-		// incorporate inner and outer values
-		// in two slices adjacent to one another:
-		outer := u.outer[:out]
-		u.inner = shrink(u.inner, len(outer))
-		innerperm := u.perms[:out]
-		// permute delimiters into unrolled delimiters:
-		for i, n := range innerperm {
-			u.inner[i] = delims[consumed+int(n)]
-		}
-		err := u.dstrc.writeRows(u.inner, u.splatParams(rp, consumed, innerperm, outer))
+		in, err := u.splat(delims, consumed)
 		if err != nil {
 			return err
 		}
+		rows := u.zip(in)
+		for start := 0; start < rows; {
+			end := rows
+			if u.parent.position != "" {
+				aux := sanitizeAux(u.params.auxbound[naux-1], end-start)
+				end = u.positions(start, end, aux)
+				u.params.auxbound[naux-1] = sanitizeAux(aux, end-start)
+			}
+			// permute delimiters into unrolled delimiters
+			// and splat existing row-oriented bindings:
+			u.inner = shrink(u.inner, end-start)
+			for i := range rp.auxbound {
+				u.params.auxbound[i] = sanitizeAux(u.params.auxbound[i], end-start)
+			}
+			for j, l := range u.lane[start:end] {
+				u.inner[j] = delims[consumed+int(l)]
+				for i := range rp.auxbound {
+					u.params.auxbound[i][j] = rp.auxbound[i][consumed+int(l)]
+				}
+			}
+			// add new bindings
+			for k := range u.cols {
+				aux := sanitizeAux(u.params.auxbound[u.auxnum+k], end-start)
+				copy(aux, u.cols[k][start:end])
+				u.params.auxbound[u.auxnum+k] = aux
+			}
+			if err := u.mem.resize(u.size()); err != nil {
+				return fmt.Errorf("unnest: %w", err)
+			}
+			if err := u.dstrc.writeRows(u.inner, &u.params); err != nil {
+				return err
+			}
+			start = end
+		}
 		consumed += in
 	}
 	return nil
 }
 
+// size returns the memory used by the buffers
+func (u *unnesting) size() int {
+	refs := cap(u.inner)
+	ints := cap(u.lane) + cap(u.index)
+	for k := range u.elems {
+		refs += cap(u.elems[k])
+		ints += cap(u.perms[k])
+	}
+	if len(u.splats) > 1 {
+		for k := range u.cols {
+			refs += cap(u.cols[k])
+		}
+	} else {
+		ints -= cap(u.lane) // aliases u.perms[0]
+	}
+	for _, aux := range u.params.auxbound {
+		refs += cap(aux)
+	}
+	return refs*int(unsafe.Sizeof(vmref{})) + ints*4
+}
+
 func (u *unnesting) Close() error {
-	u.splat.reset()
+	for i := range u.splats {
+		u.splats[i].reset()
+	}
+	if u.page != nil {
+		u.parent.budget.Free(u.page)
+		u.page = nil
+	}
+	u.mem.release()
 	return u.dstrc.Close()
 }
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/SnellerInc/sneller/expr"
+	"github.com/SnellerInc/sneller/ion"
 )
 
 func path(t testing.TB, s string) expr.Node {
@@ -152,3 +153,117 @@ func TestSplat(t *testing.T) {
 		}
 	}
 }
+
+// TestZipUnnestLarge tests arrays of different lengths
+// that do not fit in the splat buffers and positions
+// that do not fit in a single page
+func TestZipUnnestLarge(t *testing.T) {
+	const (
+		rows  = 100
+		large = 300000
+	)
+	xlen := func(id int) int {
+		if id == 0 {
+			return large
+		}
+		return 700
+	}
+	ylen := func(id int) int {
+		if id == 0 {
+			return 3
+		}
+		return 1000
+	}
+	var st ion.Symtab
+	var buf ion.Buffer
+	id := st.Intern("id")
+	xs := st.Intern("xs")
+	ys := st.Intern("ys")
+	for i := 0; i < rows; i++ {
+		buf.BeginStruct(-1)
+		buf.BeginField(id)
+		buf.WriteInt(int64(i))
+		buf.BeginField(xs)
+		buf.BeginList(-1)
+		for j := 0; j < xlen(i); j++ {
+			buf.WriteInt(int64(j % 50))
+		}
+		buf.EndList()
+		buf.BeginField(ys)
+		buf.BeginList(-1)
+		for j := 0; j < ylen(i); j++ {
+			buf.WriteInt(int64(j))
+		}
+		buf.EndList()
+		buf.EndStruct()
+	}
+	var chunk ion.Buffer
+	st.Marshal(&chunk, true)
+	chunk.UnsafeAppend(buf.Bytes())
+	if chunk.Size() > PageSize {
+		t.Fatalf("input of %d bytes does not fit in a page", chunk.Size())
+	}
+
+	var dst QueryBuffer
+	sel := Selection{
+		expr.Identity("id"), expr.Identity("x"), expr.Identity("y"), expr.Identity("i"),
+	}
+	pro, err := NewProjection(sel, &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := NewZipUnnest(pro, []expr.Node{expr.Ident("xs"), expr.Ident("ys")}, []string{"x", "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.SetPosition("i", 1)
+	var b Budget
+	u.SetBudget(&b)
+	err = CopyRows(u, buftbl(chunk.Bytes()), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Used() != 0 {
+		t.Errorf("%d bytes still charged to the budget", b.Used())
+	}
+
+	count := make([]int, rows)
+	sum := make([]int, rows)
+	for _, s := range bufferRows(t, dst.Bytes()) {
+		get := func(name string) (int, bool) {
+			f, ok := s.FieldByName(name)
+			if !ok {
+				return 0, false
+			}
+			v, err := f.Int()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return int(v), true
+		}
+		r, _ := get("id")
+		i, ok := get("i")
+		if !ok {
+			t.Fatalf("row %d: no position", r)
+		}
+		x, ok := get("x")
+		if ok != (i <= xlen(r)) || ok && x != (i-1)%50 {
+			t.Fatalf("row %d position %d: unexpected x %d (%v)", r, i, x, ok)
+		}
+		y, ok := get("y")
+		if ok != (i <= ylen(r)) || ok && y != i-1 {
+			t.Fatalf("row %d position %d: unexpected y %d (%v)", r, i, y, ok)
+		}
+		count[r]++
+		sum[r] += i
+	}
+	for r := range count {
+		n := xlen(r)
+		if m := ylen(r); m > n {
+			n = m
+		}
+		if count[r] != n || sum[r] != n*(n+1)/2 {
+			t.Errorf("row %d: %d positions with sum %d, want %d", r, count[r], sum[r], n)
+		}
+	}
+}